@@ -120,6 +120,22 @@ func (c *ConfigService) SetChannelEnabled(channel string, enabled bool) error {
 		cfg.Channels.WeComApp.Enabled = enabled
 	case "maixcam":
 		cfg.Channels.MaixCam.Enabled = enabled
+	case "email":
+		cfg.Channels.Email.Enabled = enabled
+	case "matrix":
+		cfg.Channels.Matrix.Enabled = enabled
+	case "signal":
+		cfg.Channels.Signal.Enabled = enabled
+	case "mqtt":
+		cfg.Channels.MQTT.Enabled = enabled
+	case "webhook":
+		cfg.Channels.Webhook.Enabled = enabled
+	case "irc":
+		cfg.Channels.IRC.Enabled = enabled
+	case "mattermost":
+		cfg.Channels.Mattermost.Enabled = enabled
+	case "rocketchat":
+		cfg.Channels.RocketChat.Enabled = enabled
 	default:
 		return fmt.Errorf("unknown channel: %s", channel)
 	}
@@ -262,6 +262,14 @@ func getChannelInfos(cfg *config.Config) []ChannelInfo {
 		{Name: "WeCom", Enabled: cfg.Channels.WeCom.Enabled},
 		{Name: "WeComApp", Enabled: cfg.Channels.WeComApp.Enabled},
 		{Name: "MaixCam", Enabled: cfg.Channels.MaixCam.Enabled},
+		{Name: "Email", Enabled: cfg.Channels.Email.Enabled},
+		{Name: "Matrix", Enabled: cfg.Channels.Matrix.Enabled},
+		{Name: "Signal", Enabled: cfg.Channels.Signal.Enabled},
+		{Name: "MQTT", Enabled: cfg.Channels.MQTT.Enabled},
+		{Name: "Webhook", Enabled: cfg.Channels.Webhook.Enabled},
+		{Name: "IRC", Enabled: cfg.Channels.IRC.Enabled},
+		{Name: "Mattermost", Enabled: cfg.Channels.Mattermost.Enabled},
+		{Name: "RocketChat", Enabled: cfg.Channels.RocketChat.Enabled},
 	}
 }
 
@@ -9,7 +9,6 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/Agentx-network/agentx/cmd/agentx/internal"
@@ -116,40 +115,23 @@ func gatewayCmd(debug bool) error {
 	// Inject channel manager into agent loop for command handling
 	agentLoop.SetChannelManager(channelManager)
 
-	var transcriber *voice.GroqTranscriber
-	groqAPIKey := cfg.Providers.Groq.APIKey
-	if groqAPIKey == "" {
-		for _, mc := range cfg.ModelList {
-			if strings.HasPrefix(mc.Model, "groq/") && mc.APIKey != "" {
-				groqAPIKey = mc.APIKey
-				break
-			}
+	transcriber, err := voice.NewTranscriber(cfg)
+	if err != nil {
+		logger.WarnCF("voice", "Voice transcription disabled", map[string]any{"error": err.Error()})
+	} else if transcriber != nil {
+		channelManager.SetTranscriber(transcriber)
+		provider := cfg.Voice.STT.Provider
+		if provider == "" {
+			provider = "groq"
 		}
-	}
-	if groqAPIKey != "" {
-		transcriber = voice.NewGroqTranscriber(groqAPIKey)
-		logger.InfoC("voice", "Groq voice transcription enabled")
+		logger.InfoCF("voice", "Voice transcription enabled", map[string]any{"provider": provider})
 	}
 
-	if transcriber != nil {
-		if telegramChannel, ok := channelManager.GetChannel("telegram"); ok {
-			if tc, ok := telegramChannel.(*channels.TelegramChannel); ok {
-				tc.SetTranscriber(transcriber)
-				logger.InfoC("voice", "Groq transcription attached to Telegram channel")
-			}
-		}
-		if discordChannel, ok := channelManager.GetChannel("discord"); ok {
-			if dc, ok := discordChannel.(*channels.DiscordChannel); ok {
-				dc.SetTranscriber(transcriber)
-				logger.InfoC("voice", "Groq transcription attached to Discord channel")
-			}
-		}
-		if slackChannel, ok := channelManager.GetChannel("slack"); ok {
-			if sc, ok := slackChannel.(*channels.SlackChannel); ok {
-				sc.SetTranscriber(transcriber)
-				logger.InfoC("voice", "Groq transcription attached to Slack channel")
-			}
-		}
+	synthesizer, err := voice.NewSynthesizer(cfg)
+	if err != nil {
+		logger.WarnCF("voice", "Spoken replies disabled", map[string]any{"error": err.Error()})
+	} else if synthesizer != nil {
+		channelManager.SetSynthesizer(synthesizer)
 	}
 
 	enabledChannels := channelManager.GetEnabledChannels()
@@ -276,6 +258,11 @@ func gatewayCmd(debug bool) error {
 		flusher.Flush()
 	})
 
+	for path, handler := range channelManager.HTTPHandlers() {
+		healthServer.HandleFunc(path, handler.ServeHTTP)
+		fmt.Printf("✓ Webhook endpoint available at http://%s:%d%s\n", cfg.Gateway.Host, cfg.Gateway.Port, path)
+	}
+
 	go func() {
 		if err := healthServer.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			logger.ErrorCF("health", "Health server error", map[string]any{"error": err.Error()})
@@ -298,6 +285,7 @@ func gatewayCmd(debug bool) error {
 	cronService.Stop()
 	agentLoop.Stop()
 	channelManager.StopAll(ctx)
+	agentLoop.Close()
 	fmt.Println("✓ Gateway stopped")
 
 	return nil
@@ -322,8 +310,7 @@ func setupCronTool(
 
 	// Set the onJob handler
 	cronService.SetOnJob(func(job *cron.CronJob) (string, error) {
-		result := cronTool.ExecuteJob(context.Background(), job)
-		return result, nil
+		return cronTool.ExecuteJob(context.Background(), job)
 	})
 
 	return cronService
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/Agentx-network/agentx/cmd/agentx/internal"
+)
+
+func NewModelsCommand() *cobra.Command {
+	var host string
+
+	cmd := &cobra.Command{
+		Use:   "models",
+		Short: "Manage local Ollama models",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return cmd.Help()
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&host, "host", "", "Ollama server URL (default: api_base of the first ollama model in config)")
+
+	hostFn := func() string {
+		if host != "" {
+			return host
+		}
+		cfg, err := internal.LoadConfig()
+		if err != nil {
+			return ""
+		}
+		return ollamaHost(cfg)
+	}
+
+	cmd.AddCommand(
+		newListCommand(hostFn),
+		newPullCommand(hostFn),
+	)
+
+	return cmd
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewModelsCommand(t *testing.T) {
+	cmd := NewModelsCommand()
+
+	require.NotNil(t, cmd)
+
+	assert.Equal(t, "Manage local Ollama models", cmd.Short)
+
+	assert.True(t, cmd.HasPersistentFlags())
+	assert.NotNil(t, cmd.PersistentFlags().Lookup("host"))
+
+	assert.Nil(t, cmd.Run)
+	assert.NotNil(t, cmd.RunE)
+
+	assert.True(t, cmd.HasSubCommands())
+
+	allowedCommands := []string{
+		"list",
+		"pull",
+	}
+
+	subcommands := cmd.Commands()
+	assert.Len(t, subcommands, len(allowedCommands))
+
+	for _, subcmd := range subcommands {
+		found := slices.Contains(allowedCommands, subcmd.Name())
+		assert.True(t, found, "unexpected subcommand %q", subcmd.Name())
+
+		assert.False(t, subcmd.Hidden)
+		assert.Nil(t, subcmd.Run)
+		assert.NotNil(t, subcmd.RunE)
+		assert.True(t, subcmd.HasExample())
+	}
+}
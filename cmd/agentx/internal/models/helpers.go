@@ -0,0 +1,87 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Agentx-network/agentx/pkg/config"
+	"github.com/Agentx-network/agentx/pkg/providers"
+	"github.com/Agentx-network/agentx/pkg/providers/ollama"
+)
+
+// ollamaHost returns the api_base of the first ollama entry in model_list,
+// or "" for the default local server.
+func ollamaHost(cfg *config.Config) string {
+	for _, mc := range cfg.ModelList {
+		if protocol, _ := providers.ExtractProtocol(mc.Model); protocol == "ollama" {
+			return mc.APIBase
+		}
+	}
+	return ""
+}
+
+func modelsListCmd(host string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	client := ollama.NewClient(host, nil)
+	models, err := client.List(ctx)
+	if err != nil {
+		return fmt.Errorf("listing models on %s: %w", ollama.NormalizeBaseURL(host), err)
+	}
+	if len(models) == 0 {
+		fmt.Println("No models installed. Pull one with: agentx models pull llama3.2")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSIZE\tPARAMS\tQUANT\tMODIFIED")
+	for _, m := range models {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			m.Name, formatSize(m.Size), m.Details.ParameterSize,
+			m.Details.QuantizationLevel, m.ModifiedAt.Format("2006-01-02"))
+	}
+	return w.Flush()
+}
+
+func modelsPullCmd(host, model string) error {
+	client := ollama.NewClient(host, nil)
+	lastStatus := ""
+	err := client.Pull(context.Background(), model, func(p ollama.PullProgress) {
+		if p.Total > 0 {
+			fmt.Printf("\r%s %3d%%", p.Status, p.Completed*100/p.Total)
+			lastStatus = p.Status
+			return
+		}
+		if p.Status != lastStatus {
+			if lastStatus != "" {
+				fmt.Println()
+			}
+			fmt.Print(p.Status)
+			lastStatus = p.Status
+		}
+	})
+	fmt.Println()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✓ Pulled %s. Use it with \"model\": \"ollama/%s\" in model_list.\n", model, model)
+	return nil
+}
+
+func formatSize(bytes int64) string {
+	const unit = 1000
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %s", float64(bytes)/float64(div), strings.Split("KB MB GB TB", " ")[exp])
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Agentx-network/agentx/pkg/config"
+)
+
+func TestOllamaHost(t *testing.T) {
+	cfg := &config.Config{ModelList: []config.ModelConfig{
+		{ModelName: "gpt", Model: "openai/gpt-4o", APIBase: "https://api.openai.com/v1"},
+		{ModelName: "local", Model: "ollama/llama3.2", APIBase: "http://gpu-box:11434/v1"},
+	}}
+	assert.Equal(t, "http://gpu-box:11434/v1", ollamaHost(cfg))
+	assert.Equal(t, "", ollamaHost(&config.Config{}))
+}
+
+func TestModelsPullCmd(t *testing.T) {
+	var pulled string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/pull", r.URL.Path)
+		var body struct {
+			Model string `json:"model"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		pulled = body.Model
+		fmt.Fprintln(w, `{"status":"pulling manifest"}`)
+		fmt.Fprintln(w, `{"status":"downloading","total":100,"completed":50}`)
+		fmt.Fprintln(w, `{"status":"success"}`)
+	}))
+	defer srv.Close()
+
+	require.NoError(t, modelsPullCmd(srv.URL+"/v1", "llama3.2"))
+	assert.Equal(t, "llama3.2", pulled)
+}
+
+func TestModelsListCmd_ServerError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"boom"}`, http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	err := modelsListCmd(srv.URL)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "boom")
+}
+
+func TestFormatSize(t *testing.T) {
+	assert.Equal(t, "512 B", formatSize(512))
+	assert.Equal(t, "2.0 GB", formatSize(2_000_000_000))
+	assert.Equal(t, "4.7 GB", formatSize(4_700_000_000))
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func newListCommand(hostFn func() string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "list",
+		Short:   "List models installed on the Ollama server",
+		Args:    cobra.NoArgs,
+		Example: `agentx models list`,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return modelsListCmd(hostFn())
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func newPullCommand(hostFn func() string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pull <model>",
+		Short: "Download a model to the Ollama server",
+		Args:  cobra.ExactArgs(1),
+		Example: `  agentx models pull llama3.2
+  agentx models pull qwen2.5:7b --host http://gpu-box:11434`,
+		RunE: func(_ *cobra.Command, args []string) error {
+			return modelsPullCmd(hostFn(), args[0])
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,56 @@
+package onboard
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/huh"
+
+	"github.com/Agentx-network/agentx/pkg/providers/ollama"
+)
+
+// chooseOllamaModel lets the user pick one of the models installed on the
+// local Ollama server. It keeps the default model when the server is not
+// running or has nothing installed.
+func chooseOllamaModel(provider *providerInfo) (*providerInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	models, err := ollama.NewClient(provider.APIBase, nil).List(ctx)
+	if err != nil || len(models) == 0 {
+		fmt.Printf("  No local Ollama models found; using %s. Install it with: agentx models pull %s\n",
+			provider.ModelName, provider.ModelName)
+		return provider, nil
+	}
+
+	opts := make([]huh.Option[string], len(models))
+	for i, m := range models {
+		label := m.Name
+		if m.Details.ParameterSize != "" {
+			label += "  (" + m.Details.ParameterSize + ")"
+		}
+		opts[i] = huh.NewOption(label, m.Name)
+	}
+
+	var name string
+	err = huh.NewSelect[string]().
+		Title("Choose a local model").
+		Description("Models installed on your Ollama server").
+		Options(opts...).
+		Value(&name).
+		WithTheme(neonTheme()).
+		Run()
+	if err != nil {
+		return nil, err
+	}
+	return withOllamaModel(provider, name), nil
+}
+
+// withOllamaModel returns a copy of provider that uses the given local model.
+func withOllamaModel(provider *providerInfo, name string) *providerInfo {
+	p := *provider
+	p.ModelName = name
+	p.Model = "ollama/" + name
+	return &p
+}
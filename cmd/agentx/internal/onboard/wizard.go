@@ -80,6 +80,13 @@ func runWizard() error {
 		}
 	}
 
+	if provider.ID == "ollama" {
+		provider, err = chooseOllamaModel(provider)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Step 3: Pick channel or skip
 	channelOpts := []huh.Option[string]{
 		huh.NewOption("Skip (set up later)", "skip"),
@@ -206,3 +206,13 @@ func TestSaveWizardConfig(t *testing.T) {
 		assert.False(t, cfg.Channels.Discord.Enabled)
 	})
 }
+
+func TestWithOllamaModel(t *testing.T) {
+	p := findProvider("ollama")
+	require.NotNil(t, p)
+
+	local := withOllamaModel(p, "qwen2.5:7b")
+	assert.Equal(t, "qwen2.5:7b", local.ModelName)
+	assert.Equal(t, "ollama/qwen2.5:7b", local.Model)
+	assert.Equal(t, "ollama/llama3", p.Model, "the provider table must not change")
+}
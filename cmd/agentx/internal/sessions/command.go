@@ -0,0 +1,39 @@
+package sessions
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/Agentx-network/agentx/cmd/agentx/internal"
+)
+
+func NewSessionsCommand() *cobra.Command {
+	var sessionsDir string
+
+	cmd := &cobra.Command{
+		Use:   "sessions",
+		Short: "Manage conversation session storage",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return cmd.Help()
+		},
+		// Resolve sessionsDir at execution time so it reflects the current config
+		// and is shared across all subcommands.
+		PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+			cfg, err := internal.LoadConfig()
+			if err != nil {
+				return fmt.Errorf("error loading config: %w", err)
+			}
+			sessionsDir = filepath.Join(cfg.WorkspacePath(), "sessions")
+			return nil
+		},
+	}
+
+	cmd.AddCommand(
+		newMigrateCommand(func() string { return sessionsDir }),
+	)
+
+	return cmd
+}
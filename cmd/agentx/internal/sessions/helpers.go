@@ -0,0 +1,48 @@
+package sessions
+
+import (
+	"fmt"
+
+	"github.com/Agentx-network/agentx/pkg/session"
+)
+
+func sessionsMigrateCmd(dir, from, to string, dryRun bool) error {
+	if from == to {
+		return fmt.Errorf("source and destination store are both %q", from)
+	}
+
+	src, err := session.NewStore(from, dir)
+	if err != nil {
+		return fmt.Errorf("opening %s store: %w", from, err)
+	}
+	defer src.Close()
+
+	if dryRun {
+		infos, err := src.List()
+		if err != nil {
+			return fmt.Errorf("listing %s sessions: %w", from, err)
+		}
+		fmt.Printf("Would migrate %d session(s) from %s to %s in %s:\n", len(infos), from, to, dir)
+		for _, info := range infos {
+			fmt.Printf("  %s (%d messages, updated %s)\n",
+				info.Key, info.MessageCount, info.Updated.Format("2006-01-02 15:04"))
+		}
+		return nil
+	}
+
+	dst, err := session.NewStore(to, dir)
+	if err != nil {
+		return fmt.Errorf("opening %s store: %w", to, err)
+	}
+	defer dst.Close()
+
+	n, err := session.Migrate(src, dst)
+	if err != nil {
+		return fmt.Errorf("migration failed after %d session(s): %w", n, err)
+	}
+
+	fmt.Printf("✓ Migrated %d session(s) from %s to %s\n", n, from, to)
+	fmt.Printf("  Set \"session\": {\"store\": %q} in config.json to use it.\n", to)
+	fmt.Printf("  The %s data in %s was left in place.\n", from, dir)
+	return nil
+}
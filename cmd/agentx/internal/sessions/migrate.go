@@ -0,0 +1,39 @@
+package sessions
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/Agentx-network/agentx/pkg/session"
+)
+
+func newMigrateCommand(sessionsDir func() string) *cobra.Command {
+	var (
+		from   string
+		to     string
+		dir    string
+		dryRun bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Copy sessions between storage backends",
+		Args:  cobra.NoArgs,
+		Example: `  agentx sessions migrate
+  agentx sessions migrate --dry-run
+  agentx sessions migrate --from sqlite --to json
+  agentx sessions migrate --dir ~/.agentx/workspace-coder/sessions`,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if dir == "" {
+				dir = sessionsDir()
+			}
+			return sessionsMigrateCmd(dir, from, to, dryRun)
+		},
+	}
+
+	cmd.Flags().StringVar(&from, "from", session.StoreJSON, "Source store (json or sqlite)")
+	cmd.Flags().StringVar(&to, "to", session.StoreSQLite, "Destination store (json or sqlite)")
+	cmd.Flags().StringVar(&dir, "dir", "", "Sessions directory (default: <workspace>/sessions)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show what would be migrated without making changes")
+
+	return cmd
+}
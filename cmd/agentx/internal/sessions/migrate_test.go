@@ -0,0 +1,43 @@
+package sessions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Agentx-network/agentx/pkg/session"
+)
+
+func TestNewMigrateSubcommand(t *testing.T) {
+	cmd := newMigrateCommand(func() string { return "" })
+
+	require.NotNil(t, cmd)
+
+	assert.Equal(t, "Copy sessions between storage backends", cmd.Short)
+
+	assert.NotNil(t, cmd.Flags().Lookup("from"))
+	assert.NotNil(t, cmd.Flags().Lookup("to"))
+	assert.NotNil(t, cmd.Flags().Lookup("dir"))
+	assert.NotNil(t, cmd.Flags().Lookup("dry-run"))
+}
+
+func TestSessionsMigrateCmd(t *testing.T) {
+	dir := t.TempDir()
+
+	sm := session.NewSessionManager(dir)
+	sm.AddMessage("telegram:1", "user", "hello")
+	require.NoError(t, sm.Save("telegram:1"))
+
+	require.Error(t, sessionsMigrateCmd(dir, "json", "json", false))
+	require.NoError(t, sessionsMigrateCmd(dir, "json", "sqlite", false))
+
+	store, err := session.NewStore("sqlite", dir)
+	require.NoError(t, err)
+	defer store.Close()
+
+	loaded, err := store.Load("telegram:1")
+	require.NoError(t, err)
+	require.NotNil(t, loaded)
+	assert.Len(t, loaded.Messages, 1)
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/Agentx-network/agentx/pkg/config"
+	"github.com/Agentx-network/agentx/pkg/providers"
 	"github.com/Agentx-network/agentx/pkg/skills"
 	"github.com/Agentx-network/agentx/pkg/skills/builtin"
 	"github.com/Agentx-network/agentx/pkg/utils"
@@ -187,6 +188,9 @@ func skillsSearchCmd(cfg *config.Config, query string) {
 		MaxConcurrentSearches: cfg.Tools.Skills.MaxConcurrentSearches,
 		ClawHub:               skills.ClawHubConfig(cfg.Tools.Skills.Registries.ClawHub),
 	})
+	if ranker := providers.NewRanker(cfg); ranker != nil {
+		registryMgr.SetRanker(ranker.Scores)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -18,7 +18,9 @@ import (
 	"github.com/Agentx-network/agentx/cmd/agentx/internal/cron"
 	"github.com/Agentx-network/agentx/cmd/agentx/internal/gateway"
 	"github.com/Agentx-network/agentx/cmd/agentx/internal/migrate"
+	"github.com/Agentx-network/agentx/cmd/agentx/internal/models"
 	"github.com/Agentx-network/agentx/cmd/agentx/internal/onboard"
+	"github.com/Agentx-network/agentx/cmd/agentx/internal/sessions"
 	"github.com/Agentx-network/agentx/cmd/agentx/internal/uninstall"
 	"github.com/Agentx-network/agentx/cmd/agentx/internal/skills"
 	"github.com/Agentx-network/agentx/cmd/agentx/internal/status"
@@ -43,6 +45,8 @@ func NewAgentxCommand() *cobra.Command {
 		status.NewStatusCommand(),
 		cron.NewCronCommand(),
 		migrate.NewMigrateCommand(),
+		models.NewModelsCommand(),
+		sessions.NewSessionsCommand(),
 		skills.NewSkillsCommand(),
 		version.NewVersionCommand(),
 		uninstall.NewUninstallCommand(),
@@ -38,7 +38,9 @@ func TestNewAgentxCommand(t *testing.T) {
 		"cron",
 		"gateway",
 		"migrate",
+		"models",
 		"onboard",
+		"sessions",
 		"skills",
 		"status",
 		"uninstall",
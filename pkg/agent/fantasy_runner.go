@@ -25,15 +25,19 @@ func (al *AgentLoop) runFantasyIteration(
 	opts processOptions,
 ) (string, int, error) {
 	model := agent.FantasyModel
+	if opts.UseImageModel && agent.ImageFantasyModel != nil {
+		model = agent.ImageFantasyModel
+	} else if opts.ModelTier == TierSimple && agent.SimpleFantasyModel != nil {
+		model = agent.SimpleFantasyModel
+	}
 	if model == nil {
 		return "", 0, fmt.Errorf("fantasy model not configured for agent %s", agent.ID)
 	}
 
-	// Extract system prompt from first message
-	systemPrompt := ""
+	// The system message is passed as a message rather than a system prompt
+	// string so its static block keeps its cache breakpoint.
 	startIdx := 0
 	if len(messages) > 0 && messages[0].Role == "system" {
-		systemPrompt = messages[0].Content
 		startIdx = 1
 	}
 
@@ -41,10 +45,12 @@ func (al *AgentLoop) runFantasyIteration(
 	// Messages = prior conversation history.
 	// Extract the last user message as the prompt, rest as history.
 	prompt := ""
+	var promptMedia []providers.MediaPart
 	var historyMessages []providers.Message
 	for i := len(messages) - 1; i >= startIdx; i-- {
 		if messages[i].Role == "user" {
 			prompt = messages[i].Content
+			promptMedia = messages[i].Media
 			historyMessages = messages[startIdx:i]
 			break
 		}
@@ -58,7 +64,11 @@ func (al *AgentLoop) runFantasyIteration(
 		}
 	}
 
-	fantasyMessages := providers.AgentXToFantasyMessages(historyMessages)
+	promptMessages := make([]providers.Message, 0, startIdx+len(historyMessages))
+	promptMessages = append(promptMessages, messages[:startIdx]...)
+	promptMessages = append(promptMessages, historyMessages...)
+	fantasyMessages := providers.AgentXToFantasyMessages(promptMessages)
+	historyEnd := len(fantasyMessages)
 
 	// Wrap tools
 	forUserSink := func(content string) {
@@ -72,13 +82,13 @@ func (al *AgentLoop) runFantasyIteration(
 	}
 
 	fantasyTools := tools.AdaptToolsForFantasy(agent.Tools, forUserSink)
+	providers.MarkToolCacheBreakpoint(fantasyTools)
 
 	// Create agent with options
 	maxTokens := int64(agent.MaxTokens)
 	temperature := agent.Temperature
 
 	fantasyAgent := fantasy.NewAgent(model,
-		fantasy.WithSystemPrompt(systemPrompt),
 		fantasy.WithTools(fantasyTools...),
 		fantasy.WithMaxOutputTokens(maxTokens),
 		fantasy.WithTemperature(temperature),
@@ -96,22 +106,78 @@ func (al *AgentLoop) runFantasyIteration(
 	var stepCount int
 	var mu sync.Mutex
 
+	// While the simple model may still be answering with the escalation
+	// marker, deltas are held back so the marker never reaches the user.
+	var held strings.Builder
+	holding := opts.HoldEscalation
+	escalated := false
+	publishDelta := func(text string) {
+		al.bus.PublishStreamDelta(bus.StreamDelta{
+			Channel: opts.Channel,
+			ChatID:  opts.ChatID,
+			Delta:   text,
+		})
+	}
+
+	// <think> blocks some models write into their text never reach the
+	// stream. With /think on, reasoning is sent to the chat as it ends.
+	var thinkTags providers.ThinkTagStream
+	publishReasoning := func(text string) {
+		if !escalated {
+			al.publishReasoning(opts, text)
+		}
+	}
+
 	// Run with streaming
 	result, err := fantasyAgent.Stream(ctx, fantasy.AgentStreamCall{
 		Prompt:   prompt,
+		Files:    providers.MediaToFantasyFiles(promptMedia),
 		Messages: fantasyMessages,
 
+		ProviderOptions: providers.PromptCacheOptions(opts.SessionKey),
+		PrepareStep: func(ctx context.Context, step fantasy.PrepareStepFunctionOptions) (context.Context, fantasy.PrepareStepResult, error) {
+			return ctx, fantasy.PrepareStepResult{
+				Messages: providers.MarkCacheBreakpoints(step.Messages, historyEnd),
+			}, nil
+		},
+
 		OnTextDelta: func(id, text string) error {
 			mu.Lock()
+			defer mu.Unlock()
 			textBuf.WriteString(text)
-			mu.Unlock()
+
+			if escalated || opts.NoStream {
+				return nil
+			}
+			text, tagged := thinkTags.Write(text)
+			publishReasoning(tagged)
+			if text == "" {
+				return nil
+			}
+			if holding {
+				held.WriteString(text)
+				trimmed := strings.TrimSpace(held.String())
+				switch {
+				case strings.HasPrefix(trimmed, escalationMarker):
+					escalated = true
+					return nil
+				case strings.HasPrefix(escalationMarker, trimmed):
+					return nil
+				}
+				holding = false
+				text = held.String()
+				held.Reset()
+			}
 
 			// Publish stream delta
-			al.bus.PublishStreamDelta(bus.StreamDelta{
-				Channel: opts.Channel,
-				ChatID:  opts.ChatID,
-				Delta:   text,
-			})
+			publishDelta(text)
+			return nil
+		},
+
+		OnReasoningEnd: func(id string, reasoning fantasy.ReasoningContent) error {
+			mu.Lock()
+			defer mu.Unlock()
+			publishReasoning(reasoning.Text)
 			return nil
 		},
 
@@ -122,6 +188,7 @@ func (al *AgentLoop) runFantasyIteration(
 					"tool":         tc.ToolName,
 					"tool_call_id": tc.ToolCallID,
 				})
+			al.publishStatus(opts, toolStatus(tc.ToolName), false)
 			return nil
 		},
 
@@ -147,15 +214,32 @@ func (al *AgentLoop) runFantasyIteration(
 				agent.Sessions.AddFullMessage(opts.SessionKey, msg)
 			}
 
-			logger.DebugCF("agent", "Fantasy step finished",
-				map[string]any{
-					"agent_id": agent.ID,
-					"step":     currentStep,
-				})
+			fields := providers.UsageFields(step.Usage)
+			fields["agent_id"] = agent.ID
+			fields["step"] = currentStep
+			logger.DebugCF("agent", "Fantasy step finished", fields)
 			return nil
 		},
 
 		OnTextEnd: func(id string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if escalated || opts.NoStream {
+				return nil
+			}
+			rest, tagged := thinkTags.Flush()
+			publishReasoning(tagged)
+			if holding {
+				held.WriteString(rest)
+			} else if rest != "" {
+				publishDelta(rest)
+			}
+			if holding && held.Len() > 0 {
+				holding = false
+				publishDelta(held.String())
+				held.Reset()
+			}
+
 			// Signal stream done
 			al.bus.PublishStreamDelta(bus.StreamDelta{
 				Channel: opts.Channel,
@@ -179,6 +263,16 @@ func (al *AgentLoop) runFantasyIteration(
 			})
 		}
 
+		// Shrink images the provider rejected and retry once
+		if providers.IsImageError(err) && !opts.ImagesResized && providers.HasImages(messages) {
+			logger.WarnCF("agent", "Image rejected by provider, resizing and retrying",
+				map[string]any{"error": err.Error()})
+			resizedMessages, resized := providers.ShrinkImages(messages, providers.ResizedImageMaxDim, utils.MediaDir())
+			defer utils.ReleaseMedia(resized)
+			opts.ImagesResized = true
+			return al.runFantasyIteration(ctx, agent, resizedMessages, opts)
+		}
+
 		// Check for context/token errors and attempt compression
 		errMsg := strings.ToLower(err.Error())
 		isContextError := strings.Contains(errMsg, "token") ||
@@ -196,6 +290,9 @@ func (al *AgentLoop) runFantasyIteration(
 				newHistory, newSummary, "",
 				nil, opts.Channel, opts.ChatID,
 			)
+			if opts.HoldEscalation {
+				newMessages = withEscalationHint(newMessages)
+			}
 
 			// Retry once after compression
 			return al.runFantasyIteration(ctx, agent, newMessages, opts)
@@ -208,6 +305,11 @@ func (al *AgentLoop) runFantasyIteration(
 	finalContent := ""
 	if result != nil {
 		finalContent = result.Response.Content.Text()
+
+		fields := providers.UsageFields(result.TotalUsage)
+		fields["agent_id"] = agent.ID
+		fields["model"] = model.Model()
+		logger.InfoCF("agent", "LLM usage", fields)
 	}
 
 	// If streaming buffer has content but result doesn't, use buffer
@@ -216,6 +318,7 @@ func (al *AgentLoop) runFantasyIteration(
 		finalContent = textBuf.String()
 	}
 	mu.Unlock()
+	_, finalContent = providers.SplitThinkTags(finalContent)
 
 	if finalContent != "" {
 		logger.InfoCF("agent", fmt.Sprintf("Response: %s", utils.Truncate(finalContent, 120)),
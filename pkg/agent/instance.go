@@ -8,6 +8,7 @@ import (
 	"charm.land/fantasy"
 
 	"github.com/Agentx-network/agentx/pkg/config"
+	"github.com/Agentx-network/agentx/pkg/logger"
 	"github.com/Agentx-network/agentx/pkg/providers"
 	"github.com/Agentx-network/agentx/pkg/routing"
 	"github.com/Agentx-network/agentx/pkg/session"
@@ -26,14 +27,31 @@ type AgentInstance struct {
 	MaxTokens      int
 	Temperature    float64
 	ContextWindow  int
-	Provider       providers.LLMProvider   // Legacy: kept for backward compat during migration
-	FantasyModel   fantasy.LanguageModel   // Fantasy SDK model
+	Provider       providers.LLMProvider // Legacy: kept for backward compat during migration
+	FantasyModel   fantasy.LanguageModel // Fantasy SDK model
 	Sessions       *session.SessionManager
 	ContextBuilder *ContextBuilder
 	Tools          *tools.ToolRegistry
 	Subagents      *config.SubagentsConfig
 	SkillsFilter   []string
 	Candidates     []providers.FallbackCandidate
+
+	// Vision reports whether Model accepts image input. When it does not,
+	// messages with images go to ImageModel instead. Audio and PDF report
+	// whether it takes voice notes and PDF documents as attachments.
+	Vision            bool
+	Audio             bool
+	PDF               bool
+	ImageModel        string
+	ImageCandidates   []providers.FallbackCandidate
+	ImageFantasyModel fantasy.LanguageModel
+
+	// Routing sends simple turns to SimpleModel instead of Model.
+	Routing                config.ModelRoutingConfig
+	SimpleModel            string
+	SimpleCandidates       []providers.FallbackCandidate
+	SimpleFantasyModel     fantasy.LanguageModel
+	ClassifierFantasyModel fantasy.LanguageModel
 }
 
 // NewAgentInstance creates an agent instance from config.
@@ -58,8 +76,7 @@ func NewAgentInstance(
 	toolsRegistry.Register(tools.NewEditFileTool(workspace, restrict))
 	toolsRegistry.Register(tools.NewAppendFileTool(workspace, restrict))
 
-	sessionsDir := filepath.Join(workspace, "sessions")
-	sessionsManager := session.NewSessionManager(sessionsDir)
+	sessionsManager := newSessionManager(cfg, workspace)
 
 	contextBuilder := NewContextBuilder(workspace)
 
@@ -97,6 +114,25 @@ func NewAgentInstance(
 	}
 	candidates := providers.ResolveCandidates(modelCfg, defaults.Provider)
 
+	var imageCandidates []providers.FallbackCandidate
+	if defaults.ImageModel != "" {
+		imageCandidates = providers.ResolveCandidates(providers.ModelConfig{
+			Primary:   defaults.ImageModel,
+			Fallbacks: defaults.ImageModelFallbacks,
+		}, defaults.Provider)
+	}
+
+	var simpleCandidates []providers.FallbackCandidate
+	routingCfg := defaults.ModelRouting
+	if routingCfg.Enabled && routingCfg.SimpleModel != "" {
+		simpleCandidates = providers.ResolveCandidates(providers.ModelConfig{
+			Primary:   routingCfg.SimpleModel,
+			Fallbacks: routingCfg.SimpleModelFallbacks,
+		}, defaults.Provider)
+	}
+
+	vision, audio, pdf := modelCapabilities(cfg, model)
+
 	return &AgentInstance{
 		ID:             agentID,
 		Name:           agentName,
@@ -114,7 +150,67 @@ func NewAgentInstance(
 		Subagents:      subagents,
 		SkillsFilter:   skillsFilter,
 		Candidates:     candidates,
+
+		Vision:          vision,
+		Audio:           audio,
+		PDF:             pdf,
+		ImageModel:      defaults.ImageModel,
+		ImageCandidates: imageCandidates,
+
+		Routing:          routingCfg,
+		SimpleModel:      routingCfg.SimpleModel,
+		SimpleCandidates: simpleCandidates,
+	}
+}
+
+// modelCapabilities resolves which attachments the named model accepts,
+// preferring its model_list settings over the guesses from the model ID.
+func modelCapabilities(cfg *config.Config, model string) (vision, audio, pdf bool) {
+	var mc *config.ModelConfig
+	if cfg != nil {
+		for i := range cfg.ModelList {
+			if cfg.ModelList[i].ModelName == model {
+				mc = &cfg.ModelList[i]
+				model = mc.Model
+				break
+			}
+		}
+	}
+	vision = providers.ModelSupportsVision(model)
+	audio = providers.ModelSupportsAudio(model)
+	pdf = providers.ModelSupportsPDF(model)
+	if mc != nil {
+		if mc.Vision != nil {
+			vision = *mc.Vision
+		}
+		if mc.Audio != nil {
+			audio = *mc.Audio
+		}
+		if mc.PDF != nil {
+			pdf = *mc.PDF
+		}
+	}
+	return vision, audio, pdf
+}
+
+// newSessionManager creates the session manager for a workspace using the
+// configured session store, falling back to JSON files if it cannot be opened.
+func newSessionManager(cfg *config.Config, workspace string) *session.SessionManager {
+	sessionsDir := filepath.Join(workspace, "sessions")
+	if cfg == nil {
+		return session.NewSessionManager(sessionsDir)
+	}
+
+	store, err := session.NewStore(cfg.Session.Store, sessionsDir)
+	if err != nil {
+		logger.WarnCF("agent", "Failed to open session store, using JSON files",
+			map[string]any{
+				"store": cfg.Session.Store,
+				"error": err.Error(),
+			})
+		return session.NewSessionManager(sessionsDir)
 	}
+	return session.NewSessionManagerWithStore(store)
 }
 
 // resolveAgentWorkspace determines the workspace directory for an agent.
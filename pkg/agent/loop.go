@@ -23,10 +23,12 @@ import (
 	"github.com/Agentx-network/agentx/pkg/logger"
 	"github.com/Agentx-network/agentx/pkg/providers"
 	"github.com/Agentx-network/agentx/pkg/routing"
+	"github.com/Agentx-network/agentx/pkg/session"
 	"github.com/Agentx-network/agentx/pkg/skills"
 	"github.com/Agentx-network/agentx/pkg/state"
 	"github.com/Agentx-network/agentx/pkg/tools"
 	"github.com/Agentx-network/agentx/pkg/utils"
+	"github.com/Agentx-network/agentx/pkg/voice"
 )
 
 type AgentLoop struct {
@@ -38,6 +40,10 @@ type AgentLoop struct {
 	summarizing    sync.Map
 	fallback       *providers.FallbackChain
 	channelManager *channels.Manager
+	resetPolicy    session.ResetPolicy
+	tierOverrides  sync.Map // session key -> tierOverride
+	thinkSessions  sync.Map // session key -> struct{} while /think is on
+	voiceSessions  sync.Map // session key -> struct{} while /voice is on
 }
 
 // processOptions configures how a message is processed
@@ -50,6 +56,17 @@ type processOptions struct {
 	EnableSummary   bool   // Whether to trigger summarization
 	SendResponse    bool   // Whether to send response via bus
 	NoHistory       bool   // If true, don't load session history (for heartbeat)
+
+	Media         []providers.MediaPart // Attachments sent with UserMessage
+	UseImageModel bool                  // Send the request to the agent's image model
+	ImagesResized bool                  // Attachments were already shrunk after an image error
+
+	ModelTier      string // TierSimple routes the turn to the agent's simple model
+	HoldEscalation bool   // Don't stream replies that may be an escalation marker
+
+	NoStream      bool // Don't stream the reply, e.g. when it is a structured result
+	ShowReasoning bool // Send the model's reasoning to the chat (/think on)
+	ShowStatus    bool // Show the chat that the agent is working, and on what
 }
 
 const defaultResponse = "I've completed processing but have no response to give. Increase `max_tool_iterations` in config.json."
@@ -78,6 +95,7 @@ func NewAgentLoop(cfg *config.Config, msgBus *bus.MessageBus, provider providers
 		state:       stateManager,
 		summarizing: sync.Map{},
 		fallback:    fallbackChain,
+		resetPolicy: newResetPolicy(cfg.Session),
 	}
 }
 
@@ -88,6 +106,7 @@ func registerSharedTools(
 	registry *AgentRegistry,
 	provider providers.LLMProvider,
 ) {
+	ranker := providers.NewRanker(cfg)
 	for _, agentID := range registry.ListAgentIDs() {
 		agent, ok := registry.GetAgent(agentID)
 		if !ok {
@@ -120,21 +139,28 @@ func registerSharedTools(
 
 		// Message tool
 		messageTool := tools.NewMessageTool()
-		messageTool.SetSendCallback(func(channel, chatID, content string) error {
-			msgBus.PublishOutbound(bus.OutboundMessage{
-				Channel: channel,
-				ChatID:  chatID,
-				Content: content,
-			})
+		messageTool.SetWorkspace(agent.Workspace, cfg.Agents.Defaults.RestrictToWorkspace)
+		messageTool.SetOutboundCallback(func(msg bus.OutboundMessage) error {
+			msgBus.PublishOutbound(msg)
 			return nil
 		})
 		agent.Tools.Register(messageTool)
 
+		// Recall of past conversations
+		sessionSearch := tools.NewSessionSearchTool(agent.Sessions)
+		if ranker != nil {
+			sessionSearch.SetRanker(ranker.Scores)
+		}
+		agent.Tools.Register(sessionSearch)
+
 		// Skill discovery and installation tools
 		registryMgr := skills.NewRegistryManagerFromConfig(skills.RegistryConfig{
 			MaxConcurrentSearches: cfg.Tools.Skills.MaxConcurrentSearches,
 			ClawHub:               skills.ClawHubConfig(cfg.Tools.Skills.Registries.ClawHub),
 		})
+		if ranker != nil {
+			registryMgr.SetRanker(ranker.Scores)
+		}
 		searchCache := skills.NewSearchCache(
 			cfg.Tools.Skills.SearchCache.MaxSize,
 			time.Duration(cfg.Tools.Skills.SearchCache.TTLSeconds)*time.Second,
@@ -160,6 +186,8 @@ func registerSharedTools(
 func (al *AgentLoop) Run(ctx context.Context) error {
 	al.running.Store(true)
 
+	go al.runRetention(ctx)
+
 	for al.running.Load() {
 		select {
 		case <-ctx.Done():
@@ -190,11 +218,7 @@ func (al *AgentLoop) Run(ctx context.Context) error {
 				}
 
 				if !alreadySent {
-					al.bus.PublishOutbound(bus.OutboundMessage{
-						Channel: msg.Channel,
-						ChatID:  msg.ChatID,
-						Content: response,
-					})
+					al.bus.PublishOutbound(al.replyTo(msg, response))
 				}
 			}
 		}
@@ -207,6 +231,12 @@ func (al *AgentLoop) Stop() {
 	al.running.Store(false)
 }
 
+// Close releases resources held by the agents, such as session stores.
+// It must be called after Stop, once no more messages are being processed.
+func (al *AgentLoop) Close() {
+	al.registry.Close()
+}
+
 func (al *AgentLoop) RegisterTool(tool tools.Tool) {
 	for _, agentID := range al.registry.ListAgentIDs() {
 		if agent, ok := al.registry.GetAgent(agentID); ok {
@@ -288,6 +318,9 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 			"session_key": msg.SessionKey,
 		})
 
+	// Media files are retained for this message only
+	defer utils.ReleaseMedia(msg.Media)
+
 	// Route system messages to processSystemMessage
 	if msg.Channel == "system" {
 		return al.processSystemMessage(ctx, msg)
@@ -299,8 +332,38 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 	}
 
 	// Route to determine agent and session key
+	agent, sessionKey, route := al.resolveMessageRoute(msg)
+
+	logger.InfoCF("agent", "Routed message",
+		map[string]any{
+			"agent_id":    agent.ID,
+			"session_key": sessionKey,
+			"matched_by":  route.MatchedBy,
+		})
+
+	media, useImageModel := selectMedia(agent, msg.Media)
+	tier := al.selectModelTier(ctx, agent, sessionKey, msg.Content, useImageModel)
+
+	return al.runAgentLoop(ctx, agent, processOptions{
+		SessionKey:      sessionKey,
+		Channel:         msg.Channel,
+		ChatID:          msg.ChatID,
+		UserMessage:     msg.Content,
+		DefaultResponse: defaultResponse,
+		EnableSummary:   true,
+		SendResponse:    false,
+		Media:           media,
+		UseImageModel:   useImageModel,
+		ModelTier:       tier,
+		ShowReasoning:   al.showsReasoning(sessionKey),
+		ShowStatus:      true,
+	})
+}
+
+// resolveMessageRoute determines the agent and session key for an inbound message.
+func (al *AgentLoop) resolveMessageRoute(msg bus.InboundMessage) (*AgentInstance, string, routing.ResolvedRoute) {
 	route := al.registry.ResolveRoute(routing.RouteInput{
-		Channel:    msg.Channel,
+		Channel:    channels.ChannelType(msg.Channel),
 		AccountID:  msg.Metadata["account_id"],
 		Peer:       extractPeer(msg),
 		ParentPeer: extractParentPeer(msg),
@@ -318,23 +381,7 @@ func (al *AgentLoop) processMessage(ctx context.Context, msg bus.InboundMessage)
 	if msg.SessionKey != "" && strings.HasPrefix(msg.SessionKey, "agent:") {
 		sessionKey = msg.SessionKey
 	}
-
-	logger.InfoCF("agent", "Routed message",
-		map[string]any{
-			"agent_id":    agent.ID,
-			"session_key": sessionKey,
-			"matched_by":  route.MatchedBy,
-		})
-
-	return al.runAgentLoop(ctx, agent, processOptions{
-		SessionKey:      sessionKey,
-		Channel:         msg.Channel,
-		ChatID:          msg.ChatID,
-		UserMessage:     msg.Content,
-		DefaultResponse: defaultResponse,
-		EnableSummary:   true,
-		SendResponse:    false,
-	})
+	return agent, sessionKey, route
 }
 
 func (al *AgentLoop) processSystemMessage(ctx context.Context, msg bus.InboundMessage) (string, error) {
@@ -358,11 +405,14 @@ func (al *AgentLoop) processSystemMessage(ctx context.Context, msg bus.InboundMe
 		originChatID = msg.ChatID
 	}
 
-	// Extract subagent result from message content
-	// Format: "Task 'label' completed.\n\nResult:\n<actual content>"
-	content := msg.Content
-	if idx := strings.Index(content, "Result:\n"); idx >= 0 {
-		content = content[idx+8:] // Extract just the result part
+	// Subagents attach their result as metadata; older announcements only
+	// have it in the content: "Task 'label' completed.\n\nResult:\n<actual content>"
+	content, ok := msg.Metadata["subagent_result"]
+	if !ok {
+		content = msg.Content
+		if idx := strings.Index(content, "Result:\n"); idx >= 0 {
+			content = content[idx+8:] // Extract just the result part
+		}
 	}
 
 	// Skip internal channels - only log, don't send to user
@@ -390,6 +440,7 @@ func (al *AgentLoop) processSystemMessage(ctx context.Context, msg bus.InboundMe
 		DefaultResponse: "Background task completed.",
 		EnableSummary:   false,
 		SendResponse:    true,
+		ShowStatus:      true,
 	})
 }
 
@@ -406,13 +457,18 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, agent *AgentInstance, opt
 		}
 	}
 
+	// Show the chat the agent is working until the turn is over
+	al.publishStatus(opts, "", false)
+	defer al.publishStatus(opts, "", true)
+
 	// 1. Update tool contexts
-	al.updateToolContexts(agent, opts.Channel, opts.ChatID)
+	al.updateToolContexts(agent, opts.Channel, opts.ChatID, opts.SessionKey)
 
 	// 2. Build messages (skip history for heartbeat)
 	var history []providers.Message
 	var summary string
 	if !opts.NoHistory {
+		al.maybeResetSession(agent, opts.SessionKey)
 		history = agent.Sessions.GetHistory(opts.SessionKey)
 		summary = agent.Sessions.GetSummary(opts.SessionKey)
 	}
@@ -420,23 +476,17 @@ func (al *AgentLoop) runAgentLoop(ctx context.Context, agent *AgentInstance, opt
 		history,
 		summary,
 		opts.UserMessage,
-		nil,
+		opts.Media,
 		opts.Channel,
 		opts.ChatID,
 	)
 
-	// 3. Save user message to session
+	// 3. Save user message to session. Attachments are temporary files, so
+	// only the text (which names them) is kept in history.
 	agent.Sessions.AddMessage(opts.SessionKey, "user", opts.UserMessage)
 
 	// 4. Run LLM iteration loop
-	var finalContent string
-	var iteration int
-	var err error
-	if agent.FantasyModel != nil {
-		finalContent, iteration, err = al.runFantasyIteration(ctx, agent, messages, opts)
-	} else {
-		finalContent, iteration, err = al.runLLMIteration(ctx, agent, messages, opts)
-	}
+	finalContent, iteration, err := al.runTurn(ctx, agent, messages, opts)
 	if err != nil {
 		return "", err
 	}
@@ -534,6 +584,23 @@ func (al *AgentLoop) runLLMIteration(
 		var err error
 
 		callLLM := func() (*providers.LLMResponse, error) {
+			if opts.UseImageModel && len(agent.ImageCandidates) > 0 && al.fallback != nil {
+				fbResult, fbErr := al.fallback.ExecuteImage(ctx, agent.ImageCandidates,
+					func(ctx context.Context, provider, model string) (*providers.LLMResponse, error) {
+						return agent.Provider.Chat(ctx, messages, providerToolDefs, model, map[string]any{
+							"max_tokens":  agent.MaxTokens,
+							"temperature": agent.Temperature,
+						})
+					},
+				)
+				if fbErr != nil {
+					return nil, fbErr
+				}
+				return fbResult.Response, nil
+			}
+			if opts.ModelTier == TierSimple {
+				return al.callSimpleModel(ctx, agent, messages, providerToolDefs)
+			}
 			if len(agent.Candidates) > 1 && al.fallback != nil {
 				fbResult, fbErr := al.fallback.Execute(ctx, agent.Candidates,
 					func(ctx context.Context, provider, model string) (*providers.LLMResponse, error) {
@@ -569,6 +636,17 @@ func (al *AgentLoop) runLLMIteration(
 				break
 			}
 
+			if providers.IsImageError(err) && !opts.ImagesResized && providers.HasImages(messages) {
+				logger.WarnCF("agent", "Image rejected by provider, resizing and retrying",
+					map[string]any{"error": err.Error()})
+				var resized []string
+				messages, resized = providers.ShrinkImages(messages, providers.ResizedImageMaxDim, utils.MediaDir())
+				defer utils.ReleaseMedia(resized)
+				opts.ImagesResized = true
+				retry--
+				continue
+			}
+
 			errMsg := strings.ToLower(err.Error())
 			isContextError := strings.Contains(errMsg, "token") ||
 				strings.Contains(errMsg, "context") ||
@@ -596,6 +674,9 @@ func (al *AgentLoop) runLLMIteration(
 					newHistory, newSummary, "",
 					nil, opts.Channel, opts.ChatID,
 				)
+				if opts.HoldEscalation {
+					messages = withEscalationHint(messages)
+				}
 				continue
 			}
 			break
@@ -611,6 +692,12 @@ func (al *AgentLoop) runLLMIteration(
 			return "", iteration, fmt.Errorf("LLM call failed after retries: %w", err)
 		}
 
+		if tagged, content := providers.SplitThinkTags(response.Content); tagged != "" {
+			response.Content = content
+			response.ReasoningContent = strings.TrimSpace(response.ReasoningContent + "\n\n" + tagged)
+		}
+		al.publishReasoning(opts, response.ReasoningContent)
+
 		// Check if no tool calls - we're done
 		if len(response.ToolCalls) == 0 {
 			finalContent = response.Content
@@ -684,6 +771,7 @@ func (al *AgentLoop) runLLMIteration(
 					"tool":      tc.Name,
 					"iteration": iteration,
 				})
+			al.publishStatus(opts, toolStatus(tc.Name), false)
 
 			// Create async callback for tools that implement AsyncTool
 			// NOTE: Following openclaw's design, async tools do NOT send results directly to users.
@@ -746,7 +834,7 @@ func (al *AgentLoop) runLLMIteration(
 }
 
 // updateToolContexts updates the context for tools that need channel/chatID info.
-func (al *AgentLoop) updateToolContexts(agent *AgentInstance, channel, chatID string) {
+func (al *AgentLoop) updateToolContexts(agent *AgentInstance, channel, chatID, sessionKey string) {
 	// Use ContextualTool interface instead of type assertions
 	if tool, ok := agent.Tools.Get("message"); ok {
 		if mt, ok := tool.(tools.ContextualTool); ok {
@@ -763,6 +851,11 @@ func (al *AgentLoop) updateToolContexts(agent *AgentInstance, channel, chatID st
 			st.SetContext(channel, chatID)
 		}
 	}
+	if tool, ok := agent.Tools.Get("session_search"); ok {
+		if st, ok := tool.(tools.SessionScopedTool); ok {
+			st.SetSession(channel, sessionKey)
+		}
+	}
 }
 
 // maybeSummarize triggers summarization if the session history exceeds thresholds.
@@ -825,7 +918,8 @@ func (al *AgentLoop) forceCompression(agent *AgentInstance, sessionKey string) {
 	newHistory = append(newHistory, keptConversation...)
 	newHistory = append(newHistory, history[len(history)-1]) // Last message
 
-	// Update session
+	// Update session, keeping the dropped messages searchable
+	agent.Sessions.Archive(sessionKey, conversation[:mid])
 	agent.Sessions.SetHistory(sessionKey, newHistory)
 	if err := agent.Sessions.Save(sessionKey); err != nil {
 		logger.ErrorCF("agent", "Failed to save session after compression", map[string]any{
@@ -1116,7 +1210,7 @@ func (al *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage)
 
 	case "/switch":
 		if len(args) < 3 || args[1] != "to" {
-			return "Usage: /switch [model|channel] to <name>", true
+			return "Usage: /switch [model|channel|tier] to <name>", true
 		}
 		target := args[0]
 		value := args[2]
@@ -1138,14 +1232,175 @@ func (al *AgentLoop) handleCommand(ctx context.Context, msg bus.InboundMessage)
 				return fmt.Sprintf("Channel '%s' not found or not enabled", value), true
 			}
 			return fmt.Sprintf("Switched target channel to %s", value), true
+		case "tier":
+			agent, sessionKey, _ := al.resolveMessageRoute(msg)
+			switch value {
+			case TierSimple, TierComplex:
+				if value == TierSimple && (!agent.Routing.Enabled || agent.SimpleModel == "") {
+					return "Model routing is not enabled for this agent", true
+				}
+				al.setTierOverride(sessionKey, value)
+				return fmt.Sprintf("This conversation now uses the %s model tier", value), true
+			case "auto":
+				al.tierOverrides.Delete(sessionKey)
+				return "This conversation now picks the model tier automatically", true
+			default:
+				return "Usage: /switch tier to [simple|complex|auto]", true
+			}
 		default:
 			return fmt.Sprintf("Unknown switch target: %s", target), true
 		}
+
+	case "/think":
+		agent, sessionKey, _ := al.resolveMessageRoute(msg)
+		if agent == nil {
+			return "No default agent configured", true
+		}
+		on := !al.showsReasoning(sessionKey)
+		if len(args) > 0 {
+			switch args[0] {
+			case "on":
+				on = true
+			case "off":
+				on = false
+			default:
+				return "Usage: /think [on|off]", true
+			}
+		}
+		if on {
+			al.thinkSessions.Store(sessionKey, struct{}{})
+			return "Reasoning will be shown in this chat when the model thinks", true
+		}
+		al.thinkSessions.Delete(sessionKey)
+		return "Reasoning is hidden in this chat", true
+
+	case "/voice":
+		_, sessionKey, _ := al.resolveMessageRoute(msg)
+		on := !al.speaksReplies(sessionKey)
+		if len(args) > 0 {
+			switch args[0] {
+			case "on":
+				on = true
+			case "off":
+				on = false
+			default:
+				return "Usage: /voice [on|off]", true
+			}
+		}
+		if !on {
+			al.voiceSessions.Delete(sessionKey)
+			return "Replies in this chat are text again", true
+		}
+		if synthesizer, err := voice.NewSynthesizer(al.cfg); err != nil {
+			return fmt.Sprintf("Text-to-speech is misconfigured: %v", err), true
+		} else if synthesizer == nil {
+			return "Text-to-speech is not configured; set voice.tts in config.json", true
+		}
+		al.voiceSessions.Store(sessionKey, struct{}{})
+		return "Replies in this chat will be sent as voice notes where the channel supports them", true
+
+	case "/search":
+		query := strings.TrimSpace(strings.TrimPrefix(content, cmd))
+		if query == "" {
+			return "Usage: /search <query>", true
+		}
+		agent, sessionKey, _ := al.resolveMessageRoute(msg)
+		if agent == nil {
+			return "No default agent configured", true
+		}
+		scope := session.SearchScope{SessionKey: sessionKey}
+		if constants.IsInternalChannel(msg.Channel) {
+			scope = session.SearchScope{All: true}
+		}
+		results, err := agent.Sessions.Search(query, 10, scope)
+		if err != nil {
+			return fmt.Sprintf("Search failed: %v", err), true
+		}
+		return session.FormatSearchResults(query, results), true
 	}
 
 	return "", false
 }
 
+// showsReasoning reports whether /think is on for a session.
+func (al *AgentLoop) showsReasoning(sessionKey string) bool {
+	_, ok := al.thinkSessions.Load(sessionKey)
+	return ok
+}
+
+// speaksReplies reports whether /voice is on for a session.
+func (al *AgentLoop) speaksReplies(sessionKey string) bool {
+	_, ok := al.voiceSessions.Load(sessionKey)
+	return ok
+}
+
+// replyTo builds the outbound reply to msg, spoken when /voice is on.
+func (al *AgentLoop) replyTo(msg bus.InboundMessage, content string) bus.OutboundMessage {
+	_, sessionKey, _ := al.resolveMessageRoute(msg)
+	return bus.OutboundMessage{
+		Channel: msg.Channel,
+		ChatID:  msg.ChatID,
+		Content: content,
+		Speak:   al.speaksReplies(sessionKey),
+	}
+}
+
+// publishReasoning sends a reasoning trace to the chat when /think is on.
+func (al *AgentLoop) publishReasoning(opts processOptions, reasoning string) {
+	reasoning = strings.TrimSpace(reasoning)
+	if !opts.ShowReasoning || opts.NoStream || reasoning == "" {
+		return
+	}
+	al.bus.PublishOutbound(bus.OutboundMessage{
+		Channel:   opts.Channel,
+		ChatID:    opts.ChatID,
+		Reasoning: reasoning,
+	})
+}
+
+// toolStatuses describe what the agent is doing while a tool runs.
+var toolStatuses = map[string]string{
+	"web_search":     "🔍 Searching the web",
+	"web_fetch":      "🌐 Reading a web page",
+	"exec":           "⚙️ Running a command",
+	"read_file":      "📄 Reading files",
+	"list_dir":       "📄 Reading files",
+	"write_file":     "✏️ Editing files",
+	"edit_file":      "✏️ Editing files",
+	"append_file":    "✏️ Editing files",
+	"session_search": "🗂️ Searching past conversations",
+	"find_skills":    "🧩 Looking for skills",
+	"install_skill":  "🧩 Installing a skill",
+	"spawn":          "🤖 Delegating to a subagent",
+	"subagent":       "🤖 Delegating to a subagent",
+	"cron":           "⏰ Scheduling",
+	"i2c":            "🔌 Talking to hardware",
+	"spi":            "🔌 Talking to hardware",
+	"message":        "", // the reply itself
+}
+
+// toolStatus returns the status shown while a tool runs.
+func toolStatus(name string) string {
+	if status, ok := toolStatuses[name]; ok {
+		return status
+	}
+	return "🛠️ Using " + name
+}
+
+// publishStatus tells the chat what the agent is doing, when the turn
+// shows its status. An empty status only shows that the agent is busy.
+func (al *AgentLoop) publishStatus(opts processOptions, status string, done bool) {
+	if !opts.ShowStatus || opts.Channel == "" || opts.ChatID == "" {
+		return
+	}
+	al.bus.PublishStatus(bus.StatusUpdate{
+		Channel: opts.Channel,
+		ChatID:  opts.ChatID,
+		Status:  status,
+		Done:    done,
+	})
+}
+
 // extractPeer extracts the routing peer from inbound message metadata.
 func extractPeer(msg bus.InboundMessage) *routing.RoutePeer {
 	peerKind := msg.Metadata["peer_kind"]
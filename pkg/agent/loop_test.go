@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -343,6 +344,53 @@ func TestAgentLoop_Stop(t *testing.T) {
 	}
 }
 
+func TestHandleCommand_Search(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	cfg.Session.DMScope = "per-channel-peer"
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &mockProvider{})
+	defer al.Close()
+
+	agent := al.registry.GetDefaultAgent()
+	agent.Sessions.AddMessage("agent:main:telegram:direct:42", "user", "where is the backup script?")
+	agent.Sessions.AddMessage("agent:main:telegram:direct:7", "user", "backup password is hunter2")
+	for _, key := range []string{"agent:main:telegram:direct:42", "agent:main:telegram:direct:7"} {
+		if err := agent.Sessions.Save(key); err != nil {
+			t.Fatalf("Save(%q) failed: %v", key, err)
+		}
+	}
+
+	msg := bus.InboundMessage{
+		Channel:  "telegram",
+		SenderID: "42",
+		ChatID:   "42",
+		Content:  "/search backup",
+		Metadata: map[string]string{"peer_kind": "direct"},
+	}
+	response, handled := al.handleCommand(context.Background(), msg)
+	if !handled {
+		t.Fatal("expected /search to be handled")
+	}
+	if !strings.Contains(response, "backup script") {
+		t.Errorf("expected own session in results, got %q", response)
+	}
+	if strings.Contains(response, "hunter2") {
+		t.Errorf("results leaked another user's session: %q", response)
+	}
+
+	if response, _ := al.handleCommand(context.Background(), bus.InboundMessage{Content: "/search"}); !strings.Contains(response, "Usage") {
+		t.Errorf("expected usage for empty query, got %q", response)
+	}
+}
+
 // Mock implementations for testing
 
 type simpleMockProvider struct {
@@ -631,3 +679,159 @@ func TestAgentLoop_ContextExhaustionRetry(t *testing.T) {
 		t.Errorf("Expected history to be compressed (len < 8), got %d", len(finalHistory))
 	}
 }
+
+func TestThinkCommand_ShowsReasoning(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	msgBus := bus.NewMessageBus()
+	provider := &simpleMockProvider{response: "<think>The user said hi.</think>\n\nHello!"}
+	al := NewAgentLoop(cfg, msgBus, provider)
+	defer al.Close()
+
+	if got := sendTestMessage(t, al, "hi"); got != "Hello!" {
+		t.Errorf("think tags should be removed from the reply, got %q", got)
+	}
+
+	if got := sendTestMessage(t, al, "/think on"); !strings.Contains(got, "shown") {
+		t.Errorf("unexpected command response %q", got)
+	}
+	if got := sendTestMessage(t, al, "hi"); got != "Hello!" {
+		t.Errorf("got %q", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, ok := msgBus.SubscribeOutbound(ctx)
+	if !ok {
+		t.Fatal("expected the reasoning to be sent to the chat")
+	}
+	if msg.Reasoning != "The user said hi." || msg.Content != "" {
+		t.Errorf("unexpected outbound message %+v", msg)
+	}
+}
+
+func TestVoiceCommand_SpeaksReplies(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &simpleMockProvider{response: "Hello!"})
+	defer al.Close()
+	inbound := bus.InboundMessage{Channel: "telegram", SenderID: "42", ChatID: "42"}
+
+	if got := sendTestMessage(t, al, "/voice on"); !strings.Contains(got, "not configured") {
+		t.Errorf("without voice.tts /voice should explain, got %q", got)
+	}
+	if al.replyTo(inbound, "Hello!").Speak {
+		t.Error("replies should stay text")
+	}
+
+	cfg.Voice.TTS = config.TTSConfig{Provider: "openai", APIBase: "http://localhost:8880/v1"}
+	if got := sendTestMessage(t, al, "/voice on"); !strings.Contains(got, "voice notes") {
+		t.Errorf("unexpected command response %q", got)
+	}
+	if reply := al.replyTo(inbound, "Hello!"); !reply.Speak || reply.Content != "Hello!" {
+		t.Errorf("reply should be spoken, got %+v", reply)
+	}
+
+	sendTestMessage(t, al, "/voice off")
+	if al.replyTo(inbound, "Hello!").Speak {
+		t.Error("/voice off should turn speech off")
+	}
+}
+
+// toolCallMockProvider calls a tool once, then replies.
+type toolCallMockProvider struct {
+	tool  string
+	calls int
+}
+
+func (m *toolCallMockProvider) Chat(
+	ctx context.Context,
+	messages []providers.Message,
+	tools []providers.ToolDefinition,
+	model string,
+	opts map[string]any,
+) (*providers.LLMResponse, error) {
+	m.calls++
+	if m.calls == 1 {
+		return &providers.LLMResponse{
+			ToolCalls: []providers.ToolCall{{ID: "1", Name: m.tool, Arguments: map[string]any{"path": "."}}},
+		}, nil
+	}
+	return &providers.LLMResponse{Content: "Done"}, nil
+}
+
+func (m *toolCallMockProvider) GetDefaultModel() string {
+	return "mock-model"
+}
+
+func TestAgentLoop_PublishesStatus(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	msgBus := bus.NewMessageBus()
+	al := NewAgentLoop(cfg, msgBus, &toolCallMockProvider{tool: "list_dir"})
+	defer al.Close()
+
+	if got := sendTestMessage(t, al, "what is here?"); got != "Done" {
+		t.Fatalf("got %q", got)
+	}
+
+	want := []bus.StatusUpdate{
+		{Channel: "telegram", ChatID: "42"},
+		{Channel: "telegram", ChatID: "42", Status: "📄 Reading files"},
+		{Channel: "telegram", ChatID: "42", Done: true},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	for _, w := range want {
+		update, ok := msgBus.SubscribeStatus(ctx)
+		if !ok {
+			t.Fatalf("expected status %+v", w)
+		}
+		if update != w {
+			t.Errorf("status = %+v, want %+v", update, w)
+		}
+	}
+
+	// Heartbeats run unseen.
+	if _, err := al.ProcessHeartbeat(context.Background(), "check", "telegram", "42"); err != nil {
+		t.Fatal(err)
+	}
+	short, cancelShort := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelShort()
+	if update, ok := msgBus.SubscribeStatus(short); ok {
+		t.Errorf("unexpected status %+v", update)
+	}
+}
+
+func TestToolStatus(t *testing.T) {
+	if got := toolStatus("web_search"); got != "🔍 Searching the web" {
+		t.Errorf("web_search status = %q", got)
+	}
+	if got := toolStatus("weather"); got != "🛠️ Using weather" {
+		t.Errorf("unknown tool status = %q", got)
+	}
+}
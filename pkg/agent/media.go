@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"github.com/Agentx-network/agentx/pkg/logger"
+	"github.com/Agentx-network/agentx/pkg/providers"
+)
+
+// selectMedia picks the inbound attachments to send to the model and
+// reports whether the agent's image model has to handle the request.
+//
+// Images are attached when the model can see them. Voice notes and PDF
+// documents are attached as well when the model takes them; otherwise the
+// model relies on the transcription and the file mention channels put in
+// the message text. Images are dropped when neither the primary model nor
+// a configured image model can see them, so text-only setups keep working
+// as before. A request routed to the image model carries only the images.
+func selectMedia(agent *AgentInstance, paths []string) ([]providers.MediaPart, bool) {
+	all := providers.MediaFromPaths(paths)
+	var others []providers.MediaPart
+	for _, m := range all {
+		switch {
+		case m.Type == providers.MediaTypeAudio && agent.Audio,
+			m.MIMEType == "application/pdf" && agent.PDF:
+			others = append(others, m)
+		}
+	}
+
+	images := providers.FilterMedia(all, providers.MediaTypeImage)
+	if len(images) == 0 {
+		return others, false
+	}
+	if agent.Vision {
+		return append(images, others...), false
+	}
+	if agent.ImageModel != "" {
+		logger.DebugCF("agent", "Routing message with images to image model",
+			map[string]any{
+				"agent_id":    agent.ID,
+				"model":       agent.Model,
+				"image_model": agent.ImageModel,
+				"images":      len(images),
+			})
+		return images, true
+	}
+	logger.InfoCF("agent", "Model has no vision support and no image_model is configured; ignoring images",
+		map[string]any{
+			"agent_id": agent.ID,
+			"model":    agent.Model,
+			"images":   len(images),
+		})
+	return others, false
+}
@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/config"
+	"github.com/Agentx-network/agentx/pkg/providers"
+)
+
+// imageRecordingProvider rejects the first request carrying images as too
+// large and records the model and attachments of every call.
+type imageRecordingProvider struct {
+	models []string
+	media  [][]providers.MediaPart
+}
+
+func (p *imageRecordingProvider) Chat(
+	ctx context.Context,
+	messages []providers.Message,
+	tools []providers.ToolDefinition,
+	model string,
+	opts map[string]any,
+) (*providers.LLMResponse, error) {
+	last := messages[len(messages)-1]
+	p.models = append(p.models, model)
+	p.media = append(p.media, last.Media)
+	if len(p.media) == 1 && len(last.Media) > 0 {
+		return nil, errors.New("invalid_request_error: image exceeds 5 MB maximum")
+	}
+	return &providers.LLMResponse{Content: "a cat"}, nil
+}
+
+func (p *imageRecordingProvider) GetDefaultModel() string {
+	return "text-model"
+}
+
+func writeTestImage(t *testing.T) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 32, 32))); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "cat.png")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSelectMedia(t *testing.T) {
+	img := writeTestImage(t)
+	voice := filepath.Join(t.TempDir(), "voice.ogg")
+	os.WriteFile(voice, []byte("OggS"), 0o600)
+	paths := []string{img, voice}
+
+	media, useImageModel := selectMedia(&AgentInstance{Vision: true}, paths)
+	if len(media) != 1 || media[0].Type != providers.MediaTypeImage || useImageModel {
+		t.Errorf("vision model: got %+v, useImageModel=%v", media, useImageModel)
+	}
+
+	media, useImageModel = selectMedia(&AgentInstance{ImageModel: "vision"}, paths)
+	if len(media) != 1 || !useImageModel {
+		t.Errorf("text model with image model: got %+v, useImageModel=%v", media, useImageModel)
+	}
+
+	media, useImageModel = selectMedia(&AgentInstance{}, paths)
+	if media != nil || useImageModel {
+		t.Errorf("text model without image model should drop images, got %+v", media)
+	}
+
+	pdf := filepath.Join(t.TempDir(), "report.pdf")
+	os.WriteFile(pdf, []byte("%PDF-1.7"), 0o600)
+	paths = append(paths, pdf)
+
+	media, _ = selectMedia(&AgentInstance{Vision: true, Audio: true, PDF: true}, paths)
+	if len(media) != 3 || media[0].Type != providers.MediaTypeImage ||
+		media[1].Type != providers.MediaTypeAudio || media[2].MIMEType != "application/pdf" {
+		t.Errorf("multimodal model: got %+v", media)
+	}
+
+	media, _ = selectMedia(&AgentInstance{PDF: true}, paths)
+	if len(media) != 1 || media[0].Filename != "report.pdf" {
+		t.Errorf("text model reading PDFs: got %+v", media)
+	}
+
+	// The image model only gets the images.
+	media, useImageModel = selectMedia(&AgentInstance{Audio: true, ImageModel: "vision"}, paths)
+	if len(media) != 1 || media[0].Type != providers.MediaTypeImage || !useImageModel {
+		t.Errorf("image model: got %+v, useImageModel=%v", media, useImageModel)
+	}
+}
+
+func TestProcessMessage_RoutesImagesAndResizesOnError(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "text-model",
+				ImageModel:        "vision-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+	}
+	provider := &imageRecordingProvider{}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	defer al.Close()
+
+	img := writeTestImage(t)
+	response, err := al.processMessage(context.Background(), bus.InboundMessage{
+		Channel:  "telegram",
+		SenderID: "42",
+		ChatID:   "42",
+		Content:  "what is this? [image: photo]",
+		Media:    []string{img},
+	})
+	if err != nil {
+		t.Fatalf("processMessage failed: %v", err)
+	}
+	if response != "a cat" {
+		t.Errorf("unexpected response %q", response)
+	}
+
+	if len(provider.models) != 2 {
+		t.Fatalf("expected a retry after the image error, got %d calls", len(provider.models))
+	}
+	for i, model := range provider.models {
+		if model != "vision-model" {
+			t.Errorf("call %d went to %q, want the image model", i, model)
+		}
+	}
+	retried := provider.media[1]
+	if len(retried) != 1 || retried[0].Path == img || retried[0].MIMEType != "image/jpeg" {
+		t.Errorf("expected a resized JPEG on retry, got %+v", retried)
+	}
+	if _, err := os.Stat(retried[0].Path); !os.IsNotExist(err) {
+		t.Errorf("resized copy should be removed after processing, stat err = %v", err)
+	}
+
+	history := al.registry.GetDefaultAgent().Sessions.GetHistory("agent:main:main")
+	for _, msg := range history {
+		if len(msg.Media) > 0 {
+			t.Errorf("attachments should not be persisted in history: %+v", msg)
+		}
+	}
+}
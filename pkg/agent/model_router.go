@@ -0,0 +1,335 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Agentx-network/agentx/pkg/logger"
+	"github.com/Agentx-network/agentx/pkg/providers"
+	"github.com/Agentx-network/agentx/pkg/utils"
+)
+
+// Model tiers a turn can be routed to. The complex tier is the agent's
+// primary model and its fallbacks.
+const (
+	TierSimple  = "simple"
+	TierComplex = "complex"
+)
+
+// Classifiers for model_routing.classifier.
+const (
+	classifierHeuristic = "heuristic"
+	classifierModel     = "model"
+)
+
+const defaultMaxSimpleChars = 280
+
+// maxTierOverrides bounds the /switch tier overrides kept; past it, the
+// oldest is dropped.
+const maxTierOverrides = 1000
+
+// escalationMarker is the reply with which the simple model hands a turn
+// to the complex tier.
+const escalationMarker = "[ESCALATE]"
+
+const escalationHint = "\n\n## Model Routing\n" +
+	"You are a fast model answering a request that was classified as simple. " +
+	"If it actually needs multi-step tool use, writing or debugging code, or careful reasoning, " +
+	"reply with exactly " + escalationMarker + " and nothing else, and a more capable model will take over."
+
+// routeDecision is the tier chosen for a turn and why.
+type routeDecision struct {
+	Tier   string
+	Reason string
+}
+
+// complexPattern matches requests that usually need tools, code or several
+// steps.
+var complexPattern = regexp.MustCompile(`(?i)\b(` +
+	`code|coding|script|function|class|regex|sql|api|bug|debug|error|exception|stack ?trace|traceback|` +
+	`refactor|implement|compile|build|deploy|install|configure|migrate|optimi[sz]e|` +
+	`analy[sz]e|research|investigate|compare|plan|step[- ]by[- ]step|` +
+	`file|folder|directory|repo|repository|commit|` +
+	`search|browse|fetch|download|schedule|remind|cron|run|execute` +
+	`)\b`)
+
+// classifyTurn picks a tier from the message and the recent conversation
+// without calling a model.
+func classifyTurn(message string, history []providers.Message, maxSimpleChars int) routeDecision {
+	if maxSimpleChars <= 0 {
+		maxSimpleChars = defaultMaxSimpleChars
+	}
+	text := strings.TrimSpace(message)
+
+	if n := utf8.RuneCountInString(text); n > maxSimpleChars {
+		return routeDecision{TierComplex, fmt.Sprintf("long message (%d chars)", n)}
+	}
+	if strings.Contains(text, "```") {
+		return routeDecision{TierComplex, "contains a code block"}
+	}
+	if strings.Contains(text, "://") {
+		return routeDecision{TierComplex, "contains a URL"}
+	}
+	if m := complexPattern.FindString(text); m != "" {
+		return routeDecision{TierComplex, fmt.Sprintf("mentions %q", strings.ToLower(m))}
+	}
+	if strings.Count(text, "\n") >= 3 {
+		return routeDecision{TierComplex, "multi-line request"}
+	}
+	if usedToolsLastTurn(history) {
+		return routeDecision{TierComplex, "follows a turn that used tools"}
+	}
+	return routeDecision{TierSimple, "short conversational message"}
+}
+
+// usedToolsLastTurn reports whether the previous exchange involved tool
+// calls, in which case short follow-ups ("now do the same for X") usually
+// continue that work.
+func usedToolsLastTurn(history []providers.Message) bool {
+	for i := len(history) - 1; i >= 0; i-- {
+		msg := history[i]
+		if msg.Role == "user" {
+			return false
+		}
+		if msg.Role == "tool" || len(msg.ToolCalls) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+const classifierPrompt = `Classify the user's request so it can be sent to the right model.
+Answer with one word:
+SIMPLE - chit-chat, greetings, or a question answerable from general knowledge in a few sentences.
+COMPLEX - needs tools, files, web access, several steps, code, or careful reasoning.
+
+Request:
+%s`
+
+// classifyWithModel asks the routing classifier model to label a turn.
+func (al *AgentLoop) classifyWithModel(ctx context.Context, agent *AgentInstance, message string) (routeDecision, error) {
+	prompt := fmt.Sprintf(classifierPrompt, utils.Truncate(message, 2000))
+
+	var answer string
+	var err error
+	if agent.ClassifierFantasyModel != nil {
+		answer, err = al.summarizeWithFantasy(ctx, agent.ClassifierFantasyModel, prompt)
+	} else {
+		model := agent.Routing.ClassifierModel
+		if model == "" {
+			model = agent.SimpleModel
+		}
+		var resp *providers.LLMResponse
+		resp, err = agent.Provider.Chat(ctx, []providers.Message{{Role: "user", Content: prompt}}, nil, model,
+			map[string]any{"max_tokens": 8, "temperature": 0.0})
+		if resp != nil {
+			answer = resp.Content
+		}
+	}
+	if err != nil {
+		return routeDecision{}, err
+	}
+
+	switch upper := strings.ToUpper(answer); {
+	case strings.Contains(upper, "COMPLEX"):
+		return routeDecision{TierComplex, "classifier model answered COMPLEX"}, nil
+	case strings.Contains(upper, "SIMPLE"):
+		return routeDecision{TierSimple, "classifier model answered SIMPLE"}, nil
+	default:
+		return routeDecision{}, fmt.Errorf("unexpected classifier answer %q", utils.Truncate(answer, 40))
+	}
+}
+
+// selectModelTier decides which model tier handles a user turn. Turns with
+// images stay on the complex tier, which owns the image model routing.
+func (al *AgentLoop) selectModelTier(
+	ctx context.Context,
+	agent *AgentInstance,
+	sessionKey, message string,
+	useImageModel bool,
+) string {
+	if !agent.Routing.Enabled || agent.SimpleModel == "" || useImageModel {
+		return TierComplex
+	}
+	if agent.FantasyModel != nil && agent.SimpleFantasyModel == nil {
+		// The simple model failed to initialize; it was logged then.
+		return TierComplex
+	}
+
+	decision, classifier := al.classifyModelTier(ctx, agent, sessionKey, message)
+	model := agent.Model
+	if decision.Tier == TierSimple {
+		model = agent.SimpleModel
+	}
+	logger.DebugCF("agent", "Model routing decision",
+		map[string]any{
+			"agent_id":    agent.ID,
+			"session_key": sessionKey,
+			"tier":        decision.Tier,
+			"model":       model,
+			"classifier":  classifier,
+			"reason":      decision.Reason,
+		})
+	return decision.Tier
+}
+
+func (al *AgentLoop) classifyModelTier(
+	ctx context.Context,
+	agent *AgentInstance,
+	sessionKey, message string,
+) (routeDecision, string) {
+	if override, ok := al.tierOverrides.Load(sessionKey); ok {
+		return routeDecision{override.(tierOverride).tier, "set with /switch tier"}, "override"
+	}
+
+	if agent.Routing.Classifier == classifierModel {
+		decision, err := al.classifyWithModel(ctx, agent, message)
+		if err == nil {
+			return decision, classifierModel
+		}
+		logger.WarnCF("agent", "Routing classifier failed, using heuristics",
+			map[string]any{"agent_id": agent.ID, "error": err.Error()})
+	}
+
+	history := agent.Sessions.GetHistory(sessionKey)
+	return classifyTurn(message, history, agent.Routing.MaxSimpleChars), classifierHeuristic
+}
+
+// tierOverride is a model tier set with /switch tier.
+type tierOverride struct {
+	tier string
+	set  time.Time
+}
+
+// setTierOverride pins a session to a model tier until /switch tier to auto
+// or the session resets.
+func (al *AgentLoop) setTierOverride(sessionKey, tier string) {
+	al.tierOverrides.Store(sessionKey, tierOverride{tier: tier, set: time.Now()})
+
+	n := 0
+	var oldestKey any
+	var oldest time.Time
+	al.tierOverrides.Range(func(key, value any) bool {
+		n++
+		if set := value.(tierOverride).set; oldestKey == nil || set.Before(oldest) {
+			oldestKey, oldest = key, set
+		}
+		return true
+	})
+	if n > maxTierOverrides {
+		al.tierOverrides.Delete(oldestKey)
+	}
+}
+
+// withEscalationHint returns messages with the escalation instructions
+// appended to the system prompt.
+func withEscalationHint(messages []providers.Message) []providers.Message {
+	if len(messages) == 0 || messages[0].Role != "system" {
+		return messages
+	}
+	out := make([]providers.Message, len(messages))
+	copy(out, messages)
+	out[0].Content += escalationHint
+	if len(out[0].SystemParts) > 0 {
+		parts := make([]providers.ContentBlock, len(out[0].SystemParts), len(out[0].SystemParts)+1)
+		copy(parts, out[0].SystemParts)
+		out[0].SystemParts = append(parts, providers.ContentBlock{Type: "text", Text: strings.TrimSpace(escalationHint)})
+	}
+	return out
+}
+
+// isEscalation reports whether a reply from the simple model hands the turn
+// to the complex tier.
+func isEscalation(content string) bool {
+	return strings.HasPrefix(strings.TrimSpace(content), escalationMarker)
+}
+
+// runTurn runs the LLM loop on the tier chosen for the turn. If the simple
+// model escalates, the complex tier takes over the turn. Tool calls the
+// simple model made before escalating have already run, so they and their
+// results are kept and handed to the complex tier rather than run again;
+// only the escalation reply is dropped from the session.
+func (al *AgentLoop) runTurn(
+	ctx context.Context,
+	agent *AgentInstance,
+	messages []providers.Message,
+	opts processOptions,
+) (string, int, error) {
+	if opts.ModelTier != TierSimple || !agent.Routing.Escalation {
+		return al.runIterations(ctx, agent, messages, opts)
+	}
+
+	historyLen := len(agent.Sessions.GetHistory(opts.SessionKey))
+	opts.HoldEscalation = true
+	content, iteration, err := al.runIterations(ctx, agent, withEscalationHint(messages), opts)
+	if err != nil || !isEscalation(content) {
+		return content, iteration, err
+	}
+
+	logger.DebugCF("agent", "Simple model escalated the turn",
+		map[string]any{
+			"agent_id":    agent.ID,
+			"session_key": opts.SessionKey,
+			"model":       agent.Model,
+		})
+	var done []providers.Message
+	if history := agent.Sessions.GetHistory(opts.SessionKey); len(history) > historyLen {
+		for _, msg := range history[historyLen:] {
+			if msg.Role == "assistant" && len(msg.ToolCalls) == 0 && isEscalation(msg.Content) {
+				continue
+			}
+			done = append(done, msg)
+		}
+		agent.Sessions.SetHistory(opts.SessionKey, append(history[:historyLen:historyLen], done...))
+	}
+	opts.ModelTier = TierComplex
+	opts.HoldEscalation = false
+	return al.runIterations(ctx, agent, append(messages[:len(messages):len(messages)], done...), opts)
+}
+
+// runIterations runs the LLM loop with the Fantasy SDK when the agent has a
+// Fantasy model and with the legacy provider otherwise.
+func (al *AgentLoop) runIterations(
+	ctx context.Context,
+	agent *AgentInstance,
+	messages []providers.Message,
+	opts processOptions,
+) (string, int, error) {
+	if agent.FantasyModel != nil {
+		return al.runFantasyIteration(ctx, agent, messages, opts)
+	}
+	return al.runLLMIteration(ctx, agent, messages, opts)
+}
+
+// callSimpleModel sends a request to the simple tier through the legacy
+// provider, trying its fallbacks when it has any.
+func (al *AgentLoop) callSimpleModel(
+	ctx context.Context,
+	agent *AgentInstance,
+	messages []providers.Message,
+	toolDefs []providers.ToolDefinition,
+) (*providers.LLMResponse, error) {
+	chat := func(ctx context.Context, model string) (*providers.LLMResponse, error) {
+		return agent.Provider.Chat(ctx, messages, toolDefs, model, map[string]any{
+			"max_tokens":       agent.MaxTokens,
+			"temperature":      agent.Temperature,
+			"prompt_cache_key": agent.ID,
+		})
+	}
+	if len(agent.SimpleCandidates) > 1 && al.fallback != nil {
+		fbResult, err := al.fallback.Execute(ctx, agent.SimpleCandidates,
+			func(ctx context.Context, provider, model string) (*providers.LLMResponse, error) {
+				return chat(ctx, model)
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+		return fbResult.Response, nil
+	}
+	return chat(ctx, agent.SimpleModel)
+}
@@ -0,0 +1,254 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/config"
+	"github.com/Agentx-network/agentx/pkg/providers"
+	"github.com/Agentx-network/agentx/pkg/tools"
+)
+
+// tierRecordingProvider records which model each call went to and replies
+// with the escalation marker when the simple model is asked to escalate.
+type tierRecordingProvider struct {
+	models   []string
+	escalate bool
+}
+
+func (p *tierRecordingProvider) Chat(
+	ctx context.Context,
+	messages []providers.Message,
+	tools []providers.ToolDefinition,
+	model string,
+	opts map[string]any,
+) (*providers.LLMResponse, error) {
+	p.models = append(p.models, model)
+	if model == "small-model" && p.escalate && strings.Contains(messages[0].Content, escalationMarker) {
+		return &providers.LLMResponse{Content: escalationMarker}, nil
+	}
+	return &providers.LLMResponse{Content: "answer from " + model}, nil
+}
+
+func (p *tierRecordingProvider) GetDefaultModel() string {
+	return "big-model"
+}
+
+func newRoutingTestLoop(t *testing.T, escalation bool) (*AgentLoop, *tierRecordingProvider) {
+	t.Helper()
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "big-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+				ModelRouting: config.ModelRoutingConfig{
+					Enabled:     true,
+					SimpleModel: "small-model",
+					Escalation:  escalation,
+				},
+			},
+		},
+	}
+	provider := &tierRecordingProvider{escalate: escalation}
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), provider)
+	t.Cleanup(al.Close)
+	return al, provider
+}
+
+func sendTestMessage(t *testing.T, al *AgentLoop, content string) string {
+	t.Helper()
+	response, err := al.processMessage(context.Background(), bus.InboundMessage{
+		Channel:  "telegram",
+		SenderID: "42",
+		ChatID:   "42",
+		Content:  content,
+	})
+	if err != nil {
+		t.Fatalf("processMessage(%q) failed: %v", content, err)
+	}
+	return response
+}
+
+func TestClassifyTurn(t *testing.T) {
+	toolHistory := []providers.Message{
+		{Role: "user", Content: "list my files"},
+		{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "1", Name: "list_dir"}}},
+		{Role: "tool", Content: "a.txt", ToolCallID: "1"},
+		{Role: "assistant", Content: "You have a.txt"},
+	}
+
+	tests := []struct {
+		name    string
+		message string
+		history []providers.Message
+		want    string
+	}{
+		{"greeting", "hi there!", nil, TierSimple},
+		{"general question", "What's the capital of France?", nil, TierSimple},
+		{"long message", strings.Repeat("please tell me more ", 20), nil, TierComplex},
+		{"code block", "what does ```ls -la``` print?", nil, TierComplex},
+		{"url", "summarize https://example.com", nil, TierComplex},
+		{"keyword", "Can you debug this for me?", nil, TierComplex},
+		{"keyword needs word boundary", "I love my coder friends", nil, TierSimple},
+		{"multi-line", "a\nb\nc\nd", nil, TierComplex},
+		{"follow-up after tools", "and the other one?", toolHistory, TierComplex},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyTurn(tt.message, tt.history, 0)
+			if got.Tier != tt.want {
+				t.Errorf("classifyTurn(%q) = %s (%s), want %s", tt.message, got.Tier, got.Reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessMessage_RoutesByTier(t *testing.T) {
+	al, provider := newRoutingTestLoop(t, false)
+
+	if got := sendTestMessage(t, al, "hello!"); got != "answer from small-model" {
+		t.Errorf("simple turn: got %q", got)
+	}
+	if got := sendTestMessage(t, al, "please refactor my script"); got != "answer from big-model" {
+		t.Errorf("complex turn: got %q", got)
+	}
+	if len(provider.models) != 2 {
+		t.Errorf("expected one call per turn, got %v", provider.models)
+	}
+}
+
+func TestProcessMessage_EscalatesToComplexTier(t *testing.T) {
+	al, provider := newRoutingTestLoop(t, true)
+
+	if got := sendTestMessage(t, al, "hello!"); got != "answer from big-model" {
+		t.Errorf("escalated turn: got %q", got)
+	}
+	want := []string{"small-model", "big-model"}
+	if strings.Join(provider.models, ",") != strings.Join(want, ",") {
+		t.Errorf("calls went to %v, want %v", provider.models, want)
+	}
+
+	agent, sessionKey, _ := al.resolveMessageRoute(bus.InboundMessage{
+		Channel: "telegram", SenderID: "42", ChatID: "42",
+	})
+	history := agent.Sessions.GetHistory(sessionKey)
+	if len(history) != 2 {
+		t.Errorf("expected the user message and final answer in history, got %+v", history)
+	}
+	for _, msg := range history {
+		if strings.Contains(msg.Content, escalationMarker) {
+			t.Errorf("escalation marker kept in history: %+v", msg)
+		}
+	}
+}
+
+// toolThenEscalateProvider has the simple model call a tool before it
+// escalates, and records what the complex model is given.
+type toolThenEscalateProvider struct {
+	complexMessages []providers.Message
+}
+
+func (p *toolThenEscalateProvider) Chat(
+	ctx context.Context,
+	messages []providers.Message,
+	tools []providers.ToolDefinition,
+	model string,
+	opts map[string]any,
+) (*providers.LLMResponse, error) {
+	if model == "big-model" {
+		p.complexMessages = messages
+		return &providers.LLMResponse{Content: "answer from big-model"}, nil
+	}
+	if messages[len(messages)-1].Role != "tool" {
+		return &providers.LLMResponse{ToolCalls: []providers.ToolCall{{ID: "call-1", Name: "counting_tool"}}}, nil
+	}
+	return &providers.LLMResponse{Content: escalationMarker}, nil
+}
+
+func (p *toolThenEscalateProvider) GetDefaultModel() string {
+	return "big-model"
+}
+
+type countingTool struct{ calls int }
+
+func (c *countingTool) Name() string               { return "counting_tool" }
+func (c *countingTool) Description() string        { return "Counts its calls" }
+func (c *countingTool) Parameters() map[string]any { return map[string]any{"type": "object"} }
+
+func (c *countingTool) Execute(ctx context.Context, args map[string]any) *tools.ToolResult {
+	c.calls++
+	return tools.SilentResult("counted")
+}
+
+func TestProcessMessage_EscalationKeepsToolResults(t *testing.T) {
+	al, _ := newRoutingTestLoop(t, true)
+	provider := &toolThenEscalateProvider{}
+	agent, sessionKey, _ := al.resolveMessageRoute(bus.InboundMessage{
+		Channel: "telegram", SenderID: "42", ChatID: "42",
+	})
+	agent.Provider = provider
+	tool := &countingTool{}
+	al.RegisterTool(tool)
+
+	if got := sendTestMessage(t, al, "hello!"); got != "answer from big-model" {
+		t.Errorf("escalated turn: got %q", got)
+	}
+	if tool.calls != 1 {
+		t.Errorf("tool ran %d times, want once", tool.calls)
+	}
+	// The complex model sees the call and its result instead of making it again.
+	n := len(provider.complexMessages)
+	if n < 2 || provider.complexMessages[n-1].Content != "counted" || len(provider.complexMessages[n-2].ToolCalls) != 1 {
+		t.Errorf("complex model got %+v", provider.complexMessages)
+	}
+
+	history := agent.Sessions.GetHistory(sessionKey)
+	if len(history) != 4 {
+		t.Errorf("expected user message, tool call, tool result and answer in history, got %+v", history)
+	}
+	for _, msg := range history {
+		if strings.Contains(msg.Content, escalationMarker) {
+			t.Errorf("escalation marker kept in history: %+v", msg)
+		}
+	}
+}
+
+func TestSwitchTierCommand(t *testing.T) {
+	al, provider := newRoutingTestLoop(t, false)
+
+	if got := sendTestMessage(t, al, "/switch tier to complex"); !strings.Contains(got, "complex") {
+		t.Errorf("unexpected command response %q", got)
+	}
+	sendTestMessage(t, al, "hello!")
+
+	sendTestMessage(t, al, "/switch tier to auto")
+	sendTestMessage(t, al, "hello again")
+
+	want := []string{"big-model", "small-model"}
+	if strings.Join(provider.models, ",") != strings.Join(want, ",") {
+		t.Errorf("calls went to %v, want %v", provider.models, want)
+	}
+}
+
+func TestSetTierOverride_Bounded(t *testing.T) {
+	al, _ := newRoutingTestLoop(t, false)
+	for i := 0; i <= maxTierOverrides; i++ {
+		al.setTierOverride(fmt.Sprintf("session-%d", i), TierComplex)
+	}
+	n := 0
+	al.tierOverrides.Range(func(_, _ any) bool {
+		n++
+		return true
+	})
+	if n != maxTierOverrides {
+		t.Errorf("kept %d overrides, want %d", n, maxTierOverrides)
+	}
+	if _, ok := al.tierOverrides.Load("session-0"); ok {
+		t.Error("the oldest override should have been dropped")
+	}
+}
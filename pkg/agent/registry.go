@@ -1,7 +1,11 @@
 package agent
 
 import (
+	"context"
 	"sync"
+	"time"
+
+	"charm.land/fantasy"
 
 	"github.com/Agentx-network/agentx/pkg/config"
 	"github.com/Agentx-network/agentx/pkg/logger"
@@ -9,47 +13,114 @@ import (
 	"github.com/Agentx-network/agentx/pkg/routing"
 )
 
+// contextWindowTimeout bounds the context window lookup done at startup.
+const contextWindowTimeout = 5 * time.Second
+
 // initFantasyModels initializes Fantasy models for all agents in the registry.
+// Models are shared by name, so agents using the same balanced pool share
+// its rate limits and cooldowns.
 func (r *AgentRegistry) initFantasyModels(cfg *config.Config) {
+	defaultModel, err := providers.FantasyModelFromFullConfig(cfg)
+	if err != nil {
+		logger.WarnCF("agent", "Failed to create Fantasy model, using legacy provider",
+			map[string]any{"error": err.Error()})
+		return
+	}
+	r.models = map[string]fantasy.LanguageModel{cfg.Agents.Defaults.GetModelName(): defaultModel}
+
 	for id, agent := range r.agents {
-		model, err := providers.FantasyModelFromFullConfig(cfg)
-		if err != nil {
-			logger.WarnCF("agent", "Failed to create Fantasy model, using legacy provider",
-				map[string]any{
-					"agent_id": id,
-					"error":    err.Error(),
-				})
-			continue
-		}
+		model := defaultModel
 
 		// Wrap with fallback if configured
-		if len(agent.Fallbacks) > 0 {
-			cooldown := providers.NewCooldownTracker()
-			var fallbackCfgs []*config.ModelConfig
-			for _, fb := range agent.Fallbacks {
-				fbCfg, err := cfg.GetModelConfig(fb)
-				if err != nil {
-					// Try treating as protocol/model directly
-					fbCfg = &config.ModelConfig{
-						Model:     fb,
-						ModelName: fb,
-					}
-				}
-				fallbackCfgs = append(fallbackCfgs, fbCfg)
-			}
-			if len(fallbackCfgs) > 0 {
-				model = providers.NewFallbackLanguageModel(model, fallbackCfgs, cooldown)
-			}
+		if fallbacks := r.fantasyModelsForNames(cfg, agent.Fallbacks); len(fallbacks) > 0 {
+			model = providers.NewFallbackLanguageModel(model, fallbacks, providers.NewCooldownTracker())
 		}
 
 		agent.FantasyModel = model
+		if window := detectContextWindow(cfg, agent.Model); window > 0 {
+			agent.ContextWindow = window
+		}
 		logger.InfoCF("agent", "Fantasy model initialized",
 			map[string]any{
 				"agent_id": id,
 				"provider": model.Provider(),
 				"model":    model.Model(),
 			})
+
+		if agent.ImageModel != "" {
+			agent.ImageFantasyModel = r.fantasyModelWithFallbacks(cfg, agent.ImageModel,
+				cfg.Agents.Defaults.ImageModelFallbacks)
+		}
+		if agent.Routing.Enabled && agent.SimpleModel != "" {
+			agent.SimpleFantasyModel = r.fantasyModelWithFallbacks(cfg, agent.SimpleModel,
+				agent.Routing.SimpleModelFallbacks)
+			if agent.Routing.Classifier == classifierModel {
+				if name := agent.Routing.ClassifierModel; name != "" && name != agent.SimpleModel {
+					agent.ClassifierFantasyModel = r.fantasyModelWithFallbacks(cfg, name, nil)
+				} else {
+					agent.ClassifierFantasyModel = agent.SimpleFantasyModel
+				}
+			}
+		}
+	}
+}
+
+// detectContextWindow asks a local Ollama server for the model's context
+// window, so compression kicks in before the server starts truncating.
+func detectContextWindow(cfg *config.Config, name string) int {
+	ctx, cancel := context.WithTimeout(context.Background(), contextWindowTimeout)
+	defer cancel()
+	return providers.ContextWindowForName(ctx, cfg, name)
+}
+
+// fantasyModelWithFallbacks returns a secondary model of an agent (the
+// image model or a routing tier) wrapped with its own fallbacks.
+func (r *AgentRegistry) fantasyModelWithFallbacks(cfg *config.Config, name string, fallbacks []string) fantasy.LanguageModel {
+	model := r.fantasyModel(cfg, name)
+	if model == nil {
+		return nil
+	}
+	if fbModels := r.fantasyModelsForNames(cfg, fallbacks); len(fbModels) > 0 {
+		return providers.NewFallbackLanguageModel(model, fbModels, providers.NewCooldownTracker())
+	}
+	return model
+}
+
+// fantasyModel returns the model for a model_list name, balanced over all
+// entries with that name, creating it on first use. Names not in
+// model_list are treated as protocol/model identifiers.
+func (r *AgentRegistry) fantasyModel(cfg *config.Config, name string) fantasy.LanguageModel {
+	if model, ok := r.models[name]; ok {
+		return model
+	}
+	var model fantasy.LanguageModel
+	var err error
+	if _, lookupErr := cfg.GetModelConfigs(name); lookupErr == nil {
+		model, err = providers.FantasyModelForName(cfg, name)
+	} else {
+		model, err = providers.FantasyModelFromConfig(&config.ModelConfig{Model: name, ModelName: name})
+	}
+	if err != nil {
+		logger.WarnCF("agent", "Failed to create model",
+			map[string]any{
+				"model": name,
+				"error": err.Error(),
+			})
+		return nil
+	}
+	r.models[name] = model
+	return model
+}
+
+// fantasyModelsForNames returns fallback models, skipping any that fail.
+func (r *AgentRegistry) fantasyModelsForNames(cfg *config.Config, names []string) []fantasy.LanguageModel {
+	var models []fantasy.LanguageModel
+	for _, name := range names {
+		if model := r.fantasyModel(cfg, name); model != nil {
+			models = append(models, model)
+		}
 	}
+	return models
 }
 
 // AgentRegistry manages multiple agent instances and routes messages to them.
@@ -57,6 +128,10 @@ type AgentRegistry struct {
 	agents   map[string]*AgentInstance
 	resolver *routing.RouteResolver
 	mu       sync.RWMutex
+
+	// models holds the models created by name, so every agent using a
+	// name gets the same instance.
+	models map[string]fantasy.LanguageModel
 }
 
 // NewAgentRegistry creates a registry from config, instantiating all agents.
@@ -158,3 +233,18 @@ func (r *AgentRegistry) GetDefaultAgent() *AgentInstance {
 	}
 	return nil
 }
+
+// Close releases the session stores of all agents.
+func (r *AgentRegistry) Close() {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for id, agent := range r.agents {
+		if err := agent.Sessions.Close(); err != nil {
+			logger.WarnCF("agent", "Failed to close session store",
+				map[string]any{
+					"agent_id": id,
+					"error":    err.Error(),
+				})
+		}
+	}
+}
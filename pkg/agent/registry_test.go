@@ -82,6 +82,30 @@ func TestNewAgentRegistry_ExplicitAgents(t *testing.T) {
 	}
 }
 
+func TestAgentRegistry_SharesModelPools(t *testing.T) {
+	cfg := testCfg([]config.AgentConfig{
+		{ID: "sales", Default: true},
+		{ID: "support", Model: &config.AgentModelConfig{Primary: "gpt-4", Fallbacks: []string{"pool"}}},
+	})
+	cfg.Agents.Defaults.Model = "pool"
+	cfg.Agents.Defaults.ImageModel = "pool"
+	cfg.ModelList = []config.ModelConfig{
+		{ModelName: "pool", Model: "openai/gpt-4o", APIKey: "key1", RPM: 60},
+		{ModelName: "pool", Model: "openai/gpt-4o", APIKey: "key2", RPM: 60},
+	}
+	registry := NewAgentRegistry(cfg, &mockRegistryProvider{})
+
+	pool := registry.models["pool"]
+	if _, ok := pool.(*providers.BalancedLanguageModel); !ok {
+		t.Fatalf("expected a balanced pool, got %T", pool)
+	}
+	sales, _ := registry.GetAgent("sales")
+	support, _ := registry.GetAgent("support")
+	if sales.FantasyModel != pool || sales.ImageFantasyModel != pool || support.ImageFantasyModel != pool {
+		t.Error("agents should share the pool of a model_name")
+	}
+}
+
 func TestAgentRegistry_GetAgent_Normalize(t *testing.T) {
 	cfg := testCfg([]config.AgentConfig{
 		{ID: "my-agent", Default: true},
@@ -0,0 +1,179 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/Agentx-network/agentx/pkg/config"
+	"github.com/Agentx-network/agentx/pkg/logger"
+	"github.com/Agentx-network/agentx/pkg/providers"
+	"github.com/Agentx-network/agentx/pkg/session"
+)
+
+// retentionInterval is how often expired sessions are archived or deleted.
+const retentionInterval = time.Hour
+
+// newResetPolicy builds the session reset policy from config. LoadConfig
+// rejects an invalid daily reset time; one set otherwise is logged and
+// ignored so the other rules still apply.
+func newResetPolicy(cfg config.SessionConfig) session.ResetPolicy {
+	policy, err := session.NewResetPolicy(cfg.IdleResetHours, cfg.DailyResetAt, cfg.MaxAgeHours)
+	if err != nil {
+		logger.WarnCF("agent", "Ignoring session.daily_reset_at", map[string]any{"error": err.Error()})
+	}
+	return policy
+}
+
+// maybeResetSession clears a session that the reset policy considers stale,
+// so a user returning later starts a fresh conversation. When
+// summarize_on_reset is set, the discarded conversation is summarized into
+// today's memory notes in the background.
+func (al *AgentLoop) maybeResetSession(agent *AgentInstance, sessionKey string) {
+	if !al.resetPolicy.Enabled() {
+		return
+	}
+
+	info, ok := agent.Sessions.Info(sessionKey)
+	if !ok {
+		return
+	}
+	reason := al.resetPolicy.ResetReason(info, time.Now())
+	if reason == "" {
+		return
+	}
+
+	var history []providers.Message
+	var summary string
+	if al.cfg.Session.SummarizeOnReset {
+		history = agent.Sessions.GetHistory(sessionKey)
+		summary = agent.Sessions.GetSummary(sessionKey)
+	}
+
+	agent.Sessions.Reset(sessionKey)
+	al.tierOverrides.Delete(sessionKey)
+	if err := agent.Sessions.Save(sessionKey); err != nil {
+		logger.ErrorCF("agent", "Failed to save session after reset", map[string]any{
+			"error":       err.Error(),
+			"session_key": sessionKey,
+		})
+	}
+
+	logger.InfoCF("agent", "Session reset", map[string]any{
+		"agent_id":     agent.ID,
+		"session_key":  sessionKey,
+		"reason":       reason,
+		"last_updated": info.Updated.Format(time.RFC3339),
+		"messages":     info.MessageCount,
+	})
+
+	if len(history) > 0 || summary != "" {
+		go al.summarizeToMemory(agent, sessionKey, reason, history, summary)
+	}
+}
+
+// summarizeToMemory appends a summary of a reset conversation to the
+// agent's daily memory notes.
+func (al *AgentLoop) summarizeToMemory(
+	agent *AgentInstance,
+	sessionKey, reason string,
+	history []providers.Message,
+	summary string,
+) {
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	maxMessageTokens := agent.ContextWindow / 2
+	conversation := make([]providers.Message, 0, len(history))
+	for _, m := range history {
+		if m.Role != "user" && m.Role != "assistant" {
+			continue
+		}
+		if m.Content == "" || len(m.Content)/2 > maxMessageTokens {
+			continue
+		}
+		conversation = append(conversation, m)
+	}
+
+	text := summary
+	if len(conversation) > 0 {
+		s, err := al.summarizeBatch(ctx, agent, conversation, summary)
+		if err != nil {
+			logger.WarnCF("agent", "Failed to summarize session before reset", map[string]any{
+				"error":       err.Error(),
+				"session_key": sessionKey,
+			})
+		} else if s != "" {
+			text = s
+		}
+	}
+	if text == "" {
+		return
+	}
+
+	note := fmt.Sprintf("## Conversation %s (%s reset at %s)\n\n%s\n",
+		sessionKey, reason, time.Now().Format("15:04"), text)
+	if err := agent.ContextBuilder.memory.AppendToday(note); err != nil {
+		logger.ErrorCF("agent", "Failed to write session summary to memory", map[string]any{
+			"error":       err.Error(),
+			"session_key": sessionKey,
+		})
+	}
+}
+
+// runRetention periodically archives or deletes sessions that have not been
+// used for session.retention.after_days, until ctx is cancelled.
+func (al *AgentLoop) runRetention(ctx context.Context) {
+	if al.cfg.Session.Retention.AfterDays <= 0 {
+		return
+	}
+
+	al.applyRetention()
+
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			al.applyRetention()
+		}
+	}
+}
+
+// applyRetention runs the retention policy once for every agent.
+func (al *AgentLoop) applyRetention() {
+	retention := al.cfg.Session.Retention
+	action := retention.Action
+	if action == "" {
+		action = session.RetentionArchive
+	}
+	for _, agentID := range al.registry.ListAgentIDs() {
+		agent, ok := al.registry.GetAgent(agentID)
+		if !ok {
+			continue
+		}
+
+		policy := session.RetentionPolicy{
+			MaxAge:     time.Duration(retention.AfterDays) * 24 * time.Hour,
+			Action:     action,
+			ArchiveDir: filepath.Join(agent.Workspace, "sessions", "archive"),
+		}
+		removed, err := agent.Sessions.ApplyRetention(policy, time.Now())
+		if err != nil {
+			logger.ErrorCF("agent", "Session retention failed", map[string]any{
+				"agent_id": agent.ID,
+				"error":    err.Error(),
+			})
+		}
+		if removed > 0 {
+			logger.InfoCF("agent", "Applied session retention", map[string]any{
+				"agent_id": agent.ID,
+				"removed":  removed,
+				"action":   policy.Action,
+			})
+		}
+	}
+}
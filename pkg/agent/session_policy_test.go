@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/config"
+)
+
+func TestAgentLoop_IdleSessionIsReset(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Session: config.SessionConfig{
+			IdleResetHours: 1,
+		},
+	}
+
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &simpleMockProvider{response: "fresh start"})
+	agent := al.registry.GetDefaultAgent()
+
+	sessionKey := "agent:main:test:stale"
+	agent.Sessions.AddMessage(sessionKey, "user", "yesterday's question")
+	agent.Sessions.AddMessage(sessionKey, "assistant", "yesterday's answer")
+	agent.Sessions.GetOrCreate(sessionKey).Updated = time.Now().Add(-2 * time.Hour)
+
+	if _, err := al.ProcessDirectWithChannel(context.Background(), "good morning", sessionKey, "test", "chat"); err != nil {
+		t.Fatalf("ProcessDirectWithChannel failed: %v", err)
+	}
+
+	history := agent.Sessions.GetHistory(sessionKey)
+	if len(history) != 2 {
+		t.Fatalf("expected only the new turn after reset, got %d messages", len(history))
+	}
+	if history[0].Content != "good morning" {
+		t.Errorf("expected first message %q, got %q", "good morning", history[0].Content)
+	}
+}
+
+func TestAgentLoop_ActiveSessionIsKept(t *testing.T) {
+	cfg := &config.Config{
+		Agents: config.AgentsConfig{
+			Defaults: config.AgentDefaults{
+				Workspace:         t.TempDir(),
+				Model:             "test-model",
+				MaxTokens:         4096,
+				MaxToolIterations: 10,
+			},
+		},
+		Session: config.SessionConfig{
+			IdleResetHours: 1,
+		},
+	}
+
+	al := NewAgentLoop(cfg, bus.NewMessageBus(), &simpleMockProvider{response: "ok"})
+	agent := al.registry.GetDefaultAgent()
+
+	sessionKey := "agent:main:test:active"
+	agent.Sessions.AddMessage(sessionKey, "user", "earlier question")
+	agent.Sessions.AddMessage(sessionKey, "assistant", "earlier answer")
+
+	if _, err := al.ProcessDirectWithChannel(context.Background(), "follow-up", sessionKey, "test", "chat"); err != nil {
+		t.Fatalf("ProcessDirectWithChannel failed: %v", err)
+	}
+
+	if got := len(agent.Sessions.GetHistory(sessionKey)); got != 4 {
+		t.Errorf("expected history to be kept (4 messages), got %d", got)
+	}
+}
@@ -0,0 +1,39 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/structured"
+)
+
+// ProcessDirectStructured runs content from senderID like
+// ProcessDirectWithChannel, but asks for a final answer matching schema and
+// returns it parsed. Answers that do not match are sent back to the agent,
+// in the same session, with the validation error. Nothing is streamed to
+// the channel.
+func (al *AgentLoop) ProcessDirectStructured(
+	ctx context.Context,
+	content, senderID, sessionKey, channel, chatID string,
+	schema map[string]any,
+) (*structured.Result, error) {
+	agent, sessionKey, _ := al.resolveMessageRoute(bus.InboundMessage{
+		Channel:    channel,
+		SenderID:   senderID,
+		ChatID:     chatID,
+		Content:    content,
+		SessionKey: sessionKey,
+	})
+
+	req := structured.Request{Task: content, Schema: schema}
+	return structured.Run(ctx, req, func(ctx context.Context, prompt string) (string, error) {
+		return al.runAgentLoop(ctx, agent, processOptions{
+			SessionKey:    sessionKey,
+			Channel:       channel,
+			ChatID:        chatID,
+			UserMessage:   prompt,
+			EnableSummary: true,
+			NoStream:      true,
+		})
+	})
+}
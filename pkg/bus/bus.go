@@ -16,6 +16,7 @@ type MessageBus struct {
 	outbound   chan OutboundMessage
 	stream     chan StreamDelta
 	streamSubs []*StreamSubscriber
+	status     chan StatusUpdate
 	handlers   map[string]MessageHandler
 	closed     bool
 	mu         sync.RWMutex
@@ -26,6 +27,7 @@ func NewMessageBus() *MessageBus {
 		inbound:  make(chan InboundMessage, 100),
 		outbound: make(chan OutboundMessage, 100),
 		stream:   make(chan StreamDelta, 500),
+		status:   make(chan StatusUpdate, 100),
 		handlers: make(map[string]MessageHandler),
 	}
 }
@@ -119,6 +121,28 @@ func (mb *MessageBus) SubscribeStream(ctx context.Context) (StreamDelta, bool) {
 	}
 }
 
+func (mb *MessageBus) PublishStatus(update StatusUpdate) {
+	mb.mu.RLock()
+	defer mb.mu.RUnlock()
+	if mb.closed {
+		return
+	}
+	select {
+	case mb.status <- update:
+	default:
+		// Drop the update if the channel is full; a status is not worth blocking the agent
+	}
+}
+
+func (mb *MessageBus) SubscribeStatus(ctx context.Context) (StatusUpdate, bool) {
+	select {
+	case update := <-mb.status:
+		return update, true
+	case <-ctx.Done():
+		return StatusUpdate{}, false
+	}
+}
+
 func (mb *MessageBus) RegisterHandler(channel string, handler MessageHandler) {
 	mb.mu.Lock()
 	defer mb.mu.Unlock()
@@ -142,4 +166,5 @@ func (mb *MessageBus) Close() {
 	close(mb.inbound)
 	close(mb.outbound)
 	close(mb.stream)
+	close(mb.status)
 }
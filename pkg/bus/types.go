@@ -1,5 +1,13 @@
 package bus
 
+import (
+	"mime"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
 type InboundMessage struct {
 	Channel    string            `json:"channel"`
 	SenderID   string            `json:"sender_id"`
@@ -11,9 +19,90 @@ type InboundMessage struct {
 }
 
 type OutboundMessage struct {
-	Channel string `json:"channel"`
-	ChatID  string `json:"chat_id"`
-	Content string `json:"content"`
+	Channel     string       `json:"channel"`
+	ChatID      string       `json:"chat_id"`
+	Content     string       `json:"content"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	ReplyTo     string       `json:"reply_to,omitempty"` // platform message ID to reply to
+	Buttons     []Button     `json:"buttons,omitempty"`
+	Reasoning   string       `json:"reasoning,omitempty"` // model's thinking trace, folded where the channel can
+	Speak       bool         `json:"speak,omitempty"`     // send as a voice note where the channel can (/voice on)
+}
+
+// Attachment is a file sent with an outbound message, either a local file
+// (Path) or a remote one (URL).
+type Attachment struct {
+	Path     string `json:"path,omitempty"`
+	URL      string `json:"url,omitempty"`
+	MIMEType string `json:"mime_type,omitempty"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// Kinds returned by Attachment.Kind.
+const (
+	AttachmentImage = "image"
+	AttachmentAudio = "audio"
+	AttachmentVideo = "video"
+	AttachmentFile  = "file"
+)
+
+// Name returns the attachment's file name, derived from its path or URL
+// when Filename is empty.
+func (a Attachment) Name() string {
+	if a.Filename != "" {
+		return a.Filename
+	}
+	if a.Path != "" {
+		return filepath.Base(a.Path)
+	}
+	if u, err := url.Parse(a.URL); err == nil && path.Base(u.Path) != "/" && path.Base(u.Path) != "." {
+		return path.Base(u.Path)
+	}
+	return "file"
+}
+
+// ContentType returns the attachment's MIME type, guessed from its name
+// when MIMEType is empty.
+func (a Attachment) ContentType() string {
+	if a.MIMEType != "" {
+		return a.MIMEType
+	}
+	if t := mime.TypeByExtension(strings.ToLower(filepath.Ext(a.Name()))); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// Kind classifies the attachment as an image, audio, video or generic file.
+func (a Attachment) Kind() string {
+	ct := a.ContentType()
+	switch {
+	case strings.HasPrefix(ct, "image/"):
+		return AttachmentImage
+	case strings.HasPrefix(ct, "audio/"), ct == "application/ogg":
+		return AttachmentAudio
+	case strings.HasPrefix(ct, "video/"):
+		return AttachmentVideo
+	default:
+		return AttachmentFile
+	}
+}
+
+// Button is a quick-reply option shown with an outbound message. Pressing
+// it sends Value (or Text when Value is empty) back as a user message;
+// buttons with a URL open the link instead.
+type Button struct {
+	Text  string `json:"text"`
+	Value string `json:"value,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+// Reply returns the text sent back when the button is pressed.
+func (b Button) Reply() string {
+	if b.Value != "" {
+		return b.Value
+	}
+	return b.Text
 }
 
 // StreamDelta represents a streaming text delta for progressive message updates.
@@ -24,4 +113,13 @@ type StreamDelta struct {
 	Done    bool   `json:"done"`
 }
 
+// StatusUpdate tells a chat what the agent is doing while it works on a
+// reply. An empty Status only shows that it is busy; Done clears it.
+type StatusUpdate struct {
+	Channel string `json:"channel"`
+	ChatID  string `json:"chat_id"`
+	Status  string `json:"status,omitempty"` // e.g. "🔍 Searching the web"
+	Done    bool   `json:"done"`
+}
+
 type MessageHandler func(InboundMessage) error
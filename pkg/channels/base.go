@@ -2,11 +2,25 @@ package channels
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/config"
+	"github.com/Agentx-network/agentx/pkg/logger"
+	"github.com/Agentx-network/agentx/pkg/utils"
+	"github.com/Agentx-network/agentx/pkg/voice"
 )
 
+// transcriptionTimeout bounds how long a voice message may take to
+// transcribe before the channel falls back to a placeholder.
+const transcriptionTimeout = 30 * time.Second
+
 type Channel interface {
 	Name() string
 	Start(ctx context.Context) error
@@ -23,12 +37,63 @@ type StreamableChannel interface {
 	StartStreamConsumer(ctx context.Context)
 }
 
+// ReasoningChannel is an optional interface for channels that can show a
+// model's reasoning trace (OutboundMessage.Reasoning) folded, so it does not
+// crowd the chat. Other channels get the trace as quoted text.
+type ReasoningChannel interface {
+	Channel
+	FoldsReasoning() bool
+}
+
+// VoiceChannel is an optional interface for channels that receive voice
+// messages. The manager hands them the configured speech-to-text backend.
+type VoiceChannel interface {
+	Channel
+	SetTranscriber(transcriber voice.Transcriber)
+}
+
+// VoiceReplyChannel is an optional interface for channels that can deliver
+// replies as audio when /voice is on. VoiceFormat is the format the channel
+// plays, voice.FormatOgg or voice.FormatWAV, or "" when the other end cannot
+// take audio. Other channels get text.
+type VoiceReplyChannel interface {
+	Channel
+	VoiceFormat() string
+}
+
+// HTTPChannel is an optional interface for channels that receive requests
+// on the gateway's HTTP server rather than running their own.
+type HTTPChannel interface {
+	Channel
+	http.Handler
+	HTTPPath() string
+}
+
+// TypingChannel is an optional interface for channels that can show the
+// platform's typing indicator while the agent works on a reply. The manager
+// calls SendTyping again before the indicator runs out.
+type TypingChannel interface {
+	Channel
+	SendTyping(ctx context.Context, chatID string) error
+}
+
+// StatusChannel is an optional interface for channels that can keep a
+// message saying what the agent is doing, such as "🔍 Searching the web",
+// edited as the status changes. Used by channels in "verbose" status mode.
+type StatusChannel interface {
+	Channel
+	ShowStatus(ctx context.Context, chatID, status string) error
+	ClearStatus(ctx context.Context, chatID string) error
+}
+
 type BaseChannel struct {
-	config    any
-	bus       *bus.MessageBus
-	running   bool
-	name      string
-	allowList []string
+	config      any
+	bus         *bus.MessageBus
+	running     bool
+	name        string
+	allowList   []string
+	accountID   string
+	transcriber voice.Transcriber
 }
 
 func NewBaseChannel(name string, config any, bus *bus.MessageBus, allowList []string) *BaseChannel {
@@ -45,6 +110,18 @@ func (c *BaseChannel) Name() string {
 	return c.name
 }
 
+// StatusMode returns what the channel shows while the agent works, one of
+// config.StatusOff, config.StatusTyping and config.StatusVerbose.
+func (c *BaseChannel) StatusMode() string {
+	if sc, ok := c.config.(interface{ StatusMode() string }); ok {
+		switch mode := sc.StatusMode(); mode {
+		case config.StatusOff, config.StatusVerbose:
+			return mode
+		}
+	}
+	return config.StatusTyping
+}
+
 func (c *BaseChannel) IsRunning() bool {
 	return c.running
 }
@@ -93,18 +170,149 @@ func (c *BaseChannel) HandleMessage(senderID, chatID, content string, media []st
 		return
 	}
 
+	if c.accountID != "" {
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
+		metadata["account_id"] = c.accountID
+	}
+
 	msg := bus.InboundMessage{
 		Channel:  c.name,
 		SenderID: senderID,
 		ChatID:   chatID,
 		Content:  content,
-		Media:    media,
+		Media:    utils.RetainMedia(media),
 		Metadata: metadata,
 	}
 
 	c.bus.PublishInbound(msg)
 }
 
+// setAccount makes the channel an instance of one of its type's accounts:
+// its name becomes "type/account" and its messages carry account_id.
+func (c *BaseChannel) setAccount(accountID string) {
+	c.name = AccountChannelName(c.name, accountID)
+	c.accountID = accountID
+}
+
+// AccountChannelName names the instance of a channel type running accountID.
+func AccountChannelName(channelType, accountID string) string {
+	return channelType + "/" + accountID
+}
+
+// ChannelType returns the type of the channel instance name, e.g. "telegram"
+// for "telegram/brand-b".
+func ChannelType(name string) string {
+	channelType, _, _ := strings.Cut(name, "/")
+	return channelType
+}
+
+// SetTranscriber sets the backend used to transcribe voice messages.
+func (c *BaseChannel) SetTranscriber(transcriber voice.Transcriber) {
+	c.transcriber = transcriber
+}
+
+// canTranscribe reports whether voice messages can be turned into text.
+func (c *BaseChannel) canTranscribe() bool {
+	return c.transcriber != nil && c.transcriber.IsAvailable()
+}
+
+// transcribe turns the audio file at path into text.
+func (c *BaseChannel) transcribe(ctx context.Context, path string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, transcriptionTimeout)
+	defer cancel()
+	result, err := c.transcriber.Transcribe(ctx, path)
+	if err != nil {
+		logger.ErrorCF(c.name, "Voice transcription failed", map[string]any{
+			"error": err.Error(),
+			"path":  path,
+		})
+		return "", err
+	}
+	return result.Text, nil
+}
+
+// voiceText describes a voice message for the agent: its transcription,
+// or a placeholder when there is no transcriber or transcription fails.
+func (c *BaseChannel) voiceText(ctx context.Context, path string) string {
+	if !c.canTranscribe() {
+		return "[voice]"
+	}
+	text, err := c.transcribe(ctx, path)
+	if err != nil {
+		return "[voice (transcription failed)]"
+	}
+	return fmt.Sprintf("[voice transcription: %s]", text)
+}
+
+// removeTempFiles deletes media a channel downloaded for one message once
+// the message has been handed off.
+func removeTempFiles(channel string, files []string) {
+	for _, file := range files {
+		if err := os.Remove(file); err != nil {
+			logger.DebugCF(channel, "Failed to cleanup temp file", map[string]any{
+				"file":  file,
+				"error": err.Error(),
+			})
+		}
+	}
+}
+
+// firstNonEmpty returns the first value that is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// jsonLookup follows a dotted path such as "data.text" or "alerts.0.labels"
+// through a decoded JSON document; numbers index arrays.
+func jsonLookup(doc any, path string) (any, bool) {
+	if path == "" {
+		return nil, false
+	}
+	v := doc
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			var ok bool
+			if v, ok = node[key]; !ok {
+				return nil, false
+			}
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, v != nil
+}
+
+// jsonField returns the value at path as text: strings as they are, other
+// values as JSON.
+func jsonField(doc any, path string) (string, bool) {
+	v, ok := jsonLookup(doc, path)
+	if !ok {
+		return "", false
+	}
+	if s, ok := v.(string); ok {
+		return s, true
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
 func (c *BaseChannel) setRunning(running bool) {
 	c.running = running
 }
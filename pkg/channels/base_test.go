@@ -1,6 +1,12 @@
 package channels
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Agentx-network/agentx/pkg/voice"
+)
 
 func TestBaseChannelIsAllowed(t *testing.T) {
 	tests := []struct {
@@ -50,3 +56,36 @@ func TestBaseChannelIsAllowed(t *testing.T) {
 		})
 	}
 }
+
+type fakeTranscriber struct {
+	text string
+	err  error
+}
+
+func (f *fakeTranscriber) Transcribe(ctx context.Context, path string) (*voice.TranscriptionResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &voice.TranscriptionResponse{Text: f.text}, nil
+}
+
+func (f *fakeTranscriber) IsAvailable() bool { return true }
+
+func TestBaseChannelVoiceText(t *testing.T) {
+	ch := NewBaseChannel("test", nil, nil, nil)
+	ctx := context.Background()
+
+	if got := ch.voiceText(ctx, "voice.ogg"); got != "[voice]" {
+		t.Errorf("without a transcriber: %q", got)
+	}
+
+	ch.SetTranscriber(&fakeTranscriber{text: "call mom"})
+	if got := ch.voiceText(ctx, "voice.ogg"); got != "[voice transcription: call mom]" {
+		t.Errorf("transcribed: %q", got)
+	}
+
+	ch.SetTranscriber(&fakeTranscriber{err: errors.New("boom")})
+	if got := ch.voiceText(ctx, "voice.ogg"); got != "[voice (transcription failed)]" {
+		t.Errorf("failed: %q", got)
+	}
+}
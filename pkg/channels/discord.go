@@ -1,11 +1,11 @@
 package channels
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -18,19 +18,25 @@ import (
 )
 
 const (
-	transcriptionTimeout = 30 * time.Second
-	sendTimeout          = 10 * time.Second
+	sendTimeout   = 10 * time.Second
+	uploadTimeout = 2 * time.Minute
+
+	// discordMaxLength is Discord's limit for a message's content.
+	discordMaxLength = 2000
+	// discordStreamFlush spaces out edits; Discord allows five per five
+	// seconds in a channel.
+	discordStreamFlush = time.Second
 )
 
 type DiscordChannel struct {
 	*BaseChannel
-	session     *discordgo.Session
-	config      config.DiscordConfig
-	transcriber *voice.GroqTranscriber
-	ctx         context.Context
-	typingMu    sync.Mutex
-	typingStop  map[string]chan struct{} // chatID → stop signal
-	botUserID   string                   // stored for mention checking
+	session   *discordgo.Session
+	config    config.DiscordConfig
+	ctx       context.Context
+	botUserID string // stored for mention checking
+	stream    *streamRenderer
+
+	buttonReplies buttonReplyStore // button custom ID → reply text
 }
 
 func NewDiscordChannel(cfg config.DiscordConfig, bus *bus.MessageBus) (*DiscordChannel, error) {
@@ -41,18 +47,49 @@ func NewDiscordChannel(cfg config.DiscordConfig, bus *bus.MessageBus) (*DiscordC
 
 	base := NewBaseChannel("discord", cfg, bus, cfg.AllowFrom)
 
-	return &DiscordChannel{
+	c := &DiscordChannel{
 		BaseChannel: base,
 		session:     session,
 		config:      cfg,
-		transcriber: nil,
 		ctx:         context.Background(),
-		typingStop:  make(map[string]chan struct{}),
-	}, nil
+	}
+	c.stream = &streamRenderer{
+		name:     "discord",
+		interval: discordStreamFlush,
+		maxLen:   discordMaxLength,
+		post: func(ctx context.Context, channelID, text string) (string, error) {
+			m, err := session.ChannelMessageSend(channelID, text, discordgo.WithContext(ctx))
+			if err != nil {
+				return "", err
+			}
+			return m.ID, nil
+		},
+		edit: func(ctx context.Context, channelID, messageID, text string) error {
+			_, err := session.ChannelMessageEdit(channelID, messageID, text, discordgo.WithContext(ctx))
+			return err
+		},
+		remove: func(ctx context.Context, channelID, messageID string) error {
+			return session.ChannelMessageDelete(channelID, messageID, discordgo.WithContext(ctx))
+		},
+	}
+	return c, nil
+}
+
+// StartStreamConsumer starts a goroutine that shows stream deltas by
+// sending messages to the channel and then editing them.
+func (c *DiscordChannel) StartStreamConsumer(ctx context.Context) {
+	go c.stream.run(ctx)
+}
+
+// HandleStreamDelta processes a single stream delta event.
+func (c *DiscordChannel) HandleStreamDelta(delta bus.StreamDelta) {
+	c.stream.add(delta)
 }
 
-func (c *DiscordChannel) SetTranscriber(transcriber *voice.GroqTranscriber) {
-	c.transcriber = transcriber
+// VoiceFormat reports that replies can be sent as Ogg audio, which Discord
+// plays inline.
+func (c *DiscordChannel) VoiceFormat() string {
+	return voice.FormatOgg
 }
 
 func (c *DiscordChannel) getContext() context.Context {
@@ -75,6 +112,7 @@ func (c *DiscordChannel) Start(ctx context.Context) error {
 	c.botUserID = botUser.ID
 
 	c.session.AddHandler(c.handleMessage)
+	c.session.AddHandler(c.handleInteraction)
 
 	if err := c.session.Open(); err != nil {
 		return fmt.Errorf("failed to open discord session: %w", err)
@@ -94,14 +132,6 @@ func (c *DiscordChannel) Stop(ctx context.Context) error {
 	logger.InfoC("discord", "Stopping Discord bot")
 	c.setRunning(false)
 
-	// Stop all typing goroutines before closing session
-	c.typingMu.Lock()
-	for chatID, stop := range c.typingStop {
-		close(stop)
-		delete(c.typingStop, chatID)
-	}
-	c.typingMu.Unlock()
-
 	if err := c.session.Close(); err != nil {
 		return fmt.Errorf("failed to close discord session: %w", err)
 	}
@@ -110,8 +140,6 @@ func (c *DiscordChannel) Stop(ctx context.Context) error {
 }
 
 func (c *DiscordChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
-	c.stopTyping(msg.ChatID)
-
 	if !c.IsRunning() {
 		return fmt.Errorf("discord bot not running")
 	}
@@ -121,15 +149,49 @@ func (c *DiscordChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 		return fmt.Errorf("channel ID is empty")
 	}
 
-	runes := []rune(msg.Content)
-	if len(runes) == 0 {
+	if strings.TrimSpace(msg.Content) == "" && len(msg.Attachments) == 0 {
+		// Nothing to show, so the streamed messages are left as they are.
+		c.stream.take(channelID)
 		return nil
 	}
 
-	chunks := utils.SplitMessage(msg.Content, 2000) // Split messages into chunks, Discord length limit: 2000 chars
+	// Files the API rejects or cannot fetch are linked in the text instead.
+	files, unsent := c.discordFiles(ctx, msg.Attachments)
+	content := appendFallbackText(msg.Content, unsent, nil)
+
+	chunks := utils.SplitMessage(content, discordMaxLength)
+	if len(chunks) == 0 {
+		chunks = []string{""}
+	}
+
+	// The reply takes the place of the streamed messages; one without
+	// text, such as a voice note, removes them. An edit cannot add a reply
+	// reference, files or buttons, so the message carrying them is sent
+	// anew.
+	switch last := len(chunks) - 1; {
+	case msg.ReplyTo != "" || strings.TrimSpace(content) == "":
+		c.stream.finish(ctx, channelID, nil)
+	case len(files) > 0 || len(msg.Buttons) > 0:
+		chunks = append(c.stream.finish(ctx, channelID, chunks[:last]), chunks[last])
+	default:
+		chunks = c.stream.finish(ctx, channelID, chunks)
+	}
 
-	for _, chunk := range chunks {
-		if err := c.sendChunk(ctx, channelID, chunk); err != nil {
+	for i, chunk := range chunks {
+		send := &discordgo.MessageSend{Content: chunk}
+		if i == 0 && msg.ReplyTo != "" {
+			failIfNotExists := false
+			send.Reference = &discordgo.MessageReference{
+				MessageID:       msg.ReplyTo,
+				ChannelID:       channelID,
+				FailIfNotExists: &failIfNotExists,
+			}
+		}
+		if i == len(chunks)-1 {
+			send.Files = files
+			send.Components = c.discordComponents(msg.Buttons)
+		}
+		if err := c.sendChunk(ctx, channelID, send); err != nil {
 			return err
 		}
 	}
@@ -137,14 +199,18 @@ func (c *DiscordChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 	return nil
 }
 
-func (c *DiscordChannel) sendChunk(ctx context.Context, channelID, content string) error {
+func (c *DiscordChannel) sendChunk(ctx context.Context, channelID string, send *discordgo.MessageSend) error {
 	// Use the passed ctx for timeout control
-	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	timeout := sendTimeout
+	if len(send.Files) > 0 {
+		timeout = uploadTimeout
+	}
+	sendCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	done := make(chan error, 1)
 	go func() {
-		_, err := c.session.ChannelMessageSend(channelID, content)
+		_, err := c.session.ChannelMessageSendComplex(channelID, send)
 		done <- err
 	}()
 
@@ -159,6 +225,111 @@ func (c *DiscordChannel) sendChunk(ctx context.Context, channelID, content strin
 	}
 }
 
+// discordMaxFiles is the number of files Discord accepts per message.
+const discordMaxFiles = 10
+
+// discordFiles loads attachments for upload. It returns the ones that could
+// not be loaded (or exceed the per-message limit) separately so they can be
+// described in the text.
+func (c *DiscordChannel) discordFiles(ctx context.Context, attachments []bus.Attachment) ([]*discordgo.File, []bus.Attachment) {
+	var files []*discordgo.File
+	var unsent []bus.Attachment
+	for _, a := range attachments {
+		if len(files) == discordMaxFiles {
+			unsent = append(unsent, a)
+			continue
+		}
+		data, err := readAttachment(ctx, a)
+		if err != nil {
+			logger.WarnCF("discord", "Failed to load attachment", map[string]any{
+				"attachment": a.Name(),
+				"error":      err.Error(),
+			})
+			unsent = append(unsent, a)
+			continue
+		}
+		files = append(files, &discordgo.File{
+			Name:        a.Name(),
+			ContentType: a.ContentType(),
+			Reader:      bytes.NewReader(data),
+		})
+	}
+	return files, unsent
+}
+
+// discordButtonPrefix marks the custom IDs of buttons sent by the agent.
+const discordButtonPrefix = "agentx:btn:"
+
+// discordComponents lays buttons out in action rows of five, Discord's
+// per-row maximum. Button replies are kept in memory and looked up by
+// custom ID when pressed; those of old messages are dropped.
+func (c *DiscordChannel) discordComponents(buttons []bus.Button) []discordgo.MessageComponent {
+	var rows []discordgo.MessageComponent
+	var row discordgo.ActionsRow
+	for _, b := range buttons {
+		btn := discordgo.Button{Label: b.Text, Style: discordgo.PrimaryButton}
+		if b.URL != "" {
+			btn.Style = discordgo.LinkButton
+			btn.URL = b.URL
+		} else {
+			btn.CustomID = c.buttonReplies.store(discordButtonPrefix, b.Reply())
+		}
+		row.Components = append(row.Components, btn)
+		if len(row.Components) == 5 {
+			rows = append(rows, row)
+			row = discordgo.ActionsRow{}
+		}
+	}
+	if len(row.Components) > 0 {
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// handleInteraction turns a press of one of our buttons into a user message
+// carrying the button's reply text.
+func (c *DiscordChannel) handleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionMessageComponent {
+		return
+	}
+	reply, ok := c.buttonReplies.load(i.MessageComponentData().CustomID)
+	if !ok {
+		return
+	}
+
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	}); err != nil {
+		logger.DebugCF("discord", "Failed to acknowledge interaction", map[string]any{"error": err.Error()})
+	}
+
+	user := i.User
+	if i.Member != nil && i.Member.User != nil {
+		user = i.Member.User
+	}
+	if user == nil {
+		return
+	}
+
+	peerKind := "channel"
+	peerID := i.ChannelID
+	if i.GuildID == "" {
+		peerKind = "direct"
+		peerID = user.ID
+	}
+
+	c.HandleMessage(user.ID, i.ChannelID, reply, nil, map[string]string{
+		"user_id":    user.ID,
+		"username":   user.Username,
+		"guild_id":   i.GuildID,
+		"channel_id": i.ChannelID,
+		"is_dm":      fmt.Sprintf("%t", i.GuildID == ""),
+		"peer_kind":  peerKind,
+		"peer_id":    peerID,
+		"button":     "true",
+	})
+}
+
 // appendContent safely appends content to existing text
 func appendContent(content, suffix string) string {
 	if content == "" {
@@ -234,21 +405,11 @@ func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.Messag
 				localFiles = append(localFiles, localPath)
 
 				var transcribedText string
-				if c.transcriber != nil && c.transcriber.IsAvailable() {
-					ctx, cancel := context.WithTimeout(c.getContext(), transcriptionTimeout)
-					result, err := c.transcriber.Transcribe(ctx, localPath)
-					cancel() // Release context resources immediately to avoid leaks in for loop
-
-					if err != nil {
-						logger.ErrorCF("discord", "Voice transcription failed", map[string]any{
-							"error": err.Error(),
-						})
+				if c.canTranscribe() {
+					if text, err := c.transcribe(c.getContext(), localPath); err != nil {
 						transcribedText = fmt.Sprintf("[audio: %s (transcription failed)]", attachment.Filename)
 					} else {
-						transcribedText = fmt.Sprintf("[audio transcription: %s]", result.Text)
-						logger.DebugCF("discord", "Audio transcribed successfully", map[string]any{
-							"text": result.Text,
-						})
+						transcribedText = fmt.Sprintf("[audio transcription: %s]", text)
 					}
 				} else {
 					transcribedText = fmt.Sprintf("[audio: %s]", attachment.Filename)
@@ -263,6 +424,16 @@ func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.Messag
 				mediaPaths = append(mediaPaths, attachment.URL)
 				content = appendContent(content, fmt.Sprintf("[attachment: %s]", attachment.URL))
 			}
+		} else if strings.HasPrefix(attachment.ContentType, "image/") {
+			// Download images so vision models receive the pixels, not a URL.
+			if localPath := c.downloadAttachment(attachment.URL, attachment.Filename); localPath != "" {
+				localFiles = append(localFiles, localPath)
+				mediaPaths = append(mediaPaths, localPath)
+				content = appendContent(content, fmt.Sprintf("[image: %s]", attachment.Filename))
+			} else {
+				mediaPaths = append(mediaPaths, attachment.URL)
+				content = appendContent(content, fmt.Sprintf("[attachment: %s]", attachment.URL))
+			}
 		} else {
 			mediaPaths = append(mediaPaths, attachment.URL)
 			content = appendContent(content, fmt.Sprintf("[attachment: %s]", attachment.URL))
@@ -277,9 +448,6 @@ func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.Messag
 		content = "[media only]"
 	}
 
-	// Start typing after all early returns — guaranteed to have a matching Send()
-	c.startTyping(m.ChannelID)
-
 	logger.DebugCF("discord", "Received message", map[string]any{
 		"sender_name": senderName,
 		"sender_id":   senderID,
@@ -308,50 +476,20 @@ func (c *DiscordChannel) handleMessage(s *discordgo.Session, m *discordgo.Messag
 	c.HandleMessage(senderID, m.ChannelID, content, mediaPaths, metadata)
 }
 
-// startTyping starts a continuous typing indicator loop for the given chatID.
-// It stops any existing typing loop for that chatID before starting a new one.
-func (c *DiscordChannel) startTyping(chatID string) {
-	c.typingMu.Lock()
-	// Stop existing loop for this chatID if any
-	if stop, ok := c.typingStop[chatID]; ok {
-		close(stop)
-	}
-	stop := make(chan struct{})
-	c.typingStop[chatID] = stop
-	c.typingMu.Unlock()
+// SendTyping shows the typing indicator in a channel for ten seconds, or
+// until the bot sends a message there.
+func (c *DiscordChannel) SendTyping(ctx context.Context, chatID string) error {
+	return c.session.ChannelTyping(chatID, discordgo.WithContext(ctx))
+}
 
-	go func() {
-		if err := c.session.ChannelTyping(chatID); err != nil {
-			logger.DebugCF("discord", "ChannelTyping error", map[string]any{"chatID": chatID, "err": err})
-		}
-		ticker := time.NewTicker(8 * time.Second)
-		defer ticker.Stop()
-		timeout := time.After(5 * time.Minute)
-		for {
-			select {
-			case <-stop:
-				return
-			case <-timeout:
-				return
-			case <-c.ctx.Done():
-				return
-			case <-ticker.C:
-				if err := c.session.ChannelTyping(chatID); err != nil {
-					logger.DebugCF("discord", "ChannelTyping error", map[string]any{"chatID": chatID, "err": err})
-				}
-			}
-		}
-	}()
+// ShowStatus shows what the agent is doing in a message of its own.
+func (c *DiscordChannel) ShowStatus(ctx context.Context, chatID, status string) error {
+	return c.stream.showStatus(ctx, chatID, status)
 }
 
-// stopTyping stops the typing indicator loop for the given chatID.
-func (c *DiscordChannel) stopTyping(chatID string) {
-	c.typingMu.Lock()
-	defer c.typingMu.Unlock()
-	if stop, ok := c.typingStop[chatID]; ok {
-		close(stop)
-		delete(c.typingStop, chatID)
-	}
+// ClearStatus removes the status message.
+func (c *DiscordChannel) ClearStatus(ctx context.Context, chatID string) error {
+	return c.stream.clearStatus(ctx, chatID)
 }
 
 func (c *DiscordChannel) downloadAttachment(url, filename string) string {
@@ -0,0 +1,933 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/config"
+	"github.com/Agentx-network/agentx/pkg/logger"
+	"github.com/Agentx-network/agentx/pkg/utils"
+)
+
+const (
+	// emailIdleTimeout restarts IDLE before servers drop idle clients,
+	// which RFC 2177 allows after 30 minutes.
+	emailIdleTimeout      = 25 * time.Minute
+	emailReconnectDelay   = 30 * time.Second
+	emailMaxBodyChars     = 20000
+	emailDefaultSubject   = "Message from your agent"
+	emailMaxSubjectLength = 78
+	// emailMaxThreads bounds the threads kept in memory for replies; older
+	// ones are looked up again in the mailbox.
+	emailMaxThreads = 1000
+)
+
+// EmailChannel reads mail from an IMAP mailbox and replies over SMTP. Each
+// email thread, found through Message-ID, In-Reply-To and References, is
+// its own conversation; the chat ID is the Message-ID that started it.
+type EmailChannel struct {
+	*BaseChannel
+	config    config.EmailConfig
+	address   string
+	cancel    context.CancelFunc
+	threadsMu sync.Mutex
+	threads   map[string]*emailThread // chat ID -> thread
+	// send delivers a composed message; it is replaced in tests.
+	send func(from string, to []string, msg []byte) error
+}
+
+// emailThread is what a reply needs to stay in its thread.
+type emailThread struct {
+	mu         sync.Mutex
+	to         string
+	subject    string
+	lastID     string
+	references []string
+	used       time.Time // for eviction, guarded by threadsMu
+}
+
+// emailFile is an attachment of an incoming or outgoing mail.
+type emailFile struct {
+	name        string
+	contentType string
+	data        []byte
+}
+
+// emailContent is the readable part of a parsed mail.
+type emailContent struct {
+	text  string
+	html  string
+	files []emailFile
+}
+
+func NewEmailChannel(cfg config.EmailConfig, messageBus *bus.MessageBus) (*EmailChannel, error) {
+	if cfg.IMAPHost == "" || cfg.SMTPHost == "" {
+		return nil, fmt.Errorf("email imap_host and smtp_host are required")
+	}
+	address := strings.ToLower(strings.TrimSpace(cfg.Address))
+	if address == "" {
+		address = strings.ToLower(strings.TrimSpace(cfg.Username))
+	}
+	if !strings.Contains(address, "@") {
+		return nil, fmt.Errorf("email address is required (set address or use the full address as username)")
+	}
+	if cfg.IMAPPort == 0 {
+		cfg.IMAPPort = 993
+	}
+	if cfg.SMTPPort == 0 {
+		cfg.SMTPPort = 587
+	}
+	if cfg.Mailbox == "" {
+		cfg.Mailbox = "INBOX"
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 60
+	}
+
+	// The allow list holds domains as well as addresses, so the channel
+	// checks senders itself rather than through BaseChannel.
+	c := &EmailChannel{
+		BaseChannel: NewBaseChannel("email", cfg, messageBus, nil),
+		config:      cfg,
+		address:     address,
+		threads:     make(map[string]*emailThread),
+	}
+	c.send = c.sendSMTP
+	return c, nil
+}
+
+func (c *EmailChannel) Start(ctx context.Context) error {
+	logger.InfoCF("email", "Starting email channel", map[string]any{
+		"imap_host": c.config.IMAPHost,
+		"mailbox":   c.config.Mailbox,
+		"address":   c.address,
+	})
+
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.setRunning(true)
+	go c.run(ctx)
+	return nil
+}
+
+func (c *EmailChannel) Stop(ctx context.Context) error {
+	logger.InfoC("email", "Stopping email channel")
+	c.setRunning(false)
+	if c.cancel != nil {
+		c.cancel()
+	}
+	return nil
+}
+
+// IsAllowed matches the sender address against allow_from, which takes
+// full addresses and domains ("example.com" or "@example.com"). It only
+// looks at the address; handleRaw also checks that the sender is verified.
+func (c *EmailChannel) IsAllowed(senderID string) bool {
+	if len(c.config.AllowFrom) == 0 {
+		return true
+	}
+	sender := strings.ToLower(strings.TrimSpace(senderID))
+	_, domain, _ := strings.Cut(sender, "@")
+	for _, allowed := range c.config.AllowFrom {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == "" {
+			continue
+		}
+		if allowed == sender || strings.TrimPrefix(allowed, "@") == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// run keeps an IMAP session open, reconnecting after errors.
+func (c *EmailChannel) run(ctx context.Context) {
+	for {
+		err := c.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		logger.WarnCF("email", "IMAP session ended, reconnecting", map[string]any{
+			"error": fmt.Sprint(err),
+			"delay": emailReconnectDelay.String(),
+		})
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(emailReconnectDelay):
+		}
+	}
+}
+
+// session logs in, then handles unseen mail each time the server reports
+// new mail (IDLE) or the poll interval passes.
+func (c *EmailChannel) session(ctx context.Context) error {
+	client, err := c.openMailbox(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	logger.InfoCF("email", "Connected to IMAP mailbox", map[string]any{
+		"mailbox": c.config.Mailbox,
+		"idle":    client.caps["IDLE"],
+	})
+
+	poll := time.Duration(c.config.PollInterval) * time.Second
+	for {
+		if err := c.fetchUnseen(ctx, client); err != nil {
+			return err
+		}
+		if client.caps["IDLE"] {
+			if err := client.Idle(ctx, emailIdleTimeout); err != nil {
+				return err
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			client.Logout()
+			return ctx.Err()
+		case <-time.After(poll):
+		}
+	}
+}
+
+// openMailbox connects to the IMAP server, logs in and selects the mailbox.
+func (c *EmailChannel) openMailbox(ctx context.Context) (*imapClient, error) {
+	client, err := dialIMAP(ctx, c.config.IMAPHost, c.config.IMAPPort, c.config.IMAPInsecure)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Login(c.config.Username, c.config.Password); err != nil {
+		client.Close()
+		return nil, err
+	}
+	if err := client.Select(c.config.Mailbox); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// fetchUnseen hands every unseen message to the agent and marks it seen.
+func (c *EmailChannel) fetchUnseen(ctx context.Context, client *imapClient) error {
+	uids, err := client.SearchUnseen()
+	if err != nil {
+		return err
+	}
+	for _, uid := range uids {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		raw, err := client.Fetch(uid)
+		if err != nil {
+			return err
+		}
+		c.handleRaw(ctx, raw)
+		if err := client.MarkSeen(uid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleRaw parses one RFC 5322 message and publishes it.
+func (c *EmailChannel) handleRaw(ctx context.Context, raw []byte) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		logger.WarnCF("email", "Failed to parse email", map[string]any{"error": err.Error()})
+		return
+	}
+
+	from, err := mail.ParseAddress(msg.Header.Get("From"))
+	if err != nil {
+		logger.WarnCF("email", "Email without a valid sender", map[string]any{"from": msg.Header.Get("From")})
+		return
+	}
+	sender := strings.ToLower(from.Address)
+	if sender == c.address || isAutomatedEmail(msg.Header) {
+		return
+	}
+	if !c.IsAllowed(sender) {
+		logger.DebugCF("email", "Email from sender not in allow list", map[string]any{"sender": sender})
+		return
+	}
+	// The From header is set by the sender, so with an allow list the
+	// receiving server must have verified it.
+	if len(c.config.AllowFrom) > 0 && !c.config.AllowUnauthenticated &&
+		!emailSenderVerified(msg.Header, c.config.AuthServID, sender) {
+		logger.WarnCF("email", "Email sender not verified by DKIM or DMARC", map[string]any{
+			"sender":                 sender,
+			"authentication_results": msg.Header.Get("Authentication-Results"),
+		})
+		return
+	}
+
+	var content emailContent
+	if err := parseEmailPart(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), "", msg.Body, &content); err != nil {
+		logger.WarnCF("email", "Failed to parse email body", map[string]any{"error": err.Error()})
+	}
+
+	subject := decodeHeader(msg.Header.Get("Subject"))
+	messageID := firstMessageID(msg.Header.Get("Message-Id"))
+	inReplyTo := firstMessageID(msg.Header.Get("In-Reply-To"))
+	references := messageIDs(msg.Header.Get("References"))
+	if messageID == "" {
+		messageID = newMessageID(c.address)
+	}
+	chatID := emailThreadID(messageID, inReplyTo, references)
+
+	body := emailBodyText(content)
+	if inReplyTo == "" && subject != "" {
+		body = appendContent("Subject: "+subject, body)
+	}
+
+	var mediaPaths []string
+	for _, file := range content.files {
+		path, err := saveEmailFile(file)
+		if err != nil {
+			logger.WarnCF("email", "Failed to save attachment", map[string]any{
+				"file":  file.name,
+				"error": err.Error(),
+			})
+			continue
+		}
+		mediaPaths = append(mediaPaths, path)
+		if utils.IsAudioFile(file.name, file.contentType) {
+			body = appendContent(body, c.voiceText(ctx, path))
+		} else {
+			body = appendContent(body, fmt.Sprintf("[file: %s]", file.name))
+		}
+	}
+	defer removeTempFiles("email", mediaPaths)
+
+	if strings.TrimSpace(body) == "" {
+		body = "[empty email]"
+	}
+
+	c.rememberThread(chatID, sender, subject, messageID, references)
+
+	metadata := map[string]string{
+		"message_id": messageID,
+		"subject":    subject,
+		"from_name":  from.Name,
+		"peer_kind":  "thread",
+		"peer_id":    strings.ToLower(strings.Trim(chatID, "<>")),
+	}
+
+	logger.DebugCF("email", "Received email", map[string]any{
+		"sender":  sender,
+		"chat_id": chatID,
+		"subject": subject,
+		"files":   len(mediaPaths),
+	})
+
+	c.HandleMessage(sender, chatID, body, mediaPaths, metadata)
+}
+
+// rememberThread records the latest message of a thread, for replies.
+func (c *EmailChannel) rememberThread(chatID, sender, subject, messageID string, references []string) {
+	t := c.storeThread(chatID, &emailThread{})
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.to = sender
+	if subject != "" {
+		t.subject = subject
+	}
+	t.lastID = messageID
+	t.references = appendUnique(references, messageID)
+}
+
+// storeThread returns the thread for chatID, adding t when there is none
+// and evicting the least recently used thread when the map is full.
+func (c *EmailChannel) storeThread(chatID string, t *emailThread) *emailThread {
+	c.threadsMu.Lock()
+	defer c.threadsMu.Unlock()
+	if existing, ok := c.threads[chatID]; ok {
+		existing.used = time.Now()
+		return existing
+	}
+	if len(c.threads) >= emailMaxThreads {
+		var oldest string
+		var oldestUsed time.Time
+		for id, other := range c.threads {
+			if oldest == "" || other.used.Before(oldestUsed) {
+				oldest, oldestUsed = id, other.used
+			}
+		}
+		delete(c.threads, oldest)
+	}
+	t.used = time.Now()
+	c.threads[chatID] = t
+	return t
+}
+
+// thread returns what a reply to chatID needs. Threads not in memory, after
+// a restart or an eviction, are rebuilt from the latest message in the
+// mailbox that has chatID as its Message-ID or in its References.
+func (c *EmailChannel) thread(ctx context.Context, chatID string) (*emailThread, error) {
+	c.threadsMu.Lock()
+	t, ok := c.threads[chatID]
+	if ok {
+		t.used = time.Now()
+	}
+	c.threadsMu.Unlock()
+	if ok {
+		return t, nil
+	}
+
+	client, err := c.openMailbox(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("looking up email thread %s: %w", chatID, err)
+	}
+	defer client.Close()
+	uids, err := client.SearchThread(chatID)
+	if err != nil {
+		return nil, fmt.Errorf("looking up email thread %s: %w", chatID, err)
+	}
+	if len(uids) == 0 {
+		return nil, fmt.Errorf("unknown email thread %s", chatID)
+	}
+	raw, err := client.Fetch(slices.Max(uids))
+	client.Logout()
+	if err != nil {
+		return nil, fmt.Errorf("looking up email thread %s: %w", chatID, err)
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("parsing email thread %s: %w", chatID, err)
+	}
+
+	// The latest message may be one of ours when the mailbox keeps sent
+	// mail; the reply then goes to its recipient.
+	header := "From"
+	if from, err := mail.ParseAddress(msg.Header.Get("From")); err == nil && strings.EqualFold(from.Address, c.address) {
+		header = "To"
+	}
+	to, err := mail.ParseAddress(msg.Header.Get(header))
+	if err != nil {
+		return nil, fmt.Errorf("email thread %s has no valid %s address", chatID, header)
+	}
+	messageID := firstMessageID(msg.Header.Get("Message-Id"))
+	t = &emailThread{
+		to:         strings.ToLower(to.Address),
+		subject:    decodeHeader(msg.Header.Get("Subject")),
+		lastID:     messageID,
+		references: appendUnique(messageIDs(msg.Header.Get("References")), messageID),
+	}
+	return c.storeThread(chatID, t), nil
+}
+
+func (c *EmailChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("email channel not running")
+	}
+	msg = withReasoningText(msg)
+
+	var to, subject, inReplyTo string
+	var references []string
+	var thread *emailThread
+	if strings.HasPrefix(msg.ChatID, "<") {
+		var err error
+		if thread, err = c.thread(ctx, msg.ChatID); err != nil {
+			return err
+		}
+		thread.mu.Lock()
+		to, inReplyTo = thread.to, thread.lastID
+		subject = replySubject(thread.subject)
+		references = append([]string(nil), thread.references...)
+		thread.mu.Unlock()
+	} else {
+		// A bare address starts a new thread, e.g. for cron messages.
+		to = msg.ChatID
+		subject = newSubject(msg.Content)
+	}
+
+	body := appendFallbackText(msg.Content, nil, msg.Buttons)
+	var files []emailFile
+	for _, a := range msg.Attachments {
+		data, err := readAttachment(ctx, a)
+		if err != nil {
+			logger.WarnCF("email", "Failed to read attachment, sending it as text", map[string]any{
+				"file":  a.Name(),
+				"error": err.Error(),
+			})
+			body = appendContent(body, attachmentText([]bus.Attachment{a}))
+			continue
+		}
+		files = append(files, emailFile{name: a.Name(), contentType: a.ContentType(), data: data})
+	}
+
+	messageID := newMessageID(c.address)
+	raw, err := composeEmail(c.address, to, subject, messageID, inReplyTo, references, body, files)
+	if err != nil {
+		return err
+	}
+	if err := c.send(c.address, []string{to}, raw); err != nil {
+		return fmt.Errorf("sending email: %w", err)
+	}
+
+	if thread != nil {
+		thread.mu.Lock()
+		thread.lastID = messageID
+		thread.references = appendUnique(thread.references, messageID)
+		thread.mu.Unlock()
+	}
+	return nil
+}
+
+// sendSMTP delivers a message through the configured SMTP server: implicit
+// TLS on port 465, STARTTLS otherwise.
+func (c *EmailChannel) sendSMTP(from string, to []string, msg []byte) error {
+	host := c.config.SMTPHost
+	addr := net.JoinHostPort(host, strconv.Itoa(c.config.SMTPPort))
+	var auth smtp.Auth
+	if c.config.Username != "" {
+		auth = smtp.PlainAuth("", c.config.Username, c.config.Password, host)
+	}
+	if c.config.SMTPPort != 465 {
+		return smtp.SendMail(addr, auth, from, to, msg)
+	}
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: imapTimeout}, "tcp", addr, &tls.Config{ServerName: host})
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// composeEmail builds a plain-text mail, multipart/mixed when it carries
+// files. inReplyTo and references keep a reply in its thread.
+func composeEmail(from, to, subject, messageID, inReplyTo string, references []string, body string, files []emailFile) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+	header("From", (&mail.Address{Address: from}).String())
+	header("To", (&mail.Address{Address: to}).String())
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", messageID)
+	if inReplyTo != "" {
+		header("In-Reply-To", inReplyTo)
+	}
+	if len(references) > 0 {
+		header("References", strings.Join(references, " "))
+	}
+	header("Auto-Submitted", "auto-replied")
+	header("MIME-Version", "1.0")
+
+	if len(files) == 0 {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		return buf.Bytes(), writeQuotedPrintable(&buf, body)
+	}
+
+	mw := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/mixed; boundary="+mw.Boundary())
+	buf.WriteString("\r\n")
+
+	textPart, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeQuotedPrintable(textPart, body); err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		contentType := f.contentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(contentType, map[string]string{"name": f.name})},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": f.name})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoded := base64.StdEncoding.EncodeToString(f.data)
+		for len(encoded) > 76 {
+			io.WriteString(part, encoded[:76]+"\r\n")
+			encoded = encoded[76:]
+		}
+		io.WriteString(part, encoded+"\r\n")
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, text string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := io.WriteString(qp, text); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// parseEmailPart walks a MIME part, collecting its text, HTML and files.
+func parseEmailPart(contentType, encoding, disposition string, body io.Reader, out *emailContent) error {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			err = parseEmailPart(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"),
+				part.Header.Get("Content-Disposition"), part, out)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(decodeTransfer(encoding, body), maxAttachmentBytes+1))
+	if err != nil {
+		return err
+	}
+
+	dispType, dispParams, _ := mime.ParseMediaType(disposition)
+	filename := decodeHeader(firstNonEmpty(dispParams["filename"], params["name"]))
+	if dispType == "attachment" || filename != "" {
+		if len(data) > maxAttachmentBytes {
+			return nil
+		}
+		if filename == "" {
+			filename = "attachment"
+		}
+		out.files = append(out.files, emailFile{name: filename, contentType: mediaType, data: data})
+		return nil
+	}
+
+	switch mediaType {
+	case "text/plain":
+		if out.text == "" {
+			out.text = decodeCharset(data, params["charset"])
+		}
+	case "text/html":
+		if out.html == "" {
+			out.html = decodeCharset(data, params["charset"])
+		}
+	}
+	return nil
+}
+
+func decodeTransfer(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	default:
+		return r
+	}
+}
+
+// decodeCharset converts Latin-1 text to UTF-8; UTF-8 and ASCII pass as is.
+func decodeCharset(data []byte, charset string) string {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252":
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	default:
+		return strings.ToValidUTF8(string(data), "�")
+	}
+}
+
+var emailWordDecoder = &mime.WordDecoder{
+	CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		return strings.NewReader(decodeCharset(data, charset)), nil
+	},
+}
+
+// decodeHeader decodes RFC 2047 encoded words such as =?UTF-8?Q?...?=.
+func decodeHeader(value string) string {
+	decoded, err := emailWordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+// emailBodyText picks the plain text of a mail, or the HTML part as text,
+// and drops the quoted message it replies to.
+func emailBodyText(content emailContent) string {
+	text := content.text
+	if strings.TrimSpace(text) == "" && content.html != "" {
+		text = htmlToText(content.html)
+	}
+	text = stripQuotedReply(strings.ReplaceAll(text, "\r\n", "\n"))
+	return utils.Truncate(strings.TrimSpace(text), emailMaxBodyChars)
+}
+
+var (
+	htmlDropRe  = regexp.MustCompile(`(?is)<(script|style|head)[^>]*>.*?</(script|style|head)>`)
+	htmlBreakRe = regexp.MustCompile(`(?i)<(br|/p|/div|/li|/tr|/h[1-6])[^>]*>`)
+	htmlTagRe   = regexp.MustCompile(`<[^>]+>`)
+	blankLineRe = regexp.MustCompile(`\n[ \t]*(\n[ \t]*)+`)
+	quoteHeadRe = regexp.MustCompile(`^On .+ wrote:$`)
+)
+
+// htmlToText reduces an HTML body to its text.
+func htmlToText(s string) string {
+	s = htmlDropRe.ReplaceAllString(s, "")
+	s = htmlBreakRe.ReplaceAllString(s, "\n")
+	s = html.UnescapeString(htmlTagRe.ReplaceAllString(s, ""))
+	return blankLineRe.ReplaceAllString(s, "\n\n")
+}
+
+// stripQuotedReply removes the quoted earlier message, "> " lines and the
+// "On ... wrote:" line before them, and the signature.
+func stripQuotedReply(text string) string {
+	lines := strings.Split(text, "\n")
+	kept := make([]string, 0, len(lines))
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		// "-- " starts a signature; quoted-printable loses the space.
+		if strings.TrimRight(line, " ") == "--" || trimmed == "-----Original Message-----" {
+			break
+		}
+		if quoteHeadRe.MatchString(trimmed) && i+1 < len(lines) {
+			if next := strings.TrimSpace(strings.Join(lines[i+1:], "\n")); next == "" || strings.HasPrefix(next, ">") {
+				break
+			}
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// isAutomatedEmail reports auto-replies and list mail (RFC 3834), which
+// must not be answered.
+func isAutomatedEmail(h mail.Header) bool {
+	if v := strings.ToLower(strings.TrimSpace(h.Get("Auto-Submitted"))); v != "" && v != "no" {
+		return true
+	}
+	switch strings.ToLower(strings.TrimSpace(h.Get("Precedence"))) {
+	case "bulk", "list", "junk":
+		return true
+	}
+	return h.Get("List-Id") != ""
+}
+
+// emailSenderVerified reports whether the Authentication-Results header
+// (RFC 8601) added by the receiving server shows the domain of sender
+// passing DMARC, or DKIM with a signing domain aligned to it. The topmost
+// header is used, or the first one from authServID when set, since
+// headers further down may come from the sender.
+func emailSenderVerified(h mail.Header, authServID, sender string) bool {
+	_, domain, _ := strings.Cut(strings.ToLower(sender), "@")
+	if domain == "" {
+		return false
+	}
+	for _, value := range h["Authentication-Results"] {
+		results := strings.Split(stripHeaderComments(value), ";")
+		id := strings.ToLower(strings.TrimSpace(results[0]))
+		if i := strings.IndexAny(id, " \t"); i >= 0 {
+			id = id[:i]
+		}
+		if authServID != "" && id != strings.ToLower(authServID) {
+			continue
+		}
+		for _, result := range results[1:] {
+			fields := strings.Fields(strings.ToLower(result))
+			if len(fields) == 0 {
+				continue
+			}
+			method, outcome, _ := strings.Cut(fields[0], "=")
+			if outcome != "pass" {
+				continue
+			}
+			method, _, _ = strings.Cut(method, "/")
+			for _, prop := range fields[1:] {
+				name, value, _ := strings.Cut(prop, "=")
+				value = strings.Trim(value, `"`)
+				switch {
+				case method == "dmarc" && name == "header.from":
+					if value == domain {
+						return true
+					}
+				case method == "dkim" && (name == "header.d" || name == "header.i"):
+					if _, d, ok := strings.Cut(value, "@"); ok {
+						value = d
+					}
+					if value != "" && (value == domain || strings.HasSuffix(domain, "."+value)) {
+						return true
+					}
+				}
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// stripHeaderComments removes the (comments) of a structured header.
+func stripHeaderComments(value string) string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range value {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')' && depth > 0:
+			depth--
+		case depth == 0:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+var messageIDRe = regexp.MustCompile(`<[^<>\s]+>`)
+
+func messageIDs(value string) []string {
+	return messageIDRe.FindAllString(value, -1)
+}
+
+func firstMessageID(value string) string {
+	if ids := messageIDs(value); len(ids) > 0 {
+		return ids[0]
+	}
+	return ""
+}
+
+// emailThreadID names a thread by the Message-ID that started it: the
+// first References entry, else In-Reply-To, else the message itself.
+func emailThreadID(messageID, inReplyTo string, references []string) string {
+	if len(references) > 0 {
+		return references[0]
+	}
+	if inReplyTo != "" {
+		return inReplyTo
+	}
+	return messageID
+}
+
+func newMessageID(address string) string {
+	_, domain, _ := strings.Cut(address, "@")
+	if domain == "" {
+		domain = "localhost"
+	}
+	return "<" + uuid.New().String() + "@" + domain + ">"
+}
+
+func replySubject(subject string) string {
+	if subject == "" {
+		return "Re: " + emailDefaultSubject
+	}
+	if strings.HasPrefix(strings.ToLower(subject), "re:") {
+		return subject
+	}
+	return "Re: " + subject
+}
+
+// newSubject uses the first line of a message that starts a thread.
+func newSubject(content string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(content), "\n")
+	line = strings.TrimSpace(strings.TrimLeft(line, "#*_ "))
+	if line == "" {
+		return emailDefaultSubject
+	}
+	return utils.Truncate(line, emailMaxSubjectLength)
+}
+
+func appendUnique(ids []string, id string) []string {
+	out := append([]string(nil), ids...)
+	for _, existing := range out {
+		if existing == id {
+			return out
+		}
+	}
+	return append(out, id)
+}
+
+func saveEmailFile(file emailFile) (string, error) {
+	dir := utils.MediaDir()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, uuid.New().String()[:8]+"_"+utils.SanitizeFilename(file.name))
+	if err := os.WriteFile(path, file.data, 0o600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,294 @@
+package channels
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// imapClient is the small subset of IMAP4rev1 (RFC 3501) the email channel
+// needs: login, select, search for unseen mail, fetch, flag and IDLE.
+type imapClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+	caps map[string]bool
+}
+
+// imapResponse is one server response line, with the contents of any
+// literals ({n} followed by n bytes) it carried.
+type imapResponse struct {
+	text     string
+	literals [][]byte
+}
+
+const imapTimeout = 60 * time.Second
+
+// dialIMAP connects to an IMAP server. Port 993 uses implicit TLS; other
+// ports upgrade with STARTTLS. A server that does not offer STARTTLS is
+// refused, since the password would cross the network in the clear,
+// unless insecure allows a plaintext session.
+func dialIMAP(ctx context.Context, host string, port int, insecure bool) (*imapClient, error) {
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	dialer := &net.Dialer{Timeout: imapTimeout}
+	var conn net.Conn
+	var err error
+	if port == 993 {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	c := &imapClient{conn: conn, r: bufio.NewReader(conn)}
+	greeting, err := c.readResponse()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(greeting.text, "* OK") && !strings.HasPrefix(greeting.text, "* PREAUTH") {
+		conn.Close()
+		return nil, fmt.Errorf("imap: unexpected greeting %q", greeting.text)
+	}
+	if err := c.capability(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if port != 993 && !c.caps["STARTTLS"] && !insecure {
+		conn.Close()
+		return nil, fmt.Errorf("imap: %s does not offer STARTTLS; use port 993 or set imap_insecure to log in without TLS", addr)
+	}
+	if port != 993 && c.caps["STARTTLS"] {
+		if _, err := c.command("STARTTLS"); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: host})
+		c.conn, c.r = tlsConn, bufio.NewReader(tlsConn)
+		if err := c.capability(); err != nil {
+			tlsConn.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *imapClient) Close() error {
+	return c.conn.Close()
+}
+
+func (c *imapClient) capability() error {
+	resps, err := c.command("CAPABILITY")
+	if err != nil {
+		return err
+	}
+	c.caps = map[string]bool{}
+	for _, resp := range resps {
+		if fields := strings.Fields(resp.text); len(fields) > 1 && strings.EqualFold(fields[1], "CAPABILITY") {
+			for _, capName := range fields[2:] {
+				c.caps[strings.ToUpper(capName)] = true
+			}
+		}
+	}
+	return nil
+}
+
+func (c *imapClient) Login(username, password string) error {
+	_, err := c.command("LOGIN " + imapQuote(username) + " " + imapQuote(password))
+	return err
+}
+
+func (c *imapClient) Select(mailbox string) error {
+	_, err := c.command("SELECT " + imapQuote(mailbox))
+	return err
+}
+
+// SearchUnseen returns the UIDs of messages without the \Seen flag.
+func (c *imapClient) SearchUnseen() ([]uint32, error) {
+	return c.search("UNSEEN")
+}
+
+// SearchThread returns the UIDs of the message with the given Message-ID
+// and of the messages that reference it.
+func (c *imapClient) SearchThread(messageID string) ([]uint32, error) {
+	return c.search("OR HEADER Message-ID " + imapQuote(messageID) + " HEADER References " + imapQuote(messageID))
+}
+
+func (c *imapClient) search(criteria string) ([]uint32, error) {
+	resps, err := c.command("UID SEARCH " + criteria)
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint32
+	for _, resp := range resps {
+		fields := strings.Fields(resp.text)
+		if len(fields) < 2 || !strings.EqualFold(fields[1], "SEARCH") {
+			continue
+		}
+		for _, f := range fields[2:] {
+			if uid, err := strconv.ParseUint(f, 10, 32); err == nil {
+				uids = append(uids, uint32(uid))
+			}
+		}
+	}
+	return uids, nil
+}
+
+// Fetch returns the raw RFC 5322 message with the given UID without
+// marking it seen.
+func (c *imapClient) Fetch(uid uint32) ([]byte, error) {
+	resps, err := c.command(fmt.Sprintf("UID FETCH %d (BODY.PEEK[])", uid))
+	if err != nil {
+		return nil, err
+	}
+	for _, resp := range resps {
+		if strings.Contains(strings.ToUpper(resp.text), "FETCH") && len(resp.literals) > 0 {
+			return resp.literals[0], nil
+		}
+	}
+	return nil, fmt.Errorf("imap: message %d not returned", uid)
+}
+
+// MarkSeen sets the \Seen flag on a message.
+func (c *imapClient) MarkSeen(uid uint32) error {
+	_, err := c.command(fmt.Sprintf(`UID STORE %d +FLAGS.SILENT (\Seen)`, uid))
+	return err
+}
+
+// Idle waits until the server reports new mail, timeout passes or ctx is
+// done (RFC 2177). Servers drop idle clients after 30 minutes, so timeout
+// should be shorter.
+func (c *imapClient) Idle(ctx context.Context, timeout time.Duration) error {
+	tag := c.nextTag()
+	if _, err := fmt.Fprintf(c.conn, "%s IDLE\r\n", tag); err != nil {
+		return err
+	}
+	resp, err := c.readResponse()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(resp.text, "+") {
+		return fmt.Errorf("imap: IDLE refused: %s", resp.text)
+	}
+
+	stop := context.AfterFunc(ctx, func() { c.conn.Close() })
+	defer stop()
+
+	c.conn.SetReadDeadline(time.Now().Add(timeout))
+	for {
+		resp, err := c.readResponse()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				break
+			}
+			return err
+		}
+		if strings.HasSuffix(strings.ToUpper(resp.text), " EXISTS") {
+			break
+		}
+	}
+	c.conn.SetReadDeadline(time.Time{})
+
+	if _, err := io.WriteString(c.conn, "DONE\r\n"); err != nil {
+		return err
+	}
+	_, err = c.waitTagged(tag)
+	return err
+}
+
+func (c *imapClient) Logout() {
+	c.command("LOGOUT")
+}
+
+func (c *imapClient) nextTag() string {
+	c.tag++
+	return fmt.Sprintf("a%03d", c.tag)
+}
+
+// command sends a command and returns the untagged responses that came
+// before its tagged OK.
+func (c *imapClient) command(cmd string) ([]imapResponse, error) {
+	tag := c.nextTag()
+	c.conn.SetDeadline(time.Now().Add(imapTimeout))
+	defer c.conn.SetDeadline(time.Time{})
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, cmd); err != nil {
+		return nil, err
+	}
+	return c.waitTagged(tag)
+}
+
+func (c *imapClient) waitTagged(tag string) ([]imapResponse, error) {
+	var untagged []imapResponse
+	for {
+		resp, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+		rest, ok := strings.CutPrefix(resp.text, tag+" ")
+		if !ok {
+			untagged = append(untagged, resp)
+			continue
+		}
+		status, _, _ := strings.Cut(rest, " ")
+		if strings.EqualFold(status, "OK") {
+			return untagged, nil
+		}
+		return nil, fmt.Errorf("imap: %s", rest)
+	}
+}
+
+// readResponse reads one response line, following any literals in it.
+func (c *imapClient) readResponse() (imapResponse, error) {
+	var resp imapResponse
+	var text strings.Builder
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return resp, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		n, ok := literalSize(line)
+		if !ok {
+			text.WriteString(line)
+			resp.text = text.String()
+			return resp, nil
+		}
+		text.WriteString(line[:strings.LastIndex(line, "{")])
+		literal := make([]byte, n)
+		if _, err := io.ReadFull(c.r, literal); err != nil {
+			return resp, err
+		}
+		resp.literals = append(resp.literals, literal)
+	}
+}
+
+// literalSize parses the {n} that ends a line announcing a literal.
+func literalSize(line string) (int, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+	open := strings.LastIndex(line, "{")
+	if open < 0 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(line[open+1:len(line)-1], "+"))
+	return n, err == nil && n >= 0
+}
+
+func imapQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,541 @@
+package channels
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/config"
+)
+
+func newTestEmailChannel(t *testing.T, allowFrom ...string) (*EmailChannel, *bus.MessageBus) {
+	t.Helper()
+	msgBus := bus.NewMessageBus()
+	ch, err := NewEmailChannel(config.EmailConfig{
+		IMAPHost:  "imap.example.org",
+		SMTPHost:  "smtp.example.org",
+		Username:  "Agent@Example.org",
+		AllowFrom: allowFrom,
+	}, msgBus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ch, msgBus
+}
+
+func consumeInbound(t *testing.T, msgBus *bus.MessageBus) (bus.InboundMessage, bool) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	return msgBus.ConsumeInbound(ctx)
+}
+
+const firstEmail = "From: Alice <alice@corp.example>\r\n" +
+	"To: agent@example.org\r\n" +
+	"Subject: =?UTF-8?Q?Quarterly_r=C3=A9port?=\r\n" +
+	"Message-ID: <m1@corp.example>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=\"b1\"\r\n" +
+	"\r\n" +
+	"--b1\r\n" +
+	"Content-Type: multipart/alternative; boundary=\"b2\"\r\n" +
+	"\r\n" +
+	"--b2\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Can you summarise the attached r=C3=A9port?\r\n" +
+	"\r\n" +
+	"-- \r\n" +
+	"Alice\r\n" +
+	"--b2\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>Can you summarise the attached report?</p>\r\n" +
+	"--b2--\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/csv; name=\"q3.csv\"\r\n" +
+	"Content-Disposition: attachment; filename=\"q3.csv\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"cmV2ZW51ZSwxMDAK\r\n" +
+	"--b1--\r\n"
+
+const replyEmail = "From: alice@corp.example\r\n" +
+	"Subject: Re: Quarterly report\r\n" +
+	"Message-ID: <m3@corp.example>\r\n" +
+	"In-Reply-To: <m2@example.org>\r\n" +
+	"References: <m1@corp.example> <m2@example.org>\r\n" +
+	"Content-Type: text/plain\r\n" +
+	"\r\n" +
+	"Thanks, and Q4?\r\n" +
+	"\r\n" +
+	"On Mon, 5 Oct 2026 at 10:00, Agent <agent@example.org> wrote:\r\n" +
+	"> Revenue was 100.\r\n"
+
+func TestEmailChannel_HandleRaw(t *testing.T) {
+	ch, msgBus := newTestEmailChannel(t)
+
+	ch.handleRaw(context.Background(), []byte(firstEmail))
+	msg, ok := consumeInbound(t, msgBus)
+	if !ok {
+		t.Fatal("expected an inbound message")
+	}
+	if msg.SenderID != "alice@corp.example" || msg.ChatID != "<m1@corp.example>" {
+		t.Errorf("sender = %q, chat = %q", msg.SenderID, msg.ChatID)
+	}
+	want := "Subject: Quarterly réport\nCan you summarise the attached réport?\n[file: q3.csv]"
+	if msg.Content != want {
+		t.Errorf("content = %q, want %q", msg.Content, want)
+	}
+	if len(msg.Media) != 1 || !strings.HasSuffix(msg.Media[0], "q3.csv") {
+		t.Fatalf("media = %v", msg.Media)
+	}
+	if data, _ := os.ReadFile(msg.Media[0]); string(data) != "revenue,100\n" {
+		t.Errorf("attachment = %q", data)
+	}
+	os.Remove(msg.Media[0])
+	if msg.Metadata["peer_kind"] != "thread" || msg.Metadata["peer_id"] != "m1@corp.example" {
+		t.Errorf("metadata = %v", msg.Metadata)
+	}
+
+	ch.handleRaw(context.Background(), []byte(replyEmail))
+	msg, ok = consumeInbound(t, msgBus)
+	if !ok {
+		t.Fatal("expected the reply")
+	}
+	if msg.ChatID != "<m1@corp.example>" {
+		t.Errorf("a reply should stay in its thread, got %q", msg.ChatID)
+	}
+	if msg.Content != "Thanks, and Q4?" {
+		t.Errorf("quoted text should be dropped, got %q", msg.Content)
+	}
+}
+
+func TestEmailChannel_Skips(t *testing.T) {
+	ch, msgBus := newTestEmailChannel(t, "@corp.example", "bob@else.example")
+
+	tests := map[string]string{
+		"disallowed sender": "From: eve@evil.example\r\nMessage-ID: <e@evil.example>\r\n\r\nhi\r\n",
+		"own address":       "From: agent@example.org\r\nMessage-ID: <o@example.org>\r\n\r\nhi\r\n",
+		"auto reply":        "From: alice@corp.example\r\nAuto-Submitted: auto-replied\r\n\r\nOut of office\r\n",
+		"list mail":         "From: alice@corp.example\r\nPrecedence: bulk\r\n\r\nnewsletter\r\n",
+	}
+	for name, raw := range tests {
+		ch.handleRaw(context.Background(), []byte(raw))
+		if msg, ok := consumeInbound(t, msgBus); ok {
+			t.Errorf("%s: should be skipped, got %+v", name, msg)
+		}
+	}
+}
+
+func TestEmailChannel_IsAllowed(t *testing.T) {
+	ch, _ := newTestEmailChannel(t, "corp.example", "@partner.example", "Bob@Else.example")
+	for sender, want := range map[string]bool{
+		"alice@corp.example":    true,
+		"carol@partner.example": true,
+		"bob@else.example":      true,
+		"eve@else.example":      false,
+		"eve@notcorp.example":   false,
+	} {
+		if got := ch.IsAllowed(sender); got != want {
+			t.Errorf("IsAllowed(%q) = %v, want %v", sender, got, want)
+		}
+	}
+}
+
+func TestEmailChannel_SenderVerified(t *testing.T) {
+	ch, msgBus := newTestEmailChannel(t, "@corp.example")
+	const body = "From: alice@corp.example\r\nMessage-ID: <v@corp.example>\r\n\r\nhi\r\n"
+	tests := map[string]struct {
+		headers string
+		want    bool
+	}{
+		"no results":  {"", false},
+		"dmarc pass":  {"Authentication-Results: mx.example.org; dmarc=pass (p=reject) header.from=corp.example\r\n", true},
+		"dkim parent": {"Authentication-Results: mx.example.org; spf=fail; dkim=pass header.d=corp.example header.s=s1\r\n", true},
+		"dkim other":  {"Authentication-Results: mx.example.org; dkim=pass header.d=evil.example\r\n", false},
+		"dkim fail":   {"Authentication-Results: mx.example.org; dkim=fail header.d=corp.example\r\n", false},
+		"spf only":    {"Authentication-Results: mx.example.org; spf=pass smtp.mailfrom=corp.example\r\n", false},
+		// Only the topmost header comes from the receiving server.
+		"forged below": {
+			"Authentication-Results: mx.example.org; dkim=none\r\n" +
+				"Authentication-Results: mx.example.org; dmarc=pass header.from=corp.example\r\n",
+			false,
+		},
+	}
+	for name, tt := range tests {
+		ch.handleRaw(context.Background(), []byte(tt.headers+body))
+		if _, ok := consumeInbound(t, msgBus); ok != tt.want {
+			t.Errorf("%s: delivered = %v, want %v", name, ok, tt.want)
+		}
+	}
+
+	// A pinned authserv-id skips headers from other servers.
+	ch.config.AuthServID = "mx.example.org"
+	raw := "Authentication-Results: relay.evil.example; dmarc=pass header.from=corp.example\r\n" +
+		"Authentication-Results: mx.example.org; dkim=pass header.i=@corp.example\r\n" + body
+	ch.handleRaw(context.Background(), []byte(raw))
+	if _, ok := consumeInbound(t, msgBus); !ok {
+		t.Error("expected the message verified by the pinned server")
+	}
+	ch.config.AuthServID = "mx.other.example"
+	ch.handleRaw(context.Background(), []byte(raw))
+	if _, ok := consumeInbound(t, msgBus); ok {
+		t.Error("results from other servers should not count")
+	}
+
+	ch.config.AuthServID = ""
+	ch.config.AllowUnauthenticated = true
+	ch.handleRaw(context.Background(), []byte(body))
+	if _, ok := consumeInbound(t, msgBus); !ok {
+		t.Error("allow_unauthenticated should accept the sender")
+	}
+}
+
+func TestEmailChannel_SendReply(t *testing.T) {
+	ch, msgBus := newTestEmailChannel(t)
+	ch.setRunning(true)
+	var sent [][]byte
+	var recipients []string
+	ch.send = func(from string, to []string, msg []byte) error {
+		recipients = append(recipients, to...)
+		sent = append(sent, msg)
+		return nil
+	}
+
+	ch.handleRaw(context.Background(), []byte(firstEmail))
+	in, _ := consumeInbound(t, msgBus)
+	for _, m := range in.Media {
+		os.Remove(m)
+	}
+
+	attachment := writeTempFile(t, "summary.txt", "Revenue was 100.")
+	err := ch.Send(context.Background(), bus.OutboundMessage{
+		Channel:     "email",
+		ChatID:      in.ChatID,
+		Content:     "Revenue was 100.",
+		Attachments: []bus.Attachment{{Path: attachment}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || recipients[0] != "alice@corp.example" {
+		t.Fatalf("sent %d mails to %v", len(sent), recipients)
+	}
+
+	reply, err := mail.ReadMessage(bytes.NewReader(sent[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decodeHeader(reply.Header.Get("Subject")); got != "Re: Quarterly réport" {
+		t.Errorf("subject = %q", got)
+	}
+	if got := reply.Header.Get("In-Reply-To"); got != "<m1@corp.example>" {
+		t.Errorf("In-Reply-To = %q", got)
+	}
+	if got := reply.Header.Get("References"); got != "<m1@corp.example>" {
+		t.Errorf("References = %q", got)
+	}
+	if !strings.HasSuffix(reply.Header.Get("Message-Id"), "@example.org>") {
+		t.Errorf("Message-ID = %q", reply.Header.Get("Message-Id"))
+	}
+
+	var content emailContent
+	if err := parseEmailPart(reply.Header.Get("Content-Type"), "", "", reply.Body, &content); err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(content.text) != "Revenue was 100." {
+		t.Errorf("body = %q", content.text)
+	}
+	if len(content.files) != 1 || content.files[0].name != "summary.txt" || string(content.files[0].data) != "Revenue was 100." {
+		t.Errorf("files = %+v", content.files)
+	}
+
+	// The next reply follows the one just sent.
+	ch.Send(context.Background(), bus.OutboundMessage{ChatID: in.ChatID, Content: "More"})
+	next, _ := mail.ReadMessage(bytes.NewReader(sent[1]))
+	if next.Header.Get("In-Reply-To") != reply.Header.Get("Message-Id") {
+		t.Errorf("In-Reply-To = %q, want %q", next.Header.Get("In-Reply-To"), reply.Header.Get("Message-Id"))
+	}
+	if refs := next.Header.Get("References"); refs != "<m1@corp.example> "+reply.Header.Get("Message-Id") {
+		t.Errorf("References = %q", refs)
+	}
+}
+
+func TestEmailChannel_SendRebuildsThread(t *testing.T) {
+	srv := newFakeIMAPServer(t, replyEmail)
+	ch, _ := newTestEmailChannel(t)
+	ch.config.IMAPHost = "127.0.0.1"
+	ch.config.IMAPPort = srv.port()
+	ch.config.IMAPInsecure = true
+	ch.setRunning(true)
+	var sent []byte
+	var recipients []string
+	ch.send = func(from string, to []string, msg []byte) error {
+		recipients, sent = to, msg
+		return nil
+	}
+
+	// After a restart the thread is only in the mailbox.
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "<m1@corp.example>", Content: "Q4 was 120."}); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := mail.ReadMessage(bytes.NewReader(sent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if recipients[0] != "alice@corp.example" || reply.Header.Get("Subject") != "Re: Quarterly report" {
+		t.Errorf("to = %v, subject = %q", recipients, reply.Header.Get("Subject"))
+	}
+	if got := reply.Header.Get("In-Reply-To"); got != "<m3@corp.example>" {
+		t.Errorf("In-Reply-To = %q", got)
+	}
+	if got := reply.Header.Get("References"); got != "<m1@corp.example> <m2@example.org> <m3@corp.example>" {
+		t.Errorf("References = %q", got)
+	}
+
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "<unknown@x>", Content: "hi"}); err == nil {
+		t.Error("an unknown thread should fail")
+	}
+}
+
+func TestEmailChannel_ThreadsBounded(t *testing.T) {
+	ch, _ := newTestEmailChannel(t)
+	for i := range emailMaxThreads + 10 {
+		ch.rememberThread(fmt.Sprintf("<t%d@x>", i), "alice@corp.example", "s", fmt.Sprintf("<t%d@x>", i), nil)
+	}
+	if len(ch.threads) != emailMaxThreads {
+		t.Errorf("threads = %d, want %d", len(ch.threads), emailMaxThreads)
+	}
+	if _, ok := ch.threads[fmt.Sprintf("<t%d@x>", emailMaxThreads+9)]; !ok {
+		t.Error("the newest thread should be kept")
+	}
+}
+
+func TestEmailChannel_SendNewThread(t *testing.T) {
+	ch, _ := newTestEmailChannel(t)
+	ch.setRunning(true)
+	var sent []byte
+	ch.send = func(from string, to []string, msg []byte) error {
+		sent = msg
+		return nil
+	}
+
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "bob@else.example", Content: "## Daily digest\n\nAll good."}); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(sent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Header.Get("To") != "<bob@else.example>" || msg.Header.Get("Subject") != "Daily digest" || msg.Header.Get("In-Reply-To") != "" {
+		t.Errorf("header = %v", msg.Header)
+	}
+}
+
+func TestStripQuotedReply(t *testing.T) {
+	in := "Sounds good.\n> earlier\nOn Tue, Bob wrote:\n> quoted\n> more"
+	if got := stripQuotedReply(in); got != "Sounds good." {
+		t.Errorf("stripQuotedReply() = %q", got)
+	}
+	keep := "On Tuesday I wrote:\nthe plan is below"
+	if got := stripQuotedReply(keep); got != keep {
+		t.Errorf("an unquoted line should stay, got %q", got)
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	in := "<html><head><style>p{}</style></head><body><p>Hello &amp; welcome</p><p>Line<br>two</p></body></html>"
+	if got := strings.TrimSpace(htmlToText(in)); got != "Hello & welcome\nLine\ntwo" {
+		t.Errorf("htmlToText() = %q", got)
+	}
+}
+
+func writeTempFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := t.TempDir() + "/" + name
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// fakeIMAPServer answers the commands the channel sends with canned
+// responses and records them.
+type fakeIMAPServer struct {
+	ln       net.Listener
+	message  string
+	mu       sync.Mutex
+	commands []string
+}
+
+func newFakeIMAPServer(t *testing.T, message string) *fakeIMAPServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeIMAPServer{ln: ln, message: message}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *fakeIMAPServer) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeIMAPServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *fakeIMAPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	io.WriteString(conn, "* OK fake IMAP ready\r\n")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		tag, cmd, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		s.mu.Lock()
+		s.commands = append(s.commands, cmd)
+		s.mu.Unlock()
+
+		switch {
+		case cmd == "CAPABILITY":
+			io.WriteString(conn, "* CAPABILITY IMAP4rev1 IDLE\r\n")
+		case strings.HasPrefix(cmd, "SELECT"):
+			io.WriteString(conn, "* 1 EXISTS\r\n")
+		case cmd == "UID SEARCH UNSEEN":
+			io.WriteString(conn, "* SEARCH 7\r\n")
+		case strings.HasPrefix(cmd, "UID SEARCH OR HEADER"):
+			if strings.Contains(cmd, "<m1@corp.example>") {
+				io.WriteString(conn, "* SEARCH 3 7\r\n")
+			} else {
+				io.WriteString(conn, "* SEARCH\r\n")
+			}
+		case strings.HasPrefix(cmd, "UID FETCH 7"):
+			fmt.Fprintf(conn, "* 1 FETCH (UID 7 BODY[] {%d}\r\n%s)\r\n", len(s.message), s.message)
+		case cmd == "IDLE":
+			io.WriteString(conn, "+ idling\r\n* 2 EXISTS\r\n")
+			if done, _ := r.ReadString('\n'); done != "DONE\r\n" {
+				fmt.Fprintf(conn, "%s BAD expected DONE\r\n", tag)
+				continue
+			}
+		case cmd == "LOGOUT":
+			io.WriteString(conn, "* BYE\r\n")
+		}
+		fmt.Fprintf(conn, "%s OK done\r\n", tag)
+	}
+}
+
+func TestIMAPClient(t *testing.T) {
+	srv := newFakeIMAPServer(t, replyEmail)
+	ctx := context.Background()
+
+	// The fake server offers no STARTTLS, so only an insecure session may
+	// log in to it.
+	if _, err := dialIMAP(ctx, "127.0.0.1", srv.port(), false); err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("a server without STARTTLS should be refused, got %v", err)
+	}
+	c, err := dialIMAP(ctx, "127.0.0.1", srv.port(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if !c.caps["IDLE"] {
+		t.Errorf("caps = %v", c.caps)
+	}
+	if err := c.Login(`agent@example.org`, `pa"ss`); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Select("INBOX"); err != nil {
+		t.Fatal(err)
+	}
+	uids, err := c.SearchUnseen()
+	if err != nil || len(uids) != 1 || uids[0] != 7 {
+		t.Fatalf("uids = %v, %v", uids, err)
+	}
+	raw, err := c.Fetch(7)
+	if err != nil || string(raw) != replyEmail {
+		t.Fatalf("fetch = %q, %v", raw, err)
+	}
+	if err := c.MarkSeen(7); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Idle(ctx, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	c.Logout()
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	want := []string{
+		"CAPABILITY", // the refused plaintext dial
+		"CAPABILITY",
+		`LOGIN "agent@example.org" "pa\"ss"`,
+		`SELECT "INBOX"`,
+		"UID SEARCH UNSEEN",
+		"UID FETCH 7 (BODY.PEEK[])",
+		`UID STORE 7 +FLAGS.SILENT (\Seen)`,
+		"IDLE",
+		"LOGOUT",
+	}
+	if strings.Join(srv.commands, "\n") != strings.Join(want, "\n") {
+		t.Errorf("commands = %q", srv.commands)
+	}
+}
+
+func TestComposeEmail_Multipart(t *testing.T) {
+	raw, err := composeEmail("agent@example.org", "alice@corp.example", "Report", "<id@example.org>", "", nil,
+		"See attached.", []emailFile{{name: "a.bin", data: bytes.Repeat([]byte{0xff}, 200)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, _ := mail.ReadMessage(bytes.NewReader(raw))
+	if !strings.HasPrefix(msg.Header.Get("Content-Type"), "multipart/mixed; boundary=") {
+		t.Fatalf("Content-Type = %q", msg.Header.Get("Content-Type"))
+	}
+	_, boundary, _ := strings.Cut(msg.Header.Get("Content-Type"), "boundary=")
+	mr := multipart.NewReader(msg.Body, boundary)
+	var parts int
+	for {
+		p, err := mr.NextRawPart()
+		if err != nil {
+			break
+		}
+		parts++
+		if p.Header.Get("Content-Transfer-Encoding") == "base64" {
+			body, _ := io.ReadAll(p)
+			for _, line := range strings.Split(strings.TrimSpace(string(body)), "\r\n") {
+				if len(line) > 76 {
+					t.Errorf("base64 line of %s chars", strconv.Itoa(len(line)))
+				}
+			}
+		}
+	}
+	if parts != 2 {
+		t.Errorf("parts = %d, want 2", parts)
+	}
+}
@@ -3,9 +3,14 @@
 package channels
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -25,19 +30,63 @@ type FeishuChannel struct {
 	config   config.FeishuConfig
 	client   *lark.Client
 	wsClient *larkws.Client
+	stream   *streamRenderer
 
 	mu     sync.Mutex
 	cancel context.CancelFunc
 }
 
+const (
+	// feishuMaxLength keeps a text message well under the 150 KB request
+	// limit.
+	feishuMaxLength = 30000
+	// feishuStreamFlush and feishuMaxEdits follow Feishu's limits on
+	// editing messages: a few edits a second, and 20 per message.
+	feishuStreamFlush = 2 * time.Second
+	feishuMaxEdits    = 20
+)
+
 func NewFeishuChannel(cfg config.FeishuConfig, bus *bus.MessageBus) (*FeishuChannel, error) {
 	base := NewBaseChannel("feishu", cfg, bus, cfg.AllowFrom)
 
-	return &FeishuChannel{
+	c := &FeishuChannel{
 		BaseChannel: base,
 		config:      cfg,
 		client:      lark.NewClient(cfg.AppID, cfg.AppSecret),
-	}, nil
+	}
+	c.stream = &streamRenderer{
+		name:     "feishu",
+		interval: feishuStreamFlush,
+		maxLen:   feishuMaxLength,
+		maxEdits: feishuMaxEdits,
+		post: func(ctx context.Context, chatID, text string) (string, error) {
+			return c.sendMessage(ctx, chatID, "", larkim.MsgTypeText, map[string]string{"text": text})
+		},
+		edit:   c.editMessage,
+		remove: c.deleteMessage,
+	}
+	return c, nil
+}
+
+// StartStreamConsumer starts a goroutine that shows stream deltas by
+// sending messages to the chat and then editing them.
+func (c *FeishuChannel) StartStreamConsumer(ctx context.Context) {
+	go c.stream.run(ctx)
+}
+
+// HandleStreamDelta processes a single stream delta event.
+func (c *FeishuChannel) HandleStreamDelta(delta bus.StreamDelta) {
+	c.stream.add(delta)
+}
+
+// ShowStatus shows what the agent is doing in a message of its own.
+func (c *FeishuChannel) ShowStatus(ctx context.Context, chatID, status string) error {
+	return c.stream.showStatus(ctx, chatID, status)
+}
+
+// ClearStatus removes the status message.
+func (c *FeishuChannel) ClearStatus(ctx context.Context, chatID string) error {
+	return c.stream.clearStatus(ctx, chatID)
 }
 
 func (c *FeishuChannel) Start(ctx context.Context) error {
@@ -97,38 +146,197 @@ func (c *FeishuChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 		return fmt.Errorf("chat ID is empty")
 	}
 
-	payload, err := json.Marshal(map[string]string{"text": msg.Content})
+	// Interactive cards need a callback endpoint, so buttons are listed as text.
+	replyTo := msg.ReplyTo
+	text := appendFallbackText(msg.Content, nil, msg.Buttons)
+	// The reply takes the place of the streamed messages, unless it
+	// answers a message, which an edit cannot make it do. A reply without
+	// text, such as a voice note, removes them.
+	if replyTo != "" || strings.TrimSpace(msg.Content) == "" {
+		c.stream.finish(ctx, msg.ChatID, nil)
+	} else if len(c.stream.finish(ctx, msg.ChatID, []string{text})) == 0 {
+		text = ""
+	}
+	if text != "" {
+		if _, err := c.sendMessage(ctx, msg.ChatID, replyTo, larkim.MsgTypeText, map[string]string{"text": text}); err != nil {
+			return err
+		}
+		replyTo = ""
+	}
+
+	for _, a := range msg.Attachments {
+		msgType, content, err := c.uploadAttachment(ctx, a)
+		if err != nil {
+			logger.WarnCF("feishu", "Failed to upload attachment, sending link instead", map[string]any{
+				"attachment": a.Name(),
+				"error":      err.Error(),
+			})
+			msgType, content = larkim.MsgTypeText, map[string]string{"text": attachmentText([]bus.Attachment{a})}
+		}
+		if _, err := c.sendMessage(ctx, msg.ChatID, replyTo, msgType, content); err != nil {
+			return err
+		}
+		replyTo = ""
+	}
+
+	logger.DebugCF("feishu", "Feishu message sent", map[string]any{
+		"chat_id": msg.ChatID,
+	})
+
+	return nil
+}
+
+// sendMessage sends one message to a chat, as a reply when replyTo is set,
+// and returns its ID.
+func (c *FeishuChannel) sendMessage(ctx context.Context, chatID, replyTo, msgType string, content any) (string, error) {
+	payload, err := json.Marshal(content)
 	if err != nil {
-		return fmt.Errorf("failed to marshal feishu content: %w", err)
+		return "", fmt.Errorf("failed to marshal feishu content: %w", err)
+	}
+	uuid := fmt.Sprintf("agentx-%d", time.Now().UnixNano())
+
+	if replyTo != "" {
+		req := larkim.NewReplyMessageReqBuilder().
+			MessageId(replyTo).
+			Body(larkim.NewReplyMessageReqBodyBuilder().
+				MsgType(msgType).
+				Content(string(payload)).
+				Uuid(uuid).
+				Build()).
+			Build()
+
+		resp, err := c.client.Im.V1.Message.Reply(ctx, req)
+		if err != nil {
+			return "", fmt.Errorf("failed to send feishu message: %w", err)
+		}
+		if !resp.Success() {
+			return "", fmt.Errorf("feishu api error: code=%d msg=%s", resp.Code, resp.Msg)
+		}
+		return stringValue(resp.Data.MessageId), nil
 	}
 
 	req := larkim.NewCreateMessageReqBuilder().
 		ReceiveIdType(larkim.ReceiveIdTypeChatId).
 		Body(larkim.NewCreateMessageReqBodyBuilder().
-			ReceiveId(msg.ChatID).
-			MsgType(larkim.MsgTypeText).
+			ReceiveId(chatID).
+			MsgType(msgType).
 			Content(string(payload)).
-			Uuid(fmt.Sprintf("agentx-%d", time.Now().UnixNano())).
+			Uuid(uuid).
 			Build()).
 		Build()
 
 	resp, err := c.client.Im.V1.Message.Create(ctx, req)
 	if err != nil {
-		return fmt.Errorf("failed to send feishu message: %w", err)
+		return "", fmt.Errorf("failed to send feishu message: %w", err)
 	}
 
 	if !resp.Success() {
-		return fmt.Errorf("feishu api error: code=%d msg=%s", resp.Code, resp.Msg)
+		return "", fmt.Errorf("feishu api error: code=%d msg=%s", resp.Code, resp.Msg)
 	}
+	return stringValue(resp.Data.MessageId), nil
+}
 
-	logger.DebugCF("feishu", "Feishu message sent", map[string]any{
-		"chat_id": msg.ChatID,
-	})
+// editMessage replaces the text of a text message.
+func (c *FeishuChannel) editMessage(ctx context.Context, chatID, messageID, text string) error {
+	payload, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return fmt.Errorf("failed to marshal feishu content: %w", err)
+	}
+	req := larkim.NewUpdateMessageReqBuilder().
+		MessageId(messageID).
+		Body(larkim.NewUpdateMessageReqBodyBuilder().
+			MsgType(larkim.MsgTypeText).
+			Content(string(payload)).
+			Build()).
+		Build()
 
+	resp, err := c.client.Im.V1.Message.Update(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to edit feishu message: %w", err)
+	}
+	if !resp.Success() {
+		return fmt.Errorf("feishu api error: code=%d msg=%s", resp.Code, resp.Msg)
+	}
+	return nil
+}
+
+// deleteMessage recalls a message the bot sent.
+func (c *FeishuChannel) deleteMessage(ctx context.Context, chatID, messageID string) error {
+	resp, err := c.client.Im.V1.Message.Delete(ctx, larkim.NewDeleteMessageReqBuilder().MessageId(messageID).Build())
+	if err != nil {
+		return fmt.Errorf("failed to delete feishu message: %w", err)
+	}
+	if !resp.Success() {
+		return fmt.Errorf("feishu api error: code=%d msg=%s", resp.Code, resp.Msg)
+	}
 	return nil
 }
 
-func (c *FeishuChannel) handleMessageReceive(_ context.Context, event *larkim.P2MessageReceiveV1) error {
+// uploadAttachment uploads an attachment and returns the message type and
+// content that reference it: images as image messages, everything else as
+// file messages.
+func (c *FeishuChannel) uploadAttachment(ctx context.Context, a bus.Attachment) (string, any, error) {
+	data, err := readAttachment(ctx, a)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if a.Kind() == bus.AttachmentImage {
+		req := larkim.NewCreateImageReqBuilder().
+			Body(larkim.NewCreateImageReqBodyBuilder().
+				ImageType(larkim.ImageTypeMessage).
+				Image(bytes.NewReader(data)).
+				Build()).
+			Build()
+		resp, err := c.client.Im.V1.Image.Create(ctx, req)
+		if err != nil {
+			return "", nil, err
+		}
+		if !resp.Success() {
+			return "", nil, fmt.Errorf("feishu api error: code=%d msg=%s", resp.Code, resp.Msg)
+		}
+		return larkim.MsgTypeImage, map[string]string{"image_key": stringValue(resp.Data.ImageKey)}, nil
+	}
+
+	req := larkim.NewCreateFileReqBuilder().
+		Body(larkim.NewCreateFileReqBodyBuilder().
+			FileType(feishuFileType(a)).
+			FileName(a.Name()).
+			File(bytes.NewReader(data)).
+			Build()).
+		Build()
+	resp, err := c.client.Im.V1.File.Create(ctx, req)
+	if err != nil {
+		return "", nil, err
+	}
+	if !resp.Success() {
+		return "", nil, fmt.Errorf("feishu api error: code=%d msg=%s", resp.Code, resp.Msg)
+	}
+	return larkim.MsgTypeFile, map[string]string{"file_key": stringValue(resp.Data.FileKey)}, nil
+}
+
+// feishuFileType maps an attachment to one of the upload file types Feishu
+// accepts, falling back to a generic stream.
+func feishuFileType(a bus.Attachment) string {
+	switch strings.ToLower(strings.TrimPrefix(filepath.Ext(a.Name()), ".")) {
+	case "opus":
+		return larkim.FileTypeOpus
+	case "mp4":
+		return larkim.FileTypeMp4
+	case "pdf":
+		return larkim.FileTypePdf
+	case "doc", "docx":
+		return larkim.FileTypeDoc
+	case "xls", "xlsx":
+		return larkim.FileTypeXls
+	case "ppt", "pptx":
+		return larkim.FileTypePpt
+	default:
+		return larkim.FileTypeStream
+	}
+}
+
+func (c *FeishuChannel) handleMessageReceive(ctx context.Context, event *larkim.P2MessageReceiveV1) error {
 	if event == nil || event.Event == nil || event.Event.Message == nil {
 		return nil
 	}
@@ -147,6 +355,14 @@ func (c *FeishuChannel) handleMessageReceive(_ context.Context, event *larkim.P2
 	}
 
 	content := extractFeishuMessageContent(message)
+	var mediaPaths []string
+	if stringValue(message.MessageType) == larkim.MsgTypeAudio {
+		if localPath := c.downloadAudio(ctx, message); localPath != "" {
+			defer removeTempFiles("feishu", []string{localPath})
+			mediaPaths = append(mediaPaths, localPath)
+			content = c.voiceText(ctx, localPath)
+		}
+	}
 	if content == "" {
 		content = "[empty message]"
 	}
@@ -180,10 +396,52 @@ func (c *FeishuChannel) handleMessageReceive(_ context.Context, event *larkim.P2
 		"preview":   utils.Truncate(content, 80),
 	})
 
-	c.HandleMessage(senderID, chatID, content, nil, metadata)
+	c.HandleMessage(senderID, chatID, content, mediaPaths, metadata)
 	return nil
 }
 
+// downloadAudio saves the audio of a voice message to the media directory
+// and returns its path, or "" if it cannot be fetched.
+func (c *FeishuChannel) downloadAudio(ctx context.Context, message *larkim.EventMessage) string {
+	var payload struct {
+		FileKey string `json:"file_key"`
+	}
+	if err := json.Unmarshal([]byte(stringValue(message.Content)), &payload); err != nil || payload.FileKey == "" {
+		return ""
+	}
+
+	req := larkim.NewGetMessageResourceReqBuilder().
+		MessageId(stringValue(message.MessageId)).
+		FileKey(payload.FileKey).
+		Type("file").
+		Build()
+	resp, err := c.client.Im.V1.MessageResource.Get(ctx, req)
+	if err == nil && !resp.Success() {
+		err = fmt.Errorf("feishu api error: code=%d msg=%s", resp.Code, resp.Msg)
+	}
+	if err != nil {
+		logger.ErrorCF("feishu", "Failed to download voice message", map[string]any{
+			"message_id": stringValue(message.MessageId),
+			"error":      err.Error(),
+		})
+		return ""
+	}
+
+	if err := os.MkdirAll(utils.MediaDir(), 0o700); err != nil {
+		return ""
+	}
+	f, err := os.CreateTemp(utils.MediaDir(), "feishu_voice_*.opus")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, resp.File); err != nil {
+		os.Remove(f.Name())
+		return ""
+	}
+	return f.Name()
+}
+
 func extractFeishuSenderID(sender *larkim.EventSender) string {
 	if sender == nil || sender.SenderId == nil {
 		return ""
@@ -0,0 +1,569 @@
+package channels
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/config"
+	"github.com/Agentx-network/agentx/pkg/logger"
+	"github.com/Agentx-network/agentx/pkg/utils"
+)
+
+const (
+	ircDialTimeout       = 30 * time.Second
+	ircReadTimeout       = 5 * time.Minute
+	ircKeepAlive         = 90 * time.Second // PING, and retry the configured nick
+	ircReconnectDelay    = 5 * time.Second
+	ircMaxReconnectDelay = 5 * time.Minute
+	ircRejoinDelay       = 10 * time.Second
+	ircFloodInterval     = time.Second // one line per interval once the burst is used
+	ircFloodBurst        = 5
+	ircMaxLine           = 510 // bytes, without CRLF
+	ircPrefixAllowance   = 100 // room for the ":nick!user@host " servers prepend when relaying
+)
+
+// ircFormatting matches mIRC formatting codes: bold, colors, italics,
+// underline, reverse, monospace and reset.
+var ircFormatting = regexp.MustCompile("\x03(\\d{1,2}(,\\d{1,2})?)?|[\x02\x0F\x11\x16\x1D\x1E\x1F]")
+
+// IRCChannel connects to an IRC server as a client. Channels are chats of
+// their own (peer_kind "group"); private messages are chats with the
+// sender's nick (peer_kind "direct").
+type IRCChannel struct {
+	*BaseChannel
+	config config.IRCConfig
+	cancel context.CancelFunc
+
+	mu   sync.Mutex // guards conn and nick
+	conn net.Conn
+	nick string
+
+	sendMu    sync.Mutex // keeps the lines of one reply together
+	floodMu   sync.Mutex
+	floodNext time.Time
+
+	// Timings, shortened in tests.
+	floodInterval  time.Duration
+	reconnectDelay time.Duration
+	keepAlive      time.Duration
+
+	askers sync.Map // channel -> nick that last mentioned the agent there
+}
+
+// ircMessage is one parsed protocol line.
+type ircMessage struct {
+	tags    map[string]string
+	prefix  string
+	command string
+	params  []string
+}
+
+func NewIRCChannel(cfg config.IRCConfig, messageBus *bus.MessageBus) (*IRCChannel, error) {
+	if cfg.Server == "" || cfg.Nick == "" {
+		return nil, fmt.Errorf("irc server and nick are required")
+	}
+	if (cfg.SASLUser == "") != (cfg.SASLPassword == "") {
+		return nil, fmt.Errorf("irc sasl_user and sasl_password go together")
+	}
+	return &IRCChannel{
+		BaseChannel:    NewBaseChannel("irc", cfg, messageBus, cfg.AllowFrom),
+		config:         cfg,
+		floodInterval:  ircFloodInterval,
+		reconnectDelay: ircReconnectDelay,
+		keepAlive:      ircKeepAlive,
+	}, nil
+}
+
+func (c *IRCChannel) Start(ctx context.Context) error {
+	logger.InfoCF("irc", "Starting IRC channel", map[string]any{
+		"server":   c.config.Server,
+		"nick":     c.config.Nick,
+		"channels": strings.Join(c.config.Channels, ", "),
+	})
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.setRunning(true)
+	go c.run(ctx)
+	return nil
+}
+
+func (c *IRCChannel) Stop(ctx context.Context) error {
+	logger.InfoC("irc", "Stopping IRC channel")
+	c.setRunning(false)
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	if conn != nil {
+		c.writeLine(conn, "QUIT :Shutting down")
+	}
+	if c.cancel != nil {
+		c.cancel()
+	}
+	return nil
+}
+
+// run keeps a session going, reconnecting with backoff when it drops.
+func (c *IRCChannel) run(ctx context.Context) {
+	delay := c.reconnectDelay
+	for {
+		registered, err := c.session(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if registered {
+			delay = c.reconnectDelay
+		}
+		logger.WarnCF("irc", "Disconnected from IRC server, reconnecting", map[string]any{
+			"error": fmt.Sprint(err),
+			"delay": delay.String(),
+		})
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, ircMaxReconnectDelay)
+	}
+}
+
+func (c *IRCChannel) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: ircDialTimeout}
+	if !c.config.TLS {
+		return dialer.DialContext(ctx, "tcp", c.config.Server)
+	}
+	host, _, err := net.SplitHostPort(c.config.Server)
+	if err != nil {
+		return nil, err
+	}
+	return (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", c.config.Server)
+}
+
+// session connects, registers and handles messages until the connection
+// drops. It reports whether registration completed.
+func (c *IRCChannel) session(ctx context.Context) (bool, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	c.mu.Lock()
+	c.conn = conn
+	c.nick = c.config.Nick
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		if c.conn == conn {
+			c.conn = nil
+		}
+		c.mu.Unlock()
+	}()
+
+	done := make(chan struct{})
+	defer close(done)
+	go c.keepAliveLoop(ctx, conn, done)
+
+	if c.config.Password != "" {
+		c.writeLine(conn, "PASS %s", c.config.Password)
+	}
+	c.writeLine(conn, "CAP LS 302")
+	c.writeLine(conn, "NICK %s", c.config.Nick)
+	c.writeLine(conn, "USER %s 0 * :%s", firstNonEmpty(c.config.Username, c.config.Nick), firstNonEmpty(c.config.RealName, c.config.Nick))
+
+	registered := false
+	var offered []string
+	r := bufio.NewReader(conn)
+	for {
+		conn.SetReadDeadline(time.Now().Add(ircReadTimeout))
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return registered, err
+		}
+		msg, ok := parseIRCLine(strings.TrimRight(line, "\r\n"))
+		if !ok {
+			continue
+		}
+
+		switch msg.command {
+		case "PING":
+			c.writeLine(conn, "PONG :%s", msg.param(0))
+		case "CAP":
+			if err := c.negotiateCaps(conn, msg, &offered); err != nil {
+				return registered, err
+			}
+		case "AUTHENTICATE":
+			if msg.param(0) == "+" {
+				creds := c.config.SASLUser + "\x00" + c.config.SASLUser + "\x00" + c.config.SASLPassword
+				c.writeLine(conn, "AUTHENTICATE %s", base64.StdEncoding.EncodeToString([]byte(creds)))
+			}
+		case "903": // RPL_SASLSUCCESS
+			c.writeLine(conn, "CAP END")
+		case "902", "904", "905", "906": // SASL failed or aborted
+			return registered, fmt.Errorf("irc: SASL authentication failed: %s", msg.param(len(msg.params)-1))
+		case "001": // RPL_WELCOME
+			registered = true
+			c.setNick(msg.param(0))
+			logger.InfoCF("irc", "Connected to IRC server", map[string]any{"nick": msg.param(0)})
+			for _, channel := range c.config.Channels {
+				c.writeLine(conn, "JOIN %s", channel)
+			}
+		case "432", "433", "436": // nick invalid, in use or colliding
+			if !registered {
+				nick := msg.param(1) + "_"
+				c.setNick(nick)
+				c.writeLine(conn, "NICK %s", nick)
+			}
+		case "NICK":
+			if strings.EqualFold(msg.nick(), c.currentNick()) {
+				c.setNick(msg.param(0))
+				logger.InfoCF("irc", "Nick changed", map[string]any{"nick": msg.param(0)})
+			} else if strings.EqualFold(msg.nick(), c.config.Nick) {
+				c.recoverNick(conn)
+			}
+		case "QUIT":
+			if strings.EqualFold(msg.nick(), c.config.Nick) {
+				c.recoverNick(conn)
+			}
+		case "KICK":
+			if strings.EqualFold(msg.param(1), c.currentNick()) {
+				channel := msg.param(0)
+				logger.WarnCF("irc", "Kicked from channel", map[string]any{
+					"channel": channel,
+					"by":      msg.nick(),
+				})
+				time.AfterFunc(ircRejoinDelay, func() {
+					if key := c.channelKey(channel); key != "" {
+						c.writeLine(conn, "JOIN %s %s", channel, key)
+					} else {
+						c.writeLine(conn, "JOIN %s", channel)
+					}
+				})
+			}
+		case "PRIVMSG":
+			c.handlePrivmsg(conn, msg)
+		case "ERROR":
+			return registered, fmt.Errorf("irc: server closed the link: %s", msg.param(0))
+		}
+	}
+}
+
+// negotiateCaps requests SASL when credentials are configured and
+// account-tag, which tells who is logged in as whom.
+func (c *IRCChannel) negotiateCaps(conn net.Conn, msg ircMessage, offered *[]string) error {
+	switch msg.param(1) {
+	case "LS":
+		// "CAP * LS * :caps" continues on the next line.
+		last := msg.param(len(msg.params) - 1)
+		*offered = append(*offered, strings.Fields(last)...)
+		if msg.param(2) == "*" && len(msg.params) > 3 {
+			return nil
+		}
+		var want []string
+		for _, capability := range *offered {
+			name, _, _ := strings.Cut(capability, "=")
+			if name == "account-tag" || (name == "sasl" && c.config.SASLUser != "") {
+				want = append(want, name)
+			}
+		}
+		if c.config.SASLUser != "" && !slices.Contains(want, "sasl") {
+			return errors.New("irc: server does not offer SASL")
+		}
+		if len(want) == 0 {
+			c.writeLine(conn, "CAP END")
+			return nil
+		}
+		c.writeLine(conn, "CAP REQ :%s", strings.Join(want, " "))
+	case "ACK":
+		if slices.Contains(strings.Fields(msg.param(2)), "sasl") {
+			c.writeLine(conn, "AUTHENTICATE PLAIN")
+			return nil
+		}
+		c.writeLine(conn, "CAP END")
+	case "NAK":
+		if c.config.SASLUser != "" {
+			return errors.New("irc: server refused SASL")
+		}
+		c.writeLine(conn, "CAP END")
+	}
+	return nil
+}
+
+func (c *IRCChannel) keepAliveLoop(ctx context.Context, conn net.Conn, done chan struct{}) {
+	ticker := time.NewTicker(c.keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+			c.writeLine(conn, "PING :keepalive")
+			c.recoverNick(conn)
+		}
+	}
+}
+
+// recoverNick asks for the configured nick back when running under
+// another one.
+func (c *IRCChannel) recoverNick(conn net.Conn) {
+	if !strings.EqualFold(c.currentNick(), c.config.Nick) {
+		c.writeLine(conn, "NICK %s", c.config.Nick)
+	}
+}
+
+func (c *IRCChannel) handlePrivmsg(conn net.Conn, msg ircMessage) {
+	sender := msg.nick()
+	target, text := msg.param(0), msg.param(1)
+	if sender == "" || text == "" {
+		return
+	}
+
+	if strings.HasPrefix(text, "\x01") {
+		// CTCP: answer VERSION, ignore the rest (including /me).
+		if strings.HasPrefix(text, "\x01VERSION") {
+			c.writeLine(conn, "NOTICE %s :\x01VERSION AgentX\x01", sender)
+		}
+		return
+	}
+	text = strings.TrimSpace(ircFormatting.ReplaceAllString(text, ""))
+
+	chatID := sender
+	metadata := map[string]string{
+		"peer_kind": "direct",
+		"peer_id":   sender,
+	}
+	if isIRCChannel(target) {
+		content, mentioned := ircMention(text, c.currentNick())
+		if !mentioned {
+			return
+		}
+		text = content
+		chatID = target
+		metadata["peer_kind"] = "group"
+		metadata["peer_id"] = target
+	}
+	if text == "" {
+		return
+	}
+	if id := msg.tags["msgid"]; id != "" {
+		metadata["message_id"] = id
+	}
+
+	// Nicks can be taken by anyone; the account, when the server tags it,
+	// cannot.
+	senderID := sender
+	if account := msg.tags["account"]; account != "" && account != "*" {
+		senderID = sender + "|" + account
+	}
+	if !c.IsAllowed(senderID) {
+		logger.DebugCF("irc", "Message rejected by allowlist", map[string]any{
+			"sender": senderID,
+		})
+		return
+	}
+	if chatID != sender {
+		c.askers.Store(strings.ToLower(chatID), sender)
+	}
+
+	logger.DebugCF("irc", "Received message", map[string]any{
+		"sender":  senderID,
+		"chat_id": chatID,
+		"preview": utils.Truncate(text, 50),
+	})
+	c.HandleMessage(senderID, chatID, text, nil, metadata)
+}
+
+func (c *IRCChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("irc channel not running")
+	}
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	if conn == nil {
+		return fmt.Errorf("irc: not connected")
+	}
+
+	lines := ircLines(appendFallbackText(msg.Content, msg.Attachments, msg.Buttons), ircMaxLine-ircPrefixAllowance-len("PRIVMSG "+msg.ChatID+" :"))
+	if len(lines) == 0 {
+		return nil
+	}
+	if asker, ok := c.askers.LoadAndDelete(strings.ToLower(msg.ChatID)); ok {
+		lines[0] = asker.(string) + ": " + lines[0]
+	}
+
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	for _, line := range lines {
+		if err := c.throttle(ctx); err != nil {
+			return err
+		}
+		if err := c.writeLine(conn, "PRIVMSG %s :%s", msg.ChatID, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// throttle paces lines so the server does not disconnect the agent for
+// flooding: a burst of lines goes out at once, the rest one per interval.
+func (c *IRCChannel) throttle(ctx context.Context) error {
+	c.floodMu.Lock()
+	defer c.floodMu.Unlock()
+	now := time.Now()
+	if c.floodNext.Before(now) {
+		c.floodNext = now
+	}
+	if wait := c.floodNext.Sub(now) - ircFloodBurst*c.floodInterval; wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	c.floodNext = c.floodNext.Add(c.floodInterval)
+	return nil
+}
+
+func (c *IRCChannel) writeLine(conn net.Conn, format string, args ...any) error {
+	line := fmt.Sprintf(format, args...)
+	// A line break in a parameter would smuggle in another command.
+	line = strings.NewReplacer("\r", " ", "\n", " ").Replace(line)
+	conn.SetWriteDeadline(time.Now().Add(ircDialTimeout))
+	_, err := conn.Write([]byte(line + "\r\n"))
+	return err
+}
+
+func (c *IRCChannel) currentNick() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.nick
+}
+
+func (c *IRCChannel) setNick(nick string) {
+	c.mu.Lock()
+	c.nick = nick
+	c.mu.Unlock()
+}
+
+// channelKey returns the key configured for a channel, if any.
+func (c *IRCChannel) channelKey(channel string) string {
+	for _, entry := range c.config.Channels {
+		name, key, _ := strings.Cut(entry, " ")
+		if strings.EqualFold(name, channel) {
+			return strings.TrimSpace(key)
+		}
+	}
+	return ""
+}
+
+// ircLines turns a reply into lines that fit one PRIVMSG each. Code fences
+// and blank lines are dropped, as IRC clients show neither.
+func ircLines(content string, maxLen int) []string {
+	var lines []string
+	for _, chunk := range utils.SplitMessage(content, maxLen) {
+		for _, line := range strings.Split(chunk, "\n") {
+			line = strings.TrimRight(line, " \t\r")
+			if strings.TrimSpace(line) == "" || strings.HasPrefix(strings.TrimSpace(line), "```") {
+				continue
+			}
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// ircMention reports whether text mentions nick, either addressed to it
+// ("nick: question") or anywhere as a word. An address is stripped.
+func ircMention(text, nick string) (string, bool) {
+	if nick == "" {
+		return text, false
+	}
+	if len(text) >= len(nick) && strings.EqualFold(text[:len(nick)], nick) {
+		rest := text[len(nick):]
+		if rest == "" || strings.ContainsRune(":, ", rune(rest[0])) {
+			return strings.TrimSpace(strings.TrimLeft(rest, ":,")), true
+		}
+	}
+	lower, lowerNick := strings.ToLower(text), strings.ToLower(nick)
+	for i := strings.Index(lower, lowerNick); i >= 0; {
+		end := i + len(lowerNick)
+		if (i == 0 || !isIRCNickChar(lower[i-1])) && (end == len(lower) || !isIRCNickChar(lower[end])) {
+			return text, true
+		}
+		next := strings.Index(lower[i+1:], lowerNick)
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return text, false
+}
+
+func isIRCNickChar(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || strings.IndexByte("-_[]\\`^{}|", b) >= 0
+}
+
+func isIRCChannel(target string) bool {
+	return target != "" && strings.ContainsRune("#&+!", rune(target[0]))
+}
+
+// parseIRCLine parses "@tags :prefix COMMAND params :trailing".
+func parseIRCLine(line string) (ircMessage, bool) {
+	var msg ircMessage
+	if strings.HasPrefix(line, "@") {
+		tags, rest, _ := strings.Cut(line[1:], " ")
+		msg.tags = make(map[string]string)
+		for _, tag := range strings.Split(tags, ";") {
+			key, value, _ := strings.Cut(tag, "=")
+			msg.tags[key] = value
+		}
+		line = rest
+	}
+	line = strings.TrimLeft(line, " ")
+	if strings.HasPrefix(line, ":") {
+		msg.prefix, line, _ = strings.Cut(line[1:], " ")
+	}
+	for line != "" {
+		line = strings.TrimLeft(line, " ")
+		if strings.HasPrefix(line, ":") {
+			msg.params = append(msg.params, line[1:])
+			break
+		}
+		var param string
+		param, line, _ = strings.Cut(line, " ")
+		if msg.command == "" {
+			msg.command = strings.ToUpper(param)
+		} else if param != "" {
+			msg.params = append(msg.params, param)
+		}
+	}
+	return msg, msg.command != ""
+}
+
+func (m ircMessage) param(i int) string {
+	if i < 0 || i >= len(m.params) {
+		return ""
+	}
+	return m.params[i]
+}
+
+// nick returns the nick from a "nick!user@host" prefix.
+func (m ircMessage) nick() string {
+	nick, _, _ := strings.Cut(m.prefix, "!")
+	return nick
+}
@@ -37,6 +37,14 @@ type replyTokenEntry struct {
 	timestamp time.Time
 }
 
+// lineQuote is the quote token of the last message received in a chat.
+// LINE can only quote messages by token, so only that message can be
+// replied to.
+type lineQuote struct {
+	messageID string
+	token     string
+}
+
 // LINEChannel implements the Channel interface for LINE Official Account
 // using the LINE Messaging API with HTTP webhook for receiving messages
 // and REST API for sending messages.
@@ -48,7 +56,7 @@ type LINEChannel struct {
 	botBasicID     string   // Bot's basic ID (e.g. @216ru...)
 	botDisplayName string   // Bot's display name for text-based mention detection
 	replyTokens    sync.Map // chatID -> replyTokenEntry
-	quoteTokens    sync.Map // chatID -> quoteToken (string)
+	quoteTokens    sync.Map // chatID -> lineQuote
 	ctx            context.Context
 	cancel         context.CancelFunc
 }
@@ -302,7 +310,7 @@ func (c *LINEChannel) processEvent(event lineEvent) {
 
 	// Store quote token for quoting the original message in reply
 	if msg.QuoteToken != "" {
-		c.quoteTokens.Store(chatID, msg.QuoteToken)
+		c.quoteTokens.Store(chatID, lineQuote{messageID: msg.ID, token: msg.QuoteToken})
 	}
 
 	var content string
@@ -339,7 +347,7 @@ func (c *LINEChannel) processEvent(event lineEvent) {
 		if localPath != "" {
 			localFiles = append(localFiles, localPath)
 			mediaPaths = append(mediaPaths, localPath)
-			content = "[audio]"
+			content = c.voiceText(c.ctx, localPath)
 		}
 	case "video":
 		localPath := c.downloadContent(msg.ID, "video.mp4")
@@ -383,7 +391,9 @@ func (c *LINEChannel) processEvent(event lineEvent) {
 	})
 
 	// Show typing/loading indicator (requires user ID, not group ID)
-	c.sendLoading(senderID)
+	if c.StatusMode() != config.StatusOff {
+		c.sendLoading(senderID)
+	}
 
 	c.HandleMessage(senderID, chatID, content, mediaPaths, metadata)
 }
@@ -497,14 +507,19 @@ func (c *LINEChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 	// Load and consume quote token for this chat
 	var quoteToken string
 	if qt, ok := c.quoteTokens.LoadAndDelete(msg.ChatID); ok {
-		quoteToken = qt.(string)
+		quote := qt.(lineQuote)
+		if msg.ReplyTo == "" || msg.ReplyTo == quote.messageID {
+			quoteToken = quote.token
+		}
 	}
 
+	messages := buildLINEMessages(msg, quoteToken)
+
 	// Try reply token first (free, valid for ~25 seconds)
 	if entry, ok := c.replyTokens.LoadAndDelete(msg.ChatID); ok {
 		tokenEntry := entry.(replyTokenEntry)
 		if time.Since(tokenEntry.timestamp) < lineReplyTokenMaxAge {
-			if err := c.sendReply(ctx, tokenEntry.token, msg.Content, quoteToken); err == nil {
+			if err := c.sendReply(ctx, tokenEntry.token, messages); err == nil {
 				logger.DebugCF("line", "Message sent via Reply API", map[string]any{
 					"chat_id": msg.ChatID,
 					"quoted":  quoteToken != "",
@@ -516,12 +531,72 @@ func (c *LINEChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 	}
 
 	// Fall back to Push API
-	return c.sendPush(ctx, msg.ChatID, msg.Content, quoteToken)
+	return c.sendPush(ctx, msg.ChatID, messages)
+}
+
+// LINE limits on message objects per request and quick reply items.
+const (
+	lineMaxMessages         = 5
+	lineMaxQuickReplyItems  = 13
+	lineMaxQuickReplyLabel  = 20
+	lineMaxImageMessageURLs = lineMaxMessages - 1 // leave room for the text
+)
+
+// buildLINEMessages converts an outbound message into LINE message objects.
+// LINE only accepts media by HTTPS URL, so HTTPS images become image
+// messages and every other attachment is listed in the text. Buttons become
+// quick replies on the last message.
+func buildLINEMessages(msg bus.OutboundMessage, quoteToken string) []map[string]any {
+	var images []map[string]any
+	var listed []bus.Attachment
+	for _, a := range msg.Attachments {
+		if a.Kind() == bus.AttachmentImage && strings.HasPrefix(a.URL, "https://") && len(images) < lineMaxImageMessageURLs {
+			images = append(images, map[string]any{
+				"type":               "image",
+				"originalContentUrl": a.URL,
+				"previewImageUrl":    a.URL,
+			})
+			continue
+		}
+		listed = append(listed, a)
+	}
+
+	var messages []map[string]any
+	if text := appendFallbackText(msg.Content, listed, nil); text != "" || len(images) == 0 {
+		messages = append(messages, buildTextMessage(text, quoteToken))
+	}
+	messages = append(messages, images...)
+
+	if items := lineQuickReplyItems(msg.Buttons); len(items) > 0 {
+		messages[len(messages)-1]["quickReply"] = map[string]any{"items": items}
+	}
+	return messages
+}
+
+// lineQuickReplyItems converts buttons into quick reply actions. Link
+// buttons open their URL; the rest send their reply text.
+func lineQuickReplyItems(buttons []bus.Button) []map[string]any {
+	var items []map[string]any
+	for _, b := range buttons {
+		if len(items) == lineMaxQuickReplyItems {
+			break
+		}
+		label := b.Text
+		if r := []rune(label); len(r) > lineMaxQuickReplyLabel {
+			label = string(r[:lineMaxQuickReplyLabel])
+		}
+		action := map[string]any{"type": "message", "label": label, "text": b.Reply()}
+		if b.URL != "" {
+			action = map[string]any{"type": "uri", "label": label, "uri": b.URL}
+		}
+		items = append(items, map[string]any{"type": "action", "action": action})
+	}
+	return items
 }
 
 // buildTextMessage creates a text message object, optionally with quoteToken.
-func buildTextMessage(content, quoteToken string) map[string]string {
-	msg := map[string]string{
+func buildTextMessage(content, quoteToken string) map[string]any {
+	msg := map[string]any{
 		"type": "text",
 		"text": content,
 	}
@@ -531,21 +606,21 @@ func buildTextMessage(content, quoteToken string) map[string]string {
 	return msg
 }
 
-// sendReply sends a message using the LINE Reply API.
-func (c *LINEChannel) sendReply(ctx context.Context, replyToken, content, quoteToken string) error {
+// sendReply sends messages using the LINE Reply API.
+func (c *LINEChannel) sendReply(ctx context.Context, replyToken string, messages []map[string]any) error {
 	payload := map[string]any{
 		"replyToken": replyToken,
-		"messages":   []map[string]string{buildTextMessage(content, quoteToken)},
+		"messages":   messages,
 	}
 
 	return c.callAPI(ctx, lineReplyEndpoint, payload)
 }
 
-// sendPush sends a message using the LINE Push API.
-func (c *LINEChannel) sendPush(ctx context.Context, to, content, quoteToken string) error {
+// sendPush sends messages using the LINE Push API.
+func (c *LINEChannel) sendPush(ctx context.Context, to string, messages []map[string]any) error {
 	payload := map[string]any{
 		"to":       to,
-		"messages": []map[string]string{buildTextMessage(content, quoteToken)},
+		"messages": messages,
 	}
 
 	return c.callAPI(ctx, linePushEndpoint, payload)
@@ -2,14 +2,18 @@ package channels
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"net"
+	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/Agentx-network/agentx/pkg/bus"
 	"github.com/Agentx-network/agentx/pkg/config"
 	"github.com/Agentx-network/agentx/pkg/logger"
+	"github.com/Agentx-network/agentx/pkg/voice"
 )
 
 type MaixCamChannel struct {
@@ -203,6 +207,15 @@ func (c *MaixCamChannel) Stop(ctx context.Context) error {
 	return nil
 }
 
+// VoiceFormat reports that the device plays spoken replies as WAV, when
+// its firmware handles audio.
+func (c *MaixCamChannel) VoiceFormat() string {
+	if !c.config.Audio {
+		return ""
+	}
+	return voice.FormatWAV
+}
+
 func (c *MaixCamChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 	if !c.IsRunning() {
 		return fmt.Errorf("maixcam channel not running")
@@ -216,12 +229,30 @@ func (c *MaixCamChannel) Send(ctx context.Context, msg bus.OutboundMessage) erro
 		return fmt.Errorf("no connected MaixCam devices")
 	}
 
+	// A device that handles audio plays the first WAV attachment (a spoken
+	// reply); anything else is listed in the message.
+	var others []bus.Attachment
+	var audio []byte
+	for _, a := range msg.Attachments {
+		if c.config.Audio && audio == nil && a.Kind() == bus.AttachmentAudio && strings.EqualFold(filepath.Ext(a.Name()), ".wav") {
+			if data, err := readAttachment(ctx, a); err == nil {
+				audio = data
+				continue
+			}
+		}
+		others = append(others, a)
+	}
+
 	response := map[string]any{
 		"type":      "command",
 		"timestamp": float64(0),
-		"message":   msg.Content,
+		"message":   appendFallbackText(msg.Content, others, msg.Buttons),
 		"chat_id":   msg.ChatID,
 	}
+	if audio != nil {
+		response["audio"] = base64.StdEncoding.EncodeToString(audio)
+		response["audio_format"] = voice.FormatWAV
+	}
 
 	data, err := json.Marshal(response)
 	if err != nil {
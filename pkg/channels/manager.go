@@ -9,21 +9,36 @@ package channels
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"os"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/Agentx-network/agentx/pkg/bus"
 	"github.com/Agentx-network/agentx/pkg/config"
 	"github.com/Agentx-network/agentx/pkg/constants"
 	"github.com/Agentx-network/agentx/pkg/logger"
+	"github.com/Agentx-network/agentx/pkg/routing"
+	"github.com/Agentx-network/agentx/pkg/voice"
 )
 
+// speechTimeout bounds how long a reply may take to synthesize before it
+// is sent as text.
+const speechTimeout = 60 * time.Second
+
 type Manager struct {
 	channels     map[string]Channel
 	bus          *bus.MessageBus
 	config       *config.Config
 	dispatchTask *asyncTask
 	streamTask   *asyncTask
+	statusTask   *asyncTask
+	synthesizer  voice.Synthesizer
 	mu           sync.RWMutex
+
+	statusMu sync.Mutex
+	statuses map[string]*chatStatus // channel and chat ID -> chat shown busy
 }
 
 type asyncTask struct {
@@ -47,167 +62,140 @@ func NewManager(cfg *config.Config, messageBus *bus.MessageBus) (*Manager, error
 func (m *Manager) initChannels() error {
 	logger.InfoC("channels", "Initializing channel manager")
 
-	if m.config.Channels.Telegram.Enabled && m.config.Channels.Telegram.Token != "" {
-		logger.DebugC("channels", "Attempting to initialize Telegram channel")
-		telegram, err := NewTelegramChannel(m.config, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize Telegram channel", map[string]any{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["telegram"] = telegram
-			logger.InfoC("channels", "Telegram channel enabled successfully")
-		}
-	}
+	ch := m.config.Channels
+	addChannel(m, "telegram", "Telegram", ch.Telegram, ch.Telegram.Accounts,
+		func(c config.TelegramConfig) bool { return c.Enabled && c.Token != "" },
+		func(c config.TelegramConfig) (Channel, error) {
+			// Telegram reads the rest of the config for its commands.
+			cfg := *m.config
+			cfg.Channels.Telegram = c
+			return NewTelegramChannel(&cfg, m.bus)
+		})
+	addChannel(m, "whatsapp", "WhatsApp", ch.WhatsApp, ch.WhatsApp.Accounts,
+		func(c config.WhatsAppConfig) bool { return c.Enabled && c.BridgeURL != "" },
+		func(c config.WhatsAppConfig) (Channel, error) { return NewWhatsAppChannel(c, m.bus) })
+	addChannel(m, "feishu", "Feishu", ch.Feishu, ch.Feishu.Accounts,
+		func(c config.FeishuConfig) bool { return c.Enabled },
+		func(c config.FeishuConfig) (Channel, error) { return NewFeishuChannel(c, m.bus) })
+	addChannel(m, "discord", "Discord", ch.Discord, ch.Discord.Accounts,
+		func(c config.DiscordConfig) bool { return c.Enabled && c.Token != "" },
+		func(c config.DiscordConfig) (Channel, error) { return NewDiscordChannel(c, m.bus) })
+	addChannel(m, "maixcam", "MaixCam", ch.MaixCam, ch.MaixCam.Accounts,
+		func(c config.MaixCamConfig) bool { return c.Enabled },
+		func(c config.MaixCamConfig) (Channel, error) { return NewMaixCamChannel(c, m.bus) })
+	addChannel(m, "qq", "QQ", ch.QQ, ch.QQ.Accounts,
+		func(c config.QQConfig) bool { return c.Enabled },
+		func(c config.QQConfig) (Channel, error) { return NewQQChannel(c, m.bus) })
+	addChannel(m, "dingtalk", "DingTalk", ch.DingTalk, ch.DingTalk.Accounts,
+		func(c config.DingTalkConfig) bool { return c.Enabled && c.ClientID != "" },
+		func(c config.DingTalkConfig) (Channel, error) { return NewDingTalkChannel(c, m.bus) })
+	addChannel(m, "slack", "Slack", ch.Slack, ch.Slack.Accounts,
+		func(c config.SlackConfig) bool { return c.Enabled && c.BotToken != "" },
+		func(c config.SlackConfig) (Channel, error) { return NewSlackChannel(c, m.bus) })
+	addChannel(m, "line", "LINE", ch.LINE, ch.LINE.Accounts,
+		func(c config.LINEConfig) bool { return c.Enabled && c.ChannelAccessToken != "" },
+		func(c config.LINEConfig) (Channel, error) { return NewLINEChannel(c, m.bus) })
+	addChannel(m, "onebot", "OneBot", ch.OneBot, ch.OneBot.Accounts,
+		func(c config.OneBotConfig) bool { return c.Enabled && c.WSUrl != "" },
+		func(c config.OneBotConfig) (Channel, error) { return NewOneBotChannel(c, m.bus) })
+	addChannel(m, "wecom", "WeCom", ch.WeCom, ch.WeCom.Accounts,
+		func(c config.WeComConfig) bool { return c.Enabled && c.Token != "" },
+		func(c config.WeComConfig) (Channel, error) { return NewWeComBotChannel(c, m.bus) })
+	addChannel(m, "wecom_app", "WeCom App", ch.WeComApp, ch.WeComApp.Accounts,
+		func(c config.WeComAppConfig) bool { return c.Enabled && c.CorpID != "" },
+		func(c config.WeComAppConfig) (Channel, error) { return NewWeComAppChannel(c, m.bus) })
+	addChannel(m, "email", "Email", ch.Email, ch.Email.Accounts,
+		func(c config.EmailConfig) bool { return c.Enabled && c.IMAPHost != "" },
+		func(c config.EmailConfig) (Channel, error) { return NewEmailChannel(c, m.bus) })
+	addChannel(m, "matrix", "Matrix", ch.Matrix, ch.Matrix.Accounts,
+		func(c config.MatrixConfig) bool { return c.Enabled && c.AccessToken != "" },
+		func(c config.MatrixConfig) (Channel, error) { return NewMatrixChannel(c, m.bus) })
+	addChannel(m, "signal", "Signal", ch.Signal, ch.Signal.Accounts,
+		func(c config.SignalConfig) bool { return c.Enabled && c.Socket != "" },
+		func(c config.SignalConfig) (Channel, error) { return NewSignalChannel(c, m.bus) })
+	addChannel(m, "mqtt", "MQTT", ch.MQTT, ch.MQTT.Accounts,
+		func(c config.MQTTConfig) bool { return c.Enabled && c.Broker != "" },
+		func(c config.MQTTConfig) (Channel, error) { return NewMQTTChannel(c, m.bus) })
+	addChannel(m, "webhook", "Webhook", ch.Webhook, ch.Webhook.Accounts,
+		func(c config.WebhookConfig) bool { return c.Enabled },
+		func(c config.WebhookConfig) (Channel, error) { return NewWebhookChannel(c, m.bus) })
+	addChannel(m, "irc", "IRC", ch.IRC, ch.IRC.Accounts,
+		func(c config.IRCConfig) bool { return c.Enabled && c.Server != "" },
+		func(c config.IRCConfig) (Channel, error) { return NewIRCChannel(c, m.bus) })
+	addChannel(m, "mattermost", "Mattermost", ch.Mattermost, ch.Mattermost.Accounts,
+		func(c config.MattermostConfig) bool { return c.Enabled && c.Token != "" },
+		func(c config.MattermostConfig) (Channel, error) { return NewMattermostChannel(c, m.bus) })
+	addChannel(m, "rocketchat", "Rocket.Chat", ch.RocketChat, ch.RocketChat.Accounts,
+		func(c config.RocketChatConfig) bool { return c.Enabled && c.Token != "" },
+		func(c config.RocketChatConfig) (Channel, error) { return NewRocketChatChannel(c, m.bus) })
 
-	if m.config.Channels.WhatsApp.Enabled && m.config.Channels.WhatsApp.BridgeURL != "" {
-		logger.DebugC("channels", "Attempting to initialize WhatsApp channel")
-		whatsapp, err := NewWhatsAppChannel(m.config.Channels.WhatsApp, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize WhatsApp channel", map[string]any{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["whatsapp"] = whatsapp
-			logger.InfoC("channels", "WhatsApp channel enabled successfully")
-		}
-	}
+	logger.InfoCF("channels", "Channel initialization completed", map[string]any{
+		"enabled_channels": len(m.channels),
+	})
 
-	if m.config.Channels.Feishu.Enabled {
-		logger.DebugC("channels", "Attempting to initialize Feishu channel")
-		feishu, err := NewFeishuChannel(m.config.Channels.Feishu, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize Feishu channel", map[string]any{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["feishu"] = feishu
-			logger.InfoC("channels", "Feishu channel enabled successfully")
-		}
-	}
+	return nil
+}
 
-	if m.config.Channels.Discord.Enabled && m.config.Channels.Discord.Token != "" {
-		logger.DebugC("channels", "Attempting to initialize Discord channel")
-		discord, err := NewDiscordChannel(m.config.Channels.Discord, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize Discord channel", map[string]any{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["discord"] = discord
-			logger.InfoC("channels", "Discord channel enabled successfully")
-		}
+// addChannel sets up the instances of a channel type: one for the type's
+// own settings and one per account, each created when enabled accepts its
+// settings. Account instances are named "type/account".
+func addChannel[T any](m *Manager, name, label string, cfg T, accounts config.ChannelAccounts,
+	enabled func(T) bool, create func(T) (Channel, error),
+) {
+	if enabled(cfg) {
+		m.addInstance(name, label, "", func() (Channel, error) { return create(cfg) })
 	}
 
-	if m.config.Channels.MaixCam.Enabled {
-		logger.DebugC("channels", "Attempting to initialize MaixCam channel")
-		maixcam, err := NewMaixCamChannel(m.config.Channels.MaixCam, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize MaixCam channel", map[string]any{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["maixcam"] = maixcam
-			logger.InfoC("channels", "MaixCam channel enabled successfully")
-		}
+	resolved, err := config.ResolveAccounts(cfg, accounts)
+	if err != nil {
+		logger.ErrorCF("channels", "Invalid "+label+" accounts", map[string]any{
+			"error": err.Error(),
+		})
+		return
 	}
-
-	if m.config.Channels.QQ.Enabled {
-		logger.DebugC("channels", "Attempting to initialize QQ channel")
-		qq, err := NewQQChannel(m.config.Channels.QQ, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize QQ channel", map[string]any{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["qq"] = qq
-			logger.InfoC("channels", "QQ channel enabled successfully")
-		}
+	ids := make([]string, 0, len(resolved))
+	for id := range resolved {
+		ids = append(ids, id)
 	}
-
-	if m.config.Channels.DingTalk.Enabled && m.config.Channels.DingTalk.ClientID != "" {
-		logger.DebugC("channels", "Attempting to initialize DingTalk channel")
-		dingtalk, err := NewDingTalkChannel(m.config.Channels.DingTalk, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize DingTalk channel", map[string]any{
-				"error": err.Error(),
+	sort.Strings(ids)
+	for _, id := range ids {
+		accountCfg := resolved[id]
+		accountID := routing.NormalizeAccountID(id)
+		if accountID == routing.DefaultAccountID {
+			logger.ErrorCF("channels", "Account ID is reserved for the channel's own settings", map[string]any{
+				"channel": name,
+				"account": id,
 			})
-		} else {
-			m.channels["dingtalk"] = dingtalk
-			logger.InfoC("channels", "DingTalk channel enabled successfully")
+			continue
 		}
-	}
-
-	if m.config.Channels.Slack.Enabled && m.config.Channels.Slack.BotToken != "" {
-		logger.DebugC("channels", "Attempting to initialize Slack channel")
-		slackCh, err := NewSlackChannel(m.config.Channels.Slack, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize Slack channel", map[string]any{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["slack"] = slackCh
-			logger.InfoC("channels", "Slack channel enabled successfully")
+		if enabled(accountCfg) {
+			m.addInstance(name, label, accountID, func() (Channel, error) { return create(accountCfg) })
 		}
 	}
+}
 
-	if m.config.Channels.LINE.Enabled && m.config.Channels.LINE.ChannelAccessToken != "" {
-		logger.DebugC("channels", "Attempting to initialize LINE channel")
-		line, err := NewLINEChannel(m.config.Channels.LINE, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize LINE channel", map[string]any{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["line"] = line
-			logger.InfoC("channels", "LINE channel enabled successfully")
-		}
+func (m *Manager) addInstance(name, label, accountID string, create func() (Channel, error)) {
+	if accountID != "" {
+		name = AccountChannelName(name, accountID)
 	}
-
-	if m.config.Channels.OneBot.Enabled && m.config.Channels.OneBot.WSUrl != "" {
-		logger.DebugC("channels", "Attempting to initialize OneBot channel")
-		onebot, err := NewOneBotChannel(m.config.Channels.OneBot, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize OneBot channel", map[string]any{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["onebot"] = onebot
-			logger.InfoC("channels", "OneBot channel enabled successfully")
-		}
+	fields := map[string]any{"channel": name}
+	if _, exists := m.channels[name]; exists {
+		logger.ErrorCF("channels", "Duplicate "+label+" account", fields)
+		return
 	}
 
-	if m.config.Channels.WeCom.Enabled && m.config.Channels.WeCom.Token != "" {
-		logger.DebugC("channels", "Attempting to initialize WeCom channel")
-		wecom, err := NewWeComBotChannel(m.config.Channels.WeCom, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize WeCom channel", map[string]any{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["wecom"] = wecom
-			logger.InfoC("channels", "WeCom channel enabled successfully")
-		}
+	logger.DebugCF("channels", "Attempting to initialize "+label+" channel", fields)
+	channel, err := create()
+	if err != nil {
+		fields["error"] = err.Error()
+		logger.ErrorCF("channels", "Failed to initialize "+label+" channel", fields)
+		return
 	}
-
-	if m.config.Channels.WeComApp.Enabled && m.config.Channels.WeComApp.CorpID != "" {
-		logger.DebugC("channels", "Attempting to initialize WeCom App channel")
-		wecomApp, err := NewWeComAppChannel(m.config.Channels.WeComApp, m.bus)
-		if err != nil {
-			logger.ErrorCF("channels", "Failed to initialize WeCom App channel", map[string]any{
-				"error": err.Error(),
-			})
-		} else {
-			m.channels["wecom_app"] = wecomApp
-			logger.InfoC("channels", "WeCom App channel enabled successfully")
-		}
+	if accountID != "" {
+		channel.(interface{ setAccount(string) }).setAccount(accountID)
 	}
-
-	logger.InfoCF("channels", "Channel initialization completed", map[string]any{
-		"enabled_channels": len(m.channels),
-	})
-
-	return nil
+	m.channels[name] = channel
+	logger.InfoCF("channels", label+" channel enabled successfully", fields)
 }
 
 func (m *Manager) StartAll(ctx context.Context) error {
@@ -230,6 +218,10 @@ func (m *Manager) StartAll(ctx context.Context) error {
 	m.streamTask = &asyncTask{cancel: streamCancel}
 	go m.dispatchStream(streamCtx)
 
+	statusCtx, statusCancel := context.WithCancel(ctx)
+	m.statusTask = &asyncTask{cancel: statusCancel}
+	go m.dispatchStatus(statusCtx)
+
 	for name, channel := range m.channels {
 		logger.InfoCF("channels", "Starting channel", map[string]any{
 			"channel": name,
@@ -264,6 +256,10 @@ func (m *Manager) StopAll(ctx context.Context) error {
 		m.streamTask.cancel()
 		m.streamTask = nil
 	}
+	if m.statusTask != nil {
+		m.statusTask.cancel()
+		m.statusTask = nil
+	}
 
 	for name, channel := range m.channels {
 		logger.InfoCF("channels", "Stopping channel", map[string]any{
@@ -311,12 +307,24 @@ func (m *Manager) dispatchOutbound(ctx context.Context) {
 				continue
 			}
 
+			if rc, ok := channel.(ReasoningChannel); !ok || !rc.FoldsReasoning() {
+				msg = withReasoningText(msg)
+			}
+
+			var speech string
+			if msg.Speak {
+				msg, speech = m.speak(ctx, channel, msg)
+			}
+
 			if err := channel.Send(ctx, msg); err != nil {
 				logger.ErrorCF("channels", "Error sending message to channel", map[string]any{
 					"channel": msg.Channel,
 					"error":   err.Error(),
 				})
 			}
+			if speech != "" {
+				os.Remove(speech)
+			}
 		}
 	}
 }
@@ -354,6 +362,37 @@ func (m *Manager) dispatchStream(ctx context.Context) {
 	}
 }
 
+func (m *Manager) dispatchStatus(ctx context.Context) {
+	logger.InfoC("channels", "Status dispatcher started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.InfoC("channels", "Status dispatcher stopped")
+			return
+		default:
+			update, ok := m.bus.SubscribeStatus(ctx)
+			if !ok {
+				continue
+			}
+
+			if constants.IsInternalChannel(update.Channel) {
+				continue
+			}
+
+			m.mu.RLock()
+			channel, exists := m.channels[update.Channel]
+			m.mu.RUnlock()
+
+			if !exists {
+				continue
+			}
+
+			m.handleStatus(ctx, channel, update)
+		}
+	}
+}
+
 func (m *Manager) GetChannel(name string) (Channel, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -386,6 +425,98 @@ func (m *Manager) GetEnabledChannels() []string {
 	return names
 }
 
+// SetSynthesizer sets the backend that speaks replies in chats where
+// /voice is on.
+func (m *Manager) SetSynthesizer(synthesizer voice.Synthesizer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.synthesizer = synthesizer
+}
+
+// speak replaces a reply's text with a voice note when the channel can play
+// one. Replies with code or links keep their text next to the voice note,
+// since those are not spoken. It returns the message to send and the audio
+// file to remove after sending; on any failure the reply stays text.
+func (m *Manager) speak(ctx context.Context, channel Channel, msg bus.OutboundMessage) (bus.OutboundMessage, string) {
+	m.mu.RLock()
+	synthesizer := m.synthesizer
+	m.mu.RUnlock()
+
+	vc, ok := channel.(VoiceReplyChannel)
+	text := voice.SpeechText(msg.Content)
+	if !ok || synthesizer == nil || !synthesizer.IsAvailable() || text == "" || len(text) > voice.MaxSpeechChars {
+		return msg, ""
+	}
+
+	format := vc.VoiceFormat()
+	if format == "" {
+		return msg, ""
+	}
+	ctx, cancel := context.WithTimeout(ctx, speechTimeout)
+	defer cancel()
+	path, err := synthesizer.Synthesize(ctx, text, format)
+	if err != nil {
+		logger.WarnCF("voice", "Speech synthesis failed, sending text", map[string]any{
+			"channel": msg.Channel,
+			"error":   err.Error(),
+		})
+		return msg, ""
+	}
+
+	mimeType := "audio/ogg"
+	if format == voice.FormatWAV {
+		mimeType = "audio/wav"
+	}
+	msg.Attachments = append([]bus.Attachment{{Path: path, MIMEType: mimeType, Filename: "reply." + format}}, msg.Attachments...)
+	if !voice.SpeechOmits(msg.Content) {
+		msg.Content = ""
+	}
+	return msg, path
+}
+
+// SetTranscriber gives every channel that receives voice messages the
+// speech-to-text backend to transcribe them with.
+func (m *Manager) SetTranscriber(transcriber voice.Transcriber) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for name, channel := range m.channels {
+		if vc, ok := channel.(VoiceChannel); ok {
+			vc.SetTranscriber(transcriber)
+			logger.DebugCF("voice", "Transcription attached to channel", map[string]any{"channel": name})
+		}
+	}
+}
+
+// HTTPHandlers returns the handlers of channels that serve on the gateway,
+// keyed by path.
+func (m *Manager) HTTPHandlers() map[string]http.Handler {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	names := make([]string, 0, len(m.channels))
+	for name := range m.channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	handlers := make(map[string]http.Handler)
+	for _, name := range names {
+		hc, ok := m.channels[name].(HTTPChannel)
+		if !ok {
+			continue
+		}
+		// Accounts inherit their channel's path unless they set their own.
+		if _, taken := handlers[hc.HTTPPath()]; taken {
+			logger.ErrorCF("channels", "HTTP path already served by another channel", map[string]any{
+				"channel": name,
+				"path":    hc.HTTPPath(),
+			})
+			continue
+		}
+		handlers[hc.HTTPPath()] = hc
+	}
+	return handlers
+}
+
 func (m *Manager) RegisterChannel(name string, channel Channel) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
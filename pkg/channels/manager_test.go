@@ -0,0 +1,151 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/config"
+	"github.com/Agentx-network/agentx/pkg/voice"
+)
+
+func TestManagerSetTranscriber(t *testing.T) {
+	line := &LINEChannel{BaseChannel: NewBaseChannel("line", nil, nil, nil)}
+	qq := &QQChannel{BaseChannel: NewBaseChannel("qq", nil, nil, nil)}
+	m := &Manager{channels: map[string]Channel{"line": line, "qq": qq}}
+
+	tr := &fakeTranscriber{}
+	m.SetTranscriber(tr)
+	if line.transcriber != tr || qq.transcriber != tr {
+		t.Error("every voice channel should get the transcriber")
+	}
+}
+
+type fakeSynthesizer struct {
+	err    error
+	text   string
+	format string
+}
+
+func (f *fakeSynthesizer) Synthesize(ctx context.Context, text, format string) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	f.text, f.format = text, format
+	out, err := os.CreateTemp("", "speech-*."+format)
+	if err != nil {
+		return "", err
+	}
+	out.Close()
+	return out.Name(), nil
+}
+
+func (f *fakeSynthesizer) IsAvailable() bool { return true }
+
+// voiceNoteChannel is a channel that plays Ogg voice notes.
+type voiceNoteChannel struct {
+	*BaseChannel
+}
+
+func (c *voiceNoteChannel) Start(ctx context.Context) error                         { return nil }
+func (c *voiceNoteChannel) Stop(ctx context.Context) error                          { return nil }
+func (c *voiceNoteChannel) Send(ctx context.Context, msg bus.OutboundMessage) error { return nil }
+func (c *voiceNoteChannel) VoiceFormat() string                                     { return voice.FormatOgg }
+
+func TestManagerSpeak(t *testing.T) {
+	synth := &fakeSynthesizer{}
+	m := &Manager{}
+	m.SetSynthesizer(synth)
+	ch := &voiceNoteChannel{NewBaseChannel("telegram", nil, nil, nil)}
+	reply := bus.OutboundMessage{Channel: "telegram", ChatID: "1", Content: "It is **sunny**.", Speak: true}
+
+	msg, speech := m.speak(context.Background(), ch, reply)
+	defer os.Remove(speech)
+	if speech == "" || msg.Content != "" || len(msg.Attachments) != 1 {
+		t.Fatalf("expected a voice note instead of text, got %+v", msg)
+	}
+	if a := msg.Attachments[0]; a.Path != speech || a.ContentType() != "audio/ogg" {
+		t.Errorf("attachment = %+v", a)
+	}
+	if synth.text != "It is sunny." || synth.format != voice.FormatOgg {
+		t.Errorf("synthesized %q as %q", synth.text, synth.format)
+	}
+
+	// Code and links are not spoken, so the text comes along.
+	withCode := reply
+	withCode.Content = "Run this:\n\n```sh\nmake test\n```\n\nSee [the docs](https://example.com/docs)."
+	msg, speech = m.speak(context.Background(), ch, withCode)
+	defer os.Remove(speech)
+	if speech == "" || msg.Content != withCode.Content || len(msg.Attachments) != 1 {
+		t.Errorf("expected the voice note and the text, got %+v", msg)
+	}
+
+	// Channels that cannot play audio keep the text.
+	text := &QQChannel{BaseChannel: NewBaseChannel("qq", nil, nil, nil)}
+	if msg, speech := m.speak(context.Background(), text, reply); speech != "" || msg.Content != reply.Content {
+		t.Errorf("qq should get text, got %+v", msg)
+	}
+
+	// As do channels whose other end cannot take audio.
+	bridge := &WhatsAppChannel{BaseChannel: NewBaseChannel("whatsapp", nil, nil, nil)}
+	if msg, speech := m.speak(context.Background(), bridge, reply); speech != "" || msg.Content != reply.Content {
+		t.Errorf("a bridge without media should get text, got %+v", msg)
+	}
+
+	// So does a failed synthesis.
+	synth.err = errors.New("quota exceeded")
+	if msg, speech := m.speak(context.Background(), ch, reply); speech != "" || msg.Content != reply.Content {
+		t.Errorf("failed synthesis should send text, got %+v", msg)
+	}
+}
+
+func TestManagerAccounts(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Channels.Webhook = config.WebhookConfig{
+		Enabled: true,
+		Path:    "/hooks/main",
+		Token:   "main",
+		Accounts: config.ChannelAccounts{
+			"Brand-B": json.RawMessage(`{"path": "/hooks/b", "token": "b"}`),
+			"brand-c": json.RawMessage(`{"enabled": false}`),
+			"default": json.RawMessage(`{"path": "/hooks/default"}`),
+			"shared":  json.RawMessage(`{}`),
+		},
+	}
+	msgBus := bus.NewMessageBus()
+	m, err := NewManager(cfg, msgBus)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := m.GetEnabledChannels()
+	sort.Strings(names)
+	if want := []string{"webhook", "webhook/brand-b", "webhook/shared"}; !slices.Equal(names, want) {
+		t.Fatalf("channels = %v, want %v", names, want)
+	}
+
+	// The shared account inherits the main path, which is served once.
+	handlers := m.HTTPHandlers()
+	if len(handlers) != 2 || handlers["/hooks/main"] != http.Handler(m.channels["webhook"].(*WebhookChannel)) {
+		t.Errorf("handlers = %v", handlers)
+	}
+
+	brand, _ := m.GetChannel("webhook/brand-b")
+	if brand.Name() != "webhook/brand-b" {
+		t.Errorf("name = %q", brand.Name())
+	}
+	brand.(*WebhookChannel).HandleMessage("u1", "c1", "hi", nil, nil)
+	msg := waitInbound(t, msgBus)
+	if msg.Channel != "webhook/brand-b" || msg.Metadata["account_id"] != "brand-b" {
+		t.Errorf("msg = %+v", msg)
+	}
+	if ChannelType(msg.Channel) != "webhook" {
+		t.Errorf("ChannelType(%q) = %q", msg.Channel, ChannelType(msg.Channel))
+	}
+}
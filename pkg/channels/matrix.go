@@ -0,0 +1,865 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"maunium.net/go/mautrix/crypto/attachment"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/config"
+	"github.com/Agentx-network/agentx/pkg/logger"
+	"github.com/Agentx-network/agentx/pkg/utils"
+	"github.com/Agentx-network/agentx/pkg/voice"
+)
+
+const (
+	matrixSyncTimeout   = 30 * time.Second
+	matrixRetryDelay    = 5 * time.Second
+	matrixStreamFlush   = time.Second // homeservers rate-limit edits
+	matrixStreamLength  = 8000
+	matrixTypingTimeout = 10 * time.Second
+	matrixHTMLFormat    = "org.matrix.custom.html"
+)
+
+// matrixEncryptedNotice is posted in an encrypted room the first time an
+// allowed user writes there when encryption could not be set up.
+const matrixEncryptedNotice = "I can't read end-to-end encrypted messages. Please talk to me in a room with encryption turned off."
+
+// MatrixChannel talks to a Matrix homeserver over the client-server API.
+// It long-polls /sync, joins rooms it is invited to by allow-listed users
+// and streams replies by editing its own message (m.replace).
+//
+// End-to-end encrypted rooms are read and answered through matrixCrypto.
+// If encryption cannot be set up, for example because the access token has
+// no device, their messages are skipped and allowed senders are told so
+// once per room in a notice.
+type MatrixChannel struct {
+	*BaseChannel
+	config      config.MatrixConfig
+	homeserver  string
+	httpClient  *http.Client
+	userID      string
+	deviceID    string
+	displayName string
+	cancel      context.CancelFunc
+	txnID       atomic.Int64
+	stream      *streamRenderer
+	crypto      *matrixCrypto
+
+	directRooms  sync.Map // room ID -> struct{}, rooms that are DMs
+	memberCounts sync.Map // room ID -> int, joined members
+	warnedRooms  sync.Map // room ID -> struct{}, encrypted rooms already warned about
+}
+
+type matrixEvent struct {
+	Type     string          `json:"type"`
+	EventID  string          `json:"event_id"`
+	Sender   string          `json:"sender"`
+	StateKey *string         `json:"state_key"`
+	Content  json.RawMessage `json:"content"`
+}
+
+type matrixSyncResponse struct {
+	NextBatch   string `json:"next_batch"`
+	AccountData struct {
+		Events []matrixEvent `json:"events"`
+	} `json:"account_data"`
+	Rooms struct {
+		Join map[string]struct {
+			Summary struct {
+				JoinedMemberCount *int `json:"m.joined_member_count"`
+			} `json:"summary"`
+			Timeline struct {
+				Events []matrixEvent `json:"events"`
+			} `json:"timeline"`
+		} `json:"join"`
+		Invite map[string]struct {
+			InviteState struct {
+				Events []matrixEvent `json:"events"`
+			} `json:"invite_state"`
+		} `json:"invite"`
+	} `json:"rooms"`
+}
+
+// matrixMessage is the content of an incoming m.room.message event.
+type matrixMessage struct {
+	MsgType       string                   `json:"msgtype"`
+	Body          string                   `json:"body"`
+	FormattedBody string                   `json:"formatted_body"`
+	URL           string                   `json:"url"`
+	File          *event.EncryptedFileInfo `json:"file"` // in place of url in encrypted rooms
+	FileName      string                   `json:"filename"`
+	Info          struct {
+		MimeType string `json:"mimetype"`
+	} `json:"info"`
+	Mentions *struct {
+		UserIDs []string `json:"user_ids"`
+	} `json:"m.mentions"`
+	RelatesTo struct {
+		RelType   string `json:"rel_type"`
+		InReplyTo *struct {
+			EventID string `json:"event_id"`
+		} `json:"m.in_reply_to"`
+	} `json:"m.relates_to"`
+}
+
+type matrixError struct {
+	ErrCode      string `json:"errcode"`
+	Error        string `json:"error"`
+	RetryAfterMs int    `json:"retry_after_ms"`
+}
+
+func NewMatrixChannel(cfg config.MatrixConfig, messageBus *bus.MessageBus) (*MatrixChannel, error) {
+	if cfg.Homeserver == "" || cfg.AccessToken == "" {
+		return nil, fmt.Errorf("matrix homeserver and access_token are required")
+	}
+	homeserver := strings.TrimRight(cfg.Homeserver, "/")
+	if !strings.Contains(homeserver, "://") {
+		homeserver = "https://" + homeserver
+	}
+
+	c := &MatrixChannel{
+		BaseChannel: NewBaseChannel("matrix", cfg, messageBus, cfg.AllowFrom),
+		config:      cfg,
+		homeserver:  homeserver,
+		httpClient:  &http.Client{Timeout: matrixSyncTimeout + 30*time.Second},
+		userID:      cfg.UserID,
+	}
+	c.stream = &streamRenderer{
+		name:     "matrix",
+		interval: matrixStreamFlush,
+		maxLen:   matrixStreamLength,
+		post: func(ctx context.Context, roomID, text string) (string, error) {
+			return c.sendEvent(ctx, roomID, matrixTextContent(text, ""))
+		},
+		edit: func(ctx context.Context, roomID, eventID, text string) error {
+			_, err := c.sendEvent(ctx, roomID, matrixEditContent(eventID, matrixTextContent(text, "")))
+			return err
+		},
+		remove: c.redactEvent,
+	}
+	return c, nil
+}
+
+func (c *MatrixChannel) Start(ctx context.Context) error {
+	logger.InfoCF("matrix", "Starting Matrix channel", map[string]any{
+		"homeserver": c.homeserver,
+	})
+
+	var whoami struct {
+		UserID   string `json:"user_id"`
+		DeviceID string `json:"device_id"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/_matrix/client/v3/account/whoami", nil, nil, &whoami); err != nil {
+		return fmt.Errorf("matrix whoami: %w", err)
+	}
+	if c.userID == "" {
+		c.userID = whoami.UserID
+	}
+	c.deviceID = whoami.DeviceID
+	var profile struct {
+		DisplayName string `json:"displayname"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/_matrix/client/v3/profile/"+url.PathEscape(c.userID)+"/displayname", nil, nil, &profile); err == nil {
+		c.displayName = profile.DisplayName
+	}
+
+	storePath := c.config.CryptoStore
+	if storePath == "" {
+		storePath = matrixCryptoStorePath()
+	}
+	mc, err := newMatrixCrypto(ctx, c.homeserver, c.userID, c.deviceID, c.config.AccessToken, storePath, c.config.PickleKey)
+	if err != nil {
+		logger.WarnCF("matrix", "End-to-end encryption unavailable; messages in encrypted rooms are ignored", map[string]any{
+			"error": err.Error(),
+		})
+	} else {
+		c.crypto = mc
+	}
+
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.setRunning(true)
+	go c.syncLoop(ctx)
+
+	logger.InfoCF("matrix", "Matrix channel started", map[string]any{
+		"user_id":   c.userID,
+		"device_id": c.deviceID,
+		"encrypted": c.crypto != nil,
+	})
+	return nil
+}
+
+// matrixCryptoStorePath returns ~/.agentx/matrix/crypto.db, where the
+// encryption keys are kept unless crypto_store says otherwise.
+func matrixCryptoStorePath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".agentx", "matrix", "crypto.db")
+}
+
+func (c *MatrixChannel) Stop(ctx context.Context) error {
+	logger.InfoC("matrix", "Stopping Matrix channel")
+	c.setRunning(false)
+	if c.cancel != nil {
+		c.cancel()
+	}
+	if c.crypto != nil {
+		c.crypto.close()
+	}
+	return nil
+}
+
+// FoldsReasoning reports that reasoning traces are sent in a collapsed
+// <details> block.
+func (c *MatrixChannel) FoldsReasoning() bool {
+	return true
+}
+
+// VoiceFormat reports that spoken replies are sent as Ogg Opus audio.
+func (c *MatrixChannel) VoiceFormat() string {
+	return voice.FormatOgg
+}
+
+// syncLoop long-polls /sync. The first sync only picks up invites, DM
+// rooms and the sync token, so old messages are not answered on startup.
+func (c *MatrixChannel) syncLoop(ctx context.Context) {
+	since := ""
+	for ctx.Err() == nil {
+		resp, err := c.sync(ctx, since)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.WarnCF("matrix", "Sync failed, retrying", map[string]any{
+				"error": err.Error(),
+			})
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(matrixRetryDelay):
+			}
+			continue
+		}
+		c.handleSync(ctx, resp, since == "")
+		since = resp.NextBatch
+	}
+}
+
+func (c *MatrixChannel) sync(ctx context.Context, since string) (*matrixSyncResponse, error) {
+	query := url.Values{}
+	if since == "" {
+		query.Set("filter", `{"room":{"timeline":{"limit":1}}}`)
+		query.Set("timeout", "0")
+	} else {
+		query.Set("since", since)
+		query.Set("timeout", strconv.Itoa(int(matrixSyncTimeout/time.Millisecond)))
+	}
+	var raw json.RawMessage
+	if err := c.do(ctx, http.MethodGet, "/_matrix/client/v3/sync", query, nil, &raw); err != nil {
+		return nil, err
+	}
+	var resp matrixSyncResponse
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, err
+	}
+	if c.crypto != nil {
+		// Room keys arrive as to-device events and have to be stored
+		// before the timeline is decrypted.
+		c.crypto.processSync(ctx, raw, since)
+	}
+	return &resp, nil
+}
+
+func (c *MatrixChannel) handleSync(ctx context.Context, resp *matrixSyncResponse, initial bool) {
+	for _, ev := range resp.AccountData.Events {
+		if ev.Type != "m.direct" {
+			continue
+		}
+		var direct map[string][]string
+		if json.Unmarshal(ev.Content, &direct) == nil {
+			for _, rooms := range direct {
+				for _, roomID := range rooms {
+					c.directRooms.Store(roomID, struct{}{})
+				}
+			}
+		}
+	}
+
+	for roomID, room := range resp.Rooms.Invite {
+		c.handleInvite(ctx, roomID, room.InviteState.Events)
+	}
+
+	for roomID, room := range resp.Rooms.Join {
+		if n := room.Summary.JoinedMemberCount; n != nil {
+			c.memberCounts.Store(roomID, *n)
+		}
+		if initial {
+			continue
+		}
+		for _, ev := range room.Timeline.Events {
+			c.handleEvent(ctx, roomID, ev)
+		}
+	}
+}
+
+// handleInvite joins a room when the invite comes from an allow-listed user.
+func (c *MatrixChannel) handleInvite(ctx context.Context, roomID string, events []matrixEvent) {
+	for _, ev := range events {
+		if ev.Type != "m.room.member" || ev.StateKey == nil || *ev.StateKey != c.userID {
+			continue
+		}
+		var member struct {
+			Membership string `json:"membership"`
+			IsDirect   bool   `json:"is_direct"`
+		}
+		if json.Unmarshal(ev.Content, &member) != nil || member.Membership != "invite" {
+			continue
+		}
+		if !c.config.AutoJoin || !c.IsAllowed(ev.Sender) {
+			logger.InfoCF("matrix", "Ignoring room invite", map[string]any{
+				"room_id": roomID,
+				"inviter": ev.Sender,
+			})
+			return
+		}
+		if err := c.do(ctx, http.MethodPost, "/_matrix/client/v3/join/"+url.PathEscape(roomID), nil, map[string]any{}, nil); err != nil {
+			logger.ErrorCF("matrix", "Failed to join room", map[string]any{
+				"room_id": roomID,
+				"error":   err.Error(),
+			})
+			return
+		}
+		if member.IsDirect {
+			c.directRooms.Store(roomID, struct{}{})
+		}
+		logger.InfoCF("matrix", "Joined room", map[string]any{
+			"room_id": roomID,
+			"inviter": ev.Sender,
+			"direct":  member.IsDirect,
+		})
+		return
+	}
+}
+
+func (c *MatrixChannel) handleEvent(ctx context.Context, roomID string, ev matrixEvent) {
+	if ev.Sender == c.userID {
+		return
+	}
+	switch ev.Type {
+	case "m.room.message":
+	case "m.room.encrypted":
+		if !c.IsAllowed(ev.Sender) {
+			return
+		}
+		if c.crypto != nil {
+			// Waiting for a late room key must not hold up the sync loop.
+			go c.handleEncrypted(ctx, roomID, ev)
+			return
+		}
+		if _, warned := c.warnedRooms.LoadOrStore(roomID, struct{}{}); !warned {
+			logger.WarnCF("matrix", "Ignoring encrypted room; end-to-end encryption is not supported", map[string]any{
+				"room_id": roomID,
+			})
+			if _, err := c.sendEvent(ctx, roomID, map[string]any{
+				"msgtype": "m.notice",
+				"body":    matrixEncryptedNotice,
+			}); err != nil {
+				logger.DebugCF("matrix", "Failed to send encryption notice", map[string]any{"error": err.Error()})
+			}
+		}
+		return
+	default:
+		return
+	}
+
+	var msg matrixMessage
+	if err := json.Unmarshal(ev.Content, &msg); err != nil {
+		return
+	}
+	// Edits repeat a message already answered; notices come from bots.
+	if msg.RelatesTo.RelType == "m.replace" || msg.MsgType == "m.notice" {
+		return
+	}
+	if !c.IsAllowed(ev.Sender) {
+		logger.DebugCF("matrix", "Message rejected by allowlist", map[string]any{
+			"sender": ev.Sender,
+		})
+		return
+	}
+
+	direct := c.isDirect(roomID)
+	if !direct && c.config.MentionOnly && !c.mentioned(msg) {
+		return
+	}
+
+	content := msg.Body
+	if msg.RelatesTo.InReplyTo != nil {
+		content = stripReplyFallback(content)
+	}
+	if !direct {
+		content = c.stripMention(content)
+	}
+
+	var mediaPaths []string
+	switch msg.MsgType {
+	case "m.image", "m.file", "m.audio", "m.video":
+		name := firstNonEmpty(msg.FileName, msg.Body, "file")
+		var path string
+		if msg.File != nil {
+			path = c.downloadEncryptedMedia(msg.File, name)
+		} else {
+			path = c.downloadMedia(msg.URL, name)
+		}
+		if path == "" {
+			content = fmt.Sprintf("[file: %s]", name)
+			break
+		}
+		mediaPaths = append(mediaPaths, path)
+		switch {
+		case msg.MsgType == "m.audio" || utils.IsAudioFile(name, msg.Info.MimeType):
+			content = c.voiceText(ctx, path)
+		case msg.MsgType == "m.image":
+			content = fmt.Sprintf("[image: %s]", name)
+		default:
+			content = fmt.Sprintf("[file: %s]", name)
+		}
+	}
+	defer removeTempFiles("matrix", mediaPaths)
+
+	if strings.TrimSpace(content) == "" {
+		return
+	}
+
+	metadata := map[string]string{
+		"message_id": ev.EventID,
+		"room_id":    roomID,
+	}
+	if direct {
+		metadata["peer_kind"] = "direct"
+		metadata["peer_id"] = ev.Sender
+	} else {
+		metadata["peer_kind"] = "group"
+		metadata["peer_id"] = roomID
+	}
+
+	logger.DebugCF("matrix", "Received message", map[string]any{
+		"sender":  ev.Sender,
+		"room_id": roomID,
+		"preview": utils.Truncate(content, 50),
+	})
+
+	c.HandleMessage(ev.Sender, roomID, content, mediaPaths, metadata)
+}
+
+// handleEncrypted decrypts an m.room.encrypted event and handles the event
+// inside it.
+func (c *MatrixChannel) handleEncrypted(ctx context.Context, roomID string, ev matrixEvent) {
+	decrypted, err := c.crypto.decrypt(ctx, roomID, ev)
+	if err != nil {
+		logger.WarnCF("matrix", "Failed to decrypt message", map[string]any{
+			"room_id":  roomID,
+			"event_id": ev.EventID,
+			"error":    err.Error(),
+		})
+		return
+	}
+	if decrypted.Type == "m.room.encrypted" {
+		return
+	}
+	c.handleEvent(ctx, roomID, decrypted)
+}
+
+// isDirect reports whether a room is a DM: marked as one in m.direct or
+// the invite, or shared with exactly one other member.
+func (c *MatrixChannel) isDirect(roomID string) bool {
+	if _, ok := c.directRooms.Load(roomID); ok {
+		return true
+	}
+	n, ok := c.memberCounts.Load(roomID)
+	return ok && n.(int) == 2
+}
+
+// mentioned reports whether a message mentions the bot. m.mentions is
+// authoritative when present; older clients only put the name in the text.
+func (c *MatrixChannel) mentioned(msg matrixMessage) bool {
+	if msg.Mentions != nil {
+		for _, id := range msg.Mentions.UserIDs {
+			if id == c.userID {
+				return true
+			}
+		}
+		return false
+	}
+	if strings.Contains(msg.Body, c.userID) || strings.Contains(msg.FormattedBody, "matrix.to/#/"+c.userID) {
+		return true
+	}
+	return c.displayName != "" && strings.Contains(strings.ToLower(msg.Body), strings.ToLower(c.displayName))
+}
+
+// stripMention removes the bot's user ID, and its display name when it
+// leads the message as clients write mentions ("Agent: hi").
+func (c *MatrixChannel) stripMention(content string) string {
+	content = strings.ReplaceAll(content, c.userID, "")
+	if name := c.displayName; name != "" && len(content) >= len(name) && strings.EqualFold(content[:len(name)], name) {
+		content = content[len(name):]
+	}
+	return strings.TrimSpace(strings.TrimLeft(content, ":, "))
+}
+
+// stripReplyFallback drops the quoted "> <@user> ..." lines clients put
+// at the top of a reply's body.
+func stripReplyFallback(body string) string {
+	lines := strings.Split(body, "\n")
+	i := 0
+	for i < len(lines) && strings.HasPrefix(lines[i], ">") {
+		i++
+	}
+	return strings.TrimSpace(strings.Join(lines[i:], "\n"))
+}
+
+// downloadMedia fetches an mxc:// URI through the authenticated media API,
+// falling back to the legacy endpoint on older homeservers.
+func (c *MatrixChannel) downloadMedia(mxc, name string) string {
+	serverAndID, ok := strings.CutPrefix(mxc, "mxc://")
+	if !ok {
+		return ""
+	}
+	opts := utils.DownloadOptions{
+		LoggerPrefix: "matrix",
+		ExtraHeaders: map[string]string{"Authorization": "Bearer " + c.config.AccessToken},
+	}
+	if path := utils.DownloadFile(c.homeserver+"/_matrix/client/v1/media/download/"+serverAndID, name, opts); path != "" {
+		return path
+	}
+	return utils.DownloadFile(c.homeserver+"/_matrix/media/v3/download/"+serverAndID, name, opts)
+}
+
+// downloadEncryptedMedia fetches and decrypts an attachment sent to an
+// encrypted room.
+func (c *MatrixChannel) downloadEncryptedMedia(file *event.EncryptedFileInfo, name string) string {
+	path := c.downloadMedia(string(file.URL), name)
+	if path == "" {
+		return ""
+	}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = file.DecryptInPlace(data)
+	}
+	if err == nil {
+		err = os.WriteFile(path, data, 0o600)
+	}
+	if err != nil {
+		logger.WarnCF("matrix", "Failed to decrypt attachment", map[string]any{
+			"file":  name,
+			"error": err.Error(),
+		})
+		os.Remove(path)
+		return ""
+	}
+	return path
+}
+
+func (c *MatrixChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("matrix channel not running")
+	}
+	roomID := msg.ChatID
+	body := appendFallbackText(msg.Content, nil, msg.Buttons)
+
+	// The reply takes the place of the streamed messages. An edit cannot
+	// make it a reply to another message, so those are then removed
+	// instead, as they are for a reply without text such as a voice note.
+	// Reasoning sent on its own leaves them for the reply.
+	streamed := false
+	if hasText := strings.TrimSpace(msg.Content) != ""; hasText || len(msg.Attachments) > 0 || msg.Reasoning == "" {
+		if hasText && msg.Reasoning == "" && msg.ReplyTo == "" {
+			streamed = true
+		} else {
+			c.stream.finish(ctx, roomID, nil)
+		}
+	}
+
+	if strings.TrimSpace(body) != "" || msg.Reasoning != "" {
+		if !streamed || len(c.stream.finish(ctx, roomID, []string{body})) > 0 {
+			content := matrixTextContent(body, msg.Reasoning)
+			if msg.ReplyTo != "" {
+				content["m.relates_to"] = map[string]any{
+					"m.in_reply_to": map[string]any{"event_id": msg.ReplyTo},
+				}
+			}
+			if _, err := c.sendEvent(ctx, roomID, content); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, a := range msg.Attachments {
+		if err := c.sendAttachment(ctx, roomID, a); err != nil {
+			logger.WarnCF("matrix", "Failed to upload attachment, sending it as text", map[string]any{
+				"file":  a.Name(),
+				"error": err.Error(),
+			})
+			if _, err := c.sendEvent(ctx, roomID, matrixTextContent(attachmentText([]bus.Attachment{a}), "")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sendAttachment uploads a file and posts it with the message type that
+// lets clients show it inline.
+func (c *MatrixChannel) sendAttachment(ctx context.Context, roomID string, a bus.Attachment) error {
+	data, err := readAttachment(ctx, a)
+	if err != nil {
+		return err
+	}
+	contentType := a.ContentType()
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	// Files for an encrypted room are uploaded encrypted; the key goes in
+	// the (encrypted) message.
+	var file *attachment.EncryptedFile
+	uploadType := contentType
+	if c.crypto != nil && c.crypto.isEncrypted(ctx, roomID) {
+		file = attachment.NewEncryptedFile()
+		data = file.Encrypt(data)
+		uploadType = "application/octet-stream"
+	}
+
+	var upload struct {
+		ContentURI string `json:"content_uri"`
+	}
+	query := url.Values{"filename": {a.Name()}}
+	if err := c.request(ctx, http.MethodPost, "/_matrix/media/v3/upload", query, uploadType, data, &upload); err != nil {
+		return err
+	}
+
+	msgType := "m.file"
+	switch a.Kind() {
+	case bus.AttachmentImage:
+		msgType = "m.image"
+	case bus.AttachmentAudio:
+		msgType = "m.audio"
+	case bus.AttachmentVideo:
+		msgType = "m.video"
+	}
+	content := map[string]any{
+		"msgtype":  msgType,
+		"body":     a.Name(),
+		"filename": a.Name(),
+		"info": map[string]any{
+			"mimetype": contentType,
+			"size":     len(data),
+		},
+	}
+	if file != nil {
+		content["file"] = event.EncryptedFileInfo{EncryptedFile: *file, URL: id.ContentURIString(upload.ContentURI)}
+	} else {
+		content["url"] = upload.ContentURI
+	}
+	_, err = c.sendEvent(ctx, roomID, content)
+	return err
+}
+
+// sendEvent posts an m.room.message, encrypted when the room is.
+func (c *MatrixChannel) sendEvent(ctx context.Context, roomID string, content map[string]any) (string, error) {
+	eventType := "m.room.message"
+	var body any = content
+	if c.crypto != nil && c.crypto.isEncrypted(ctx, roomID) {
+		encrypted, err := c.crypto.encrypt(ctx, roomID, eventType, content)
+		if err != nil {
+			return "", fmt.Errorf("encrypting message: %w", err)
+		}
+		eventType, body = "m.room.encrypted", encrypted
+	}
+
+	txnID := fmt.Sprintf("agentx%d.%d", time.Now().UnixNano(), c.txnID.Add(1))
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(roomID) + "/send/" + eventType + "/" + txnID
+	var resp struct {
+		EventID string `json:"event_id"`
+	}
+	if err := c.do(ctx, http.MethodPut, path, nil, body, &resp); err != nil {
+		return "", err
+	}
+	return resp.EventID, nil
+}
+
+// redactEvent removes an event from a room.
+func (c *MatrixChannel) redactEvent(ctx context.Context, roomID, eventID string) error {
+	txnID := fmt.Sprintf("agentx%d.%d", time.Now().UnixNano(), c.txnID.Add(1))
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(roomID) + "/redact/" + url.PathEscape(eventID) + "/" + txnID
+	return c.do(ctx, http.MethodPut, path, nil, map[string]any{}, nil)
+}
+
+// matrixTextContent builds an m.text message with an HTML body. A
+// reasoning trace goes first, folded in <details>.
+func matrixTextContent(body, reasoning string) map[string]any {
+	formatted := matrixHTML(body)
+	if reasoning != "" {
+		trace := utils.Truncate(strings.TrimSpace(reasoning), maxReasoningChars)
+		formatted = "<details><summary>💭 Thinking</summary>" + matrixHTML(trace) + "</details>" + formatted
+		body = strings.TrimSpace(reasoningText(reasoning) + "\n\n" + body)
+	}
+	return map[string]any{
+		"msgtype":        "m.text",
+		"body":           body,
+		"format":         matrixHTMLFormat,
+		"formatted_body": formatted,
+	}
+}
+
+// matrixEditContent replaces the message eventID with content. The "* "
+// fallback is what clients without edit support show.
+func matrixEditContent(eventID string, content map[string]any) map[string]any {
+	return map[string]any{
+		"msgtype":        content["msgtype"],
+		"body":           "* " + content["body"].(string),
+		"format":         matrixHTMLFormat,
+		"formatted_body": "* " + content["formatted_body"].(string),
+		"m.new_content":  content,
+		"m.relates_to": map[string]any{
+			"rel_type": "m.replace",
+			"event_id": eventID,
+		},
+	}
+}
+
+var matrixPreRe = regexp.MustCompile(`(?s)<pre>.*?</pre>`)
+
+// matrixHTML renders Markdown as the HTML subset Matrix clients display.
+// It reuses the Telegram converter, which keeps line breaks as newlines.
+func matrixHTML(markdown string) string {
+	html := markdownToTelegramHTML(markdown)
+	var out strings.Builder
+	last := 0
+	for _, loc := range matrixPreRe.FindAllStringIndex(html, -1) {
+		out.WriteString(strings.ReplaceAll(html[last:loc[0]], "\n", "<br>"))
+		out.WriteString(html[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	out.WriteString(strings.ReplaceAll(html[last:], "\n", "<br>"))
+	return out.String()
+}
+
+// StartStreamConsumer starts a goroutine that shows stream deltas by
+// sending messages to the room and then editing them.
+func (c *MatrixChannel) StartStreamConsumer(ctx context.Context) {
+	go c.stream.run(ctx)
+}
+
+// HandleStreamDelta processes a single stream delta event.
+func (c *MatrixChannel) HandleStreamDelta(delta bus.StreamDelta) {
+	c.stream.add(delta)
+}
+
+// SendTyping shows the bot typing in a room. Sending a message there
+// clears it.
+func (c *MatrixChannel) SendTyping(ctx context.Context, chatID string) error {
+	path := "/_matrix/client/v3/rooms/" + url.PathEscape(chatID) + "/typing/" + url.PathEscape(c.userID)
+	return c.do(ctx, http.MethodPut, path, nil, map[string]any{
+		"typing":  true,
+		"timeout": matrixTypingTimeout.Milliseconds(),
+	}, nil)
+}
+
+// ShowStatus shows what the agent is doing in a message of its own.
+func (c *MatrixChannel) ShowStatus(ctx context.Context, chatID, status string) error {
+	return c.stream.showStatus(ctx, chatID, status)
+}
+
+// ClearStatus removes the status message.
+func (c *MatrixChannel) ClearStatus(ctx context.Context, chatID string) error {
+	return c.stream.clearStatus(ctx, chatID)
+}
+
+// do sends a JSON request to the homeserver and decodes the JSON reply
+// into out.
+func (c *MatrixChannel) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	return c.request(ctx, method, path, query, "application/json", data, out)
+}
+
+// request calls the homeserver, waiting once and retrying when it
+// answers M_LIMIT_EXCEEDED.
+func (c *MatrixChannel) request(ctx context.Context, method, path string, query url.Values, contentType string, data []byte, out any) error {
+	endpoint := c.homeserver + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		var body io.Reader
+		if data != nil {
+			body = bytes.NewReader(data)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.config.AccessToken)
+		if data != nil {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		respBody, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			if out == nil {
+				return nil
+			}
+			return json.Unmarshal(respBody, out)
+		}
+
+		var merr matrixError
+		json.Unmarshal(respBody, &merr)
+		if resp.StatusCode == http.StatusTooManyRequests && attempt == 0 {
+			wait := time.Duration(merr.RetryAfterMs) * time.Millisecond
+			if wait <= 0 || wait > 10*time.Second {
+				wait = 2 * time.Second
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+			continue
+		}
+		if merr.ErrCode != "" {
+			return fmt.Errorf("matrix %s %s: %s: %s", method, path, merr.ErrCode, merr.Error)
+		}
+		return errors.New("matrix " + method + " " + path + ": status " + strconv.Itoa(resp.StatusCode))
+	}
+}
@@ -0,0 +1,198 @@
+//go:build goolm
+
+package channels
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.mau.fi/util/dbutil"
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/crypto"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+	_ "modernc.org/sqlite" // pure-Go SQLite driver
+
+	"github.com/Agentx-network/agentx/pkg/logger"
+)
+
+// matrixSessionWait is how long a message is held back when its room key
+// has not arrived yet. Clients usually send the key in the same sync.
+const matrixSessionWait = 30 * time.Second
+
+// matrixDefaultPickleKey encrypts the stored keys when pickle_key is not
+// set. It only keeps them from being readable as plain text.
+const matrixDefaultPickleKey = "agentx matrix"
+
+// matrixCrypto handles Olm and Megolm for end-to-end encrypted rooms. The
+// Olm account and sessions live in a SQLite database so the bot keeps its
+// device identity, and can read rooms it already has keys for, across
+// restarts. Room state is rebuilt from the initial sync on every start.
+type matrixCrypto struct {
+	mach   *crypto.OlmMachine
+	state  mautrix.StateStore
+	db     *dbutil.Database
+	shares sync.Mutex // one group session share per room at a time
+}
+
+// newMatrixCrypto loads or creates the Olm account for the device behind
+// the access token and uploads its keys if the homeserver has none.
+func newMatrixCrypto(ctx context.Context, homeserver, userID, deviceID, accessToken, storePath, pickleKey string) (*matrixCrypto, error) {
+	if deviceID == "" {
+		return nil, errors.New("the access token is not bound to a device; log in to get one that is")
+	}
+	client, err := mautrix.NewClient(homeserver, id.UserID(userID), accessToken)
+	if err != nil {
+		return nil, err
+	}
+	client.DeviceID = id.DeviceID(deviceID)
+
+	if err := os.MkdirAll(filepath.Dir(storePath), 0o700); err != nil {
+		return nil, fmt.Errorf("creating crypto store directory: %w", err)
+	}
+	dsn := "file:" + storePath +
+		"?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_txlock=immediate"
+	rawDB, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening crypto store: %w", err)
+	}
+	db, err := dbutil.NewWithDB(rawDB, "sqlite3")
+	if err != nil {
+		rawDB.Close()
+		return nil, err
+	}
+
+	if pickleKey == "" {
+		pickleKey = matrixDefaultPickleKey
+	}
+	store := crypto.NewSQLCryptoStore(db, dbutil.NoopLogger, userID, client.DeviceID, []byte(pickleKey))
+	if err := store.DB.Upgrade(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("upgrading crypto store: %w", err)
+	}
+	stored, err := store.FindDeviceID(ctx)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if stored != "" && stored != client.DeviceID {
+		db.Close()
+		return nil, fmt.Errorf("crypto store %s belongs to device %s, but the access token is for device %s", storePath, stored, deviceID)
+	}
+
+	state := mautrix.NewMemoryStateStore()
+	mach := crypto.NewOlmMachine(client, nil, store, state.(crypto.StateStore))
+	if err := mach.Load(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("loading olm account: %w", err)
+	}
+	if !mach.GetAccount().Shared {
+		if err := mach.ShareKeys(ctx, -1); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("uploading device keys: %w", err)
+		}
+	}
+	return &matrixCrypto{mach: mach, state: state, db: db}, nil
+}
+
+// processSync feeds a raw /sync response to the Olm machine: room keys
+// and other to-device events, device list changes, one-time key counts,
+// and the membership and encryption state of joined rooms.
+func (mc *matrixCrypto) processSync(ctx context.Context, raw []byte, since string) {
+	var resp mautrix.RespSync
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		logger.WarnCF("matrix", "Failed to decode sync for encryption", map[string]any{"error": err.Error()})
+		return
+	}
+	for roomID, room := range resp.Rooms.Join {
+		for _, evt := range append(room.State.Events, room.Timeline.Events...) {
+			if evt.StateKey == nil {
+				continue
+			}
+			evt.RoomID = roomID
+			if err := evt.Content.ParseRaw(evt.Type); err != nil {
+				continue
+			}
+			mautrix.UpdateStateStore(ctx, mc.state, evt)
+			if evt.Type == event.StateMember {
+				mc.mach.HandleMemberEvent(ctx, evt)
+			}
+		}
+	}
+	mc.mach.ProcessSyncResponse(ctx, &resp, since)
+}
+
+// decrypt returns the event inside an m.room.encrypted event. When the
+// room key is still missing it waits for it, up to matrixSessionWait.
+func (mc *matrixCrypto) decrypt(ctx context.Context, roomID string, ev matrixEvent) (matrixEvent, error) {
+	evt := &event.Event{
+		Type:    event.EventEncrypted,
+		ID:      id.EventID(ev.EventID),
+		Sender:  id.UserID(ev.Sender),
+		RoomID:  id.RoomID(roomID),
+		Content: event.Content{VeryRaw: ev.Content},
+	}
+	if err := evt.Content.ParseRaw(evt.Type); err != nil {
+		return matrixEvent{}, err
+	}
+
+	decrypted, err := mc.mach.DecryptMegolmEvent(ctx, evt)
+	if errors.Is(err, crypto.ErrNoSessionFound) {
+		content := evt.Content.AsEncrypted()
+		if mc.mach.WaitForSession(ctx, evt.RoomID, content.SenderKey, content.SessionID, matrixSessionWait) {
+			decrypted, err = mc.mach.DecryptMegolmEvent(ctx, evt)
+		}
+	}
+	if err != nil {
+		return matrixEvent{}, err
+	}
+	return matrixEvent{
+		Type:    decrypted.Type.Type,
+		EventID: ev.EventID,
+		Sender:  ev.Sender,
+		Content: decrypted.Content.VeryRaw,
+	}, nil
+}
+
+// isEncrypted reports whether a room has encryption turned on.
+func (mc *matrixCrypto) isEncrypted(ctx context.Context, roomID string) bool {
+	encrypted, _ := mc.state.IsEncrypted(ctx, id.RoomID(roomID))
+	return encrypted
+}
+
+// encrypt wraps content in an m.room.encrypted event for roomID, sharing
+// a new group session with the room's members first when needed.
+func (mc *matrixCrypto) encrypt(ctx context.Context, roomID, eventType string, content any) (any, error) {
+	mc.shares.Lock()
+	defer mc.shares.Unlock()
+
+	room := id.RoomID(roomID)
+	evtType := event.Type{Type: eventType, Class: event.MessageEventType}
+	encrypted, err := mc.mach.EncryptMegolmEvent(ctx, room, evtType, content)
+	if crypto.IsShareError(err) {
+		members, merr := mc.state.GetRoomJoinedOrInvitedMembers(ctx, room)
+		if merr != nil {
+			return nil, merr
+		}
+		if err = mc.mach.ShareGroupSession(ctx, room, members); err != nil {
+			return nil, fmt.Errorf("sharing room key: %w", err)
+		}
+		encrypted, err = mc.mach.EncryptMegolmEvent(ctx, room, evtType, content)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return encrypted, nil
+}
+
+func (mc *matrixCrypto) close() {
+	mc.mach.Destroy()
+	mc.db.Close()
+}
@@ -0,0 +1,31 @@
+//go:build !goolm
+
+package channels
+
+import (
+	"context"
+	"errors"
+)
+
+// matrixCrypto is unavailable without the goolm build tag, which selects
+// mautrix's pure-Go Olm implementation. The Makefile and release builds
+// set it.
+type matrixCrypto struct{}
+
+func newMatrixCrypto(ctx context.Context, homeserver, userID, deviceID, accessToken, storePath, pickleKey string) (*matrixCrypto, error) {
+	return nil, errors.New("this build has no end-to-end encryption support (build with -tags goolm)")
+}
+
+func (mc *matrixCrypto) processSync(ctx context.Context, raw []byte, since string) {}
+
+func (mc *matrixCrypto) decrypt(ctx context.Context, roomID string, ev matrixEvent) (matrixEvent, error) {
+	return matrixEvent{}, errors.ErrUnsupported
+}
+
+func (mc *matrixCrypto) isEncrypted(ctx context.Context, roomID string) bool { return false }
+
+func (mc *matrixCrypto) encrypt(ctx context.Context, roomID, eventType string, content any) (any, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func (mc *matrixCrypto) close() {}
@@ -0,0 +1,342 @@
+//go:build goolm
+
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"maunium.net/go/mautrix"
+	"maunium.net/go/mautrix/crypto"
+	"maunium.net/go/mautrix/event"
+	"maunium.net/go/mautrix/id"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/config"
+)
+
+// keyHomeserver is a homeserver for two devices that relays what end-to-end
+// encryption needs: device and one-time keys, to-device messages and room
+// events. Token "tok" is the bot, "alice" the other member of !enc.
+type keyHomeserver struct {
+	*httptest.Server
+	mu         sync.Mutex
+	changed    chan struct{}
+	deviceKeys map[string]json.RawMessage            // user|device -> signed device keys
+	otks       map[string]map[string]json.RawMessage // user|device -> key ID -> key
+	inbox      map[string][]map[string]any           // user|device -> to-device events
+	timeline   []map[string]any                      // room events in order
+	delivered  int                                   // timeline events already synced to the bot
+	synced     bool                                  // the bot has had its initial sync
+}
+
+var keyHomeserverUsers = map[string][2]string{
+	"tok":   {"@agent:example.org", "BOT"},
+	"alice": {"@alice:example.org", "ALICE"},
+}
+
+const keyHomeserverInitialSync = `{
+  "next_batch": "s1",
+  "rooms": {"join": {"!enc:example.org": {
+    "summary": {"m.joined_member_count": 2},
+    "state": {"events": [
+      {"type": "m.room.encryption", "event_id": "$s1", "sender": "@alice:example.org", "state_key": "", "content": {"algorithm": "m.megolm.v1.aes-sha2"}},
+      {"type": "m.room.member", "event_id": "$s2", "sender": "@alice:example.org", "state_key": "@alice:example.org", "content": {"membership": "join"}},
+      {"type": "m.room.member", "event_id": "$s3", "sender": "@agent:example.org", "state_key": "@agent:example.org", "content": {"membership": "join"}}
+    ]},
+    "timeline": {"events": []}
+  }}}
+}`
+
+func newKeyHomeserver(t *testing.T) *keyHomeserver {
+	t.Helper()
+	s := &keyHomeserver{
+		changed:    make(chan struct{}, 1),
+		deviceKeys: map[string]json.RawMessage{},
+		otks:       map[string]map[string]json.RawMessage{},
+		inbox:      map[string][]map[string]any{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *keyHomeserver) notify() {
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (s *keyHomeserver) serve(w http.ResponseWriter, r *http.Request) {
+	user, ok := keyHomeserverUsers[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+	path := r.URL.EscapedPath()
+	if path == "/_matrix/client/versions" {
+		io.WriteString(w, `{"versions":["v1.11"]}`)
+		return
+	}
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, `{"errcode":"M_UNKNOWN_TOKEN","error":"bad token"}`)
+		return
+	}
+	userID, deviceID := user[0], user[1]
+
+	switch {
+	case path == "/_matrix/client/v3/account/whoami":
+		json.NewEncoder(w).Encode(map[string]string{"user_id": userID, "device_id": deviceID})
+	case path == "/_matrix/client/v3/keys/upload":
+		var req struct {
+			DeviceKeys  json.RawMessage            `json:"device_keys"`
+			OneTimeKeys map[string]json.RawMessage `json:"one_time_keys"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		s.mu.Lock()
+		key := userID + "|" + deviceID
+		if len(req.DeviceKeys) > 0 {
+			s.deviceKeys[key] = req.DeviceKeys
+		}
+		if s.otks[key] == nil {
+			s.otks[key] = map[string]json.RawMessage{}
+		}
+		for keyID, otk := range req.OneTimeKeys {
+			s.otks[key][keyID] = otk
+		}
+		count := len(s.otks[key])
+		s.mu.Unlock()
+		fmt.Fprintf(w, `{"one_time_key_counts":{"signed_curve25519":%d}}`, count)
+	case path == "/_matrix/client/v3/keys/query":
+		var req struct {
+			DeviceKeys map[string][]string `json:"device_keys"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		resp := map[string]map[string]json.RawMessage{}
+		s.mu.Lock()
+		for user := range req.DeviceKeys {
+			resp[user] = map[string]json.RawMessage{}
+			for key, keys := range s.deviceKeys {
+				if u, d, _ := strings.Cut(key, "|"); u == user {
+					resp[user][d] = keys
+				}
+			}
+		}
+		s.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"device_keys": resp})
+	case path == "/_matrix/client/v3/keys/claim":
+		var req struct {
+			OneTimeKeys map[string]map[string]string `json:"one_time_keys"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		resp := map[string]map[string]map[string]json.RawMessage{}
+		s.mu.Lock()
+		for user, devices := range req.OneTimeKeys {
+			resp[user] = map[string]map[string]json.RawMessage{}
+			for device := range devices {
+				for keyID, otk := range s.otks[user+"|"+device] {
+					resp[user][device] = map[string]json.RawMessage{keyID: otk}
+					delete(s.otks[user+"|"+device], keyID)
+					break
+				}
+			}
+		}
+		s.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"one_time_keys": resp})
+	case strings.HasPrefix(path, "/_matrix/client/v3/sendToDevice/"):
+		eventType, _, _ := strings.Cut(strings.TrimPrefix(path, "/_matrix/client/v3/sendToDevice/"), "/")
+		var req struct {
+			Messages map[string]map[string]json.RawMessage `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		s.mu.Lock()
+		for user, devices := range req.Messages {
+			for device, content := range devices {
+				key := user + "|" + device
+				s.inbox[key] = append(s.inbox[key], map[string]any{"type": eventType, "sender": userID, "content": content})
+			}
+		}
+		s.mu.Unlock()
+		s.notify()
+		io.WriteString(w, `{}`)
+	case strings.Contains(path, "/send/"):
+		_, rest, _ := strings.Cut(path, "/send/")
+		eventType, _, _ := strings.Cut(rest, "/")
+		var content json.RawMessage
+		json.NewDecoder(r.Body).Decode(&content)
+		s.mu.Lock()
+		eventID := fmt.Sprintf("$ev%d", len(s.timeline))
+		s.timeline = append(s.timeline, map[string]any{
+			"type": eventType, "event_id": eventID, "sender": userID, "content": content,
+		})
+		s.mu.Unlock()
+		s.notify()
+		json.NewEncoder(w).Encode(map[string]string{"event_id": eventID})
+	case path == "/_matrix/client/v3/sync":
+		s.sync(w, r, userID+"|"+deviceID)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"errcode":"M_UNRECOGNIZED","error":"not here"}`)
+	}
+}
+
+// sync serves the bot: the room state first, then new to-device and room
+// events as they come in.
+func (s *keyHomeserver) sync(w http.ResponseWriter, r *http.Request, key string) {
+	s.mu.Lock()
+	if !s.synced {
+		s.synced = true
+		s.mu.Unlock()
+		io.WriteString(w, keyHomeserverInitialSync)
+		return
+	}
+	s.mu.Unlock()
+
+	for {
+		s.mu.Lock()
+		toDevice := s.inbox[key]
+		events := s.timeline[s.delivered:]
+		if len(toDevice) > 0 || len(events) > 0 {
+			delete(s.inbox, key)
+			s.delivered = len(s.timeline)
+			s.mu.Unlock()
+			json.NewEncoder(w).Encode(map[string]any{
+				"next_batch": fmt.Sprintf("s%d", s.delivered+2),
+				"to_device":  map[string]any{"events": toDevice},
+				"rooms": map[string]any{"join": map[string]any{
+					"!enc:example.org": map[string]any{"timeline": map[string]any{"events": events}},
+				}},
+			})
+			return
+		}
+		s.mu.Unlock()
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.changed:
+		}
+	}
+}
+
+// newAliceMachine sets up the other member's device, which knows the room
+// is encrypted and shared with the bot.
+func newAliceMachine(t *testing.T, ctx context.Context, hs *keyHomeserver) (*crypto.OlmMachine, *mautrix.Client) {
+	t.Helper()
+	client, err := mautrix.NewClient(hs.URL, "@alice:example.org", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.DeviceID = "ALICE"
+	room := id.RoomID("!enc:example.org")
+	state := mautrix.NewMemoryStateStore()
+	state.SetEncryptionEvent(ctx, room, &event.EncryptionEventContent{Algorithm: id.AlgorithmMegolmV1})
+	state.SetMembership(ctx, room, "@alice:example.org", event.MembershipJoin)
+	state.SetMembership(ctx, room, "@agent:example.org", event.MembershipJoin)
+
+	mach := crypto.NewOlmMachine(client, nil, crypto.NewMemoryStore(nil), state.(crypto.StateStore))
+	if err := mach.Load(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := mach.ShareKeys(ctx, -1); err != nil {
+		t.Fatal(err)
+	}
+	return mach, client
+}
+
+func TestMatrixChannel_EncryptedRoom(t *testing.T) {
+	hs := newKeyHomeserver(t)
+	msgBus := bus.NewMessageBus()
+	ch, err := NewMatrixChannel(config.MatrixConfig{
+		Homeserver:  hs.URL,
+		AccessToken: "tok",
+		CryptoStore: filepath.Join(t.TempDir(), "crypto.db"),
+		AllowFrom:   []string{"@alice:example.org"},
+	}, msgBus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := ch.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer ch.Stop(ctx)
+	if ch.crypto == nil {
+		t.Fatal("encryption was not set up")
+	}
+
+	alice, aliceClient := newAliceMachine(t, ctx, hs)
+	room := id.RoomID("!enc:example.org")
+	if err := alice.ShareGroupSession(ctx, room, []id.UserID{"@alice:example.org", "@agent:example.org"}); err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := alice.EncryptMegolmEvent(ctx, room, event.EventMessage, &event.MessageEventContent{
+		MsgType: event.MsgText,
+		Body:    "hello, encrypted",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := aliceClient.SendMessageEvent(ctx, room, event.EventEncrypted, encrypted); err != nil {
+		t.Fatal(err)
+	}
+
+	readCtx, readCancel := context.WithTimeout(ctx, 5*time.Second)
+	msg, ok := msgBus.ConsumeInbound(readCtx)
+	readCancel()
+	if !ok {
+		t.Fatal("the encrypted message was not delivered")
+	}
+	if msg.Content != "hello, encrypted" || msg.ChatID != string(room) || msg.Metadata["peer_kind"] != "direct" {
+		t.Errorf("inbound = %+v", msg)
+	}
+
+	if err := ch.Send(ctx, bus.OutboundMessage{ChatID: string(room), Content: "hi alice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Alice receives the bot's room key and reads the reply with it.
+	hs.mu.Lock()
+	toDevice := hs.inbox["@alice:example.org|ALICE"]
+	reply := hs.timeline[len(hs.timeline)-1]
+	hs.mu.Unlock()
+	for _, raw := range toDevice {
+		content, _ := raw["content"].(json.RawMessage)
+		evt := &event.Event{
+			Sender:  id.UserID(raw["sender"].(string)),
+			Type:    event.Type{Type: raw["type"].(string), Class: event.ToDeviceEventType},
+			Content: event.Content{VeryRaw: content},
+		}
+		if err := evt.Content.ParseRaw(evt.Type); err != nil {
+			t.Fatal(err)
+		}
+		alice.HandleToDeviceEvent(ctx, evt)
+	}
+	if reply["type"] != "m.room.encrypted" || reply["sender"] != "@agent:example.org" {
+		t.Fatalf("reply = %v, want an encrypted event from the bot", reply)
+	}
+	evt := &event.Event{
+		Type:    event.EventEncrypted,
+		ID:      id.EventID(reply["event_id"].(string)),
+		Sender:  "@agent:example.org",
+		RoomID:  room,
+		Content: event.Content{VeryRaw: reply["content"].(json.RawMessage)},
+	}
+	if err := evt.Content.ParseRaw(evt.Type); err != nil {
+		t.Fatal(err)
+	}
+	decrypted, err := alice.DecryptMegolmEvent(ctx, evt)
+	if err != nil {
+		t.Fatalf("decrypting the reply: %v", err)
+	}
+	if body := decrypted.Content.AsMessage().Body; body != "hi alice" {
+		t.Errorf("reply body = %q", body)
+	}
+}
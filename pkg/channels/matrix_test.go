@@ -0,0 +1,362 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/config"
+)
+
+// stubHomeserver answers the client-server API calls the Matrix channel
+// makes. Sync responses are served in order; once they run out, /sync
+// blocks until the request is cancelled.
+type stubHomeserver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	syncs    []string
+	joined   []string
+	sent     []map[string]any
+	uploads  []string
+	redacted []string
+	nextID   int
+}
+
+func newStubHomeserver(t *testing.T, syncs ...string) *stubHomeserver {
+	t.Helper()
+	s := &stubHomeserver{syncs: syncs}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *stubHomeserver) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "Bearer tok" {
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, `{"errcode":"M_UNKNOWN_TOKEN","error":"bad token"}`)
+		return
+	}
+	path := r.URL.EscapedPath()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case path == "/_matrix/client/v3/account/whoami":
+		io.WriteString(w, `{"user_id":"@agent:example.org"}`)
+	case strings.HasSuffix(path, "/displayname"):
+		io.WriteString(w, `{"displayname":"Agent"}`)
+	case path == "/_matrix/client/v3/sync":
+		if len(s.syncs) == 0 {
+			s.mu.Unlock()
+			<-r.Context().Done()
+			s.mu.Lock()
+			return
+		}
+		io.WriteString(w, s.syncs[0])
+		s.syncs = s.syncs[1:]
+	case strings.HasPrefix(path, "/_matrix/client/v3/join/"):
+		s.joined = append(s.joined, strings.TrimPrefix(r.URL.Path, "/_matrix/client/v3/join/"))
+		io.WriteString(w, `{}`)
+	case path == "/_matrix/media/v3/upload":
+		data, _ := io.ReadAll(r.Body)
+		s.uploads = append(s.uploads, r.URL.Query().Get("filename")+":"+string(data))
+		io.WriteString(w, `{"content_uri":"mxc://example.org/up1"}`)
+	case strings.Contains(path, "/redact/"):
+		_, rest, _ := strings.Cut(r.URL.Path, "/redact/")
+		eventID, _, _ := strings.Cut(rest, "/")
+		s.redacted = append(s.redacted, eventID)
+		io.WriteString(w, `{"event_id":"$redaction"}`)
+	case strings.Contains(path, "/send/m.room.message/"):
+		var content map[string]any
+		json.NewDecoder(r.Body).Decode(&content)
+		s.sent = append(s.sent, content)
+		s.nextID++
+		json.NewEncoder(w).Encode(map[string]string{"event_id": "$out" + string(rune('0'+s.nextID))})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *stubHomeserver) sentEvents() []map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]map[string]any(nil), s.sent...)
+}
+
+func newTestMatrixChannel(t *testing.T, hs *stubHomeserver, allowFrom ...string) (*MatrixChannel, *bus.MessageBus) {
+	t.Helper()
+	msgBus := bus.NewMessageBus()
+	ch, err := NewMatrixChannel(config.MatrixConfig{
+		Homeserver:  hs.URL,
+		AccessToken: "tok",
+		AutoJoin:    true,
+		MentionOnly: true,
+		AllowFrom:   allowFrom,
+	}, msgBus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ch, msgBus
+}
+
+const matrixInitialSync = `{
+  "next_batch": "s1",
+  "account_data": {"events": [{"type": "m.direct", "content": {"@alice:example.org": ["!dm:example.org"]}}]},
+  "rooms": {
+    "invite": {
+      "!team:example.org": {"invite_state": {"events": [
+        {"type": "m.room.member", "sender": "@alice:example.org", "state_key": "@agent:example.org", "content": {"membership": "invite"}}
+      ]}},
+      "!spam:example.org": {"invite_state": {"events": [
+        {"type": "m.room.member", "sender": "@eve:evil.example", "state_key": "@agent:example.org", "content": {"membership": "invite"}}
+      ]}}
+    },
+    "join": {
+      "!dm:example.org": {"timeline": {"events": [
+        {"type": "m.room.message", "event_id": "$old", "sender": "@alice:example.org", "content": {"msgtype": "m.text", "body": "old message"}}
+      ]}}
+    }
+  }
+}`
+
+const matrixMessagesSync = `{
+  "next_batch": "s2",
+  "rooms": {"join": {
+    "!dm:example.org": {"timeline": {"events": [
+      {"type": "m.room.message", "event_id": "$e1", "sender": "@alice:example.org", "content": {"msgtype": "m.text", "body": "hello in private"}}
+    ]}},
+    "!team:example.org": {"summary": {"m.joined_member_count": 5}, "timeline": {"events": [
+      {"type": "m.room.message", "event_id": "$e2", "sender": "@alice:example.org", "content": {"msgtype": "m.text", "body": "chatter between humans"}},
+      {"type": "m.room.message", "event_id": "$e3", "sender": "@alice:example.org", "content": {"msgtype": "m.text", "body": "Agent: what's the weather?", "m.mentions": {"user_ids": ["@agent:example.org"]}}},
+      {"type": "m.room.message", "event_id": "$e4", "sender": "@agent:example.org", "content": {"msgtype": "m.text", "body": "my own message"}},
+      {"type": "m.room.encrypted", "event_id": "$e5", "sender": "@alice:example.org", "content": {}},
+      {"type": "m.room.message", "event_id": "$e6", "sender": "@eve:evil.example", "content": {"msgtype": "m.text", "body": "@agent:example.org hi"}}
+    ]}}
+  }}
+}`
+
+func TestMatrixChannel_Sync(t *testing.T) {
+	hs := newStubHomeserver(t, matrixInitialSync, matrixMessagesSync)
+	ch, msgBus := newTestMatrixChannel(t, hs, "@alice:example.org")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := ch.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer ch.Stop(ctx)
+	if ch.userID != "@agent:example.org" || ch.displayName != "Agent" {
+		t.Errorf("user = %q (%q)", ch.userID, ch.displayName)
+	}
+
+	var got []bus.InboundMessage
+	for i := 0; i < 2; i++ {
+		readCtx, readCancel := context.WithTimeout(ctx, 2*time.Second)
+		msg, ok := msgBus.ConsumeInbound(readCtx)
+		readCancel()
+		if !ok {
+			t.Fatalf("got %d messages, want 2", len(got))
+		}
+		got = append(got, msg)
+	}
+	if msg, ok := consumeInbound(t, msgBus); ok {
+		t.Errorf("unexpected message %+v", msg)
+	}
+
+	sort.Slice(got, func(i, j int) bool { return got[i].ChatID < got[j].ChatID })
+	dm, room := got[0], got[1]
+	if dm.ChatID != "!dm:example.org" || dm.Content != "hello in private" ||
+		dm.Metadata["peer_kind"] != "direct" || dm.Metadata["peer_id"] != "@alice:example.org" {
+		t.Errorf("dm = %+v", dm)
+	}
+	if room.ChatID != "!team:example.org" || room.Content != "what's the weather?" ||
+		room.Metadata["peer_kind"] != "group" || room.Metadata["peer_id"] != "!team:example.org" ||
+		room.Metadata["message_id"] != "$e3" {
+		t.Errorf("room = %+v", room)
+	}
+
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	if len(hs.sent) != 1 || hs.sent[0]["msgtype"] != "m.notice" || hs.sent[0]["body"] != matrixEncryptedNotice {
+		t.Errorf("sent = %v, want the encryption notice", hs.sent)
+	}
+	if len(hs.joined) != 1 || hs.joined[0] != "!team:example.org" {
+		t.Errorf("joined = %v, want only the invite from an allowed user", hs.joined)
+	}
+}
+
+func TestMatrixChannel_SendReply(t *testing.T) {
+	hs := newStubHomeserver(t)
+	ch, _ := newTestMatrixChannel(t, hs)
+	ch.setRunning(true)
+
+	err := ch.Send(context.Background(), bus.OutboundMessage{
+		ChatID:    "!team:example.org",
+		Content:   "It is **sunny**.\nEnjoy!",
+		ReplyTo:   "$e3",
+		Reasoning: "Checked the forecast.",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := hs.sentEvents()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d events", len(sent))
+	}
+	ev := sent[0]
+	formatted := ev["formatted_body"].(string)
+	if !strings.HasPrefix(formatted, "<details><summary>💭 Thinking</summary>Checked the forecast.</details>") ||
+		!strings.HasSuffix(formatted, "It is <b>sunny</b>.<br>Enjoy!") {
+		t.Errorf("formatted_body = %q", formatted)
+	}
+	if !strings.HasSuffix(ev["body"].(string), "It is **sunny**.\nEnjoy!") {
+		t.Errorf("body = %q", ev["body"])
+	}
+	relates, _ := ev["m.relates_to"].(map[string]any)
+	if reply, _ := relates["m.in_reply_to"].(map[string]any); reply["event_id"] != "$e3" {
+		t.Errorf("m.relates_to = %v", ev["m.relates_to"])
+	}
+}
+
+func TestMatrixChannel_StreamEdits(t *testing.T) {
+	hs := newStubHomeserver(t)
+	ch, _ := newTestMatrixChannel(t, hs)
+	ch.setRunning(true)
+	ch.stream.interval = 0
+	ctx := context.Background()
+
+	ch.HandleStreamDelta(bus.StreamDelta{ChatID: "!dm:example.org", Delta: "Hel"})
+	ch.stream.flush(ctx)
+	ch.HandleStreamDelta(bus.StreamDelta{ChatID: "!dm:example.org", Delta: "lo"})
+	ch.stream.flush(ctx)
+	ch.stream.flush(ctx) // nothing new
+	ch.HandleStreamDelta(bus.StreamDelta{ChatID: "!dm:example.org", Done: true})
+	if err := ch.Send(ctx, bus.OutboundMessage{ChatID: "!dm:example.org", Content: "Hello!"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := hs.sentEvents()
+	if len(sent) != 3 {
+		t.Fatalf("sent %d events, want a message and two edits", len(sent))
+	}
+	if sent[0]["body"] != "Hel" || sent[0]["m.relates_to"] != nil {
+		t.Errorf("first = %v", sent[0])
+	}
+	for i, want := range []string{"Hello", "Hello!"} {
+		ev := sent[i+1]
+		relates := ev["m.relates_to"].(map[string]any)
+		newContent := ev["m.new_content"].(map[string]any)
+		if relates["rel_type"] != "m.replace" || relates["event_id"] != "$out1" || newContent["body"] != want || ev["body"] != "* "+want {
+			t.Errorf("edit %d = %v", i, ev)
+		}
+	}
+
+	// The stream is finished: the next reply is a new message.
+	ch.Send(ctx, bus.OutboundMessage{ChatID: "!dm:example.org", Content: "Next"})
+	if sent := hs.sentEvents(); sent[len(sent)-1]["m.relates_to"] != nil {
+		t.Errorf("expected a new message, got %v", sent[len(sent)-1])
+	}
+}
+
+func TestMatrixChannel_StreamVoiceReply(t *testing.T) {
+	hs := newStubHomeserver(t)
+	ch, _ := newTestMatrixChannel(t, hs)
+	ch.setRunning(true)
+	ch.stream.interval = 0
+	ctx := context.Background()
+
+	ch.HandleStreamDelta(bus.StreamDelta{ChatID: "!dm:example.org", Delta: "Hello"})
+	ch.stream.flush(ctx)
+	ch.HandleStreamDelta(bus.StreamDelta{ChatID: "!dm:example.org", Done: true})
+
+	// The reply is spoken: the voice note replaces the streamed text.
+	voice := writeTempFile(t, "reply.ogg", "OGGDATA")
+	if err := ch.Send(ctx, bus.OutboundMessage{
+		ChatID:      "!dm:example.org",
+		Attachments: []bus.Attachment{{Path: voice, MIMEType: "audio/ogg"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	hs.mu.Lock()
+	redacted := append([]string(nil), hs.redacted...)
+	hs.mu.Unlock()
+	if len(redacted) != 1 || redacted[0] != "$out1" {
+		t.Errorf("redacted = %v, want the streamed message", redacted)
+	}
+
+	// The next turn streams into a new message.
+	ch.HandleStreamDelta(bus.StreamDelta{ChatID: "!dm:example.org", Delta: "Next"})
+	ch.stream.flush(ctx)
+	sent := hs.sentEvents()
+	last := sent[len(sent)-1]
+	if last["body"] != "Next" || last["m.relates_to"] != nil {
+		t.Errorf("expected a new message, got %v", last)
+	}
+}
+
+func TestMatrixChannel_SendAttachment(t *testing.T) {
+	hs := newStubHomeserver(t)
+	ch, _ := newTestMatrixChannel(t, hs)
+	ch.setRunning(true)
+
+	path := writeTempFile(t, "chart.png", "PNGDATA")
+	if err := ch.Send(context.Background(), bus.OutboundMessage{
+		ChatID:      "!dm:example.org",
+		Attachments: []bus.Attachment{{Path: path}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(hs.uploads) != 1 || hs.uploads[0] != "chart.png:PNGDATA" {
+		t.Errorf("uploads = %v", hs.uploads)
+	}
+	sent := hs.sentEvents()
+	if len(sent) != 1 || sent[0]["msgtype"] != "m.image" || sent[0]["url"] != "mxc://example.org/up1" {
+		t.Errorf("sent = %v", sent)
+	}
+}
+
+func TestMatrixChannel_Mentions(t *testing.T) {
+	ch := &MatrixChannel{userID: "@agent:example.org", displayName: "Agent"}
+	tests := []struct {
+		msg  matrixMessage
+		want bool
+	}{
+		{matrixMessage{Body: "agent, help"}, true},
+		{matrixMessage{Body: "ping @agent:example.org"}, true},
+		{matrixMessage{Body: "nothing here"}, false},
+		{matrixMessage{Body: "x", FormattedBody: `<a href="https://matrix.to/#/@agent:example.org">Agent</a>`}, true},
+	}
+	for _, tt := range tests {
+		if got := ch.mentioned(tt.msg); got != tt.want {
+			t.Errorf("mentioned(%q) = %v, want %v", tt.msg.Body, got, tt.want)
+		}
+	}
+	if got := ch.stripMention("Agent: what's up"); got != "what's up" {
+		t.Errorf("stripMention() = %q", got)
+	}
+	if got := stripReplyFallback("> <@alice:example.org> earlier\n> more\n\nmy answer"); got != "my answer" {
+		t.Errorf("stripReplyFallback() = %q", got)
+	}
+}
+
+func TestMatrixChannel_RequestErrors(t *testing.T) {
+	hs := newStubHomeserver(t)
+	msgBus := bus.NewMessageBus()
+	ch, _ := NewMatrixChannel(config.MatrixConfig{Homeserver: hs.URL, AccessToken: "wrong"}, msgBus)
+	err := ch.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "M_UNKNOWN_TOKEN") {
+		t.Errorf("Start() = %v, want the homeserver's error", err)
+	}
+
+	if _, err := NewMatrixChannel(config.MatrixConfig{Homeserver: hs.URL}, msgBus); err == nil {
+		t.Error("a missing access token should fail")
+	}
+}
@@ -0,0 +1,653 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/config"
+	"github.com/Agentx-network/agentx/pkg/logger"
+	"github.com/Agentx-network/agentx/pkg/utils"
+)
+
+const (
+	mattermostRetryDelay  = 5 * time.Second
+	mattermostStreamFlush = time.Second
+	mattermostMaxLength   = 16000 // the server refuses posts over 16383 characters
+	mattermostMaxFiles    = 5     // files per post
+
+	wsPingInterval = 30 * time.Second
+
+	// wsInboundQueueSize is how many received messages may wait for the
+	// handler before new ones are dropped.
+	wsInboundQueueSize = 100
+	// maxJoinedThreads is how many threads the bot has posted in are
+	// remembered; older ones need a mention again under mention_only.
+	maxJoinedThreads = 1000
+)
+
+// MattermostChannel talks to a Mattermost server: it receives posts over
+// the WebSocket API and answers through the REST API, streaming replies by
+// editing its own post.
+//
+// Chat IDs are the channel ID, or "channelID/rootID" for a thread. Threads
+// in channels are routed as peers of their own (peer_kind "thread") with
+// the channel as parent peer, so each thread is its own session while
+// bindings for the channel, and team_id bindings, still apply.
+type MattermostChannel struct {
+	*BaseChannel
+	config     config.MattermostConfig
+	serverURL  string
+	httpClient *http.Client
+	userID     string
+	username   string
+	cancel     context.CancelFunc
+	retryDelay time.Duration
+	stream     *streamRenderer
+	threads    *recentIDs // root post IDs of threads the bot has posted in
+	inbound    chan func()
+}
+
+// mattermostEvent is a WebSocket event; only "posted" is handled.
+type mattermostEvent struct {
+	Event string `json:"event"`
+	Data  struct {
+		ChannelType string `json:"channel_type"`
+		SenderName  string `json:"sender_name"`
+		TeamID      string `json:"team_id"`
+		Post        string `json:"post"`     // the post, as JSON
+		Mentions    string `json:"mentions"` // IDs of mentioned users, as JSON
+	} `json:"data"`
+	Broadcast struct {
+		TeamID string `json:"team_id"`
+	} `json:"broadcast"`
+}
+
+type mattermostPost struct {
+	ID        string         `json:"id"`
+	UserID    string         `json:"user_id"`
+	ChannelID string         `json:"channel_id"`
+	RootID    string         `json:"root_id"`
+	Message   string         `json:"message"`
+	Type      string         `json:"type"`
+	FileIDs   []string       `json:"file_ids"`
+	Props     map[string]any `json:"props"`
+	Metadata  struct {
+		Files []mattermostFile `json:"files"`
+	} `json:"metadata"`
+}
+
+type mattermostFile struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	MimeType string `json:"mime_type"`
+}
+
+// mattermostNewPost is the body of a post created by the bot.
+type mattermostNewPost struct {
+	ChannelID string   `json:"channel_id"`
+	RootID    string   `json:"root_id,omitempty"`
+	Message   string   `json:"message"`
+	FileIDs   []string `json:"file_ids,omitempty"`
+}
+
+func NewMattermostChannel(cfg config.MattermostConfig, messageBus *bus.MessageBus) (*MattermostChannel, error) {
+	if cfg.URL == "" || cfg.Token == "" {
+		return nil, fmt.Errorf("mattermost url and token are required")
+	}
+	serverURL := strings.TrimRight(cfg.URL, "/")
+	if !strings.Contains(serverURL, "://") {
+		serverURL = "https://" + serverURL
+	}
+
+	c := &MattermostChannel{
+		BaseChannel: NewBaseChannel("mattermost", cfg, messageBus, cfg.AllowFrom),
+		config:      cfg,
+		serverURL:   serverURL,
+		httpClient:  &http.Client{Timeout: 60 * time.Second},
+		retryDelay:  mattermostRetryDelay,
+		threads:     newRecentIDs(maxJoinedThreads),
+		inbound:     make(chan func(), wsInboundQueueSize),
+	}
+	c.stream = &streamRenderer{
+		name:     "mattermost",
+		interval: mattermostStreamFlush,
+		maxLen:   mattermostMaxLength,
+		post: func(ctx context.Context, chatID, text string) (string, error) {
+			channelID, rootID, _ := strings.Cut(chatID, "/")
+			return c.createPost(ctx, mattermostNewPost{ChannelID: channelID, RootID: rootID, Message: text})
+		},
+		edit: func(ctx context.Context, chatID, postID, text string) error {
+			return c.editPost(ctx, postID, text)
+		},
+		remove: func(ctx context.Context, chatID, postID string) error {
+			return c.api(ctx, http.MethodDelete, "/api/v4/posts/"+postID, nil, nil)
+		},
+	}
+	return c, nil
+}
+
+func (c *MattermostChannel) Start(ctx context.Context) error {
+	logger.InfoCF("mattermost", "Starting Mattermost channel", map[string]any{
+		"url": c.serverURL,
+	})
+
+	var me struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	}
+	if err := c.api(ctx, http.MethodGet, "/api/v4/users/me", nil, &me); err != nil {
+		return fmt.Errorf("mattermost login: %w", err)
+	}
+	c.userID, c.username = me.ID, me.Username
+
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.setRunning(true)
+	go c.run(ctx)
+	go processInbound(ctx, c.inbound)
+
+	logger.InfoCF("mattermost", "Mattermost channel started", map[string]any{
+		"username": c.username,
+	})
+	return nil
+}
+
+func (c *MattermostChannel) Stop(ctx context.Context) error {
+	logger.InfoC("mattermost", "Stopping Mattermost channel")
+	c.setRunning(false)
+	if c.cancel != nil {
+		c.cancel()
+	}
+	return nil
+}
+
+// run keeps a WebSocket connection open, reconnecting after a delay when
+// it drops.
+func (c *MattermostChannel) run(ctx context.Context) {
+	for ctx.Err() == nil {
+		err := c.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		logger.WarnCF("mattermost", "WebSocket connection lost, reconnecting", map[string]any{
+			"error": err.Error(),
+		})
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(c.retryDelay):
+		}
+	}
+}
+
+func (c *MattermostChannel) listen(ctx context.Context) error {
+	wsURL := "ws" + strings.TrimPrefix(c.serverURL, "http") + "/api/v4/websocket"
+	conn, err := dialWebSocket(ctx, wsURL, http.Header{"Authorization": {"Bearer " + c.config.Token}})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// The upgrade request is authenticated by its header; the challenge
+	// covers proxies that drop it.
+	if err := conn.WriteJSON(map[string]any{
+		"seq":    1,
+		"action": "authentication_challenge",
+		"data":   map[string]string{"token": c.config.Token},
+	}); err != nil {
+		return err
+	}
+
+	for {
+		var ev mattermostEvent
+		if err := conn.ReadJSON(&ev); err != nil {
+			return err
+		}
+		conn.SetReadDeadline(time.Now().Add(2 * wsPingInterval))
+		switch ev.Event {
+		case "hello":
+			logger.InfoC("mattermost", "WebSocket connected")
+		case "posted":
+			enqueueInbound("mattermost", c.inbound, func() { c.handlePosted(ctx, ev) })
+		}
+	}
+}
+
+func (c *MattermostChannel) handlePosted(ctx context.Context, ev mattermostEvent) {
+	var post mattermostPost
+	if err := json.Unmarshal([]byte(ev.Data.Post), &post); err != nil {
+		return
+	}
+	// System messages have a type; other bots are not answered, to avoid loops.
+	if post.UserID == c.userID || post.Type != "" || post.Props["from_bot"] == "true" {
+		return
+	}
+
+	senderID := post.UserID
+	if username := strings.TrimPrefix(ev.Data.SenderName, "@"); username != "" {
+		senderID += "|" + username
+	}
+	if !c.IsAllowed(senderID) {
+		logger.DebugCF("mattermost", "Message rejected by allowlist", map[string]any{
+			"sender": senderID,
+		})
+		return
+	}
+
+	direct := ev.Data.ChannelType == "D"
+	content := post.Message
+	if !direct {
+		joined := c.threads.has(post.RootID)
+		if c.config.MentionOnly && !joined && !c.mentioned(ev.Data.Mentions) {
+			return
+		}
+		content = stripAtMention(content, c.username)
+	}
+
+	files := post.Metadata.Files
+	if len(files) == 0 {
+		for _, id := range post.FileIDs {
+			files = append(files, mattermostFile{ID: id})
+		}
+	}
+	var mediaPaths []string
+	for _, f := range files {
+		name := firstNonEmpty(f.Name, "file")
+		path := utils.DownloadFile(c.serverURL+"/api/v4/files/"+f.ID, name, utils.DownloadOptions{
+			LoggerPrefix: "mattermost",
+			ExtraHeaders: map[string]string{"Authorization": "Bearer " + c.config.Token},
+		})
+		if path == "" {
+			content = appendContent(content, fmt.Sprintf("[file: %s]", name))
+			continue
+		}
+		mediaPaths = append(mediaPaths, path)
+		switch {
+		case utils.IsAudioFile(name, f.MimeType):
+			content = appendContent(content, c.voiceText(ctx, path))
+		case strings.HasPrefix(f.MimeType, "image/"):
+			content = appendContent(content, fmt.Sprintf("[image: %s]", name))
+		default:
+			content = appendContent(content, fmt.Sprintf("[file: %s]", name))
+		}
+	}
+	defer removeTempFiles("mattermost", mediaPaths)
+
+	if strings.TrimSpace(content) == "" {
+		return
+	}
+
+	rootID := post.RootID
+	if rootID == "" && !direct && c.config.ReplyInThread {
+		rootID = post.ID
+	}
+	chatID := post.ChannelID
+	if rootID != "" {
+		chatID += "/" + rootID
+	}
+
+	metadata := map[string]string{
+		"message_id": post.ID,
+		"channel_id": post.ChannelID,
+		"root_id":    post.RootID,
+		"team_id":    firstNonEmpty(ev.Data.TeamID, ev.Broadcast.TeamID),
+	}
+	setThreadPeer(metadata, direct, post.UserID, post.ChannelID, rootID)
+
+	logger.DebugCF("mattermost", "Received message", map[string]any{
+		"sender":  senderID,
+		"chat_id": chatID,
+		"preview": utils.Truncate(content, 50),
+	})
+
+	c.HandleMessage(senderID, chatID, content, mediaPaths, metadata)
+}
+
+// mentioned reports whether the bot is among the users a post mentions.
+func (c *MattermostChannel) mentioned(mentions string) bool {
+	var ids []string
+	if json.Unmarshal([]byte(mentions), &ids) != nil {
+		return false
+	}
+	for _, id := range ids {
+		if id == c.userID {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *MattermostChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("mattermost channel not running")
+	}
+	channelID, rootID, _ := strings.Cut(msg.ChatID, "/")
+	if rootID == "" {
+		rootID = msg.ReplyTo
+	}
+
+	text := appendFallbackText(withReasoningText(msg).Content, nil, msg.Buttons)
+	var fileIDs []string
+	for _, a := range msg.Attachments {
+		id, err := c.uploadFile(ctx, channelID, a)
+		if err != nil {
+			logger.WarnCF("mattermost", "Failed to upload attachment, sending it as text", map[string]any{
+				"file":  a.Name(),
+				"error": err.Error(),
+			})
+			text = appendFallbackText(text, []bus.Attachment{a}, nil)
+			continue
+		}
+		fileIDs = append(fileIDs, id)
+	}
+
+	// The reply takes the place of the streamed posts; one without text,
+	// such as a voice note, removes them.
+	var chunks []string
+	if strings.TrimSpace(msg.Content) != "" {
+		chunks = c.stream.finish(ctx, msg.ChatID, utils.SplitMessage(text, mattermostMaxLength))
+	} else {
+		c.stream.finish(ctx, msg.ChatID, nil)
+		if strings.TrimSpace(text) != "" {
+			chunks = utils.SplitMessage(text, mattermostMaxLength)
+		}
+	}
+	// Files go with the last chunk, a few per post.
+	for len(chunks) > 0 || len(fileIDs) > 0 {
+		post := mattermostNewPost{ChannelID: channelID, RootID: rootID}
+		if len(chunks) > 0 {
+			post.Message, chunks = chunks[0], chunks[1:]
+		}
+		if len(chunks) == 0 {
+			n := min(len(fileIDs), mattermostMaxFiles)
+			post.FileIDs, fileIDs = fileIDs[:n], fileIDs[n:]
+		}
+		if _, err := c.createPost(ctx, post); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *MattermostChannel) createPost(ctx context.Context, post mattermostNewPost) (string, error) {
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := c.api(ctx, http.MethodPost, "/api/v4/posts", post, &created); err != nil {
+		return "", err
+	}
+	if post.RootID != "" {
+		c.threads.add(post.RootID)
+	}
+	return created.ID, nil
+}
+
+func (c *MattermostChannel) editPost(ctx context.Context, postID, message string) error {
+	return c.api(ctx, http.MethodPut, "/api/v4/posts/"+postID+"/patch", map[string]string{"message": message}, nil)
+}
+
+// uploadFile uploads an attachment to a channel and returns its file ID.
+func (c *MattermostChannel) uploadFile(ctx context.Context, channelID string, a bus.Attachment) (string, error) {
+	data, err := readAttachment(ctx, a)
+	if err != nil {
+		return "", err
+	}
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	w.WriteField("channel_id", channelID)
+	part, err := w.CreateFormFile("files", a.Name())
+	if err != nil {
+		return "", err
+	}
+	part.Write(data)
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	var uploaded struct {
+		FileInfos []struct {
+			ID string `json:"id"`
+		} `json:"file_infos"`
+	}
+	if err := c.request(ctx, http.MethodPost, "/api/v4/files", w.FormDataContentType(), body.Bytes(), &uploaded); err != nil {
+		return "", err
+	}
+	if len(uploaded.FileInfos) == 0 {
+		return "", fmt.Errorf("mattermost returned no file for %s", a.Name())
+	}
+	return uploaded.FileInfos[0].ID, nil
+}
+
+// StartStreamConsumer starts a goroutine that shows stream deltas by
+// posting to the chat and then editing the posts.
+func (c *MattermostChannel) StartStreamConsumer(ctx context.Context) {
+	go c.stream.run(ctx)
+}
+
+// HandleStreamDelta processes a single stream delta event.
+func (c *MattermostChannel) HandleStreamDelta(delta bus.StreamDelta) {
+	c.stream.add(delta)
+}
+
+// SendTyping shows the bot typing in a channel or thread for a few
+// seconds.
+func (c *MattermostChannel) SendTyping(ctx context.Context, chatID string) error {
+	channelID, rootID, _ := strings.Cut(chatID, "/")
+	return c.api(ctx, http.MethodPost, "/api/v4/users/"+c.userID+"/typing", map[string]string{
+		"channel_id": channelID,
+		"parent_id":  rootID,
+	}, nil)
+}
+
+// ShowStatus shows what the agent is doing in a message of its own.
+func (c *MattermostChannel) ShowStatus(ctx context.Context, chatID, status string) error {
+	return c.stream.showStatus(ctx, chatID, status)
+}
+
+// ClearStatus removes the status message.
+func (c *MattermostChannel) ClearStatus(ctx context.Context, chatID string) error {
+	return c.stream.clearStatus(ctx, chatID)
+}
+
+// api sends a JSON request to the server and decodes the JSON reply into out.
+func (c *MattermostChannel) api(ctx context.Context, method, path string, body, out any) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	return c.request(ctx, method, path, "application/json", data, out)
+}
+
+func (c *MattermostChannel) request(ctx context.Context, method, path, contentType string, data []byte, out any) error {
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.serverURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	if data != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(respBody, &apiErr)
+		return fmt.Errorf("mattermost %s %s: %s", method, path, firstNonEmpty(apiErr.Message, resp.Status))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+// enqueueInbound hands a received message to the goroutine running
+// processInbound. Handling one may download files and transcribe audio,
+// which must not hold up the WebSocket read loop past its read deadline.
+// When the queue is full the message is dropped.
+func enqueueInbound(name string, queue chan func(), handle func()) {
+	select {
+	case queue <- handle:
+	default:
+		logger.WarnC(name, "Inbound queue full, dropping message")
+	}
+}
+
+// processInbound handles queued messages one at a time, in the order they
+// arrived, until ctx is done.
+func processInbound(ctx context.Context, queue chan func()) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case handle := <-queue:
+			handle()
+		}
+	}
+}
+
+// recentIDs remembers up to a limit of IDs, forgetting the oldest first.
+type recentIDs struct {
+	mu    sync.Mutex
+	limit int
+	ids   map[string]struct{}
+	order []string
+}
+
+func newRecentIDs(limit int) *recentIDs {
+	return &recentIDs{limit: limit, ids: make(map[string]struct{})}
+}
+
+// add records id and reports whether it is new.
+func (r *recentIDs) add(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.ids[id]; ok {
+		return false
+	}
+	r.ids[id] = struct{}{}
+	r.order = append(r.order, id)
+	if len(r.order) > r.limit {
+		delete(r.ids, r.order[0])
+		r.order = r.order[1:]
+	}
+	return true
+}
+
+func (r *recentIDs) has(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.ids[id]
+	return ok
+}
+
+// dialWebSocket opens a WebSocket that pings the server and is closed when
+// ctx is done or the server stops answering.
+func dialWebSocket(ctx context.Context, wsURL string, header http.Header) (*websocket.Conn, error) {
+	dialer := websocket.Dialer{HandshakeTimeout: 10 * time.Second, Proxy: http.ProxyFromEnvironment}
+	conn, resp, err := dialer.DialContext(ctx, wsURL, header)
+	if resp != nil {
+		resp.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * wsPingInterval))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * wsPingInterval))
+	})
+	go func() {
+		ticker := time.NewTicker(wsPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				conn.Close()
+				return
+			case <-ticker.C:
+				// WriteControl may be called alongside the reader and writers.
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second)); err != nil {
+					return
+				}
+			}
+		}
+	}()
+	return conn, nil
+}
+
+// stripAtMention removes "@username" mentions of the bot and the
+// punctuation that follows one that leads the message.
+func stripAtMention(content, username string) string {
+	if username == "" {
+		return strings.TrimSpace(content)
+	}
+	mention := "@" + username
+	var out strings.Builder
+	for {
+		i := strings.Index(strings.ToLower(content), strings.ToLower(mention))
+		if i < 0 {
+			break
+		}
+		end := i + len(mention)
+		// "@agentx" must not eat the start of "@agentx-bot".
+		if end < len(content) && isMentionChar(content[end]) {
+			out.WriteString(content[:end])
+			content = content[end:]
+			continue
+		}
+		out.WriteString(content[:i])
+		content = content[end:]
+	}
+	out.WriteString(content)
+	return strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(out.String()), ":,"))
+}
+
+func isMentionChar(b byte) bool {
+	return b == '-' || b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// setThreadPeer sets the routing peer of a message in a team chat. A
+// thread in a channel is a peer of its own, so it gets its own session,
+// with the channel as parent peer so bindings for the channel still match.
+func setThreadPeer(metadata map[string]string, direct bool, senderID, channelID, rootID string) {
+	switch {
+	case direct:
+		metadata["peer_kind"] = "direct"
+		metadata["peer_id"] = senderID
+	case rootID == "":
+		metadata["peer_kind"] = "channel"
+		metadata["peer_id"] = channelID
+	default:
+		metadata["peer_kind"] = "thread"
+		metadata["peer_id"] = channelID + "/" + rootID
+		metadata["parent_peer_kind"] = "channel"
+		metadata["parent_peer_id"] = channelID
+	}
+}
@@ -0,0 +1,300 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/config"
+)
+
+// testMattermostServer fakes the parts of the Mattermost API the channel
+// uses and records the posts it creates and edits.
+type testMattermostServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	conn    *websocket.Conn
+	posts   []mattermostNewPost
+	edits   map[string]string
+	uploads []string
+}
+
+func newTestMattermostServer(t *testing.T) *testMattermostServer {
+	t.Helper()
+	s := &testMattermostServer{edits: map[string]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v4/users/me", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message":"Invalid or expired session"}`))
+			return
+		}
+		w.Write([]byte(`{"id":"bot1","username":"agentx"}`))
+	})
+	mux.HandleFunc("/api/v4/websocket", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		var challenge map[string]any
+		conn.ReadJSON(&challenge)
+		conn.WriteJSON(map[string]any{"event": "hello"})
+		s.mu.Lock()
+		s.conn = conn
+		s.mu.Unlock()
+	})
+	mux.HandleFunc("POST /api/v4/posts", func(w http.ResponseWriter, r *http.Request) {
+		var post mattermostNewPost
+		json.NewDecoder(r.Body).Decode(&post)
+		s.mu.Lock()
+		s.posts = append(s.posts, post)
+		id := "p" + string(rune('0'+len(s.posts)))
+		s.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]string{"id": id})
+	})
+	mux.HandleFunc("PUT /api/v4/posts/{id}/patch", func(w http.ResponseWriter, r *http.Request) {
+		var patch map[string]string
+		json.NewDecoder(r.Body).Decode(&patch)
+		s.mu.Lock()
+		s.edits[r.PathValue("id")] = patch["message"]
+		s.mu.Unlock()
+		w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("POST /api/v4/files", func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("files")
+		if err != nil || r.FormValue("channel_id") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		s.mu.Lock()
+		s.uploads = append(s.uploads, header.Filename+":"+string(data))
+		s.mu.Unlock()
+		w.Write([]byte(`{"file_infos":[{"id":"f-` + header.Filename + `"}]}`))
+	})
+	mux.HandleFunc("GET /api/v4/files/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("contents of " + r.PathValue("id")))
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// post pushes a "posted" event over the WebSocket.
+func (s *testMattermostServer) post(t *testing.T, channelType, teamID string, mentions []string, post map[string]any) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		s.mu.Lock()
+		conn := s.conn
+		s.mu.Unlock()
+		if conn != nil {
+			postJSON, _ := json.Marshal(post)
+			data := map[string]any{
+				"channel_type": channelType,
+				"sender_name":  "@" + post["username"].(string),
+				"team_id":      teamID,
+				"post":         string(postJSON),
+			}
+			if mentions != nil {
+				mentionJSON, _ := json.Marshal(mentions)
+				data["mentions"] = string(mentionJSON)
+			}
+			if err := conn.WriteJSON(map[string]any{"event": "posted", "data": data}); err != nil {
+				t.Fatal(err)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("channel never connected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func startTestMattermostChannel(t *testing.T, srv *testMattermostServer, cfg config.MattermostConfig) (*MattermostChannel, *bus.MessageBus) {
+	t.Helper()
+	cfg.URL = srv.URL
+	cfg.Token = "tok"
+	msgBus := bus.NewMessageBus()
+	ch, err := NewMattermostChannel(cfg, msgBus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		ch.Stop(ctx)
+		cancel()
+	})
+	if err := ch.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return ch, msgBus
+}
+
+func TestMattermostChannel_MentionsThreadsAndTeams(t *testing.T) {
+	srv := newTestMattermostServer(t)
+	ch, msgBus := startTestMattermostChannel(t, srv, config.MattermostConfig{
+		MentionOnly:   true,
+		ReplyInThread: true,
+		AllowFrom:     []string{"alice"},
+	})
+
+	srv.post(t, "O", "team1", nil, map[string]any{"id": "m1", "user_id": "u1", "username": "alice", "channel_id": "town", "message": "lunch?"})
+	srv.post(t, "O", "team1", []string{"bot1"}, map[string]any{"id": "m2", "user_id": "u2", "username": "mallory", "channel_id": "town", "message": "@agentx leak it"})
+	srv.post(t, "O", "team1", []string{"bot1"}, map[string]any{"id": "m3", "user_id": "u1", "username": "alice", "channel_id": "town", "message": "@agentx: what's the build status?"})
+
+	msg := waitInbound(t, msgBus)
+	if msg.ChatID != "town/m3" || msg.SenderID != "u1|alice" || msg.Content != "what's the build status?" {
+		t.Errorf("msg = %+v", msg)
+	}
+	want := map[string]string{
+		"message_id":       "m3",
+		"team_id":          "team1",
+		"peer_kind":        "thread",
+		"peer_id":          "town/m3",
+		"parent_peer_kind": "channel",
+		"parent_peer_id":   "town",
+	}
+	for k, v := range want {
+		if msg.Metadata[k] != v {
+			t.Errorf("metadata[%s] = %q, want %q", k, msg.Metadata[k], v)
+		}
+	}
+
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: msg.ChatID, Content: "Green."}); err != nil {
+		t.Fatal(err)
+	}
+	srv.mu.Lock()
+	if len(srv.posts) != 1 || srv.posts[0].RootID != "m3" || srv.posts[0].Message != "Green." {
+		t.Errorf("posts = %+v", srv.posts)
+	}
+	srv.mu.Unlock()
+
+	// Once the bot has answered in a thread, follow-ups need no mention.
+	srv.post(t, "O", "team1", nil, map[string]any{"id": "m4", "user_id": "u1", "username": "alice", "channel_id": "town", "root_id": "m3", "message": "and staging?"})
+	msg = waitInbound(t, msgBus)
+	if msg.ChatID != "town/m3" || msg.Content != "and staging?" || msg.Metadata["root_id"] != "m3" {
+		t.Errorf("msg = %+v", msg)
+	}
+
+	// Direct messages need no mention and are not threaded.
+	srv.post(t, "D", "", nil, map[string]any{
+		"id": "m5", "user_id": "u1", "username": "alice", "channel_id": "dm1", "message": "see attached",
+		"metadata": map[string]any{"files": []map[string]string{{"id": "f9", "name": "notes.txt", "mime_type": "text/plain"}}},
+	})
+	msg = waitInbound(t, msgBus)
+	if msg.ChatID != "dm1" || msg.Metadata["peer_kind"] != "direct" || msg.Metadata["peer_id"] != "u1" {
+		t.Errorf("msg = %+v", msg)
+	}
+	if msg.Content != "see attached\n[file: notes.txt]" || len(msg.Media) != 1 {
+		t.Fatalf("msg = %+v", msg)
+	}
+	if data, _ := os.ReadFile(msg.Media[0]); string(data) != "contents of f9" {
+		t.Errorf("media = %q", data)
+	}
+	os.Remove(msg.Media[0])
+
+	if extra, ok := consumeInbound(t, msgBus); ok {
+		t.Errorf("unexpected message %+v", extra)
+	}
+}
+
+func TestMattermostChannel_StreamingAndUploads(t *testing.T) {
+	srv := newTestMattermostServer(t)
+	ch, _ := startTestMattermostChannel(t, srv, config.MattermostConfig{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch.StartStreamConsumer(ctx)
+
+	ch.HandleStreamDelta(bus.StreamDelta{ChatID: "town/m1", Delta: "Checking the "})
+	ch.HandleStreamDelta(bus.StreamDelta{ChatID: "town/m1", Delta: "logs"})
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		srv.mu.Lock()
+		n := len(srv.posts)
+		srv.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no preview posted")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	ch.HandleStreamDelta(bus.StreamDelta{ChatID: "town/m1", Done: true})
+
+	report := writeTempFile(t, "report.csv", "a,b")
+	err := ch.Send(context.Background(), bus.OutboundMessage{
+		ChatID:      "town/m1",
+		Content:     "Checking the logs: all clear.",
+		Attachments: []bus.Attachment{{Path: report}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.posts[0].Message != "Checking the logs" || srv.posts[0].RootID != "m1" {
+		t.Errorf("preview = %+v", srv.posts[0])
+	}
+	if srv.edits["p1"] != "Checking the logs: all clear." {
+		t.Errorf("edits = %v", srv.edits)
+	}
+	if len(srv.uploads) != 1 || srv.uploads[0] != "report.csv:a,b" {
+		t.Errorf("uploads = %v", srv.uploads)
+	}
+	if len(srv.posts) != 2 || !slices.Equal(srv.posts[1].FileIDs, []string{"f-report.csv"}) || srv.posts[1].RootID != "m1" {
+		t.Errorf("posts = %+v", srv.posts)
+	}
+}
+
+func TestMattermostChannel_BadToken(t *testing.T) {
+	srv := newTestMattermostServer(t)
+	ch, err := NewMattermostChannel(config.MattermostConfig{URL: srv.URL, Token: "wrong"}, bus.NewMessageBus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Start(context.Background()); err == nil || !strings.Contains(err.Error(), "Invalid or expired session") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestStripAtMention(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"@agentx: hi", "hi"},
+		{"@AgentX, hi", "hi"},
+		{"ask @agentx about it", "ask  about it"},
+		{"@agentx-ci failed", "@agentx-ci failed"},
+	}
+	for _, tt := range tests {
+		if got := stripAtMention(tt.in, "agentx"); got != tt.want {
+			t.Errorf("stripAtMention(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRecentIDs(t *testing.T) {
+	r := newRecentIDs(2)
+	if !r.add("a") || r.add("a") {
+		t.Error("add should report only new IDs")
+	}
+	r.add("b")
+	r.add("c")
+	if r.has("a") || !r.has("b") || !r.has("c") {
+		t.Error("the oldest ID should have been forgotten")
+	}
+}
@@ -0,0 +1,380 @@
+package channels
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/config"
+	"github.com/Agentx-network/agentx/pkg/logger"
+	"github.com/Agentx-network/agentx/pkg/utils"
+)
+
+const (
+	mqttReconnectDelay     = 5 * time.Second
+	mqttDefaultReplyTopic  = "{topic}/reply"
+	mqttResponseFormatJSON = "json"
+	mqttInboundQueueSize   = 100
+	mqttMaxEchoes          = 1024
+	mqttEchoTTL            = 30 * time.Second
+)
+
+// MQTTChannel subscribes to the configured topics and hands their messages
+// to the agent. A message's chat is its topic unless the topic config names
+// one; replies are published to the chat's response topic. Chat IDs that are
+// not known chats are treated as topics, so the message tool can publish to
+// any topic, e.g. a device's command topic.
+type MQTTChannel struct {
+	*BaseChannel
+	config    config.MQTTConfig
+	tlsConfig *tls.Config
+	cancel    context.CancelFunc
+
+	mu      sync.Mutex // guards client
+	client  *mqttClient
+	inbound chan mqttMessage
+
+	routes sync.Map // chat ID -> mqttRoute
+
+	echoMu sync.Mutex
+	echoes map[mqttEcho]time.Time // recent publishes -> when they expire
+}
+
+// mqttEcho identifies a message the channel published, so that it can be
+// told apart from a device's message when a subscription delivers it back.
+type mqttEcho struct {
+	topic string
+	sum   [sha256.Size]byte
+}
+
+type mqttMessage struct {
+	topic   string
+	payload []byte
+}
+
+// mqttRoute is where replies to a chat go.
+type mqttRoute struct {
+	topic  string
+	format string
+	retain bool
+}
+
+func NewMQTTChannel(cfg config.MQTTConfig, messageBus *bus.MessageBus) (*MQTTChannel, error) {
+	if cfg.Broker == "" {
+		return nil, fmt.Errorf("mqtt broker is required")
+	}
+	if len(cfg.Topics) == 0 {
+		return nil, fmt.Errorf("mqtt needs at least one topic")
+	}
+	for _, t := range cfg.Topics {
+		if t.Topic == "" {
+			return nil, fmt.Errorf("mqtt topic config without a topic")
+		}
+	}
+	tlsConfig, err := mqttTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &MQTTChannel{
+		BaseChannel: NewBaseChannel("mqtt", cfg, messageBus, cfg.AllowFrom),
+		config:      cfg,
+		tlsConfig:   tlsConfig,
+		inbound:     make(chan mqttMessage, mqttInboundQueueSize),
+		echoes:      make(map[mqttEcho]time.Time),
+	}
+	// Chats named in the config can be written to before anything arrives.
+	for _, t := range cfg.Topics {
+		if t.ChatID == "" {
+			continue
+		}
+		if route := c.route(t, t.Topic, t.ChatID); !strings.ContainsAny(route.topic, "+#") {
+			c.routes.Store(t.ChatID, route)
+		}
+	}
+	return c, nil
+}
+
+func mqttTLSConfig(cfg config.MQTTConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: cfg.TLSInsecure} //nolint:gosec // opt-in for self-signed brokers
+	if cfg.TLSCA != "" {
+		pem, err := os.ReadFile(cfg.TLSCA)
+		if err != nil {
+			return nil, fmt.Errorf("mqtt: reading CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("mqtt: no certificates in %s", cfg.TLSCA)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.TLSCert != "" || cfg.TLSKey != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey)
+		if err != nil {
+			return nil, fmt.Errorf("mqtt: loading client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+func (c *MQTTChannel) Start(ctx context.Context) error {
+	logger.InfoCF("mqtt", "Starting MQTT channel", map[string]any{
+		"broker": c.config.Broker,
+		"topics": len(c.config.Topics),
+	})
+
+	ctx, c.cancel = context.WithCancel(ctx)
+	client, err := c.connect(ctx)
+	if err != nil {
+		logger.WarnCF("mqtt", "MQTT broker not reachable, will keep retrying", map[string]any{
+			"error": err.Error(),
+		})
+	}
+	c.setRunning(true)
+	go c.run(ctx, client)
+	go c.processInbound(ctx)
+	return nil
+}
+
+func (c *MQTTChannel) Stop(ctx context.Context) error {
+	logger.InfoC("mqtt", "Stopping MQTT channel")
+	c.setRunning(false)
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.mu.Lock()
+	if c.client != nil {
+		c.client.Close()
+		c.client = nil
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *MQTTChannel) connect(ctx context.Context) (*mqttClient, error) {
+	client, err := dialMQTT(ctx, c.config.Broker, mqttConnectOptions{
+		clientID: c.config.ClientID,
+		username: c.config.Username,
+		password: c.config.Password,
+		tls:      c.tlsConfig,
+	}, c.enqueue)
+	if err != nil {
+		return nil, err
+	}
+	filters := make([]string, 0, len(c.config.Topics))
+	for _, t := range c.config.Topics {
+		filters = append(filters, t.Topic)
+	}
+	if err := client.Subscribe(ctx, filters, c.qos()); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.client = client
+	c.mu.Unlock()
+	logger.InfoCF("mqtt", "Connected to MQTT broker", map[string]any{
+		"topics": strings.Join(filters, ", "),
+	})
+	return client, nil
+}
+
+// run reconnects whenever the connection to the broker drops.
+func (c *MQTTChannel) run(ctx context.Context, client *mqttClient) {
+	for {
+		if client != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-client.Done():
+				logger.WarnC("mqtt", "Disconnected from MQTT broker")
+			}
+			c.mu.Lock()
+			if c.client == client {
+				c.client = nil
+			}
+			c.mu.Unlock()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(mqttReconnectDelay):
+		}
+		var err error
+		if client, err = c.connect(ctx); err != nil {
+			logger.DebugCF("mqtt", "Reconnect failed", map[string]any{
+				"error": err.Error(),
+			})
+		}
+	}
+}
+
+// enqueue runs on the client's read loop; handling a message publishes,
+// which needs that loop, so messages are handled on another goroutine.
+func (c *MQTTChannel) enqueue(topic string, payload []byte) {
+	select {
+	case c.inbound <- mqttMessage{topic: topic, payload: payload}:
+	default:
+		logger.WarnCF("mqtt", "Inbound queue full, dropping message", map[string]any{
+			"topic": topic,
+		})
+	}
+}
+
+func (c *MQTTChannel) processInbound(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-c.inbound:
+			c.handleMessage(msg.topic, msg.payload)
+		}
+	}
+}
+
+func (c *MQTTChannel) handleMessage(topic string, payload []byte) {
+	if c.isEcho(topic, payload) {
+		// Our own reply, delivered back by an overlapping subscription.
+		return
+	}
+	var tc *config.MQTTTopicConfig
+	for i := range c.config.Topics {
+		if mqttTopicMatches(c.config.Topics[i].Topic, topic) {
+			tc = &c.config.Topics[i]
+			break
+		}
+	}
+	if tc == nil {
+		return
+	}
+
+	content := string(payload)
+	senderID := topic
+	if tc.PayloadField != "" || tc.SenderField != "" {
+		var doc any
+		if err := json.Unmarshal(payload, &doc); err == nil {
+			if v, ok := jsonField(doc, tc.PayloadField); ok {
+				content = v
+			}
+			if v, ok := jsonField(doc, tc.SenderField); ok && v != "" {
+				senderID = v
+			}
+		}
+	}
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return
+	}
+
+	chatID := firstNonEmpty(tc.ChatID, topic)
+	c.routes.Store(chatID, c.route(*tc, topic, chatID))
+
+	logger.DebugCF("mqtt", "Received message", map[string]any{
+		"topic":   topic,
+		"sender":  senderID,
+		"preview": utils.Truncate(content, 50),
+	})
+
+	c.HandleMessage(senderID, chatID, content, nil, map[string]string{
+		"topic":     topic,
+		"peer_kind": "direct",
+		"peer_id":   chatID,
+	})
+}
+
+func (c *MQTTChannel) route(tc config.MQTTTopicConfig, topic, chatID string) mqttRoute {
+	replyTopic := firstNonEmpty(tc.ResponseTopic, mqttDefaultReplyTopic)
+	replyTopic = strings.NewReplacer("{topic}", topic, "{chat_id}", chatID).Replace(replyTopic)
+	return mqttRoute{topic: replyTopic, format: tc.ResponseFormat, retain: tc.Retain}
+}
+
+func (c *MQTTChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("mqtt channel not running")
+	}
+	c.mu.Lock()
+	client := c.client
+	c.mu.Unlock()
+	if client == nil {
+		return fmt.Errorf("mqtt broker not connected")
+	}
+
+	route := mqttRoute{topic: msg.ChatID}
+	if r, ok := c.routes.Load(msg.ChatID); ok {
+		route = r.(mqttRoute)
+	}
+	if route.topic == "" || strings.ContainsAny(route.topic, "+#") {
+		return fmt.Errorf("mqtt: no topic to publish to for chat %q", msg.ChatID)
+	}
+
+	// Files cannot be published; they are listed in the text instead.
+	text := appendFallbackText(msg.Content, msg.Attachments, msg.Buttons)
+	payload := []byte(text)
+	if route.format == mqttResponseFormatJSON {
+		var err error
+		payload, err = json.Marshal(map[string]string{
+			"chat_id":  msg.ChatID,
+			"text":     text,
+			"reply_to": msg.ReplyTo,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	c.markPublished(route.topic, payload)
+	if err := client.Publish(ctx, route.topic, payload, c.qos(), route.retain); err != nil {
+		c.isEcho(route.topic, payload) // nothing will come back
+		return err
+	}
+	return nil
+}
+
+// markPublished remembers a message about to be published for
+// mqttEchoTTL, so that it is not taken for a new message if it comes back.
+// Devices may write to the topics the agent writes to; only the exact
+// payload is ignored, and only once.
+func (c *MQTTChannel) markPublished(topic string, payload []byte) {
+	now := time.Now()
+	c.echoMu.Lock()
+	defer c.echoMu.Unlock()
+	for echo, expires := range c.echoes {
+		if now.After(expires) {
+			delete(c.echoes, echo)
+		}
+	}
+	if len(c.echoes) < mqttMaxEchoes {
+		c.echoes[mqttEcho{topic: topic, sum: sha256.Sum256(payload)}] = now.Add(mqttEchoTTL)
+	}
+}
+
+// isEcho reports whether a message is one the channel recently published,
+// and forgets it.
+func (c *MQTTChannel) isEcho(topic string, payload []byte) bool {
+	echo := mqttEcho{topic: topic, sum: sha256.Sum256(payload)}
+	c.echoMu.Lock()
+	defer c.echoMu.Unlock()
+	expires, ok := c.echoes[echo]
+	if !ok {
+		return false
+	}
+	delete(c.echoes, echo)
+	return time.Now().Before(expires)
+}
+
+func (c *MQTTChannel) qos() byte {
+	if c.config.QoS > 0 {
+		return 1
+	}
+	return 0
+}
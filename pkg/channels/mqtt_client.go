@@ -0,0 +1,440 @@
+package channels
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MQTT 3.1.1 control packet types, shifted into the fixed header.
+const (
+	mqttConnect        byte = 1 << 4
+	mqttConnack        byte = 2 << 4
+	mqttPublish        byte = 3 << 4
+	mqttPuback         byte = 4 << 4
+	mqttPubrec         byte = 5 << 4
+	mqttPubrel         byte = 6 << 4
+	mqttPubcomp        byte = 7 << 4
+	mqttSubscribe      byte = 8 << 4
+	mqttSuback         byte = 9 << 4
+	mqttPingreq        byte = 12 << 4
+	mqttPingresp       byte = 13 << 4
+	mqttDisconnect     byte = 14 << 4
+	mqttMaxPacket           = 16 << 20
+	mqttTimeout             = 30 * time.Second
+	mqttKeepAlive           = 60 * time.Second
+	mqttDefaultPort         = "1883"
+	mqttDefaultTLSPort      = "8883"
+)
+
+var mqttConnackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// mqttClient is the subset of MQTT 3.1.1 the MQTT channel needs: connect,
+// subscribe, publish at QoS 0 and 1, and receive at any QoS.
+type mqttClient struct {
+	conn      net.Conn
+	writeMu   sync.Mutex
+	nextID    atomic.Uint32
+	pending   sync.Map // packet ID -> chan []byte, closed when the connection drops
+	lastRead  atomic.Int64
+	onMessage func(topic string, payload []byte)
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type mqttConnectOptions struct {
+	clientID  string
+	username  string
+	password  string
+	keepAlive time.Duration
+	tls       *tls.Config // used for ssl://, tls:// and mqtts:// brokers
+}
+
+// dialMQTT connects to a broker given as tcp://host:port, ssl://host:port
+// or host:port. onMessage is called from the read loop, so it must not
+// block on the client.
+func dialMQTT(ctx context.Context, broker string, opts mqttConnectOptions, onMessage func(string, []byte)) (*mqttClient, error) {
+	addr, useTLS, err := parseMQTTBroker(broker)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: mqttTimeout}
+	var conn net.Conn
+	if useTLS {
+		tlsConfig := opts.tls
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.keepAlive <= 0 {
+		opts.keepAlive = mqttKeepAlive
+	}
+	c := &mqttClient{conn: conn, onMessage: onMessage, done: make(chan struct{})}
+	if err := c.handshake(opts); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c.lastRead.Store(time.Now().UnixNano())
+	go c.readLoop()
+	go c.pingLoop(opts.keepAlive)
+	return c, nil
+}
+
+func parseMQTTBroker(broker string) (addr string, useTLS bool, err error) {
+	if !strings.Contains(broker, "://") {
+		broker = "tcp://" + broker
+	}
+	u, err := url.Parse(broker)
+	if err != nil {
+		return "", false, fmt.Errorf("mqtt: invalid broker %q: %w", broker, err)
+	}
+	port := mqttDefaultPort
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "ssl", "tls", "mqtts":
+		useTLS = true
+		port = mqttDefaultTLSPort
+	default:
+		return "", false, fmt.Errorf("mqtt: unsupported broker scheme %q", u.Scheme)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	return net.JoinHostPort(u.Hostname(), port), useTLS, nil
+}
+
+func (c *mqttClient) handshake(opts mqttConnectOptions) error {
+	var flags byte = 0x02 // clean session
+	if opts.username != "" {
+		flags |= 0x80
+	}
+	if opts.password != "" {
+		flags |= 0x40
+	}
+	body := mqttAppendString(nil, "MQTT")
+	body = append(body, 4, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(opts.keepAlive/time.Second))
+	body = mqttAppendString(body, opts.clientID)
+	if opts.username != "" {
+		body = mqttAppendString(body, opts.username)
+	}
+	if opts.password != "" {
+		body = mqttAppendString(body, opts.password)
+	}
+
+	c.conn.SetDeadline(time.Now().Add(mqttTimeout))
+	defer c.conn.SetDeadline(time.Time{})
+	if err := c.write(mqttConnect, body); err != nil {
+		return err
+	}
+	header, ack, err := readMQTTPacket(c.conn)
+	if err != nil {
+		return fmt.Errorf("mqtt: reading CONNACK: %w", err)
+	}
+	if header&0xF0 != mqttConnack || len(ack) < 2 {
+		return fmt.Errorf("mqtt: expected CONNACK, got packet type %d", header>>4)
+	}
+	if code := ack[1]; code != 0 {
+		if msg, ok := mqttConnackErrors[code]; ok {
+			return fmt.Errorf("mqtt: connection refused: %s", msg)
+		}
+		return fmt.Errorf("mqtt: connection refused (code %d)", code)
+	}
+	return nil
+}
+
+// Done is closed when the connection drops.
+func (c *mqttClient) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *mqttClient) Close() error {
+	c.write(mqttDisconnect, nil)
+	return c.shutdown()
+}
+
+func (c *mqttClient) shutdown() error {
+	var err error
+	c.closeOnce.Do(func() {
+		err = c.conn.Close()
+		close(c.done)
+		c.pending.Range(func(key, value any) bool {
+			c.pending.Delete(key)
+			close(value.(chan []byte))
+			return true
+		})
+	})
+	return err
+}
+
+// Subscribe subscribes to topic filters and waits for the broker to grant
+// them.
+func (c *mqttClient) Subscribe(ctx context.Context, filters []string, qos byte) error {
+	id, ack := c.newPacketID()
+	body := binary.BigEndian.AppendUint16(nil, id)
+	for _, f := range filters {
+		body = mqttAppendString(body, f)
+		body = append(body, qos)
+	}
+	if err := c.write(mqttSubscribe|0x02, body); err != nil {
+		c.pending.Delete(id)
+		return err
+	}
+	granted, err := c.waitAck(ctx, id, ack)
+	if err != nil {
+		return fmt.Errorf("mqtt: subscribe: %w", err)
+	}
+	for i, code := range granted[min(2, len(granted)):] {
+		if code == 0x80 && i < len(filters) {
+			return fmt.Errorf("mqtt: subscription to %q refused", filters[i])
+		}
+	}
+	return nil
+}
+
+// Publish sends a message. At QoS 1 it waits for the broker's PUBACK.
+func (c *mqttClient) Publish(ctx context.Context, topic string, payload []byte, qos byte, retain bool) error {
+	header := mqttPublish | qos<<1
+	if retain {
+		header |= 0x01
+	}
+	body := mqttAppendString(nil, topic)
+	if qos == 0 {
+		return c.write(header, append(body, payload...))
+	}
+
+	id, ack := c.newPacketID()
+	body = binary.BigEndian.AppendUint16(body, id)
+	if err := c.write(header, append(body, payload...)); err != nil {
+		c.pending.Delete(id)
+		return err
+	}
+	if _, err := c.waitAck(ctx, id, ack); err != nil {
+		return fmt.Errorf("mqtt: publish to %s: %w", topic, err)
+	}
+	return nil
+}
+
+func (c *mqttClient) newPacketID() (uint16, chan []byte) {
+	id := uint16(c.nextID.Add(1)%0xFFFF + 1)
+	ack := make(chan []byte, 1)
+	c.pending.Store(id, ack)
+	return id, ack
+}
+
+func (c *mqttClient) waitAck(ctx context.Context, id uint16, ack chan []byte) ([]byte, error) {
+	timer := time.NewTimer(mqttTimeout)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		c.pending.Delete(id)
+		return nil, ctx.Err()
+	case <-timer.C:
+		c.pending.Delete(id)
+		return nil, errors.New("timed out")
+	case body, ok := <-ack:
+		if !ok {
+			return nil, errors.New("connection closed")
+		}
+		return body, nil
+	}
+}
+
+func (c *mqttClient) readLoop() {
+	defer c.shutdown()
+	r := bufio.NewReader(c.conn)
+	// QoS 2 messages delivered and waiting for their PUBREL, so that the
+	// broker resending one before that does not deliver it twice.
+	received := make(map[uint16]bool)
+	for {
+		header, body, err := readMQTTPacket(r)
+		if err != nil {
+			return
+		}
+		c.lastRead.Store(time.Now().UnixNano())
+
+		switch header & 0xF0 {
+		case mqttPublish:
+			qos := (header >> 1) & 0x03
+			topic, rest, err := mqttReadString(body)
+			if err != nil {
+				return
+			}
+			if qos > 0 && len(rest) < 2 {
+				return
+			}
+			switch qos {
+			case 1:
+				c.write(mqttPuback, rest[:2])
+				rest = rest[2:]
+			case 2:
+				id := binary.BigEndian.Uint16(rest)
+				c.write(mqttPubrec, rest[:2])
+				rest = rest[2:]
+				if received[id] {
+					continue
+				}
+				received[id] = true
+			}
+			if c.onMessage != nil {
+				c.onMessage(topic, rest)
+			}
+		case mqttPubrel:
+			if len(body) < 2 {
+				continue
+			}
+			delete(received, binary.BigEndian.Uint16(body))
+			c.write(mqttPubcomp, body[:2])
+		case mqttPuback, mqttSuback:
+			if len(body) < 2 {
+				continue
+			}
+			if ack, ok := c.pending.LoadAndDelete(binary.BigEndian.Uint16(body)); ok {
+				ack.(chan []byte) <- body
+			}
+		case mqttPingresp:
+		}
+	}
+}
+
+// pingLoop keeps the connection alive and drops it when the broker stops
+// answering.
+func (c *mqttClient) pingLoop(keepAlive time.Duration) {
+	ticker := time.NewTicker(keepAlive / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if time.Since(time.Unix(0, c.lastRead.Load())) > keepAlive*3/2 {
+				c.shutdown()
+				return
+			}
+			c.write(mqttPingreq, nil)
+		}
+	}
+}
+
+// write sends a packet. A write that fails or stalls past mqttTimeout may
+// have left part of a packet on the wire, so it drops the connection.
+func (c *mqttClient) write(header byte, body []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(mqttTimeout))
+	if err := writeMQTTPacket(c.conn, header, body); err != nil {
+		c.shutdown()
+		return err
+	}
+	return nil
+}
+
+func writeMQTTPacket(w io.Writer, header byte, body []byte) error {
+	packet := []byte{header}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		packet = append(packet, b)
+		if n == 0 {
+			break
+		}
+	}
+	_, err := w.Write(append(packet, body...))
+	return err
+}
+
+func readMQTTPacket(r io.Reader) (byte, []byte, error) {
+	var buf [1]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return 0, nil, err
+	}
+	header := buf[0]
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("mqtt: malformed remaining length")
+		}
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return 0, nil, err
+		}
+		length += int(buf[0]&0x7F) * multiplier
+		multiplier *= 128
+		if buf[0]&0x80 == 0 {
+			break
+		}
+	}
+	if length > mqttMaxPacket {
+		return 0, nil, fmt.Errorf("mqtt: packet of %d bytes is too large", length)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
+
+func mqttAppendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func mqttReadString(b []byte) (string, []byte, error) {
+	if len(b) < 2 {
+		return "", nil, errors.New("mqtt: truncated string")
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, errors.New("mqtt: truncated string")
+	}
+	return string(b[2 : 2+n]), b[2+n:], nil
+}
+
+// mqttTopicMatches reports whether a topic matches a filter with + (one
+// level) and # (all remaining levels) wildcards.
+func mqttTopicMatches(filter, topic string) bool {
+	// Wildcards at the first level do not match topics starting with $.
+	if strings.HasPrefix(topic, "$") && (strings.HasPrefix(filter, "+") || strings.HasPrefix(filter, "#")) {
+		return false
+	}
+	f := strings.Split(filter, "/")
+	t := strings.Split(topic, "/")
+	for i, level := range f {
+		if level == "#" {
+			return true
+		}
+		if i >= len(t) {
+			return false
+		}
+		if level != "+" && level != t[i] {
+			return false
+		}
+	}
+	return len(f) == len(t)
+}
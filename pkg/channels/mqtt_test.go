@@ -0,0 +1,511 @@
+package channels
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	mochi "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/config"
+)
+
+// testMQTTBroker is a tiny embedded MQTT 3.1.1 broker: it accepts any
+// client with the right password and forwards publishes at QoS 0 to every
+// matching subscription.
+type testMQTTBroker struct {
+	ln       net.Listener
+	password string
+
+	mu      sync.Mutex
+	clients map[net.Conn][]string
+	retains []bool
+}
+
+func newTestMQTTBroker(t *testing.T, password string) *testMQTTBroker {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &testMQTTBroker{ln: ln, password: password, clients: map[net.Conn][]string{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	t.Cleanup(func() {
+		ln.Close()
+		b.mu.Lock()
+		for conn := range b.clients {
+			conn.Close()
+		}
+		b.mu.Unlock()
+	})
+	return b
+}
+
+func (b *testMQTTBroker) addr() string {
+	return "tcp://" + b.ln.Addr().String()
+}
+
+func (b *testMQTTBroker) send(conn net.Conn, header byte, body []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	writeMQTTPacket(conn, header, body)
+}
+
+func (b *testMQTTBroker) serve(conn net.Conn) {
+	defer func() {
+		b.mu.Lock()
+		delete(b.clients, conn)
+		b.mu.Unlock()
+		conn.Close()
+	}()
+	r := bufio.NewReader(conn)
+	for {
+		header, body, err := readMQTTPacket(r)
+		if err != nil {
+			return
+		}
+		switch header & 0xF0 {
+		case mqttConnect:
+			code := byte(0)
+			if b.password != "" && !strings.Contains(string(body), b.password) {
+				code = 5
+			}
+			b.send(conn, mqttConnack, []byte{0, code})
+			if code != 0 {
+				return
+			}
+			b.mu.Lock()
+			b.clients[conn] = nil
+			b.mu.Unlock()
+		case mqttSubscribe:
+			rest := body[2:]
+			var granted []byte
+			for len(rest) > 0 {
+				filter, tail, _ := mqttReadString(rest)
+				b.mu.Lock()
+				b.clients[conn] = append(b.clients[conn], filter)
+				b.mu.Unlock()
+				granted = append(granted, tail[0])
+				rest = tail[1:]
+			}
+			b.send(conn, mqttSuback, append(body[:2:2], granted...))
+		case mqttPublish:
+			topic, rest, _ := mqttReadString(body)
+			if qos := (header >> 1) & 0x03; qos > 0 {
+				b.send(conn, mqttPuback, rest[:2])
+				rest = rest[2:]
+			}
+			b.mu.Lock()
+			b.retains = append(b.retains, header&0x01 != 0)
+			var targets []net.Conn
+			for client, filters := range b.clients {
+				for _, f := range filters {
+					if mqttTopicMatches(f, topic) {
+						targets = append(targets, client)
+						break
+					}
+				}
+			}
+			b.mu.Unlock()
+			for _, client := range targets {
+				b.send(client, mqttPublish, append(mqttAppendString(nil, topic), rest...))
+			}
+		case mqttPingreq:
+			b.send(conn, mqttPingresp, nil)
+		case mqttDisconnect:
+			return
+		}
+	}
+}
+
+type testMQTTMessage struct {
+	topic   string
+	payload string
+}
+
+// dialTestMQTT connects a device-side client that records what it receives.
+func dialTestMQTT(t *testing.T, broker *testMQTTBroker, filters ...string) (*mqttClient, chan testMQTTMessage) {
+	t.Helper()
+	received := make(chan testMQTTMessage, 10)
+	client, err := dialMQTT(context.Background(), broker.addr(), mqttConnectOptions{
+		clientID: "device",
+		password: broker.password,
+	}, func(topic string, payload []byte) {
+		received <- testMQTTMessage{topic: topic, payload: string(payload)}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	if len(filters) > 0 {
+		if err := client.Subscribe(context.Background(), filters, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return client, received
+}
+
+func startTestMQTTChannel(t *testing.T, cfg config.MQTTConfig) (*MQTTChannel, *bus.MessageBus) {
+	t.Helper()
+	msgBus := bus.NewMessageBus()
+	ch, err := NewMQTTChannel(cfg, msgBus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		ch.Stop(ctx)
+		cancel()
+	})
+	if err := ch.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return ch, msgBus
+}
+
+func waitMQTTMessage(t *testing.T, received chan testMQTTMessage) testMQTTMessage {
+	t.Helper()
+	select {
+	case msg := <-received:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message published")
+		return testMQTTMessage{}
+	}
+}
+
+func TestMQTTChannel_TopicsAndReplies(t *testing.T) {
+	broker := newTestMQTTBroker(t, "secret")
+	ch, msgBus := startTestMQTTChannel(t, config.MQTTConfig{
+		Broker:   broker.addr(),
+		ClientID: "agentx",
+		Password: "secret",
+		QoS:      1,
+		Topics: []config.MQTTTopicConfig{
+			{Topic: "home/+/ask", PayloadField: "data.text", SenderField: "user"},
+			{Topic: "sensors/#", ChatID: "sensors", ResponseTopic: "agentx/{chat_id}", ResponseFormat: "json", Retain: true},
+		},
+	})
+	device, received := dialTestMQTT(t, broker, "home/+/ask/reply", "agentx/#")
+	ctx := context.Background()
+
+	if err := device.Publish(ctx, "home/kitchen/ask", []byte(`{"data":{"text":"Are the lights on?"},"user":"alice"}`), 1, false); err != nil {
+		t.Fatal(err)
+	}
+	msg := waitInbound(t, msgBus)
+	if msg.Content != "Are the lights on?" || msg.SenderID != "alice" || msg.ChatID != "home/kitchen/ask" {
+		t.Errorf("msg = %+v", msg)
+	}
+	if msg.Metadata["topic"] != "home/kitchen/ask" {
+		t.Errorf("metadata = %v", msg.Metadata)
+	}
+	if err := ch.Send(ctx, bus.OutboundMessage{ChatID: msg.ChatID, Content: "Yes, two of them."}); err != nil {
+		t.Fatal(err)
+	}
+	if got := waitMQTTMessage(t, received); got.topic != "home/kitchen/ask/reply" || got.payload != "Yes, two of them." {
+		t.Errorf("reply = %+v", got)
+	}
+
+	// Plain payloads are taken as they are and land in the configured chat.
+	if err := device.Publish(ctx, "sensors/garage/temp", []byte("31.5"), 0, false); err != nil {
+		t.Fatal(err)
+	}
+	msg = waitInbound(t, msgBus)
+	if msg.Content != "31.5" || msg.SenderID != "sensors/garage/temp" || msg.ChatID != "sensors" {
+		t.Errorf("msg = %+v", msg)
+	}
+	if err := ch.Send(ctx, bus.OutboundMessage{ChatID: "sensors", Content: "The garage is hot.", ReplyTo: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	got := waitMQTTMessage(t, received)
+	var reply map[string]string
+	if err := json.Unmarshal([]byte(got.payload), &reply); err != nil {
+		t.Fatal(err)
+	}
+	if got.topic != "agentx/sensors" || reply["chat_id"] != "sensors" || reply["text"] != "The garage is hot." || reply["reply_to"] != "1" {
+		t.Errorf("reply on %s = %v", got.topic, reply)
+	}
+	broker.mu.Lock()
+	retained := broker.retains[len(broker.retains)-1]
+	broker.mu.Unlock()
+	if !retained {
+		t.Error("reply should be retained")
+	}
+}
+
+func TestMQTTChannel_AllowListAndArbitraryTopics(t *testing.T) {
+	broker := newTestMQTTBroker(t, "")
+	ch, msgBus := startTestMQTTChannel(t, config.MQTTConfig{
+		Broker:    broker.addr(),
+		ClientID:  "agentx",
+		Topics:    []config.MQTTTopicConfig{{Topic: "#"}},
+		AllowFrom: []string{"cmd/ok"},
+	})
+	device, received := dialTestMQTT(t, broker, "lights/set", "cmd/ok/reply")
+	ctx := context.Background()
+
+	device.Publish(ctx, "cmd/blocked", []byte("let me in"), 0, false)
+	device.Publish(ctx, "cmd/ok", []byte("turn on the lights"), 0, false)
+	msg := waitInbound(t, msgBus)
+	if msg.ChatID != "cmd/ok" || msg.Content != "turn on the lights" {
+		t.Errorf("msg = %+v", msg)
+	}
+
+	// A chat ID that is no known chat is published to as a topic.
+	if err := ch.Send(ctx, bus.OutboundMessage{ChatID: "lights/set", Content: "ON"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := waitMQTTMessage(t, received); got.topic != "lights/set" || got.payload != "ON" {
+		t.Errorf("command = %+v", got)
+	}
+	if err := ch.Send(ctx, bus.OutboundMessage{ChatID: "cmd/ok", Content: "Done"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := waitMQTTMessage(t, received); got.topic != "cmd/ok/reply" {
+		t.Errorf("reply = %+v", got)
+	}
+
+	// The channel's own publishes come back through "#" and are ignored.
+	if extra, ok := consumeInbound(t, msgBus); ok {
+		t.Errorf("unexpected message %+v", extra)
+	}
+	if err := ch.Send(ctx, bus.OutboundMessage{ChatID: "cmd/+", Content: "x"}); err == nil {
+		t.Error("publishing to a wildcard topic should fail")
+	}
+}
+
+func TestMQTTChannel_ReplyOnSubscribedTopic(t *testing.T) {
+	broker := newTestMQTTBroker(t, "")
+	ch, msgBus := startTestMQTTChannel(t, config.MQTTConfig{
+		Broker:   broker.addr(),
+		ClientID: "agentx",
+		Topics:   []config.MQTTTopicConfig{{Topic: "room/chat", ResponseTopic: "{topic}"}},
+	})
+	device, received := dialTestMQTT(t, broker, "room/chat")
+	ctx := context.Background()
+
+	device.Publish(ctx, "room/chat", []byte("hello"), 0, false)
+	waitMQTTMessage(t, received) // the device's own message
+	if msg := waitInbound(t, msgBus); msg.Content != "hello" {
+		t.Fatalf("msg = %+v", msg)
+	}
+	if err := ch.Send(ctx, bus.OutboundMessage{ChatID: "room/chat", Content: "hi there"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := waitMQTTMessage(t, received); got.payload != "hi there" {
+		t.Fatalf("reply = %+v", got)
+	}
+
+	// The reply comes back to the channel and is dropped.
+	if extra, ok := consumeInbound(t, msgBus); ok {
+		t.Errorf("unexpected message %+v", extra)
+	}
+	// The topic stays open: the device's next message is handled, even
+	// with the same text as the reply.
+	device.Publish(ctx, "room/chat", []byte("hi there"), 0, false)
+	if msg := waitInbound(t, msgBus); msg.Content != "hi there" {
+		t.Errorf("msg = %+v", msg)
+	}
+}
+
+// newMochiBroker runs a full MQTT broker, to check the client against a
+// real implementation of the protocol rather than testMQTTBroker.
+func newMochiBroker(t *testing.T) (*mochi.Server, string) {
+	t.Helper()
+	server := mochi.New(&mochi.Options{
+		InlineClient: true,
+		Logger:       slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err := server.AddHook(new(auth.AllowHook), nil); err != nil {
+		t.Fatal(err)
+	}
+	tcp := listeners.NewTCP(listeners.Config{ID: "tcp", Address: "127.0.0.1:0"})
+	if err := server.AddListener(tcp); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Serve(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+	return server, "tcp://" + tcp.Address()
+}
+
+func TestMQTTChannel_MochiBroker(t *testing.T) {
+	server, addr := newMochiBroker(t)
+	ch, msgBus := startTestMQTTChannel(t, config.MQTTConfig{
+		Broker:   addr,
+		ClientID: "agentx",
+		QoS:      1,
+		Topics:   []config.MQTTTopicConfig{{Topic: "home/+/ask"}},
+	})
+	replies := make(chan string, 1)
+	err := server.Subscribe("home/+/ask/reply", 1, func(_ *mochi.Client, _ packets.Subscription, pk packets.Packet) {
+		replies <- string(pk.Payload)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The channel connects and subscribes in the background.
+	deadline := time.Now().Add(2 * time.Second)
+	for server.Topics.Subscribers("home/kitchen/ask").Subscriptions["agentx"].Filter == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("the channel did not subscribe")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := server.Publish("home/kitchen/ask", []byte("Are the lights on?"), false, 1); err != nil {
+		t.Fatal(err)
+	}
+	msg := waitInbound(t, msgBus)
+	if msg.Content != "Are the lights on?" || msg.ChatID != "home/kitchen/ask" {
+		t.Errorf("msg = %+v", msg)
+	}
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: msg.ChatID, Content: "Yes."}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-replies:
+		if got != "Yes." {
+			t.Errorf("reply = %q", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no reply published")
+	}
+}
+
+// mochiPacketLog records the types of the packets a broker reads.
+type mochiPacketLog struct {
+	mochi.HookBase
+	mu    sync.Mutex
+	types []byte
+}
+
+func (h *mochiPacketLog) ID() string { return "packet-log" }
+
+func (h *mochiPacketLog) Provides(b byte) bool { return b == mochi.OnPacketRead }
+
+func (h *mochiPacketLog) OnPacketRead(_ *mochi.Client, pk packets.Packet) (packets.Packet, error) {
+	h.mu.Lock()
+	h.types = append(h.types, pk.FixedHeader.Type)
+	h.mu.Unlock()
+	return pk, nil
+}
+
+func (h *mochiPacketLog) has(typ byte) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return slices.Contains(h.types, typ)
+}
+
+func TestMQTTClient_QoS2(t *testing.T) {
+	server, addr := newMochiBroker(t)
+	packetLog := new(mochiPacketLog)
+	if err := server.AddHook(packetLog, nil); err != nil {
+		t.Fatal(err)
+	}
+	received := make(chan string, 4)
+	client, err := dialMQTT(context.Background(), addr, mqttConnectOptions{clientID: "device"}, func(_ string, payload []byte) {
+		received <- string(payload)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if err := client.Subscribe(context.Background(), []string{"cmd"}, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := server.Publish("cmd", []byte("exactly once"), false, 2); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-received; got != "exactly once" {
+		t.Errorf("payload = %q", got)
+	}
+	// The client answers with PUBREC, and the broker's PUBREL with PUBCOMP.
+	deadline := time.Now().Add(2 * time.Second)
+	for !packetLog.has(packets.Pubcomp) {
+		if time.Now().After(deadline) {
+			t.Fatal("the QoS 2 exchange did not complete")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !packetLog.has(packets.Pubrec) || packetLog.has(packets.Puback) {
+		t.Errorf("client sent packets %v", packetLog.types)
+	}
+	select {
+	case got := <-received:
+		t.Errorf("delivered twice: %q", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestMQTTClient_Refused(t *testing.T) {
+	broker := newTestMQTTBroker(t, "secret")
+	_, err := dialMQTT(context.Background(), broker.addr(), mqttConnectOptions{clientID: "x", password: "wrong"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "not authorized") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestMQTTTopicMatches(t *testing.T) {
+	tests := []struct {
+		filter, topic string
+		want          bool
+	}{
+		{"home/kitchen/temp", "home/kitchen/temp", true},
+		{"home/+/temp", "home/kitchen/temp", true},
+		{"home/+/temp", "home/kitchen/light", false},
+		{"home/+", "home/kitchen/temp", false},
+		{"home/#", "home/kitchen/temp", true},
+		{"home/#", "home", true},
+		{"#", "home/kitchen", true},
+		{"#", "$SYS/uptime", false},
+		{"home/kitchen", "home/kitchen/temp", false},
+	}
+	for _, tt := range tests {
+		if got := mqttTopicMatches(tt.filter, tt.topic); got != tt.want {
+			t.Errorf("mqttTopicMatches(%q, %q) = %v, want %v", tt.filter, tt.topic, got, tt.want)
+		}
+	}
+}
+
+func TestMQTTPacketLength(t *testing.T) {
+	var buf strings.Builder
+	body := make([]byte, 321)
+	binary.BigEndian.PutUint16(body, 7)
+	if err := writeMQTTPacket(&buf, mqttPuback, body); err != nil {
+		t.Fatal(err)
+	}
+	header, got, err := readMQTTPacket(strings.NewReader(buf.String()))
+	if err != nil || header != mqttPuback || len(got) != 321 || binary.BigEndian.Uint16(got) != 7 {
+		t.Errorf("header = %x, len = %d, err = %v", header, len(got), err)
+	}
+	if _, _, err := parseMQTTBroker("ws://example.com"); err == nil {
+		t.Error("unsupported schemes should fail")
+	}
+	if addr, useTLS, _ := parseMQTTBroker("ssl://broker.local"); addr != "broker.local:8883" || !useTLS {
+		t.Errorf("addr = %s, tls = %v", addr, useTLS)
+	}
+}
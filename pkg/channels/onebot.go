@@ -2,6 +2,7 @@ package channels
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -17,7 +18,6 @@ import (
 	"github.com/Agentx-network/agentx/pkg/config"
 	"github.com/Agentx-network/agentx/pkg/logger"
 	"github.com/Agentx-network/agentx/pkg/utils"
-	"github.com/Agentx-network/agentx/pkg/voice"
 )
 
 type OneBotChannel struct {
@@ -35,7 +35,6 @@ type OneBotChannel struct {
 	selfID          int64
 	pending         map[string]chan json.RawMessage
 	pendingMu       sync.Mutex
-	transcriber     *voice.GroqTranscriber
 	lastMessageID   sync.Map
 	pendingEmojiMsg sync.Map
 }
@@ -111,10 +110,6 @@ func NewOneBotChannel(cfg config.OneBotConfig, messageBus *bus.MessageBus) (*One
 	}, nil
 }
 
-func (c *OneBotChannel) SetTranscriber(transcriber *voice.GroqTranscriber) {
-	c.transcriber = transcriber
-}
-
 func (c *OneBotChannel) setMsgEmojiLike(messageID string, emojiID int, set bool) {
 	go func() {
 		_, err := c.sendAPIRequest("set_msg_emoji_like", map[string]any{
@@ -385,7 +380,7 @@ func (c *OneBotChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 		return fmt.Errorf("OneBot WebSocket not connected")
 	}
 
-	action, params, err := c.buildSendRequest(msg)
+	action, params, err := c.buildSendRequest(ctx, msg)
 	if err != nil {
 		return err
 	}
@@ -423,29 +418,79 @@ func (c *OneBotChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 	return nil
 }
 
-func (c *OneBotChannel) buildMessageSegments(chatID, content string) []oneBotMessageSegment {
+func (c *OneBotChannel) buildMessageSegments(ctx context.Context, msg bus.OutboundMessage) []oneBotMessageSegment {
 	var segments []oneBotMessageSegment
 
-	if lastMsgID, ok := c.lastMessageID.Load(chatID); ok {
-		if msgID, ok := lastMsgID.(string); ok && msgID != "" {
-			segments = append(segments, oneBotMessageSegment{
-				Type: "reply",
-				Data: map[string]any{"id": msgID},
+	replyTo := msg.ReplyTo
+	if replyTo == "" {
+		if lastMsgID, ok := c.lastMessageID.Load(msg.ChatID); ok {
+			replyTo, _ = lastMsgID.(string)
+		}
+	}
+	if replyTo != "" {
+		segments = append(segments, oneBotMessageSegment{
+			Type: "reply",
+			Data: map[string]any{"id": replyTo},
+		})
+	}
+
+	// Images, voice and video have message segments; other files can only
+	// be uploaded through implementation-specific APIs, so they are listed.
+	var media []oneBotMessageSegment
+	var listed []bus.Attachment
+	for _, a := range msg.Attachments {
+		segType := ""
+		switch a.Kind() {
+		case bus.AttachmentImage:
+			segType = "image"
+		case bus.AttachmentAudio:
+			segType = "record"
+		case bus.AttachmentVideo:
+			segType = "video"
+		}
+		if segType == "" {
+			listed = append(listed, a)
+			continue
+		}
+		file, err := oneBotFile(ctx, a)
+		if err != nil {
+			logger.WarnCF("onebot", "Failed to load attachment", map[string]any{
+				"attachment": a.Name(),
+				"error":      err.Error(),
 			})
+			listed = append(listed, a)
+			continue
 		}
+		media = append(media, oneBotMessageSegment{Type: segType, Data: map[string]any{"file": file}})
 	}
 
-	segments = append(segments, oneBotMessageSegment{
-		Type: "text",
-		Data: map[string]any{"text": content},
-	})
+	if text := appendFallbackText(msg.Content, listed, msg.Buttons); text != "" || len(media) == 0 {
+		segments = append(segments, oneBotMessageSegment{
+			Type: "text",
+			Data: map[string]any{"text": text},
+		})
+	}
 
-	return segments
+	return append(segments, media...)
+}
+
+// oneBotFile returns the file reference for a media segment. Local files
+// are inlined as base64 since the OneBot implementation may run on another
+// host.
+func oneBotFile(ctx context.Context, a bus.Attachment) (string, error) {
+	if a.URL != "" {
+		return a.URL, nil
+	}
+	data, err := readAttachment(ctx, a)
+	if err != nil {
+		return "", err
+	}
+	return "base64://" + base64.StdEncoding.EncodeToString(data), nil
 }
 
-func (c *OneBotChannel) buildSendRequest(msg bus.OutboundMessage) (string, any, error) {
+func (c *OneBotChannel) buildSendRequest(ctx context.Context, msg bus.OutboundMessage) (string, any, error) {
 	chatID := msg.ChatID
-	segments := c.buildMessageSegments(chatID, msg.Content)
+	segments := c.buildMessageSegments(ctx, msg)
 
 	var action, idKey string
 	var rawID string
@@ -660,18 +705,12 @@ func (c *OneBotChannel) parseMessageSegments(raw json.RawMessage, selfID int64)
 					})
 					if localPath != "" {
 						localFiles = append(localFiles, localPath)
-						if c.transcriber != nil && c.transcriber.IsAvailable() {
-							tctx, tcancel := context.WithTimeout(c.ctx, 30*time.Second)
-							result, err := c.transcriber.Transcribe(tctx, localPath)
-							tcancel()
-							if err != nil {
-								logger.WarnCF("onebot", "Voice transcription failed", map[string]any{
-									"error": err.Error(),
-								})
+						if c.canTranscribe() {
+							if text, err := c.transcribe(c.ctx, localPath); err != nil {
 								textParts = append(textParts, "[voice (transcription failed)]")
 								media = append(media, localPath)
 							} else {
-								textParts = append(textParts, fmt.Sprintf("[voice transcription: %s]", result.Text))
+								textParts = append(textParts, fmt.Sprintf("[voice transcription: %s]", text))
 							}
 						} else {
 							textParts = append(textParts, "[voice]")
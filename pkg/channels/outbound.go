@@ -0,0 +1,164 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/utils"
+)
+
+// maxReasoningChars bounds the reasoning trace shown in a chat.
+const maxReasoningChars = 3000
+
+// maxAttachmentBytes caps how much of an attachment a channel reads into
+// memory before uploading it.
+const maxAttachmentBytes = 50 << 20
+
+// attachmentText describes attachments as text for channels that cannot
+// upload them: remote files are linked, local files are listed by name.
+func attachmentText(attachments []bus.Attachment) string {
+	if len(attachments) == 0 {
+		return ""
+	}
+	lines := make([]string, 0, len(attachments))
+	for _, a := range attachments {
+		if a.URL != "" {
+			lines = append(lines, fmt.Sprintf("📎 %s: %s", a.Name(), a.URL))
+		} else {
+			lines = append(lines, fmt.Sprintf("📎 %s", a.Name()))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// buttonText lists buttons as numbered options for channels without
+// interactive buttons. URL buttons show their link.
+func buttonText(buttons []bus.Button) string {
+	if len(buttons) == 0 {
+		return ""
+	}
+	lines := make([]string, 0, len(buttons))
+	for i, b := range buttons {
+		if b.URL != "" {
+			lines = append(lines, fmt.Sprintf("%d. %s: %s", i+1, b.Text, b.URL))
+		} else {
+			lines = append(lines, fmt.Sprintf("%d. %s", i+1, b.Text))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// appendFallbackText appends the text form of attachments and buttons to
+// content, for channels that support neither.
+func appendFallbackText(content string, attachments []bus.Attachment, buttons []bus.Button) string {
+	parts := []string{}
+	for _, s := range []string{content, attachmentText(attachments), buttonText(buttons)} {
+		if strings.TrimSpace(s) != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// maxButtonReplies is how many button replies a channel keeps in memory;
+// the buttons of older messages stop working.
+const maxButtonReplies = 1000
+
+// buttonReplyStore keeps button replies that are looked up when a button is
+// pressed, under short references. Past maxButtonReplies, the oldest reply
+// is dropped.
+type buttonReplyStore struct {
+	replies sync.Map // reference -> reply text
+	seq     atomic.Uint64
+}
+
+// store keeps reply and returns its reference, which starts with prefix.
+func (s *buttonReplyStore) store(prefix, reply string) string {
+	seq := s.seq.Add(1)
+	ref := prefix + strconv.FormatUint(seq, 36)
+	s.replies.Store(ref, reply)
+	if seq > maxButtonReplies {
+		s.replies.Delete(prefix + strconv.FormatUint(seq-maxButtonReplies, 36))
+	}
+	return ref
+}
+
+// load returns the reply stored under ref.
+func (s *buttonReplyStore) load(ref string) (string, bool) {
+	v, ok := s.replies.Load(ref)
+	if !ok {
+		return "", false
+	}
+	return v.(string), true
+}
+
+// reasoningText renders a reasoning trace as quoted text, for channels
+// that cannot fold it.
+func reasoningText(reasoning string) string {
+	lines := strings.Split(utils.Truncate(strings.TrimSpace(reasoning), maxReasoningChars), "\n")
+	for i, line := range lines {
+		lines[i] = "> " + line
+	}
+	return "💭 Thinking\n" + strings.Join(lines, "\n")
+}
+
+// withReasoningText moves the reasoning trace of msg into its content.
+func withReasoningText(msg bus.OutboundMessage) bus.OutboundMessage {
+	if msg.Reasoning == "" {
+		return msg
+	}
+	if strings.TrimSpace(msg.Content) == "" {
+		msg.Content = reasoningText(msg.Reasoning)
+	} else {
+		msg.Content = reasoningText(msg.Reasoning) + "\n\n" + msg.Content
+	}
+	msg.Reasoning = ""
+	return msg
+}
+
+// readAttachment loads an attachment into memory, from disk or over HTTP.
+func readAttachment(ctx context.Context, a bus.Attachment) ([]byte, error) {
+	var r io.Reader
+	if a.Path != "" {
+		f, err := os.Open(a.Path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	} else {
+		ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.URL, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("downloading %s: status %d", a.URL, resp.StatusCode)
+		}
+		r = resp.Body
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, maxAttachmentBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxAttachmentBytes {
+		return nil, fmt.Errorf("attachment %s is larger than %d MB", a.Name(), maxAttachmentBytes>>20)
+	}
+	return data, nil
+}
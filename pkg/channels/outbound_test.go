@@ -0,0 +1,151 @@
+package channels
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+)
+
+func TestAppendFallbackText(t *testing.T) {
+	got := appendFallbackText("Report ready",
+		[]bus.Attachment{
+			{URL: "https://example.com/files/report.pdf"},
+			{Path: "/tmp/chart.png"},
+		},
+		[]bus.Button{
+			{Text: "Approve"},
+			{Text: "Docs", URL: "https://example.com/docs"},
+		},
+	)
+	want := "Report ready\n\n" +
+		"📎 report.pdf: https://example.com/files/report.pdf\n📎 chart.png\n\n" +
+		"1. Approve\n2. Docs: https://example.com/docs"
+	if got != want {
+		t.Errorf("appendFallbackText() =\n%q\nwant\n%q", got, want)
+	}
+
+	if got := appendFallbackText("plain", nil, nil); got != "plain" {
+		t.Errorf("plain message changed: %q", got)
+	}
+}
+
+func TestWithReasoningText(t *testing.T) {
+	got := withReasoningText(bus.OutboundMessage{Content: "Hello!", Reasoning: "Greet back.\nKeep it short."})
+	want := "💭 Thinking\n> Greet back.\n> Keep it short.\n\nHello!"
+	if got.Content != want || got.Reasoning != "" {
+		t.Errorf("withReasoningText() = %q (reasoning %q), want %q", got.Content, got.Reasoning, want)
+	}
+
+	if got := withReasoningText(bus.OutboundMessage{Content: "plain"}); got.Content != "plain" {
+		t.Errorf("message without reasoning changed: %q", got.Content)
+	}
+}
+
+func TestBuildLINEMessages(t *testing.T) {
+	msg := bus.OutboundMessage{
+		Content: "Look",
+		Attachments: []bus.Attachment{
+			{URL: "https://example.com/cat.jpg"},
+			{Path: "/tmp/local.png"},
+		},
+		Buttons: []bus.Button{
+			{Text: "More cats please, as many as you have", Value: "more"},
+			{Text: "Source", URL: "https://example.com"},
+		},
+	}
+
+	messages := buildLINEMessages(msg, "quote-1")
+	if len(messages) != 2 {
+		t.Fatalf("expected text + image messages, got %d: %+v", len(messages), messages)
+	}
+	if messages[0]["type"] != "text" || messages[0]["quoteToken"] != "quote-1" {
+		t.Errorf("unexpected text message: %+v", messages[0])
+	}
+	if !strings.Contains(messages[0]["text"].(string), "local.png") {
+		t.Errorf("local file should be listed in the text: %q", messages[0]["text"])
+	}
+	if messages[1]["type"] != "image" || messages[1]["originalContentUrl"] != "https://example.com/cat.jpg" {
+		t.Errorf("unexpected image message: %+v", messages[1])
+	}
+
+	quick, ok := messages[1]["quickReply"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected quick replies on the last message: %+v", messages[1])
+	}
+	items := quick["items"].([]map[string]any)
+	first := items[0]["action"].(map[string]any)
+	if first["text"] != "more" || len([]rune(first["label"].(string))) > lineMaxQuickReplyLabel {
+		t.Errorf("unexpected message action: %+v", first)
+	}
+	if second := items[1]["action"].(map[string]any); second["type"] != "uri" {
+		t.Errorf("expected uri action for link button: %+v", second)
+	}
+}
+
+func TestSlackBlocks(t *testing.T) {
+	if blocks := slackBlocks("hi", nil); blocks != nil {
+		t.Errorf("plain messages should not use blocks: %+v", blocks)
+	}
+
+	blocks := slackBlocks("Deploy?", []bus.Button{
+		{Text: "Yes", Value: "deploy now"},
+		{Text: "Runbook", URL: "https://example.com/runbook"},
+	})
+	if len(blocks) != 2 {
+		t.Fatalf("expected section + actions blocks, got %d", len(blocks))
+	}
+	actions, ok := blocks[1].(*slack.ActionBlock)
+	if !ok {
+		t.Fatalf("expected an action block, got %T", blocks[1])
+	}
+	yes := actions.Elements.ElementSet[0].(*slack.ButtonBlockElement)
+	if yes.Value != "deploy now" || !strings.HasPrefix(yes.ActionID, slackButtonActionPrefix) {
+		t.Errorf("unexpected reply button: %+v", yes)
+	}
+	link := actions.Elements.ElementSet[1].(*slack.ButtonBlockElement)
+	if link.URL != "https://example.com/runbook" || link.Value != "" {
+		t.Errorf("unexpected link button: %+v", link)
+	}
+}
+
+func TestOneBotMessageSegments(t *testing.T) {
+	dir := t.TempDir()
+	imagePath := filepath.Join(dir, "photo.png")
+	if err := os.WriteFile(imagePath, []byte("png-data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &OneBotChannel{}
+	segments := c.buildMessageSegments(context.Background(), bus.OutboundMessage{
+		ChatID:  "group:1",
+		Content: "here",
+		ReplyTo: "99",
+		Attachments: []bus.Attachment{
+			{Path: imagePath},
+			{URL: "https://example.com/notes.txt"},
+		},
+		Buttons: []bus.Button{{Text: "ok"}},
+	})
+
+	if len(segments) != 3 {
+		t.Fatalf("expected reply, text and image segments, got %+v", segments)
+	}
+	if segments[0].Type != "reply" || segments[0].Data["id"] != "99" {
+		t.Errorf("unexpected reply segment: %+v", segments[0])
+	}
+	text := segments[1].Data["text"].(string)
+	if !strings.Contains(text, "notes.txt") || !strings.Contains(text, "1. ok") {
+		t.Errorf("file and buttons should be listed in the text: %q", text)
+	}
+	want := "base64://" + base64.StdEncoding.EncodeToString([]byte("png-data"))
+	if segments[2].Type != "image" || segments[2].Data["file"] != want {
+		t.Errorf("unexpected image segment: %+v", segments[2])
+	}
+}
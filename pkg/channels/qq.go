@@ -3,6 +3,7 @@ package channels
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,6 +17,7 @@ import (
 	"github.com/Agentx-network/agentx/pkg/bus"
 	"github.com/Agentx-network/agentx/pkg/config"
 	"github.com/Agentx-network/agentx/pkg/logger"
+	"github.com/Agentx-network/agentx/pkg/utils"
 )
 
 type QQChannel struct {
@@ -118,7 +120,7 @@ func (c *QQChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
 
 	// construct message
 	msgToCreate := &dto.MessageToCreate{
-		Content: msg.Content,
+		Content: appendFallbackText(msg.Content, msg.Attachments, msg.Buttons),
 	}
 
 	// send C2C message
@@ -151,7 +153,9 @@ func (c *QQChannel) handleC2CMessage() event.C2CMessageEventHandler {
 		}
 
 		// extract message content
-		content := data.Content
+		voiceText, voiceFiles := c.voiceContent(data.Attachments)
+		defer removeTempFiles("qq", voiceFiles)
+		content := appendContent(data.Content, voiceText)
 		if content == "" {
 			logger.DebugC("qq", "Received empty message, ignoring")
 			return nil
@@ -169,7 +173,7 @@ func (c *QQChannel) handleC2CMessage() event.C2CMessageEventHandler {
 			"peer_id":    senderID,
 		}
 
-		c.HandleMessage(senderID, senderID, content, []string{}, metadata)
+		c.HandleMessage(senderID, senderID, content, voiceFiles, metadata)
 
 		return nil
 	}
@@ -193,7 +197,9 @@ func (c *QQChannel) handleGroupATMessage() event.GroupATMessageEventHandler {
 		}
 
 		// extract message content (remove @bot part)
-		content := data.Content
+		voiceText, voiceFiles := c.voiceContent(data.Attachments)
+		defer removeTempFiles("qq", voiceFiles)
+		content := appendContent(data.Content, voiceText)
 		if content == "" {
 			logger.DebugC("qq", "Received empty group message, ignoring")
 			return nil
@@ -213,12 +219,39 @@ func (c *QQChannel) handleGroupATMessage() event.GroupATMessageEventHandler {
 			"peer_id":    data.GroupID,
 		}
 
-		c.HandleMessage(senderID, data.GroupID, content, []string{}, metadata)
+		c.HandleMessage(senderID, data.GroupID, content, voiceFiles, metadata)
 
 		return nil
 	}
 }
 
+// voiceContent downloads and transcribes the voice attachments of a
+// message. It returns the text to add to the message and the downloaded
+// files, which the caller removes once the message is handed off.
+func (c *QQChannel) voiceContent(attachments []*dto.MessageAttachment) (string, []string) {
+	var parts, files []string
+	for _, a := range attachments {
+		if a == nil || a.ContentType != "voice" || a.URL == "" {
+			continue
+		}
+		url := a.URL
+		if !strings.HasPrefix(url, "http") {
+			url = "https://" + strings.TrimPrefix(url, "//")
+		}
+		name := a.FileName
+		if name == "" {
+			name = "voice.silk"
+		}
+		localPath := utils.DownloadFile(url, name, utils.DownloadOptions{LoggerPrefix: "qq"})
+		if localPath == "" {
+			continue
+		}
+		files = append(files, localPath)
+		parts = append(parts, c.voiceText(c.ctx, localPath))
+	}
+	return strings.Join(parts, "\n"), files
+}
+
 // isDuplicate checks if message is duplicate
 func (c *QQChannel) isDuplicate(messageID string) bool {
 	c.mu.Lock()
@@ -0,0 +1,530 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/config"
+	"github.com/Agentx-network/agentx/pkg/logger"
+	"github.com/Agentx-network/agentx/pkg/utils"
+)
+
+const (
+	rocketChatRetryDelay  = 5 * time.Second
+	rocketChatStreamFlush = time.Second
+	rocketChatMaxLength   = 5000 // Message_MaxAllowedSize's default
+	rocketChatMaxSeen     = 1024
+)
+
+// RocketChatChannel talks to a Rocket.Chat server: it subscribes to the
+// messages of the bot's rooms over the realtime (DDP) WebSocket API and
+// answers through the REST API, streaming replies by editing its own
+// message.
+//
+// Chat IDs are the room ID, or "roomID/threadID" for a thread, and are
+// routed like Mattermost's: a thread in a channel is a peer of its own with
+// the room as parent peer, and team_id is the room's team.
+type RocketChatChannel struct {
+	*BaseChannel
+	config     config.RocketChatConfig
+	serverURL  string
+	httpClient *http.Client
+	userID     string
+	username   string
+	cancel     context.CancelFunc
+	retryDelay time.Duration
+	stream     *streamRenderer
+	threads    *recentIDs // IDs of threads the bot has posted in
+	rooms      sync.Map   // room ID -> rocketChatRoom
+	inbound    chan func()
+
+	// The stream sends a message again when it is edited, reacted to or
+	// gets a thread reply; seen keeps it from being answered twice.
+	seen *recentIDs
+}
+
+// rocketChatFrame is a DDP message from the server.
+type rocketChatFrame struct {
+	Msg        string `json:"msg"`
+	ID         string `json:"id"`
+	Collection string `json:"collection"`
+	Error      *struct {
+		Reason  string `json:"reason"`
+		Message string `json:"message"`
+	} `json:"error"`
+	Fields struct {
+		Args []json.RawMessage `json:"args"`
+	} `json:"fields"`
+}
+
+type rocketChatMessage struct {
+	ID       string `json:"_id"`
+	RoomID   string `json:"rid"`
+	Msg      string `json:"msg"`
+	Type     string `json:"t"`    // system messages only
+	ThreadID string `json:"tmid"` // root of the thread the message is in
+	User     struct {
+		ID       string `json:"_id"`
+		Username string `json:"username"`
+	} `json:"u"`
+	Mentions []struct {
+		ID       string `json:"_id"`
+		Username string `json:"username"`
+	} `json:"mentions"`
+	File     *rocketChatFile  `json:"file"`
+	Files    []rocketChatFile `json:"files"`
+	Bot      any              `json:"bot"` // set on messages from bots and integrations
+	EditedAt any              `json:"editedAt"`
+}
+
+type rocketChatFile struct {
+	ID   string `json:"_id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type rocketChatRoom struct {
+	Type   string `json:"t"` // "d" for DMs, "c" and "p" for channels
+	TeamID string `json:"teamId"`
+}
+
+func NewRocketChatChannel(cfg config.RocketChatConfig, messageBus *bus.MessageBus) (*RocketChatChannel, error) {
+	if cfg.URL == "" || cfg.UserID == "" || cfg.Token == "" {
+		return nil, fmt.Errorf("rocketchat url, user_id and token are required")
+	}
+	serverURL := strings.TrimRight(cfg.URL, "/")
+	if !strings.Contains(serverURL, "://") {
+		serverURL = "https://" + serverURL
+	}
+
+	c := &RocketChatChannel{
+		BaseChannel: NewBaseChannel("rocketchat", cfg, messageBus, cfg.AllowFrom),
+		config:      cfg,
+		serverURL:   serverURL,
+		httpClient:  &http.Client{Timeout: 60 * time.Second},
+		userID:      cfg.UserID,
+		retryDelay:  rocketChatRetryDelay,
+		threads:     newRecentIDs(maxJoinedThreads),
+		inbound:     make(chan func(), wsInboundQueueSize),
+		seen:        newRecentIDs(rocketChatMaxSeen),
+	}
+	c.stream = &streamRenderer{
+		name:     "rocketchat",
+		interval: rocketChatStreamFlush,
+		maxLen:   rocketChatMaxLength,
+		post: func(ctx context.Context, chatID, text string) (string, error) {
+			roomID, threadID, _ := strings.Cut(chatID, "/")
+			return c.sendMessage(ctx, roomID, threadID, text)
+		},
+		edit: func(ctx context.Context, chatID, messageID, text string) error {
+			roomID, _, _ := strings.Cut(chatID, "/")
+			return c.updateMessage(ctx, roomID, messageID, text)
+		},
+		remove: func(ctx context.Context, chatID, messageID string) error {
+			roomID, _, _ := strings.Cut(chatID, "/")
+			return c.api(ctx, http.MethodPost, "/api/v1/chat.delete", map[string]string{
+				"roomId": roomID,
+				"msgId":  messageID,
+			}, nil)
+		},
+	}
+	return c, nil
+}
+
+func (c *RocketChatChannel) Start(ctx context.Context) error {
+	logger.InfoCF("rocketchat", "Starting Rocket.Chat channel", map[string]any{
+		"url": c.serverURL,
+	})
+
+	var me struct {
+		Username string `json:"username"`
+	}
+	if err := c.api(ctx, http.MethodGet, "/api/v1/me", nil, &me); err != nil {
+		return fmt.Errorf("rocketchat login: %w", err)
+	}
+	c.username = me.Username
+
+	ctx, c.cancel = context.WithCancel(ctx)
+	c.setRunning(true)
+	go c.run(ctx)
+	go processInbound(ctx, c.inbound)
+
+	logger.InfoCF("rocketchat", "Rocket.Chat channel started", map[string]any{
+		"username": c.username,
+	})
+	return nil
+}
+
+func (c *RocketChatChannel) Stop(ctx context.Context) error {
+	logger.InfoC("rocketchat", "Stopping Rocket.Chat channel")
+	c.setRunning(false)
+	if c.cancel != nil {
+		c.cancel()
+	}
+	return nil
+}
+
+// run keeps a realtime connection open, reconnecting after a delay when
+// it drops.
+func (c *RocketChatChannel) run(ctx context.Context) {
+	for ctx.Err() == nil {
+		err := c.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		logger.WarnCF("rocketchat", "Realtime connection lost, reconnecting", map[string]any{
+			"error": err.Error(),
+		})
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(c.retryDelay):
+		}
+	}
+}
+
+// listen connects, logs in with the token and subscribes to the messages
+// of every room the bot is in.
+func (c *RocketChatChannel) listen(ctx context.Context) error {
+	conn, err := dialWebSocket(ctx, "ws"+strings.TrimPrefix(c.serverURL, "http")+"/websocket", nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for _, frame := range []map[string]any{
+		{"msg": "connect", "version": "1", "support": []string{"1"}},
+		{"msg": "method", "method": "login", "id": "login", "params": []any{map[string]string{"resume": c.config.Token}}},
+		{"msg": "sub", "id": "messages", "name": "stream-room-messages", "params": []any{"__my_messages__", false}},
+	} {
+		if err := conn.WriteJSON(frame); err != nil {
+			return err
+		}
+	}
+
+	for {
+		var frame rocketChatFrame
+		if err := conn.ReadJSON(&frame); err != nil {
+			return err
+		}
+		conn.SetReadDeadline(time.Now().Add(2 * wsPingInterval))
+		switch frame.Msg {
+		case "ping":
+			if err := conn.WriteJSON(map[string]string{"msg": "pong"}); err != nil {
+				return err
+			}
+		case "result":
+			if frame.ID == "login" && frame.Error != nil {
+				return fmt.Errorf("login failed: %s", firstNonEmpty(frame.Error.Reason, frame.Error.Message))
+			}
+		case "nosub":
+			return fmt.Errorf("subscription refused")
+		case "ready":
+			logger.InfoC("rocketchat", "Realtime API connected")
+		case "changed":
+			if frame.Collection != "stream-room-messages" || len(frame.Fields.Args) == 0 {
+				continue
+			}
+			var msg rocketChatMessage
+			if json.Unmarshal(frame.Fields.Args[0], &msg) != nil {
+				continue
+			}
+			// The second argument describes the room, without its team.
+			var hint struct {
+				RoomType string `json:"roomType"`
+			}
+			if len(frame.Fields.Args) > 1 {
+				json.Unmarshal(frame.Fields.Args[1], &hint)
+			}
+			enqueueInbound("rocketchat", c.inbound, func() { c.handleMessage(ctx, msg, hint.RoomType) })
+		}
+	}
+}
+
+func (c *RocketChatChannel) handleMessage(ctx context.Context, msg rocketChatMessage, roomType string) {
+	// Other bots are not answered, to avoid loops.
+	if msg.User.ID == c.userID || msg.Type != "" || msg.Bot != nil || msg.EditedAt != nil {
+		return
+	}
+	if !c.seen.add(msg.ID) {
+		return
+	}
+
+	senderID := msg.User.ID
+	if msg.User.Username != "" {
+		senderID += "|" + msg.User.Username
+	}
+	if !c.IsAllowed(senderID) {
+		logger.DebugCF("rocketchat", "Message rejected by allowlist", map[string]any{
+			"sender": senderID,
+		})
+		return
+	}
+
+	room := c.room(ctx, msg.RoomID, roomType)
+	direct := room.Type == "d"
+	content := msg.Msg
+	if !direct {
+		joined := c.threads.has(msg.ThreadID)
+		if c.config.MentionOnly && !joined && !c.mentioned(msg) {
+			return
+		}
+		content = stripAtMention(content, c.username)
+	}
+
+	files := msg.Files
+	if len(files) == 0 && msg.File != nil {
+		files = append(files, *msg.File)
+	}
+	var mediaPaths []string
+	for _, f := range files {
+		name := firstNonEmpty(f.Name, "file")
+		path := utils.DownloadFile(c.serverURL+"/file-upload/"+f.ID+"/"+url.PathEscape(name), name, utils.DownloadOptions{
+			LoggerPrefix: "rocketchat",
+			ExtraHeaders: map[string]string{"X-User-Id": c.userID, "X-Auth-Token": c.config.Token},
+		})
+		if path == "" {
+			content = appendContent(content, fmt.Sprintf("[file: %s]", name))
+			continue
+		}
+		mediaPaths = append(mediaPaths, path)
+		switch {
+		case utils.IsAudioFile(name, f.Type):
+			content = appendContent(content, c.voiceText(ctx, path))
+		case strings.HasPrefix(f.Type, "image/"):
+			content = appendContent(content, fmt.Sprintf("[image: %s]", name))
+		default:
+			content = appendContent(content, fmt.Sprintf("[file: %s]", name))
+		}
+	}
+	defer removeTempFiles("rocketchat", mediaPaths)
+
+	if strings.TrimSpace(content) == "" {
+		return
+	}
+
+	threadID := msg.ThreadID
+	if threadID == "" && !direct && c.config.ReplyInThread {
+		threadID = msg.ID
+	}
+	chatID := msg.RoomID
+	if threadID != "" {
+		chatID += "/" + threadID
+	}
+
+	metadata := map[string]string{
+		"message_id": msg.ID,
+		"room_id":    msg.RoomID,
+		"thread_id":  msg.ThreadID,
+		"team_id":    room.TeamID,
+	}
+	setThreadPeer(metadata, direct, msg.User.ID, msg.RoomID, threadID)
+
+	logger.DebugCF("rocketchat", "Received message", map[string]any{
+		"sender":  senderID,
+		"chat_id": chatID,
+		"preview": utils.Truncate(content, 50),
+	})
+
+	c.HandleMessage(senderID, chatID, content, mediaPaths, metadata)
+}
+
+// room returns a room's type and team, looked up once per room. When the
+// lookup fails the room is taken to be of roomType, with no team.
+func (c *RocketChatChannel) room(ctx context.Context, roomID, roomType string) rocketChatRoom {
+	if room, ok := c.rooms.Load(roomID); ok {
+		return room.(rocketChatRoom)
+	}
+	var info struct {
+		Room rocketChatRoom `json:"room"`
+	}
+	if err := c.api(ctx, http.MethodGet, "/api/v1/rooms.info?roomId="+url.QueryEscape(roomID), nil, &info); err != nil {
+		logger.WarnCF("rocketchat", "Failed to look up room", map[string]any{
+			"room_id": roomID,
+			"error":   err.Error(),
+		})
+		return rocketChatRoom{Type: roomType}
+	}
+	c.rooms.Store(roomID, info.Room)
+	return info.Room
+}
+
+func (c *RocketChatChannel) mentioned(msg rocketChatMessage) bool {
+	for _, m := range msg.Mentions {
+		if m.ID == c.userID || strings.EqualFold(m.Username, c.username) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *RocketChatChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("rocketchat channel not running")
+	}
+	roomID, threadID, _ := strings.Cut(msg.ChatID, "/")
+	if threadID == "" {
+		threadID = msg.ReplyTo
+	}
+
+	text := appendFallbackText(withReasoningText(msg).Content, nil, msg.Buttons)
+	// The reply takes the place of the streamed messages; one without
+	// text, such as a voice note, removes them.
+	var chunks []string
+	if strings.TrimSpace(msg.Content) != "" {
+		chunks = c.stream.finish(ctx, msg.ChatID, utils.SplitMessage(text, rocketChatMaxLength))
+	} else {
+		c.stream.finish(ctx, msg.ChatID, nil)
+		if strings.TrimSpace(text) != "" {
+			chunks = utils.SplitMessage(text, rocketChatMaxLength)
+		}
+	}
+	for _, chunk := range chunks {
+		if _, err := c.sendMessage(ctx, roomID, threadID, chunk); err != nil {
+			return err
+		}
+	}
+
+	for _, a := range msg.Attachments {
+		if err := c.uploadFile(ctx, roomID, threadID, a); err != nil {
+			logger.WarnCF("rocketchat", "Failed to upload attachment, sending it as text", map[string]any{
+				"file":  a.Name(),
+				"error": err.Error(),
+			})
+			if _, err := c.sendMessage(ctx, roomID, threadID, attachmentText([]bus.Attachment{a})); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *RocketChatChannel) sendMessage(ctx context.Context, roomID, threadID, text string) (string, error) {
+	message := map[string]string{"rid": roomID, "msg": text}
+	if threadID != "" {
+		message["tmid"] = threadID
+	}
+	var sent struct {
+		Message struct {
+			ID string `json:"_id"`
+		} `json:"message"`
+	}
+	if err := c.api(ctx, http.MethodPost, "/api/v1/chat.sendMessage", map[string]any{"message": message}, &sent); err != nil {
+		return "", err
+	}
+	if threadID != "" {
+		c.threads.add(threadID)
+	}
+	return sent.Message.ID, nil
+}
+
+func (c *RocketChatChannel) updateMessage(ctx context.Context, roomID, messageID, text string) error {
+	return c.api(ctx, http.MethodPost, "/api/v1/chat.update", map[string]string{
+		"roomId": roomID,
+		"msgId":  messageID,
+		"text":   text,
+	}, nil)
+}
+
+// uploadFile posts an attachment to a room, in the thread if there is one.
+func (c *RocketChatChannel) uploadFile(ctx context.Context, roomID, threadID string, a bus.Attachment) error {
+	data, err := readAttachment(ctx, a)
+	if err != nil {
+		return err
+	}
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	if threadID != "" {
+		w.WriteField("tmid", threadID)
+	}
+	part, err := w.CreateFormFile("file", a.Name())
+	if err != nil {
+		return err
+	}
+	part.Write(data)
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.request(ctx, http.MethodPost, "/api/v1/rooms.upload/"+url.PathEscape(roomID), w.FormDataContentType(), body.Bytes(), nil)
+}
+
+// StartStreamConsumer starts a goroutine that shows stream deltas by
+// sending messages to the chat and then editing them.
+func (c *RocketChatChannel) StartStreamConsumer(ctx context.Context) {
+	go c.stream.run(ctx)
+}
+
+// HandleStreamDelta processes a single stream delta event.
+func (c *RocketChatChannel) HandleStreamDelta(delta bus.StreamDelta) {
+	c.stream.add(delta)
+}
+
+// ShowStatus shows what the agent is doing in a message of its own.
+func (c *RocketChatChannel) ShowStatus(ctx context.Context, chatID, status string) error {
+	return c.stream.showStatus(ctx, chatID, status)
+}
+
+// ClearStatus removes the status message.
+func (c *RocketChatChannel) ClearStatus(ctx context.Context, chatID string) error {
+	return c.stream.clearStatus(ctx, chatID)
+}
+
+// api sends a JSON request to the REST API and decodes the JSON reply into out.
+func (c *RocketChatChannel) api(ctx context.Context, method, path string, body, out any) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	return c.request(ctx, method, path, "application/json", data, out)
+}
+
+func (c *RocketChatChannel) request(ctx context.Context, method, path, contentType string, data []byte, out any) error {
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.serverURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-User-Id", c.userID)
+	req.Header.Set("X-Auth-Token", c.config.Token)
+	if data != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error   string `json:"error"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(respBody, &apiErr)
+		return fmt.Errorf("rocketchat %s %s: %s", method, path, firstNonEmpty(apiErr.Error, apiErr.Message, resp.Status))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
@@ -0,0 +1,322 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/config"
+)
+
+// testRocketChatServer fakes the realtime and REST APIs of Rocket.Chat and
+// records the messages the channel sends, edits and uploads.
+type testRocketChatServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	conn     *websocket.Conn
+	frames   []map[string]any
+	sent     []map[string]string
+	updates  map[string]string
+	uploads  []string
+	roomInfo int
+}
+
+func newTestRocketChatServer(t *testing.T) *testRocketChatServer {
+	t.Helper()
+	s := &testRocketChatServer{updates: map[string]string{}}
+	auth := func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Header.Get("X-User-Id") != "bot1" || r.Header.Get("X-Auth-Token") != "tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"status":"error","message":"You must be logged in to do this."}`))
+			return false
+		}
+		return true
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/me", func(w http.ResponseWriter, r *http.Request) {
+		if auth(w, r) {
+			w.Write([]byte(`{"_id":"bot1","username":"agentx","success":true}`))
+		}
+	})
+	mux.HandleFunc("/api/v1/rooms.info", func(w http.ResponseWriter, r *http.Request) {
+		if !auth(w, r) {
+			return
+		}
+		s.mu.Lock()
+		s.roomInfo++
+		s.mu.Unlock()
+		switch r.URL.Query().Get("roomId") {
+		case "general":
+			w.Write([]byte(`{"room":{"_id":"general","t":"c","teamId":"eng"},"success":true}`))
+		default:
+			w.Write([]byte(`{"room":{"_id":"dm","t":"d"},"success":true}`))
+		}
+	})
+	mux.HandleFunc("/websocket", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		for i := 0; i < 3; i++ {
+			var frame map[string]any
+			if conn.ReadJSON(&frame) != nil {
+				return
+			}
+			s.mu.Lock()
+			s.frames = append(s.frames, frame)
+			s.mu.Unlock()
+		}
+		conn.WriteJSON(map[string]any{"msg": "connected"})
+		conn.WriteJSON(map[string]any{"msg": "result", "id": "login", "result": map[string]any{"id": "bot1"}})
+		conn.WriteJSON(map[string]any{"msg": "ready", "subs": []string{"messages"}})
+		s.mu.Lock()
+		s.conn = conn
+		s.mu.Unlock()
+	})
+	mux.HandleFunc("POST /api/v1/chat.sendMessage", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Message map[string]string `json:"message"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		s.mu.Lock()
+		s.sent = append(s.sent, body.Message)
+		id := "r" + string(rune('0'+len(s.sent)))
+		s.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"message": map[string]string{"_id": id}, "success": true})
+	})
+	mux.HandleFunc("POST /api/v1/chat.update", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		s.mu.Lock()
+		s.updates[body["msgId"]] = body["text"]
+		s.mu.Unlock()
+		w.Write([]byte(`{"success":true}`))
+	})
+	mux.HandleFunc("POST /api/v1/rooms.upload/{rid}", func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"success":false,"error":"No file uploaded"}`))
+			return
+		}
+		data, _ := io.ReadAll(file)
+		s.mu.Lock()
+		s.uploads = append(s.uploads, r.PathValue("rid")+"/"+r.FormValue("tmid")+":"+header.Filename+":"+string(data))
+		s.mu.Unlock()
+		w.Write([]byte(`{"success":true}`))
+	})
+	mux.HandleFunc("/file-upload/{id}/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if auth(w, r) {
+			w.Write([]byte("audio of " + r.PathValue("id")))
+		}
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// push sends a message on the stream-room-messages subscription.
+func (s *testRocketChatServer) push(t *testing.T, roomType string, msg map[string]any) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		s.mu.Lock()
+		conn := s.conn
+		s.mu.Unlock()
+		if conn != nil {
+			err := conn.WriteJSON(map[string]any{
+				"msg":        "changed",
+				"collection": "stream-room-messages",
+				"id":         "id",
+				"fields": map[string]any{
+					"eventName": "__my_messages__",
+					"args":      []any{msg, map[string]any{"roomType": roomType}},
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("channel never subscribed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func startTestRocketChatChannel(t *testing.T, srv *testRocketChatServer, cfg config.RocketChatConfig) (*RocketChatChannel, *bus.MessageBus) {
+	t.Helper()
+	cfg.URL = srv.URL
+	cfg.UserID = "bot1"
+	cfg.Token = "tok"
+	msgBus := bus.NewMessageBus()
+	ch, err := NewRocketChatChannel(cfg, msgBus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		ch.Stop(ctx)
+		cancel()
+	})
+	if err := ch.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return ch, msgBus
+}
+
+func rocketChatUser(id, username string) map[string]string {
+	return map[string]string{"_id": id, "username": username}
+}
+
+func TestRocketChatChannel_MentionsThreadsAndTeams(t *testing.T) {
+	srv := newTestRocketChatServer(t)
+	ch, msgBus := startTestRocketChatChannel(t, srv, config.RocketChatConfig{
+		MentionOnly:   true,
+		ReplyInThread: true,
+		AllowFrom:     []string{"alice"},
+	})
+
+	mention := map[string]any{
+		"_id": "m2", "rid": "general", "msg": "@agentx is prod up?", "u": rocketChatUser("u1", "alice"),
+		"mentions": []any{rocketChatUser("bot1", "agentx")},
+	}
+	srv.push(t, "c", map[string]any{"_id": "m1", "rid": "general", "msg": "morning", "u": rocketChatUser("u1", "alice")})
+	srv.push(t, "c", map[string]any{"_id": "x1", "rid": "general", "t": "uj", "msg": "alice", "u": rocketChatUser("u1", "alice")})
+	srv.push(t, "c", mention)
+	msg := waitInbound(t, msgBus)
+	if msg.ChatID != "general/m2" || msg.SenderID != "u1|alice" || msg.Content != "is prod up?" {
+		t.Errorf("msg = %+v", msg)
+	}
+	want := map[string]string{
+		"team_id":          "eng",
+		"peer_kind":        "thread",
+		"peer_id":          "general/m2",
+		"parent_peer_kind": "channel",
+		"parent_peer_id":   "general",
+	}
+	for k, v := range want {
+		if msg.Metadata[k] != v {
+			t.Errorf("metadata[%s] = %q, want %q", k, msg.Metadata[k], v)
+		}
+	}
+
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: msg.ChatID, Content: "Yes."}); err != nil {
+		t.Fatal(err)
+	}
+	srv.mu.Lock()
+	if len(srv.sent) != 1 || srv.sent[0]["rid"] != "general" || srv.sent[0]["tmid"] != "m2" || srv.sent[0]["msg"] != "Yes." {
+		t.Errorf("sent = %v", srv.sent)
+	}
+	srv.mu.Unlock()
+
+	// The thread root comes back when the thread grows; it is not answered
+	// again. Follow-ups in the thread need no mention.
+	mention["tcount"] = 1
+	srv.push(t, "c", mention)
+	srv.push(t, "c", map[string]any{"_id": "m3", "rid": "general", "tmid": "m2", "msg": "and staging?", "u": rocketChatUser("u1", "alice")})
+	msg = waitInbound(t, msgBus)
+	if msg.ChatID != "general/m2" || msg.Content != "and staging?" {
+		t.Errorf("msg = %+v", msg)
+	}
+
+	// Direct messages need no mention; voice notes are downloaded.
+	srv.push(t, "d", map[string]any{
+		"_id": "m4", "rid": "dm", "msg": "", "u": rocketChatUser("u1", "alice"),
+		"files": []any{map[string]string{"_id": "f1", "name": "note.ogg", "type": "audio/ogg"}},
+	})
+	msg = waitInbound(t, msgBus)
+	if msg.ChatID != "dm" || msg.Metadata["peer_kind"] != "direct" || msg.Metadata["peer_id"] != "u1" || msg.Content != "[voice]" {
+		t.Errorf("msg = %+v", msg)
+	}
+	if len(msg.Media) != 1 {
+		t.Fatalf("media = %v", msg.Media)
+	}
+	if data, _ := os.ReadFile(msg.Media[0]); string(data) != "audio of f1" {
+		t.Errorf("media = %q", data)
+	}
+	os.Remove(msg.Media[0])
+
+	if extra, ok := consumeInbound(t, msgBus); ok {
+		t.Errorf("unexpected message %+v", extra)
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.roomInfo != 2 {
+		t.Errorf("rooms looked up %d times", srv.roomInfo)
+	}
+	if login := srv.frames[1]; login["method"] != "login" {
+		t.Errorf("login frame = %v", login)
+	}
+}
+
+func TestRocketChatChannel_StreamingAndUploads(t *testing.T) {
+	srv := newTestRocketChatServer(t)
+	ch, _ := startTestRocketChatChannel(t, srv, config.RocketChatConfig{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch.StartStreamConsumer(ctx)
+
+	ch.HandleStreamDelta(bus.StreamDelta{ChatID: "general/m1", Delta: "Deploying"})
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		srv.mu.Lock()
+		n := len(srv.sent)
+		srv.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no preview sent")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	ch.HandleStreamDelta(bus.StreamDelta{ChatID: "general/m1", Done: true})
+
+	chart := writeTempFile(t, "chart.png", "png")
+	err := ch.Send(context.Background(), bus.OutboundMessage{
+		ChatID:      "general/m1",
+		Content:     "Deployed.",
+		Attachments: []bus.Attachment{{Path: chart}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.sent) != 1 || srv.sent[0]["msg"] != "Deploying" || srv.sent[0]["tmid"] != "m1" {
+		t.Errorf("sent = %v", srv.sent)
+	}
+	if srv.updates["r1"] != "Deployed." {
+		t.Errorf("updates = %v", srv.updates)
+	}
+	if len(srv.uploads) != 1 || srv.uploads[0] != "general/m1:chart.png:png" {
+		t.Errorf("uploads = %v", srv.uploads)
+	}
+}
+
+func TestRocketChatChannel_Refused(t *testing.T) {
+	srv := newTestRocketChatServer(t)
+	ch, err := NewRocketChatChannel(config.RocketChatConfig{URL: srv.URL, UserID: "bot1", Token: "wrong"}, bus.NewMessageBus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Start(context.Background()); err == nil {
+		t.Error("expected a login error")
+	}
+	if _, err := NewRocketChatChannel(config.RocketChatConfig{URL: srv.URL, Token: "tok"}, bus.NewMessageBus()); err == nil {
+		t.Error("a token without user_id should be refused")
+	}
+}
@@ -0,0 +1,545 @@
+package channels
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/config"
+	"github.com/Agentx-network/agentx/pkg/logger"
+	"github.com/Agentx-network/agentx/pkg/utils"
+)
+
+const (
+	signalCallTimeout     = 60 * time.Second
+	signalReconnectDelay  = 5 * time.Second
+	signalRecentMessages  = 256
+	signalGroupChatPrefix = "group:"
+)
+
+// SignalChannel talks to a signal-cli daemon over JSON-RPC (one JSON
+// object per line) on a unix socket or TCP port. DMs use the sender's
+// number or UUID as chat ID; groups use "group:" and the group ID.
+type SignalChannel struct {
+	*BaseChannel
+	config config.SignalConfig
+	cancel context.CancelFunc
+
+	mu      sync.Mutex // guards conn
+	conn    net.Conn
+	writeMu sync.Mutex
+	nextID  atomic.Int64
+	pending sync.Map // request ID -> chan signalRPCResponse
+	inbound chan json.RawMessage
+
+	typing sync.Map // chat IDs shown typing, to clear on Send
+	acks   sync.Map // chat ID -> signalMessageRef reacted to
+
+	recentMu   sync.Mutex
+	recent     map[int64]string // message timestamp -> author, for quoting
+	recentRing []int64
+	recentIdx  int
+}
+
+// signalMessageRef identifies a Signal message: its author and the
+// timestamp the author sent it at.
+type signalMessageRef struct {
+	author    string
+	timestamp int64
+}
+
+type signalRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type signalRPCResponse struct {
+	ID     *int64          `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *signalRPCError `json:"error"`
+}
+
+type signalEnvelope struct {
+	Source       string             `json:"source"`
+	SourceNumber string             `json:"sourceNumber"`
+	SourceUUID   string             `json:"sourceUuid"`
+	SourceName   string             `json:"sourceName"`
+	Timestamp    int64              `json:"timestamp"`
+	DataMessage  *signalDataMessage `json:"dataMessage"`
+}
+
+type signalDataMessage struct {
+	Timestamp int64  `json:"timestamp"`
+	Message   string `json:"message"`
+	GroupInfo *struct {
+		GroupID string `json:"groupId"`
+	} `json:"groupInfo"`
+	Attachments []struct {
+		ID          string `json:"id"`
+		ContentType string `json:"contentType"`
+		Filename    string `json:"filename"`
+	} `json:"attachments"`
+	Reaction *struct {
+		Emoji    string `json:"emoji"`
+		IsRemove bool   `json:"isRemove"`
+	} `json:"reaction"`
+}
+
+func NewSignalChannel(cfg config.SignalConfig, messageBus *bus.MessageBus) (*SignalChannel, error) {
+	if cfg.Socket == "" {
+		return nil, fmt.Errorf("signal socket is required")
+	}
+	return &SignalChannel{
+		BaseChannel: NewBaseChannel("signal", cfg, messageBus, cfg.AllowFrom),
+		config:      cfg,
+		inbound:     make(chan json.RawMessage, 100),
+		recent:      make(map[int64]string, signalRecentMessages),
+		recentRing:  make([]int64, signalRecentMessages),
+	}, nil
+}
+
+func (c *SignalChannel) Start(ctx context.Context) error {
+	logger.InfoCF("signal", "Starting Signal channel", map[string]any{
+		"socket":  c.config.Socket,
+		"account": c.config.Account,
+	})
+
+	ctx, c.cancel = context.WithCancel(ctx)
+	if err := c.connect(ctx); err != nil {
+		logger.WarnCF("signal", "signal-cli daemon not reachable, will keep retrying", map[string]any{
+			"error": err.Error(),
+		})
+	}
+	c.setRunning(true)
+	go c.reconnectLoop(ctx)
+	go c.processInbound(ctx)
+	return nil
+}
+
+func (c *SignalChannel) Stop(ctx context.Context) error {
+	logger.InfoC("signal", "Stopping Signal channel")
+	c.setRunning(false)
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.mu.Lock()
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *SignalChannel) dial(ctx context.Context) (net.Conn, error) {
+	socket := strings.TrimPrefix(c.config.Socket, "unix://")
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if strings.HasPrefix(socket, "/") || strings.HasPrefix(socket, ".") {
+		return dialer.DialContext(ctx, "unix", socket)
+	}
+	return dialer.DialContext(ctx, "tcp", strings.TrimPrefix(socket, "tcp://"))
+}
+
+func (c *SignalChannel) connect(ctx context.Context) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.conn = conn
+	c.mu.Unlock()
+	go c.readLoop(conn)
+	logger.InfoC("signal", "Connected to signal-cli daemon")
+	return nil
+}
+
+// reconnectLoop redials the daemon whenever the connection drops.
+func (c *SignalChannel) reconnectLoop(ctx context.Context) {
+	ticker := time.NewTicker(signalReconnectDelay)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.mu.Lock()
+			connected := c.conn != nil
+			c.mu.Unlock()
+			if connected {
+				continue
+			}
+			if err := c.connect(ctx); err != nil {
+				logger.DebugCF("signal", "Reconnect failed", map[string]any{
+					"error": err.Error(),
+				})
+			}
+		}
+	}
+}
+
+// readLoop routes responses to their callers and queues received
+// messages. Messages are handled on another goroutine, as handling them
+// makes calls whose responses this loop must read.
+func (c *SignalChannel) readLoop(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			break
+		}
+		var resp signalRPCResponse
+		if err := json.Unmarshal(line, &resp); err != nil {
+			logger.DebugCF("signal", "Ignoring malformed line", map[string]any{"error": err.Error()})
+			continue
+		}
+		if resp.Method == "receive" {
+			select {
+			case c.inbound <- resp.Params:
+			default:
+				logger.WarnC("signal", "Inbound queue full, dropping message")
+			}
+			continue
+		}
+		if resp.ID != nil {
+			if ch, ok := c.pending.LoadAndDelete(*resp.ID); ok {
+				ch.(chan signalRPCResponse) <- resp
+			}
+		}
+	}
+
+	c.mu.Lock()
+	if c.conn == conn {
+		c.conn = nil
+	}
+	c.mu.Unlock()
+	conn.Close()
+	c.pending.Range(func(key, value any) bool {
+		c.pending.Delete(key)
+		value.(chan signalRPCResponse) <- signalRPCResponse{Error: &signalRPCError{Message: "connection closed"}}
+		return true
+	})
+	logger.WarnC("signal", "Disconnected from signal-cli daemon")
+}
+
+func (c *SignalChannel) processInbound(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case params := <-c.inbound:
+			var p struct {
+				Envelope signalEnvelope `json:"envelope"`
+			}
+			if err := json.Unmarshal(params, &p); err != nil {
+				continue
+			}
+			c.handleEnvelope(ctx, p.Envelope)
+		}
+	}
+}
+
+// call makes a JSON-RPC request and decodes its result into out.
+func (c *SignalChannel) call(ctx context.Context, method string, params map[string]any, out any) error {
+	c.mu.Lock()
+	conn := c.conn
+	c.mu.Unlock()
+	if conn == nil {
+		return fmt.Errorf("signal-cli daemon not connected")
+	}
+
+	if c.config.Account != "" {
+		params["account"] = c.config.Account
+	}
+	id := c.nextID.Add(1)
+	data, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+		"id":      id,
+	})
+	if err != nil {
+		return err
+	}
+
+	ch := make(chan signalRPCResponse, 1)
+	c.pending.Store(id, ch)
+	defer c.pending.Delete(id)
+
+	c.writeMu.Lock()
+	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err = conn.Write(append(data, '\n'))
+	c.writeMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("signal-cli %s: %w", method, err)
+	}
+
+	timer := time.NewTimer(signalCallTimeout)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return fmt.Errorf("signal-cli %s: timed out", method)
+	case resp := <-ch:
+		if resp.Error != nil {
+			return fmt.Errorf("signal-cli %s: %s", method, resp.Error.Message)
+		}
+		if out != nil && len(resp.Result) > 0 {
+			return json.Unmarshal(resp.Result, out)
+		}
+		return nil
+	}
+}
+
+func (c *SignalChannel) handleEnvelope(ctx context.Context, env signalEnvelope) {
+	dm := env.DataMessage
+	if dm == nil {
+		return
+	}
+	if dm.Reaction != nil {
+		// Reactions are feedback on a reply, not something to answer.
+		logger.DebugCF("signal", "Received reaction", map[string]any{
+			"sender":  env.Source,
+			"emoji":   dm.Reaction.Emoji,
+			"removed": dm.Reaction.IsRemove,
+		})
+		return
+	}
+	if dm.Message == "" && len(dm.Attachments) == 0 {
+		return
+	}
+
+	author := firstNonEmpty(env.SourceNumber, env.SourceUUID, env.Source)
+	if author == "" {
+		return
+	}
+	// Number and UUID are both allow-list keys; the number is hidden when
+	// the sender shares it with nobody.
+	senderID := author
+	if env.SourceNumber != "" && env.SourceUUID != "" {
+		senderID = env.SourceNumber + "|" + env.SourceUUID
+	}
+	if !c.IsAllowed(senderID) {
+		logger.DebugCF("signal", "Message rejected by allowlist", map[string]any{
+			"sender": senderID,
+		})
+		return
+	}
+
+	timestamp := firstNonZero(dm.Timestamp, env.Timestamp)
+	chatID := author
+	metadata := map[string]string{
+		"message_id":  strconv.FormatInt(timestamp, 10),
+		"sender_name": env.SourceName,
+		"peer_kind":   "direct",
+		"peer_id":     author,
+	}
+	if dm.GroupInfo != nil && dm.GroupInfo.GroupID != "" {
+		chatID = signalGroupChatPrefix + dm.GroupInfo.GroupID
+		metadata["peer_kind"] = "group"
+		metadata["peer_id"] = dm.GroupInfo.GroupID
+	}
+
+	content := dm.Message
+	var mediaPaths []string
+	for _, a := range dm.Attachments {
+		name := firstNonEmpty(a.Filename, a.ID)
+		path, err := c.downloadAttachment(ctx, chatID, a.ID, name)
+		if err != nil {
+			logger.WarnCF("signal", "Failed to download attachment", map[string]any{
+				"id":    a.ID,
+				"error": err.Error(),
+			})
+			content = appendContent(content, fmt.Sprintf("[file: %s]", name))
+			continue
+		}
+		mediaPaths = append(mediaPaths, path)
+		switch {
+		case utils.IsAudioFile(name, a.ContentType):
+			content = appendContent(content, c.voiceText(ctx, path))
+		case strings.HasPrefix(a.ContentType, "image/"):
+			content = appendContent(content, fmt.Sprintf("[image: %s]", name))
+		default:
+			content = appendContent(content, fmt.Sprintf("[file: %s]", name))
+		}
+	}
+	defer removeTempFiles("signal", mediaPaths)
+
+	ref := signalMessageRef{author: author, timestamp: timestamp}
+	c.remember(ref)
+	if c.config.AckReaction != "" {
+		if err := c.react(ctx, chatID, ref, c.config.AckReaction, false); err == nil {
+			c.acks.Store(chatID, ref)
+		}
+	}
+
+	logger.DebugCF("signal", "Received message", map[string]any{
+		"sender":  senderID,
+		"chat_id": chatID,
+		"preview": utils.Truncate(content, 50),
+	})
+
+	c.HandleMessage(senderID, chatID, content, mediaPaths, metadata)
+}
+
+// downloadAttachment fetches an attachment from the daemon, which may not
+// share this filesystem, and saves it in the media directory.
+func (c *SignalChannel) downloadAttachment(ctx context.Context, chatID, id, name string) (string, error) {
+	params := c.target(chatID)
+	params["id"] = id
+	var result json.RawMessage
+	if err := c.call(ctx, "getAttachment", params, &result); err != nil {
+		return "", err
+	}
+	// Depending on the version, signal-cli returns the data or {"data": ...}.
+	var encoded string
+	if json.Unmarshal(result, &encoded) != nil {
+		var obj struct {
+			Data string `json:"data"`
+		}
+		if err := json.Unmarshal(result, &obj); err != nil {
+			return "", err
+		}
+		encoded = obj.Data
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+
+	dir := utils.MediaDir()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, uuid.New().String()[:8]+"_"+utils.SanitizeFilename(name))
+	return path, os.WriteFile(path, data, 0o600)
+}
+
+func (c *SignalChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("signal channel not running")
+	}
+	c.stopTyping(ctx, msg.ChatID)
+
+	// Files go to the daemon inline as data URIs; unreadable ones are listed.
+	var attachments []string
+	var unsent []bus.Attachment
+	for _, a := range msg.Attachments {
+		data, err := readAttachment(ctx, a)
+		if err != nil {
+			unsent = append(unsent, a)
+			continue
+		}
+		contentType := a.ContentType()
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		attachments = append(attachments, fmt.Sprintf("data:%s;filename=%s;base64,%s",
+			contentType, a.Name(), base64.StdEncoding.EncodeToString(data)))
+	}
+
+	params := c.target(msg.ChatID)
+	params["message"] = appendFallbackText(msg.Content, unsent, msg.Buttons)
+	if len(attachments) > 0 {
+		params["attachments"] = attachments
+	}
+	if msg.ReplyTo != "" {
+		if ts, err := strconv.ParseInt(msg.ReplyTo, 10, 64); err == nil {
+			if author, ok := c.recentAuthor(ts); ok {
+				params["quoteTimestamp"] = ts
+				params["quoteAuthor"] = author
+			}
+		}
+	}
+	if err := c.call(ctx, "send", params, nil); err != nil {
+		return err
+	}
+
+	if ref, ok := c.acks.LoadAndDelete(msg.ChatID); ok {
+		c.react(ctx, msg.ChatID, ref.(signalMessageRef), c.config.AckReaction, true)
+	}
+	return nil
+}
+
+// target returns the params that address a chat: a group or a recipient.
+func (c *SignalChannel) target(chatID string) map[string]any {
+	if groupID, ok := strings.CutPrefix(chatID, signalGroupChatPrefix); ok {
+		return map[string]any{"groupId": groupID}
+	}
+	return map[string]any{"recipient": []string{chatID}}
+}
+
+func (c *SignalChannel) react(ctx context.Context, chatID string, ref signalMessageRef, emoji string, remove bool) error {
+	params := c.target(chatID)
+	params["emoji"] = emoji
+	params["targetAuthor"] = ref.author
+	params["targetTimestamp"] = ref.timestamp
+	params["remove"] = remove
+	err := c.call(ctx, "sendReaction", params, nil)
+	if err != nil {
+		logger.DebugCF("signal", "Failed to send reaction", map[string]any{
+			"chat_id": chatID,
+			"error":   err.Error(),
+		})
+	}
+	return err
+}
+
+// SendTyping shows the typing indicator in a chat. Signal clients hide it
+// after 15 seconds; Send clears it.
+func (c *SignalChannel) SendTyping(ctx context.Context, chatID string) error {
+	c.typing.Store(chatID, struct{}{})
+	return c.call(ctx, "sendTyping", c.target(chatID), nil)
+}
+
+func (c *SignalChannel) stopTyping(ctx context.Context, chatID string) {
+	if _, ok := c.typing.LoadAndDelete(chatID); !ok {
+		return
+	}
+	params := c.target(chatID)
+	params["stop"] = true
+	c.call(ctx, "sendTyping", params, nil)
+}
+
+// remember records who wrote a recent message, so replies can quote it.
+func (c *SignalChannel) remember(ref signalMessageRef) {
+	c.recentMu.Lock()
+	defer c.recentMu.Unlock()
+	if old := c.recentRing[c.recentIdx]; old != 0 {
+		delete(c.recent, old)
+	}
+	c.recent[ref.timestamp] = ref.author
+	c.recentRing[c.recentIdx] = ref.timestamp
+	c.recentIdx = (c.recentIdx + 1) % len(c.recentRing)
+}
+
+func (c *SignalChannel) recentAuthor(timestamp int64) (string, bool) {
+	c.recentMu.Lock()
+	defer c.recentMu.Unlock()
+	author, ok := c.recent[timestamp]
+	return author, ok
+}
+
+func firstNonZero(values ...int64) int64 {
+	for _, v := range values {
+		if v != 0 {
+			return v
+		}
+	}
+	return 0
+}
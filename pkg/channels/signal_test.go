@@ -0,0 +1,273 @@
+package channels
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/config"
+)
+
+type signalCall struct {
+	Method string
+	Params map[string]any
+}
+
+// fakeSignalDaemon speaks signal-cli's JSON-RPC on a TCP port: it records
+// calls, answers them and pushes received messages.
+type fakeSignalDaemon struct {
+	ln        net.Listener
+	mu        sync.Mutex
+	conn      net.Conn
+	calls     []signalCall
+	connected chan struct{}
+}
+
+func newFakeSignalDaemon(t *testing.T) *fakeSignalDaemon {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &fakeSignalDaemon{ln: ln, connected: make(chan struct{})}
+	go d.serve()
+	t.Cleanup(func() {
+		ln.Close()
+		d.mu.Lock()
+		if d.conn != nil {
+			d.conn.Close()
+		}
+		d.mu.Unlock()
+	})
+	return d
+}
+
+func (d *fakeSignalDaemon) serve() {
+	conn, err := d.ln.Accept()
+	if err != nil {
+		return
+	}
+	d.mu.Lock()
+	d.conn = conn
+	d.mu.Unlock()
+	close(d.connected)
+
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+		var req struct {
+			ID     int64          `json:"id"`
+			Method string         `json:"method"`
+			Params map[string]any `json:"params"`
+		}
+		json.Unmarshal(line, &req)
+
+		result := `{}`
+		switch req.Method {
+		case "getAttachment":
+			result = fmt.Sprintf(`{"data":%q}`, base64.StdEncoding.EncodeToString([]byte("imgdata")))
+		case "send":
+			result = `{"timestamp":1800}`
+		}
+		d.mu.Lock()
+		d.calls = append(d.calls, signalCall{Method: req.Method, Params: req.Params})
+		fmt.Fprintf(conn, `{"jsonrpc":"2.0","result":%s,"id":%d}`+"\n", result, req.ID)
+		d.mu.Unlock()
+	}
+}
+
+func (d *fakeSignalDaemon) push(t *testing.T, envelope string) {
+	t.Helper()
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(envelope)); err != nil {
+		t.Fatal(err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	line := `{"jsonrpc":"2.0","method":"receive","params":{"envelope":` + compact.String() + `,"account":"+15550000000"}}` + "\n"
+	if _, err := d.conn.Write([]byte(line)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitForCall returns the first call to method, waiting for it briefly.
+func (d *fakeSignalDaemon) waitForCall(t *testing.T, method string, match func(signalCall) bool) signalCall {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		d.mu.Lock()
+		for _, call := range d.calls {
+			if call.Method == method && (match == nil || match(call)) {
+				d.mu.Unlock()
+				return call
+			}
+		}
+		d.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no %s call", method)
+	return signalCall{}
+}
+
+func startTestSignalChannel(t *testing.T, allowFrom ...string) (*SignalChannel, *fakeSignalDaemon, *bus.MessageBus) {
+	t.Helper()
+	daemon := newFakeSignalDaemon(t)
+	msgBus := bus.NewMessageBus()
+	ch, err := NewSignalChannel(config.SignalConfig{
+		Socket:      daemon.ln.Addr().String(),
+		Account:     "+15550000000",
+		AckReaction: "👀",
+		AllowFrom:   allowFrom,
+	}, msgBus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		ch.Stop(ctx)
+		cancel()
+	})
+	if err := ch.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-daemon.connected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("channel did not connect")
+	}
+	return ch, daemon, msgBus
+}
+
+func waitInbound(t *testing.T, msgBus *bus.MessageBus) bus.InboundMessage {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	msg, ok := msgBus.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("expected an inbound message")
+	}
+	return msg
+}
+
+func TestSignalChannel_DirectMessage(t *testing.T) {
+	ch, daemon, msgBus := startTestSignalChannel(t)
+
+	daemon.push(t, `{"source":"+15551112222","sourceNumber":"+15551112222","sourceUuid":"u-1","sourceName":"Alice","timestamp":1700,
+		"dataMessage":{"timestamp":1700,"message":"look at this","attachments":[{"id":"att1.jpg","contentType":"image/jpeg","filename":"photo.jpg"}]}}`)
+
+	msg := waitInbound(t, msgBus)
+	if msg.SenderID != "+15551112222|u-1" || msg.ChatID != "+15551112222" {
+		t.Errorf("sender = %q, chat = %q", msg.SenderID, msg.ChatID)
+	}
+	if msg.Content != "look at this\n[image: photo.jpg]" {
+		t.Errorf("content = %q", msg.Content)
+	}
+	if msg.Metadata["peer_kind"] != "direct" || msg.Metadata["message_id"] != "1700" || msg.Metadata["sender_name"] != "Alice" {
+		t.Errorf("metadata = %v", msg.Metadata)
+	}
+	if len(msg.Media) != 1 {
+		t.Fatalf("media = %v", msg.Media)
+	}
+	if data, _ := os.ReadFile(msg.Media[0]); string(data) != "imgdata" {
+		t.Errorf("attachment = %q", data)
+	}
+	os.Remove(msg.Media[0])
+
+	get := daemon.waitForCall(t, "getAttachment", nil)
+	if get.Params["id"] != "att1.jpg" || get.Params["account"] != "+15550000000" {
+		t.Errorf("getAttachment params = %v", get.Params)
+	}
+	ack := daemon.waitForCall(t, "sendReaction", nil)
+	if ack.Params["emoji"] != "👀" || ack.Params["targetAuthor"] != "+15551112222" || ack.Params["targetTimestamp"] != float64(1700) {
+		t.Errorf("ack reaction = %v", ack.Params)
+	}
+	if err := ch.SendTyping(context.Background(), msg.ChatID); err != nil {
+		t.Fatal(err)
+	}
+	daemon.waitForCall(t, "sendTyping", nil)
+
+	notes := writeTempFile(t, "notes.txt", "hello")
+	if err := ch.Send(context.Background(), bus.OutboundMessage{
+		ChatID:      msg.ChatID,
+		Content:     "Nice photo",
+		ReplyTo:     "1700",
+		Attachments: []bus.Attachment{{Path: notes}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	send := daemon.waitForCall(t, "send", nil)
+	recipients, _ := send.Params["recipient"].([]any)
+	if len(recipients) != 1 || recipients[0] != "+15551112222" || send.Params["message"] != "Nice photo" {
+		t.Errorf("send params = %v", send.Params)
+	}
+	if send.Params["quoteTimestamp"] != float64(1700) || send.Params["quoteAuthor"] != "+15551112222" {
+		t.Errorf("quote = %v / %v", send.Params["quoteTimestamp"], send.Params["quoteAuthor"])
+	}
+	attachments, _ := send.Params["attachments"].([]any)
+	if len(attachments) != 1 || !strings.HasPrefix(attachments[0].(string), "data:text/plain") ||
+		!strings.HasSuffix(attachments[0].(string), ";filename=notes.txt;base64,aGVsbG8=") {
+		t.Errorf("attachments = %v", attachments)
+	}
+
+	daemon.waitForCall(t, "sendTyping", func(c signalCall) bool { return c.Params["stop"] == true })
+	daemon.waitForCall(t, "sendReaction", func(c signalCall) bool { return c.Params["remove"] == true })
+}
+
+func TestSignalChannel_GroupsAndAllowList(t *testing.T) {
+	ch, daemon, msgBus := startTestSignalChannel(t, "u-2")
+
+	// A reaction and a sender outside the allow list are not handed on.
+	daemon.push(t, `{"sourceNumber":"+15553334444","sourceUuid":"u-2","timestamp":1,
+		"dataMessage":{"timestamp":1,"reaction":{"emoji":"👍","targetSentTimestamp":1800}}}`)
+	daemon.push(t, `{"sourceNumber":"+15559990000","sourceUuid":"u-3","timestamp":2,
+		"dataMessage":{"timestamp":2,"message":"let me in","groupInfo":{"groupId":"grp=="}}}`)
+	daemon.push(t, `{"sourceUuid":"u-2","timestamp":3,
+		"dataMessage":{"timestamp":3,"message":"hi team","groupInfo":{"groupId":"grp=="}}}`)
+
+	msg := waitInbound(t, msgBus)
+	if msg.ChatID != "group:grp==" || msg.SenderID != "u-2" || msg.Content != "hi team" {
+		t.Errorf("msg = %+v", msg)
+	}
+	if msg.Metadata["peer_kind"] != "group" || msg.Metadata["peer_id"] != "grp==" {
+		t.Errorf("metadata = %v", msg.Metadata)
+	}
+	if extra, ok := consumeInbound(t, msgBus); ok {
+		t.Errorf("unexpected message %+v", extra)
+	}
+
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: msg.ChatID, Content: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	send := daemon.waitForCall(t, "send", nil)
+	if send.Params["groupId"] != "grp==" || send.Params["recipient"] != nil {
+		t.Errorf("send params = %v", send.Params)
+	}
+}
+
+func TestSignalChannel_NotConnected(t *testing.T) {
+	ch, err := NewSignalChannel(config.SignalConfig{Socket: "/nonexistent/signal.sock"}, bus.NewMessageBus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch.setRunning(true)
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "+1555", Content: "hi"}); err == nil {
+		t.Error("sending without a daemon should fail")
+	}
+	if _, err := NewSignalChannel(config.SignalConfig{}, bus.NewMessageBus()); err == nil {
+		t.Error("a missing socket should fail")
+	}
+}
@@ -1,7 +1,9 @@
 package channels
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -16,7 +18,6 @@ import (
 	"github.com/Agentx-network/agentx/pkg/config"
 	"github.com/Agentx-network/agentx/pkg/logger"
 	"github.com/Agentx-network/agentx/pkg/utils"
-	"github.com/Agentx-network/agentx/pkg/voice"
 )
 
 type SlackChannel struct {
@@ -26,12 +27,20 @@ type SlackChannel struct {
 	socketClient *socketmode.Client
 	botUserID    string
 	teamID       string
-	transcriber  *voice.GroqTranscriber
 	ctx          context.Context
 	cancel       context.CancelFunc
 	pendingAcks  sync.Map
+	stream       *streamRenderer
 }
 
+const (
+	// slackMaxLength is where Slack truncates a message's text.
+	slackMaxLength = 40000
+	// slackStreamFlush keeps edits within chat.update's rate limit of
+	// about 50 a minute.
+	slackStreamFlush = 1500 * time.Millisecond
+)
+
 type slackMessageRef struct {
 	ChannelID string
 	Timestamp string
@@ -51,16 +60,65 @@ func NewSlackChannel(cfg config.SlackConfig, messageBus *bus.MessageBus) (*Slack
 
 	base := NewBaseChannel("slack", cfg, messageBus, cfg.AllowFrom)
 
-	return &SlackChannel{
+	c := &SlackChannel{
 		BaseChannel:  base,
 		config:       cfg,
 		api:          api,
 		socketClient: socketClient,
-	}, nil
+	}
+	c.stream = &streamRenderer{
+		name:     "slack",
+		interval: slackStreamFlush,
+		maxLen:   slackMaxLength,
+		post: func(ctx context.Context, chatID, text string) (string, error) {
+			channelID, threadTS := parseSlackChatID(chatID)
+			opts := []slack.MsgOption{slack.MsgOptionText(text, false)}
+			if threadTS != "" {
+				opts = append(opts, slack.MsgOptionTS(threadTS))
+			}
+			_, ts, err := api.PostMessageContext(ctx, channelID, opts...)
+			return ts, err
+		},
+		edit: func(ctx context.Context, chatID, ts, text string) error {
+			channelID, _ := parseSlackChatID(chatID)
+			_, _, _, err := api.UpdateMessageContext(ctx, channelID, ts, slack.MsgOptionText(text, false))
+			return err
+		},
+		remove: func(ctx context.Context, chatID, ts string) error {
+			channelID, _ := parseSlackChatID(chatID)
+			_, _, err := api.DeleteMessageContext(ctx, channelID, ts)
+			return err
+		},
+		retryAfter: func(err error) time.Duration {
+			var rateErr *slack.RateLimitedError
+			if errors.As(err, &rateErr) {
+				return rateErr.RetryAfter
+			}
+			return 0
+		},
+	}
+	return c, nil
+}
+
+// StartStreamConsumer starts a goroutine that shows stream deltas by
+// posting to the chat and then editing the message.
+func (c *SlackChannel) StartStreamConsumer(ctx context.Context) {
+	go c.stream.run(ctx)
 }
 
-func (c *SlackChannel) SetTranscriber(transcriber *voice.GroqTranscriber) {
-	c.transcriber = transcriber
+// HandleStreamDelta processes a single stream delta event.
+func (c *SlackChannel) HandleStreamDelta(delta bus.StreamDelta) {
+	c.stream.add(delta)
+}
+
+// ShowStatus shows what the agent is doing in a message of its own.
+func (c *SlackChannel) ShowStatus(ctx context.Context, chatID, status string) error {
+	return c.stream.showStatus(ctx, chatID, status)
+}
+
+// ClearStatus removes the status message.
+func (c *SlackChannel) ClearStatus(ctx context.Context, chatID string) error {
+	return c.stream.clearStatus(ctx, chatID)
 }
 
 func (c *SlackChannel) Start(ctx context.Context) error {
@@ -119,17 +177,54 @@ func (c *SlackChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 		return fmt.Errorf("invalid slack chat ID: %s", msg.ChatID)
 	}
 
-	opts := []slack.MsgOption{
-		slack.MsgOptionText(msg.Content, false),
+	// Slack replies are threads; a reply target outside a thread starts one.
+	if threadTS == "" && msg.ReplyTo != "" {
+		threadTS = msg.ReplyTo
 	}
 
-	if threadTS != "" {
-		opts = append(opts, slack.MsgOptionTS(threadTS))
+	// The reply takes the place of the streamed message, unless it has
+	// buttons or starts a thread the stream is not in. A reply without
+	// text, such as a voice note, removes the streamed message.
+	posted := false
+	if _, chatThreadTS := parseSlackChatID(msg.ChatID); strings.TrimSpace(msg.Content) == "" ||
+		threadTS != chatThreadTS || len(msg.Buttons) > 0 {
+		c.stream.finish(ctx, msg.ChatID, nil)
+	} else {
+		posted = len(c.stream.finish(ctx, msg.ChatID, []string{msg.Content})) == 0
 	}
 
-	_, _, err := c.api.PostMessageContext(ctx, channelID, opts...)
-	if err != nil {
-		return fmt.Errorf("failed to send slack message: %w", err)
+	if !posted && (strings.TrimSpace(msg.Content) != "" || len(msg.Buttons) > 0) {
+		opts := []slack.MsgOption{
+			slack.MsgOptionText(msg.Content, false),
+		}
+		if blocks := slackBlocks(msg.Content, msg.Buttons); len(blocks) > 0 {
+			opts = append(opts, slack.MsgOptionBlocks(blocks...))
+		}
+
+		if threadTS != "" {
+			opts = append(opts, slack.MsgOptionTS(threadTS))
+		}
+
+		_, _, err := c.api.PostMessageContext(ctx, channelID, opts...)
+		if err != nil {
+			return fmt.Errorf("failed to send slack message: %w", err)
+		}
+	}
+
+	for _, a := range msg.Attachments {
+		if err := c.uploadAttachment(ctx, channelID, threadTS, a); err != nil {
+			logger.WarnCF("slack", "Failed to upload attachment, sending link instead", map[string]any{
+				"attachment": a.Name(),
+				"error":      err.Error(),
+			})
+			opts := []slack.MsgOption{slack.MsgOptionText(attachmentText([]bus.Attachment{a}), false)}
+			if threadTS != "" {
+				opts = append(opts, slack.MsgOptionTS(threadTS))
+			}
+			if _, _, err := c.api.PostMessageContext(ctx, channelID, opts...); err != nil {
+				return fmt.Errorf("failed to send slack message: %w", err)
+			}
+		}
 	}
 
 	if ref, ok := c.pendingAcks.LoadAndDelete(msg.ChatID); ok {
@@ -148,6 +243,102 @@ func (c *SlackChannel) Send(ctx context.Context, msg bus.OutboundMessage) error
 	return nil
 }
 
+func (c *SlackChannel) uploadAttachment(ctx context.Context, channelID, threadTS string, a bus.Attachment) error {
+	data, err := readAttachment(ctx, a)
+	if err != nil {
+		return err
+	}
+	_, err = c.api.UploadFileV2Context(ctx, slack.UploadFileV2Parameters{
+		Reader:          bytes.NewReader(data),
+		FileSize:        len(data),
+		Filename:        a.Name(),
+		Title:           a.Name(),
+		Channel:         channelID,
+		ThreadTimestamp: threadTS,
+	})
+	return err
+}
+
+// slackButtonActionPrefix marks the action IDs of buttons sent by the agent.
+const slackButtonActionPrefix = "agentx_btn_"
+
+// slackBlocks renders content and buttons as Block Kit blocks. Plain
+// messages return nil so Slack formats the text as before.
+func slackBlocks(content string, buttons []bus.Button) []slack.Block {
+	if len(buttons) == 0 {
+		return nil
+	}
+	var blocks []slack.Block
+	if strings.TrimSpace(content) != "" {
+		blocks = append(blocks, slack.NewSectionBlock(
+			slack.NewTextBlockObject(slack.MarkdownType, content, false, false), nil, nil))
+	}
+	elements := make([]slack.BlockElement, 0, len(buttons))
+	for i, b := range buttons {
+		value := b.Reply()
+		if b.URL != "" {
+			value = ""
+		}
+		btn := slack.NewButtonBlockElement(
+			fmt.Sprintf("%s%d", slackButtonActionPrefix, i),
+			value,
+			slack.NewTextBlockObject(slack.PlainTextType, b.Text, true, false),
+		)
+		if b.URL != "" {
+			btn = btn.WithURL(b.URL)
+		}
+		elements = append(elements, btn)
+	}
+	return append(blocks, slack.NewActionBlock("", elements...))
+}
+
+// handleInteractive turns a press of one of our buttons into a user message
+// carrying the button's value.
+func (c *SlackChannel) handleInteractive(event socketmode.Event) {
+	if event.Request != nil {
+		c.socketClient.Ack(*event.Request)
+	}
+
+	callback, ok := event.Data.(slack.InteractionCallback)
+	if !ok || callback.Type != slack.InteractionTypeBlockActions {
+		return
+	}
+
+	// Link buttons also report a press but carry no value.
+	for _, action := range callback.ActionCallback.BlockActions {
+		if !strings.HasPrefix(action.ActionID, slackButtonActionPrefix) || action.Value == "" {
+			continue
+		}
+		senderID := callback.User.ID
+		channelID := callback.Channel.ID
+		if channelID == "" {
+			channelID = callback.Container.ChannelID
+		}
+		chatID := channelID
+		if callback.Container.ThreadTs != "" {
+			chatID = channelID + "/" + callback.Container.ThreadTs
+		}
+
+		peerKind := "channel"
+		peerID := channelID
+		if strings.HasPrefix(channelID, "D") {
+			peerKind = "direct"
+			peerID = senderID
+		}
+
+		c.HandleMessage(senderID, chatID, action.Value, nil, map[string]string{
+			"message_ts": callback.Container.MessageTs,
+			"channel_id": channelID,
+			"thread_ts":  callback.Container.ThreadTs,
+			"platform":   "slack",
+			"peer_kind":  peerKind,
+			"peer_id":    peerID,
+			"team_id":    c.teamID,
+			"button":     "true",
+		})
+	}
+}
+
 func (c *SlackChannel) eventLoop() {
 	for {
 		select {
@@ -163,9 +354,7 @@ func (c *SlackChannel) eventLoop() {
 			case socketmode.EventTypeSlashCommand:
 				c.handleSlashCommand(event)
 			case socketmode.EventTypeInteractive:
-				if event.Request != nil {
-					c.socketClient.Ack(*event.Request)
-				}
+				c.handleInteractive(event)
 			}
 		}
 	}
@@ -255,16 +444,11 @@ func (c *SlackChannel) handleMessageEvent(ev *slackevents.MessageEvent) {
 			localFiles = append(localFiles, localPath)
 			mediaPaths = append(mediaPaths, localPath)
 
-			if utils.IsAudioFile(file.Name, file.Mimetype) && c.transcriber != nil && c.transcriber.IsAvailable() {
-				ctx, cancel := context.WithTimeout(c.ctx, 30*time.Second)
-				defer cancel()
-				result, err := c.transcriber.Transcribe(ctx, localPath)
-
-				if err != nil {
-					logger.ErrorCF("slack", "Voice transcription failed", map[string]any{"error": err.Error()})
+			if utils.IsAudioFile(file.Name, file.Mimetype) && c.canTranscribe() {
+				if text, err := c.transcribe(c.ctx, localPath); err != nil {
 					content += fmt.Sprintf("\n[audio: %s (transcription failed)]", file.Name)
 				} else {
-					content += fmt.Sprintf("\n[voice transcription: %s]", result.Text)
+					content += fmt.Sprintf("\n[voice transcription: %s]", text)
 				}
 			} else {
 				content += fmt.Sprintf("\n[file: %s]", file.Name)
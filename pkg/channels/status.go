@@ -0,0 +1,179 @@
+package channels
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/config"
+	"github.com/Agentx-network/agentx/pkg/logger"
+)
+
+const (
+	// typingRefresh is how often the typing indicator is sent again while
+	// the agent works. Telegram hides it after five seconds, the shortest
+	// of the platforms.
+	typingRefresh = 4 * time.Second
+	// statusMaxDuration bounds how long a chat is shown as busy, in case
+	// the update clearing it is lost.
+	statusMaxDuration = 10 * time.Minute
+)
+
+// chatStatus shows a chat that the agent is working on a reply: the typing
+// indicator, refreshed until the agent is done, and in verbose mode a
+// status message kept up to date with the latest status. Updates arriving
+// while the channel is busy are coalesced, so a slow platform only shows
+// the newest status.
+type chatStatus struct {
+	mu      sync.Mutex
+	status  string
+	changed bool
+	done    bool
+	wake    chan struct{}
+}
+
+func newChatStatus() *chatStatus {
+	return &chatStatus{wake: make(chan struct{}, 1)}
+}
+
+// set records an update and wakes the worker.
+func (s *chatStatus) set(update bus.StatusUpdate) {
+	s.mu.Lock()
+	if update.Done {
+		s.done = true
+	} else {
+		s.done = false
+		if update.Status != "" && update.Status != s.status {
+			s.status, s.changed = update.Status, true
+		}
+	}
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// statusMode returns the status mode of a channel.
+func statusMode(channel Channel) string {
+	if sc, ok := channel.(interface{ StatusMode() string }); ok {
+		return sc.StatusMode()
+	}
+	return config.StatusTyping
+}
+
+// handleStatus passes an update to the worker of its chat, starting one
+// when the agent starts working there.
+func (m *Manager) handleStatus(ctx context.Context, channel Channel, update bus.StatusUpdate) {
+	mode := statusMode(channel)
+	if mode == config.StatusOff {
+		return
+	}
+	key := update.Channel + "\x00" + update.ChatID
+
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+	s, ok := m.statuses[key]
+	if !ok {
+		if update.Done {
+			return
+		}
+		if m.statuses == nil {
+			m.statuses = make(map[string]*chatStatus)
+		}
+		s = newChatStatus()
+		m.statuses[key] = s
+		go m.runStatus(ctx, key, channel, update.ChatID, mode == config.StatusVerbose, s)
+	}
+	s.set(update)
+}
+
+// runStatus shows a chat as busy until the agent is done, then removes the
+// status message if one was shown.
+func (m *Manager) runStatus(ctx context.Context, key string, channel Channel, chatID string, verbose bool, s *chatStatus) {
+	typing, _ := channel.(TypingChannel)
+	var sc StatusChannel
+	if verbose {
+		sc, _ = channel.(StatusChannel)
+	}
+	busyCtx, cancel := context.WithTimeout(ctx, statusMaxDuration)
+	defer cancel()
+
+	sendTyping := func() {
+		if typing == nil {
+			return
+		}
+		if err := typing.SendTyping(busyCtx, chatID); err != nil {
+			logger.DebugCF("channels", "Failed to send typing indicator", map[string]any{
+				"channel": channel.Name(),
+				"chat_id": chatID,
+				"error":   err.Error(),
+			})
+		}
+	}
+	sendTyping()
+	ticker := time.NewTicker(typingRefresh)
+	defer ticker.Stop()
+
+	shown := false
+loop:
+	for !m.endStatus(key, s) {
+		s.mu.Lock()
+		status, changed := s.status, s.changed
+		s.changed = false
+		s.mu.Unlock()
+
+		if changed && sc != nil {
+			if err := sc.ShowStatus(busyCtx, chatID, status); err != nil {
+				logger.DebugCF("channels", "Failed to show status", map[string]any{
+					"channel": channel.Name(),
+					"chat_id": chatID,
+					"error":   err.Error(),
+				})
+			} else {
+				shown = true
+			}
+		}
+
+		select {
+		case <-busyCtx.Done():
+			m.statusMu.Lock()
+			if m.statuses[key] == s {
+				delete(m.statuses, key)
+			}
+			m.statusMu.Unlock()
+			if ctx.Err() != nil {
+				return
+			}
+			break loop
+		case <-ticker.C:
+			sendTyping()
+		case <-s.wake:
+		}
+	}
+
+	if shown {
+		if err := sc.ClearStatus(ctx, chatID); err != nil {
+			logger.DebugCF("channels", "Failed to clear status", map[string]any{
+				"channel": channel.Name(),
+				"chat_id": chatID,
+				"error":   err.Error(),
+			})
+		}
+	}
+}
+
+// endStatus removes a chat's worker once the agent is done there. Updates
+// are passed on under statusMu, so none can arrive for a removed worker.
+func (m *Manager) endStatus(key string, s *chatStatus) bool {
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+	s.mu.Lock()
+	done := s.done
+	s.mu.Unlock()
+	if done && m.statuses[key] == s {
+		delete(m.statuses, key)
+	}
+	return done
+}
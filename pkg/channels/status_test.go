@@ -0,0 +1,122 @@
+package channels
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/config"
+)
+
+// statusRecorder is a channel that records typing and status calls.
+type statusRecorder struct {
+	*BaseChannel
+	calls chan string
+}
+
+func newStatusRecorder(mode string) *statusRecorder {
+	cfg := config.TelegramConfig{ChannelStatus: config.ChannelStatus{Status: mode}}
+	return &statusRecorder{
+		BaseChannel: NewBaseChannel("test", cfg, nil, nil),
+		calls:       make(chan string, 10),
+	}
+}
+
+func (c *statusRecorder) Start(ctx context.Context) error                         { return nil }
+func (c *statusRecorder) Stop(ctx context.Context) error                          { return nil }
+func (c *statusRecorder) Send(ctx context.Context, msg bus.OutboundMessage) error { return nil }
+
+func (c *statusRecorder) SendTyping(ctx context.Context, chatID string) error {
+	c.calls <- "typing " + chatID
+	return nil
+}
+
+func (c *statusRecorder) ShowStatus(ctx context.Context, chatID, status string) error {
+	c.calls <- "status " + chatID + " " + status
+	return nil
+}
+
+func (c *statusRecorder) ClearStatus(ctx context.Context, chatID string) error {
+	c.calls <- "clear " + chatID
+	return nil
+}
+
+func (c *statusRecorder) expect(t *testing.T, want string) {
+	t.Helper()
+	select {
+	case got := <-c.calls:
+		if got != want {
+			t.Fatalf("call = %q, want %q", got, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for %q", want)
+	}
+}
+
+func (c *statusRecorder) expectNone(t *testing.T) {
+	t.Helper()
+	select {
+	case got := <-c.calls:
+		t.Fatalf("unexpected call %q", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func waitStatusDone(t *testing.T, m *Manager) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		m.statusMu.Lock()
+		n := len(m.statuses)
+		m.statusMu.Unlock()
+		if n == 0 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("status worker did not finish")
+}
+
+func TestManagerStatus_Verbose(t *testing.T) {
+	ch := newStatusRecorder(config.StatusVerbose)
+	m := &Manager{}
+	ctx := context.Background()
+
+	m.handleStatus(ctx, ch, bus.StatusUpdate{Channel: "test", ChatID: "c"})
+	ch.expect(t, "typing c")
+	m.handleStatus(ctx, ch, bus.StatusUpdate{Channel: "test", ChatID: "c", Status: "🔍 Searching the web"})
+	ch.expect(t, "status c 🔍 Searching the web")
+	m.handleStatus(ctx, ch, bus.StatusUpdate{Channel: "test", ChatID: "c", Done: true})
+	ch.expect(t, "clear c")
+	waitStatusDone(t, m)
+
+	// A new turn in the chat starts over.
+	m.handleStatus(ctx, ch, bus.StatusUpdate{Channel: "test", ChatID: "c"})
+	ch.expect(t, "typing c")
+	m.handleStatus(ctx, ch, bus.StatusUpdate{Channel: "test", ChatID: "c", Done: true})
+	waitStatusDone(t, m)
+	ch.expectNone(t)
+}
+
+func TestManagerStatus_Modes(t *testing.T) {
+	ctx := context.Background()
+
+	// Typing mode shows no status messages.
+	ch := newStatusRecorder("")
+	m := &Manager{}
+	m.handleStatus(ctx, ch, bus.StatusUpdate{Channel: "test", ChatID: "c", Status: "⚙️ Running a command"})
+	ch.expect(t, "typing c")
+	m.handleStatus(ctx, ch, bus.StatusUpdate{Channel: "test", ChatID: "c", Done: true})
+	waitStatusDone(t, m)
+	ch.expectNone(t)
+
+	ch = newStatusRecorder(config.StatusOff)
+	m.handleStatus(ctx, ch, bus.StatusUpdate{Channel: "test", ChatID: "c", Status: "⚙️ Running a command"})
+	ch.expectNone(t)
+
+	// Done alone does not start a worker.
+	ch = newStatusRecorder(config.StatusVerbose)
+	m.handleStatus(ctx, ch, bus.StatusUpdate{Channel: "test", ChatID: "c", Done: true})
+	ch.expectNone(t)
+}
@@ -0,0 +1,266 @@
+package channels
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/logger"
+	"github.com/Agentx-network/agentx/pkg/utils"
+)
+
+const (
+	// streamTick is how often streams are checked for text to show.
+	streamTick = 100 * time.Millisecond
+	// streamExpiry is how long an ended stream waits for Send before its
+	// messages are forgotten, so that a reply which never comes (the
+	// message tool already answered, say) does not leave the next reply
+	// editing them.
+	streamExpiry = time.Minute
+)
+
+// streamRenderer shows replies while they are streamed by editing messages
+// in place, for channels that can edit their own messages.
+//
+// A chat's messages are updated at most once per interval to stay within
+// the platform's rate limits, and text beyond maxLen continues in a new
+// message. When the stream ends the rest of its text is shown at the next
+// update. The messages are kept for Send, which shows the full reply in
+// them with finish.
+type streamRenderer struct {
+	name     string // channel name, for logs
+	interval time.Duration
+	maxLen   int
+	maxEdits int // edits the platform allows per message; 0 for no limit
+
+	// post sends a new message and returns its ID.
+	post func(ctx context.Context, chatID, text string) (string, error)
+	// edit replaces the text of a message.
+	edit func(ctx context.Context, chatID, msgID, text string) error
+	// remove, if set, deletes a message the full reply does not need.
+	remove func(ctx context.Context, chatID, msgID string) error
+	// claim, if set, returns a message already in the chat, such as a
+	// "Thinking..." placeholder, to show the start of the stream in.
+	claim func(chatID string) (string, bool)
+	// retryAfter, if set, returns how long the platform asked to wait
+	// after err, or 0.
+	retryAfter func(err error) time.Duration
+
+	chats    sync.Map // chat ID -> *chatStream
+	statuses sync.Map // chat ID -> status message ID
+}
+
+// chatStream is the stream of one chat.
+type chatStream struct {
+	mu      sync.Mutex // guards the fields below until sendMu
+	text    strings.Builder
+	dirty   bool
+	ended   bool
+	endedAt time.Time
+
+	sendMu sync.Mutex // held while messages are posted or edited
+	taken  bool
+	msgs   []streamMessage
+	next   time.Time // earliest time of the next update
+}
+
+// streamMessage is a message showing part of a stream.
+type streamMessage struct {
+	id    string
+	text  string // as last shown
+	edits int
+}
+
+// add buffers a stream delta. Text arriving after the stream ended, from
+// the agent's next step, continues it in a new paragraph.
+func (r *streamRenderer) add(delta bus.StreamDelta) {
+	if delta.Done {
+		if val, ok := r.chats.Load(delta.ChatID); ok {
+			s := val.(*chatStream)
+			s.mu.Lock()
+			s.ended, s.endedAt = true, time.Now()
+			s.mu.Unlock()
+		}
+		return
+	}
+	if delta.Delta == "" {
+		return
+	}
+	val, _ := r.chats.LoadOrStore(delta.ChatID, &chatStream{})
+	s := val.(*chatStream)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended && s.text.Len() > 0 {
+		s.text.WriteString("\n\n")
+	}
+	s.ended = false
+	s.text.WriteString(delta.Delta)
+	s.dirty = true
+}
+
+// run updates the messages of every chat with new text until ctx is done.
+func (r *streamRenderer) run(ctx context.Context) {
+	ticker := time.NewTicker(streamTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.flush(ctx)
+		}
+	}
+}
+
+// flush updates the messages of the chats that are due an update.
+func (r *streamRenderer) flush(ctx context.Context) {
+	r.chats.Range(func(key, value any) bool {
+		r.update(ctx, key.(string), value.(*chatStream))
+		return true
+	})
+}
+
+func (r *streamRenderer) update(ctx context.Context, chatID string, s *chatStream) {
+	// A chat being finished by Send is skipped rather than waited for.
+	if !s.sendMu.TryLock() {
+		return
+	}
+	defer s.sendMu.Unlock()
+	now := time.Now()
+	if s.taken || now.Before(s.next) {
+		return
+	}
+
+	s.mu.Lock()
+	text, dirty := s.text.String(), s.dirty
+	expired := s.ended && !dirty && now.Sub(s.endedAt) > streamExpiry
+	s.dirty = false
+	s.mu.Unlock()
+
+	if expired {
+		s.taken = true
+		r.chats.CompareAndDelete(chatID, s)
+		return
+	}
+	if !dirty || strings.TrimSpace(text) == "" {
+		return
+	}
+
+	s.next = now.Add(r.interval)
+	if err := r.show(ctx, chatID, s, utils.SplitMessage(text, r.maxLen)); err != nil {
+		logger.DebugCF(r.name, "Stream update failed", map[string]any{
+			"chat_id": chatID,
+			"error":   err.Error(),
+		})
+		if r.retryAfter != nil {
+			if wait := r.retryAfter(err); wait > r.interval {
+				s.next = now.Add(wait)
+			}
+		}
+		s.mu.Lock()
+		s.dirty = true
+		s.mu.Unlock()
+	}
+}
+
+// show brings the chat's messages up to date with chunks, editing those
+// that changed and posting the ones that are new.
+func (r *streamRenderer) show(ctx context.Context, chatID string, s *chatStream, chunks []string) error {
+	for i, chunk := range chunks {
+		if i < len(s.msgs) {
+			m := &s.msgs[i]
+			// The last edit a platform allows is saved for the full reply.
+			if m.text == chunk || (r.maxEdits > 0 && m.edits >= r.maxEdits-1) {
+				continue
+			}
+			if err := r.edit(ctx, chatID, m.id, chunk); err != nil {
+				return err
+			}
+			m.text = chunk
+			m.edits++
+			continue
+		}
+
+		if i == 0 && r.claim != nil {
+			if id, ok := r.claim(chatID); ok {
+				if err := r.edit(ctx, chatID, id, chunk); err == nil {
+					s.msgs = append(s.msgs, streamMessage{id: id, text: chunk, edits: 1})
+					continue
+				}
+			}
+		}
+		id, err := r.post(ctx, chatID, chunk)
+		if err != nil {
+			return err
+		}
+		s.msgs = append(s.msgs, streamMessage{id: id, text: chunk})
+	}
+	return nil
+}
+
+// take ends the chat's stream and returns the messages showing it.
+func (r *streamRenderer) take(chatID string) []streamMessage {
+	val, ok := r.chats.LoadAndDelete(chatID)
+	if !ok {
+		return nil
+	}
+	s := val.(*chatStream)
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	s.taken = true
+	return s.msgs
+}
+
+// finish shows the start of a reply in the chat's stream messages: the
+// first chunks replace their text and messages left over are removed. It
+// returns the chunks that still need sending.
+func (r *streamRenderer) finish(ctx context.Context, chatID string, chunks []string) []string {
+	msgs := r.take(chatID)
+	for i, m := range msgs {
+		if i >= len(chunks) {
+			if r.remove != nil {
+				if err := r.remove(ctx, chatID, m.id); err != nil {
+					logger.DebugCF(r.name, "Failed to remove stream message", map[string]any{
+						"error": err.Error(),
+					})
+				}
+			}
+			continue
+		}
+		if m.text == chunks[i] {
+			continue
+		}
+		if err := r.edit(ctx, chatID, m.id, chunks[i]); err != nil {
+			logger.DebugCF(r.name, "Failed to edit stream message, sending the reply anew", map[string]any{
+				"error": err.Error(),
+			})
+			return chunks[i:]
+		}
+	}
+	return chunks[min(len(msgs), len(chunks)):]
+}
+
+// showStatus shows status in the chat's status message, posting it first if
+// the chat has none.
+func (r *streamRenderer) showStatus(ctx context.Context, chatID, status string) error {
+	if id, ok := r.statuses.Load(chatID); ok {
+		return r.edit(ctx, chatID, id.(string), status)
+	}
+	id, err := r.post(ctx, chatID, status)
+	if err != nil {
+		return err
+	}
+	r.statuses.Store(chatID, id)
+	return nil
+}
+
+// clearStatus removes the chat's status message.
+func (r *streamRenderer) clearStatus(ctx context.Context, chatID string) error {
+	id, ok := r.statuses.LoadAndDelete(chatID)
+	if !ok || r.remove == nil {
+		return nil
+	}
+	return r.remove(ctx, chatID, id.(string))
+}
@@ -0,0 +1,205 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+)
+
+// fakeStreamChat records what a streamRenderer does to a chat.
+type fakeStreamChat struct {
+	msgs    map[string]string
+	calls   []string
+	failing error
+}
+
+func newTestStreamRenderer(chat *fakeStreamChat) *streamRenderer {
+	chat.msgs = map[string]string{}
+	return &streamRenderer{
+		name:   "test",
+		maxLen: 10,
+		post: func(ctx context.Context, chatID, text string) (string, error) {
+			if chat.failing != nil {
+				return "", chat.failing
+			}
+			id := fmt.Sprintf("m%d", len(chat.msgs)+1)
+			chat.msgs[id] = text
+			chat.calls = append(chat.calls, "post "+id+" "+text)
+			return id, nil
+		},
+		edit: func(ctx context.Context, chatID, msgID, text string) error {
+			if chat.failing != nil {
+				return chat.failing
+			}
+			chat.msgs[msgID] = text
+			chat.calls = append(chat.calls, "edit "+msgID+" "+text)
+			return nil
+		},
+		remove: func(ctx context.Context, chatID, msgID string) error {
+			delete(chat.msgs, msgID)
+			chat.calls = append(chat.calls, "remove "+msgID)
+			return nil
+		},
+	}
+}
+
+func (c *fakeStreamChat) takeCalls() []string {
+	calls := c.calls
+	c.calls = nil
+	return calls
+}
+
+// makeDue lets a chat's next update happen right away.
+func makeDue(r *streamRenderer, chatID string) {
+	val, _ := r.chats.Load(chatID)
+	s := val.(*chatStream)
+	s.sendMu.Lock()
+	s.next = time.Time{}
+	s.sendMu.Unlock()
+}
+
+func TestStreamRenderer_EditsAndSplits(t *testing.T) {
+	chat := &fakeStreamChat{}
+	r := newTestStreamRenderer(chat)
+	ctx := context.Background()
+
+	r.add(bus.StreamDelta{ChatID: "c", Delta: "Hello"})
+	r.flush(ctx)
+	r.add(bus.StreamDelta{ChatID: "c", Delta: " world"})
+	r.flush(ctx)
+	r.flush(ctx) // nothing new
+	want := []string{"post m1 Hello", "post m2 world"}
+	if got := chat.takeCalls(); !slices.Equal(got, want) {
+		t.Fatalf("calls = %q, want %q", got, want)
+	}
+
+	// Text from the agent's next step continues the stream.
+	r.add(bus.StreamDelta{ChatID: "c", Done: true})
+	r.add(bus.StreamDelta{ChatID: "c", Delta: "Bye"})
+	r.flush(ctx)
+	if got := chat.takeCalls(); !slices.Equal(got, []string{"edit m2 world\n\nBye"}) {
+		t.Fatalf("calls = %q", got)
+	}
+
+	// The reply is shorter than the stream: the message left over goes.
+	rest := r.finish(ctx, "c", []string{"Hello", "world!"})
+	if len(rest) != 0 {
+		t.Errorf("rest = %q", rest)
+	}
+	if got := chat.takeCalls(); !slices.Equal(got, []string{"edit m2 world!"}) {
+		t.Errorf("calls = %q", got)
+	}
+
+	// The stream is over: the next reply is sent anew.
+	if rest := r.finish(ctx, "c", []string{"Next"}); !slices.Equal(rest, []string{"Next"}) {
+		t.Errorf("rest = %q", rest)
+	}
+}
+
+func TestStreamRenderer_FinishRemovesLeftovers(t *testing.T) {
+	chat := &fakeStreamChat{}
+	r := newTestStreamRenderer(chat)
+	ctx := context.Background()
+
+	r.add(bus.StreamDelta{ChatID: "c", Delta: "one two three four"})
+	r.flush(ctx)
+	chat.takeCalls()
+	if rest := r.finish(ctx, "c", nil); len(rest) != 0 {
+		t.Errorf("rest = %q", rest)
+	}
+	if len(chat.msgs) != 0 {
+		t.Errorf("messages left: %v", chat.msgs)
+	}
+}
+
+func TestStreamRenderer_RateLimits(t *testing.T) {
+	chat := &fakeStreamChat{}
+	r := newTestStreamRenderer(chat)
+	r.interval = time.Hour
+	r.maxEdits = 2
+	ctx := context.Background()
+
+	r.add(bus.StreamDelta{ChatID: "c", Delta: "a"})
+	r.flush(ctx)
+	r.add(bus.StreamDelta{ChatID: "c", Delta: "b"})
+	r.flush(ctx) // within the interval
+	if got := chat.takeCalls(); !slices.Equal(got, []string{"post m1 a"}) {
+		t.Fatalf("calls = %q", got)
+	}
+
+	r.interval = 0
+	makeDue(r, "c")
+	r.flush(ctx)
+	r.add(bus.StreamDelta{ChatID: "c", Delta: "c"})
+	r.flush(ctx) // the last edit is kept for the reply
+	if got := chat.takeCalls(); !slices.Equal(got, []string{"edit m1 ab"}) {
+		t.Fatalf("calls = %q", got)
+	}
+	r.finish(ctx, "c", []string{"abc."})
+	if got := chat.takeCalls(); !slices.Equal(got, []string{"edit m1 abc."}) {
+		t.Errorf("calls = %q", got)
+	}
+
+	// A failed update is retried after the wait the platform asks for.
+	chat.failing = errors.New("429")
+	r.retryAfter = func(err error) time.Duration { return time.Hour }
+	r.add(bus.StreamDelta{ChatID: "d", Delta: "x"})
+	r.flush(ctx)
+	chat.failing = nil
+	r.flush(ctx)
+	if got := chat.takeCalls(); len(got) != 0 {
+		t.Errorf("expected no update before the retry, got %q", got)
+	}
+	makeDue(r, "d")
+	r.flush(ctx)
+	if got := chat.takeCalls(); !slices.Equal(got, []string{"post m2 x"}) {
+		t.Errorf("calls = %q", got)
+	}
+}
+
+func TestStreamRenderer_ClaimsPlaceholder(t *testing.T) {
+	chat := &fakeStreamChat{}
+	r := newTestStreamRenderer(chat)
+	claimed := false
+	r.claim = func(chatID string) (string, bool) {
+		if claimed {
+			return "", false
+		}
+		claimed = true
+		return "p1", true
+	}
+	ctx := context.Background()
+
+	r.add(bus.StreamDelta{ChatID: "c", Delta: "Hi"})
+	r.flush(ctx)
+	if got := chat.takeCalls(); !slices.Equal(got, []string{"edit p1 Hi"}) {
+		t.Errorf("calls = %q", got)
+	}
+}
+
+func TestStreamRenderer_ExpiresEndedStreams(t *testing.T) {
+	chat := &fakeStreamChat{}
+	r := newTestStreamRenderer(chat)
+	ctx := context.Background()
+
+	r.add(bus.StreamDelta{ChatID: "c", Delta: "Hi"})
+	r.flush(ctx)
+	r.add(bus.StreamDelta{ChatID: "c", Done: true})
+	val, _ := r.chats.Load("c")
+	s := val.(*chatStream)
+	s.endedAt = time.Now().Add(-2 * streamExpiry)
+	r.flush(ctx)
+
+	if rest := r.finish(ctx, "c", []string{"Hi!"}); len(rest) != 1 {
+		t.Errorf("an expired stream should not be edited, rest = %q", rest)
+	}
+	if strings.Join(chat.takeCalls(), ",") != "post m1 Hi" {
+		t.Error("unexpected calls")
+	}
+}
@@ -2,16 +2,19 @@ package channels
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/mymmrac/telego"
+	"github.com/mymmrac/telego/telegoapi"
 	"github.com/mymmrac/telego/telegohandler"
 	th "github.com/mymmrac/telego/telegohandler"
 	tu "github.com/mymmrac/telego/telegoutil"
@@ -41,22 +44,14 @@ var (
 
 type TelegramChannel struct {
 	*BaseChannel
-	bot             *telego.Bot
-	commands        TelegramCommander
-	config          *config.Config
-	chatIDs         map[string]int64
-	transcriber     *voice.GroqTranscriber
-	placeholders    sync.Map // chatID -> messageID
-	stopThinking    sync.Map // chatID -> thinkingCancel
-	streamBuffers   sync.Map // chatID -> *streamBuffer
-	streamMsgIDs    sync.Map // chatID -> int (message ID for progressive edits)
-}
-
-// streamBuffer accumulates stream deltas for debounced Telegram edits.
-type streamBuffer struct {
-	mu      sync.Mutex
-	content strings.Builder
-	dirty   bool
+	bot          *telego.Bot
+	commands     TelegramCommander
+	config       *config.Config
+	chatIDs      map[string]int64
+	placeholders sync.Map // chatID -> messageID
+	stopThinking sync.Map // chatID -> thinkingCancel
+	stream       *streamRenderer
+	callbackData buttonReplyStore // button replies too long for callback data
 }
 
 type thinkingCancel struct {
@@ -99,20 +94,33 @@ func NewTelegramChannel(cfg *config.Config, bus *bus.MessageBus) (*TelegramChann
 
 	base := NewBaseChannel("telegram", telegramCfg, bus, telegramCfg.AllowFrom)
 
-	return &TelegramChannel{
+	c := &TelegramChannel{
 		BaseChannel:  base,
 		commands:     NewTelegramCommands(bot, cfg),
 		bot:          bot,
 		config:       cfg,
 		chatIDs:      make(map[string]int64),
-		transcriber:  nil,
 		placeholders: sync.Map{},
 		stopThinking: sync.Map{},
-	}, nil
-}
-
-func (c *TelegramChannel) SetTranscriber(transcriber *voice.GroqTranscriber) {
-	c.transcriber = transcriber
+	}
+	c.stream = &streamRenderer{
+		name:     "telegram",
+		interval: telegramStreamFlush,
+		maxLen:   telegramStreamLength,
+		post:     c.streamPost,
+		edit:     c.streamEdit,
+		remove:   c.deleteMessage,
+		// The "Thinking..." placeholder becomes the first stream message.
+		claim: func(chatID string) (string, bool) {
+			pID, ok := c.placeholders.LoadAndDelete(chatID)
+			if !ok {
+				return "", false
+			}
+			return strconv.Itoa(pID.(int)), true
+		},
+		retryAfter: telegramRetryAfter,
+	}
+	return c, nil
 }
 
 func (c *TelegramChannel) Start(ctx context.Context) error {
@@ -150,6 +158,10 @@ func (c *TelegramChannel) Start(ctx context.Context) error {
 		return c.handleMessage(ctx, &message)
 	}, th.AnyMessage())
 
+	bh.HandleCallbackQuery(func(ctx *th.Context, query telego.CallbackQuery) error {
+		return c.handleCallbackQuery(ctx, query)
+	}, th.AnyCallbackQuery())
+
 	c.setRunning(true)
 	logger.InfoCF("telegram", "Telegram bot connected", map[string]any{
 		"username": c.bot.Username(),
@@ -165,106 +177,79 @@ func (c *TelegramChannel) Start(ctx context.Context) error {
 	return nil
 }
 
-// StartStreamConsumer starts a goroutine that consumes stream deltas and
-// progressively updates Telegram messages with debounced edits.
-// It reuses the "Thinking..." placeholder message instead of creating new ones.
+// StartStreamConsumer starts a goroutine that shows stream deltas by
+// editing the "Thinking..." placeholder, continuing in new messages when
+// the reply outgrows it.
 func (c *TelegramChannel) StartStreamConsumer(ctx context.Context) {
-	go func() {
-		ticker := time.NewTicker(500 * time.Millisecond)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
-				// Flush all dirty stream buffers
-				c.streamBuffers.Range(func(key, value any) bool {
-					chatIDStr := key.(string)
-					buf := value.(*streamBuffer)
-
-					buf.mu.Lock()
-					if !buf.dirty {
-						buf.mu.Unlock()
-						return true
-					}
-					content := buf.content.String()
-					buf.dirty = false
-					buf.mu.Unlock()
-
-					if content == "" {
-						return true
-					}
-
-					chatID, err := parseChatID(chatIDStr)
-					if err != nil {
-						return true
-					}
-
-					// Truncate streaming preview to avoid hitting Telegram limits mid-stream.
-					// The full content will be sent via Send() when done.
-					displayContent := content
-					if len(displayContent) > 3500 {
-						displayContent = displayContent[:3500] + "\n\n..."
-					}
-
-					htmlContent := markdownToTelegramHTML(displayContent)
-
-					// Try to reuse existing stream message, or claim the placeholder
-					if msgIDVal, ok := c.streamMsgIDs.Load(chatIDStr); ok {
-						msgID := msgIDVal.(int)
-						editMsg := tu.EditMessageText(tu.ID(chatID), msgID, htmlContent)
-						editMsg.ParseMode = telego.ModeHTML
-						if _, err := c.bot.EditMessageText(ctx, editMsg); err != nil {
-							logger.DebugCF("telegram", "Stream edit failed", map[string]any{
-								"error": err.Error(),
-							})
-						}
-					} else if pID, ok := c.placeholders.Load(chatIDStr); ok {
-						// Claim the placeholder for streaming
-						msgID := pID.(int)
-						c.placeholders.Delete(chatIDStr)
-						c.streamMsgIDs.Store(chatIDStr, msgID)
-						editMsg := tu.EditMessageText(tu.ID(chatID), msgID, htmlContent)
-						editMsg.ParseMode = telego.ModeHTML
-						if _, err := c.bot.EditMessageText(ctx, editMsg); err != nil {
-							logger.DebugCF("telegram", "Stream placeholder edit failed", map[string]any{
-								"error": err.Error(),
-							})
-						}
-					} else {
-						// No placeholder available, create a new message
-						tgMsg := tu.Message(tu.ID(chatID), htmlContent)
-						tgMsg.ParseMode = telego.ModeHTML
-						sent, err := c.bot.SendMessage(ctx, tgMsg)
-						if err == nil {
-							c.streamMsgIDs.Store(chatIDStr, sent.MessageID)
-						}
-					}
-
-					return true
-				})
-			}
-		}
-	}()
+	go c.stream.run(ctx)
+}
+
+const (
+	// telegramStreamFlush spaces out edits: Telegram allows about one
+	// message update per second in a chat.
+	telegramStreamFlush = time.Second
+	// telegramStreamLength leaves room for the HTML a chunk turns into.
+	telegramStreamLength = 3500
+)
+
+func (c *TelegramChannel) streamPost(ctx context.Context, chatIDStr, text string) (string, error) {
+	chatID, err := parseChatID(chatIDStr)
+	if err != nil {
+		return "", err
+	}
+	tgMsg := tu.Message(tu.ID(chatID), markdownToTelegramHTML(text))
+	tgMsg.ParseMode = telego.ModeHTML
+	sent, err := c.bot.SendMessage(ctx, tgMsg)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(sent.MessageID), nil
+}
+
+func (c *TelegramChannel) streamEdit(ctx context.Context, chatIDStr, msgID, text string) error {
+	chatID, err := parseChatID(chatIDStr)
+	if err != nil {
+		return err
+	}
+	id, err := strconv.Atoi(msgID)
+	if err != nil {
+		return err
+	}
+	editMsg := tu.EditMessageText(tu.ID(chatID), id, markdownToTelegramHTML(text))
+	editMsg.ParseMode = telego.ModeHTML
+	_, err = c.bot.EditMessageText(ctx, editMsg)
+	return err
+}
+
+func (c *TelegramChannel) deleteMessage(ctx context.Context, chatIDStr, msgID string) error {
+	chatID, err := parseChatID(chatIDStr)
+	if err != nil {
+		return err
+	}
+	id, err := strconv.Atoi(msgID)
+	if err != nil {
+		return err
+	}
+	return c.bot.DeleteMessage(ctx, tu.Delete(tu.ID(chatID), id))
+}
+
+// telegramRetryAfter returns the wait Telegram asks for when it answers
+// 429 Too Many Requests.
+func telegramRetryAfter(err error) time.Duration {
+	var apiErr *telegoapi.Error
+	if errors.As(err, &apiErr) && apiErr.Parameters != nil {
+		return time.Duration(apiErr.Parameters.RetryAfter) * time.Second
+	}
+	return 0
 }
 
 // HandleStreamDelta processes a single stream delta event.
 func (c *TelegramChannel) HandleStreamDelta(delta bus.StreamDelta) {
+	c.stream.add(delta)
 	if delta.Done {
-		// Keep streamMsgIDs so Send() can edit the stream message with the final content.
-		// Only clean up the buffer.
-		c.streamBuffers.Delete(delta.ChatID)
 		return
 	}
 
-	val, _ := c.streamBuffers.LoadOrStore(delta.ChatID, &streamBuffer{})
-	buf := val.(*streamBuffer)
-	buf.mu.Lock()
-	buf.content.WriteString(delta.Delta)
-	buf.dirty = true
-	buf.mu.Unlock()
-
 	// Stop thinking animation on first delta
 	if stop, ok := c.stopThinking.Load(delta.ChatID); ok {
 		if cf, ok := stop.(*thinkingCancel); ok && cf != nil {
@@ -274,6 +259,41 @@ func (c *TelegramChannel) HandleStreamDelta(delta bus.StreamDelta) {
 	}
 }
 
+// SendTyping shows the bot typing in a chat for five seconds, or until it
+// sends a message there.
+func (c *TelegramChannel) SendTyping(ctx context.Context, chatIDStr string) error {
+	chatID, err := parseChatID(chatIDStr)
+	if err != nil {
+		return err
+	}
+	return c.bot.SendChatAction(ctx, tu.ChatAction(tu.ID(chatID), telego.ChatActionTyping))
+}
+
+// ShowStatus shows what the agent is doing in the "Thinking..."
+// placeholder, posting a new one if the stream took it. The reply then
+// replaces the status.
+func (c *TelegramChannel) ShowStatus(ctx context.Context, chatIDStr, status string) error {
+	if pID, ok := c.placeholders.Load(chatIDStr); ok {
+		return c.streamEdit(ctx, chatIDStr, strconv.Itoa(pID.(int)), status)
+	}
+	id, err := c.streamPost(ctx, chatIDStr, status)
+	if err != nil {
+		return err
+	}
+	pID, err := strconv.Atoi(id)
+	if err != nil {
+		return err
+	}
+	c.placeholders.Store(chatIDStr, pID)
+	return nil
+}
+
+// ClearStatus leaves the status in the placeholder for Send to replace
+// with the reply.
+func (c *TelegramChannel) ClearStatus(ctx context.Context, chatIDStr string) error {
+	return nil
+}
+
 func (c *TelegramChannel) Stop(ctx context.Context) error {
 	logger.InfoC("telegram", "Stopping Telegram bot...")
 	c.setRunning(false)
@@ -293,6 +313,17 @@ func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 		return fmt.Errorf("invalid chat ID: %w", err)
 	}
 
+	// Reasoning goes in its own message, leaving the placeholder and the
+	// stream message for the reply.
+	if msg.Reasoning != "" {
+		if err := c.sendChunk(ctx, chatID, telegramReasoningHTML(msg.Reasoning), nil, nil); err != nil {
+			return err
+		}
+		if strings.TrimSpace(msg.Content) == "" && len(msg.Attachments) == 0 {
+			return nil
+		}
+	}
+
 	// Stop thinking animation
 	if stop, ok := c.stopThinking.Load(msg.ChatID); ok {
 		if cf, ok := stop.(*thinkingCancel); ok && cf != nil {
@@ -301,43 +332,125 @@ func (c *TelegramChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 		c.stopThinking.Delete(msg.ChatID)
 	}
 
-	htmlContent := markdownToTelegramHTML(msg.Content)
+	reply := telegramReplyParameters(msg.ReplyTo)
+	keyboard := c.inlineKeyboard(msg.Buttons)
 
-	// Find a message to edit: prefer stream message, then placeholder
-	var editMsgID int
-	if smID, ok := c.streamMsgIDs.LoadAndDelete(msg.ChatID); ok {
-		editMsgID = smID.(int)
-		// Also clean up placeholder since we're using the stream message
-		c.placeholders.Delete(msg.ChatID)
-	} else if pID, ok := c.placeholders.LoadAndDelete(msg.ChatID); ok {
-		editMsgID = pID.(int)
+	// Find messages to edit: the streamed ones, else the placeholder. The
+	// stream is taken even for a reply without text, such as a voice note,
+	// so the next turn does not continue it.
+	var editMsgIDs []int
+	for _, m := range c.stream.take(msg.ChatID) {
+		if id, err := strconv.Atoi(m.id); err == nil {
+			editMsgIDs = append(editMsgIDs, id)
+		}
+	}
+	if pID, ok := c.placeholders.LoadAndDelete(msg.ChatID); ok {
+		if len(editMsgIDs) == 0 {
+			editMsgIDs = []int{pID.(int)}
+		} else {
+			// A status posted after the stream took the placeholder.
+			c.deleteMessages(ctx, chatID, []int{pID.(int)})
+		}
+	}
+
+	// An edit cannot turn a message into a reply, and an attachment-only
+	// message has no text to put in it, so drop those messages instead.
+	if len(editMsgIDs) > 0 && (reply != nil || strings.TrimSpace(msg.Content) == "") {
+		c.deleteMessages(ctx, chatID, editMsgIDs)
+		editMsgIDs = nil
 	}
 
 	// Split into chunks if content exceeds Telegram's limit
-	chunks := splitTelegramMessage(htmlContent)
+	var chunks []string
+	if strings.TrimSpace(msg.Content) != "" {
+		chunks = splitTelegramMessage(markdownToTelegramHTML(msg.Content))
+	}
 
 	for i, chunk := range chunks {
-		if i == 0 && editMsgID != 0 {
-			// Edit the existing message with the first chunk
-			editMsg := tu.EditMessageText(tu.ID(chatID), editMsgID, chunk)
+		// Buttons go on the last message sent; the reply target on the first.
+		var markup *telego.InlineKeyboardMarkup
+		if i == len(chunks)-1 && len(msg.Attachments) == 0 {
+			markup = keyboard
+		}
+		if i < len(editMsgIDs) {
+			// Edit an existing message with the chunk
+			editMsg := tu.EditMessageText(tu.ID(chatID), editMsgIDs[i], chunk)
 			editMsg.ParseMode = telego.ModeHTML
+			editMsg.ReplyMarkup = markup
 			if _, err = c.bot.EditMessageText(ctx, editMsg); err == nil {
 				continue
 			}
 			// Edit failed — fall through to send as new message
 		}
-		if err := c.sendChunk(ctx, chatID, chunk); err != nil {
+		if err := c.sendChunk(ctx, chatID, chunk, reply, markup); err != nil {
 			return err
 		}
+		reply = nil
+	}
+	// Streamed messages the reply turned out shorter than.
+	if len(editMsgIDs) > len(chunks) {
+		c.deleteMessages(ctx, chatID, editMsgIDs[len(chunks):])
+	}
+
+	for i, a := range msg.Attachments {
+		var markup *telego.InlineKeyboardMarkup
+		if i == len(msg.Attachments)-1 {
+			markup = keyboard
+		}
+		if err := c.sendAttachment(ctx, chatID, a, reply, markup); err != nil {
+			logger.WarnCF("telegram", "Failed to send attachment, sending link instead", map[string]any{
+				"attachment": a.Name(),
+				"error":      err.Error(),
+			})
+			if err := c.sendChunk(ctx, chatID, escapeHTML(attachmentText([]bus.Attachment{a})), reply, markup); err != nil {
+				return err
+			}
+		}
+		reply = nil
 	}
 
 	return nil
 }
 
+func (c *TelegramChannel) deleteMessages(ctx context.Context, chatID int64, msgIDs []int) {
+	for _, id := range msgIDs {
+		if err := c.bot.DeleteMessage(ctx, tu.Delete(tu.ID(chatID), id)); err != nil {
+			logger.DebugCF("telegram", "Failed to delete message", map[string]any{"error": err.Error()})
+		}
+	}
+}
+
+// VoiceFormat reports that replies can be sent as Opus voice notes.
+func (c *TelegramChannel) VoiceFormat() string {
+	return voice.FormatOgg
+}
+
+// FoldsReasoning reports that reasoning traces are shown in expandable
+// blockquotes.
+func (c *TelegramChannel) FoldsReasoning() bool {
+	return true
+}
+
+// telegramReasoningHTML renders a reasoning trace as a collapsed blockquote.
+func telegramReasoningHTML(reasoning string) string {
+	text := utils.Truncate(strings.TrimSpace(reasoning), maxReasoningChars)
+	return "💭 <b>Thinking</b>\n<blockquote expandable>" + escapeHTML(text) + "</blockquote>"
+}
+
 // sendChunk sends a single message chunk, falling back to plain text if HTML fails.
-func (c *TelegramChannel) sendChunk(ctx context.Context, chatID int64, htmlContent string) error {
+func (c *TelegramChannel) sendChunk(
+	ctx context.Context,
+	chatID int64,
+	htmlContent string,
+	reply *telego.ReplyParameters,
+	markup *telego.InlineKeyboardMarkup,
+) error {
 	tgMsg := tu.Message(tu.ID(chatID), htmlContent)
 	tgMsg.ParseMode = telego.ModeHTML
+	tgMsg.ReplyParameters = reply
+	if markup != nil {
+		tgMsg.ReplyMarkup = markup
+	}
 
 	if _, err := c.bot.SendMessage(ctx, tgMsg); err != nil {
 		logger.DebugCF("telegram", "HTML send failed, falling back to plain text", map[string]any{
@@ -350,6 +463,142 @@ func (c *TelegramChannel) sendChunk(ctx context.Context, chatID int64, htmlConte
 	return nil
 }
 
+// sendAttachment uploads a file with the Telegram method matching its kind.
+// Remote files are passed by URL for Telegram to fetch.
+func (c *TelegramChannel) sendAttachment(
+	ctx context.Context,
+	chatID int64,
+	a bus.Attachment,
+	reply *telego.ReplyParameters,
+	markup *telego.InlineKeyboardMarkup,
+) error {
+	var file telego.InputFile
+	if a.URL != "" {
+		file = tu.FileFromURL(a.URL)
+	} else {
+		data, err := readAttachment(ctx, a)
+		if err != nil {
+			return err
+		}
+		file = tu.FileFromBytes(data, a.Name())
+	}
+
+	var rm telego.ReplyMarkup
+	if markup != nil {
+		rm = markup
+	}
+
+	var err error
+	switch a.Kind() {
+	case bus.AttachmentImage:
+		p := tu.Photo(tu.ID(chatID), file)
+		p.ReplyParameters, p.ReplyMarkup = reply, rm
+		_, err = c.bot.SendPhoto(ctx, p)
+	case bus.AttachmentAudio:
+		if a.ContentType() == "audio/ogg" || a.ContentType() == "application/ogg" {
+			p := tu.Voice(tu.ID(chatID), file)
+			p.ReplyParameters, p.ReplyMarkup = reply, rm
+			_, err = c.bot.SendVoice(ctx, p)
+		} else {
+			p := tu.Audio(tu.ID(chatID), file)
+			p.ReplyParameters, p.ReplyMarkup = reply, rm
+			_, err = c.bot.SendAudio(ctx, p)
+		}
+	case bus.AttachmentVideo:
+		p := tu.Video(tu.ID(chatID), file)
+		p.ReplyParameters, p.ReplyMarkup = reply, rm
+		_, err = c.bot.SendVideo(ctx, p)
+	default:
+		p := tu.Document(tu.ID(chatID), file)
+		p.ReplyParameters, p.ReplyMarkup = reply, rm
+		_, err = c.bot.SendDocument(ctx, p)
+	}
+	return err
+}
+
+// telegramReplyParameters returns the reply target for a message ID, or nil
+// when there is none. Replies to deleted messages are sent as plain messages.
+func telegramReplyParameters(messageID string) *telego.ReplyParameters {
+	id, err := strconv.Atoi(messageID)
+	if err != nil || id == 0 {
+		return nil
+	}
+	return &telego.ReplyParameters{MessageID: id, AllowSendingWithoutReply: true}
+}
+
+// telegramCallbackDataLimit is the maximum size of inline button callback data.
+const telegramCallbackDataLimit = 64
+
+// inlineKeyboard lays buttons out one per row. Replies that do not fit in
+// callback data are kept in memory and referenced by a short token.
+func (c *TelegramChannel) inlineKeyboard(buttons []bus.Button) *telego.InlineKeyboardMarkup {
+	if len(buttons) == 0 {
+		return nil
+	}
+	rows := make([][]telego.InlineKeyboardButton, 0, len(buttons))
+	for _, b := range buttons {
+		btn := tu.InlineKeyboardButton(b.Text)
+		if b.URL != "" {
+			btn = btn.WithURL(b.URL)
+		} else {
+			data := b.Reply()
+			if len(data) > telegramCallbackDataLimit || strings.HasPrefix(data, telegramCallbackRefPrefix) {
+				data = c.callbackData.store(telegramCallbackRefPrefix, data)
+			}
+			btn = btn.WithCallbackData(data)
+		}
+		rows = append(rows, tu.InlineKeyboardRow(btn))
+	}
+	return tu.InlineKeyboard(rows...)
+}
+
+// telegramCallbackRefPrefix marks callback data that refers to a stored reply.
+const telegramCallbackRefPrefix = "agentx:ref:"
+
+// handleCallbackQuery turns a button press into a user message carrying the
+// button's reply text.
+func (c *TelegramChannel) handleCallbackQuery(ctx context.Context, query telego.CallbackQuery) error {
+	data, expired := query.Data, false
+	if strings.HasPrefix(data, telegramCallbackRefPrefix) {
+		reply, ok := c.callbackData.load(data)
+		if ok {
+			data = reply
+		}
+		expired = !ok
+	}
+
+	answer := tu.CallbackQuery(query.ID)
+	if expired {
+		answer = answer.WithText("This button has expired")
+	}
+	if err := c.bot.AnswerCallbackQuery(ctx, answer); err != nil {
+		logger.DebugCF("telegram", "Failed to answer callback query", map[string]any{"error": err.Error()})
+	}
+	if query.Message == nil || expired {
+		return nil
+	}
+
+	chat := query.Message.GetChat()
+	peerKind := "direct"
+	peerID := fmt.Sprintf("%d", query.From.ID)
+	if chat.Type != "private" {
+		peerKind = "group"
+		peerID = fmt.Sprintf("%d", chat.ID)
+	}
+
+	c.HandleMessage(telegramSenderID(query.From), fmt.Sprintf("%d", chat.ID), data, nil, map[string]string{
+		"message_id": fmt.Sprintf("%d", query.Message.GetMessageID()),
+		"user_id":    fmt.Sprintf("%d", query.From.ID),
+		"username":   query.From.Username,
+		"first_name": query.From.FirstName,
+		"is_group":   fmt.Sprintf("%t", chat.Type != "private"),
+		"peer_kind":  peerKind,
+		"peer_id":    peerID,
+		"button":     "true",
+	})
+	return nil
+}
+
 // splitTelegramMessage splits HTML content into chunks that fit within Telegram's
 // message length limit. It splits on paragraph boundaries (\n\n) to keep
 // formatting intact.
@@ -385,6 +634,15 @@ func splitTelegramMessage(html string) []string {
 	return chunks
 }
 
+// telegramSenderID identifies a user as "id|username", or by ID alone when
+// they have no username, so allow lists can name either.
+func telegramSenderID(user telego.User) string {
+	if user.Username != "" {
+		return fmt.Sprintf("%d|%s", user.ID, user.Username)
+	}
+	return fmt.Sprintf("%d", user.ID)
+}
+
 func (c *TelegramChannel) handleMessage(ctx context.Context, message *telego.Message) error {
 	if message == nil {
 		return fmt.Errorf("message is nil")
@@ -395,10 +653,7 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, message *telego.Mes
 		return fmt.Errorf("message sender (user) is nil")
 	}
 
-	senderID := fmt.Sprintf("%d", user.ID)
-	if user.Username != "" {
-		senderID = fmt.Sprintf("%d|%s", user.ID, user.Username)
-	}
+	senderID := telegramSenderID(*user)
 
 	// check allowlist to avoid downloading attachments for rejected users
 	if !c.IsAllowed(senderID) {
@@ -457,32 +712,10 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, message *telego.Mes
 			localFiles = append(localFiles, voicePath)
 			mediaPaths = append(mediaPaths, voicePath)
 
-			var transcribedText string
-			if c.transcriber != nil && c.transcriber.IsAvailable() {
-				transcriberCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-				defer cancel()
-
-				result, err := c.transcriber.Transcribe(transcriberCtx, voicePath)
-				if err != nil {
-					logger.ErrorCF("telegram", "Voice transcription failed", map[string]any{
-						"error": err.Error(),
-						"path":  voicePath,
-					})
-					transcribedText = "[voice (transcription failed)]"
-				} else {
-					transcribedText = fmt.Sprintf("[voice transcription: %s]", result.Text)
-					logger.InfoCF("telegram", "Voice transcribed successfully", map[string]any{
-						"text": result.Text,
-					})
-				}
-			} else {
-				transcribedText = "[voice]"
-			}
-
 			if content != "" {
 				content += "\n"
 			}
-			content += transcribedText
+			content += c.voiceText(ctx, voicePath)
 		}
 	}
 
@@ -520,14 +753,6 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, message *telego.Mes
 		"preview":   utils.Truncate(content, 50),
 	})
 
-	// Thinking indicator
-	err := c.bot.SendChatAction(ctx, tu.ChatAction(tu.ID(chatID), telego.ChatActionTyping))
-	if err != nil {
-		logger.ErrorCF("telegram", "Failed to send chat action", map[string]any{
-			"error": err.Error(),
-		})
-	}
-
 	// Stop any previous thinking animation
 	chatIDStr := fmt.Sprintf("%d", chatID)
 	if prevStop, ok := c.stopThinking.Load(chatIDStr); ok {
@@ -540,10 +765,12 @@ func (c *TelegramChannel) handleMessage(ctx context.Context, message *telego.Mes
 	_, thinkCancel := context.WithTimeout(ctx, 5*time.Minute)
 	c.stopThinking.Store(chatIDStr, &thinkingCancel{fn: thinkCancel})
 
-	pMsg, err := c.bot.SendMessage(ctx, tu.Message(tu.ID(chatID), "Thinking... 💭"))
-	if err == nil {
-		pID := pMsg.MessageID
-		c.placeholders.Store(chatIDStr, pID)
+	if c.StatusMode() != config.StatusOff {
+		pMsg, err := c.bot.SendMessage(ctx, tu.Message(tu.ID(chatID), "Thinking... 💭"))
+		if err == nil {
+			pID := pMsg.MessageID
+			c.placeholders.Store(chatIDStr, pID)
+		}
 	}
 
 	peerKind := "direct"
@@ -0,0 +1,48 @@
+package channels
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mymmrac/telego"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+)
+
+func TestTelegramInlineKeyboard_BoundsStoredReplies(t *testing.T) {
+	c := &TelegramChannel{}
+	long := strings.Repeat("x", telegramCallbackDataLimit+1)
+
+	first := c.inlineKeyboard([]bus.Button{{Text: "Go", Value: long}, {Text: "Ok", Value: "ok"}})
+	ref := first.InlineKeyboard[0][0].CallbackData
+	if !strings.HasPrefix(ref, telegramCallbackRefPrefix) {
+		t.Fatalf("long reply should be stored, got callback data %q", ref)
+	}
+	if got := first.InlineKeyboard[1][0].CallbackData; got != "ok" {
+		t.Errorf("short reply = %q", got)
+	}
+	if v, ok := c.callbackData.load(ref); !ok || v != long {
+		t.Fatalf("stored reply = %v, %v", v, ok)
+	}
+
+	for i := 0; i < maxButtonReplies; i++ {
+		c.inlineKeyboard([]bus.Button{{Text: "Go", Value: long}})
+	}
+	if _, ok := c.callbackData.load(ref); ok {
+		t.Error("the oldest stored reply should have been dropped")
+	}
+	n := 0
+	c.callbackData.replies.Range(func(_, _ any) bool { n++; return true })
+	if n != maxButtonReplies {
+		t.Errorf("stored %d replies, want %d", n, maxButtonReplies)
+	}
+}
+
+func TestTelegramSenderID(t *testing.T) {
+	if got := telegramSenderID(telego.User{ID: 42, Username: "alice"}); got != "42|alice" {
+		t.Errorf("with username: %q", got)
+	}
+	if got := telegramSenderID(telego.User{ID: 42}); got != "42" {
+		t.Errorf("without username: %q", got)
+	}
+}
@@ -0,0 +1,391 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/config"
+	"github.com/Agentx-network/agentx/pkg/logger"
+	"github.com/Agentx-network/agentx/pkg/utils"
+)
+
+const (
+	webhookMaxBody       = 1 << 20
+	webhookMaxMedia      = 10
+	webhookDefaultSender = "webhook"
+)
+
+// webhookPlaceholder matches "{path}" in a content template.
+var webhookPlaceholder = regexp.MustCompile(`\{([A-Za-z0-9_.\-]+)\}`)
+
+// WebhookChannel turns authenticated HTTP POSTs on the gateway into
+// messages. Without a callback URL the request is held until the agent
+// replies and the reply is the response body; with one, the request is
+// acknowledged at once and replies are POSTed to the callback.
+type WebhookChannel struct {
+	*BaseChannel
+	config     config.WebhookConfig
+	httpClient *http.Client
+
+	mu      sync.Mutex
+	waiters map[string][]chan bus.OutboundMessage // chat ID -> requests awaiting a reply, oldest first
+}
+
+// webhookReply is the body of a synchronous response and of a callback.
+type webhookReply struct {
+	ChatID  string `json:"chat_id"`
+	Text    string `json:"text"`
+	ReplyTo string `json:"reply_to,omitempty"`
+}
+
+func NewWebhookChannel(cfg config.WebhookConfig, messageBus *bus.MessageBus) (*WebhookChannel, error) {
+	if cfg.Secret == "" && cfg.Token == "" {
+		return nil, fmt.Errorf("webhook needs a secret or a token")
+	}
+	if cfg.Path == "" || !strings.HasPrefix(cfg.Path, "/") {
+		return nil, fmt.Errorf("webhook path must start with /")
+	}
+	if cfg.Secret != "" && cfg.SignatureHeader == "" {
+		cfg.SignatureHeader = "X-Signature-256"
+	}
+	if cfg.ReplyTimeout <= 0 {
+		cfg.ReplyTimeout = 60
+	}
+	return &WebhookChannel{
+		BaseChannel: NewBaseChannel("webhook", cfg, messageBus, cfg.AllowFrom),
+		config:      cfg,
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+		waiters:     make(map[string][]chan bus.OutboundMessage),
+	}, nil
+}
+
+func (c *WebhookChannel) Start(ctx context.Context) error {
+	logger.InfoCF("webhook", "Starting Webhook channel", map[string]any{
+		"path":     c.config.Path,
+		"callback": c.config.CallbackURL != "",
+	})
+	c.setRunning(true)
+	return nil
+}
+
+func (c *WebhookChannel) Stop(ctx context.Context) error {
+	logger.InfoC("webhook", "Stopping Webhook channel")
+	c.setRunning(false)
+	return nil
+}
+
+// HTTPPath is where the gateway serves the webhook.
+func (c *WebhookChannel) HTTPPath() string {
+	return c.config.Path
+}
+
+func (c *WebhookChannel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !c.IsRunning() {
+		http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, webhookMaxBody+1))
+	if err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if len(body) > webhookMaxBody {
+		http.Error(w, "Request too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if !c.authorized(r, body) {
+		logger.WarnCF("webhook", "Rejected unauthenticated request", map[string]any{
+			"remote": r.RemoteAddr,
+		})
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	doc, err := parseWebhookBody(r.Header.Get("Content-Type"), body)
+	if err != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	senderID := webhookDefaultSender
+	if v, ok := jsonField(doc, c.config.SenderField); ok && v != "" {
+		senderID = v
+	}
+	chatID := senderID
+	if v, ok := jsonField(doc, c.config.ChatField); ok && v != "" {
+		chatID = v
+	}
+	if !c.IsAllowed(senderID) {
+		logger.DebugCF("webhook", "Message rejected by allowlist", map[string]any{
+			"sender": senderID,
+		})
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	content := c.content(doc, body)
+	mediaPaths := c.downloadMedia(doc)
+	defer removeTempFiles("webhook", mediaPaths)
+	if content == "" && len(mediaPaths) == 0 {
+		http.Error(w, "Empty message", http.StatusBadRequest)
+		return
+	}
+
+	var reply chan bus.OutboundMessage
+	if c.config.CallbackURL == "" {
+		reply = c.addWaiter(chatID)
+		defer c.removeWaiter(chatID, reply)
+	}
+
+	logger.DebugCF("webhook", "Received message", map[string]any{
+		"sender":  senderID,
+		"chat_id": chatID,
+		"preview": utils.Truncate(content, 50),
+	})
+	c.HandleMessage(senderID, chatID, content, mediaPaths, map[string]string{
+		"peer_kind": "direct",
+		"peer_id":   chatID,
+	})
+
+	if reply == nil {
+		writeWebhookJSON(w, http.StatusAccepted, map[string]string{"status": "accepted", "chat_id": chatID})
+		return
+	}
+	timer := time.NewTimer(time.Duration(c.config.ReplyTimeout) * time.Second)
+	defer timer.Stop()
+	select {
+	case msg := <-reply:
+		writeWebhookJSON(w, http.StatusOK, c.reply(msg))
+	case <-timer.C:
+		http.Error(w, "No reply in time", http.StatusGatewayTimeout)
+	case <-r.Context().Done():
+	}
+}
+
+// authorized checks the HMAC signature when a secret is set, then the
+// bearer token when one is set.
+func (c *WebhookChannel) authorized(r *http.Request, body []byte) bool {
+	if c.config.Secret != "" {
+		signature := strings.TrimPrefix(r.Header.Get(c.config.SignatureHeader), "sha256=")
+		got, err := hex.DecodeString(signature)
+		if err != nil || !hmac.Equal(got, webhookSignature(c.config.Secret, body)) {
+			return false
+		}
+	}
+	if c.config.Token != "" {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(c.config.Token)) != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+func webhookSignature(secret string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// parseWebhookBody decodes a JSON or form-encoded body into a document
+// paths can be looked up in. Form fields keep their first value.
+func parseWebhookBody(contentType string, body []byte) (any, error) {
+	if strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, err
+		}
+		doc := make(map[string]any, len(values))
+		for k := range values {
+			doc[k] = values.Get(k)
+		}
+		return doc, nil
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return map[string]any{}, nil
+	}
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// content builds the message text from the template, the content field or,
+// failing both, the whole body, so that unknown payloads still get through.
+func (c *WebhookChannel) content(doc any, body []byte) string {
+	if c.config.ContentTemplate != "" {
+		return strings.TrimSpace(webhookPlaceholder.ReplaceAllStringFunc(c.config.ContentTemplate, func(m string) string {
+			v, _ := jsonField(doc, m[1:len(m)-1])
+			return v
+		}))
+	}
+	if v, ok := jsonField(doc, c.config.ContentField); ok {
+		return strings.TrimSpace(v)
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// downloadMedia fetches the URLs at the media field, which holds a URL or
+// a list of them. With media_hosts set only those hosts are fetched from;
+// otherwise any public host is, and internal addresses are refused since the
+// URLs come from whoever can call the webhook.
+func (c *WebhookChannel) downloadMedia(doc any) []string {
+	v, ok := jsonLookup(doc, c.config.MediaField)
+	if !ok {
+		return nil
+	}
+	var urls []string
+	switch v := v.(type) {
+	case string:
+		urls = []string{v}
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				urls = append(urls, s)
+			}
+		}
+	}
+
+	var paths []string
+	for _, u := range urls {
+		if len(paths) == webhookMaxMedia {
+			break
+		}
+		parsed, err := url.Parse(u)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			continue
+		}
+		opts := utils.DownloadOptions{LoggerPrefix: "webhook", PublicOnly: true}
+		if len(c.config.MediaHosts) > 0 {
+			host := parsed.Hostname()
+			if !slices.ContainsFunc(c.config.MediaHosts, func(h string) bool { return strings.EqualFold(h, host) }) {
+				logger.WarnCF("webhook", "Media host not in media_hosts", map[string]any{"host": host})
+				continue
+			}
+			opts.PublicOnly = false
+		}
+		name := (bus.Attachment{URL: u}).Name()
+		if path := utils.DownloadFile(u, name, opts); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+func (c *WebhookChannel) Send(ctx context.Context, msg bus.OutboundMessage) error {
+	if !c.IsRunning() {
+		return fmt.Errorf("webhook channel not running")
+	}
+	if reply := c.takeWaiter(msg.ChatID); reply != nil {
+		reply <- msg
+		return nil
+	}
+	if c.config.CallbackURL == "" {
+		return fmt.Errorf("webhook: no request awaiting a reply in chat %s", msg.ChatID)
+	}
+
+	payload, err := json.Marshal(c.reply(msg))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.config.CallbackURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.config.CallbackToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.CallbackToken)
+	}
+	if c.config.Secret != "" {
+		req.Header.Set(c.config.SignatureHeader, "sha256="+hex.EncodeToString(webhookSignature(c.config.Secret, payload)))
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook callback: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook callback: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// reply renders an outbound message; files cannot be sent, so they are
+// listed in the text.
+func (c *WebhookChannel) reply(msg bus.OutboundMessage) webhookReply {
+	return webhookReply{
+		ChatID:  msg.ChatID,
+		Text:    appendFallbackText(msg.Content, msg.Attachments, msg.Buttons),
+		ReplyTo: msg.ReplyTo,
+	}
+}
+
+func (c *WebhookChannel) addWaiter(chatID string) chan bus.OutboundMessage {
+	reply := make(chan bus.OutboundMessage, 1)
+	c.mu.Lock()
+	c.waiters[chatID] = append(c.waiters[chatID], reply)
+	c.mu.Unlock()
+	return reply
+}
+
+// takeWaiter hands the oldest request waiting in a chat its reply.
+func (c *WebhookChannel) takeWaiter(chatID string) chan bus.OutboundMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	waiting := c.waiters[chatID]
+	if len(waiting) == 0 {
+		return nil
+	}
+	reply := waiting[0]
+	if len(waiting) == 1 {
+		delete(c.waiters, chatID)
+	} else {
+		c.waiters[chatID] = waiting[1:]
+	}
+	return reply
+}
+
+func (c *WebhookChannel) removeWaiter(chatID string, reply chan bus.OutboundMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	waiting := c.waiters[chatID]
+	for i, w := range waiting {
+		if w == reply {
+			waiting = append(waiting[:i:i], waiting[i+1:]...)
+			break
+		}
+	}
+	if len(waiting) == 0 {
+		delete(c.waiters, chatID)
+	} else {
+		c.waiters[chatID] = waiting
+	}
+}
+
+func writeWebhookJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
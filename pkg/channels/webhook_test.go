@@ -0,0 +1,258 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/config"
+)
+
+func newTestWebhookChannel(t *testing.T, cfg config.WebhookConfig) (*WebhookChannel, *httptest.Server, *bus.MessageBus) {
+	t.Helper()
+	if cfg.Path == "" {
+		cfg.Path = "/webhook/agentx"
+	}
+	msgBus := bus.NewMessageBus()
+	ch, err := NewWebhookChannel(cfg, msgBus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ch.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(ch.HTTPPath(), ch)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return ch, srv, msgBus
+}
+
+func signedRequest(t *testing.T, target, secret, body string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(webhookSignature(secret, []byte(body))))
+	}
+	return req
+}
+
+func TestWebhookChannel_SynchronousReply(t *testing.T) {
+	images := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("png-bytes"))
+	}))
+	defer images.Close()
+
+	ch, srv, msgBus := newTestWebhookChannel(t, config.WebhookConfig{
+		Secret:          "s3cret",
+		SignatureHeader: "X-Hub-Signature-256",
+		SenderField:     "sender.login",
+		ChatField:       "repository.full_name",
+		ContentTemplate: "{action} issue: {issue.title} ({issue.labels.0})",
+		MediaField:      "images",
+		MediaHosts:      []string{"127.0.0.1"},
+		ReplyTimeout:    5,
+	})
+	body := `{"action":"opened","sender":{"login":"octocat"},"repository":{"full_name":"acme/app"},
+		"issue":{"title":"Crash on start","labels":["bug"]},"images":["` + images.URL + `/screenshot.png"]}`
+
+	// Unsigned and wrongly signed requests are refused.
+	for _, secret := range []string{"", "wrong"} {
+		resp, err := http.DefaultClient.Do(signedRequest(t, srv.URL+"/webhook/agentx", secret, body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("secret %q: status = %d", secret, resp.StatusCode)
+		}
+	}
+
+	done := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.DefaultClient.Do(signedRequest(t, srv.URL+"/webhook/agentx", "s3cret", body))
+		if err != nil {
+			t.Error(err)
+		}
+		done <- resp
+	}()
+
+	msg := waitInbound(t, msgBus)
+	if msg.SenderID != "octocat" || msg.ChatID != "acme/app" || msg.Content != "opened issue: Crash on start (bug)" {
+		t.Errorf("msg = %+v", msg)
+	}
+	if len(msg.Media) != 1 {
+		t.Fatalf("media = %v", msg.Media)
+	}
+	if data, _ := os.ReadFile(msg.Media[0]); string(data) != "png-bytes" {
+		t.Errorf("media = %q", data)
+	}
+	os.Remove(msg.Media[0])
+
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "acme/app", Content: "Looks like a nil map."}); err != nil {
+		t.Fatal(err)
+	}
+	resp := <-done
+	if resp == nil {
+		t.FailNow()
+	}
+	defer resp.Body.Close()
+	var reply webhookReply
+	json.NewDecoder(resp.Body).Decode(&reply)
+	if resp.StatusCode != http.StatusOK || reply.ChatID != "acme/app" || reply.Text != "Looks like a nil map." {
+		t.Errorf("status = %d, reply = %+v", resp.StatusCode, reply)
+	}
+
+	// With nobody waiting and no callback, a reply has nowhere to go.
+	if err := ch.Send(context.Background(), bus.OutboundMessage{ChatID: "acme/app", Content: "late"}); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestWebhookChannel_RefusesInternalMedia(t *testing.T) {
+	fetched := make(chan struct{}, 2)
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched <- struct{}{}
+		w.Write([]byte("secret"))
+	}))
+	defer internal.Close()
+
+	_, srv, msgBus := newTestWebhookChannel(t, config.WebhookConfig{
+		Secret:          "s3cret",
+		SignatureHeader: "X-Hub-Signature-256",
+		MediaField:      "media",
+		CallbackURL:     "http://callback.invalid",
+	})
+	body := `{"media":["` + internal.URL + `/a.png","http://169.254.169.254/latest/meta-data/"]}`
+	resp, err := http.DefaultClient.Do(signedRequest(t, srv.URL+"/webhook/agentx", "s3cret", body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	msg := waitInbound(t, msgBus)
+	if len(msg.Media) != 0 {
+		t.Errorf("media = %v", msg.Media)
+	}
+	select {
+	case <-fetched:
+		t.Error("internal media URL was fetched")
+	default:
+	}
+}
+
+func TestWebhookChannel_CallbackAndForms(t *testing.T) {
+	callbacks := make(chan *http.Request, 1)
+	callbackBodies := make(chan []byte, 1)
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		callbacks <- r
+		callbackBodies <- body
+	}))
+	defer callback.Close()
+
+	ch, srv, msgBus := newTestWebhookChannel(t, config.WebhookConfig{
+		Token:         "tok",
+		SenderField:   "From",
+		ContentField:  "Body",
+		CallbackURL:   callback.URL,
+		CallbackToken: "cb-token",
+		AllowFrom:     []string{"+15551112222"},
+	})
+
+	post := func(target, token string, form url.Values) int {
+		req, err := http.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	sms := url.Values{"From": {"+15551112222"}, "Body": {"Is the door locked?"}}
+	if status := post(srv.URL+"/webhook/agentx", "nope", sms); status != http.StatusUnauthorized {
+		t.Errorf("bad token: status = %d", status)
+	}
+	// Tokens in the URL end up in access logs and are not accepted.
+	if status := post(srv.URL+"/webhook/agentx?token=tok", "", sms); status != http.StatusUnauthorized {
+		t.Errorf("query token: status = %d", status)
+	}
+	blocked := url.Values{"From": {"+15559990000"}, "Body": {"hi"}}
+	if status := post(srv.URL+"/webhook/agentx", "tok", blocked); status != http.StatusForbidden {
+		t.Errorf("blocked sender: status = %d", status)
+	}
+	if status := post(srv.URL+"/webhook/agentx", "tok", sms); status != http.StatusAccepted {
+		t.Errorf("status = %d", status)
+	}
+
+	msg := waitInbound(t, msgBus)
+	if msg.SenderID != "+15551112222" || msg.ChatID != "+15551112222" || msg.Content != "Is the door locked?" {
+		t.Errorf("msg = %+v", msg)
+	}
+
+	if err := ch.Send(context.Background(), bus.OutboundMessage{
+		ChatID:      msg.ChatID,
+		Content:     "Yes.",
+		Attachments: []bus.Attachment{{URL: "https://cams.example.com/door.jpg"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	req, body := <-callbacks, <-callbackBodies
+	if req.Header.Get("Authorization") != "Bearer cb-token" {
+		t.Errorf("authorization = %q", req.Header.Get("Authorization"))
+	}
+	var reply webhookReply
+	json.Unmarshal(body, &reply)
+	if reply.ChatID != "+15551112222" || !strings.HasPrefix(reply.Text, "Yes.") || !strings.Contains(reply.Text, "door.jpg") {
+		t.Errorf("callback = %s", body)
+	}
+}
+
+func TestWebhookChannel_BearerTimeoutAndRawBody(t *testing.T) {
+	_, srv, msgBus := newTestWebhookChannel(t, config.WebhookConfig{
+		Token:        "tok",
+		ContentField: "text",
+		ReplyTimeout: 1,
+	})
+
+	// A payload without the content field is passed on whole.
+	alert := `{"status":"firing","alerts":[{"labels":{"alertname":"DiskFull"}}]}`
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/webhook/agentx", bytes.NewBufferString(alert))
+	req.Header.Set("Authorization", "Bearer tok")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("status = %d", resp.StatusCode)
+	}
+	msg := waitInbound(t, msgBus)
+	if msg.SenderID != "webhook" || msg.ChatID != "webhook" || msg.Content != alert {
+		t.Errorf("msg = %+v", msg)
+	}
+
+	if _, err := NewWebhookChannel(config.WebhookConfig{Path: "/hook"}, bus.NewMessageBus()); err == nil {
+		t.Error("a webhook without secret or token should be refused")
+	}
+}
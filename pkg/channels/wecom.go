@@ -9,9 +9,11 @@ import (
 	"context"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/md5"
 	"crypto/sha1"
 	"encoding/base64"
 	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
@@ -183,7 +185,48 @@ func (c *WeComBotChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 		"preview": utils.Truncate(msg.Content, 100),
 	})
 
-	return c.sendWebhookReply(ctx, msg.ChatID, msg.Content)
+	// The webhook cannot reply to a specific message, upload files or show
+	// buttons; small JPEG/PNG images are sent inline and everything else is
+	// listed in the text.
+	var images [][]byte
+	var listed []bus.Attachment
+	for _, a := range msg.Attachments {
+		if ct := a.ContentType(); ct == "image/jpeg" || ct == "image/png" {
+			data, err := readAttachment(ctx, a)
+			if err == nil && len(data) <= wecomBotMaxImageBytes {
+				images = append(images, data)
+				continue
+			}
+		}
+		listed = append(listed, a)
+	}
+
+	if text := appendFallbackText(msg.Content, listed, msg.Buttons); text != "" || len(images) == 0 {
+		if err := c.sendWebhookReply(ctx, msg.ChatID, text); err != nil {
+			return err
+		}
+	}
+	for _, data := range images {
+		if err := c.sendWebhookImage(ctx, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wecomBotMaxImageBytes is the largest image the webhook accepts inline.
+const wecomBotMaxImageBytes = 2 << 20
+
+// sendWebhookImage sends an image through the webhook as base64 with its MD5.
+func (c *WeComBotChannel) sendWebhookImage(ctx context.Context, data []byte) error {
+	sum := md5.Sum(data)
+	return c.postWebhook(ctx, map[string]any{
+		"msgtype": "image",
+		"image": map[string]string{
+			"base64": base64.StdEncoding.EncodeToString(data),
+			"md5":    hex.EncodeToString(sum[:]),
+		},
+	})
 }
 
 // handleWebhook handles incoming webhook requests from WeCom
@@ -418,8 +461,12 @@ func (c *WeComBotChannel) sendWebhookReply(ctx context.Context, userID, content
 		MsgType: "text",
 	}
 	reply.Text.Content = content
+	return c.postWebhook(ctx, reply)
+}
 
-	jsonData, err := json.Marshal(reply)
+// postWebhook posts a message payload to the webhook URL.
+func (c *WeComBotChannel) postWebhook(ctx context.Context, payload any) error {
+	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal reply: %w", err)
 	}
@@ -11,6 +11,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"strings"
@@ -223,7 +224,105 @@ func (c *WeComAppChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 		"preview": utils.Truncate(msg.Content, 100),
 	})
 
-	return c.sendTextMessage(ctx, accessToken, msg.ChatID, msg.Content)
+	// WeCom app messages cannot quote a message or carry reply buttons, so
+	// buttons are listed in the text.
+	var uploaded []map[string]any
+	var listed []bus.Attachment
+	for _, a := range msg.Attachments {
+		mediaType := wecomMediaType(a)
+		mediaID, err := c.uploadMedia(ctx, accessToken, mediaType, a)
+		if err != nil {
+			logger.WarnCF("wecom_app", "Failed to upload attachment, listing it instead", map[string]any{
+				"attachment": a.Name(),
+				"error":      err.Error(),
+			})
+			listed = append(listed, a)
+			continue
+		}
+		uploaded = append(uploaded, map[string]any{
+			"touser":  msg.ChatID,
+			"msgtype": mediaType,
+			"agentid": c.config.AgentID,
+			mediaType: map[string]string{"media_id": mediaID},
+		})
+	}
+
+	if text := appendFallbackText(msg.Content, listed, msg.Buttons); text != "" || len(uploaded) == 0 {
+		if err := c.sendTextMessage(ctx, accessToken, msg.ChatID, text); err != nil {
+			return err
+		}
+	}
+	for _, m := range uploaded {
+		if err := c.postMessage(ctx, accessToken, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// wecomMediaType picks the WeCom media type for an attachment. Images,
+// voice and video have format restrictions; anything else is sent as a file.
+func wecomMediaType(a bus.Attachment) string {
+	switch ct := a.ContentType(); {
+	case ct == "image/jpeg" || ct == "image/png":
+		return "image"
+	case ct == "audio/amr":
+		return "voice"
+	case ct == "video/mp4":
+		return "video"
+	default:
+		return "file"
+	}
+}
+
+// uploadMedia uploads an attachment as temporary media and returns its media ID.
+func (c *WeComAppChannel) uploadMedia(ctx context.Context, accessToken, mediaType string, a bus.Attachment) (string, error) {
+	data, err := readAttachment(ctx, a)
+	if err != nil {
+		return "", err
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("media", a.Name())
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	apiURL := fmt.Sprintf("%s/cgi-bin/media/upload?access_token=%s&type=%s", wecomAPIBase, accessToken, mediaType)
+	reqCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, apiURL, &body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload media: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+		MediaID string `json:"media_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if result.ErrCode != 0 {
+		return "", fmt.Errorf("API error: %s (code: %d)", result.ErrMsg, result.ErrCode)
+	}
+	return result.MediaID, nil
 }
 
 // handleWebhook handles incoming webhook requests from WeCom
@@ -440,6 +539,19 @@ func (c *WeComAppChannel) processMessage(ctx context.Context, msg WeComXMLMessag
 	}
 
 	content := msg.Content
+	var mediaPaths []string
+	if msg.MsgType == "voice" {
+		content = "[voice]"
+		format := strings.ToLower(msg.Format)
+		if format == "" {
+			format = "amr"
+		}
+		if localPath := c.downloadMedia(msg.MediaId, "voice."+format); localPath != "" {
+			defer removeTempFiles("wecom_app", []string{localPath})
+			mediaPaths = append(mediaPaths, localPath)
+			content = c.voiceText(ctx, localPath)
+		}
+	}
 
 	logger.DebugCF("wecom_app", "Received message", map[string]any{
 		"sender_id": senderID,
@@ -448,7 +560,19 @@ func (c *WeComAppChannel) processMessage(ctx context.Context, msg WeComXMLMessag
 	})
 
 	// Handle the message through the base channel
-	c.HandleMessage(senderID, chatID, content, nil, metadata)
+	c.HandleMessage(senderID, chatID, content, mediaPaths, metadata)
+}
+
+// downloadMedia saves a file received in a message (by its media_id) and
+// returns the local path, or "" if it cannot be fetched.
+func (c *WeComAppChannel) downloadMedia(mediaID, filename string) string {
+	accessToken := c.getAccessToken()
+	if accessToken == "" || mediaID == "" {
+		return ""
+	}
+	apiURL := fmt.Sprintf("%s/cgi-bin/media/get?access_token=%s&media_id=%s",
+		wecomAPIBase, url.QueryEscape(accessToken), url.QueryEscape(mediaID))
+	return utils.DownloadFile(apiURL, filename, utils.DownloadOptions{LoggerPrefix: "wecom_app"})
 }
 
 // tokenRefreshLoop periodically refreshes the access token
@@ -518,16 +642,20 @@ func (c *WeComAppChannel) getAccessToken() string {
 
 // sendTextMessage sends a text message to a user
 func (c *WeComAppChannel) sendTextMessage(ctx context.Context, accessToken, userID, content string) error {
-	apiURL := fmt.Sprintf("%s/cgi-bin/message/send?access_token=%s", wecomAPIBase, accessToken)
-
 	msg := WeComTextMessage{
 		ToUser:  userID,
 		MsgType: "text",
 		AgentID: c.config.AgentID,
 	}
 	msg.Text.Content = content
+	return c.postMessage(ctx, accessToken, msg)
+}
+
+// postMessage sends a message payload through the message/send API.
+func (c *WeComAppChannel) postMessage(ctx context.Context, accessToken string, payload any) error {
+	apiURL := fmt.Sprintf("%s/cgi-bin/message/send?access_token=%s", wecomAPIBase, accessToken)
 
-	jsonData, err := json.Marshal(msg)
+	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
@@ -2,6 +2,7 @@ package channels
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -13,6 +14,7 @@ import (
 	"github.com/Agentx-network/agentx/pkg/bus"
 	"github.com/Agentx-network/agentx/pkg/config"
 	"github.com/Agentx-network/agentx/pkg/utils"
+	"github.com/Agentx-network/agentx/pkg/voice"
 )
 
 type WhatsAppChannel struct {
@@ -89,10 +91,36 @@ func (c *WhatsAppChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 		return fmt.Errorf("whatsapp connection not established")
 	}
 
+	// A bridge that takes media gets files inline, as it may not share this
+	// filesystem, and Ogg audio as a voice note (ptt). Other bridges, and
+	// files that cannot be read, get them listed in the text.
+	var media []map[string]any
+	var unsent []bus.Attachment
+	for _, a := range msg.Attachments {
+		if !c.config.BridgeMedia {
+			unsent = append(unsent, a)
+			continue
+		}
+		data, err := readAttachment(ctx, a)
+		if err != nil {
+			unsent = append(unsent, a)
+			continue
+		}
+		media = append(media, map[string]any{
+			"filename": a.Name(),
+			"mimetype": a.ContentType(),
+			"data":     base64.StdEncoding.EncodeToString(data),
+			"ptt":      a.ContentType() == "audio/ogg",
+		})
+	}
+
 	payload := map[string]any{
 		"type":    "message",
 		"to":      msg.ChatID,
-		"content": msg.Content,
+		"content": appendFallbackText(msg.Content, unsent, msg.Buttons),
+	}
+	if len(media) > 0 {
+		payload["media"] = media
 	}
 
 	data, err := json.Marshal(payload)
@@ -107,6 +135,15 @@ func (c *WhatsAppChannel) Send(ctx context.Context, msg bus.OutboundMessage) err
 	return nil
 }
 
+// VoiceFormat reports that replies can be sent as Opus voice notes when
+// the bridge takes media.
+func (c *WhatsAppChannel) VoiceFormat() string {
+	if !c.config.BridgeMedia {
+		return ""
+	}
+	return voice.FormatOgg
+}
+
 func (c *WhatsAppChannel) listen(ctx context.Context) {
 	for {
 		select {
@@ -173,6 +210,13 @@ func (c *WhatsAppChannel) handleIncomingMessage(msg map[string]any) {
 		}
 	}
 
+	// The bridge saves voice notes to disk; transcribe them like other channels do.
+	for _, path := range mediaPaths {
+		if utils.IsAudioFile(path, "") {
+			content = appendContent(content, c.voiceText(context.Background(), path))
+		}
+	}
+
 	metadata := make(map[string]string)
 	if messageID, ok := msg["id"].(string); ok {
 		metadata["message_id"] = messageID
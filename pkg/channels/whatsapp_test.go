@@ -0,0 +1,96 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
+	"github.com/Agentx-network/agentx/pkg/config"
+)
+
+// newFakeWhatsAppBridge accepts one connection and passes on every message
+// the channel writes to it.
+func newFakeWhatsAppBridge(t *testing.T) (string, <-chan map[string]any) {
+	t.Helper()
+	received := make(chan map[string]any, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var msg map[string]any
+			json.Unmarshal(data, &msg)
+			received <- msg
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http"), received
+}
+
+func sendToWhatsAppBridge(t *testing.T, cfg config.WhatsAppConfig, msg bus.OutboundMessage) map[string]any {
+	t.Helper()
+	url, received := newFakeWhatsAppBridge(t)
+	cfg.BridgeURL = url
+	ch, err := NewWhatsAppChannel(cfg, bus.NewMessageBus())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := ch.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer ch.Stop(ctx)
+
+	if err := ch.Send(ctx, msg); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-received:
+		return got
+	case <-time.After(2 * time.Second):
+		t.Fatal("the bridge got nothing")
+		return nil
+	}
+}
+
+func TestWhatsAppChannel_SendAttachments(t *testing.T) {
+	path := writeTempFile(t, "report.pdf", "PDFDATA")
+	msg := bus.OutboundMessage{
+		ChatID:      "123@s.whatsapp.net",
+		Content:     "Here it is.",
+		Attachments: []bus.Attachment{{Path: path}},
+	}
+
+	// A bridge without media support gets the file listed in the text.
+	got := sendToWhatsAppBridge(t, config.WhatsAppConfig{}, msg)
+	if got["content"] != "Here it is.\n\n📎 report.pdf" || got["media"] != nil {
+		t.Errorf("legacy bridge got %v", got)
+	}
+	if format := (&WhatsAppChannel{}).VoiceFormat(); format != "" {
+		t.Errorf("VoiceFormat() = %q for a bridge without media", format)
+	}
+
+	// One that takes media gets the file inline.
+	got = sendToWhatsAppBridge(t, config.WhatsAppConfig{BridgeMedia: true}, msg)
+	media, _ := got["media"].([]any)
+	if got["content"] != "Here it is." || len(media) != 1 {
+		t.Fatalf("media bridge got %v", got)
+	}
+	if m := media[0].(map[string]any); m["filename"] != "report.pdf" || m["data"] != "UERGREFUQQ==" || m["ptt"] != false {
+		t.Errorf("media = %v", m)
+	}
+}
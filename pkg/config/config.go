@@ -1,19 +1,19 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
-	"sync/atomic"
+	"reflect"
+	"strings"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 
 	"github.com/Agentx-network/agentx/pkg/fileutil"
 )
 
-// rrCounter is a global counter for round-robin load balancing across models.
-var rrCounter atomic.Uint64
-
 // FlexibleStringSlice is a []string that also accepts JSON numbers,
 // so allow_from can contain both "123" and 123.
 type FlexibleStringSlice []string
@@ -58,6 +58,9 @@ type Config struct {
 	Tools     ToolsConfig     `json:"tools"`
 	Heartbeat HeartbeatConfig `json:"heartbeat"`
 	Devices   DevicesConfig   `json:"devices"`
+	Voice     VoiceConfig     `json:"voice"`
+
+	LoadBalancing LoadBalancingConfig `json:"load_balancing"`
 }
 
 // MarshalJSON implements custom JSON marshaling for Config
@@ -78,7 +81,7 @@ func (c Config) MarshalJSON() ([]byte, error) {
 	}
 
 	// Only include session if not empty
-	if c.Session.DMScope != "" || len(c.Session.IdentityLinks) > 0 {
+	if !c.Session.IsEmpty() {
 		aux.Session = &c.Session
 	}
 
@@ -165,6 +168,54 @@ type AgentBinding struct {
 type SessionConfig struct {
 	DMScope       string              `json:"dm_scope,omitempty"`
 	IdentityLinks map[string][]string `json:"identity_links,omitempty"`
+	Store         string              `json:"store,omitempty"` // "json" (default) or "sqlite"
+
+	// Reset policies start a fresh conversation when a session goes stale.
+	IdleResetHours   int    `json:"idle_reset_hours,omitempty"`   // reset after N hours without messages
+	DailyResetAt     string `json:"daily_reset_at,omitempty"`     // local "HH:MM" time of a daily reset
+	MaxAgeHours      int    `json:"max_age_hours,omitempty"`      // reset N hours after the session started
+	SummarizeOnReset bool   `json:"summarize_on_reset,omitempty"` // write a summary to memory before resetting
+
+	Retention SessionRetentionConfig `json:"retention,omitempty"`
+}
+
+// IsEmpty reports whether no session options are set.
+func (s SessionConfig) IsEmpty() bool {
+	return s.DMScope == "" && len(s.IdentityLinks) == 0 && s.Store == "" &&
+		s.IdleResetHours == 0 && s.DailyResetAt == "" && s.MaxAgeHours == 0 &&
+		!s.SummarizeOnReset && s.Retention.AfterDays == 0 && s.Retention.Action == ""
+}
+
+// Validate checks the daily reset time and the retention settings.
+func (s SessionConfig) Validate() error {
+	if at := strings.TrimSpace(s.DailyResetAt); at != "" {
+		if _, err := time.Parse("15:04", at); err != nil {
+			return fmt.Errorf("daily_reset_at must be a HH:MM time, got %q", s.DailyResetAt)
+		}
+	}
+	if err := s.Retention.Validate(); err != nil {
+		return fmt.Errorf("retention.%w", err)
+	}
+	return nil
+}
+
+// SessionRetentionConfig removes session files that have not been used for a while,
+// keeping disk usage bounded. Archived sessions are deleted after as many days again.
+type SessionRetentionConfig struct {
+	AfterDays int    `json:"after_days,omitempty"` // 0 keeps sessions forever
+	Action    string `json:"action,omitempty"`     // "archive" (default) or "delete"
+}
+
+// Validate checks the retention period and action.
+func (r SessionRetentionConfig) Validate() error {
+	if r.AfterDays < 0 {
+		return fmt.Errorf("after_days must not be negative")
+	}
+	switch r.Action {
+	case "", "archive", "delete":
+		return nil
+	}
+	return fmt.Errorf("action must be archive or delete, got %q", r.Action)
 }
 
 type AgentDefaults struct {
@@ -179,6 +230,33 @@ type AgentDefaults struct {
 	MaxTokens           int      `json:"max_tokens"                      env:"AGENTX_AGENTS_DEFAULTS_MAX_TOKENS"`
 	Temperature         *float64 `json:"temperature,omitempty"           env:"AGENTX_AGENTS_DEFAULTS_TEMPERATURE"`
 	MaxToolIterations   int      `json:"max_tool_iterations"             env:"AGENTX_AGENTS_DEFAULTS_MAX_TOOL_ITERATIONS"`
+
+	// EmbeddingModel and RerankModel rank skill and session search results
+	// by meaning rather than by keyword or registry score.
+	EmbeddingModel          string   `json:"embedding_model,omitempty" env:"AGENTX_AGENTS_DEFAULTS_EMBEDDING_MODEL"`
+	EmbeddingModelFallbacks []string `json:"embedding_model_fallbacks,omitempty"`
+	RerankModel             string   `json:"rerank_model,omitempty"    env:"AGENTX_AGENTS_DEFAULTS_RERANK_MODEL"`
+
+	ModelRouting ModelRoutingConfig `json:"model_routing"`
+}
+
+// ModelRoutingConfig sends turns that look simple (chit-chat, quick lookups)
+// to a cheaper model, keeping the primary model for multi-step tool use and
+// coding.
+type ModelRoutingConfig struct {
+	Enabled              bool     `json:"enabled"                          env:"AGENTX_AGENTS_DEFAULTS_MODEL_ROUTING_ENABLED"`
+	SimpleModel          string   `json:"simple_model"                     env:"AGENTX_AGENTS_DEFAULTS_MODEL_ROUTING_SIMPLE_MODEL"`
+	SimpleModelFallbacks []string `json:"simple_model_fallbacks,omitempty"`
+	// Classifier is "heuristic" (default) or "model", which asks
+	// ClassifierModel (default: SimpleModel) to label each turn.
+	Classifier      string `json:"classifier,omitempty"       env:"AGENTX_AGENTS_DEFAULTS_MODEL_ROUTING_CLASSIFIER"`
+	ClassifierModel string `json:"classifier_model,omitempty" env:"AGENTX_AGENTS_DEFAULTS_MODEL_ROUTING_CLASSIFIER_MODEL"`
+	// Escalation lets the simple model hand a turn it cannot handle to the
+	// primary model.
+	Escalation bool `json:"escalation" env:"AGENTX_AGENTS_DEFAULTS_MODEL_ROUTING_ESCALATION"`
+	// MaxSimpleChars is the longest message the heuristic treats as simple
+	// (default 280).
+	MaxSimpleChars int `json:"max_simple_chars,omitempty" env:"AGENTX_AGENTS_DEFAULTS_MODEL_ROUTING_MAX_SIMPLE_CHARS"`
 }
 
 // GetModelName returns the effective model name for the agent defaults.
@@ -191,24 +269,110 @@ func (d *AgentDefaults) GetModelName() string {
 }
 
 type ChannelsConfig struct {
-	WhatsApp WhatsAppConfig `json:"whatsapp"`
-	Telegram TelegramConfig `json:"telegram"`
-	Feishu   FeishuConfig   `json:"feishu"`
-	Discord  DiscordConfig  `json:"discord"`
-	MaixCam  MaixCamConfig  `json:"maixcam"`
-	QQ       QQConfig       `json:"qq"`
-	DingTalk DingTalkConfig `json:"dingtalk"`
-	Slack    SlackConfig    `json:"slack"`
-	LINE     LINEConfig     `json:"line"`
-	OneBot   OneBotConfig   `json:"onebot"`
-	WeCom    WeComConfig    `json:"wecom"`
-	WeComApp WeComAppConfig `json:"wecom_app"`
+	WhatsApp   WhatsAppConfig   `json:"whatsapp"`
+	Telegram   TelegramConfig   `json:"telegram"`
+	Feishu     FeishuConfig     `json:"feishu"`
+	Discord    DiscordConfig    `json:"discord"`
+	MaixCam    MaixCamConfig    `json:"maixcam"`
+	QQ         QQConfig         `json:"qq"`
+	DingTalk   DingTalkConfig   `json:"dingtalk"`
+	Slack      SlackConfig      `json:"slack"`
+	LINE       LINEConfig       `json:"line"`
+	OneBot     OneBotConfig     `json:"onebot"`
+	WeCom      WeComConfig      `json:"wecom"`
+	WeComApp   WeComAppConfig   `json:"wecom_app"`
+	Email      EmailConfig      `json:"email"`
+	Matrix     MatrixConfig     `json:"matrix"`
+	Signal     SignalConfig     `json:"signal"`
+	MQTT       MQTTConfig       `json:"mqtt"`
+	Webhook    WebhookConfig    `json:"webhook"`
+	IRC        IRCConfig        `json:"irc"`
+	Mattermost MattermostConfig `json:"mattermost"`
+	RocketChat RocketChatConfig `json:"rocketchat"`
+}
+
+// ChannelStatus sets what a channel shows while the agent works on a reply:
+// "off", "typing" (the default) for the platform's typing indicator, or
+// "verbose" to also keep a message saying what the agent is doing.
+type ChannelStatus struct {
+	Status string `json:"status,omitempty" env:"STATUS"`
+}
+
+// Status modes of ChannelStatus.
+const (
+	StatusOff     = "off"
+	StatusTyping  = "typing"
+	StatusVerbose = "verbose"
+)
+
+// Validate checks that Status is one of the status modes.
+func (s ChannelStatus) Validate() error {
+	switch s.Status {
+	case "", StatusOff, StatusTyping, StatusVerbose:
+		return nil
+	}
+	return fmt.Errorf("status must be off, typing or verbose, got %q", s.Status)
+}
+
+// StatusMode returns the status mode, "typing" when unset.
+func (s ChannelStatus) StatusMode() string {
+	if s.Status == "" {
+		return StatusTyping
+	}
+	return s.Status
+}
+
+// ChannelAccounts lists extra accounts of a channel type, keyed by account
+// ID, each run as an instance of its own. An account takes the channel's
+// settings and overrides those it sets, so {"token": "..."} is enough for
+// a second bot.
+type ChannelAccounts map[string]json.RawMessage
+
+// ResolveAccounts returns the settings of each account: base, the
+// channel's own settings, overlaid with the account's.
+func ResolveAccounts[T any](base T, accounts ChannelAccounts) (map[string]T, error) {
+	if len(accounts) == 0 {
+		return nil, nil
+	}
+	// Going through a map drops base's own accounts and leaves every
+	// account with its own copies of base's slices.
+	data, err := json.Marshal(base)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "accounts")
+	if data, err = json.Marshal(fields); err != nil {
+		return nil, err
+	}
+
+	resolved := make(map[string]T, len(accounts))
+	for id, raw := range accounts {
+		var cfg T
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			return nil, fmt.Errorf("account %q: %w", id, err)
+		}
+		resolved[id] = cfg
+	}
+	return resolved, nil
 }
 
 type WhatsAppConfig struct {
-	Enabled   bool                `json:"enabled"    env:"AGENTX_CHANNELS_WHATSAPP_ENABLED"`
-	BridgeURL string              `json:"bridge_url" env:"AGENTX_CHANNELS_WHATSAPP_BRIDGE_URL"`
-	AllowFrom FlexibleStringSlice `json:"allow_from" env:"AGENTX_CHANNELS_WHATSAPP_ALLOW_FROM"`
+	Enabled     bool                `json:"enabled"      env:"AGENTX_CHANNELS_WHATSAPP_ENABLED"`
+	BridgeURL   string              `json:"bridge_url"   env:"AGENTX_CHANNELS_WHATSAPP_BRIDGE_URL"`
+	BridgeMedia bool                `json:"bridge_media" env:"AGENTX_CHANNELS_WHATSAPP_BRIDGE_MEDIA"` // the bridge takes files and voice notes in a "media" array
+	AllowFrom   FlexibleStringSlice `json:"allow_from"   env:"AGENTX_CHANNELS_WHATSAPP_ALLOW_FROM"`
+	Accounts    ChannelAccounts     `json:"accounts,omitempty"`
+
+	ChannelStatus `envPrefix:"AGENTX_CHANNELS_WHATSAPP_"`
 }
 
 type TelegramConfig struct {
@@ -216,6 +380,9 @@ type TelegramConfig struct {
 	Token     string              `json:"token"      env:"AGENTX_CHANNELS_TELEGRAM_TOKEN"`
 	Proxy     string              `json:"proxy"      env:"AGENTX_CHANNELS_TELEGRAM_PROXY"`
 	AllowFrom FlexibleStringSlice `json:"allow_from" env:"AGENTX_CHANNELS_TELEGRAM_ALLOW_FROM"`
+	Accounts  ChannelAccounts     `json:"accounts,omitempty"`
+
+	ChannelStatus `envPrefix:"AGENTX_CHANNELS_TELEGRAM_"`
 }
 
 type FeishuConfig struct {
@@ -225,6 +392,9 @@ type FeishuConfig struct {
 	EncryptKey        string              `json:"encrypt_key"        env:"AGENTX_CHANNELS_FEISHU_ENCRYPT_KEY"`
 	VerificationToken string              `json:"verification_token" env:"AGENTX_CHANNELS_FEISHU_VERIFICATION_TOKEN"`
 	AllowFrom         FlexibleStringSlice `json:"allow_from"         env:"AGENTX_CHANNELS_FEISHU_ALLOW_FROM"`
+	Accounts          ChannelAccounts     `json:"accounts,omitempty"`
+
+	ChannelStatus `envPrefix:"AGENTX_CHANNELS_FEISHU_"`
 }
 
 type DiscordConfig struct {
@@ -232,13 +402,20 @@ type DiscordConfig struct {
 	Token       string              `json:"token"        env:"AGENTX_CHANNELS_DISCORD_TOKEN"`
 	AllowFrom   FlexibleStringSlice `json:"allow_from"   env:"AGENTX_CHANNELS_DISCORD_ALLOW_FROM"`
 	MentionOnly bool                `json:"mention_only" env:"AGENTX_CHANNELS_DISCORD_MENTION_ONLY"`
+	Accounts    ChannelAccounts     `json:"accounts,omitempty"`
+
+	ChannelStatus `envPrefix:"AGENTX_CHANNELS_DISCORD_"`
 }
 
 type MaixCamConfig struct {
 	Enabled   bool                `json:"enabled"    env:"AGENTX_CHANNELS_MAIXCAM_ENABLED"`
 	Host      string              `json:"host"       env:"AGENTX_CHANNELS_MAIXCAM_HOST"`
 	Port      int                 `json:"port"       env:"AGENTX_CHANNELS_MAIXCAM_PORT"`
+	Audio     bool                `json:"audio"      env:"AGENTX_CHANNELS_MAIXCAM_AUDIO"` // the device firmware plays the "audio" field of commands
 	AllowFrom FlexibleStringSlice `json:"allow_from" env:"AGENTX_CHANNELS_MAIXCAM_ALLOW_FROM"`
+	Accounts  ChannelAccounts     `json:"accounts,omitempty"`
+
+	ChannelStatus `envPrefix:"AGENTX_CHANNELS_MAIXCAM_"`
 }
 
 type QQConfig struct {
@@ -246,6 +423,9 @@ type QQConfig struct {
 	AppID     string              `json:"app_id"     env:"AGENTX_CHANNELS_QQ_APP_ID"`
 	AppSecret string              `json:"app_secret" env:"AGENTX_CHANNELS_QQ_APP_SECRET"`
 	AllowFrom FlexibleStringSlice `json:"allow_from" env:"AGENTX_CHANNELS_QQ_ALLOW_FROM"`
+	Accounts  ChannelAccounts     `json:"accounts,omitempty"`
+
+	ChannelStatus `envPrefix:"AGENTX_CHANNELS_QQ_"`
 }
 
 type DingTalkConfig struct {
@@ -253,6 +433,9 @@ type DingTalkConfig struct {
 	ClientID     string              `json:"client_id"     env:"AGENTX_CHANNELS_DINGTALK_CLIENT_ID"`
 	ClientSecret string              `json:"client_secret" env:"AGENTX_CHANNELS_DINGTALK_CLIENT_SECRET"`
 	AllowFrom    FlexibleStringSlice `json:"allow_from"    env:"AGENTX_CHANNELS_DINGTALK_ALLOW_FROM"`
+	Accounts     ChannelAccounts     `json:"accounts,omitempty"`
+
+	ChannelStatus `envPrefix:"AGENTX_CHANNELS_DINGTALK_"`
 }
 
 type SlackConfig struct {
@@ -260,6 +443,9 @@ type SlackConfig struct {
 	BotToken  string              `json:"bot_token"  env:"AGENTX_CHANNELS_SLACK_BOT_TOKEN"`
 	AppToken  string              `json:"app_token"  env:"AGENTX_CHANNELS_SLACK_APP_TOKEN"`
 	AllowFrom FlexibleStringSlice `json:"allow_from" env:"AGENTX_CHANNELS_SLACK_ALLOW_FROM"`
+	Accounts  ChannelAccounts     `json:"accounts,omitempty"`
+
+	ChannelStatus `envPrefix:"AGENTX_CHANNELS_SLACK_"`
 }
 
 type LINEConfig struct {
@@ -270,6 +456,9 @@ type LINEConfig struct {
 	WebhookPort        int                 `json:"webhook_port"         env:"AGENTX_CHANNELS_LINE_WEBHOOK_PORT"`
 	WebhookPath        string              `json:"webhook_path"         env:"AGENTX_CHANNELS_LINE_WEBHOOK_PATH"`
 	AllowFrom          FlexibleStringSlice `json:"allow_from"           env:"AGENTX_CHANNELS_LINE_ALLOW_FROM"`
+	Accounts           ChannelAccounts     `json:"accounts,omitempty"`
+
+	ChannelStatus `envPrefix:"AGENTX_CHANNELS_LINE_"`
 }
 
 type OneBotConfig struct {
@@ -279,6 +468,9 @@ type OneBotConfig struct {
 	ReconnectInterval  int                 `json:"reconnect_interval"   env:"AGENTX_CHANNELS_ONEBOT_RECONNECT_INTERVAL"`
 	GroupTriggerPrefix []string            `json:"group_trigger_prefix" env:"AGENTX_CHANNELS_ONEBOT_GROUP_TRIGGER_PREFIX"`
 	AllowFrom          FlexibleStringSlice `json:"allow_from"           env:"AGENTX_CHANNELS_ONEBOT_ALLOW_FROM"`
+	Accounts           ChannelAccounts     `json:"accounts,omitempty"`
+
+	ChannelStatus `envPrefix:"AGENTX_CHANNELS_ONEBOT_"`
 }
 
 type WeComConfig struct {
@@ -291,6 +483,9 @@ type WeComConfig struct {
 	WebhookPath    string              `json:"webhook_path"     env:"AGENTX_CHANNELS_WECOM_WEBHOOK_PATH"`
 	AllowFrom      FlexibleStringSlice `json:"allow_from"       env:"AGENTX_CHANNELS_WECOM_ALLOW_FROM"`
 	ReplyTimeout   int                 `json:"reply_timeout"    env:"AGENTX_CHANNELS_WECOM_REPLY_TIMEOUT"`
+	Accounts       ChannelAccounts     `json:"accounts,omitempty"`
+
+	ChannelStatus `envPrefix:"AGENTX_CHANNELS_WECOM_"`
 }
 
 type WeComAppConfig struct {
@@ -305,6 +500,175 @@ type WeComAppConfig struct {
 	WebhookPath    string              `json:"webhook_path"     env:"AGENTX_CHANNELS_WECOM_APP_WEBHOOK_PATH"`
 	AllowFrom      FlexibleStringSlice `json:"allow_from"       env:"AGENTX_CHANNELS_WECOM_APP_ALLOW_FROM"`
 	ReplyTimeout   int                 `json:"reply_timeout"    env:"AGENTX_CHANNELS_WECOM_APP_REPLY_TIMEOUT"`
+	Accounts       ChannelAccounts     `json:"accounts,omitempty"`
+
+	ChannelStatus `envPrefix:"AGENTX_CHANNELS_WECOM_APP_"`
+}
+
+// EmailConfig configures the email channel: mail is read from an IMAP
+// mailbox and replies go out over SMTP. AllowFrom takes addresses or
+// domains ("example.com" or "@example.com"), and senders must pass DKIM or
+// DMARC at the receiving server unless AllowUnauthenticated is set.
+type EmailConfig struct {
+	Enabled              bool                `json:"enabled"               env:"AGENTX_CHANNELS_EMAIL_ENABLED"`
+	IMAPHost             string              `json:"imap_host"             env:"AGENTX_CHANNELS_EMAIL_IMAP_HOST"`
+	IMAPPort             int                 `json:"imap_port"             env:"AGENTX_CHANNELS_EMAIL_IMAP_PORT"`     // 993 for TLS, otherwise STARTTLS
+	IMAPInsecure         bool                `json:"imap_insecure"         env:"AGENTX_CHANNELS_EMAIL_IMAP_INSECURE"` // log in without TLS when the server lacks STARTTLS
+	SMTPHost             string              `json:"smtp_host"             env:"AGENTX_CHANNELS_EMAIL_SMTP_HOST"`
+	SMTPPort             int                 `json:"smtp_port"             env:"AGENTX_CHANNELS_EMAIL_SMTP_PORT"` // 465 for TLS, otherwise STARTTLS
+	Username             string              `json:"username"              env:"AGENTX_CHANNELS_EMAIL_USERNAME"`
+	Password             string              `json:"password"              env:"AGENTX_CHANNELS_EMAIL_PASSWORD"`
+	Address              string              `json:"address"               env:"AGENTX_CHANNELS_EMAIL_ADDRESS"` // From address, defaults to username
+	Mailbox              string              `json:"mailbox"               env:"AGENTX_CHANNELS_EMAIL_MAILBOX"`
+	PollInterval         int                 `json:"poll_interval"         env:"AGENTX_CHANNELS_EMAIL_POLL_INTERVAL"` // seconds, used when the server lacks IDLE
+	AllowFrom            FlexibleStringSlice `json:"allow_from"            env:"AGENTX_CHANNELS_EMAIL_ALLOW_FROM"`
+	AuthServID           string              `json:"auth_serv_id"          env:"AGENTX_CHANNELS_EMAIL_AUTH_SERV_ID"`          // trusted Authentication-Results, defaults to the topmost
+	AllowUnauthenticated bool                `json:"allow_unauthenticated" env:"AGENTX_CHANNELS_EMAIL_ALLOW_UNAUTHENTICATED"` // accept allow_from senders without DKIM/DMARC
+	Accounts             ChannelAccounts     `json:"accounts,omitempty"`
+
+	ChannelStatus `envPrefix:"AGENTX_CHANNELS_EMAIL_"`
+}
+
+// MatrixConfig configures the Matrix channel, which talks to a homeserver
+// over the client-server API as the user owning AccessToken.
+type MatrixConfig struct {
+	Enabled     bool                `json:"enabled"      env:"AGENTX_CHANNELS_MATRIX_ENABLED"`
+	Homeserver  string              `json:"homeserver"   env:"AGENTX_CHANNELS_MATRIX_HOMESERVER"` // e.g. https://matrix.example.org
+	UserID      string              `json:"user_id"      env:"AGENTX_CHANNELS_MATRIX_USER_ID"`    // looked up with whoami when empty
+	AccessToken string              `json:"access_token" env:"AGENTX_CHANNELS_MATRIX_ACCESS_TOKEN"`
+	AutoJoin    bool                `json:"auto_join"    env:"AGENTX_CHANNELS_MATRIX_AUTO_JOIN"`    // accept invites from allow-listed users
+	MentionOnly bool                `json:"mention_only" env:"AGENTX_CHANNELS_MATRIX_MENTION_ONLY"` // in rooms, answer only when mentioned; DMs always
+	CryptoStore string              `json:"crypto_store" env:"AGENTX_CHANNELS_MATRIX_CRYPTO_STORE"` // encryption keys database; ~/.agentx/matrix/crypto.db when empty
+	PickleKey   string              `json:"pickle_key"   env:"AGENTX_CHANNELS_MATRIX_PICKLE_KEY"`   // encrypts the keys in crypto_store
+	AllowFrom   FlexibleStringSlice `json:"allow_from"   env:"AGENTX_CHANNELS_MATRIX_ALLOW_FROM"`
+	Accounts    ChannelAccounts     `json:"accounts,omitempty"`
+
+	ChannelStatus `envPrefix:"AGENTX_CHANNELS_MATRIX_"`
+}
+
+// SignalConfig configures the Signal channel, which talks to a local
+// signal-cli daemon over its JSON-RPC socket. AllowFrom takes phone numbers
+// and account UUIDs.
+type SignalConfig struct {
+	Enabled     bool                `json:"enabled"      env:"AGENTX_CHANNELS_SIGNAL_ENABLED"`
+	Socket      string              `json:"socket"       env:"AGENTX_CHANNELS_SIGNAL_SOCKET"`       // unix socket path (daemon --socket) or host:port (daemon --tcp)
+	Account     string              `json:"account"      env:"AGENTX_CHANNELS_SIGNAL_ACCOUNT"`      // phone number, when the daemon serves several accounts
+	AckReaction string              `json:"ack_reaction" env:"AGENTX_CHANNELS_SIGNAL_ACK_REACTION"` // reaction shown while a message is handled, empty to disable
+	AllowFrom   FlexibleStringSlice `json:"allow_from"   env:"AGENTX_CHANNELS_SIGNAL_ALLOW_FROM"`
+	Accounts    ChannelAccounts     `json:"accounts,omitempty"`
+
+	ChannelStatus `envPrefix:"AGENTX_CHANNELS_SIGNAL_"`
+}
+
+// MQTTConfig configures the MQTT channel: messages on the subscribed topics
+// reach the agent and its replies are published to response topics.
+// AllowFrom matches the sender, which is the topic unless a topic sets
+// SenderField.
+type MQTTConfig struct {
+	Enabled     bool                `json:"enabled"      env:"AGENTX_CHANNELS_MQTT_ENABLED"`
+	Broker      string              `json:"broker"       env:"AGENTX_CHANNELS_MQTT_BROKER"` // tcp://host:1883, or ssl://host:8883 for TLS
+	ClientID    string              `json:"client_id"    env:"AGENTX_CHANNELS_MQTT_CLIENT_ID"`
+	Username    string              `json:"username"     env:"AGENTX_CHANNELS_MQTT_USERNAME"`
+	Password    string              `json:"password"     env:"AGENTX_CHANNELS_MQTT_PASSWORD"`
+	TLSCA       string              `json:"tls_ca"       env:"AGENTX_CHANNELS_MQTT_TLS_CA"`   // PEM file of the broker's CA
+	TLSCert     string              `json:"tls_cert"     env:"AGENTX_CHANNELS_MQTT_TLS_CERT"` // client certificate, with TLSKey
+	TLSKey      string              `json:"tls_key"      env:"AGENTX_CHANNELS_MQTT_TLS_KEY"`
+	TLSInsecure bool                `json:"tls_insecure" env:"AGENTX_CHANNELS_MQTT_TLS_INSECURE"` // skip certificate verification
+	QoS         int                 `json:"qos"          env:"AGENTX_CHANNELS_MQTT_QOS"`          // 0 or 1
+	Topics      []MQTTTopicConfig   `json:"topics"`
+	AllowFrom   FlexibleStringSlice `json:"allow_from"   env:"AGENTX_CHANNELS_MQTT_ALLOW_FROM"`
+	Accounts    ChannelAccounts     `json:"accounts,omitempty"`
+
+	ChannelStatus `envPrefix:"AGENTX_CHANNELS_MQTT_"`
+}
+
+// MQTTTopicConfig maps a subscription to a chat. Topic may use the + and #
+// wildcards. ResponseTopic may contain {topic}, the topic a message arrived
+// on, and {chat_id}.
+type MQTTTopicConfig struct {
+	Topic          string `json:"topic"`
+	ChatID         string `json:"chat_id,omitempty"`         // defaults to the topic a message arrived on
+	ResponseTopic  string `json:"response_topic,omitempty"`  // defaults to "{topic}/reply"
+	PayloadField   string `json:"payload_field,omitempty"`   // dotted path of the text in JSON payloads, e.g. "data.text"
+	SenderField    string `json:"sender_field,omitempty"`    // dotted path of the sender in JSON payloads
+	ResponseFormat string `json:"response_format,omitempty"` // "text" (default) or "json"
+	Retain         bool   `json:"retain,omitempty"`          // publish replies as retained messages
+}
+
+// WebhookConfig configures the generic webhook channel. It receives POSTs
+// on the gateway at Path, authenticated by an HMAC signature of the body
+// (Secret) or a bearer token (Token), and maps fields of the JSON or form
+// body to a message by dotted paths such as "alerts.0.labels.alertname".
+// Replies are POSTed to CallbackURL, or returned in the response when it is
+// empty.
+type WebhookConfig struct {
+	Enabled         bool                `json:"enabled"          env:"AGENTX_CHANNELS_WEBHOOK_ENABLED"`
+	Path            string              `json:"path"             env:"AGENTX_CHANNELS_WEBHOOK_PATH"`
+	Secret          string              `json:"secret"           env:"AGENTX_CHANNELS_WEBHOOK_SECRET"`           // HMAC-SHA256 key
+	SignatureHeader string              `json:"signature_header" env:"AGENTX_CHANNELS_WEBHOOK_SIGNATURE_HEADER"` // e.g. X-Hub-Signature-256; hex digest, "sha256=" optional
+	Token           string              `json:"token"            env:"AGENTX_CHANNELS_WEBHOOK_TOKEN"`            // sent as Authorization: Bearer
+	SenderField     string              `json:"sender_field"     env:"AGENTX_CHANNELS_WEBHOOK_SENDER_FIELD"`
+	ChatField       string              `json:"chat_field"       env:"AGENTX_CHANNELS_WEBHOOK_CHAT_FIELD"`       // chat defaults to the sender
+	ContentField    string              `json:"content_field"    env:"AGENTX_CHANNELS_WEBHOOK_CONTENT_FIELD"`    // the whole body when missing
+	ContentTemplate string              `json:"content_template" env:"AGENTX_CHANNELS_WEBHOOK_CONTENT_TEMPLATE"` // e.g. "{action}: {issue.title}", overrides ContentField
+	MediaField      string              `json:"media_field"      env:"AGENTX_CHANNELS_WEBHOOK_MEDIA_FIELD"`      // a URL or a list of URLs
+	MediaHosts      FlexibleStringSlice `json:"media_hosts"      env:"AGENTX_CHANNELS_WEBHOOK_MEDIA_HOSTS"`      // hosts media may come from; any public host when empty
+	CallbackURL     string              `json:"callback_url"     env:"AGENTX_CHANNELS_WEBHOOK_CALLBACK_URL"`
+	CallbackToken   string              `json:"callback_token"   env:"AGENTX_CHANNELS_WEBHOOK_CALLBACK_TOKEN"`
+	ReplyTimeout    int                 `json:"reply_timeout"    env:"AGENTX_CHANNELS_WEBHOOK_REPLY_TIMEOUT"` // seconds to wait for a synchronous reply
+	AllowFrom       FlexibleStringSlice `json:"allow_from"       env:"AGENTX_CHANNELS_WEBHOOK_ALLOW_FROM"`
+	Accounts        ChannelAccounts     `json:"accounts,omitempty"`
+}
+
+// IRCConfig configures the IRC channel. In channels the agent answers when
+// its nick is mentioned; private messages are always answered. AllowFrom
+// takes nicks and, on networks that tag messages with it, account names.
+type IRCConfig struct {
+	Enabled      bool                `json:"enabled"       env:"AGENTX_CHANNELS_IRC_ENABLED"`
+	Server       string              `json:"server"        env:"AGENTX_CHANNELS_IRC_SERVER"` // host:port
+	TLS          bool                `json:"tls"           env:"AGENTX_CHANNELS_IRC_TLS"`
+	Nick         string              `json:"nick"          env:"AGENTX_CHANNELS_IRC_NICK"`
+	Username     string              `json:"username"      env:"AGENTX_CHANNELS_IRC_USERNAME"`  // defaults to the nick
+	RealName     string              `json:"real_name"     env:"AGENTX_CHANNELS_IRC_REAL_NAME"` // defaults to the nick
+	Password     string              `json:"password"      env:"AGENTX_CHANNELS_IRC_PASSWORD"`  // server password (PASS)
+	SASLUser     string              `json:"sasl_user"     env:"AGENTX_CHANNELS_IRC_SASL_USER"`
+	SASLPassword string              `json:"sasl_password" env:"AGENTX_CHANNELS_IRC_SASL_PASSWORD"`
+	Channels     FlexibleStringSlice `json:"channels"      env:"AGENTX_CHANNELS_IRC_CHANNELS"` // "#chan", or "#chan key"
+	AllowFrom    FlexibleStringSlice `json:"allow_from"    env:"AGENTX_CHANNELS_IRC_ALLOW_FROM"`
+	Accounts     ChannelAccounts     `json:"accounts,omitempty"`
+
+	ChannelStatus `envPrefix:"AGENTX_CHANNELS_IRC_"`
+}
+
+// MattermostConfig configures the Mattermost channel, which listens on the
+// WebSocket API and posts through the REST API as a bot or user account.
+// AllowFrom takes user IDs and usernames.
+type MattermostConfig struct {
+	Enabled       bool                `json:"enabled"         env:"AGENTX_CHANNELS_MATTERMOST_ENABLED"`
+	URL           string              `json:"url"             env:"AGENTX_CHANNELS_MATTERMOST_URL"`             // e.g. https://chat.example.com
+	Token         string              `json:"token"           env:"AGENTX_CHANNELS_MATTERMOST_TOKEN"`           // bot or personal access token
+	MentionOnly   bool                `json:"mention_only"    env:"AGENTX_CHANNELS_MATTERMOST_MENTION_ONLY"`    // in channels, answer only when mentioned or in a thread already joined; DMs always
+	ReplyInThread bool                `json:"reply_in_thread" env:"AGENTX_CHANNELS_MATTERMOST_REPLY_IN_THREAD"` // answer channel posts in a thread of their own
+	AllowFrom     FlexibleStringSlice `json:"allow_from"      env:"AGENTX_CHANNELS_MATTERMOST_ALLOW_FROM"`
+	Accounts      ChannelAccounts     `json:"accounts,omitempty"`
+
+	ChannelStatus `envPrefix:"AGENTX_CHANNELS_MATTERMOST_"`
+}
+
+// RocketChatConfig configures the Rocket.Chat channel, which listens on the
+// realtime (DDP) WebSocket API and posts through the REST API. It logs in
+// with a personal access token. AllowFrom takes user IDs and usernames.
+type RocketChatConfig struct {
+	Enabled       bool                `json:"enabled"         env:"AGENTX_CHANNELS_ROCKETCHAT_ENABLED"`
+	URL           string              `json:"url"             env:"AGENTX_CHANNELS_ROCKETCHAT_URL"` // e.g. https://chat.example.com
+	UserID        string              `json:"user_id"         env:"AGENTX_CHANNELS_ROCKETCHAT_USER_ID"`
+	Token         string              `json:"token"           env:"AGENTX_CHANNELS_ROCKETCHAT_TOKEN"`           // personal access token
+	MentionOnly   bool                `json:"mention_only"    env:"AGENTX_CHANNELS_ROCKETCHAT_MENTION_ONLY"`    // in channels, answer only when mentioned or in a thread already joined; DMs always
+	ReplyInThread bool                `json:"reply_in_thread" env:"AGENTX_CHANNELS_ROCKETCHAT_REPLY_IN_THREAD"` // answer channel messages in a thread of their own
+	AllowFrom     FlexibleStringSlice `json:"allow_from"      env:"AGENTX_CHANNELS_ROCKETCHAT_ALLOW_FROM"`
+	Accounts      ChannelAccounts     `json:"accounts,omitempty"`
+
+	ChannelStatus `envPrefix:"AGENTX_CHANNELS_ROCKETCHAT_"`
 }
 
 type HeartbeatConfig struct {
@@ -312,11 +676,62 @@ type HeartbeatConfig struct {
 	Interval int  `json:"interval" env:"AGENTX_HEARTBEAT_INTERVAL"` // minutes, min 5
 }
 
+// LoadBalancingConfig controls how requests are spread over model_list
+// entries that share a model_name.
+type LoadBalancingConfig struct {
+	// Strategy is "round_robin" (default), "weighted", "least_latency" or
+	// "least_outstanding".
+	Strategy string `json:"strategy,omitempty" env:"AGENTX_LOAD_BALANCING_STRATEGY"`
+	// MaxWait is how many seconds a request waits for RPM/TPM budget when
+	// every entry is at its limit (default 30).
+	MaxWait int `json:"max_wait,omitempty" env:"AGENTX_LOAD_BALANCING_MAX_WAIT"`
+}
+
 type DevicesConfig struct {
 	Enabled    bool `json:"enabled"     env:"AGENTX_DEVICES_ENABLED"`
 	MonitorUSB bool `json:"monitor_usb" env:"AGENTX_DEVICES_MONITOR_USB"`
 }
 
+// VoiceConfig configures how voice messages are handled on channels.
+type VoiceConfig struct {
+	STT STTConfig `json:"stt"`
+	TTS TTSConfig `json:"tts"`
+}
+
+// STTConfig selects the speech-to-text backend that transcribes voice
+// messages. With no provider set, Groq is used when a Groq key exists.
+type STTConfig struct {
+	// Provider is "groq", "openai" (any OpenAI-compatible
+	// /audio/transcriptions endpoint, local whisper servers included) or
+	// "whisper_cpp".
+	Provider string `json:"provider,omitempty" env:"AGENTX_VOICE_STT_PROVIDER"`
+	APIBase  string `json:"api_base,omitempty" env:"AGENTX_VOICE_STT_API_BASE"`
+	APIKey   string `json:"api_key,omitempty"  env:"AGENTX_VOICE_STT_API_KEY"`
+	Model    string `json:"model,omitempty"    env:"AGENTX_VOICE_STT_MODEL"`
+	Language string `json:"language,omitempty" env:"AGENTX_VOICE_STT_LANGUAGE"` // ISO-639-1, empty to auto-detect
+	// Binary and ModelPath locate whisper.cpp (binary defaults to
+	// whisper-cli) and its ggml model.
+	Binary    string `json:"binary,omitempty"     env:"AGENTX_VOICE_STT_BINARY"`
+	ModelPath string `json:"model_path,omitempty" env:"AGENTX_VOICE_STT_MODEL_PATH"`
+}
+
+// TTSConfig selects the text-to-speech backend that speaks replies in
+// chats where /voice is on. With no provider set, OpenAI is used when an
+// OpenAI key exists.
+type TTSConfig struct {
+	// Provider is "openai" (any OpenAI-compatible /audio/speech endpoint)
+	// or "piper".
+	Provider string `json:"provider,omitempty" env:"AGENTX_VOICE_TTS_PROVIDER"`
+	APIBase  string `json:"api_base,omitempty" env:"AGENTX_VOICE_TTS_API_BASE"`
+	APIKey   string `json:"api_key,omitempty"  env:"AGENTX_VOICE_TTS_API_KEY"`
+	Model    string `json:"model,omitempty"    env:"AGENTX_VOICE_TTS_MODEL"`
+	Voice    string `json:"voice,omitempty"    env:"AGENTX_VOICE_TTS_VOICE"`
+	// Binary and ModelPath locate piper (binary defaults to piper) and its
+	// .onnx voice.
+	Binary    string `json:"binary,omitempty"     env:"AGENTX_VOICE_TTS_BINARY"`
+	ModelPath string `json:"model_path,omitempty" env:"AGENTX_VOICE_TTS_MODEL_PATH"`
+}
+
 type ProvidersConfig struct {
 	Anthropic     ProviderConfig       `json:"anthropic"`
 	OpenAI        OpenAIProviderConfig `json:"openai"`
@@ -407,8 +822,30 @@ type ModelConfig struct {
 
 	// Optional optimizations
 	RPM            int    `json:"rpm,omitempty"`              // Requests per minute limit
+	TPM            int    `json:"tpm,omitempty"`              // Tokens per minute limit
+	Weight         int    `json:"weight,omitempty"`           // Share of traffic under the weighted strategy (default 1)
 	MaxTokensField string `json:"max_tokens_field,omitempty"` // Field name for max tokens (e.g., "max_completion_tokens")
 	RequestTimeout int    `json:"request_timeout,omitempty"`
+
+	// Capabilities
+	Vision    *bool            `json:"vision,omitempty"`    // Accepts image input; guessed from the model ID when unset
+	Audio     *bool            `json:"audio,omitempty"`     // Accepts audio input; guessed from the model ID when unset
+	PDF       *bool            `json:"pdf,omitempty"`       // Reads PDF documents; guessed from the model ID when unset
+	Reasoning *ReasoningConfig `json:"reasoning,omitempty"` // Enables thinking on reasoning models
+
+	// Ollama
+	KeepAlive string `json:"keep_alive,omitempty"` // How long the server keeps the model loaded (e.g. "10m", "-1")
+	NumCtx    int    `json:"num_ctx,omitempty"`    // Context window; detected from the model when unset
+	AutoPull  bool   `json:"auto_pull,omitempty"`  // Pull the model on first use if it is not installed
+}
+
+// ReasoningConfig sets how much a reasoning model thinks before answering.
+// Providers that take a token budget (Anthropic, Gemini) derive it from the
+// effort when BudgetTokens is unset; providers that take an effort ignore
+// BudgetTokens.
+type ReasoningConfig struct {
+	Effort       string `json:"effort,omitempty"`        // "minimal", "low", "medium" or "high"
+	BudgetTokens int    `json:"budget_tokens,omitempty"` // Tokens the model may spend thinking
 }
 
 // Validate checks if the ModelConfig has all required fields.
@@ -419,6 +856,16 @@ func (c *ModelConfig) Validate() error {
 	if c.Model == "" {
 		return fmt.Errorf("model is required")
 	}
+	if r := c.Reasoning; r != nil {
+		switch r.Effort {
+		case "", "minimal", "low", "medium", "high":
+		default:
+			return fmt.Errorf("reasoning.effort must be minimal, low, medium or high, got %q", r.Effort)
+		}
+		if r.BudgetTokens < 0 {
+			return fmt.Errorf("reasoning.budget_tokens must not be negative")
+		}
+	}
 	return nil
 }
 
@@ -546,6 +993,12 @@ func LoadConfig(path string) (*Config, error) {
 	if err := cfg.ValidateModelList(); err != nil {
 		return nil, err
 	}
+	if err := cfg.ValidateChannels(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Session.Validate(); err != nil {
+		return nil, fmt.Errorf("session.%w", err)
+	}
 
 	return cfg, nil
 }
@@ -625,21 +1078,26 @@ func expandHome(path string) string {
 	return path
 }
 
-// GetModelConfig returns the ModelConfig for the given model name.
-// If multiple configs exist with the same model_name, it uses round-robin
-// selection for load balancing. Returns an error if the model is not found.
+// GetModelConfig returns the first ModelConfig with the given model name.
+// Requests to a model_name with several entries are balanced across all of
+// them by the provider layer; use GetModelConfigs to get the whole pool.
+// Returns an error if the model is not found.
 func (c *Config) GetModelConfig(modelName string) (*ModelConfig, error) {
+	matches, err := c.GetModelConfigs(modelName)
+	if err != nil {
+		return nil, err
+	}
+	return &matches[0], nil
+}
+
+// GetModelConfigs returns every ModelConfig with the given model name, in
+// model_list order. Returns an error if the model is not found.
+func (c *Config) GetModelConfigs(modelName string) ([]ModelConfig, error) {
 	matches := c.findMatches(modelName)
 	if len(matches) == 0 {
 		return nil, fmt.Errorf("model %q not found in model_list or providers", modelName)
 	}
-	if len(matches) == 1 {
-		return &matches[0], nil
-	}
-
-	// Multiple configs - use round-robin for load balancing
-	idx := rrCounter.Add(1) % uint64(len(matches))
-	return &matches[idx], nil
+	return matches, nil
 }
 
 // findMatches finds all ModelConfig entries with the given model_name.
@@ -687,3 +1145,40 @@ func (c *Config) ValidateModelList() error {
 	}
 	return nil
 }
+
+// ValidateChannels checks the status setting of every channel and of each
+// of its accounts.
+func (c *Config) ValidateChannels() error {
+	channels := reflect.ValueOf(c.Channels)
+	for i := 0; i < channels.NumField(); i++ {
+		name, _, _ := strings.Cut(channels.Type().Field(i).Tag.Get("json"), ",")
+		channel := channels.Field(i)
+		if status, ok := channelField[ChannelStatus](channel, "ChannelStatus"); ok {
+			if err := status.Validate(); err != nil {
+				return fmt.Errorf("channels.%s.%w", name, err)
+			}
+		}
+		accounts, _ := channelField[ChannelAccounts](channel, "Accounts")
+		for id, raw := range accounts {
+			var status ChannelStatus
+			if err := json.Unmarshal(raw, &status); err != nil {
+				return fmt.Errorf("channels.%s.accounts.%s: %w", name, id, err)
+			}
+			if err := status.Validate(); err != nil {
+				return fmt.Errorf("channels.%s.accounts.%s.%w", name, id, err)
+			}
+		}
+	}
+	return nil
+}
+
+// channelField returns the named field of a channel config, if it has one.
+func channelField[T any](channel reflect.Value, name string) (T, bool) {
+	field := channel.FieldByName(name)
+	if !field.IsValid() {
+		var zero T
+		return zero, false
+	}
+	value, ok := field.Interface().(T)
+	return value, ok
+}
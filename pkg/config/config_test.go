@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -422,3 +423,133 @@ func TestDefaultConfig_DMScope(t *testing.T) {
 		t.Errorf("Session.DMScope = %q, want 'per-channel-peer'", cfg.Session.DMScope)
 	}
 }
+
+func TestResolveAccounts_InheritsAndOverrides(t *testing.T) {
+	var cfg Config
+	data := `{"channels":{"telegram":{
+		"enabled": true,
+		"token": "main-token",
+		"allow_from": ["alice"],
+		"accounts": {
+			"brand-b": {"token": "b-token"},
+			"brand-c": {"token": "c-token", "allow_from": ["carol"], "enabled": false}
+		}
+	}}}`
+	if err := json.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	accounts, err := ResolveAccounts(cfg.Channels.Telegram, cfg.Channels.Telegram.Accounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, c := accounts["brand-b"], accounts["brand-c"]
+	if !b.Enabled || b.Token != "b-token" || len(b.AllowFrom) != 1 || b.AllowFrom[0] != "alice" {
+		t.Errorf("brand-b = %+v", b)
+	}
+	if c.Enabled || c.Token != "c-token" || len(c.AllowFrom) != 1 || c.AllowFrom[0] != "carol" {
+		t.Errorf("brand-c = %+v", c)
+	}
+	if b.Accounts != nil {
+		t.Errorf("accounts should not nest, got %v", b.Accounts)
+	}
+
+	b.AllowFrom[0] = "mallory"
+	if cfg.Channels.Telegram.AllowFrom[0] != "alice" {
+		t.Error("accounts should not share slices with the channel's settings")
+	}
+}
+
+func TestResolveAccounts_BadAccount(t *testing.T) {
+	_, err := ResolveAccounts(TelegramConfig{}, ChannelAccounts{"brand-b": json.RawMessage(`{"token": 42}`)})
+	if err == nil || !strings.Contains(err.Error(), `account "brand-b"`) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestLoadConfig_SessionValidation(t *testing.T) {
+	tests := []struct {
+		session string
+		wantErr string
+	}{
+		{`{"daily_reset_at": "04:30"}`, ""},
+		{`{"daily_reset_at": "4am"}`, "session.daily_reset_at"},
+		{`{"daily_reset_at": "25:00"}`, "session.daily_reset_at"},
+		{`{"retention": {"action": "arhcive"}}`, "session.retention.action"},
+	}
+	for _, tt := range tests {
+		configPath := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(configPath, []byte(`{"session": `+tt.session+`}`), 0o600); err != nil {
+			t.Fatalf("os.WriteFile() error: %v", err)
+		}
+		_, err := LoadConfig(configPath)
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.session, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("%s: error = %v, want %q", tt.session, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadConfig_ChannelStatus(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	configJSON := `{"channels":{
+		"discord": {"status": "verbose", "accounts": {"quiet": {"status": "off"}, "other": {}}},
+		"slack": {}
+	}}`
+	if err := os.WriteFile(configPath, []byte(configJSON), 0o600); err != nil {
+		t.Fatalf("os.WriteFile() error: %v", err)
+	}
+	t.Setenv("AGENTX_CHANNELS_TELEGRAM_STATUS", "off")
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+	if got := cfg.Channels.Discord.StatusMode(); got != StatusVerbose {
+		t.Errorf("discord status = %q", got)
+	}
+	if got := cfg.Channels.Slack.StatusMode(); got != StatusTyping {
+		t.Errorf("slack status should default to typing, got %q", got)
+	}
+	if got := cfg.Channels.Telegram.StatusMode(); got != StatusOff {
+		t.Errorf("telegram status from env = %q", got)
+	}
+
+	accounts, err := ResolveAccounts(cfg.Channels.Discord, cfg.Channels.Discord.Accounts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if accounts["quiet"].StatusMode() != StatusOff || accounts["other"].StatusMode() != StatusVerbose {
+		t.Errorf("accounts = %+v", accounts)
+	}
+}
+
+func TestLoadConfig_RejectsUnknownChannelStatus(t *testing.T) {
+	tests := map[string]string{
+		`{"channels":{"telegram":{"status":"verbos"}}}`:                  `channels.telegram.status must be off, typing or verbose, got "verbos"`,
+		`{"channels":{"slack":{"accounts":{"work":{"status":"loud"}}}}}`: `channels.slack.accounts.work.status must be off, typing or verbose, got "loud"`,
+	}
+	for configJSON, want := range tests {
+		configPath := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(configPath, []byte(configJSON), 0o600); err != nil {
+			t.Fatalf("os.WriteFile() error: %v", err)
+		}
+		if _, err := LoadConfig(configPath); err == nil || err.Error() != want {
+			t.Errorf("LoadConfig(%s) error = %v, want %q", configJSON, err, want)
+		}
+	}
+}
+
+func TestLoadConfig_RejectsUnknownRetentionAction(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	configJSON := `{"session":{"retention":{"after_days":30,"action":"arhcive"}}}`
+	if err := os.WriteFile(configPath, []byte(configJSON), 0o600); err != nil {
+		t.Fatalf("os.WriteFile() error: %v", err)
+	}
+	want := `session.retention.action must be archive or delete, got "arhcive"`
+	if _, err := LoadConfig(configPath); err == nil || err.Error() != want {
+		t.Errorf("LoadConfig() error = %v, want %q", err, want)
+	}
+}
@@ -113,6 +113,69 @@ func DefaultConfig() *Config {
 				AllowFrom:      FlexibleStringSlice{},
 				ReplyTimeout:   5,
 			},
+			Email: EmailConfig{
+				Enabled:      false,
+				IMAPHost:     "",
+				IMAPPort:     993,
+				SMTPHost:     "",
+				SMTPPort:     587,
+				Mailbox:      "INBOX",
+				PollInterval: 60,
+				AllowFrom:    FlexibleStringSlice{},
+			},
+			Matrix: MatrixConfig{
+				Enabled:     false,
+				Homeserver:  "",
+				AccessToken: "",
+				AutoJoin:    true,
+				MentionOnly: true,
+				AllowFrom:   FlexibleStringSlice{},
+			},
+			Signal: SignalConfig{
+				Enabled:     false,
+				Socket:      "127.0.0.1:7583",
+				AckReaction: "👀",
+				AllowFrom:   FlexibleStringSlice{},
+			},
+			MQTT: MQTTConfig{
+				Enabled:   false,
+				Broker:    "tcp://127.0.0.1:1883",
+				ClientID:  "agentx",
+				QoS:       1,
+				Topics:    []MQTTTopicConfig{},
+				AllowFrom: FlexibleStringSlice{},
+			},
+			Webhook: WebhookConfig{
+				Enabled:         false,
+				Path:            "/webhook/agentx",
+				SignatureHeader: "X-Signature-256",
+				SenderField:     "sender",
+				ChatField:       "chat_id",
+				ContentField:    "text",
+				MediaField:      "media",
+				ReplyTimeout:    60,
+				AllowFrom:       FlexibleStringSlice{},
+			},
+			IRC: IRCConfig{
+				Enabled:   false,
+				Server:    "irc.libera.chat:6697",
+				TLS:       true,
+				Nick:      "agentx",
+				Channels:  FlexibleStringSlice{},
+				AllowFrom: FlexibleStringSlice{},
+			},
+			Mattermost: MattermostConfig{
+				Enabled:       false,
+				MentionOnly:   true,
+				ReplyInThread: true,
+				AllowFrom:     FlexibleStringSlice{},
+			},
+			RocketChat: RocketChatConfig{
+				Enabled:       false,
+				MentionOnly:   true,
+				ReplyInThread: true,
+				AllowFrom:     FlexibleStringSlice{},
+			},
 		},
 		Providers: ProvidersConfig{
 			OpenAI: OpenAIProviderConfig{WebSearch: true},
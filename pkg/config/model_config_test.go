@@ -53,29 +53,38 @@ func TestGetModelConfig_EmptyList(t *testing.T) {
 	}
 }
 
-func TestGetModelConfig_RoundRobin(t *testing.T) {
+func TestGetModelConfigs_Pool(t *testing.T) {
 	cfg := &Config{
 		ModelList: []ModelConfig{
 			{ModelName: "lb-model", Model: "openai/gpt-4o-1", APIKey: "key1"},
+			{ModelName: "other", Model: "openai/gpt-4o-mini", APIKey: "key"},
 			{ModelName: "lb-model", Model: "openai/gpt-4o-2", APIKey: "key2"},
 			{ModelName: "lb-model", Model: "openai/gpt-4o-3", APIKey: "key3"},
 		},
 	}
 
-	// Test round-robin distribution
-	results := make(map[string]int)
-	for i := 0; i < 30; i++ {
+	pool, err := cfg.GetModelConfigs("lb-model")
+	if err != nil {
+		t.Fatalf("GetModelConfigs() error = %v", err)
+	}
+	if len(pool) != 3 {
+		t.Fatalf("GetModelConfigs() returned %d entries, want 3", len(pool))
+	}
+	for i, want := range []string{"openai/gpt-4o-1", "openai/gpt-4o-2", "openai/gpt-4o-3"} {
+		if pool[i].Model != want {
+			t.Errorf("pool[%d] = %s, want %s", i, pool[i].Model, want)
+		}
+	}
+
+	// GetModelConfig is deterministic; balancing happens per request in
+	// the provider layer.
+	for i := 0; i < 3; i++ {
 		result, err := cfg.GetModelConfig("lb-model")
 		if err != nil {
 			t.Fatalf("GetModelConfig() error = %v", err)
 		}
-		results[result.Model]++
-	}
-
-	// Each model should appear roughly 10 times (30 calls / 3 models)
-	for model, count := range results {
-		if count < 5 || count > 15 {
-			t.Errorf("Model %s appeared %d times, expected ~10", model, count)
+		if result.Model != "openai/gpt-4o-1" {
+			t.Errorf("GetModelConfig() = %s, want the first entry", result.Model)
 		}
 	}
 }
@@ -279,6 +288,24 @@ func TestModelConfig_Validate(t *testing.T) {
 			config:  ModelConfig{},
 			wantErr: true,
 		},
+		{
+			name: "reasoning effort",
+			config: ModelConfig{
+				ModelName: "test",
+				Model:     "anthropic/claude-sonnet-4",
+				Reasoning: &ReasoningConfig{Effort: "high", BudgetTokens: 8000},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown reasoning effort",
+			config: ModelConfig{
+				ModelName: "test",
+				Model:     "openai/o3",
+				Reasoning: &ReasoningConfig{Effort: "max"},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -30,6 +30,9 @@ type CronPayload struct {
 	Deliver bool   `json:"deliver"`
 	Channel string `json:"channel,omitempty"`
 	To      string `json:"to,omitempty"`
+
+	// Schema makes an agent turn return JSON matching this JSON schema.
+	Schema map[string]any `json:"schema,omitempty"`
 }
 
 type CronJobState struct {
@@ -37,6 +40,7 @@ type CronJobState struct {
 	LastRunAtMS *int64 `json:"lastRunAtMs,omitempty"`
 	LastStatus  string `json:"lastStatus,omitempty"`
 	LastError   string `json:"lastError,omitempty"`
+	LastResult  string `json:"lastResult,omitempty"` // JSON result of the last structured run
 }
 
 type CronJob struct {
@@ -200,9 +204,10 @@ func (cs *CronService) executeJobByID(jobID string) {
 		return
 	}
 
+	var output string
 	var err error
 	if cs.onJob != nil {
-		_, err = cs.onJob(callbackJob)
+		output, err = cs.onJob(callbackJob)
 	}
 
 	// Now acquire lock to update state
@@ -231,6 +236,13 @@ func (cs *CronService) executeJobByID(jobID string) {
 		job.State.LastStatus = "ok"
 		job.State.LastError = ""
 	}
+	if job.Payload.Schema != nil {
+		// A failed run leaves no result, rather than the previous run's.
+		job.State.LastResult = ""
+		if err == nil && json.Valid([]byte(output)) {
+			job.State.LastResult = output
+		}
+	}
 
 	// Compute next run time
 	if job.Schedule.Kind == "at" {
@@ -1,6 +1,7 @@
 package cron
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -33,6 +34,37 @@ func TestSaveStore_FilePermissions(t *testing.T) {
 	}
 }
 
+func TestExecuteJob_StructuredResult(t *testing.T) {
+	fail := false
+	cs := NewCronService(filepath.Join(t.TempDir(), "jobs.json"), func(job *CronJob) (string, error) {
+		if fail {
+			return "", errors.New("model unavailable")
+		}
+		return `{"temp":21}`, nil
+	})
+	job, err := cs.AddJob("weather", CronSchedule{Kind: "every", EveryMS: int64Ptr(60000)}, "report", false, "cli", "direct")
+	if err != nil {
+		t.Fatal(err)
+	}
+	job.Payload.Schema = map[string]any{"type": "object"}
+	if err := cs.UpdateJob(job); err != nil {
+		t.Fatal(err)
+	}
+
+	cs.executeJobByID(job.ID)
+	state := cs.ListJobs(true)[0].State
+	if state.LastStatus != "ok" || state.LastResult != `{"temp":21}` {
+		t.Errorf("after a good run: %+v", state)
+	}
+
+	fail = true
+	cs.executeJobByID(job.ID)
+	state = cs.ListJobs(true)[0].State
+	if state.LastStatus != "error" || state.LastError != "model unavailable" || state.LastResult != "" {
+		t.Errorf("after a failed run: %+v", state)
+	}
+}
+
 func int64Ptr(v int64) *int64 {
 	return &v
 }
@@ -0,0 +1,468 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"charm.land/fantasy"
+
+	"github.com/Agentx-network/agentx/pkg/logger"
+)
+
+// Load balancing strategies for config.LoadBalancingConfig.Strategy.
+const (
+	BalanceRoundRobin       = "round_robin"
+	BalanceWeighted         = "weighted"
+	BalanceLeastLatency     = "least_latency"
+	BalanceLeastOutstanding = "least_outstanding"
+)
+
+const (
+	defaultBalancerMaxWait = 30 * time.Second
+	// latencyAlpha weights the newest sample in the latency moving average.
+	latencyAlpha = 0.3
+)
+
+// BalancedMember is one model_list entry in a load-balanced pool.
+type BalancedMember struct {
+	Model  fantasy.LanguageModel
+	Name   string // Label for logs and cooldown tracking, unique in the pool
+	Weight int    // Share of traffic under the weighted strategy (default 1)
+	RPM    int    // Requests per minute, 0 for unlimited
+	TPM    int    // Tokens per minute, 0 for unlimited
+}
+
+// BalancedLanguageModel spreads requests over several endpoints serving the
+// same model, such as pooled API keys or self-hosted replicas. Endpoints in
+// cooldown or out of RPM/TPM budget are skipped, and a request that fails
+// with a rate limit, timeout or server error is retried on another endpoint.
+type BalancedLanguageModel struct {
+	mu       sync.Mutex
+	strategy string
+	members  []*poolMember
+	cooldown *CooldownTracker
+	maxWait  time.Duration
+	next     int
+	nowFunc  func() time.Time
+}
+
+type poolMember struct {
+	BalancedMember
+	rpm         *tokenBucket
+	tpm         *tokenBucket
+	outstanding int
+	latency     time.Duration // moving average of successful calls
+	samples     int
+	current     int // smooth weighted round-robin state
+}
+
+// NewBalancedLanguageModel creates a pool over members. maxWait bounds how
+// long a request waits for RPM/TPM budget when every member is at its limit;
+// zero uses the default of 30 seconds.
+func NewBalancedLanguageModel(
+	strategy string,
+	members []BalancedMember,
+	cooldown *CooldownTracker,
+	maxWait time.Duration,
+) *BalancedLanguageModel {
+	if cooldown == nil {
+		cooldown = NewCooldownTracker()
+	}
+	if maxWait <= 0 {
+		maxWait = defaultBalancerMaxWait
+	}
+	b := &BalancedLanguageModel{
+		strategy: strategy,
+		cooldown: cooldown,
+		maxWait:  maxWait,
+		nowFunc:  time.Now,
+	}
+	now := b.nowFunc()
+	for _, m := range members {
+		if m.Weight <= 0 {
+			m.Weight = 1
+		}
+		b.members = append(b.members, &poolMember{
+			BalancedMember: m,
+			rpm:            newTokenBucket(m.RPM, now),
+			tpm:            newTokenBucket(m.TPM, now),
+		})
+	}
+	return b
+}
+
+// Generate sends the call to the best available member, retrying on
+// another member when the chosen one is unhealthy.
+func (b *BalancedLanguageModel) Generate(ctx context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	tried := make(map[*poolMember]bool, len(b.members))
+	var lastErr error
+	for {
+		m, err := b.acquire(ctx, tried)
+		if err != nil {
+			return nil, b.exhausted(err, lastErr)
+		}
+		tried[m] = true
+
+		start := b.nowFunc()
+		resp, err := m.Model.Generate(ctx, call)
+		var tokens int64
+		if resp != nil {
+			tokens = resp.Usage.TotalTokens
+		}
+		retry := b.release(m, b.nowFunc().Sub(start), tokens, err)
+		if err == nil {
+			return resp, nil
+		}
+		if !retry || ctx.Err() != nil {
+			return nil, err
+		}
+		lastErr = err
+	}
+}
+
+// Stream opens a stream on the best available member. Members that fail to
+// start streaming are skipped like in Generate; once parts flow, errors are
+// recorded against the member but the stream is not restarted.
+func (b *BalancedLanguageModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.StreamResponse, error) {
+	tried := make(map[*poolMember]bool, len(b.members))
+	var lastErr error
+	for {
+		m, err := b.acquire(ctx, tried)
+		if err != nil {
+			return nil, b.exhausted(err, lastErr)
+		}
+		tried[m] = true
+
+		start := b.nowFunc()
+		stream, err := m.Model.Stream(ctx, call)
+		if err != nil {
+			if !b.release(m, 0, 0, err) || ctx.Err() != nil {
+				return nil, err
+			}
+			lastErr = err
+			continue
+		}
+		return b.trackStream(m, start, stream), nil
+	}
+}
+
+// trackStream wraps stream so the member's outstanding count, latency and
+// token usage are updated when the stream ends.
+func (b *BalancedLanguageModel) trackStream(m *poolMember, start time.Time, stream fantasy.StreamResponse) fantasy.StreamResponse {
+	return func(yield func(fantasy.StreamPart) bool) {
+		var tokens int64
+		var streamErr error
+		defer func() { b.release(m, b.nowFunc().Sub(start), tokens, streamErr) }()
+
+		for part := range stream {
+			switch part.Type {
+			case fantasy.StreamPartTypeFinish:
+				tokens += part.Usage.TotalTokens
+			case fantasy.StreamPartTypeError:
+				streamErr = part.Error
+			}
+			if !yield(part) {
+				return
+			}
+		}
+	}
+}
+
+// GenerateObject sends the call to the best available member.
+func (b *BalancedLanguageModel) GenerateObject(ctx context.Context, call fantasy.ObjectCall) (*fantasy.ObjectResponse, error) {
+	m, err := b.acquire(ctx, map[*poolMember]bool{})
+	if err != nil {
+		return nil, err
+	}
+	start := b.nowFunc()
+	resp, err := m.Model.GenerateObject(ctx, call)
+	var tokens int64
+	if resp != nil {
+		tokens = resp.Usage.TotalTokens
+	}
+	b.release(m, b.nowFunc().Sub(start), tokens, err)
+	return resp, err
+}
+
+// StreamObject streams from the best available member.
+func (b *BalancedLanguageModel) StreamObject(ctx context.Context, call fantasy.ObjectCall) (fantasy.ObjectStreamResponse, error) {
+	m, err := b.acquire(ctx, map[*poolMember]bool{})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := m.Model.StreamObject(ctx, call)
+	// Object streams carry no usage we can hook cheaply; count the request only.
+	b.release(m, 0, 0, err)
+	return resp, err
+}
+
+// Provider returns the provider of the first member.
+func (b *BalancedLanguageModel) Provider() string {
+	return b.members[0].Model.Provider()
+}
+
+// Model returns the model of the first member.
+func (b *BalancedLanguageModel) Model() string {
+	return b.members[0].Model.Model()
+}
+
+// acquire picks a member that has not been tried yet, waiting for RPM/TPM
+// budget if all healthy members are at their limit.
+func (b *BalancedLanguageModel) acquire(ctx context.Context, tried map[*poolMember]bool) (*poolMember, error) {
+	deadline := b.nowFunc().Add(b.maxWait)
+	for {
+		b.mu.Lock()
+		now := b.nowFunc()
+
+		var healthy []*poolMember
+		for _, m := range b.members {
+			if !tried[m] && b.cooldown.IsAvailable(m.Name) {
+				healthy = append(healthy, m)
+			}
+		}
+		if len(healthy) == 0 {
+			// Everything left is cooling down. Try the one that recovers
+			// first rather than failing outright.
+			m := b.soonestAvailable(tried)
+			if m == nil {
+				b.mu.Unlock()
+				return nil, fmt.Errorf("all %d endpoints tried", len(b.members))
+			}
+			m.outstanding++
+			m.rpm.take(1, now)
+			b.mu.Unlock()
+			logger.WarnCF("providers", "All endpoints in cooldown, trying the one that recovers first",
+				map[string]any{"endpoint": m.Name})
+			return m, nil
+		}
+
+		var ready []*poolMember
+		wait := time.Duration(math.MaxInt64)
+		for _, m := range healthy {
+			w := max(m.rpm.wait(1, now), m.tpm.wait(0, now))
+			if w == 0 {
+				ready = append(ready, m)
+			}
+			wait = min(wait, w)
+		}
+		if len(ready) > 0 {
+			m := b.choose(ready)
+			m.outstanding++
+			m.rpm.take(1, now)
+			b.mu.Unlock()
+			return m, nil
+		}
+		b.mu.Unlock()
+
+		if now.Add(wait).After(deadline) {
+			return nil, fmt.Errorf("rate limit budget exhausted on all endpoints (next slot in %s)", wait.Round(time.Second))
+		}
+		logger.DebugCF("providers", "All endpoints at their rate limit, waiting",
+			map[string]any{"wait": wait.String()})
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// choose applies the balancing strategy to members that can take a request.
+// The caller holds b.mu.
+func (b *BalancedLanguageModel) choose(ready []*poolMember) *poolMember {
+	switch b.strategy {
+	case BalanceWeighted:
+		// Smooth weighted round-robin, as in nginx: spreads picks evenly
+		// instead of sending bursts to the heaviest member.
+		total := 0
+		var best *poolMember
+		for _, m := range ready {
+			m.current += m.Weight
+			total += m.Weight
+			if best == nil || m.current > best.current {
+				best = m
+			}
+		}
+		best.current -= total
+		return best
+
+	case BalanceLeastLatency:
+		// Members without samples go first so every endpoint gets measured.
+		var best *poolMember
+		for _, m := range b.rotate(ready) {
+			if m.samples == 0 {
+				return m
+			}
+			if best == nil || m.latency < best.latency {
+				best = m
+			}
+		}
+		return best
+
+	case BalanceLeastOutstanding:
+		var best *poolMember
+		for _, m := range b.rotate(ready) {
+			if best == nil || m.outstanding < best.outstanding {
+				best = m
+			}
+		}
+		return best
+
+	default:
+		return b.rotate(ready)[0]
+	}
+}
+
+// rotate returns ready starting at the round-robin cursor, so ties are
+// broken fairly. The caller holds b.mu.
+func (b *BalancedLanguageModel) rotate(ready []*poolMember) []*poolMember {
+	start := b.next % len(ready)
+	b.next++
+	out := make([]*poolMember, 0, len(ready))
+	out = append(out, ready[start:]...)
+	return append(out, ready[:start]...)
+}
+
+// soonestAvailable returns the untried member whose cooldown ends first.
+// The caller holds b.mu.
+func (b *BalancedLanguageModel) soonestAvailable(tried map[*poolMember]bool) *poolMember {
+	var best *poolMember
+	var bestRemaining time.Duration
+	for _, m := range b.members {
+		if tried[m] {
+			continue
+		}
+		remaining := b.cooldown.CooldownRemaining(m.Name)
+		if best == nil || remaining < bestRemaining {
+			best, bestRemaining = m, remaining
+		}
+	}
+	return best
+}
+
+// release records the outcome of a call on m and reports whether the error,
+// if any, is worth retrying on another member.
+func (b *BalancedLanguageModel) release(m *poolMember, latency time.Duration, tokens int64, err error) bool {
+	b.mu.Lock()
+	m.outstanding--
+	if tokens > 0 {
+		m.tpm.take(float64(tokens), b.nowFunc())
+	}
+	if err == nil && latency > 0 {
+		if m.samples == 0 {
+			m.latency = latency
+		} else {
+			m.latency = time.Duration(latencyAlpha*float64(latency) + (1-latencyAlpha)*float64(m.latency))
+		}
+		m.samples++
+	}
+	b.mu.Unlock()
+
+	if err == nil {
+		b.cooldown.MarkSuccess(m.Name)
+		return false
+	}
+	reason, retry := classifyPoolError(err)
+	if !retry {
+		return false
+	}
+	b.cooldown.MarkFailure(m.Name, reason)
+	logger.WarnCF("providers", "Endpoint failed, trying another",
+		map[string]any{
+			"endpoint": m.Name,
+			"reason":   string(reason),
+			"error":    err.Error(),
+		})
+	return true
+}
+
+// exhausted builds the error returned when acquire gives up, keeping the
+// last endpoint error when there was one.
+func (b *BalancedLanguageModel) exhausted(err, lastErr error) error {
+	if lastErr != nil {
+		return fmt.Errorf("all endpoints for %s failed: %w", b.Model(), lastErr)
+	}
+	return fmt.Errorf("no endpoint available for %s: %w", b.Model(), err)
+}
+
+// classifyPoolError reports why a call failed and whether another endpoint
+// might succeed. Bad requests fail the same way everywhere and are not retried.
+func classifyPoolError(err error) (FailoverReason, bool) {
+	if errors.Is(err, context.Canceled) {
+		return "", false
+	}
+	var providerErr *fantasy.ProviderError
+	if errors.As(err, &providerErr) && providerErr.StatusCode > 0 {
+		if reason := classifyByStatus(providerErr.StatusCode); reason != "" {
+			return reason, reason != FailoverFormat
+		}
+	}
+	if fe := ClassifyError(err, "", ""); fe != nil {
+		return fe.Reason, fe.Reason != FailoverFormat
+	}
+	msg := strings.ToLower(err.Error())
+	if strings.Contains(msg, "connection refused") || strings.Contains(msg, "no such host") ||
+		strings.Contains(msg, "eof") {
+		return FailoverTimeout, true
+	}
+	return FailoverUnknown, false
+}
+
+// tokenBucket is a per-minute budget that refills continuously. A nil
+// bucket is unlimited.
+type tokenBucket struct {
+	capacity float64
+	tokens   float64
+	perSec   float64
+	last     time.Time
+}
+
+func newTokenBucket(perMinute int, now time.Time) *tokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &tokenBucket{
+		capacity: float64(perMinute),
+		tokens:   float64(perMinute),
+		perSec:   float64(perMinute) / 60,
+		last:     now,
+	}
+}
+
+func (tb *tokenBucket) refill(now time.Time) {
+	if elapsed := now.Sub(tb.last).Seconds(); elapsed > 0 {
+		tb.tokens = min(tb.capacity, tb.tokens+elapsed*tb.perSec)
+		tb.last = now
+	}
+}
+
+// wait returns how long until need tokens are available. TPM buckets use
+// need 0: usage is only known after the call, so a request may start as
+// long as the budget is not overdrawn.
+func (tb *tokenBucket) wait(need float64, now time.Time) time.Duration {
+	if tb == nil {
+		return 0
+	}
+	tb.refill(now)
+	// The epsilon absorbs float rounding in the refill arithmetic.
+	if tb.tokens+1e-9 >= need && tb.tokens > 0 {
+		return 0
+	}
+	missing := max(need, 1) - tb.tokens
+	return time.Duration(missing / tb.perSec * float64(time.Second))
+}
+
+func (tb *tokenBucket) take(n float64, now time.Time) {
+	if tb == nil {
+		return
+	}
+	tb.refill(now)
+	tb.tokens -= n
+}
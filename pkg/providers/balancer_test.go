@@ -0,0 +1,259 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+
+	"github.com/Agentx-network/agentx/pkg/config"
+)
+
+// stubModel is a fantasy.LanguageModel that counts calls and returns err
+// when set.
+type stubModel struct {
+	name   string
+	mu     sync.Mutex
+	calls  int
+	err    error
+	tokens int64
+}
+
+func (m *stubModel) Generate(ctx context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &fantasy.Response{Usage: fantasy.Usage{TotalTokens: m.tokens}}, nil
+}
+
+func (m *stubModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.StreamResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	return func(yield func(fantasy.StreamPart) bool) {
+		yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeFinish, Usage: fantasy.Usage{TotalTokens: m.tokens}})
+	}, nil
+}
+
+func (m *stubModel) GenerateObject(ctx context.Context, call fantasy.ObjectCall) (*fantasy.ObjectResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *stubModel) StreamObject(ctx context.Context, call fantasy.ObjectCall) (fantasy.ObjectStreamResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *stubModel) Provider() string { return "stub" }
+func (m *stubModel) Model() string    { return m.name }
+
+func (m *stubModel) callCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.calls
+}
+
+func newTestBalancer(strategy string, members ...BalancedMember) (*BalancedLanguageModel, *time.Time) {
+	b := NewBalancedLanguageModel(strategy, members, nil, time.Second)
+	current := time.Now()
+	b.nowFunc = func() time.Time { return current }
+	b.cooldown.nowFunc = b.nowFunc
+	return b, &current
+}
+
+func generateN(t *testing.T, b *BalancedLanguageModel, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if _, err := b.Generate(context.Background(), fantasy.Call{}); err != nil {
+			t.Fatalf("Generate() call %d: %v", i, err)
+		}
+	}
+}
+
+func TestBalancer_RoundRobin(t *testing.T) {
+	a, b2, c := &stubModel{name: "a"}, &stubModel{name: "b"}, &stubModel{name: "c"}
+	b, _ := newTestBalancer(BalanceRoundRobin,
+		BalancedMember{Model: a, Name: "a"},
+		BalancedMember{Model: b2, Name: "b"},
+		BalancedMember{Model: c, Name: "c"},
+	)
+	generateN(t, b, 30)
+	for _, m := range []*stubModel{a, b2, c} {
+		if m.callCount() != 10 {
+			t.Errorf("%s got %d calls, want 10", m.name, m.callCount())
+		}
+	}
+}
+
+func TestBalancer_Weighted(t *testing.T) {
+	heavy, light := &stubModel{name: "heavy"}, &stubModel{name: "light"}
+	b, _ := newTestBalancer(BalanceWeighted,
+		BalancedMember{Model: heavy, Name: "heavy", Weight: 3},
+		BalancedMember{Model: light, Name: "light", Weight: 1},
+	)
+	generateN(t, b, 40)
+	if heavy.callCount() != 30 || light.callCount() != 10 {
+		t.Errorf("got heavy=%d light=%d, want 30/10", heavy.callCount(), light.callCount())
+	}
+}
+
+func TestBalancer_LeastLatency(t *testing.T) {
+	slow, fast := &stubModel{name: "slow"}, &stubModel{name: "fast"}
+	b, _ := newTestBalancer(BalanceLeastLatency,
+		BalancedMember{Model: slow, Name: "slow"},
+		BalancedMember{Model: fast, Name: "fast"},
+	)
+	b.members[0].latency, b.members[0].samples = 2*time.Second, 5
+	b.members[1].latency, b.members[1].samples = 200*time.Millisecond, 5
+
+	generateN(t, b, 5)
+	if fast.callCount() != 5 {
+		t.Errorf("fast endpoint got %d of 5 calls", fast.callCount())
+	}
+}
+
+func TestBalancer_LeastOutstanding(t *testing.T) {
+	busy, idle := &stubModel{name: "busy"}, &stubModel{name: "idle"}
+	b, _ := newTestBalancer(BalanceLeastOutstanding,
+		BalancedMember{Model: busy, Name: "busy"},
+		BalancedMember{Model: idle, Name: "idle"},
+	)
+	b.members[0].outstanding = 3
+
+	generateN(t, b, 3)
+	if idle.callCount() != 3 {
+		t.Errorf("idle endpoint got %d of 3 calls", idle.callCount())
+	}
+}
+
+func TestBalancer_RetriesRateLimitedEndpoint(t *testing.T) {
+	limited := &stubModel{name: "limited", err: errors.New("429 Too Many Requests: rate limit exceeded")}
+	healthy := &stubModel{name: "healthy"}
+	b, _ := newTestBalancer(BalanceRoundRobin,
+		BalancedMember{Model: limited, Name: "limited"},
+		BalancedMember{Model: healthy, Name: "healthy"},
+	)
+
+	generateN(t, b, 4)
+	if limited.callCount() != 1 {
+		t.Errorf("endpoint in cooldown should be skipped, got %d calls", limited.callCount())
+	}
+	if healthy.callCount() != 4 {
+		t.Errorf("healthy endpoint got %d of 4 calls", healthy.callCount())
+	}
+	if b.cooldown.IsAvailable("limited") {
+		t.Error("rate-limited endpoint should be in cooldown")
+	}
+}
+
+func TestBalancer_DoesNotRetryBadRequest(t *testing.T) {
+	bad := &stubModel{name: "a", err: errors.New("400 Bad Request: invalid request")}
+	other := &stubModel{name: "b"}
+	b, _ := newTestBalancer(BalanceRoundRobin,
+		BalancedMember{Model: bad, Name: "a"},
+		BalancedMember{Model: other, Name: "b"},
+	)
+
+	if _, err := b.Generate(context.Background(), fantasy.Call{}); err == nil {
+		t.Fatal("expected the bad request error")
+	}
+	if other.callCount() != 0 {
+		t.Error("bad requests should not be retried on another endpoint")
+	}
+	if !b.cooldown.IsAvailable("a") {
+		t.Error("bad requests should not put the endpoint in cooldown")
+	}
+}
+
+func TestBalancer_RPMBudget(t *testing.T) {
+	a, c := &stubModel{name: "a"}, &stubModel{name: "b"}
+	b, now := newTestBalancer(BalanceRoundRobin,
+		BalancedMember{Model: a, Name: "a", RPM: 2},
+		BalancedMember{Model: c, Name: "b", RPM: 2},
+	)
+
+	generateN(t, b, 4)
+	if _, err := b.Generate(context.Background(), fantasy.Call{}); err == nil {
+		t.Fatal("expected an error once every endpoint is out of budget")
+	}
+
+	// Budget refills continuously: 2 RPM is one request every 30s.
+	*now = now.Add(30 * time.Second)
+	generateN(t, b, 2)
+	if a.callCount() != 3 || c.callCount() != 3 {
+		t.Errorf("got a=%d b=%d, want 3/3", a.callCount(), c.callCount())
+	}
+}
+
+func TestBalancer_TPMBudget(t *testing.T) {
+	hungry, other := &stubModel{name: "hungry", tokens: 1000}, &stubModel{name: "other", tokens: 10}
+	b, _ := newTestBalancer(BalanceRoundRobin,
+		BalancedMember{Model: hungry, Name: "hungry", TPM: 500},
+		BalancedMember{Model: other, Name: "other"},
+	)
+
+	generateN(t, b, 4)
+	if hungry.callCount() != 1 {
+		t.Errorf("endpoint over its TPM budget should be skipped, got %d calls", hungry.callCount())
+	}
+}
+
+func TestBalancer_StreamReleasesOnCompletion(t *testing.T) {
+	m := &stubModel{name: "a", tokens: 100}
+	b, _ := newTestBalancer(BalanceLeastOutstanding, BalancedMember{Model: m, Name: "a", TPM: 1000})
+
+	stream, err := b.Stream(context.Background(), fantasy.Call{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.members[0].outstanding != 1 {
+		t.Errorf("outstanding = %d while streaming, want 1", b.members[0].outstanding)
+	}
+	for range stream {
+	}
+	if b.members[0].outstanding != 0 {
+		t.Errorf("outstanding = %d after the stream, want 0", b.members[0].outstanding)
+	}
+	if got := b.members[0].tpm.tokens; got != 900 {
+		t.Errorf("TPM budget = %v, want 900 after a 100-token stream", got)
+	}
+}
+
+func TestFantasyModelForName_Pool(t *testing.T) {
+	cfg := &config.Config{
+		ModelList: []config.ModelConfig{
+			{ModelName: "pool", Model: "openai/gpt-4o", APIKey: "key1"},
+			{ModelName: "pool", Model: "openai/gpt-4o", APIKey: "key2", Weight: 2},
+			{ModelName: "solo", Model: "openai/gpt-4o-mini", APIKey: "key"},
+		},
+		LoadBalancing: config.LoadBalancingConfig{Strategy: BalanceWeighted},
+	}
+
+	model, err := FantasyModelForName(cfg, "pool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool, ok := model.(*BalancedLanguageModel)
+	if !ok {
+		t.Fatalf("expected a balanced model, got %T", model)
+	}
+	if len(pool.members) != 2 || pool.members[1].Weight != 2 || pool.strategy != BalanceWeighted {
+		t.Errorf("unexpected pool: %+v", pool.members)
+	}
+
+	model, err = FantasyModelForName(cfg, "solo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := model.(*BalancedLanguageModel); ok {
+		t.Error("a single entry without limits should not be wrapped")
+	}
+}
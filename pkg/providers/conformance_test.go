@@ -0,0 +1,230 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+
+	"github.com/Agentx-network/agentx/pkg/config"
+	"github.com/Agentx-network/agentx/pkg/providers/providertest"
+)
+
+// Conformance tests replay recorded provider traffic from
+// testdata/conformance/<provider>/<scenario>.json through the same
+// FantasyModelFromConfig path the agent uses. See package providertest for
+// how to record them again.
+
+// replayModel starts a replay of a fixture and returns a model configured
+// against it.
+func replayModel(t *testing.T, provider, scenario string) (fantasy.LanguageModel, *providertest.Server) {
+	t.Helper()
+	srv := providertest.Replay(t, filepath.Join("testdata", "conformance", provider, scenario+".json"))
+
+	apiBase := srv.URL
+	if provider == "anthropic" {
+		// Anthropic bases are often configured with /v1, which must not end
+		// up doubled in the request path.
+		apiBase += "/v1"
+	}
+	model, err := FantasyModelFromConfig(&config.ModelConfig{
+		ModelName: provider,
+		Model:     srv.Fixture.Model,
+		APIBase:   apiBase,
+		APIKey:    srv.APIKey(),
+	})
+	if err != nil {
+		t.Fatalf("FantasyModelFromConfig: %v", err)
+	}
+	return model, srv
+}
+
+// The calls below must stay in sync with the recorded requests.
+
+func weatherCall() fantasy.Call {
+	maxTokens := int64(256)
+	return fantasy.Call{
+		Prompt:          fantasy.Prompt{fantasy.NewUserMessage("What's the weather in Paris?")},
+		MaxOutputTokens: &maxTokens,
+		Tools: []fantasy.Tool{fantasy.FunctionTool{
+			Name:        "get_weather",
+			Description: "Get the current weather for a city",
+			InputSchema: map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"city": map[string]any{"type": "string"}},
+				"required":   []string{"city"},
+			},
+		}},
+	}
+}
+
+func greetingCall() fantasy.Call {
+	maxTokens := int64(256)
+	return fantasy.Call{
+		Prompt:          fantasy.Prompt{fantasy.NewUserMessage("Say hello in three words.")},
+		MaxOutputTokens: &maxTokens,
+	}
+}
+
+func TestConformance_ToolCall(t *testing.T) {
+	for _, provider := range []string{"anthropic", "openai", "gemini", "openrouter", "deepseek"} {
+		t.Run(provider, func(t *testing.T) {
+			model, srv := replayModel(t, provider, "tool_call")
+
+			resp, err := model.Generate(context.Background(), weatherCall())
+			if err != nil {
+				t.Fatalf("Generate: %v", err)
+			}
+			if !strings.Contains(string(srv.Requests()[0].RequestBody), "get_weather") {
+				t.Error("the tool definition was not sent")
+			}
+
+			calls := resp.Content.ToolCalls()
+			if len(calls) != 1 || calls[0].ToolName != "get_weather" || calls[0].ToolCallID == "" {
+				t.Fatalf("tool calls = %+v", calls)
+			}
+			var args struct{ City string }
+			if err := json.Unmarshal([]byte(calls[0].Input), &args); err != nil || args.City != "Paris" {
+				t.Errorf("tool input %q did not parse to city Paris (%v)", calls[0].Input, err)
+			}
+			if resp.FinishReason != fantasy.FinishReasonToolCalls {
+				t.Errorf("finish reason = %q", resp.FinishReason)
+			}
+			if resp.Usage.InputTokens == 0 || resp.Usage.OutputTokens == 0 {
+				t.Errorf("usage not parsed: %+v", resp.Usage)
+			}
+
+			msgs := FantasyStepToAgentXMessages(fantasy.StepResult{Response: *resp})
+			if len(msgs) != 1 || len(msgs[0].ToolCalls) != 1 || toolCallName(msgs[0].ToolCalls[0]) != "get_weather" {
+				t.Errorf("session message = %+v", msgs)
+			}
+		})
+	}
+}
+
+// streamResult collects what a stream produced.
+type streamResult struct {
+	text, reasoning string
+	textDeltas      int
+	finish          fantasy.FinishReason
+	usage           fantasy.Usage
+}
+
+func collectStream(t *testing.T, model fantasy.LanguageModel, call fantasy.Call) streamResult {
+	t.Helper()
+	stream, err := model.Stream(context.Background(), call)
+	if err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	var res streamResult
+	var text, reasoning strings.Builder
+	for part := range stream {
+		switch part.Type {
+		case fantasy.StreamPartTypeTextDelta:
+			text.WriteString(part.Delta)
+			res.textDeltas++
+		case fantasy.StreamPartTypeReasoningDelta:
+			reasoning.WriteString(part.Delta)
+		case fantasy.StreamPartTypeFinish:
+			res.finish = part.FinishReason
+			res.usage = part.Usage
+		case fantasy.StreamPartTypeError:
+			t.Fatalf("stream error: %v", part.Error)
+		}
+	}
+	res.text, res.reasoning = text.String(), reasoning.String()
+	return res
+}
+
+func TestConformance_StreamText(t *testing.T) {
+	for _, provider := range []string{"anthropic", "openai", "gemini", "openrouter"} {
+		t.Run(provider, func(t *testing.T) {
+			model, _ := replayModel(t, provider, "stream_text")
+
+			res := collectStream(t, model, greetingCall())
+			if res.text != "Hello there, friend!" {
+				t.Errorf("text = %q", res.text)
+			}
+			if res.textDeltas < 3 {
+				t.Errorf("got %d text deltas, want one per chunk", res.textDeltas)
+			}
+			if res.finish != fantasy.FinishReasonStop {
+				t.Errorf("finish reason = %q", res.finish)
+			}
+			if res.usage.OutputTokens == 0 {
+				t.Errorf("usage not parsed: %+v", res.usage)
+			}
+		})
+	}
+}
+
+func TestConformance_StreamReasoning(t *testing.T) {
+	model, _ := replayModel(t, "deepseek", "stream_reasoning")
+
+	res := collectStream(t, model, greetingCall())
+	if res.reasoning != "The user wants a short greeting." {
+		t.Errorf("reasoning = %q", res.reasoning)
+	}
+	if res.text != "Hello there, friend!" {
+		t.Errorf("text = %q", res.text)
+	}
+}
+
+func TestConformance_ErrorClassification(t *testing.T) {
+	tests := []struct {
+		provider, scenario string
+		want               FailoverReason
+	}{
+		{"anthropic", "overloaded", FailoverTimeout}, // 529 is a transient status
+		{"openai", "rate_limit", FailoverRateLimit},
+		{"gemini", "rate_limit", FailoverRateLimit},
+		{"openrouter", "unauthorized", FailoverAuth},
+		{"deepseek", "insufficient_balance", FailoverBilling},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider+"/"+tt.scenario, func(t *testing.T) {
+			model, _ := replayModel(t, tt.provider, tt.scenario)
+
+			_, err := model.Generate(context.Background(), weatherCall())
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			fe := ClassifyError(err, tt.provider, model.Model())
+			if fe == nil {
+				t.Fatalf("ClassifyError(%q) = nil, want %s", err, tt.want)
+			}
+			if fe.Reason != tt.want {
+				t.Errorf("ClassifyError(%q) = %s, want %s", err, fe.Reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestConformance_Fallback(t *testing.T) {
+	primary, _ := replayModel(t, "anthropic", "overloaded")
+	fallback, _ := replayModel(t, "openai", "tool_call")
+	cooldown := NewCooldownTracker()
+
+	model := NewFallbackLanguageModel(primary, []fantasy.LanguageModel{fallback}, cooldown)
+	resp, err := model.Generate(context.Background(), weatherCall())
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if calls := resp.Content.ToolCalls(); len(calls) != 1 || calls[0].ToolName != "get_weather" {
+		t.Errorf("expected the fallback's tool call, got %+v", resp.Content)
+	}
+	if cooldown.IsAvailable(primary.Provider()) {
+		t.Error("the overloaded provider should be in cooldown")
+	}
+
+	primary, _ = replayModel(t, "anthropic", "overloaded")
+	var pe *fantasy.ProviderError
+	_, err = NewFallbackLanguageModel(primary, nil, nil).Generate(context.Background(), weatherCall())
+	if !errors.As(err, &pe) || pe.StatusCode != 529 {
+		t.Errorf("exhausted fallback should wrap the provider error, got %v", err)
+	}
+}
@@ -0,0 +1,495 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"charm.land/fantasy"
+
+	"github.com/Agentx-network/agentx/pkg/config"
+	"github.com/Agentx-network/agentx/pkg/logger"
+	"github.com/Agentx-network/agentx/pkg/providers/ollama"
+)
+
+// Embeddings and reranking.
+//
+// Embedding and rerank models are model_list entries like chat models,
+// selected with agents.defaults.embedding_model and rerank_model. The
+// protocol prefix picks the API: OpenAI-compatible /embeddings (openai,
+// jina, vllm, ...), Ollama's /api/embed, or Gemini's batchEmbedContents.
+// Rerank models use the /rerank endpoint shared by Jina, Cohere, vLLM and
+// llama.cpp. A Ranker puts both behind one call for search features.
+
+// embeddingTimeout bounds one embedding or rerank request.
+const embeddingTimeout = 30 * time.Second
+
+// EmbeddingProvider turns texts into vectors for semantic search.
+type EmbeddingProvider interface {
+	// Embed returns one vector per text, in order.
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Reranker scores documents by their relevance to a query.
+type Reranker interface {
+	// Rerank returns one score per document, in document order. Higher is
+	// more relevant.
+	Rerank(ctx context.Context, query string, documents []string) ([]float64, error)
+}
+
+// EmbeddingProviderFromConfig creates the embedding provider for a
+// model_list entry.
+func EmbeddingProviderFromConfig(cfg *config.ModelConfig) (EmbeddingProvider, error) {
+	if cfg == nil || cfg.Model == "" {
+		return nil, fmt.Errorf("model is required")
+	}
+	protocol, modelID := ExtractProtocol(cfg.Model)
+	apiKey := strings.TrimSpace(cfg.APIKey)
+	client := &http.Client{Timeout: embeddingTimeout}
+
+	switch protocol {
+	case "ollama":
+		return &ollamaEmbedder{client: ollama.NewClient(cfg.APIBase, client), model: modelID}, nil
+	case "gemini", "google":
+		apiBase := apiBaseFor("gemini", cfg)
+		if cfg.APIBase != "" && !strings.Contains(apiBase, "/v1") {
+			// Chat takes the host only; the SDK adds the version
+			apiBase += "/v1beta"
+		}
+		return &geminiEmbedder{client: client, apiBase: apiBase, apiKey: apiKey, model: modelID}, nil
+	case "anthropic":
+		return nil, fmt.Errorf("anthropic has no embeddings API")
+	default:
+		apiBase := apiBaseFor(protocol, cfg)
+		if apiBase == "" {
+			return nil, fmt.Errorf("api_base is required for %s embeddings", protocol)
+		}
+		return &openAIEmbedder{client: client, apiBase: apiBase, apiKey: apiKey, model: modelID}, nil
+	}
+}
+
+// RerankerFromConfig creates the reranker for a model_list entry.
+func RerankerFromConfig(cfg *config.ModelConfig) (Reranker, error) {
+	if cfg == nil || cfg.Model == "" {
+		return nil, fmt.Errorf("model is required")
+	}
+	protocol, modelID := ExtractProtocol(cfg.Model)
+	switch {
+	case protocol == "anthropic" || protocol == "gemini" || protocol == "google" || protocol == "ollama",
+		protocol == "openai" && cfg.APIBase == "":
+		return nil, fmt.Errorf("%s has no rerank API", protocol)
+	}
+	apiBase := apiBaseFor(protocol, cfg)
+	if apiBase == "" {
+		return nil, fmt.Errorf("api_base is required for %s rerank", protocol)
+	}
+	return &rerankClient{
+		client:  &http.Client{Timeout: embeddingTimeout},
+		apiBase: apiBase,
+		apiKey:  strings.TrimSpace(cfg.APIKey),
+		model:   modelID,
+	}, nil
+}
+
+func apiBaseFor(protocol string, cfg *config.ModelConfig) string {
+	if cfg.APIBase != "" {
+		return strings.TrimRight(cfg.APIBase, "/")
+	}
+	return getDefaultAPIBase(protocol)
+}
+
+// openAIEmbedder calls an OpenAI-compatible /embeddings endpoint.
+type openAIEmbedder struct {
+	client  *http.Client
+	apiBase string
+	apiKey  string
+	model   string
+}
+
+func (e *openAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var out struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	body := map[string]any{"model": e.model, "input": texts}
+	if err := postJSON(ctx, e.client, e.apiBase+"/embeddings", bearer(e.apiKey), body, &out); err != nil {
+		return nil, err
+	}
+	vectors := make([][]float32, len(texts))
+	for _, d := range out.Data {
+		if d.Index >= 0 && d.Index < len(vectors) {
+			vectors[d.Index] = d.Embedding
+		}
+	}
+	return vectors, checkVectors(vectors)
+}
+
+// geminiEmbedder calls Gemini's batchEmbedContents.
+type geminiEmbedder struct {
+	client  *http.Client
+	apiBase string
+	apiKey  string
+	model   string
+}
+
+func (e *geminiEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	type part struct {
+		Text string `json:"text"`
+	}
+	type request struct {
+		Model   string `json:"model"`
+		Content struct {
+			Parts []part `json:"parts"`
+		} `json:"content"`
+	}
+	reqs := make([]request, len(texts))
+	for i, text := range texts {
+		reqs[i].Model = "models/" + e.model
+		reqs[i].Content.Parts = []part{{Text: text}}
+	}
+
+	var out struct {
+		Embeddings []struct {
+			Values []float32 `json:"values"`
+		} `json:"embeddings"`
+	}
+	endpoint := fmt.Sprintf("%s/models/%s:batchEmbedContents", e.apiBase, url.PathEscape(e.model))
+	headers := map[string]string{"x-goog-api-key": e.apiKey}
+	if err := postJSON(ctx, e.client, endpoint, headers, map[string]any{"requests": reqs}, &out); err != nil {
+		return nil, err
+	}
+	if len(out.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("gemini returned %d embeddings for %d texts", len(out.Embeddings), len(texts))
+	}
+	vectors := make([][]float32, len(texts))
+	for i, emb := range out.Embeddings {
+		vectors[i] = emb.Values
+	}
+	return vectors, checkVectors(vectors)
+}
+
+// ollamaEmbedder calls Ollama's native /api/embed.
+type ollamaEmbedder struct {
+	client *ollama.Client
+	model  string
+}
+
+func (e *ollamaEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return e.client.Embed(ctx, e.model, texts)
+}
+
+// rerankClient calls a /rerank endpoint (Jina, Cohere, vLLM, llama.cpp).
+type rerankClient struct {
+	client  *http.Client
+	apiBase string
+	apiKey  string
+	model   string
+}
+
+func (r *rerankClient) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	var out struct {
+		Results []struct {
+			Index          int     `json:"index"`
+			RelevanceScore float64 `json:"relevance_score"`
+		} `json:"results"`
+	}
+	body := map[string]any{
+		"model":     r.model,
+		"query":     query,
+		"documents": documents,
+		"top_n":     len(documents),
+	}
+	if err := postJSON(ctx, r.client, r.apiBase+"/rerank", bearer(r.apiKey), body, &out); err != nil {
+		return nil, err
+	}
+	if len(out.Results) != len(documents) {
+		return nil, fmt.Errorf("rerank returned %d scores for %d documents", len(out.Results), len(documents))
+	}
+	scores := make([]float64, len(documents))
+	for _, res := range out.Results {
+		if res.Index < 0 || res.Index >= len(scores) {
+			return nil, fmt.Errorf("rerank returned index %d for %d documents", res.Index, len(documents))
+		}
+		scores[res.Index] = res.RelevanceScore
+	}
+	return scores, nil
+}
+
+func bearer(apiKey string) map[string]string {
+	if apiKey == "" {
+		return nil
+	}
+	return map[string]string{"Authorization": "Bearer " + apiKey}
+}
+
+// postJSON sends body to endpoint and decodes the response into out. Error
+// statuses are returned as a *fantasy.ProviderError so ClassifyError sees
+// the status code.
+func postJSON(ctx context.Context, client *http.Client, endpoint string, headers map[string]string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return &fantasy.ProviderError{
+			Title:        fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)),
+			Message:      strings.TrimSpace(string(respBody)),
+			URL:          endpoint,
+			StatusCode:   resp.StatusCode,
+			ResponseBody: respBody,
+		}
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func checkVectors(vectors [][]float32) error {
+	for i, v := range vectors {
+		if len(v) == 0 {
+			return fmt.Errorf("no embedding returned for input %d", i)
+		}
+	}
+	return nil
+}
+
+// modelCandidate is one model a fallback provider can use.
+type modelCandidate[T any] struct {
+	name  string // model_list name, for logs and cooldown
+	model T
+}
+
+// FallbackEmbeddingProvider tries embedding models in order, skipping models
+// in cooldown, the way FallbackChain does for chat models. Vectors from
+// different models cannot be compared, so callers should embed the texts
+// they compare in a single call.
+type FallbackEmbeddingProvider struct {
+	candidates []modelCandidate[EmbeddingProvider]
+	cooldown   *CooldownTracker
+}
+
+// Embed tries each candidate until one succeeds. Errors that would fail on
+// every model (bad request) or that ClassifyError does not recognise are
+// returned right away.
+func (f *FallbackEmbeddingProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return tryModels(ctx, f.cooldown, "embedding", f.candidates, func(e EmbeddingProvider) ([][]float32, error) {
+		return e.Embed(ctx, texts)
+	})
+}
+
+// FallbackReranker tries rerank models in order like
+// FallbackEmbeddingProvider.
+type FallbackReranker struct {
+	candidates []modelCandidate[Reranker]
+	cooldown   *CooldownTracker
+}
+
+// Rerank tries each candidate until one succeeds, with the same rules as
+// FallbackEmbeddingProvider.Embed.
+func (f *FallbackReranker) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	return tryModels(ctx, f.cooldown, "rerank", f.candidates, func(r Reranker) ([]float64, error) {
+		return r.Rerank(ctx, query, documents)
+	})
+}
+
+// tryModels calls each candidate not in cooldown until one succeeds. kind
+// names the models in logs and errors.
+func tryModels[T, R any](ctx context.Context, cooldown *CooldownTracker, kind string, candidates []modelCandidate[T], call func(T) (R, error)) (R, error) {
+	var zero R
+	var lastErr error
+	for _, c := range candidates {
+		if ctx.Err() != nil {
+			return zero, ctx.Err()
+		}
+		if !cooldown.IsAvailable(c.name) {
+			lastErr = fmt.Errorf("%s model %s in cooldown", kind, c.name)
+			continue
+		}
+
+		out, err := call(c.model)
+		if err == nil {
+			cooldown.MarkSuccess(c.name)
+			return out, nil
+		}
+		lastErr = err
+		failErr := ClassifyError(err, c.name, c.name)
+		if failErr == nil || !failErr.IsRetriable() {
+			return zero, err
+		}
+		cooldown.MarkFailure(c.name, failErr.Reason)
+		logger.WarnCF("providers", "Model failed, trying next",
+			map[string]any{
+				"kind":   kind,
+				"model":  c.name,
+				"reason": string(failErr.Reason),
+				"error":  err.Error(),
+			})
+	}
+	return zero, fmt.Errorf("all %s models failed: %w", kind, lastErr)
+}
+
+// modelCandidates builds a candidate for every model_list entry of each
+// name, in order. Entries that fail to build are skipped; the last error
+// is returned when none could be built.
+func modelCandidates[T any](cfg *config.Config, kind string, names []string, build func(*config.ModelConfig) (T, error)) ([]modelCandidate[T], error) {
+	var candidates []modelCandidate[T]
+	var lastErr error
+	for _, n := range names {
+		for i, modelCfg := range modelConfigsForName(cfg, n) {
+			model, err := build(&modelCfg)
+			if err != nil {
+				lastErr = fmt.Errorf("%s model %q: %w", kind, n, err)
+				continue
+			}
+			candidateName := n
+			if i > 0 {
+				candidateName = fmt.Sprintf("%s#%d", n, i+1)
+			}
+			candidates = append(candidates, modelCandidate[T]{name: candidateName, model: model})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, lastErr
+	}
+	return candidates, nil
+}
+
+// NewEmbeddingProvider creates the embedding provider for a model_list
+// name, trying every entry with that name and then the fallbacks in order.
+// Names not in model_list are treated as protocol/model identifiers.
+func NewEmbeddingProvider(cfg *config.Config, name string, fallbacks []string) (EmbeddingProvider, error) {
+	candidates, err := modelCandidates(cfg, "embedding", append([]string{name}, fallbacks...), EmbeddingProviderFromConfig)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 1 {
+		return candidates[0].model, nil
+	}
+	return &FallbackEmbeddingProvider{candidates: candidates, cooldown: NewCooldownTracker()}, nil
+}
+
+// NewReranker creates the reranker for a model_list name, trying every
+// entry with that name in order.
+func NewReranker(cfg *config.Config, name string) (Reranker, error) {
+	candidates, err := modelCandidates(cfg, "rerank", []string{name}, RerankerFromConfig)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 1 {
+		return candidates[0].model, nil
+	}
+	return &FallbackReranker{candidates: candidates, cooldown: NewCooldownTracker()}, nil
+}
+
+// modelConfigsForName returns the model_list entries for name, or an entry
+// built from name itself when it is not in model_list.
+func modelConfigsForName(cfg *config.Config, name string) []config.ModelConfig {
+	if modelCfgs, err := cfg.GetModelConfigs(name); err == nil {
+		return modelCfgs
+	}
+	return []config.ModelConfig{{ModelName: name, Model: name}}
+}
+
+// Ranker scores search results against a query: with the rerank model when
+// one is configured, and by embedding similarity otherwise or when
+// reranking fails.
+type Ranker struct {
+	Embeddings EmbeddingProvider
+	Reranker   Reranker
+}
+
+// NewRanker creates a Ranker from agents.defaults.embedding_model and
+// rerank_model. It returns nil when neither is configured or usable.
+func NewRanker(cfg *config.Config) *Ranker {
+	defaults := cfg.Agents.Defaults
+	r := &Ranker{}
+	if defaults.EmbeddingModel != "" {
+		embeddings, err := NewEmbeddingProvider(cfg, defaults.EmbeddingModel, defaults.EmbeddingModelFallbacks)
+		if err != nil {
+			logger.WarnCF("providers", "Embedding model unavailable",
+				map[string]any{"model": defaults.EmbeddingModel, "error": err.Error()})
+		} else {
+			r.Embeddings = embeddings
+		}
+	}
+	if defaults.RerankModel != "" {
+		reranker, err := NewReranker(cfg, defaults.RerankModel)
+		if err != nil {
+			logger.WarnCF("providers", "Rerank model unavailable",
+				map[string]any{"model": defaults.RerankModel, "error": err.Error()})
+		} else {
+			r.Reranker = reranker
+		}
+	}
+	if r.Embeddings == nil && r.Reranker == nil {
+		return nil
+	}
+	return r
+}
+
+// Scores returns a relevance score per document, in document order.
+func (r *Ranker) Scores(ctx context.Context, query string, documents []string) ([]float64, error) {
+	if len(documents) == 0 {
+		return nil, nil
+	}
+	if r.Reranker != nil {
+		scores, err := r.Reranker.Rerank(ctx, query, documents)
+		if err == nil || r.Embeddings == nil {
+			return scores, err
+		}
+		logger.WarnCF("providers", "Rerank failed, using embeddings",
+			map[string]any{"error": err.Error()})
+	}
+
+	// The query and documents are embedded together so they come from the
+	// same model, even if the call falls back.
+	vectors, err := r.Embeddings.Embed(ctx, append([]string{query}, documents...))
+	if err != nil {
+		return nil, err
+	}
+	scores := make([]float64, len(documents))
+	for i := range documents {
+		scores[i] = CosineSimilarity(vectors[0], vectors[i+1])
+	}
+	return scores, nil
+}
+
+// CosineSimilarity returns the cosine of the angle between a and b, or 0
+// when they differ in length or either is zero.
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,236 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Agentx-network/agentx/pkg/config"
+)
+
+func TestOpenAIEmbedder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			t.Errorf("unexpected request %s (auth %q)", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var body struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Model != "text-embedding-3-small" || len(body.Input) != 2 {
+			t.Errorf("unexpected body %+v", body)
+		}
+		// Results may come back out of order.
+		fmt.Fprint(w, `{"object":"list","data":[{"object":"embedding","index":1,"embedding":[0,1]},{"object":"embedding","index":0,"embedding":[1,0]}]}`)
+	}))
+	defer srv.Close()
+
+	embedder, err := EmbeddingProviderFromConfig(&config.ModelConfig{
+		Model:   "openai/text-embedding-3-small",
+		APIBase: srv.URL + "/v1/",
+		APIKey:  "sk-test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vectors, err := embedder.Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vectors[0][0] != 1 || vectors[1][1] != 1 {
+		t.Errorf("vectors not in input order: %v", vectors)
+	}
+}
+
+func TestGeminiEmbedder(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/text-embedding-004:batchEmbedContents" || r.Header.Get("x-goog-api-key") != "g-key" {
+			t.Errorf("unexpected request %s", r.URL.Path)
+		}
+		var body struct {
+			Requests []struct {
+				Model string `json:"model"`
+			} `json:"requests"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if len(body.Requests) != 1 || body.Requests[0].Model != "models/text-embedding-004" {
+			t.Errorf("unexpected body %+v", body)
+		}
+		fmt.Fprint(w, `{"embeddings":[{"values":[0.5,0.5]}]}`)
+	}))
+	defer srv.Close()
+
+	embedder, err := EmbeddingProviderFromConfig(&config.ModelConfig{
+		Model:   "gemini/text-embedding-004",
+		APIBase: srv.URL,
+		APIKey:  "g-key",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := embedder.Embed(context.Background(), []string{"hello"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReranker(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/rerank" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		// Results come back sorted by relevance.
+		fmt.Fprint(w, `{"model":"jina-reranker-v2-base-multilingual","results":[{"index":1,"relevance_score":0.9},{"index":0,"relevance_score":0.2}]}`)
+	}))
+	defer srv.Close()
+
+	reranker, err := RerankerFromConfig(&config.ModelConfig{Model: "jina/jina-reranker-v2-base-multilingual", APIBase: srv.URL + "/v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	scores, err := reranker.Rerank(context.Background(), "backups", []string{"weather", "backup script"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scores[0] != 0.2 || scores[1] != 0.9 {
+		t.Errorf("scores not in document order: %v", scores)
+	}
+
+	if _, err := RerankerFromConfig(&config.ModelConfig{Model: "anthropic/claude-sonnet-4"}); err == nil {
+		t.Error("anthropic has no rerank API")
+	}
+}
+
+// fixedEmbedder returns a fixed vector per text, or an error.
+type fixedEmbedder struct {
+	vectors map[string][]float32
+	err     error
+	calls   int
+}
+
+func (e *fixedEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.calls++
+	if e.err != nil {
+		return nil, e.err
+	}
+	out := make([][]float32, len(texts))
+	for i, text := range texts {
+		out[i] = e.vectors[text]
+	}
+	return out, nil
+}
+
+func TestFallbackEmbeddingProvider(t *testing.T) {
+	primary := &fixedEmbedder{err: fmt.Errorf("status: 429 too many requests")}
+	backup := &fixedEmbedder{vectors: map[string][]float32{"a": {1}}}
+	f := &FallbackEmbeddingProvider{
+		candidates: []modelCandidate[EmbeddingProvider]{{name: "primary", model: primary}, {name: "backup", model: backup}},
+		cooldown:   NewCooldownTracker(),
+	}
+
+	if _, err := f.Embed(context.Background(), []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if f.cooldown.IsAvailable("primary") {
+		t.Error("the rate limited model should be in cooldown")
+	}
+	f.Embed(context.Background(), []string{"a"})
+	if primary.calls != 1 || backup.calls != 2 {
+		t.Errorf("calls: primary %d, backup %d", primary.calls, backup.calls)
+	}
+
+	// Bad requests would fail on every model.
+	primary.err = fmt.Errorf("status: 400 input too long")
+	f.cooldown = NewCooldownTracker()
+	if _, err := f.Embed(context.Background(), []string{"a"}); err == nil || backup.calls != 2 {
+		t.Errorf("expected no fallback on a format error, got %v", err)
+	}
+}
+
+func TestRanker_Scores(t *testing.T) {
+	embedder := &fixedEmbedder{vectors: map[string][]float32{
+		"query": {1, 0},
+		"same":  {2, 0},
+		"other": {0, 3},
+	}}
+	r := &Ranker{Embeddings: embedder}
+	scores, err := r.Scores(context.Background(), "query", []string{"other", "same"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(scores[0]) > 1e-9 || math.Abs(scores[1]-1) > 1e-9 {
+		t.Errorf("scores = %v", scores)
+	}
+	if embedder.calls != 1 {
+		t.Errorf("query and documents should be embedded in one call, got %d", embedder.calls)
+	}
+
+	// A failing reranker falls back to embeddings.
+	r.Reranker = failingReranker{}
+	if scores, err := r.Scores(context.Background(), "query", []string{"same"}); err != nil || scores[0] < 0.99 {
+		t.Errorf("got %v, %v", scores, err)
+	}
+}
+
+type failingReranker struct{}
+
+func (failingReranker) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	return nil, fmt.Errorf("status: 503 unavailable")
+}
+
+func TestNewRanker(t *testing.T) {
+	cfg := &config.Config{
+		ModelList: []config.ModelConfig{
+			{ModelName: "embed", Model: "ollama/nomic-embed-text"},
+		},
+	}
+	if NewRanker(cfg) != nil {
+		t.Error("no ranker without embedding_model or rerank_model")
+	}
+	cfg.Agents.Defaults.EmbeddingModel = "embed"
+	cfg.Agents.Defaults.EmbeddingModelFallbacks = []string{"openai/text-embedding-3-small"}
+	r := NewRanker(cfg)
+	if r == nil {
+		t.Fatal("expected a ranker")
+	}
+	if _, ok := r.Embeddings.(*FallbackEmbeddingProvider); !ok {
+		t.Errorf("fallbacks should wrap the embedding model, got %T", r.Embeddings)
+	}
+}
+
+func TestNewReranker_FallsBackAcrossEntries(t *testing.T) {
+	var primaryCalls int
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primaryCalls++
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+	backup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"results":[{"index":0,"relevance_score":0.7}]}`)
+	}))
+	defer backup.Close()
+
+	cfg := &config.Config{
+		ModelList: []config.ModelConfig{
+			{ModelName: "rerank", Model: "jina/jina-reranker-v2-base-multilingual", APIBase: primary.URL + "/v1"},
+			{ModelName: "rerank", Model: "jina/jina-reranker-v2-base-multilingual", APIBase: backup.URL + "/v1"},
+		},
+	}
+	reranker, err := NewReranker(cfg, "rerank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		scores, err := reranker.Rerank(context.Background(), "backups", []string{"backup script"})
+		if err != nil || len(scores) != 1 || scores[0] != 0.7 {
+			t.Fatalf("got %v, %v", scores, err)
+		}
+	}
+	if primaryCalls != 1 {
+		t.Errorf("the failing entry should be in cooldown, called %d times", primaryCalls)
+	}
+}
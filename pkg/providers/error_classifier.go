@@ -2,8 +2,11 @@ package providers
 
 import (
 	"context"
+	"errors"
 	"regexp"
 	"strings"
+
+	"charm.land/fantasy"
 )
 
 // errorPattern defines a single pattern (string or regex) for error classification.
@@ -128,8 +131,14 @@ func ClassifyError(err error, provider, model string) *FailoverError {
 		}
 	}
 
-	// Try HTTP status code extraction first.
-	if status := extractHTTPStatus(msg); status > 0 {
+	// Try the HTTP status code first: from the provider error when there is
+	// one, since error messages can contain other numbers (ports, IDs).
+	status := extractHTTPStatus(msg)
+	var providerErr *fantasy.ProviderError
+	if errors.As(err, &providerErr) && providerErr.StatusCode > 0 {
+		status = providerErr.StatusCode
+	}
+	if status > 0 {
 		if reason := classifyByStatus(status); reason != "" {
 			return &FailoverError{
 				Reason:   reason,
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+
+	"charm.land/fantasy"
 )
 
 func TestClassifyError_Nil(t *testing.T) {
@@ -65,6 +67,19 @@ func TestClassifyError_StatusCodes(t *testing.T) {
 	}
 }
 
+func TestClassifyError_ProviderErrorStatus(t *testing.T) {
+	// The URL's port contains "429"; the status code must win.
+	err := fmt.Errorf("wrapped: %w", &fantasy.ProviderError{
+		Title:      "unauthorized",
+		Message:    `POST "http://127.0.0.1:54290/chat/completions": 401 Unauthorized`,
+		StatusCode: 401,
+	})
+	result := ClassifyError(err, "openrouter", "model")
+	if result == nil || result.Reason != FailoverAuth || result.Status != 401 {
+		t.Errorf("got %+v, want auth with status 401", result)
+	}
+}
+
 func TestClassifyError_RateLimitPatterns(t *testing.T) {
 	patterns := []string{
 		"rate limit exceeded",
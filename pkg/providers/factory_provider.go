@@ -50,6 +50,8 @@ func getDefaultAPIBase(protocol string) string {
 		return "http://localhost:8000/v1"
 	case "mistral":
 		return "https://api.mistral.ai/v1"
+	case "jina":
+		return "https://api.jina.ai/v1"
 	default:
 		return ""
 	}
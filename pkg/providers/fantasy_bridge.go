@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"charm.land/fantasy"
 	"charm.land/fantasy/providers/anthropic"
@@ -12,6 +13,8 @@ import (
 	"charm.land/fantasy/providers/openaicompat"
 
 	"github.com/Agentx-network/agentx/pkg/config"
+	"github.com/Agentx-network/agentx/pkg/logger"
+	"github.com/Agentx-network/agentx/pkg/providers/ollama"
 )
 
 // FantasyModelFromConfig creates a Fantasy LanguageModel from a ModelConfig.
@@ -39,7 +42,7 @@ func FantasyModelFromConfig(cfg *config.ModelConfig) (fantasy.LanguageModel, err
 		return nil, fmt.Errorf("creating language model %s: %w", modelID, err)
 	}
 
-	return model, nil
+	return WithReasoning(model, protocol, cfg.Reasoning), nil
 }
 
 // createFantasyProvider creates the appropriate Fantasy provider for a given protocol.
@@ -54,7 +57,10 @@ func createFantasyProvider(protocol string, cfg *config.ModelConfig) (fantasy.Pr
 	case "gemini", "google":
 		return createGoogleProvider(cfg)
 
-	case "openrouter", "groq", "deepseek", "ollama", "mistral", "cerebras",
+	case "ollama":
+		return createOllamaProvider(cfg)
+
+	case "openrouter", "groq", "deepseek", "mistral", "cerebras",
 		"qwen", "vllm", "nvidia", "moonshot", "zhipu", "volcengine",
 		"shengsuanyun":
 		return createOpenAICompatProvider(protocol, cfg)
@@ -105,6 +111,16 @@ func createGoogleProvider(cfg *config.ModelConfig) (fantasy.Provider, error) {
 	return google.New(opts...)
 }
 
+func createOllamaProvider(cfg *config.ModelConfig) (fantasy.Provider, error) {
+	return ollama.New(
+		ollama.WithBaseURL(cfg.APIBase),
+		ollama.WithKeepAlive(cfg.KeepAlive),
+		ollama.WithNumCtx(cfg.NumCtx),
+		ollama.WithAutoPull(cfg.AutoPull),
+		ollama.WithThink(cfg.Reasoning != nil),
+	)
+}
+
 func createOpenAICompatProvider(protocol string, cfg *config.ModelConfig) (fantasy.Provider, error) {
 	var opts []openaicompat.Option
 	if cfg.APIKey != "" {
@@ -144,10 +160,76 @@ func FantasyModelFromFullConfig(cfg *config.Config) (fantasy.LanguageModel, erro
 		return nil, fmt.Errorf("no providers configured")
 	}
 
-	modelCfg, err := cfg.GetModelConfig(model)
+	return FantasyModelForName(cfg, model)
+}
+
+// FantasyModelForName creates a Fantasy LanguageModel for a model_name.
+// When several model_list entries share the name, or an entry sets rpm or
+// tpm, requests are spread over them by a BalancedLanguageModel using the
+// configured load balancing strategy. Each call creates a new pool with its
+// own budgets and cooldowns, so callers should create one per name and
+// share it.
+func FantasyModelForName(cfg *config.Config, name string) (fantasy.LanguageModel, error) {
+	modelCfgs, err := cfg.GetModelConfigs(name)
 	if err != nil {
-		return nil, fmt.Errorf("model %q not found in model_list: %w", model, err)
+		return nil, fmt.Errorf("model %q not found in model_list: %w", name, err)
+	}
+	if len(modelCfgs) == 1 && modelCfgs[0].RPM <= 0 && modelCfgs[0].TPM <= 0 {
+		return FantasyModelFromConfig(&modelCfgs[0])
+	}
+
+	members := make([]BalancedMember, 0, len(modelCfgs))
+	var lastErr error
+	for i := range modelCfgs {
+		modelCfg := &modelCfgs[i]
+		model, err := FantasyModelFromConfig(modelCfg)
+		if err != nil {
+			logger.WarnCF("providers", "Failed to create model_list entry, leaving it out of the pool",
+				map[string]any{
+					"model_name": name,
+					"entry":      i + 1,
+					"error":      err.Error(),
+				})
+			lastErr = err
+			continue
+		}
+		members = append(members, BalancedMember{
+			Model:  model,
+			Name:   fmt.Sprintf("%s#%d", name, i+1),
+			Weight: modelCfg.Weight,
+			RPM:    modelCfg.RPM,
+			TPM:    modelCfg.TPM,
+		})
+	}
+	if len(members) == 0 {
+		return nil, lastErr
 	}
 
-	return FantasyModelFromConfig(modelCfg)
+	lb := cfg.LoadBalancing
+	return NewBalancedLanguageModel(lb.Strategy, members, NewCooldownTracker(),
+		time.Duration(lb.MaxWait)*time.Second), nil
+}
+
+// ContextWindowForName returns the context window of a native Ollama model
+// in model_list: its configured num_ctx, or the one detected from the
+// server. It returns 0 for other protocols or when the server is unreachable.
+func ContextWindowForName(ctx context.Context, cfg *config.Config, name string) int {
+	mc, err := cfg.GetModelConfig(name)
+	if err != nil {
+		return 0
+	}
+	protocol, modelID := ExtractProtocol(mc.Model)
+	if protocol != "ollama" {
+		return 0
+	}
+	if mc.NumCtx > 0 {
+		return mc.NumCtx
+	}
+	info, err := ollama.NewClient(mc.APIBase, nil).Show(ctx, modelID)
+	if err != nil {
+		logger.DebugCF("providers", "Could not detect Ollama context window",
+			map[string]any{"model": modelID, "error": err.Error()})
+		return 0
+	}
+	return ollama.EffectiveNumCtx(info, 0)
 }
@@ -7,7 +7,6 @@ import (
 
 	"charm.land/fantasy"
 
-	"github.com/Agentx-network/agentx/pkg/config"
 	"github.com/Agentx-network/agentx/pkg/logger"
 )
 
@@ -25,32 +24,15 @@ type fallbackModelCandidate struct {
 	modelID  string
 }
 
-// NewFallbackLanguageModel creates a FallbackLanguageModel from a primary model and fallback configs.
+// NewFallbackLanguageModel creates a FallbackLanguageModel from a primary model and
+// the fallback models to try after it, in order.
 func NewFallbackLanguageModel(
 	primary fantasy.LanguageModel,
-	fallbackCfgs []*config.ModelConfig,
+	fallbacks []fantasy.LanguageModel,
 	cooldown *CooldownTracker,
 ) *FallbackLanguageModel {
-	candidates := make([]fallbackModelCandidate, 0, len(fallbackCfgs)+1)
-
-	// Primary is always first
-	candidates = append(candidates, fallbackModelCandidate{
-		model:    primary,
-		provider: primary.Provider(),
-		modelID:  primary.Model(),
-	})
-
-	// Add fallbacks
-	for _, cfg := range fallbackCfgs {
-		model, err := FantasyModelFromConfig(cfg)
-		if err != nil {
-			logger.WarnCF("providers", "Failed to create fallback model",
-				map[string]any{
-					"model": cfg.Model,
-					"error": err.Error(),
-				})
-			continue
-		}
+	candidates := make([]fallbackModelCandidate, 0, len(fallbacks)+1)
+	for _, model := range append([]fantasy.LanguageModel{primary}, fallbacks...) {
 		candidates = append(candidates, fallbackModelCandidate{
 			model:    model,
 			provider: model.Provider(),
@@ -99,8 +81,9 @@ func (f *FallbackLanguageModel) Generate(ctx context.Context, call fantasy.Call)
 		lastErr = err
 		errMsg := strings.ToLower(err.Error())
 
-		// Non-retriable: format errors
-		if strings.Contains(errMsg, "invalid") && strings.Contains(errMsg, "request") {
+		// Non-retriable: format errors, including images the caller must shrink
+		if (strings.Contains(errMsg, "invalid") && strings.Contains(errMsg, "request")) ||
+			IsImageDimensionError(errMsg) || IsImageSizeError(errMsg) {
 			return nil, err
 		}
 
@@ -25,7 +25,11 @@ func convertOneMessage(msg Message) fantasy.Message {
 		if len(msg.SystemParts) > 0 {
 			parts := make([]fantasy.MessagePart, 0, len(msg.SystemParts))
 			for _, block := range msg.SystemParts {
-				parts = append(parts, fantasy.TextPart{Text: block.Text})
+				part := fantasy.TextPart{Text: block.Text}
+				if block.CacheControl != nil {
+					part.ProviderOptions = cacheControlOptions()
+				}
+				parts = append(parts, part)
 			}
 			return fantasy.Message{
 				Role:    fantasy.MessageRoleSystem,
@@ -35,16 +39,21 @@ func convertOneMessage(msg Message) fantasy.Message {
 		return fantasy.NewSystemMessage(msg.Content)
 
 	case "user":
-		return fantasy.NewUserMessage(msg.Content)
+		return fantasy.NewUserMessage(msg.Content, MediaToFantasyFiles(msg.Media)...)
 
 	case "assistant":
+		// Reasoning goes first: Anthropic expects the thinking block to
+		// open the turn it belongs to.
 		parts := make([]fantasy.MessagePart, 0)
+		if msg.ReasoningContent != "" {
+			parts = append(parts, fantasy.ReasoningPart{
+				Text:            msg.ReasoningContent,
+				ProviderOptions: decodeReasoningMetadata(msg.ReasoningData),
+			})
+		}
 		if msg.Content != "" {
 			parts = append(parts, fantasy.TextPart{Text: msg.Content})
 		}
-		if msg.ReasoningContent != "" {
-			parts = append(parts, fantasy.ReasoningPart{Text: msg.ReasoningContent})
-		}
 		// Convert tool calls to Fantasy ToolCallParts
 		for _, tc := range msg.ToolCalls {
 			argsStr := ""
@@ -103,6 +112,7 @@ func FantasyStepToAgentXMessages(step fantasy.StepResult) []Message {
 	}
 
 	var toolResultMsgs []Message
+	var reasoning []fantasy.ReasoningContent
 
 	for _, content := range step.Content {
 		switch content.GetType() {
@@ -112,7 +122,7 @@ func FantasyStepToAgentXMessages(step fantasy.StepResult) []Message {
 			}
 		case fantasy.ContentTypeReasoning:
 			if rc, ok := fantasy.AsContentType[fantasy.ReasoningContent](content); ok {
-				assistantMsg.ReasoningContent = rc.Text
+				reasoning = append(reasoning, rc)
 			}
 		case fantasy.ContentTypeToolCall:
 			if tc, ok := fantasy.AsContentType[fantasy.ToolCallContent](content); ok {
@@ -147,6 +157,22 @@ func FantasyStepToAgentXMessages(step fantasy.StepResult) []Message {
 		}
 	}
 
+	// A signature covers a single thinking block, so provider data is only
+	// kept when there is one. Reasoning the model wrote as <think> tags is
+	// moved out of the reply.
+	tagged, content := SplitThinkTags(assistantMsg.Content)
+	assistantMsg.Content = content
+	texts := make([]string, 0, len(reasoning)+1)
+	for _, rc := range reasoning {
+		texts = append(texts, rc.Text)
+	}
+	assistantMsg.ReasoningContent = joinReasoning(append(texts, tagged)...)
+	if len(reasoning) == 1 && tagged == "" {
+		// Keep the signed text exactly as it was returned
+		assistantMsg.ReasoningContent = reasoning[0].Text
+		assistantMsg.ReasoningData = encodeReasoningMetadata(reasoning[0].ProviderMetadata)
+	}
+
 	// Only add assistant message if it has content or tool calls
 	if assistantMsg.Content != "" || len(assistantMsg.ToolCalls) > 0 {
 		result = append(result, assistantMsg)
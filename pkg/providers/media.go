@@ -0,0 +1,352 @@
+package providers
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // register GIF decoder for ResizeImage
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"charm.land/fantasy"
+
+	"github.com/Agentx-network/agentx/pkg/logger"
+)
+
+// Media types for MediaPart.Type.
+const (
+	MediaTypeImage = "image"
+	MediaTypeAudio = "audio"
+	MediaTypeFile  = "file"
+)
+
+// ResizedImageMaxDim is the longest side images are scaled down to when a
+// provider rejects them for their dimensions or file size. It is within the
+// limits of all supported vision APIs.
+const ResizedImageMaxDim = 1568
+
+// NewMediaPart describes the local file at path. It returns false for
+// remote URLs and files that cannot be read.
+func NewMediaPart(path string) (MediaPart, bool) {
+	if path == "" || strings.Contains(path, "://") {
+		return MediaPart{}, false
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return MediaPart{}, false
+	}
+
+	mimeType := mime.TypeByExtension(strings.ToLower(filepath.Ext(path)))
+	if mimeType == "" {
+		mimeType = sniffContentType(path)
+	}
+	if i := strings.Index(mimeType, ";"); i >= 0 {
+		mimeType = strings.TrimSpace(mimeType[:i])
+	}
+
+	mediaType := MediaTypeFile
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		mediaType = MediaTypeImage
+	case strings.HasPrefix(mimeType, "audio/"), mimeType == "application/ogg":
+		mediaType = MediaTypeAudio
+	}
+
+	return MediaPart{
+		Type:     mediaType,
+		Path:     path,
+		MIMEType: mimeType,
+		Filename: filepath.Base(path),
+	}, true
+}
+
+// MediaFromPaths converts inbound media paths into MediaParts, skipping
+// remote URLs and missing files.
+func MediaFromPaths(paths []string) []MediaPart {
+	var parts []MediaPart
+	for _, path := range paths {
+		part, ok := NewMediaPart(path)
+		if !ok {
+			logger.DebugCF("providers", "Skipping media that is not a local file", map[string]any{"path": path})
+			continue
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// FilterMedia returns the parts of the given type.
+func FilterMedia(media []MediaPart, mediaType string) []MediaPart {
+	var out []MediaPart
+	for _, m := range media {
+		if m.Type == mediaType {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// HasImages reports whether any message carries an image attachment.
+func HasImages(messages []Message) bool {
+	for _, msg := range messages {
+		for _, m := range msg.Media {
+			if m.Type == MediaTypeImage {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsImageError reports whether err is a provider rejecting an image for its
+// dimensions or file size, which shrinking the image can fix.
+func IsImageError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	return IsImageDimensionError(msg) || IsImageSizeError(msg)
+}
+
+// MediaToFantasyFiles reads media files into Fantasy file parts. Files that
+// can no longer be read are skipped with a warning.
+func MediaToFantasyFiles(media []MediaPart) []fantasy.FilePart {
+	files := make([]fantasy.FilePart, 0, len(media))
+	for _, m := range media {
+		data, err := os.ReadFile(m.Path)
+		if err != nil {
+			logger.WarnCF("providers", "Failed to read media attachment", map[string]any{
+				"path":  m.Path,
+				"error": err.Error(),
+			})
+			continue
+		}
+		files = append(files, fantasy.FilePart{
+			Filename:  m.Filename,
+			Data:      data,
+			MediaType: m.MIMEType,
+		})
+	}
+	return files
+}
+
+// ShrinkImages writes copies of the image attachments in messages scaled
+// down to maxDim into dir and returns messages pointing at them, along with
+// the paths of the new files so the caller can remove them. Messages are
+// copied; the input slice is not modified.
+func ShrinkImages(messages []Message, maxDim int, dir string) ([]Message, []string) {
+	var created []string
+	out := make([]Message, len(messages))
+	for i, msg := range messages {
+		out[i] = msg
+		if len(msg.Media) == 0 {
+			continue
+		}
+		media := make([]MediaPart, len(msg.Media))
+		for j, m := range msg.Media {
+			media[j] = m
+			if m.Type != MediaTypeImage {
+				continue
+			}
+			resized, err := shrinkImageFile(m, maxDim, dir)
+			if err != nil {
+				logger.WarnCF("providers", "Failed to resize image", map[string]any{
+					"path":  m.Path,
+					"error": err.Error(),
+				})
+				continue
+			}
+			media[j] = resized
+			created = append(created, resized.Path)
+		}
+		out[i].Media = media
+	}
+	return out, created
+}
+
+func shrinkImageFile(m MediaPart, maxDim int, dir string) (MediaPart, error) {
+	data, err := os.ReadFile(m.Path)
+	if err != nil {
+		return m, err
+	}
+	resized, mimeType, err := ResizeImage(data, maxDim)
+	if err != nil {
+		return m, err
+	}
+
+	ext := ".jpg"
+	if mimeType == "image/png" {
+		ext = ".png"
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return m, err
+	}
+	f, err := os.CreateTemp(dir, "resized-*"+ext)
+	if err != nil {
+		return m, err
+	}
+	if _, err := f.Write(resized); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return m, err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return m, err
+	}
+
+	m.Path = f.Name()
+	m.MIMEType = mimeType
+	m.Filename = strings.TrimSuffix(m.Filename, filepath.Ext(m.Filename)) + ext
+	return m, nil
+}
+
+// ResizeImage scales an image down so its longest side is at most maxDim
+// and re-encodes it, returning the new bytes and MIME type. Images with
+// transparency stay PNG; everything else becomes JPEG, which also shrinks
+// images that are too large in bytes but not in pixels.
+func ResizeImage(data []byte, maxDim int) ([]byte, string, error) {
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("decoding image: %w", err)
+	}
+
+	dst := src
+	b := src.Bounds()
+	if w, h := b.Dx(), b.Dy(); maxDim > 0 && (w > maxDim || h > maxDim) {
+		scale := float64(maxDim) / float64(max(w, h))
+		dst = scaleDown(src, max(1, int(float64(w)*scale)), max(1, int(float64(h)*scale)))
+	}
+
+	var buf bytes.Buffer
+	if format == "png" && !isOpaque(dst) {
+		if err := png.Encode(&buf, dst); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/png", nil
+	}
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/jpeg", nil
+}
+
+// scaleDown resizes src to w×h by averaging the source pixels that fall in
+// each destination pixel (a box filter), which avoids the aliasing of
+// nearest-neighbour sampling without pulling in an imaging library.
+func scaleDown(src image.Image, w, h int) *image.RGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*sh/h
+		y1 := max(b.Min.Y+(y+1)*sh/h, y0+1)
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*sw/w
+			x1 := max(b.Min.X+(x+1)*sw/w, x0+1)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					bl += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(bl / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
+
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+func sniffContentType(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	return http.DetectContentType(head[:n])
+}
+
+// visionModelHints are substrings of model IDs known to accept image input.
+var visionModelHints = []string{
+	"claude-3", "claude-sonnet-4", "claude-opus-4", "claude-haiku-4",
+	"gpt-4o", "gpt-4.1", "gpt-4-turbo", "gpt-4-vision", "gpt-5",
+	"gemini", "gemma-3",
+	"llava", "bakllava", "moondream", "minicpm-v",
+	"-vl", "vision", "pixtral", "mistral-medium", "mistral-small-3",
+	"grok-2-vision", "grok-4", "glm-4v", "glm-4.5v",
+	"llama-4", "kimi-vl",
+}
+
+// ModelSupportsVision guesses whether a model accepts image input from its
+// "protocol/model" identifier. Models can override the guess with
+// model_list[].vision in the config.
+func ModelSupportsVision(model string) bool {
+	_, modelID := ExtractProtocol(model)
+	modelID = strings.ToLower(modelID)
+	switch {
+	case modelID == "o1-mini" || modelID == "o3-mini":
+		// Text-only, unlike o1, o3 and o4-mini.
+		return false
+	case strings.HasPrefix(modelID, "o1"), strings.HasPrefix(modelID, "o3"), strings.HasPrefix(modelID, "o4"):
+		return true
+	}
+	for _, hint := range visionModelHints {
+		if strings.Contains(modelID, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// ModelSupportsAudio guesses whether a model accepts audio input from its
+// "protocol/model" identifier. Only Gemini models over the Gemini API are
+// assumed to: OpenAI-style APIs take audio on a few models and in a few
+// formats only. Models can override the guess with model_list[].audio.
+func ModelSupportsAudio(model string) bool {
+	protocol, modelID := ExtractProtocol(model)
+	return (protocol == "gemini" || protocol == "google") && strings.Contains(strings.ToLower(modelID), "gemini")
+}
+
+// ModelSupportsPDF guesses whether a model reads PDF documents from its
+// "protocol/model" identifier: Gemini models over the Gemini API and OpenAI
+// models that accept images. Claude reads PDFs too, but the Anthropic
+// provider only sends images, so it is left out. Models can override the
+// guess with model_list[].pdf.
+func ModelSupportsPDF(model string) bool {
+	protocol, modelID := ExtractProtocol(model)
+	switch protocol {
+	case "gemini", "google":
+		return strings.Contains(strings.ToLower(modelID), "gemini")
+	case "openai":
+		return ModelSupportsVision(model)
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package providers
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"charm.land/fantasy"
+)
+
+func writeTestPNG(t *testing.T, dir string, w, h int) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	path := filepath.Join(dir, "photo.png")
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	return path
+}
+
+func TestMediaFromPaths(t *testing.T) {
+	dir := t.TempDir()
+	imgPath := writeTestPNG(t, dir, 4, 4)
+	voicePath := filepath.Join(dir, "voice.ogg")
+	docPath := filepath.Join(dir, "notes")
+	os.WriteFile(voicePath, []byte("OggS"), 0o600)
+	os.WriteFile(docPath, []byte("plain text notes"), 0o600)
+
+	parts := MediaFromPaths([]string{
+		imgPath,
+		voicePath,
+		docPath,
+		"https://cdn.example.com/a.png",
+		filepath.Join(dir, "missing.jpg"),
+	})
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 local parts, got %d: %+v", len(parts), parts)
+	}
+
+	want := []struct{ typ, mime string }{
+		{MediaTypeImage, "image/png"},
+		{MediaTypeAudio, "audio/ogg"},
+		{MediaTypeFile, "text/plain"},
+	}
+	for i, w := range want {
+		if parts[i].Type != w.typ || parts[i].MIMEType != w.mime {
+			t.Errorf("part %d = %s (%s), want %s (%s)", i, parts[i].Type, parts[i].MIMEType, w.typ, w.mime)
+		}
+	}
+	if got := FilterMedia(parts, MediaTypeImage); len(got) != 1 || got[0].Path != imgPath {
+		t.Errorf("FilterMedia(image) = %+v", got)
+	}
+}
+
+func TestResizeImage(t *testing.T) {
+	path := writeTestPNG(t, t.TempDir(), 200, 100)
+	data, _ := os.ReadFile(path)
+
+	resized, mimeType, err := ResizeImage(data, 50)
+	if err != nil {
+		t.Fatalf("ResizeImage failed: %v", err)
+	}
+	if mimeType != "image/jpeg" {
+		t.Errorf("opaque PNG should be re-encoded as JPEG, got %s", mimeType)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(resized))
+	if err != nil {
+		t.Fatalf("decoding resized image: %v", err)
+	}
+	if cfg.Width != 50 || cfg.Height != 25 {
+		t.Errorf("resized to %dx%d, want 50x25", cfg.Width, cfg.Height)
+	}
+}
+
+func TestShrinkImages(t *testing.T) {
+	dir := t.TempDir()
+	path := writeTestPNG(t, dir, 64, 64)
+	part, _ := NewMediaPart(path)
+
+	messages := []Message{
+		{Role: "system", Content: "sys"},
+		{Role: "user", Content: "what is this?", Media: []MediaPart{part}},
+	}
+	shrunk, created := ShrinkImages(messages, 16, dir)
+	if len(created) != 1 {
+		t.Fatalf("expected 1 resized file, got %v", created)
+	}
+	if messages[1].Media[0].Path != path {
+		t.Error("ShrinkImages must not modify its input")
+	}
+	got := shrunk[1].Media[0]
+	if got.Path != created[0] || got.MIMEType != "image/jpeg" || got.Filename != "photo.jpg" {
+		t.Errorf("unexpected resized part %+v", got)
+	}
+}
+
+func TestAgentXToFantasyMessages_UserMedia(t *testing.T) {
+	path := writeTestPNG(t, t.TempDir(), 2, 2)
+	part, _ := NewMediaPart(path)
+
+	msgs := AgentXToFantasyMessages([]Message{{Role: "user", Content: "look", Media: []MediaPart{part}}})
+	if len(msgs) != 1 || len(msgs[0].Content) != 2 {
+		t.Fatalf("expected text and file parts, got %+v", msgs)
+	}
+	file, ok := msgs[0].Content[1].(fantasy.FilePart)
+	if !ok {
+		t.Fatalf("expected FilePart, got %T", msgs[0].Content[1])
+	}
+	if file.MediaType != "image/png" || len(file.Data) == 0 {
+		t.Errorf("unexpected file part: type=%s bytes=%d", file.MediaType, len(file.Data))
+	}
+}
+
+func TestModelSupportsAudioAndPDF(t *testing.T) {
+	tests := []struct {
+		model      string
+		audio, pdf bool
+	}{
+		{"gemini/gemini-2.5-flash", true, true},
+		{"google/gemini-2.5-pro", true, true},
+		{"openai/gpt-4o", false, true},
+		{"openai/gpt-3.5-turbo", false, false},
+		{"anthropic/claude-sonnet-4.6", false, false},
+		{"openrouter/google/gemini-2.5-flash", false, false},
+		{"ollama/llava:13b", false, false},
+	}
+	for _, tt := range tests {
+		if got := ModelSupportsAudio(tt.model); got != tt.audio {
+			t.Errorf("ModelSupportsAudio(%q) = %v, want %v", tt.model, got, tt.audio)
+		}
+		if got := ModelSupportsPDF(tt.model); got != tt.pdf {
+			t.Errorf("ModelSupportsPDF(%q) = %v, want %v", tt.model, got, tt.pdf)
+		}
+	}
+}
+
+func TestModelSupportsVision(t *testing.T) {
+	tests := []struct {
+		model string
+		want  bool
+	}{
+		{"openai/gpt-4o", true},
+		{"anthropic/claude-sonnet-4.6", true},
+		{"gemini/gemini-2.5-flash", true},
+		{"ollama/llava:13b", true},
+		{"openrouter/qwen/qwen2.5-vl-72b-instruct", true},
+		{"openai/o3", true},
+		{"openai/o3-mini", false},
+		{"deepseek/deepseek-chat", false},
+		{"groq/llama-3.3-70b-versatile", false},
+	}
+	for _, tt := range tests {
+		if got := ModelSupportsVision(tt.model); got != tt.want {
+			t.Errorf("ModelSupportsVision(%q) = %v, want %v", tt.model, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+// Package ollama talks to an Ollama server through its native API, which,
+// unlike the OpenAI-compatible endpoint, exposes keep_alive, num_ctx and
+// model management.
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"charm.land/fantasy"
+)
+
+// DefaultBaseURL is where a local Ollama server listens.
+const DefaultBaseURL = "http://localhost:11434"
+
+// Client is a minimal client for the Ollama REST API.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the server at baseURL. An empty baseURL
+// uses DefaultBaseURL; a trailing /v1 (the OpenAI-compatible prefix) is
+// dropped so existing api_base values keep working.
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	return &Client{baseURL: NormalizeBaseURL(baseURL), httpClient: httpClient}
+}
+
+// NormalizeBaseURL turns an api_base into the root of the native API.
+func NormalizeBaseURL(baseURL string) string {
+	if baseURL == "" {
+		return DefaultBaseURL
+	}
+	baseURL = strings.TrimRight(baseURL, "/")
+	return strings.TrimSuffix(baseURL, "/v1")
+}
+
+// Model is an installed model as reported by /api/tags.
+type Model struct {
+	Name       string       `json:"name"`
+	Size       int64        `json:"size"`
+	ModifiedAt time.Time    `json:"modified_at"`
+	Details    ModelDetails `json:"details"`
+}
+
+// ModelDetails describes a model's family, size and quantization.
+type ModelDetails struct {
+	Family            string `json:"family"`
+	ParameterSize     string `json:"parameter_size"`
+	QuantizationLevel string `json:"quantization_level"`
+}
+
+// ModelInfo is the subset of /api/show used by AgentX.
+type ModelInfo struct {
+	ModelInfo    map[string]any `json:"model_info"`
+	Parameters   string         `json:"parameters"`
+	Capabilities []string       `json:"capabilities"`
+}
+
+// ContextLength returns the context window the model was trained with, or
+// 0 if the server does not report it.
+func (m *ModelInfo) ContextLength() int {
+	for key, value := range m.ModelInfo {
+		if !strings.HasSuffix(key, ".context_length") {
+			continue
+		}
+		if n, ok := value.(float64); ok {
+			return int(n)
+		}
+	}
+	return 0
+}
+
+// NumCtx returns the num_ctx parameter baked into the model's Modelfile,
+// or 0 if it has none.
+func (m *ModelInfo) NumCtx() int {
+	for _, line := range strings.Split(m.Parameters, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "num_ctx" {
+			n, _ := strconv.Atoi(fields[1])
+			return n
+		}
+	}
+	return 0
+}
+
+// HasCapability reports whether the model lists capability, such as
+// "tools" or "vision".
+func (m *ModelInfo) HasCapability(capability string) bool {
+	for _, c := range m.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// PullProgress is one status update while pulling a model.
+type PullProgress struct {
+	Status    string `json:"status"`
+	Digest    string `json:"digest,omitempty"`
+	Total     int64  `json:"total,omitempty"`
+	Completed int64  `json:"completed,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// List returns the models installed on the server.
+func (c *Client) List(ctx context.Context) ([]Model, error) {
+	var out struct {
+		Models []Model `json:"models"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/tags", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Models, nil
+}
+
+// Show returns details about an installed model.
+func (c *Client) Show(ctx context.Context, model string) (*ModelInfo, error) {
+	var out ModelInfo
+	if err := c.do(ctx, http.MethodPost, "/api/show", map[string]any{"model": model}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Pull downloads a model, calling progress for each status update.
+func (c *Client) Pull(ctx context.Context, model string, progress func(PullProgress)) error {
+	resp, err := c.post(ctx, "/api/pull", map[string]any{"model": model, "stream": true})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var p PullProgress
+		if err := json.Unmarshal(scanner.Bytes(), &p); err != nil {
+			continue
+		}
+		if p.Error != "" {
+			return fmt.Errorf("pulling %s: %s", model, p.Error)
+		}
+		if progress != nil {
+			progress(p)
+		}
+		if p.Status == "success" {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("pulling %s: %w", model, err)
+	}
+	return fmt.Errorf("pulling %s: stream ended before success", model)
+}
+
+// Embed returns an embedding for each input text, in order.
+func (c *Client) Embed(ctx context.Context, model string, input []string) ([][]float32, error) {
+	var out struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/embed", map[string]any{"model": model, "input": input}, &out); err != nil {
+		return nil, err
+	}
+	if len(out.Embeddings) != len(input) {
+		return nil, fmt.Errorf("ollama returned %d embeddings for %d inputs", len(out.Embeddings), len(input))
+	}
+	return out.Embeddings, nil
+}
+
+// do sends a JSON request and decodes the JSON response into out.
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var resp *http.Response
+	var err error
+	if method == http.MethodGet {
+		var req *http.Request
+		req, err = http.NewRequestWithContext(ctx, method, c.baseURL+path, nil)
+		if err != nil {
+			return err
+		}
+		resp, err = c.send(req, nil)
+	} else {
+		resp, err = c.post(ctx, path, body)
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// post sends a JSON body and returns the response if its status is 2xx.
+func (c *Client) post(ctx context.Context, path string, body any) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.send(req, data)
+}
+
+func (c *Client) send(req *http.Request, reqBody []byte) (*http.Response, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var apiErr struct {
+		Error string `json:"error"`
+	}
+	msg := strings.TrimSpace(string(body))
+	if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != "" {
+		msg = apiErr.Error
+	}
+	return nil, &fantasy.ProviderError{
+		Title:        fmt.Sprintf("ollama: %d %s", resp.StatusCode, http.StatusText(resp.StatusCode)),
+		Message:      msg,
+		URL:          req.URL.String(),
+		StatusCode:   resp.StatusCode,
+		RequestBody:  reqBody,
+		ResponseBody: body,
+	}
+}
+
+// IsModelNotFound reports whether err is the server saying a model is not
+// installed.
+func IsModelNotFound(err error) bool {
+	var pe *fantasy.ProviderError
+	return errors.As(err, &pe) && pe.StatusCode == http.StatusNotFound && strings.Contains(strings.ToLower(pe.Message), "not found")
+}
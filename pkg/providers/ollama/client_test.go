@@ -0,0 +1,142 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNormalizeBaseURL(t *testing.T) {
+	tests := map[string]string{
+		"":                          DefaultBaseURL,
+		"http://localhost:11434/v1": "http://localhost:11434",
+		"http://box:11434/v1/":      "http://box:11434",
+		"http://box:11434/":         "http://box:11434",
+	}
+	for in, want := range tests {
+		if got := NormalizeBaseURL(in); got != want {
+			t.Errorf("NormalizeBaseURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestClient_List(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/tags" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"models":[{"name":"llama3.2:latest","size":2019393189,"details":{"parameter_size":"3.2B"}}]}`)
+	}))
+	defer srv.Close()
+
+	models, err := NewClient(srv.URL+"/v1", nil).List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 1 || models[0].Name != "llama3.2:latest" || models[0].Details.ParameterSize != "3.2B" {
+		t.Errorf("unexpected models: %+v", models)
+	}
+}
+
+func TestClient_Show(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["model"] != "llama3.2" {
+			t.Errorf("model = %v", body["model"])
+		}
+		fmt.Fprint(w, `{"model_info":{"llama.context_length":131072},"parameters":"stop \"<|eot_id|>\"\nnum_ctx 8192","capabilities":["completion","tools"]}`)
+	}))
+	defer srv.Close()
+
+	info, err := NewClient(srv.URL, nil).Show(context.Background(), "llama3.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ContextLength() != 131072 {
+		t.Errorf("ContextLength() = %d", info.ContextLength())
+	}
+	if info.NumCtx() != 8192 {
+		t.Errorf("NumCtx() = %d", info.NumCtx())
+	}
+	if !info.HasCapability("tools") || info.HasCapability("vision") {
+		t.Errorf("unexpected capabilities: %v", info.Capabilities)
+	}
+}
+
+func TestClient_Pull(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"status":"pulling manifest"}`)
+		fmt.Fprintln(w, `{"status":"pulling abc","digest":"abc","total":10,"completed":10}`)
+		fmt.Fprintln(w, `{"status":"success"}`)
+	}))
+	defer srv.Close()
+
+	var statuses []string
+	err := NewClient(srv.URL, nil).Pull(context.Background(), "llama3.2", func(p PullProgress) {
+		statuses = append(statuses, p.Status)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 3 || statuses[2] != "success" {
+		t.Errorf("statuses = %v", statuses)
+	}
+}
+
+func TestClient_PullError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"status":"pulling manifest"}`)
+		fmt.Fprintln(w, `{"error":"pull model manifest: file does not exist"}`)
+	}))
+	defer srv.Close()
+
+	if err := NewClient(srv.URL, nil).Pull(context.Background(), "nope", nil); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestIsModelNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":"model \"nope\" not found, try pulling it first"}`)
+	}))
+	defer srv.Close()
+
+	_, err := NewClient(srv.URL, nil).Show(context.Background(), "nope")
+	if !IsModelNotFound(err) {
+		t.Errorf("IsModelNotFound(%v) = false", err)
+	}
+	if IsModelNotFound(fmt.Errorf("connection refused")) {
+		t.Error("a network error is not a missing model")
+	}
+}
+
+func TestClient_Embed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var body struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Model != "nomic-embed-text" || len(body.Input) != 2 {
+			t.Errorf("unexpected request %+v", body)
+		}
+		fmt.Fprint(w, `{"model":"nomic-embed-text","embeddings":[[0.1,0.2],[0.3,0.4]]}`)
+	}))
+	defer srv.Close()
+
+	vectors, err := NewClient(srv.URL, nil).Embed(context.Background(), "nomic-embed-text", []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != 2 || vectors[1][0] != 0.3 {
+		t.Errorf("unexpected embeddings: %v", vectors)
+	}
+}
@@ -0,0 +1,568 @@
+package ollama
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"charm.land/fantasy"
+
+	"github.com/Agentx-network/agentx/pkg/logger"
+)
+
+// Name is the provider name reported by Ollama models.
+const Name = "ollama"
+
+// MaxAutoNumCtx caps the context window picked from a model's metadata when
+// num_ctx is not configured. Ollama allocates the whole window up front, and
+// many models advertise 128k or more, which would not fit on most machines.
+const MaxAutoNumCtx = 32768
+
+// showTimeout bounds the metadata lookup done before the first request.
+const showTimeout = 10 * time.Second
+
+type options struct {
+	baseURL    string
+	httpClient *http.Client
+	keepAlive  string
+	numCtx     int
+	autoPull   bool
+	think      bool
+}
+
+// Option configures the Ollama provider.
+type Option func(*options)
+
+// WithBaseURL sets the server URL. A trailing /v1 is ignored.
+func WithBaseURL(baseURL string) Option {
+	return func(o *options) { o.baseURL = baseURL }
+}
+
+// WithHTTPClient sets the HTTP client used for requests.
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *options) { o.httpClient = c }
+}
+
+// WithKeepAlive sets how long the server keeps the model loaded after a
+// request, as a duration string such as "10m" or "-1" for forever.
+func WithKeepAlive(keepAlive string) Option {
+	return func(o *options) { o.keepAlive = keepAlive }
+}
+
+// WithNumCtx sets the context window. Zero picks it from the model.
+func WithNumCtx(numCtx int) Option {
+	return func(o *options) { o.numCtx = numCtx }
+}
+
+// WithAutoPull makes the first request pull a model that is not installed.
+func WithAutoPull(autoPull bool) Option {
+	return func(o *options) { o.autoPull = autoPull }
+}
+
+// WithThink asks thinking models to return their reasoning separately.
+func WithThink(think bool) Option {
+	return func(o *options) { o.think = think }
+}
+
+type provider struct {
+	opts   options
+	client *Client
+}
+
+// New creates a fantasy.Provider backed by the native Ollama API.
+func New(opts ...Option) (fantasy.Provider, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &provider{opts: o, client: NewClient(o.baseURL, o.httpClient)}, nil
+}
+
+func (p *provider) Name() string {
+	return Name
+}
+
+func (p *provider) LanguageModel(ctx context.Context, modelID string) (fantasy.LanguageModel, error) {
+	if modelID == "" {
+		return nil, errors.New("model is required")
+	}
+	return &languageModel{provider: p, modelID: modelID}, nil
+}
+
+type languageModel struct {
+	provider *provider
+	modelID  string
+
+	mu       sync.Mutex
+	numCtx   int
+	resolved bool
+	pullMu   sync.Mutex
+}
+
+func (m *languageModel) Provider() string { return Name }
+func (m *languageModel) Model() string    { return m.modelID }
+
+// EffectiveNumCtx returns the context window requests will use: the
+// configured value, then the model's own num_ctx, then its trained context
+// length capped at MaxAutoNumCtx. Zero leaves the server default.
+func EffectiveNumCtx(info *ModelInfo, configured int) int {
+	if configured > 0 {
+		return configured
+	}
+	if info == nil {
+		return 0
+	}
+	if n := info.NumCtx(); n > 0 {
+		return n
+	}
+	if n := info.ContextLength(); n > 0 {
+		return min(n, MaxAutoNumCtx)
+	}
+	return 0
+}
+
+// prepare resolves num_ctx before the first request. A model that is not
+// installed yet is looked up again after it has been pulled.
+func (m *languageModel) prepare(ctx context.Context) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.resolved {
+		return m.numCtx
+	}
+	m.numCtx = m.provider.opts.numCtx
+	if m.numCtx > 0 {
+		m.resolved = true
+		return m.numCtx
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, showTimeout)
+	defer cancel()
+	info, err := m.provider.client.Show(ctx, m.modelID)
+	if err != nil {
+		logger.DebugCF("ollama", "Could not read model metadata, using the server's default context",
+			map[string]any{"model": m.modelID, "error": err.Error()})
+		m.resolved = !IsModelNotFound(err)
+		return 0
+	}
+	m.numCtx = EffectiveNumCtx(info, 0)
+	m.resolved = true
+	return m.numCtx
+}
+
+// withAutoPull runs fn and, if the model is missing and auto_pull is on,
+// pulls it and runs fn again.
+func (m *languageModel) withAutoPull(ctx context.Context, fn func() error) error {
+	err := fn()
+	if err == nil || !m.provider.opts.autoPull || !IsModelNotFound(err) {
+		return err
+	}
+
+	m.pullMu.Lock()
+	logger.InfoCF("ollama", "Model not installed, pulling it", map[string]any{"model": m.modelID})
+	pullErr := m.provider.client.Pull(ctx, m.modelID, nil)
+	m.pullMu.Unlock()
+	if pullErr != nil {
+		return fmt.Errorf("%w (auto pull failed: %v)", err, pullErr)
+	}
+	logger.InfoCF("ollama", "Model pulled", map[string]any{"model": m.modelID})
+	return fn()
+}
+
+// Generate sends a non-streaming chat request.
+func (m *languageModel) Generate(ctx context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	var resp *fantasy.Response
+	err := m.withAutoPull(ctx, func() error {
+		req, err := m.buildRequest(call, m.prepare(ctx), false)
+		if err != nil {
+			return err
+		}
+		httpResp, err := m.provider.client.post(ctx, "/api/chat", req)
+		if err != nil {
+			return err
+		}
+		defer httpResp.Body.Close()
+
+		var chunk chatResponse
+		if err := json.NewDecoder(httpResp.Body).Decode(&chunk); err != nil {
+			return fmt.Errorf("decoding ollama response: %w", err)
+		}
+		if chunk.Error != "" {
+			return &fantasy.ProviderError{Title: "ollama", Message: chunk.Error}
+		}
+		resp = chunk.toResponse()
+		return nil
+	})
+	return resp, err
+}
+
+// Stream sends a streaming chat request.
+func (m *languageModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.StreamResponse, error) {
+	var httpResp *http.Response
+	err := m.withAutoPull(ctx, func() error {
+		req, err := m.buildRequest(call, m.prepare(ctx), true)
+		if err != nil {
+			return err
+		}
+		httpResp, err = m.provider.client.post(ctx, "/api/chat", req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return streamParts(httpResp), nil
+}
+
+// GenerateObject is not supported; agents only use text generation.
+func (m *languageModel) GenerateObject(ctx context.Context, call fantasy.ObjectCall) (*fantasy.ObjectResponse, error) {
+	return nil, errors.New("ollama: object generation is not supported")
+}
+
+// StreamObject is not supported; agents only use text generation.
+func (m *languageModel) StreamObject(ctx context.Context, call fantasy.ObjectCall) (fantasy.ObjectStreamResponse, error) {
+	return nil, errors.New("ollama: object generation is not supported")
+}
+
+// chatRequest is the body of /api/chat.
+type chatRequest struct {
+	Model     string         `json:"model"`
+	Messages  []chatMessage  `json:"messages"`
+	Tools     []chatTool     `json:"tools,omitempty"`
+	Stream    bool           `json:"stream"`
+	Options   map[string]any `json:"options,omitempty"`
+	KeepAlive string         `json:"keep_alive,omitempty"`
+	Think     bool           `json:"think,omitempty"`
+}
+
+type chatMessage struct {
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	Thinking  string     `json:"thinking,omitempty"`
+	Images    []string   `json:"images,omitempty"`
+	ToolCalls []toolCall `json:"tool_calls,omitempty"`
+	ToolName  string     `json:"tool_name,omitempty"`
+}
+
+type toolCall struct {
+	ID       string       `json:"id,omitempty"`
+	Function toolFunction `json:"function"`
+}
+
+type toolFunction struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+type chatTool struct {
+	Type     string       `json:"type"`
+	Function toolSpecBody `json:"function"`
+}
+
+type toolSpecBody struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+// chatResponse is one line of an /api/chat response.
+type chatResponse struct {
+	Message         chatMessage `json:"message"`
+	Done            bool        `json:"done"`
+	DoneReason      string      `json:"done_reason"`
+	PromptEvalCount int64       `json:"prompt_eval_count"`
+	EvalCount       int64       `json:"eval_count"`
+	Error           string      `json:"error"`
+}
+
+func (m *languageModel) buildRequest(call fantasy.Call, numCtx int, stream bool) (*chatRequest, error) {
+	messages, err := toChatMessages(call.Prompt)
+	if err != nil {
+		return nil, err
+	}
+	req := &chatRequest{
+		Model:     m.modelID,
+		Messages:  messages,
+		Stream:    stream,
+		KeepAlive: m.provider.opts.keepAlive,
+		Think:     m.provider.opts.think,
+		Options:   map[string]any{},
+	}
+	for _, tool := range call.Tools {
+		ft, ok := tool.(fantasy.FunctionTool)
+		if !ok {
+			continue
+		}
+		req.Tools = append(req.Tools, chatTool{
+			Type: "function",
+			Function: toolSpecBody{
+				Name:        ft.Name,
+				Description: ft.Description,
+				Parameters:  ft.InputSchema,
+			},
+		})
+	}
+
+	if numCtx > 0 {
+		req.Options["num_ctx"] = numCtx
+	}
+	if call.MaxOutputTokens != nil {
+		req.Options["num_predict"] = *call.MaxOutputTokens
+	}
+	if call.Temperature != nil {
+		req.Options["temperature"] = *call.Temperature
+	}
+	if call.TopP != nil {
+		req.Options["top_p"] = *call.TopP
+	}
+	if call.TopK != nil {
+		req.Options["top_k"] = *call.TopK
+	}
+	if call.PresencePenalty != nil {
+		req.Options["presence_penalty"] = *call.PresencePenalty
+	}
+	if call.FrequencyPenalty != nil {
+		req.Options["frequency_penalty"] = *call.FrequencyPenalty
+	}
+	return req, nil
+}
+
+// toChatMessages converts a Fantasy prompt to Ollama messages. Tool results
+// carry the tool name instead of the call ID, so names are looked up from
+// the assistant messages that made the calls.
+func toChatMessages(prompt fantasy.Prompt) ([]chatMessage, error) {
+	toolNames := map[string]string{}
+	var out []chatMessage
+	for _, msg := range prompt {
+		switch msg.Role {
+		case fantasy.MessageRoleSystem, fantasy.MessageRoleUser:
+			cm := chatMessage{Role: string(msg.Role)}
+			var text []string
+			for _, part := range msg.Content {
+				switch p := part.(type) {
+				case fantasy.TextPart:
+					text = append(text, p.Text)
+				case fantasy.FilePart:
+					if strings.HasPrefix(p.MediaType, "image/") {
+						cm.Images = append(cm.Images, base64.StdEncoding.EncodeToString(p.Data))
+					} else {
+						text = append(text, fmt.Sprintf("[file: %s]", p.Filename))
+					}
+				}
+			}
+			cm.Content = strings.Join(text, "\n")
+			out = append(out, cm)
+
+		case fantasy.MessageRoleAssistant:
+			cm := chatMessage{Role: "assistant"}
+			for _, part := range msg.Content {
+				switch p := part.(type) {
+				case fantasy.TextPart:
+					cm.Content += p.Text
+				case fantasy.ReasoningPart:
+					cm.Thinking += p.Text
+				case fantasy.ToolCallPart:
+					args := json.RawMessage(p.Input)
+					if !json.Valid(args) {
+						args = json.RawMessage("{}")
+					}
+					toolNames[p.ToolCallID] = p.ToolName
+					cm.ToolCalls = append(cm.ToolCalls, toolCall{
+						ID:       p.ToolCallID,
+						Function: toolFunction{Name: p.ToolName, Arguments: args},
+					})
+				}
+			}
+			out = append(out, cm)
+
+		case fantasy.MessageRoleTool:
+			for _, part := range msg.Content {
+				p, ok := part.(fantasy.ToolResultPart)
+				if !ok {
+					continue
+				}
+				out = append(out, chatMessage{
+					Role:     "tool",
+					Content:  toolResultText(p.Output),
+					ToolName: toolNames[p.ToolCallID],
+				})
+			}
+
+		default:
+			return nil, fmt.Errorf("ollama: unsupported message role %q", msg.Role)
+		}
+	}
+	return out, nil
+}
+
+func toolResultText(output fantasy.ToolResultOutputContent) string {
+	switch o := output.(type) {
+	case fantasy.ToolResultOutputContentText:
+		return o.Text
+	case fantasy.ToolResultOutputContentError:
+		if o.Error != nil {
+			return "Error: " + o.Error.Error()
+		}
+		return "Error"
+	case fantasy.ToolResultOutputContentMedia:
+		return o.Text
+	}
+	return ""
+}
+
+func (r *chatResponse) usage() fantasy.Usage {
+	return fantasy.Usage{
+		InputTokens:  r.PromptEvalCount,
+		OutputTokens: r.EvalCount,
+		TotalTokens:  r.PromptEvalCount + r.EvalCount,
+	}
+}
+
+func finishReason(doneReason string, toolCalls bool) fantasy.FinishReason {
+	switch {
+	case toolCalls:
+		return fantasy.FinishReasonToolCalls
+	case doneReason == "length":
+		return fantasy.FinishReasonLength
+	case doneReason == "stop" || doneReason == "":
+		return fantasy.FinishReasonStop
+	default:
+		return fantasy.FinishReasonOther
+	}
+}
+
+func callID(tc toolCall, n int) string {
+	if tc.ID != "" {
+		return tc.ID
+	}
+	return fmt.Sprintf("call_%d", n)
+}
+
+func (r *chatResponse) toResponse() *fantasy.Response {
+	var content fantasy.ResponseContent
+	if r.Message.Thinking != "" {
+		content = append(content, fantasy.ReasoningContent{Text: r.Message.Thinking})
+	}
+	if r.Message.Content != "" {
+		content = append(content, fantasy.TextContent{Text: r.Message.Content})
+	}
+	for i, tc := range r.Message.ToolCalls {
+		content = append(content, fantasy.ToolCallContent{
+			ToolCallID: callID(tc, i),
+			ToolName:   tc.Function.Name,
+			Input:      string(tc.Function.Arguments),
+		})
+	}
+	return &fantasy.Response{
+		Content:      content,
+		FinishReason: finishReason(r.DoneReason, len(r.Message.ToolCalls) > 0),
+		Usage:        r.usage(),
+	}
+}
+
+// streamParts converts an NDJSON /api/chat stream into Fantasy stream parts.
+func streamParts(resp *http.Response) iter.Seq[fantasy.StreamPart] {
+	return func(yield func(fantasy.StreamPart) bool) {
+		defer resp.Body.Close()
+
+		const textID, reasoningID = "0", "reasoning-0"
+		var textOpen, reasoningOpen bool
+		toolCalls := 0
+
+		closeReasoning := func() bool {
+			if !reasoningOpen {
+				return true
+			}
+			reasoningOpen = false
+			return yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeReasoningEnd, ID: reasoningID})
+		}
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line := scanner.Bytes()
+			if len(line) == 0 {
+				continue
+			}
+			var chunk chatResponse
+			if err := json.Unmarshal(line, &chunk); err != nil {
+				yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeError, Error: fmt.Errorf("decoding ollama stream: %w", err)})
+				return
+			}
+			if chunk.Error != "" {
+				yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeError, Error: &fantasy.ProviderError{Title: "ollama", Message: chunk.Error}})
+				return
+			}
+
+			if chunk.Message.Thinking != "" {
+				if !reasoningOpen {
+					reasoningOpen = true
+					if !yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeReasoningStart, ID: reasoningID}) {
+						return
+					}
+				}
+				if !yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeReasoningDelta, ID: reasoningID, Delta: chunk.Message.Thinking}) {
+					return
+				}
+			}
+
+			if chunk.Message.Content != "" {
+				if !closeReasoning() {
+					return
+				}
+				if !textOpen {
+					textOpen = true
+					if !yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeTextStart, ID: textID}) {
+						return
+					}
+				}
+				if !yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeTextDelta, ID: textID, Delta: chunk.Message.Content}) {
+					return
+				}
+			}
+
+			for _, tc := range chunk.Message.ToolCalls {
+				id := callID(tc, toolCalls)
+				toolCalls++
+				input := string(tc.Function.Arguments)
+				parts := []fantasy.StreamPart{
+					{Type: fantasy.StreamPartTypeToolInputStart, ID: id, ToolCallName: tc.Function.Name},
+					{Type: fantasy.StreamPartTypeToolInputDelta, ID: id, Delta: input},
+					{Type: fantasy.StreamPartTypeToolInputEnd, ID: id},
+					{Type: fantasy.StreamPartTypeToolCall, ID: id, ToolCallName: tc.Function.Name, ToolCallInput: input},
+				}
+				for _, part := range parts {
+					if !yield(part) {
+						return
+					}
+				}
+			}
+
+			if chunk.Done {
+				if !closeReasoning() {
+					return
+				}
+				if textOpen && !yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeTextEnd, ID: textID}) {
+					return
+				}
+				yield(fantasy.StreamPart{
+					Type:         fantasy.StreamPartTypeFinish,
+					Usage:        chunk.usage(),
+					FinishReason: finishReason(chunk.DoneReason, toolCalls > 0),
+				})
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeError, Error: fmt.Errorf("reading ollama stream: %w", err)})
+			return
+		}
+		yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeError, Error: errors.New("ollama stream ended unexpectedly")})
+	}
+}
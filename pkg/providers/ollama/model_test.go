@@ -0,0 +1,218 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"charm.land/fantasy"
+)
+
+func newTestModel(t *testing.T, handler http.HandlerFunc, opts ...Option) fantasy.LanguageModel {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	p, err := New(append([]Option{WithBaseURL(srv.URL + "/v1")}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, err := p.LanguageModel(context.Background(), "llama3.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return model
+}
+
+func TestEffectiveNumCtx(t *testing.T) {
+	trained := &ModelInfo{ModelInfo: map[string]any{"llama.context_length": float64(131072)}}
+	small := &ModelInfo{ModelInfo: map[string]any{"phi.context_length": float64(4096)}}
+	modelfile := &ModelInfo{Parameters: "num_ctx 16384", ModelInfo: trained.ModelInfo}
+
+	tests := []struct {
+		name       string
+		info       *ModelInfo
+		configured int
+		want       int
+	}{
+		{"configured wins", trained, 2048, 2048},
+		{"modelfile num_ctx", modelfile, 0, 16384},
+		{"trained length is capped", trained, 0, MaxAutoNumCtx},
+		{"small trained length", small, 0, 4096},
+		{"unknown", nil, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := EffectiveNumCtx(tt.info, tt.configured); got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestGenerate_Request(t *testing.T) {
+	var req chatRequest
+	model := newTestModel(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/show":
+			fmt.Fprint(w, `{"model_info":{"llama.context_length":8192}}`)
+		case "/api/chat":
+			json.NewDecoder(r.Body).Decode(&req)
+			fmt.Fprint(w, `{"message":{"role":"assistant","content":"4"},"done":true,"done_reason":"stop","prompt_eval_count":20,"eval_count":2}`)
+		}
+	}, WithKeepAlive("10m"))
+
+	temp := 0.2
+	resp, err := model.Generate(context.Background(), fantasy.Call{
+		Prompt: fantasy.Prompt{
+			{Role: fantasy.MessageRoleSystem, Content: []fantasy.MessagePart{fantasy.TextPart{Text: "be brief"}}},
+			{Role: fantasy.MessageRoleUser, Content: []fantasy.MessagePart{
+				fantasy.TextPart{Text: "what is 2+2?"},
+				fantasy.FilePart{Filename: "a.png", Data: []byte("png"), MediaType: "image/png"},
+			}},
+			{Role: fantasy.MessageRoleAssistant, Content: []fantasy.MessagePart{
+				fantasy.ToolCallPart{ToolCallID: "c1", ToolName: "calc", Input: `{"expr":"2+2"}`},
+			}},
+			{Role: fantasy.MessageRoleTool, Content: []fantasy.MessagePart{
+				fantasy.ToolResultPart{ToolCallID: "c1", Output: fantasy.ToolResultOutputContentText{Text: "4"}},
+			}},
+		},
+		Temperature: &temp,
+		Tools: []fantasy.Tool{fantasy.FunctionTool{
+			Name:        "calc",
+			Description: "evaluate",
+			InputSchema: map[string]any{"type": "object"},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.Content.Text() != "4" || resp.FinishReason != fantasy.FinishReasonStop || resp.Usage.TotalTokens != 22 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if req.Model != "llama3.2" || req.Stream || req.KeepAlive != "10m" {
+		t.Errorf("unexpected request: %+v", req)
+	}
+	if req.Options["num_ctx"] != float64(8192) || req.Options["temperature"] != 0.2 {
+		t.Errorf("unexpected options: %v", req.Options)
+	}
+	if len(req.Messages) != 4 || req.Messages[1].Images[0] != "cG5n" {
+		t.Fatalf("unexpected messages: %+v", req.Messages)
+	}
+	if tc := req.Messages[2].ToolCalls; len(tc) != 1 || tc[0].Function.Name != "calc" || string(tc[0].Function.Arguments) != `{"expr":"2+2"}` {
+		t.Errorf("unexpected tool calls: %+v", tc)
+	}
+	if req.Messages[3].Role != "tool" || req.Messages[3].ToolName != "calc" {
+		t.Errorf("tool result should carry the tool name: %+v", req.Messages[3])
+	}
+	if len(req.Tools) != 1 || req.Tools[0].Function.Name != "calc" {
+		t.Errorf("unexpected tools: %+v", req.Tools)
+	}
+}
+
+func TestStream(t *testing.T) {
+	model := newTestModel(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/show" {
+			fmt.Fprint(w, `{}`)
+			return
+		}
+		fmt.Fprintln(w, `{"message":{"role":"assistant","thinking":"hmm"},"done":false}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"Let me "},"done":false}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"check.","tool_calls":[{"function":{"name":"web","arguments":{"q":"go"}}}]},"done":false}`)
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":5,"eval_count":7}`)
+	}, WithNumCtx(4096))
+
+	stream, err := model.Stream(context.Background(), fantasy.Call{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var text, reasoning string
+	var calls []fantasy.StreamPart
+	var finish fantasy.StreamPart
+	for part := range stream {
+		switch part.Type {
+		case fantasy.StreamPartTypeTextDelta:
+			text += part.Delta
+		case fantasy.StreamPartTypeReasoningDelta:
+			reasoning += part.Delta
+		case fantasy.StreamPartTypeToolCall:
+			calls = append(calls, part)
+		case fantasy.StreamPartTypeFinish:
+			finish = part
+		case fantasy.StreamPartTypeError:
+			t.Fatal(part.Error)
+		}
+	}
+
+	if text != "Let me check." || reasoning != "hmm" {
+		t.Errorf("text=%q reasoning=%q", text, reasoning)
+	}
+	if len(calls) != 1 || calls[0].ID != "call_0" || calls[0].ToolCallName != "web" || calls[0].ToolCallInput != `{"q":"go"}` {
+		t.Errorf("unexpected tool calls: %+v", calls)
+	}
+	if finish.FinishReason != fantasy.FinishReasonToolCalls || finish.Usage.TotalTokens != 12 {
+		t.Errorf("unexpected finish: %+v", finish)
+	}
+}
+
+func TestStream_MidStreamError(t *testing.T) {
+	model := newTestModel(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"message":{"role":"assistant","content":"Hi"},"done":false}`)
+		fmt.Fprintln(w, `{"error":"out of memory"}`)
+	}, WithNumCtx(4096))
+
+	stream, err := model.Stream(context.Background(), fantasy.Call{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var streamErr error
+	for part := range stream {
+		if part.Type == fantasy.StreamPartTypeError {
+			streamErr = part.Error
+		}
+	}
+	var pe *fantasy.ProviderError
+	if !errors.As(streamErr, &pe) || pe.Message != "out of memory" {
+		t.Errorf("unexpected error: %v", streamErr)
+	}
+}
+
+func TestGenerate_AutoPull(t *testing.T) {
+	var installed atomic.Bool
+	var pulls atomic.Int32
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/pull":
+			pulls.Add(1)
+			installed.Store(true)
+			fmt.Fprintln(w, `{"status":"success"}`)
+		case "/api/chat":
+			if !installed.Load() {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"error":"model \"llama3.2\" not found, try pulling it first"}`)
+				return
+			}
+			fmt.Fprint(w, `{"message":{"role":"assistant","content":"ok"},"done":true}`)
+		}
+	}
+
+	model := newTestModel(t, handler, WithNumCtx(4096))
+	if _, err := model.Generate(context.Background(), fantasy.Call{}); !IsModelNotFound(err) {
+		t.Fatalf("without auto_pull the missing model error should surface, got %v", err)
+	}
+
+	model = newTestModel(t, handler, WithNumCtx(4096), WithAutoPull(true))
+	installed.Store(false)
+	resp, err := model.Generate(context.Background(), fantasy.Call{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content.Text() != "ok" || pulls.Load() != 1 {
+		t.Errorf("text=%q pulls=%d", resp.Content.Text(), pulls.Load())
+	}
+}
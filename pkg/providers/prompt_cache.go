@@ -0,0 +1,81 @@
+package providers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/anthropic"
+	"charm.land/fantasy/providers/openai"
+)
+
+// Provider-side prompt caching.
+//
+// Anthropic caches a prompt prefix only up to blocks marked with
+// cache_control, and allows at most four such breakpoints per request.
+// AgentX uses them for, in prompt order:
+//   - the tool definitions (last tool),
+//   - the static system prompt (identity, bootstrap files, skills, memory),
+//   - the end of the older history, before the current user message,
+//   - the last message, so each tool iteration reuses the previous one.
+//
+// OpenAI caches prefixes of 1024+ tokens automatically; a stable
+// prompt_cache_key per session keeps requests on the same cache.
+// Each provider reads only its own options, so both are always attached.
+
+// cacheControlOptions marks a message, part or tool as a cache breakpoint.
+func cacheControlOptions() fantasy.ProviderOptions {
+	return anthropic.NewProviderCacheControlOptions(&anthropic.ProviderCacheControlOptions{
+		CacheControl: anthropic.CacheControl{Type: "ephemeral"},
+	})
+}
+
+// MarkToolCacheBreakpoint marks the last tool so the tool definitions are
+// cached together with the system prompt that follows them.
+func MarkToolCacheBreakpoint(tools []fantasy.AgentTool) {
+	if len(tools) > 0 {
+		tools[len(tools)-1].SetProviderOptions(cacheControlOptions())
+	}
+}
+
+// MarkCacheBreakpoints returns a copy of prompt with breakpoints on the
+// message before historyEnd (the last message of the stored history) and
+// on the last message. System messages are skipped: their static block is
+// marked when it is converted. prompt itself is not modified.
+func MarkCacheBreakpoints(prompt []fantasy.Message, historyEnd int) []fantasy.Message {
+	out := make([]fantasy.Message, len(prompt))
+	copy(out, prompt)
+	for _, i := range []int{historyEnd - 1, len(out) - 1} {
+		if i < 0 || i >= len(out) || out[i].Role == fantasy.MessageRoleSystem {
+			continue
+		}
+		out[i].ProviderOptions = cacheControlOptions()
+	}
+	return out
+}
+
+// PromptCacheOptions returns call options that keep every request of a
+// session on the same OpenAI prompt cache. The session key is hashed so
+// chat IDs are not sent to the provider.
+func PromptCacheOptions(sessionKey string) fantasy.ProviderOptions {
+	if sessionKey == "" {
+		return nil
+	}
+	sum := sha256.Sum256([]byte(sessionKey))
+	key := "agentx-" + hex.EncodeToString(sum[:8])
+	return fantasy.ProviderOptions{
+		openai.Name: &openai.ProviderOptions{PromptCacheKey: &key},
+	}
+}
+
+// UsageFields returns usage as log fields, including prompt cache reads and
+// writes. Whether input_tokens includes cached tokens depends on the
+// provider: OpenAI counts them, Anthropic does not.
+func UsageFields(usage fantasy.Usage) map[string]any {
+	return map[string]any{
+		"input_tokens":          usage.InputTokens,
+		"output_tokens":         usage.OutputTokens,
+		"cache_read_tokens":     usage.CacheReadTokens,
+		"cache_creation_tokens": usage.CacheCreationTokens,
+	}
+}
@@ -0,0 +1,126 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/anthropic"
+	"charm.land/fantasy/providers/openai"
+
+	"github.com/Agentx-network/agentx/pkg/config"
+)
+
+func hasCacheControl(opts fantasy.ProviderOptions) bool {
+	return anthropic.GetCacheControl(opts) != nil
+}
+
+func TestMarkCacheBreakpoints(t *testing.T) {
+	prompt := []fantasy.Message{
+		fantasy.NewSystemMessage("system"),
+		fantasy.NewUserMessage("old question"),
+		{Role: fantasy.MessageRoleAssistant, Content: []fantasy.MessagePart{fantasy.TextPart{Text: "old answer"}}},
+		fantasy.NewUserMessage("new question"),
+	}
+
+	marked := MarkCacheBreakpoints(prompt, 3)
+	for i, want := range []bool{false, false, true, true} {
+		if got := hasCacheControl(marked[i].ProviderOptions); got != want {
+			t.Errorf("message %d: breakpoint = %v, want %v", i, got, want)
+		}
+	}
+	for i, msg := range prompt {
+		if msg.ProviderOptions != nil {
+			t.Errorf("input message %d was modified", i)
+		}
+	}
+
+	// Without history only the current message is marked, never the system.
+	marked = MarkCacheBreakpoints(prompt[:1:1], 1)
+	if hasCacheControl(marked[0].ProviderOptions) {
+		t.Error("the system message should not get a message-level breakpoint")
+	}
+}
+
+func TestAgentXToFantasyMessages_SystemCacheControl(t *testing.T) {
+	msgs := AgentXToFantasyMessages([]Message{{
+		Role:    "system",
+		Content: "static\n\ndynamic",
+		SystemParts: []ContentBlock{
+			{Type: "text", Text: "static", CacheControl: &CacheControl{Type: "ephemeral"}},
+			{Type: "text", Text: "dynamic"},
+		},
+	}})
+
+	parts := msgs[0].Content
+	if len(parts) != 2 {
+		t.Fatalf("got %d parts, want 2", len(parts))
+	}
+	if !hasCacheControl(parts[0].Options()) || hasCacheControl(parts[1].Options()) {
+		t.Error("only the static block should be a cache breakpoint")
+	}
+}
+
+func TestPromptCacheOptions(t *testing.T) {
+	if PromptCacheOptions("") != nil {
+		t.Error("no session, no cache key")
+	}
+	opts := PromptCacheOptions("agent:main:telegram:12345")
+	o, ok := opts[openai.Name].(*openai.ProviderOptions)
+	if !ok || o.PromptCacheKey == nil {
+		t.Fatalf("expected an openai prompt_cache_key, got %+v", opts)
+	}
+	if strings.Contains(*o.PromptCacheKey, "12345") {
+		t.Error("the cache key should not leak the chat ID")
+	}
+	if again := PromptCacheOptions("agent:main:telegram:12345")[openai.Name].(*openai.ProviderOptions); *again.PromptCacheKey != *o.PromptCacheKey {
+		t.Error("the cache key should be stable for a session")
+	}
+}
+
+func TestAnthropicRequestCarriesBreakpoints(t *testing.T) {
+	var body map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5",
+			"content":[{"type":"text","text":"hi"}],"stop_reason":"end_turn",
+			"usage":{"input_tokens":12,"output_tokens":3,"cache_read_input_tokens":9000,"cache_creation_input_tokens":40}}`)
+	}))
+	defer srv.Close()
+
+	model, err := FantasyModelFromConfig(&config.ModelConfig{
+		Model: "anthropic/claude-sonnet-4-5", APIKey: "key", APIBase: srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prompt := AgentXToFantasyMessages([]Message{
+		{Role: "system", SystemParts: []ContentBlock{
+			{Type: "text", Text: "static", CacheControl: &CacheControl{Type: "ephemeral"}},
+			{Type: "text", Text: "dynamic"},
+		}},
+		{Role: "user", Content: "earlier"},
+		{Role: "assistant", Content: "reply"},
+		{Role: "user", Content: "now"},
+	})
+	resp, err := model.Generate(context.Background(), fantasy.Call{Prompt: MarkCacheBreakpoints(prompt, 3)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, _ := json.Marshal(body)
+	if got := strings.Count(string(raw), `"cache_control"`); got != 3 {
+		t.Errorf("request has %d cache_control blocks, want 3 (system, history, last):\n%s", got, raw)
+	}
+	if resp.Usage.CacheReadTokens != 9000 || resp.Usage.CacheCreationTokens != 40 {
+		t.Errorf("cache usage not reported: %+v", resp.Usage)
+	}
+}
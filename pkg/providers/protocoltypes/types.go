@@ -1,5 +1,7 @@
 package protocoltypes
 
+import "encoding/json"
+
 type ToolCall struct {
 	ID               string         `json:"id"`
 	Type             string         `json:"type,omitempty"`
@@ -53,13 +55,25 @@ type ContentBlock struct {
 	CacheControl *CacheControl `json:"cache_control,omitempty"`
 }
 
+// MediaPart is a file attached to a message, such as a photo or voice note
+// received from a chat channel. Path points to a local file whose bytes are
+// read only when the message is sent to a provider.
+type MediaPart struct {
+	Type     string `json:"type"` // "image", "audio" or "file"
+	Path     string `json:"path"`
+	MIMEType string `json:"mime_type,omitempty"`
+	Filename string `json:"filename,omitempty"`
+}
+
 type Message struct {
-	Role             string         `json:"role"`
-	Content          string         `json:"content"`
-	ReasoningContent string         `json:"reasoning_content,omitempty"`
-	SystemParts      []ContentBlock `json:"system_parts,omitempty"` // structured system blocks for cache-aware adapters
-	ToolCalls        []ToolCall     `json:"tool_calls,omitempty"`
-	ToolCallID       string         `json:"tool_call_id,omitempty"`
+	Role             string          `json:"role"`
+	Content          string          `json:"content"`
+	ReasoningContent string          `json:"reasoning_content,omitempty"`
+	ReasoningData    json.RawMessage `json:"reasoning_data,omitempty"` // provider data needed to send the reasoning back (e.g. signatures)
+	SystemParts      []ContentBlock  `json:"system_parts,omitempty"`   // structured system blocks for cache-aware adapters
+	Media            []MediaPart     `json:"media,omitempty"`          // attachments on user messages
+	ToolCalls        []ToolCall      `json:"tool_calls,omitempty"`
+	ToolCallID       string          `json:"tool_call_id,omitempty"`
 }
 
 type ToolDefinition struct {
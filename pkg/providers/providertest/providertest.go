@@ -0,0 +1,246 @@
+// Package providertest records and replays provider HTTP exchanges so
+// providers can be tested without network access or API keys.
+//
+// A fixture is a JSON file holding the model it was recorded against and
+// the HTTP exchanges of one scenario, in order. Replay serves them from a
+// local server; point the model's api_base at Server.URL. Each request must
+// match the method and path of the next exchange, so a wrong endpoint (such
+// as a doubled /v1/v1/messages) fails the test.
+//
+// Run the tests with AGENTX_RECORD=1 and the key named by the fixture's
+// api_key_env to record it again: the server then forwards requests to the
+// fixture's upstream and rewrites the file with what came back.
+package providertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// RecordEnv is the environment variable that switches Replay to recording.
+const RecordEnv = "AGENTX_RECORD"
+
+// Fixture is one recorded scenario.
+type Fixture struct {
+	Model     string     `json:"model"`       // model_list model, e.g. "anthropic/claude-sonnet-4"
+	Upstream  string     `json:"upstream"`    // Real API host the fixture was recorded from
+	APIKeyEnv string     `json:"api_key_env"` // Variable holding the key used when recording
+	Exchanges []Exchange `json:"exchanges"`
+}
+
+// Exchange is one HTTP request and the response it got. Request headers are
+// never stored so keys cannot end up in fixtures.
+type Exchange struct {
+	Method      string            `json:"method"`
+	Path        string            `json:"path"`
+	Query       string            `json:"query,omitempty"`
+	RequestBody json.RawMessage   `json:"request_body,omitempty"`
+	Status      int               `json:"status"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	Body        string            `json:"body"`
+}
+
+// keptHeaders are the response headers stored in fixtures.
+var keptHeaders = []string{"Content-Type", "Retry-After"}
+
+// secretParams are query parameters dropped from recorded URLs.
+var secretParams = []string{"key", "api_key"}
+
+// Server serves a fixture, or records one in record mode.
+type Server struct {
+	URL     string
+	Fixture *Fixture
+
+	t         testing.TB
+	path      string
+	recording bool
+	upstream  *url.URL
+
+	mu       sync.Mutex
+	next     int
+	requests []Exchange
+}
+
+// Replay loads the fixture at path and starts a server for it. The server
+// is closed when the test ends; in replay mode the test fails if an
+// exchange was not used.
+func Replay(t testing.TB, path string) *Server {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading fixture: %v", err)
+	}
+	var fx Fixture
+	if err := json.Unmarshal(data, &fx); err != nil {
+		t.Fatalf("parsing fixture %s: %v", path, err)
+	}
+
+	s := &Server{
+		Fixture:   &fx,
+		t:         t,
+		path:      path,
+		recording: os.Getenv(RecordEnv) != "",
+	}
+	if s.recording {
+		if s.upstream, err = url.Parse(fx.Upstream); err != nil || fx.Upstream == "" {
+			t.Fatalf("fixture %s has no valid upstream to record from", path)
+		}
+		if s.APIKey() == "" {
+			t.Skipf("%s is not set, cannot record %s", fx.APIKeyEnv, path)
+		}
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(s.serve))
+	s.URL = srv.URL
+	t.Cleanup(func() {
+		srv.Close()
+		s.finish()
+	})
+	return s
+}
+
+// APIKey returns the key to configure the model with: a placeholder when
+// replaying, the real key when recording.
+func (s *Server) APIKey() string {
+	if s.recording {
+		return os.Getenv(s.Fixture.APIKeyEnv)
+	}
+	return "test-key"
+}
+
+// Requests returns the requests the server received so far, for checking
+// what a provider sent.
+func (s *Server) Requests() []Exchange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Exchange(nil), s.requests...)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	req := Exchange{
+		Method:      r.Method,
+		Path:        r.URL.Path,
+		Query:       scrubQuery(r.URL.Query()),
+		RequestBody: jsonBody(body),
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+
+	if s.recording {
+		s.forward(w, r, body, req)
+		return
+	}
+
+	s.mu.Lock()
+	i := s.next
+	s.next++
+	s.mu.Unlock()
+
+	if i >= len(s.Fixture.Exchanges) {
+		s.t.Errorf("%s: unexpected request %s %s, fixture has %d exchanges", s.path, req.Method, req.Path, len(s.Fixture.Exchanges))
+		http.Error(w, "no recorded exchange left", http.StatusNotImplemented)
+		return
+	}
+	ex := s.Fixture.Exchanges[i]
+	if ex.Method != req.Method || ex.Path != req.Path {
+		s.t.Errorf("%s: request %d is %s %s, recorded %s %s", s.path, i, req.Method, req.Path, ex.Method, ex.Path)
+		http.Error(w, "request does not match the recording", http.StatusNotImplemented)
+		return
+	}
+	writeExchange(w, ex)
+}
+
+// forward sends a request upstream and records the exchange.
+func (s *Server) forward(w http.ResponseWriter, r *http.Request, body []byte, ex Exchange) {
+	target := *s.upstream
+	target.Path = strings.TrimSuffix(target.Path, "/") + r.URL.Path
+	target.RawQuery = r.URL.RawQuery
+
+	out, err := http.NewRequestWithContext(r.Context(), r.Method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	out.Header = r.Header.Clone()
+	out.Header.Del("Accept-Encoding") // Keep bodies readable in fixtures
+
+	resp, err := http.DefaultClient.Do(out)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+
+	ex.Status = resp.StatusCode
+	ex.Body = string(respBody)
+	for _, h := range keptHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			if ex.Headers == nil {
+				ex.Headers = map[string]string{}
+			}
+			ex.Headers[h] = v
+		}
+	}
+
+	s.mu.Lock()
+	s.Fixture.Exchanges = append(s.Fixture.Exchanges[:s.next], ex)
+	s.next++
+	s.mu.Unlock()
+
+	writeExchange(w, ex)
+}
+
+// finish checks a replay used every exchange, or saves a recording.
+func (s *Server) finish() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.recording {
+		if s.next < len(s.Fixture.Exchanges) {
+			s.t.Errorf("%s: %d recorded exchanges were not requested", s.path, len(s.Fixture.Exchanges)-s.next)
+		}
+		return
+	}
+	data, err := json.MarshalIndent(s.Fixture, "", "  ")
+	if err != nil {
+		s.t.Errorf("encoding fixture: %v", err)
+		return
+	}
+	if err := os.WriteFile(s.path, append(data, '\n'), 0o644); err != nil {
+		s.t.Errorf("saving fixture: %v", err)
+	}
+}
+
+func writeExchange(w http.ResponseWriter, ex Exchange) {
+	for k, v := range ex.Headers {
+		w.Header().Set(k, v)
+	}
+	w.WriteHeader(ex.Status)
+	io.WriteString(w, ex.Body)
+}
+
+// jsonBody returns a request body when it is JSON, nil otherwise.
+func jsonBody(body []byte) json.RawMessage {
+	if len(body) == 0 || !json.Valid(body) {
+		return nil
+	}
+	return body
+}
+
+func scrubQuery(q url.Values) string {
+	for _, p := range secretParams {
+		q.Del(p)
+	}
+	return q.Encode()
+}
@@ -0,0 +1,104 @@
+package providertest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFixture(t *testing.T, fx Fixture) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	data, _ := json.Marshal(fx)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReplay(t *testing.T) {
+	path := writeFixture(t, Fixture{Exchanges: []Exchange{{
+		Method:  "POST",
+		Path:    "/v1/messages",
+		Status:  http.StatusTooManyRequests,
+		Headers: map[string]string{"Retry-After": "3"},
+		Body:    `{"error":"slow down"}`,
+	}}})
+	srv := Replay(t, path)
+
+	resp, err := http.Post(srv.URL+"/v1/messages", "application/json", strings.NewReader(`{"model":"m"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "3" || string(body) != `{"error":"slow down"}` {
+		t.Errorf("got %d %v %s", resp.StatusCode, resp.Header, body)
+	}
+	if reqs := srv.Requests(); len(reqs) != 1 || string(reqs[0].RequestBody) != `{"model":"m"}` {
+		t.Errorf("requests = %+v", reqs)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Request-Id", "req_1")
+		io.WriteString(w, `{"ok":true}`)
+	}))
+	defer upstream.Close()
+
+	path := writeFixture(t, Fixture{
+		Model:     "openai/gpt-4o-mini",
+		Upstream:  upstream.URL + "/v1",
+		APIKeyEnv: "TEST_PROVIDER_KEY",
+		Exchanges: []Exchange{{Method: "POST", Path: "/old", Status: 500}},
+	})
+	t.Setenv(RecordEnv, "1")
+	t.Setenv("TEST_PROVIDER_KEY", "secret")
+
+	t.Run("record", func(t *testing.T) {
+		srv := Replay(t, path)
+		req, _ := http.NewRequest("POST", srv.URL+"/chat/completions?key=secret&alt=sse", strings.NewReader(`{}`))
+		req.Header.Set("Authorization", "Bearer "+srv.APIKey())
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("request was not forwarded upstream: %d", resp.StatusCode)
+		}
+	})
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("the key was saved in the fixture:\n%s", data)
+	}
+	var fx Fixture
+	if err := json.Unmarshal(data, &fx); err != nil {
+		t.Fatal(err)
+	}
+	if fx.Model != "openai/gpt-4o-mini" || len(fx.Exchanges) != 1 {
+		t.Fatalf("fixture = %+v", fx)
+	}
+	ex := fx.Exchanges[0]
+	if ex.Path != "/chat/completions" || ex.Query != "alt=sse" || ex.Body != `{"ok":true}` || ex.Headers["Content-Type"] != "application/json" {
+		t.Errorf("recorded %+v", ex)
+	}
+	if _, ok := ex.Headers["X-Request-Id"]; ok {
+		t.Error("only the headers clients need should be kept")
+	}
+}
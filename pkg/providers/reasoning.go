@@ -0,0 +1,304 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/anthropic"
+	"charm.land/fantasy/providers/google"
+	"charm.land/fantasy/providers/openai"
+	"charm.land/fantasy/providers/openaicompat"
+
+	"github.com/Agentx-network/agentx/pkg/config"
+)
+
+// Reasoning models.
+//
+// A model_list entry with a "reasoning" section gets its thinking options
+// attached to every call: a token budget for Anthropic and Gemini, an
+// effort for OpenAI and OpenAI-compatible APIs, and "think" for Ollama.
+// Thinking comes back as reasoning content, kept apart from the reply, and
+// is sent back with the provider data (Anthropic signatures) the API needs
+// to continue a tool loop. Models that only write <think> tags into their
+// text are split the same way.
+
+// effortBudgets maps an effort to a thinking budget for providers that only
+// take a budget.
+var effortBudgets = map[string]int64{
+	"minimal": 1024,
+	"low":     2048,
+	"medium":  8192,
+	"high":    16384,
+}
+
+// reasoningBudget returns the configured budget, or the one for the effort.
+func reasoningBudget(rc *config.ReasoningConfig) int64 {
+	if rc.BudgetTokens > 0 {
+		return int64(rc.BudgetTokens)
+	}
+	if budget, ok := effortBudgets[rc.Effort]; ok {
+		return budget
+	}
+	return effortBudgets["medium"]
+}
+
+// ReasoningOptions returns the provider options that enable thinking for a
+// protocol. It returns nil when rc is nil or the protocol is configured
+// another way (Ollama takes it at provider creation).
+func ReasoningOptions(protocol string, rc *config.ReasoningConfig) fantasy.ProviderOptions {
+	if rc == nil {
+		return nil
+	}
+	switch protocol {
+	case "anthropic":
+		return fantasy.ProviderOptions{
+			anthropic.Name: &anthropic.ProviderOptions{
+				Thinking: &anthropic.ThinkingProviderOption{BudgetTokens: reasoningBudget(rc)},
+			},
+		}
+	case "gemini", "google":
+		budget := reasoningBudget(rc)
+		include := true
+		return fantasy.ProviderOptions{
+			google.Name: &google.ProviderOptions{
+				ThinkingConfig: &google.ThinkingConfig{ThinkingBudget: &budget, IncludeThoughts: &include},
+			},
+		}
+	case "openai":
+		if rc.Effort == "" {
+			return nil
+		}
+		return fantasy.ProviderOptions{
+			openai.Name: &openai.ProviderOptions{ReasoningEffort: openai.ReasoningEffortOption(openai.ReasoningEffort(rc.Effort))},
+		}
+	case "ollama":
+		return nil
+	default:
+		if rc.Effort == "" {
+			return nil
+		}
+		return fantasy.ProviderOptions{
+			openaicompat.Name: &openaicompat.ProviderOptions{
+				ReasoningEffort: openai.ReasoningEffortOption(openai.ReasoningEffort(rc.Effort)),
+			},
+		}
+	}
+}
+
+// WithReasoning wraps model so every call carries the thinking options for
+// protocol. The model is returned unchanged when there are none.
+func WithReasoning(model fantasy.LanguageModel, protocol string, rc *config.ReasoningConfig) fantasy.LanguageModel {
+	opts := ReasoningOptions(protocol, rc)
+	if opts == nil {
+		return model
+	}
+	return &reasoningModel{LanguageModel: model, opts: opts}
+}
+
+// reasoningModel adds thinking options to the calls of a LanguageModel.
+type reasoningModel struct {
+	fantasy.LanguageModel
+	opts fantasy.ProviderOptions
+}
+
+func (m *reasoningModel) Generate(ctx context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	return m.LanguageModel.Generate(ctx, m.apply(call))
+}
+
+func (m *reasoningModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.StreamResponse, error) {
+	return m.LanguageModel.Stream(ctx, m.apply(call))
+}
+
+// apply merges the thinking options into the call's options. Options set
+// by the caller win. Anthropic counts thinking against max_tokens, so the
+// budget is added on top of the requested output.
+func (m *reasoningModel) apply(call fantasy.Call) fantasy.Call {
+	merged := make(fantasy.ProviderOptions, len(call.ProviderOptions)+len(m.opts))
+	for k, v := range call.ProviderOptions {
+		merged[k] = v
+	}
+	for k, v := range m.opts {
+		merged[k] = mergeReasoningOption(merged[k], v)
+	}
+	call.ProviderOptions = merged
+
+	if a, ok := merged[anthropic.Name].(*anthropic.ProviderOptions); ok && a.Thinking != nil && call.MaxOutputTokens != nil {
+		maxTokens := *call.MaxOutputTokens + a.Thinking.BudgetTokens
+		call.MaxOutputTokens = &maxTokens
+	}
+	return call
+}
+
+// mergeReasoningOption fills the reasoning fields of existing from the
+// defaults, leaving its other fields alone.
+func mergeReasoningOption(existing, defaults fantasy.ProviderOptionsData) fantasy.ProviderOptionsData {
+	if existing == nil {
+		return defaults
+	}
+	switch d := defaults.(type) {
+	case *openai.ProviderOptions:
+		if e, ok := existing.(*openai.ProviderOptions); ok && e.ReasoningEffort == nil {
+			merged := *e
+			merged.ReasoningEffort = d.ReasoningEffort
+			return &merged
+		}
+	case *openaicompat.ProviderOptions:
+		if e, ok := existing.(*openaicompat.ProviderOptions); ok && e.ReasoningEffort == nil {
+			merged := *e
+			merged.ReasoningEffort = d.ReasoningEffort
+			return &merged
+		}
+	case *anthropic.ProviderOptions:
+		if e, ok := existing.(*anthropic.ProviderOptions); ok && e.Thinking == nil && e.Effort == nil {
+			merged := *e
+			merged.Thinking = d.Thinking
+			return &merged
+		}
+	case *google.ProviderOptions:
+		if e, ok := existing.(*google.ProviderOptions); ok && e.ThinkingConfig == nil {
+			merged := *e
+			merged.ThinkingConfig = d.ThinkingConfig
+			return &merged
+		}
+	}
+	return existing
+}
+
+// encodeReasoningMetadata stores the provider data of a reasoning block,
+// such as an Anthropic signature, so it can be sent back later.
+func encodeReasoningMetadata(metadata fantasy.ProviderMetadata) json.RawMessage {
+	if len(metadata) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// decodeReasoningMetadata restores data saved by encodeReasoningMetadata.
+func decodeReasoningMetadata(data json.RawMessage) fantasy.ProviderOptions {
+	if len(data) == 0 {
+		return nil
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil
+	}
+	opts, err := fantasy.UnmarshalProviderOptions(raw)
+	if err != nil {
+		return nil
+	}
+	return opts
+}
+
+const (
+	thinkOpen  = "<think>"
+	thinkClose = "</think>"
+)
+
+// SplitThinkTags separates <think>...</think> blocks, which some models
+// write into their reply instead of returning reasoning content, from the
+// rest of text. An unclosed block runs to the end of text.
+func SplitThinkTags(text string) (reasoning, content string) {
+	if !strings.Contains(text, thinkOpen) {
+		return "", text
+	}
+	var s ThinkTagStream
+	content, reasoning = s.Write(text)
+	restText, restReasoning := s.Flush()
+	return joinReasoning(reasoning, restReasoning), strings.TrimSpace(content + restText)
+}
+
+// ThinkTagStream removes <think>...</think> blocks from streamed text.
+// Tags may be split across deltas, so a partial tag at the end of a delta
+// is held back until the next one.
+type ThinkTagStream struct {
+	pending  string
+	inThink  bool
+	trimLeft bool // Drop the whitespace that follows a closed block
+	thinking strings.Builder
+}
+
+// Write consumes a delta. It returns the text to show and the reasoning of
+// any block that closed in this delta.
+func (s *ThinkTagStream) Write(delta string) (text, reasoning string) {
+	buf := s.pending + delta
+	s.pending = ""
+	var out strings.Builder
+	for buf != "" {
+		tag := thinkOpen
+		if s.inThink {
+			tag = thinkClose
+		}
+		if i := strings.Index(buf, tag); i >= 0 {
+			s.emit(&out, buf[:i])
+			buf = buf[i+len(tag):]
+			if s.inThink {
+				reasoning = joinReasoning(reasoning, s.thinking.String())
+				s.thinking.Reset()
+			}
+			s.trimLeft = s.inThink
+			s.inThink = !s.inThink
+			continue
+		}
+		// Keep a possible tag prefix for the next delta.
+		keep := partialSuffix(buf, tag)
+		s.emit(&out, buf[:len(buf)-keep])
+		s.pending = buf[len(buf)-keep:]
+		break
+	}
+	return out.String(), reasoning
+}
+
+// Flush returns held-back text at the end of the stream. An unclosed
+// block is returned as reasoning.
+func (s *ThinkTagStream) Flush() (text, reasoning string) {
+	pending := s.pending
+	s.pending = ""
+	if s.inThink {
+		s.thinking.WriteString(pending)
+		reasoning = strings.TrimSpace(s.thinking.String())
+		s.thinking.Reset()
+		s.inThink = false
+		return "", reasoning
+	}
+	return pending, ""
+}
+
+func (s *ThinkTagStream) emit(out *strings.Builder, text string) {
+	if s.inThink {
+		s.thinking.WriteString(text)
+		return
+	}
+	if s.trimLeft {
+		text = strings.TrimLeft(text, " \t\r\n")
+		s.trimLeft = text == ""
+	}
+	out.WriteString(text)
+}
+
+// joinReasoning joins reasoning blocks, skipping empty ones.
+func joinReasoning(blocks ...string) string {
+	parts := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		if b = strings.TrimSpace(b); b != "" {
+			parts = append(parts, b)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// partialSuffix returns the length of the longest suffix of s that is a
+// proper prefix of tag.
+func partialSuffix(s, tag string) int {
+	for n := min(len(tag)-1, len(s)); n > 0; n-- {
+		if strings.HasSuffix(s, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,155 @@
+package providers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/anthropic"
+	"charm.land/fantasy/providers/openai"
+	"charm.land/fantasy/providers/openaicompat"
+
+	"github.com/Agentx-network/agentx/pkg/config"
+)
+
+// callRecorder is a LanguageModel that keeps the last call it got.
+type callRecorder struct {
+	fantasy.LanguageModel
+	call fantasy.Call
+}
+
+func (m *callRecorder) Generate(ctx context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	m.call = call
+	return &fantasy.Response{}, nil
+}
+
+func TestReasoningOptions(t *testing.T) {
+	if ReasoningOptions("openai", nil) != nil {
+		t.Error("no reasoning config, no options")
+	}
+
+	a := ReasoningOptions("anthropic", &config.ReasoningConfig{Effort: "low"})[anthropic.Name].(*anthropic.ProviderOptions)
+	if a.Thinking == nil || a.Thinking.BudgetTokens != 2048 {
+		t.Errorf("anthropic budget from effort: %+v", a.Thinking)
+	}
+	a = ReasoningOptions("anthropic", &config.ReasoningConfig{Effort: "low", BudgetTokens: 5000})[anthropic.Name].(*anthropic.ProviderOptions)
+	if a.Thinking.BudgetTokens != 5000 {
+		t.Errorf("budget_tokens should win over effort, got %d", a.Thinking.BudgetTokens)
+	}
+
+	o := ReasoningOptions("openai", &config.ReasoningConfig{Effort: "high"})[openai.Name].(*openai.ProviderOptions)
+	if o.ReasoningEffort == nil || *o.ReasoningEffort != openai.ReasoningEffortHigh {
+		t.Errorf("openai effort: %+v", o.ReasoningEffort)
+	}
+
+	c := ReasoningOptions("deepseek", &config.ReasoningConfig{Effort: "medium"})[openaicompat.Name].(*openaicompat.ProviderOptions)
+	if c.ReasoningEffort == nil || *c.ReasoningEffort != openai.ReasoningEffortMedium {
+		t.Errorf("openai-compatible effort: %+v", c.ReasoningEffort)
+	}
+
+	if ReasoningOptions("ollama", &config.ReasoningConfig{}) != nil {
+		t.Error("ollama takes thinking at provider creation")
+	}
+}
+
+func TestWithReasoning_MergesCallOptions(t *testing.T) {
+	inner := &callRecorder{}
+	model := WithReasoning(inner, "openai", &config.ReasoningConfig{Effort: "low"})
+
+	model.Generate(context.Background(), fantasy.Call{ProviderOptions: PromptCacheOptions("session")})
+	o := inner.call.ProviderOptions[openai.Name].(*openai.ProviderOptions)
+	if o.PromptCacheKey == nil || o.ReasoningEffort == nil || *o.ReasoningEffort != openai.ReasoningEffortLow {
+		t.Errorf("expected both the cache key and the effort, got %+v", o)
+	}
+
+	if WithReasoning(inner, "openai", nil) != fantasy.LanguageModel(inner) {
+		t.Error("without reasoning the model should not be wrapped")
+	}
+}
+
+func TestWithReasoning_AnthropicMaxTokens(t *testing.T) {
+	inner := &callRecorder{}
+	model := WithReasoning(inner, "anthropic", &config.ReasoningConfig{BudgetTokens: 4000})
+
+	maxTokens := int64(1000)
+	model.Generate(context.Background(), fantasy.Call{MaxOutputTokens: &maxTokens})
+	if got := *inner.call.MaxOutputTokens; got != 5000 {
+		t.Errorf("max tokens = %d, want the budget on top of the reply (5000)", got)
+	}
+	if maxTokens != 1000 {
+		t.Error("the caller's value was modified")
+	}
+}
+
+func TestSplitThinkTags(t *testing.T) {
+	reasoning, content := SplitThinkTags("<think>\nThe user wants a greeting.\n</think>\n\nHello!")
+	if reasoning != "The user wants a greeting." || content != "Hello!" {
+		t.Errorf("got reasoning %q, content %q", reasoning, content)
+	}
+
+	if reasoning, content := SplitThinkTags("no tags here"); reasoning != "" || content != "no tags here" {
+		t.Errorf("text without tags changed: %q, %q", reasoning, content)
+	}
+
+	if reasoning, content := SplitThinkTags("<think>cut off"); reasoning != "cut off" || content != "" {
+		t.Errorf("unclosed block: %q, %q", reasoning, content)
+	}
+}
+
+func TestThinkTagStream(t *testing.T) {
+	var s ThinkTagStream
+	var text, reasoning strings.Builder
+	for _, delta := range []string{"<thi", "nk>plan ", "it</th", "ink>", "\n\nAn", "swer <", "b>"} {
+		out, r := s.Write(delta)
+		text.WriteString(out)
+		reasoning.WriteString(r)
+	}
+	out, r := s.Flush()
+	text.WriteString(out)
+	reasoning.WriteString(r)
+
+	if text.String() != "Answer <b>" {
+		t.Errorf("text = %q", text.String())
+	}
+	if reasoning.String() != "plan it" {
+		t.Errorf("reasoning = %q", reasoning.String())
+	}
+}
+
+func TestReasoningSignatureRoundTrip(t *testing.T) {
+	step := fantasy.StepResult{Response: fantasy.Response{Content: fantasy.ResponseContent{
+		fantasy.ReasoningContent{
+			Text: "I should list the files.",
+			ProviderMetadata: fantasy.ProviderMetadata{
+				anthropic.Name: &anthropic.ReasoningOptionMetadata{Signature: "sig-123"},
+			},
+		},
+		fantasy.ToolCallContent{ToolCallID: "call_1", ToolName: "list_dir", Input: "{}"},
+	}}}
+
+	msgs := FantasyStepToAgentXMessages(step)
+	if len(msgs) != 1 || msgs[0].ReasoningContent != "I should list the files." {
+		t.Fatalf("reasoning not kept apart from the reply: %+v", msgs)
+	}
+
+	back := AgentXToFantasyMessages(msgs)[0]
+	part, ok := fantasy.AsMessagePart[fantasy.ReasoningPart](back.Content[0])
+	if !ok {
+		t.Fatalf("reasoning should open the assistant message, got %+v", back.Content)
+	}
+	if meta := anthropic.GetReasoningMetadata(part.Options()); meta == nil || meta.Signature != "sig-123" {
+		t.Errorf("signature lost: %+v", meta)
+	}
+}
+
+func TestFantasyStepToAgentXMessages_ThinkTags(t *testing.T) {
+	step := fantasy.StepResult{Response: fantasy.Response{Content: fantasy.ResponseContent{
+		fantasy.TextContent{Text: "<think>Simple question.</think>It is 4."},
+	}}}
+
+	msgs := FantasyStepToAgentXMessages(step)
+	if msgs[0].Content != "It is 4." || msgs[0].ReasoningContent != "Simple question." {
+		t.Errorf("got %+v", msgs[0])
+	}
+}
@@ -0,0 +1,374 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// JSONStore keeps one JSON file per session inside a directory.
+// Every Save rewrites the whole file; it is the default store. Messages
+// dropped from a history are appended to "<session>.archive.jsonl" next to
+// it.
+type JSONStore struct {
+	dir string
+}
+
+// NewJSONStore creates a JSONStore rooted at dir, creating it if needed.
+func NewJSONStore(dir string) (*JSONStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &JSONStore{dir: dir}, nil
+}
+
+// sanitizeFilename converts a session key into a cross-platform safe filename.
+// Session keys use "channel:chatID" (e.g. "telegram:123456") but ':' is the
+// volume separator on Windows, so filepath.Base would misinterpret the key.
+// We replace it with '_'. The original key is preserved inside the JSON file,
+// so Load can verify the file belongs to the requested key.
+func sanitizeFilename(key string) string {
+	return strings.ReplaceAll(key, ":", "_")
+}
+
+// sessionPath returns the file path for key, rejecting keys that would
+// escape the store directory.
+func (s *JSONStore) sessionPath(key string) (string, error) {
+	filename := sanitizeFilename(key)
+
+	// filepath.IsLocal rejects empty names, "..", absolute paths, and
+	// OS-reserved device names (NUL, COM1 … on Windows).
+	// The extra checks reject "." and any directory separators so that
+	// the session file is always written directly inside the store directory.
+	if filename == "." || !filepath.IsLocal(filename) || strings.ContainsAny(filename, `/\`) {
+		return "", os.ErrInvalid
+	}
+	return filepath.Join(s.dir, filename+".json"), nil
+}
+
+// archivePath returns the archive file path for key.
+func (s *JSONStore) archivePath(key string) (string, error) {
+	path, err := s.sessionPath(key)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(path, ".json") + ".archive.jsonl", nil
+}
+
+func (s *JSONStore) Load(key string) (*Session, error) {
+	path, err := s.sessionPath(key)
+	if err != nil {
+		// Keys that cannot be saved cannot have been stored either.
+		return nil, nil
+	}
+
+	sess, err := readSessionFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if sess.Key != key {
+		// A different key sanitized to the same filename.
+		return nil, nil
+	}
+	return sess, nil
+}
+
+func (s *JSONStore) Save(sess *Session, _ int) error {
+	sessionPath, err := s.sessionPath(sess.Key)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(sess, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp(s.dir, "session-*.tmp")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := tmpFile.Name()
+	cleanup := true
+	defer func() {
+		if cleanup {
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmpFile.Write(data); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Chmod(0o644); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, sessionPath); err != nil {
+		return err
+	}
+
+	// Sync directory to ensure rename is durable.
+	// Without this, the new directory entry can be lost on crash
+	// (especially on flash storage / SD cards).
+	if dirFile, err := os.Open(s.dir); err == nil {
+		_ = dirFile.Sync()
+		dirFile.Close()
+	}
+
+	cleanup = false
+	return nil
+}
+
+func (s *JSONStore) Archive(key string, msgs []ArchivedMessage) error {
+	path, err := s.archivePath(key)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, msg := range msgs {
+		if msg.Role != "user" && msg.Role != "assistant" {
+			continue
+		}
+		if err := enc.Encode(msg); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (s *JSONStore) Delete(key string) error {
+	path, err := s.sessionPath(key)
+	if err != nil {
+		return err
+	}
+	archive, _ := s.archivePath(key)
+	for _, p := range []string{path, archive} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *JSONStore) List() ([]SessionInfo, error) {
+	var infos []SessionInfo
+	err := s.each(func(sess *Session) {
+		infos = append(infos, SessionInfo{
+			Key:          sess.Key,
+			MessageCount: len(sess.Messages),
+			Created:      sess.Created,
+			Updated:      sess.Updated,
+		})
+	})
+	return infos, err
+}
+
+// Search scans every session file for messages containing all query terms.
+// It is linear in the size of the store; use the SQLite store for large
+// deployments.
+func (s *JSONStore) Search(query string, limit int, allow func(key string) bool) ([]SearchResult, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+
+	var results []SearchResult
+	err := s.each(func(sess *Session) {
+		if allow != nil && !allow(sess.Key) {
+			return
+		}
+		if snippet, ok := matchSnippet(sess.Summary, terms); ok {
+			results = append(results, SearchResult{
+				SessionKey: sess.Key,
+				Role:       "summary",
+				Snippet:    snippet,
+				Timestamp:  sess.Updated,
+			})
+		}
+		for _, msg := range sess.Messages {
+			if msg.Role != "user" && msg.Role != "assistant" {
+				continue
+			}
+			if snippet, ok := matchSnippet(msg.Content, terms); ok {
+				results = append(results, SearchResult{
+					SessionKey: sess.Key,
+					Role:       msg.Role,
+					Snippet:    snippet,
+					Timestamp:  sess.Updated,
+				})
+			}
+		}
+		archived, _ := s.Archived(sess.Key)
+		for _, msg := range archived {
+			if snippet, ok := matchSnippet(msg.Content, terms); ok {
+				results = append(results, SearchResult{
+					SessionKey: sess.Key,
+					Role:       msg.Role,
+					Snippet:    snippet,
+					Timestamp:  msg.Time,
+				})
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Timestamp.After(results[j].Timestamp)
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+func (s *JSONStore) Close() error {
+	return nil
+}
+
+// each calls fn for every readable session file in the store directory.
+func (s *JSONStore) each(fn func(*Session)) error {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+		sess, err := readSessionFile(filepath.Join(s.dir, file.Name()))
+		if err != nil {
+			continue
+		}
+		fn(sess)
+	}
+	return nil
+}
+
+// Archived reads the archive file of a session, skipping lines that cannot
+// be decoded.
+func (s *JSONStore) Archived(key string) ([]ArchivedMessage, error) {
+	path, err := s.archivePath(key)
+	if err != nil {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var msgs []ArchivedMessage
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadBytes('\n')
+		var msg ArchivedMessage
+		if len(bytes.TrimSpace(line)) > 0 && json.Unmarshal(line, &msg) == nil {
+			msgs = append(msgs, msg)
+		}
+		if err == io.EOF {
+			return msgs, nil
+		}
+		if err != nil {
+			return msgs, err
+		}
+	}
+}
+
+func readSessionFile(path string) (*Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var sess Session
+	if err := json.Unmarshal(data, &sess); err != nil {
+		return nil, err
+	}
+	return &sess, nil
+}
+
+// snippetRadius is the number of runes kept before a match; twice as many
+// are kept after it.
+const snippetRadius = 60
+
+// matchSnippet reports whether text contains every term and returns a short
+// excerpt around the first one.
+func matchSnippet(text string, terms []string) (string, bool) {
+	if text == "" {
+		return "", false
+	}
+	lower := strings.ToLower(text)
+	first := -1
+	for _, term := range terms {
+		idx := strings.Index(lower, term)
+		if idx < 0 {
+			return "", false
+		}
+		if first < 0 || idx < first {
+			first = idx
+		}
+	}
+
+	// Lower-casing can change byte lengths for some scripts; fall back to
+	// the start of the text rather than slicing mid-rune.
+	if len(lower) != len(text) {
+		first = 0
+	}
+
+	start := first
+	for n := 0; start > 0 && n < snippetRadius; n++ {
+		_, size := utf8.DecodeLastRuneInString(text[:start])
+		start -= size
+	}
+	end := first
+	for n := 0; end < len(text) && n < 2*snippetRadius; n++ {
+		_, size := utf8.DecodeRuneInString(text[end:])
+		end += size
+	}
+
+	snippet := strings.Join(strings.Fields(text[start:end]), " ")
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(text) {
+		snippet += "…"
+	}
+	return snippet, true
+}
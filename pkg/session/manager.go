@@ -1,13 +1,13 @@
 package session
 
 import (
-	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
 	"time"
 
+	"github.com/Agentx-network/agentx/pkg/logger"
 	"github.com/Agentx-network/agentx/pkg/providers"
 )
 
@@ -17,33 +17,57 @@ type Session struct {
 	Summary  string              `json:"summary,omitempty"`
 	Created  time.Time           `json:"created"`
 	Updated  time.Time           `json:"updated"`
+
+	// persisted is the number of leading messages already written to the
+	// store; rewrites counts history rewrites that invalidate that prefix.
+	persisted int
+	rewrites  uint64
+	// archived holds messages dropped from the history that the next Save
+	// hands to the store's archive, so they stay searchable.
+	archived []ArchivedMessage
 }
 
+// SessionManager keeps sessions in memory and persists them through a
+// SessionStore. Sessions are loaded from the store on first access.
 type SessionManager struct {
 	sessions map[string]*Session
-	mu       sync.RWMutex
-	storage  string
+	mu       sync.Mutex
+	store    SessionStore
 }
 
+// NewSessionManager creates a manager backed by JSON files in storage.
+// An empty storage keeps sessions in memory only.
 func NewSessionManager(storage string) *SessionManager {
-	sm := &SessionManager{
-		sessions: make(map[string]*Session),
-		storage:  storage,
+	if storage == "" {
+		return NewSessionManagerWithStore(nil)
 	}
 
-	if storage != "" {
-		os.MkdirAll(storage, 0o755)
-		sm.loadSessions()
+	store, err := NewJSONStore(storage)
+	if err != nil {
+		logger.WarnCF("session", "Failed to open session storage, keeping sessions in memory",
+			map[string]any{
+				"storage": storage,
+				"error":   err.Error(),
+			})
+		return NewSessionManagerWithStore(nil)
 	}
+	return NewSessionManagerWithStore(store)
+}
 
-	return sm
+// NewSessionManagerWithStore creates a manager backed by store.
+// A nil store keeps sessions in memory only.
+func NewSessionManagerWithStore(store SessionStore) *SessionManager {
+	return &SessionManager{
+		sessions: make(map[string]*Session),
+		store:    store,
+	}
 }
 
 func (sm *SessionManager) GetOrCreate(key string) *Session {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	session, ok := sm.sessions[key]
+	session, ok := sm.lookup(key)
 	if ok {
 		return session
 	}
@@ -72,7 +96,7 @@ func (sm *SessionManager) AddFullMessage(sessionKey string, msg providers.Messag
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	session, ok := sm.sessions[sessionKey]
+	session, ok := sm.lookup(sessionKey)
 	if !ok {
 		session = &Session{
 			Key:      sessionKey,
@@ -87,10 +111,10 @@ func (sm *SessionManager) AddFullMessage(sessionKey string, msg providers.Messag
 }
 
 func (sm *SessionManager) GetHistory(key string) []providers.Message {
-	sm.mu.RLock()
-	defer sm.mu.RUnlock()
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
 
-	session, ok := sm.sessions[key]
+	session, ok := sm.lookup(key)
 	if !ok {
 		return []providers.Message{}
 	}
@@ -101,10 +125,10 @@ func (sm *SessionManager) GetHistory(key string) []providers.Message {
 }
 
 func (sm *SessionManager) GetSummary(key string) string {
-	sm.mu.RLock()
-	defer sm.mu.RUnlock()
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
 
-	session, ok := sm.sessions[key]
+	session, ok := sm.lookup(key)
 	if !ok {
 		return ""
 	}
@@ -115,7 +139,7 @@ func (sm *SessionManager) SetSummary(key string, summary string) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	session, ok := sm.sessions[key]
+	session, ok := sm.lookup(key)
 	if ok {
 		session.Summary = summary
 		session.Updated = time.Now()
@@ -126,14 +150,16 @@ func (sm *SessionManager) TruncateHistory(key string, keepLast int) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	session, ok := sm.sessions[key]
+	session, ok := sm.lookup(key)
 	if !ok {
 		return
 	}
 
 	if keepLast <= 0 {
+		sm.archive(session, session.Messages)
 		session.Messages = []providers.Message{}
 		session.Updated = time.Now()
+		session.markRewritten()
 		return
 	}
 
@@ -141,39 +167,189 @@ func (sm *SessionManager) TruncateHistory(key string, keepLast int) {
 		return
 	}
 
+	sm.archive(session, session.Messages[:len(session.Messages)-keepLast])
 	session.Messages = session.Messages[len(session.Messages)-keepLast:]
 	session.Updated = time.Now()
+	session.markRewritten()
 }
 
-// sanitizeFilename converts a session key into a cross-platform safe filename.
-// Session keys use "channel:chatID" (e.g. "telegram:123456") but ':' is the
-// volume separator on Windows, so filepath.Base would misinterpret the key.
-// We replace it with '_'. The original key is preserved inside the JSON file,
-// so loadSessions still maps back to the right in-memory key.
-func sanitizeFilename(key string) string {
-	return strings.ReplaceAll(key, ":", "_")
+// Info returns metadata for the session, loading it from the store if needed.
+func (sm *SessionManager) Info(key string) (SessionInfo, bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, ok := sm.lookup(key)
+	if !ok {
+		return SessionInfo{}, false
+	}
+	return SessionInfo{
+		Key:          session.Key,
+		MessageCount: len(session.Messages),
+		Created:      session.Created,
+		Updated:      session.Updated,
+	}, true
 }
 
-func (sm *SessionManager) Save(key string) error {
-	if sm.storage == "" {
-		return nil
+// Reset clears the history and summary of a session so the next turn starts
+// a fresh conversation. Call Save to persist the reset.
+func (sm *SessionManager) Reset(key string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	session, ok := sm.lookup(key)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	sm.archive(session, session.Messages)
+	session.Messages = []providers.Message{}
+	session.Summary = ""
+	session.Created = now
+	session.Updated = now
+	session.markRewritten()
+}
+
+// ApplyRetention archives or deletes stored sessions that have not been
+// updated since policy.MaxAge before now, returning how many were removed.
+// Archived copies are keyed "<key>:<updated>" so repeated archives of the
+// same session key do not overwrite each other, and are deleted in turn
+// once they have been in the archive for policy.MaxAge.
+func (sm *SessionManager) ApplyRetention(policy RetentionPolicy, now time.Time) (int, error) {
+	if sm.store == nil || !policy.Enabled() {
+		return 0, nil
+	}
+	if err := policy.Validate(); err != nil {
+		return 0, err
+	}
+
+	var archive *JSONStore
+	if policy.Action != RetentionDelete {
+		var err error
+		if archive, err = NewJSONStore(policy.ArchiveDir); err != nil {
+			return 0, fmt.Errorf("opening session archive: %w", err)
+		}
+	}
+
+	infos, err := sm.store.List()
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := now.Add(-policy.MaxAge)
+	removed := 0
+	for _, info := range infos {
+		if !info.Updated.Before(cutoff) {
+			continue
+		}
+		ok, err := sm.retire(info.Key, cutoff, archive)
+		if err != nil {
+			return removed, err
+		}
+		if ok {
+			removed++
+		}
+	}
+
+	if policy.ArchiveDir != "" {
+		pruned, err := pruneArchive(policy.ArchiveDir, cutoff)
+		if err != nil {
+			return removed, fmt.Errorf("pruning session archive: %w", err)
+		}
+		if pruned > 0 {
+			logger.InfoCF("session", "Deleted expired session archives", map[string]any{
+				"dir":     policy.ArchiveDir,
+				"deleted": pruned,
+			})
+		}
+	}
+	return removed, nil
+}
+
+// pruneArchive deletes the files in an archive directory last written
+// before cutoff, which is when the session in them was archived.
+func pruneArchive(dir string, cutoff time.Time) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	pruned := 0
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil && !os.IsNotExist(err) {
+			return pruned, err
+		}
+		pruned++
+	}
+	return pruned, nil
+}
+
+// retire removes one expired session from memory and the store, archiving
+// it and its archived messages first when archive is non-nil. The lock is
+// held throughout so a concurrent turn cannot reload the session halfway
+// through.
+func (sm *SessionManager) retire(key string, cutoff time.Time, archive *JSONStore) (bool, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if session, ok := sm.sessions[key]; ok && !session.Updated.Before(cutoff) {
+		// Active again since the store was listed.
+		return false, nil
 	}
 
-	filename := sanitizeFilename(key)
+	if archive != nil {
+		stored, err := sm.store.Load(key)
+		if err != nil {
+			return false, fmt.Errorf("loading session %q for archive: %w", key, err)
+		}
+		if stored != nil {
+			// Messages already dropped from the history move along, or the
+			// store's Delete would lose them.
+			archived, err := sm.store.Archived(key)
+			if err != nil {
+				return false, fmt.Errorf("loading archived messages of session %q: %w", key, err)
+			}
+			if session, ok := sm.sessions[key]; ok {
+				archived = append(archived, session.archived...)
+			}
+			stored.Key = key + ":" + stored.Updated.Format("20060102T150405")
+			if err := archive.Save(stored, 0); err != nil {
+				return false, fmt.Errorf("archiving session %q: %w", key, err)
+			}
+			if len(archived) > 0 {
+				if err := archive.Archive(stored.Key, archived); err != nil {
+					return false, fmt.Errorf("archiving session %q: %w", key, err)
+				}
+			}
+		}
+	}
 
-	// filepath.IsLocal rejects empty names, "..", absolute paths, and
-	// OS-reserved device names (NUL, COM1 … on Windows).
-	// The extra checks reject "." and any directory separators so that
-	// the session file is always written directly inside sm.storage.
-	if filename == "." || !filepath.IsLocal(filename) || strings.ContainsAny(filename, `/\`) {
-		return os.ErrInvalid
+	if err := sm.store.Delete(key); err != nil {
+		return false, err
+	}
+	delete(sm.sessions, key)
+	return true, nil
+}
+
+func (sm *SessionManager) Save(key string) error {
+	if sm.store == nil {
+		return nil
 	}
 
-	// Snapshot under read lock, then perform slow file I/O after unlock.
-	sm.mu.RLock()
+	// Snapshot under lock, then perform slow storage I/O after unlock.
+	sm.mu.Lock()
 	stored, ok := sm.sessions[key]
 	if !ok {
-		sm.mu.RUnlock()
+		sm.mu.Unlock()
 		return nil
 	}
 
@@ -189,89 +365,109 @@ func (sm *SessionManager) Save(key string) error {
 	} else {
 		snapshot.Messages = []providers.Message{}
 	}
-	sm.mu.RUnlock()
-
-	data, err := json.MarshalIndent(snapshot, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	sessionPath := filepath.Join(sm.storage, filename+".json")
-	tmpFile, err := os.CreateTemp(sm.storage, "session-*.tmp")
-	if err != nil {
-		return err
-	}
-
-	tmpPath := tmpFile.Name()
-	cleanup := true
-	defer func() {
-		if cleanup {
-			_ = os.Remove(tmpPath)
+	persisted := stored.persisted
+	rewrites := stored.rewrites
+	archived := stored.archived
+	stored.archived = nil
+	sm.mu.Unlock()
+
+	if len(archived) > 0 {
+		if err := sm.store.Archive(key, archived); err != nil {
+			sm.mu.Lock()
+			stored.archived = append(archived, stored.archived...)
+			sm.mu.Unlock()
+			return fmt.Errorf("archiving messages: %w", err)
 		}
-	}()
-
-	if _, err := tmpFile.Write(data); err != nil {
-		_ = tmpFile.Close()
-		return err
-	}
-	if err := tmpFile.Chmod(0o644); err != nil {
-		_ = tmpFile.Close()
-		return err
 	}
-	if err := tmpFile.Sync(); err != nil {
-		_ = tmpFile.Close()
+	if err := sm.store.Save(&snapshot, persisted); err != nil {
 		return err
 	}
-	if err := tmpFile.Close(); err != nil {
-		return err
+
+	// Only advance the persisted mark if the history was not rewritten
+	// (truncated, compressed, replaced) while the save was in flight.
+	sm.mu.Lock()
+	if current, ok := sm.sessions[key]; ok && current == stored && current.rewrites == rewrites {
+		current.persisted = len(snapshot.Messages)
 	}
+	sm.mu.Unlock()
+	return nil
+}
 
-	if err := os.Rename(tmpPath, sessionPath); err != nil {
-		return err
+// Search runs a full-text query over stored sessions visible in scope.
+func (sm *SessionManager) Search(query string, limit int, scope SearchScope) ([]SearchResult, error) {
+	if sm.store == nil {
+		return nil, nil
 	}
+	return sm.store.Search(query, limit, scope.Allows)
+}
 
-	// Sync directory to ensure rename is durable.
-	// Without this, the new directory entry can be lost on crash
-	// (especially on flash storage / SD cards).
-	if dirFile, err := os.Open(sm.storage); err == nil {
-		_ = dirFile.Sync()
-		dirFile.Close()
+// Close releases the underlying store.
+func (sm *SessionManager) Close() error {
+	if sm.store == nil {
+		return nil
 	}
+	return sm.store.Close()
+}
 
-	cleanup = false
-	return nil
+// Store returns the underlying session store, or nil for in-memory managers.
+func (sm *SessionManager) Store() SessionStore {
+	return sm.store
 }
 
-func (sm *SessionManager) loadSessions() error {
-	files, err := os.ReadDir(sm.storage)
-	if err != nil {
-		return err
+// lookup returns the in-memory session for key, loading it from the store
+// on first access. The caller must hold sm.mu for writing.
+func (sm *SessionManager) lookup(key string) (*Session, bool) {
+	if session, ok := sm.sessions[key]; ok {
+		return session, true
+	}
+	if sm.store == nil {
+		return nil, false
 	}
 
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
+	session, err := sm.store.Load(key)
+	if err != nil {
+		logger.WarnCF("session", "Failed to load session", map[string]any{
+			"session_key": key,
+			"error":       err.Error(),
+		})
+		return nil, false
+	}
+	if session == nil {
+		return nil, false
+	}
+	if session.Messages == nil {
+		session.Messages = []providers.Message{}
+	}
+	session.persisted = len(session.Messages)
+	sm.sessions[key] = session
+	return session, true
+}
 
-		if filepath.Ext(file.Name()) != ".json" {
-			continue
-		}
+// Archive keeps messages about to be dropped from a session's history
+// searchable. TruncateHistory and Reset archive what they drop; callers that
+// rewrite the history with SetHistory archive the dropped messages first.
+// The messages reach the store with the next Save.
+func (sm *SessionManager) Archive(key string, msgs []providers.Message) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
 
-		sessionPath := filepath.Join(sm.storage, file.Name())
-		data, err := os.ReadFile(sessionPath)
-		if err != nil {
-			continue
-		}
+	if session, ok := sm.lookup(key); ok {
+		sm.archive(session, msgs)
+	}
+}
 
-		var session Session
-		if err := json.Unmarshal(data, &session); err != nil {
-			continue
+// archive queues the user and assistant messages of msgs for the store's
+// archive. The caller must hold sm.mu.
+func (sm *SessionManager) archive(session *Session, msgs []providers.Message) {
+	if sm.store == nil {
+		return
+	}
+	now := time.Now()
+	for _, msg := range msgs {
+		if (msg.Role == "user" || msg.Role == "assistant") && msg.Content != "" {
+			session.archived = append(session.archived, ArchivedMessage{Role: msg.Role, Content: msg.Content, Time: now})
 		}
-
-		sm.sessions[session.Key] = &session
 	}
-
-	return nil
 }
 
 // SetHistory updates the messages of a session.
@@ -279,7 +475,7 @@ func (sm *SessionManager) SetHistory(key string, history []providers.Message) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	session, ok := sm.sessions[key]
+	session, ok := sm.lookup(key)
 	if ok {
 		// Create a deep copy to strictly isolate internal state
 		// from the caller's slice.
@@ -287,5 +483,13 @@ func (sm *SessionManager) SetHistory(key string, history []providers.Message) {
 		copy(msgs, history)
 		session.Messages = msgs
 		session.Updated = time.Now()
+		session.markRewritten()
 	}
 }
+
+// markRewritten records that the stored message prefix no longer matches
+// the in-memory history, so the next Save replaces it entirely.
+func (s *Session) markRewritten() {
+	s.persisted = 0
+	s.rewrites++
+}
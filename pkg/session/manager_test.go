@@ -72,3 +72,125 @@ func TestSave_RejectsPathTraversal(t *testing.T) {
 		}
 	}
 }
+
+func TestJSONStore_LazyLoadAndSearch(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm := NewSessionManager(tmpDir)
+	sm.AddMessage("telegram:1", "user", "remind me how to restart nginx")
+	sm.AddMessage("telegram:1", "assistant", "Use `sudo systemctl restart nginx`.")
+	if err := sm.Save("telegram:1"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	sm2 := NewSessionManager(tmpDir)
+	if len(sm2.sessions) != 0 {
+		t.Fatalf("expected no sessions loaded eagerly, got %d", len(sm2.sessions))
+	}
+
+	results, err := sm2.Store().Search("restart NGINX", 10, nil)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 hits, got %d: %+v", len(results), results)
+	}
+	if results[0].SessionKey != "telegram:1" {
+		t.Errorf("expected session key telegram:1, got %q", results[0].SessionKey)
+	}
+
+	if got := len(sm2.GetHistory("telegram:1")); got != 2 {
+		t.Errorf("expected 2 messages after lazy load, got %d", got)
+	}
+}
+
+func TestSearchScope_Allows(t *testing.T) {
+	scope := SearchScope{SessionKey: "agent:main:telegram:direct:42"}
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"agent:main:telegram:direct:42", true},
+		{"agent:helper:telegram:direct:42", true},
+		{"agent:main:discord:direct:42", false},
+		{"agent:main:telegram:direct:420", false},
+		{"agent:main:telegram:direct:43", false},
+		{"agent:main:main", false},
+	}
+	for _, tt := range tests {
+		if got := scope.Allows(tt.key); got != tt.want {
+			t.Errorf("Allows(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+
+	if (SearchScope{}).Allows("") {
+		t.Error("empty scope should not allow anything")
+	}
+	if !(SearchScope{All: true}).Allows("anything") {
+		t.Error("All scope should allow every session")
+	}
+}
+
+func TestSessionManager_TruncatedMessagesStaySearchable(t *testing.T) {
+	stores := map[string]func(dir string) (SessionStore, error){
+		"json":   func(dir string) (SessionStore, error) { return NewJSONStore(dir) },
+		"sqlite": func(dir string) (SessionStore, error) { return NewStore(StoreSQLite, dir) },
+	}
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			store, err := open(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			sm := NewSessionManagerWithStore(store)
+			defer sm.Close()
+
+			key := "agent:main:telegram:direct:42"
+			sm.AddMessage(key, "user", "my locker code is 4711")
+			sm.AddMessage(key, "assistant", "Noted.")
+			for i := 0; i < 4; i++ {
+				sm.AddMessage(key, "user", "something else")
+			}
+			if err := sm.Save(key); err != nil {
+				t.Fatal(err)
+			}
+
+			// Summarization keeps only the last messages.
+			sm.TruncateHistory(key, 4)
+			if err := sm.Save(key); err != nil {
+				t.Fatal(err)
+			}
+			results, err := sm.Search("locker code", 10, SearchScope{SessionKey: key})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 1 || results[0].Role != "user" || results[0].SessionKey != key {
+				t.Fatalf("truncated message should be found, got %+v", results)
+			}
+
+			// So does a reset, also from another agent of the same chat.
+			sm.AddMessage(key, "user", "the wifi password is hunter2")
+			sm.Reset(key)
+			if err := sm.Save(key); err != nil {
+				t.Fatal(err)
+			}
+			results, err = sm.Search("hunter2", 10, SearchScope{SessionKey: "agent:helper:telegram:direct:42"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("reset message should be found, got %+v", results)
+			}
+			if results, _ := sm.Search("hunter2", 10, SearchScope{SessionKey: "agent:main:telegram:direct:43"}); len(results) != 0 {
+				t.Errorf("another chat should not see the archive, got %+v", results)
+			}
+
+			// Deleting the session deletes its archive.
+			if err := store.Delete(key); err != nil {
+				t.Fatal(err)
+			}
+			if results, _ := sm.Search("locker", 10, SearchScope{All: true}); len(results) != 0 {
+				t.Errorf("archive should be deleted with the session, got %+v", results)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package session
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Reasons reported by ResetPolicy.ResetReason.
+const (
+	ResetReasonIdle   = "idle"
+	ResetReasonDaily  = "daily"
+	ResetReasonMaxAge = "max_age"
+)
+
+// Actions for RetentionPolicy.Action.
+const (
+	RetentionArchive = "archive"
+	RetentionDelete  = "delete"
+)
+
+// ResetPolicy decides when a session has gone stale and its conversation
+// should start fresh. Zero fields disable the corresponding rule.
+type ResetPolicy struct {
+	// IdleTimeout resets a session that has not been updated for this long.
+	IdleTimeout time.Duration
+	// DailyAt resets sessions last updated before the most recent occurrence
+	// of this local time of day, in minutes after midnight. Negative disables.
+	DailyAt int
+	// MaxAge resets a session this long after it was created.
+	MaxAge time.Duration
+}
+
+// NewResetPolicy builds a ResetPolicy from config values. dailyAt is a
+// local "HH:MM" time; an empty string disables the daily reset.
+func NewResetPolicy(idleHours int, dailyAt string, maxAgeHours int) (ResetPolicy, error) {
+	p := ResetPolicy{
+		IdleTimeout: time.Duration(idleHours) * time.Hour,
+		DailyAt:     -1,
+		MaxAge:      time.Duration(maxAgeHours) * time.Hour,
+	}
+	if strings.TrimSpace(dailyAt) != "" {
+		minutes, err := ParseTimeOfDay(dailyAt)
+		if err != nil {
+			return p, err
+		}
+		p.DailyAt = minutes
+	}
+	return p, nil
+}
+
+// Enabled reports whether any reset rule is active.
+func (p ResetPolicy) Enabled() bool {
+	return p.IdleTimeout > 0 || p.DailyAt >= 0 || p.MaxAge > 0
+}
+
+// ResetReason returns why the session described by info should be reset at
+// now, or "" if it is still fresh. Sessions without messages never reset.
+func (p ResetPolicy) ResetReason(info SessionInfo, now time.Time) string {
+	if info.MessageCount == 0 {
+		return ""
+	}
+	if p.IdleTimeout > 0 && now.Sub(info.Updated) >= p.IdleTimeout {
+		return ResetReasonIdle
+	}
+	if p.DailyAt >= 0 && info.Updated.Before(lastDailyBoundary(now, p.DailyAt)) {
+		return ResetReasonDaily
+	}
+	if p.MaxAge > 0 && !info.Created.IsZero() && now.Sub(info.Created) >= p.MaxAge {
+		return ResetReasonMaxAge
+	}
+	return ""
+}
+
+// lastDailyBoundary returns the most recent time at or before now whose
+// local time of day is minutes after midnight.
+func lastDailyBoundary(now time.Time, minutes int) time.Time {
+	y, m, d := now.Date()
+	boundary := time.Date(y, m, d, minutes/60, minutes%60, 0, 0, now.Location())
+	if boundary.After(now) {
+		boundary = boundary.AddDate(0, 0, -1)
+	}
+	return boundary
+}
+
+// ParseTimeOfDay parses a 24-hour "HH:MM" time into minutes after midnight.
+func ParseTimeOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q (expected HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// RetentionPolicy removes sessions that have not been updated for MaxAge.
+// Archived sessions are written as JSON files to ArchiveDir before removal
+// and kept there for MaxAge.
+type RetentionPolicy struct {
+	MaxAge     time.Duration
+	Action     string // RetentionArchive or RetentionDelete
+	ArchiveDir string
+}
+
+// Enabled reports whether the policy removes anything.
+func (p RetentionPolicy) Enabled() bool {
+	return p.MaxAge > 0
+}
+
+// Validate checks that Action is one of the retention actions.
+func (p RetentionPolicy) Validate() error {
+	switch p.Action {
+	case RetentionArchive, RetentionDelete:
+		return nil
+	}
+	return fmt.Errorf("retention action must be %s or %s, got %q", RetentionArchive, RetentionDelete, p.Action)
+}
@@ -0,0 +1,187 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResetPolicy_ResetReason(t *testing.T) {
+	loc := time.UTC
+	now := time.Date(2026, 3, 10, 9, 30, 0, 0, loc)
+
+	policy, err := NewResetPolicy(6, "04:00", 48)
+	if err != nil {
+		t.Fatalf("NewResetPolicy failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		created time.Time
+		updated time.Time
+		count   int
+		want    string
+	}{
+		{"fresh", now.Add(-time.Hour), now.Add(-time.Minute), 2, ""},
+		{"empty session never resets", now.Add(-100 * time.Hour), now.Add(-100 * time.Hour), 0, ""},
+		{"idle", now.Add(-7 * time.Hour), now.Add(-6 * time.Hour), 2, ResetReasonIdle},
+		{"before daily boundary", now.Add(-6 * time.Hour), time.Date(2026, 3, 10, 3, 59, 0, 0, loc), 2, ResetReasonDaily},
+		{"after daily boundary", now.Add(-5 * time.Hour), time.Date(2026, 3, 10, 4, 1, 0, 0, loc), 2, ""},
+		{"max age", now.Add(-49 * time.Hour), now.Add(-time.Minute), 2, ResetReasonMaxAge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := SessionInfo{Key: "k", MessageCount: tt.count, Created: tt.created, Updated: tt.updated}
+			if got := policy.ResetReason(info, now); got != tt.want {
+				t.Errorf("ResetReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResetPolicy_DailyBoundaryBeforeResetTime(t *testing.T) {
+	policy, err := NewResetPolicy(0, "04:00", 0)
+	if err != nil {
+		t.Fatalf("NewResetPolicy failed: %v", err)
+	}
+
+	// At 02:00 the most recent boundary is yesterday 04:00.
+	now := time.Date(2026, 3, 10, 2, 0, 0, 0, time.UTC)
+	info := SessionInfo{MessageCount: 1, Updated: time.Date(2026, 3, 9, 23, 0, 0, 0, time.UTC)}
+	if got := policy.ResetReason(info, now); got != "" {
+		t.Errorf("expected no reset before today's boundary, got %q", got)
+	}
+	info.Updated = time.Date(2026, 3, 9, 3, 0, 0, 0, time.UTC)
+	if got := policy.ResetReason(info, now); got != ResetReasonDaily {
+		t.Errorf("expected daily reset, got %q", got)
+	}
+}
+
+func TestNewResetPolicy_InvalidDailyTime(t *testing.T) {
+	if _, err := NewResetPolicy(0, "25:99", 0); err == nil {
+		t.Error("expected error for invalid time of day")
+	}
+	policy, err := NewResetPolicy(0, "", 0)
+	if err != nil {
+		t.Fatalf("NewResetPolicy failed: %v", err)
+	}
+	if policy.Enabled() {
+		t.Error("expected zero policy to be disabled")
+	}
+}
+
+func TestSessionManager_Reset(t *testing.T) {
+	tmpDir := t.TempDir()
+	sm := NewSessionManager(tmpDir)
+	sm.AddMessage("telegram:1", "user", "hello")
+	sm.SetSummary("telegram:1", "old summary")
+
+	sm.Reset("telegram:1")
+	if err := sm.Save("telegram:1"); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	sm2 := NewSessionManager(tmpDir)
+	if got := len(sm2.GetHistory("telegram:1")); got != 0 {
+		t.Errorf("expected empty history after reset, got %d messages", got)
+	}
+	if got := sm2.GetSummary("telegram:1"); got != "" {
+		t.Errorf("expected empty summary after reset, got %q", got)
+	}
+}
+
+func TestSessionManager_ApplyRetention(t *testing.T) {
+	tmpDir := t.TempDir()
+	archiveDir := filepath.Join(tmpDir, "archive")
+	sm := NewSessionManager(tmpDir)
+
+	old := time.Now().Add(-40 * 24 * time.Hour)
+	for _, key := range []string{"telegram:old", "telegram:new"} {
+		sm.AddMessage(key, "user", "hi")
+	}
+	sm.AddMessage("telegram:old", "assistant", "my locker code is 4711")
+	sm.TruncateHistory("telegram:old", 1)
+	sm.sessions["telegram:old"].Updated = old
+	for _, key := range []string{"telegram:old", "telegram:new"} {
+		if err := sm.Save(key); err != nil {
+			t.Fatalf("Save(%q) failed: %v", key, err)
+		}
+	}
+
+	removed, err := sm.ApplyRetention(RetentionPolicy{
+		MaxAge:     30 * 24 * time.Hour,
+		Action:     RetentionArchive,
+		ArchiveDir: archiveDir,
+	}, time.Now())
+	if err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+	if removed != 1 {
+		t.Fatalf("expected 1 session removed, got %d", removed)
+	}
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "telegram_old.json")); !os.IsNotExist(err) {
+		t.Error("expected expired session file to be removed")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "telegram_new.json")); err != nil {
+		t.Errorf("expected active session to be kept: %v", err)
+	}
+
+	archive, err := NewJSONStore(archiveDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	infos, err := archive.List()
+	if err != nil || len(infos) != 1 {
+		t.Fatalf("expected 1 archived session, got %+v (%v)", infos, err)
+	}
+	// Messages the session had already archived move along with it.
+	if msgs, _ := archive.Archived(infos[0].Key); len(msgs) != 1 || msgs[0].Content != "hi" {
+		t.Errorf("archived messages = %+v", msgs)
+	}
+	if results, _ := archive.Search("locker", 10, nil); len(results) != 1 {
+		t.Errorf("archived session search = %+v", results)
+	}
+
+	if got := len(sm.GetHistory("telegram:old")); got != 0 {
+		t.Errorf("expected expired session to be evicted from memory, got %d messages", got)
+	}
+}
+
+func TestSessionManager_ApplyRetentionPrunesArchive(t *testing.T) {
+	tmpDir := t.TempDir()
+	archiveDir := filepath.Join(tmpDir, "archive")
+	if err := os.MkdirAll(archiveDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	expired := filepath.Join(archiveDir, "telegram_1_20250101T000000.json")
+	recent := filepath.Join(archiveDir, "telegram_2_20250601T000000.json")
+	for _, path := range []string{expired, recent} {
+		if err := os.WriteFile(path, []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-40 * 24 * time.Hour)
+	if err := os.Chtimes(expired, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	sm := NewSessionManager(tmpDir)
+	policy := RetentionPolicy{MaxAge: 30 * 24 * time.Hour, Action: RetentionDelete, ArchiveDir: archiveDir}
+	if _, err := sm.ApplyRetention(policy, time.Now()); err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+	if _, err := os.Stat(expired); !os.IsNotExist(err) {
+		t.Error("expected the expired archive to be deleted")
+	}
+	if _, err := os.Stat(recent); err != nil {
+		t.Errorf("expected the recent archive to be kept: %v", err)
+	}
+
+	policy.Action = "arhcive"
+	if _, err := sm.ApplyRetention(policy, time.Now()); err == nil {
+		t.Error("expected an unknown action to be rejected")
+	}
+}
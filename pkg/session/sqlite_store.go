@@ -0,0 +1,364 @@
+package session
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite" // pure-Go SQLite driver
+
+	"github.com/Agentx-network/agentx/pkg/providers"
+)
+
+// sqliteSchema creates the session tables. Messages are stored one row each
+// so a turn only appends its new rows, and user/assistant content plus
+// summaries are mirrored into FTS5 indexes by triggers. Messages dropped
+// from a history are kept in archived_messages, indexed the same way.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS sessions (
+	key     TEXT PRIMARY KEY,
+	summary TEXT NOT NULL DEFAULT '',
+	created INTEGER NOT NULL,
+	updated INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS messages (
+	session_key TEXT NOT NULL,
+	seq         INTEGER NOT NULL,
+	role        TEXT NOT NULL,
+	content     TEXT NOT NULL,
+	data        TEXT NOT NULL,
+	created     INTEGER NOT NULL,
+	PRIMARY KEY (session_key, seq)
+);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(
+	content, content='messages', content_rowid='rowid'
+);
+
+CREATE TRIGGER IF NOT EXISTS messages_fts_insert AFTER INSERT ON messages
+WHEN new.role IN ('user', 'assistant') BEGIN
+	INSERT INTO messages_fts(rowid, content) VALUES (new.rowid, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS messages_fts_delete AFTER DELETE ON messages
+WHEN old.role IN ('user', 'assistant') BEGIN
+	INSERT INTO messages_fts(messages_fts, rowid, content) VALUES ('delete', old.rowid, old.content);
+END;
+
+CREATE TABLE IF NOT EXISTS archived_messages (
+	session_key TEXT NOT NULL,
+	role        TEXT NOT NULL,
+	content     TEXT NOT NULL,
+	created     INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS archived_messages_session ON archived_messages (session_key);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS archived_fts USING fts5(
+	content, content='archived_messages', content_rowid='rowid'
+);
+
+CREATE TRIGGER IF NOT EXISTS archived_fts_insert AFTER INSERT ON archived_messages BEGIN
+	INSERT INTO archived_fts(rowid, content) VALUES (new.rowid, new.content);
+END;
+
+CREATE TRIGGER IF NOT EXISTS archived_fts_delete AFTER DELETE ON archived_messages BEGIN
+	INSERT INTO archived_fts(archived_fts, rowid, content) VALUES ('delete', old.rowid, old.content);
+END;
+
+CREATE VIRTUAL TABLE IF NOT EXISTS summaries_fts USING fts5(
+	summary, content='sessions', content_rowid='rowid'
+);
+
+CREATE TRIGGER IF NOT EXISTS summaries_fts_insert AFTER INSERT ON sessions BEGIN
+	INSERT INTO summaries_fts(rowid, summary) VALUES (new.rowid, new.summary);
+END;
+
+CREATE TRIGGER IF NOT EXISTS summaries_fts_delete AFTER DELETE ON sessions BEGIN
+	INSERT INTO summaries_fts(summaries_fts, rowid, summary) VALUES ('delete', old.rowid, old.summary);
+END;
+
+CREATE TRIGGER IF NOT EXISTS summaries_fts_update AFTER UPDATE OF summary ON sessions BEGIN
+	INSERT INTO summaries_fts(summaries_fts, rowid, summary) VALUES ('delete', old.rowid, old.summary);
+	INSERT INTO summaries_fts(rowid, summary) VALUES (new.rowid, new.summary);
+END;
+`
+
+// SQLiteStore keeps all sessions of a workspace in a single embedded SQLite
+// database. Saves append only the messages added since the last save, and
+// sessions are read from disk the first time they are used.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// OpenSQLiteStore opens (or creates) the session database at path.
+func OpenSQLiteStore(path string) (*SQLiteStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	// WAL keeps appends cheap on flash storage; busy_timeout lets several
+	// agents sharing a workspace wait for each other instead of failing.
+	dsn := "file:" + path +
+		"?_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_pragma=busy_timeout(5000)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initializing session database: %w", err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Load(key string) (*Session, error) {
+	sess := &Session{Key: key}
+	var created, updated int64
+	err := s.db.QueryRow(
+		`SELECT summary, created, updated FROM sessions WHERE key = ?`, key,
+	).Scan(&sess.Summary, &created, &updated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sess.Created = time.UnixMilli(created)
+	sess.Updated = time.UnixMilli(updated)
+
+	rows, err := s.db.Query(`SELECT data FROM messages WHERE session_key = ? ORDER BY seq`, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sess.Messages = []providers.Message{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var msg providers.Message
+		if err := json.Unmarshal([]byte(data), &msg); err != nil {
+			return nil, fmt.Errorf("decoding message of session %q: %w", key, err)
+		}
+		sess.Messages = append(sess.Messages, msg)
+	}
+	return sess, rows.Err()
+}
+
+func (s *SQLiteStore) Save(sess *Session, persisted int) error {
+	if persisted < 0 || persisted > len(sess.Messages) {
+		persisted = 0
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO sessions (key, summary, created, updated) VALUES (?, ?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET summary = excluded.summary, created = excluded.created, updated = excluded.updated`,
+		sess.Key, sess.Summary, sess.Created.UnixMilli(), sess.Updated.UnixMilli(),
+	)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(
+		`DELETE FROM messages WHERE session_key = ? AND seq >= ?`, sess.Key, persisted,
+	); err != nil {
+		return err
+	}
+
+	if persisted < len(sess.Messages) {
+		stmt, err := tx.Prepare(`
+			INSERT INTO messages (session_key, seq, role, content, data, created)
+			VALUES (?, ?, ?, ?, ?, ?)`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		now := time.Now().UnixMilli()
+		for i := persisted; i < len(sess.Messages); i++ {
+			msg := sess.Messages[i]
+			data, err := json.Marshal(msg)
+			if err != nil {
+				return err
+			}
+			if _, err := stmt.Exec(sess.Key, i, msg.Role, msg.Content, string(data), now); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) Archive(key string, msgs []ArchivedMessage) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO archived_messages (session_key, role, content, created) VALUES (?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, msg := range msgs {
+		if msg.Role != "user" && msg.Role != "assistant" {
+			continue
+		}
+		if _, err := stmt.Exec(key, msg.Role, msg.Content, msg.Time.UnixMilli()); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) Archived(key string) ([]ArchivedMessage, error) {
+	rows, err := s.db.Query(`
+		SELECT role, content, created FROM archived_messages WHERE session_key = ? ORDER BY rowid`, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var msgs []ArchivedMessage
+	for rows.Next() {
+		var msg ArchivedMessage
+		var created int64
+		if err := rows.Scan(&msg.Role, &msg.Content, &created); err != nil {
+			return nil, err
+		}
+		msg.Time = time.UnixMilli(created)
+		msgs = append(msgs, msg)
+	}
+	return msgs, rows.Err()
+}
+
+func (s *SQLiteStore) Delete(key string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM messages WHERE session_key = ?`, key); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM archived_messages WHERE session_key = ?`, key); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM sessions WHERE key = ?`, key); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) List() ([]SessionInfo, error) {
+	rows, err := s.db.Query(`
+		SELECT s.key, s.created, s.updated,
+			(SELECT COUNT(*) FROM messages m WHERE m.session_key = s.key)
+		FROM sessions s ORDER BY s.updated DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var infos []SessionInfo
+	for rows.Next() {
+		var info SessionInfo
+		var created, updated int64
+		if err := rows.Scan(&info.Key, &created, &updated, &info.MessageCount); err != nil {
+			return nil, err
+		}
+		info.Created = time.UnixMilli(created)
+		info.Updated = time.UnixMilli(updated)
+		infos = append(infos, info)
+	}
+	return infos, rows.Err()
+}
+
+func (s *SQLiteStore) Search(query string, limit int, allow func(key string) bool) ([]SearchResult, error) {
+	match := ftsQuery(query)
+	if match == "" {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+
+	rows, err := s.db.Query(`
+		SELECT session_key, role, snippet, created FROM (
+			SELECT m.session_key AS session_key, m.role AS role,
+				snippet(messages_fts, 0, '', '', '…', 24) AS snippet,
+				m.created AS created, bm25(messages_fts) AS rank
+			FROM messages_fts JOIN messages m ON m.rowid = messages_fts.rowid
+			WHERE messages_fts MATCH ?
+			UNION ALL
+			SELECT a.session_key, a.role,
+				snippet(archived_fts, 0, '', '', '…', 24),
+				a.created, bm25(archived_fts)
+			FROM archived_fts JOIN archived_messages a ON a.rowid = archived_fts.rowid
+			WHERE archived_fts MATCH ?
+			UNION ALL
+			SELECT s.key, 'summary',
+				snippet(summaries_fts, 0, '', '', '…', 24),
+				s.updated, bm25(summaries_fts)
+			FROM summaries_fts JOIN sessions s ON s.rowid = summaries_fts.rowid
+			WHERE summaries_fts MATCH ?
+		) ORDER BY rank`, match, match, match)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Rows stream in rank order, so filtering here keeps the best visible hits
+	// without materializing the whole result set.
+	var results []SearchResult
+	for len(results) < limit && rows.Next() {
+		var r SearchResult
+		var created int64
+		if err := rows.Scan(&r.SessionKey, &r.Role, &r.Snippet, &created); err != nil {
+			return nil, err
+		}
+		if allow != nil && !allow(r.SessionKey) {
+			continue
+		}
+		r.Timestamp = time.UnixMilli(created)
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// ftsQuery turns free-form user input into an FTS5 query that matches rows
+// containing every term. Each term is quoted so punctuation and FTS5
+// operators in the input are searched literally.
+func ftsQuery(query string) string {
+	terms := searchTerms(query)
+	for i, term := range terms {
+		terms[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	return strings.Join(terms, " ")
+}
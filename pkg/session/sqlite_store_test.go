@@ -0,0 +1,244 @@
+package session
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Agentx-network/agentx/pkg/providers"
+)
+
+func newTestSQLiteManager(t *testing.T, dir string) *SessionManager {
+	t.Helper()
+	store, err := OpenSQLiteStore(filepath.Join(dir, "sessions.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLiteStore failed: %v", err)
+	}
+	sm := NewSessionManagerWithStore(store)
+	t.Cleanup(func() { sm.Close() })
+	return sm
+}
+
+func TestSQLiteStore_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	key := "agent:main:telegram:direct:42"
+
+	sm := newTestSQLiteManager(t, dir)
+	sm.AddMessage(key, "user", "hello")
+	sm.AddFullMessage(key, providers.Message{
+		Role: "assistant",
+		ToolCalls: []providers.ToolCall{{
+			ID:       "call_1",
+			Type:     "function",
+			Function: &providers.FunctionCall{Name: "exec", Arguments: `{"command":"ls"}`},
+		}},
+	})
+	sm.SetSummary(key, "greeting")
+	if err := sm.Save(key); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	sm.AddMessage(key, "tool", "file.txt")
+	if err := sm.Save(key); err != nil {
+		t.Fatalf("incremental Save failed: %v", err)
+	}
+	sm.Close()
+
+	sm2 := newTestSQLiteManager(t, dir)
+	history := sm2.GetHistory(key)
+	if len(history) != 3 {
+		t.Fatalf("expected 3 messages after reload, got %d", len(history))
+	}
+	if history[1].ToolCalls[0].Function.Arguments != `{"command":"ls"}` {
+		t.Errorf("tool call not preserved: %+v", history[1].ToolCalls)
+	}
+	if history[2].Content != "file.txt" {
+		t.Errorf("expected appended message, got %q", history[2].Content)
+	}
+	if got := sm2.GetSummary(key); got != "greeting" {
+		t.Errorf("expected summary %q, got %q", "greeting", got)
+	}
+}
+
+func TestSQLiteStore_TruncateRewritesHistory(t *testing.T) {
+	dir := t.TempDir()
+	key := "cli:direct"
+
+	sm := newTestSQLiteManager(t, dir)
+	for _, content := range []string{"one", "two", "three", "four"} {
+		sm.AddMessage(key, "user", content)
+	}
+	if err := sm.Save(key); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	sm.TruncateHistory(key, 2)
+	sm.AddMessage(key, "assistant", "five")
+	if err := sm.Save(key); err != nil {
+		t.Fatalf("Save after truncate failed: %v", err)
+	}
+	sm.Close()
+
+	sm2 := newTestSQLiteManager(t, dir)
+	history := sm2.GetHistory(key)
+	want := []string{"three", "four", "five"}
+	if len(history) != len(want) {
+		t.Fatalf("expected %d messages, got %d", len(want), len(history))
+	}
+	for i, content := range want {
+		if history[i].Content != content {
+			t.Errorf("message %d: expected %q, got %q", i, content, history[i].Content)
+		}
+	}
+}
+
+func TestSQLiteStore_Search(t *testing.T) {
+	sm := newTestSQLiteManager(t, t.TempDir())
+
+	sm.AddMessage("telegram:1", "user", "how do I list docker containers?")
+	sm.AddMessage("telegram:1", "assistant", "Run `docker ps -a` to list all containers.")
+	sm.AddMessage("telegram:1", "tool", "docker output that should not be indexed")
+	sm.AddMessage("discord:2", "user", "what's the weather like?")
+	sm.SetSummary("discord:2", "User asked about docker compose networking.")
+	for _, key := range []string{"telegram:1", "discord:2"} {
+		if err := sm.Save(key); err != nil {
+			t.Fatalf("Save(%q) failed: %v", key, err)
+		}
+	}
+
+	results, err := sm.Store().Search("docker", 10, nil)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 hits (2 messages + 1 summary), got %d: %+v", len(results), results)
+	}
+	for _, r := range results {
+		if r.Role == "tool" {
+			t.Errorf("tool output should not be indexed: %+v", r)
+		}
+		if r.Timestamp.IsZero() {
+			t.Errorf("expected timestamp on hit %+v", r)
+		}
+	}
+
+	// Operators and punctuation are searched literally.
+	if _, err := sm.Store().Search(`docker" OR (ps`, 10, nil); err != nil {
+		t.Errorf("Search with FTS syntax in query failed: %v", err)
+	}
+
+	if err := sm.Store().Delete("telegram:1"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	results, err = sm.Store().Search("containers", 10, nil)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("expected no hits after delete, got %+v", results)
+	}
+}
+
+func TestMigrate_JSONToSQLite(t *testing.T) {
+	dir := t.TempDir()
+
+	jsonManager := NewSessionManager(dir)
+	jsonManager.AddMessage("telegram:1", "user", "my locker code is 4711")
+	jsonManager.AddMessage("telegram:1", "assistant", "Noted.")
+	jsonManager.TruncateHistory("telegram:1", 0) // archives both messages
+	jsonManager.AddMessage("telegram:1", "user", "hi")
+	jsonManager.AddMessage("telegram:1", "assistant", "hello")
+	jsonManager.AddMessage("slack:C1", "user", "ping")
+	for _, key := range []string{"telegram:1", "slack:C1"} {
+		if err := jsonManager.Save(key); err != nil {
+			t.Fatalf("Save(%q) failed: %v", key, err)
+		}
+	}
+	archivedAt, _ := jsonManager.Store().Archived("telegram:1")
+
+	dst, err := OpenSQLiteStore(filepath.Join(dir, "sessions.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLiteStore failed: %v", err)
+	}
+	defer dst.Close()
+
+	n, err := Migrate(jsonManager.Store(), dst)
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 sessions migrated, got %d", n)
+	}
+
+	infos, err := dst.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	counts := map[string]int{}
+	for _, info := range infos {
+		counts[info.Key] = info.MessageCount
+	}
+	if counts["telegram:1"] != 2 || counts["slack:C1"] != 1 {
+		t.Errorf("unexpected migrated message counts: %v", counts)
+	}
+
+	// Archived messages move along and stay searchable, with their times.
+	results, err := dst.Search("locker code", 10, nil)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].SessionKey != "telegram:1" || results[0].Role != "user" {
+		t.Fatalf("archived message not found after migration: %+v", results)
+	}
+	if len(archivedAt) != 2 || !results[0].Timestamp.Equal(archivedAt[0].Time.Truncate(time.Millisecond)) {
+		t.Errorf("archive time = %v, want %v", results[0].Timestamp, archivedAt)
+	}
+
+	// Migrating again replaces the sessions rather than doubling archives.
+	if _, err := Migrate(jsonManager.Store(), dst); err != nil {
+		t.Fatalf("second Migrate failed: %v", err)
+	}
+	if archived, _ := dst.Archived("telegram:1"); len(archived) != 2 {
+		t.Errorf("expected 2 archived messages after migrating twice, got %+v", archived)
+	}
+}
+
+func TestSQLiteStore_ResetPersistsCreated(t *testing.T) {
+	dir := t.TempDir()
+	key := "agent:main:telegram:direct:42"
+
+	sm := newTestSQLiteManager(t, dir)
+	sm.GetOrCreate(key).Created = time.Now().Add(-72 * time.Hour)
+	sm.AddMessage(key, "user", "hello")
+	if err := sm.Save(key); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	policy, err := NewResetPolicy(0, "", 48)
+	if err != nil {
+		t.Fatalf("NewResetPolicy failed: %v", err)
+	}
+	info, _ := sm.Info(key)
+	if got := policy.ResetReason(info, time.Now()); got != ResetReasonMaxAge {
+		t.Fatalf("expected max age reset before reset, got %q", got)
+	}
+
+	sm.Reset(key)
+	sm.AddMessage(key, "user", "fresh start")
+	if err := sm.Save(key); err != nil {
+		t.Fatalf("Save after reset failed: %v", err)
+	}
+	sm.Close()
+
+	sm2 := newTestSQLiteManager(t, dir)
+	info, ok := sm2.Info(key)
+	if !ok {
+		t.Fatal("expected session after reload")
+	}
+	if time.Since(info.Created) > time.Hour {
+		t.Errorf("expected reset creation time after reload, got %v", info.Created)
+	}
+	if got := policy.ResetReason(info, time.Now()); got != "" {
+		t.Errorf("expected no reset after reload, got %q", got)
+	}
+}
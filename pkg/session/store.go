@@ -0,0 +1,193 @@
+package session
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Supported values for config.SessionConfig.Store.
+const (
+	StoreJSON   = "json"
+	StoreSQLite = "sqlite"
+)
+
+// sqliteFilename is the database file created inside the sessions directory.
+const sqliteFilename = "sessions.db"
+
+// SessionStore persists sessions on behalf of a SessionManager.
+// Implementations must be safe for concurrent use.
+type SessionStore interface {
+	// Load returns the stored session for key, or nil if it does not exist.
+	Load(key string) (*Session, error)
+
+	// Save persists s. The first persisted messages of s.Messages are already
+	// stored unchanged; any other stored messages must be replaced with
+	// s.Messages[persisted:]. Stores that cannot append may rewrite the
+	// whole session.
+	Save(s *Session, persisted int) error
+
+	// Archive keeps user/assistant messages dropped from the history of the
+	// session key (by summarization or a reset) searchable. Delete removes
+	// them with the session.
+	Archive(key string, msgs []ArchivedMessage) error
+
+	// Archived returns the archived messages of the session key, oldest
+	// first.
+	Archived(key string) ([]ArchivedMessage, error)
+
+	// Delete removes the session for key. Deleting a missing session is not an error.
+	Delete(key string) error
+
+	// List returns metadata for every stored session.
+	List() ([]SessionInfo, error)
+
+	// Search runs a full-text query over stored user/assistant messages,
+	// archived ones included, and summaries, returning at most limit hits ordered by relevance. Hits from
+	// sessions rejected by allow are skipped; a nil allow accepts all.
+	Search(query string, limit int, allow func(key string) bool) ([]SearchResult, error)
+
+	Close() error
+}
+
+// SessionInfo describes a stored session without its history.
+type SessionInfo struct {
+	Key          string
+	MessageCount int
+	Created      time.Time
+	Updated      time.Time
+}
+
+// ArchivedMessage is a message dropped from a session's history and kept
+// in its archive.
+type ArchivedMessage struct {
+	Role    string    `json:"role"`
+	Content string    `json:"content"`
+	Time    time.Time `json:"time"` // when it was archived
+}
+
+// SearchResult is a single full-text search hit.
+type SearchResult struct {
+	SessionKey string    `json:"session_key"`
+	Role       string    `json:"role"` // "user", "assistant" or "summary"
+	Snippet    string    `json:"snippet"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// SearchScope restricts search results to the sessions a caller may see.
+// A chat caller sees the sessions of its own peer: the current session and
+// those of the same chat with other agents, whose keys differ only in the
+// agent ID. Peer IDs are only unique within a channel, so other chats stay
+// hidden; identity links already merge a user's DMs into one session.
+type SearchScope struct {
+	// All grants access to every session, for trusted local callers such as the CLI.
+	All bool
+	// SessionKey is the caller's current session.
+	SessionKey string
+}
+
+// Allows reports whether the session with key is visible in this scope.
+func (s SearchScope) Allows(key string) bool {
+	if s.All {
+		return true
+	}
+	if s.SessionKey == "" {
+		return false
+	}
+	if key == s.SessionKey {
+		return true
+	}
+	peer := sessionPeer(s.SessionKey)
+	return peer != "" && sessionPeer(key) == peer
+}
+
+// sessionPeer returns the part of an agent-scoped session key
+// ("agent:<id>:<peer>") after the agent ID, or "" for other keys.
+func sessionPeer(key string) string {
+	parts := strings.SplitN(key, ":", 3)
+	if len(parts) != 3 || parts[0] != "agent" {
+		return ""
+	}
+	return parts[2]
+}
+
+// FormatSearchResults renders search hits as a compact list for the agent
+// or a chat user.
+func FormatSearchResults(query string, results []SearchResult) string {
+	if len(results) == 0 {
+		return fmt.Sprintf("No past conversations match %q.", query)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Found %d result(s) for %q:\n", len(results), query)
+	for i, r := range results {
+		fmt.Fprintf(&sb, "\n%d. [%s] %s (%s)\n   %s\n",
+			i+1, r.Timestamp.Format("2006-01-02 15:04"), r.SessionKey, r.Role, r.Snippet)
+	}
+	return sb.String()
+}
+
+// NewStore creates the SessionStore selected by kind inside the sessions
+// directory dir. An empty kind selects the JSON file store.
+func NewStore(kind, dir string) (SessionStore, error) {
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case "", StoreJSON:
+		return NewJSONStore(dir)
+	case StoreSQLite:
+		return OpenSQLiteStore(filepath.Join(dir, sqliteFilename))
+	default:
+		return nil, fmt.Errorf("unknown session store %q (expected %q or %q)", kind, StoreJSON, StoreSQLite)
+	}
+}
+
+// Migrate copies every session, with its archived messages, from src into
+// dst and returns the number of sessions copied. Existing sessions in dst
+// with the same key are replaced.
+func Migrate(src, dst SessionStore) (int, error) {
+	infos, err := src.List()
+	if err != nil {
+		return 0, fmt.Errorf("listing source sessions: %w", err)
+	}
+
+	copied := 0
+	for _, info := range infos {
+		s, err := src.Load(info.Key)
+		if err != nil {
+			return copied, fmt.Errorf("loading session %q: %w", info.Key, err)
+		}
+		if s == nil {
+			continue
+		}
+		archived, err := src.Archived(info.Key)
+		if err != nil {
+			return copied, fmt.Errorf("loading archive of session %q: %w", info.Key, err)
+		}
+		if err := dst.Delete(info.Key); err != nil {
+			return copied, fmt.Errorf("replacing session %q: %w", info.Key, err)
+		}
+		if len(archived) > 0 {
+			if err := dst.Archive(info.Key, archived); err != nil {
+				return copied, fmt.Errorf("saving archive of session %q: %w", info.Key, err)
+			}
+		}
+		if err := dst.Save(s, 0); err != nil {
+			return copied, fmt.Errorf("saving session %q: %w", info.Key, err)
+		}
+		copied++
+	}
+	return copied, nil
+}
+
+// searchTerms splits a free-form query into lower-cased terms.
+func searchTerms(query string) []string {
+	fields := strings.Fields(strings.ToLower(query))
+	terms := fields[:0]
+	for _, f := range fields {
+		f = strings.Trim(f, `"'`)
+		if f != "" {
+			terms = append(terms, f)
+		}
+	}
+	return terms
+}
@@ -77,11 +77,16 @@ type ClawHubConfig struct {
 	MaxResponseSize int    // bytes, 0 = default (2MB)
 }
 
+// RankFunc scores documents by relevance to a query, returning one score
+// per document in order.
+type RankFunc func(ctx context.Context, query string, documents []string) ([]float64, error)
+
 // RegistryManager coordinates multiple skill registries.
 // It fans out search requests and routes installs to the correct registry.
 type RegistryManager struct {
 	registries    []SkillRegistry
 	maxConcurrent int
+	rank          RankFunc
 	mu            sync.RWMutex
 }
 
@@ -113,6 +118,14 @@ func (rm *RegistryManager) AddRegistry(r SkillRegistry) {
 	rm.registries = append(rm.registries, r)
 }
 
+// SetRanker makes SearchAll order results by rank instead of by the scores
+// the registries report, which are not comparable across registries.
+func (rm *RegistryManager) SetRanker(rank RankFunc) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.rank = rank
+}
+
 // GetRegistry returns a registry by name, or nil if not found.
 func (rm *RegistryManager) GetRegistry(name string) SkillRegistry {
 	rm.mu.RLock()
@@ -131,6 +144,7 @@ func (rm *RegistryManager) SearchAll(ctx context.Context, query string, limit in
 	rm.mu.RLock()
 	regs := make([]SkillRegistry, len(rm.registries))
 	copy(regs, rm.registries)
+	rank := rm.rank
 	rm.mu.RUnlock()
 
 	if len(regs) == 0 {
@@ -198,6 +212,10 @@ func (rm *RegistryManager) SearchAll(ctx context.Context, query string, limit in
 		return nil, fmt.Errorf("all registries failed: %w", lastErr)
 	}
 
+	if rank != nil && len(merged) > 1 {
+		rescore(ctx, rank, query, merged)
+	}
+
 	// Sort by score descending.
 	sortByScoreDesc(merged)
 
@@ -209,6 +227,27 @@ func (rm *RegistryManager) SearchAll(ctx context.Context, query string, limit in
 	return merged, nil
 }
 
+// rescore replaces the registry scores with rank's scores for each skill's
+// name and summary. The registry scores are kept if ranking fails.
+func rescore(ctx context.Context, rank RankFunc, query string, results []SearchResult) {
+	docs := make([]string, len(results))
+	for i, r := range results {
+		name := r.DisplayName
+		if name == "" {
+			name = r.Slug
+		}
+		docs[i] = name + ": " + r.Summary
+	}
+	scores, err := rank(ctx, query, docs)
+	if err != nil || len(scores) != len(results) {
+		slog.Warn("skill search ranking failed, using registry scores", "error", err)
+		return
+	}
+	for i := range results {
+		results[i].Score = scores[i]
+	}
+}
+
 // sortByScoreDesc sorts SearchResults by Score in descending order (insertion sort — small slices).
 func sortByScoreDesc(results []SearchResult) {
 	for i := 1; i < len(results); i++ {
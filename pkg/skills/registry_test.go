@@ -52,6 +52,36 @@ func TestRegistryManagerSearchAllSingle(t *testing.T) {
 	assert.Equal(t, "skill-a", results[0].Slug)
 }
 
+func TestRegistryManagerSearchAllRanked(t *testing.T) {
+	mgr := NewRegistryManager()
+	mgr.AddRegistry(&mockRegistry{
+		name: "test",
+		searchResults: []SearchResult{
+			{Slug: "weather-cli", DisplayName: "Weather CLI", Summary: "Forecasts", Score: 0.9},
+			{Slug: "backup", Summary: "Back up files to S3", Score: 0.2},
+		},
+	})
+	var docs []string
+	mgr.SetRanker(func(ctx context.Context, query string, documents []string) ([]float64, error) {
+		docs = documents
+		return []float64{0.1, 0.8}, nil
+	})
+
+	results, err := mgr.SearchAll(context.Background(), "save my files", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Weather CLI: Forecasts", "backup: Back up files to S3"}, docs)
+	assert.Equal(t, "backup", results[0].Slug)
+	assert.Equal(t, 0.8, results[0].Score)
+
+	// Ranking errors keep the registry scores.
+	mgr.SetRanker(func(ctx context.Context, query string, documents []string) ([]float64, error) {
+		return nil, fmt.Errorf("embedding server down")
+	})
+	results, err = mgr.SearchAll(context.Background(), "save my files", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, "weather-cli", results[0].Slug)
+}
+
 func TestRegistryManagerSearchAllMultiple(t *testing.T) {
 	mgr := NewRegistryManager()
 	mgr.AddRegistry(&mockRegistry{
@@ -0,0 +1,207 @@
+// Package structured asks an agent for a result that matches a JSON schema,
+// validates what comes back and asks again when it does not match.
+package structured
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kaptinlin/jsonschema"
+
+	"github.com/Agentx-network/agentx/pkg/utils"
+)
+
+// DefaultMaxRetries is how many times an invalid result is sent back for
+// correction when the caller does not say.
+const DefaultMaxRetries = 2
+
+// maxEchoedChars bounds the previous answer quoted in a correction prompt.
+const maxEchoedChars = 2000
+
+// Request describes a structured-output run.
+type Request struct {
+	Task       string         // What the agent should do
+	Schema     map[string]any // JSON schema the result must match
+	MaxRetries int            // Corrections after the first attempt; 0 uses DefaultMaxRetries, <0 disables retries
+
+	// Stateless means ask does not remember earlier attempts, so every
+	// correction prompt repeats the task.
+	Stateless bool
+}
+
+// Result is a validated structured result.
+type Result struct {
+	Object   any    // The parsed JSON value
+	Raw      string // The JSON text the object was parsed from
+	Attempts int    // Number of model runs it took
+}
+
+// AskFunc runs the agent on prompt and returns its final answer.
+type AskFunc func(ctx context.Context, prompt string) (string, error)
+
+// ValidationError is returned when no attempt produced a valid result.
+type ValidationError struct {
+	Raw      string // The last answer
+	Err      error  // Why it was rejected
+	Attempts int
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("structured output invalid after %d attempt(s): %v", e.Attempts, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Run asks for a result matching req.Schema, retrying with the validation
+// error until it is valid or retries run out.
+func Run(ctx context.Context, req Request, ask AskFunc) (*Result, error) {
+	validator, err := compile(req.Schema)
+	if err != nil {
+		return nil, err
+	}
+
+	retries := req.MaxRetries
+	if retries == 0 {
+		retries = DefaultMaxRetries
+	} else if retries < 0 {
+		retries = 0
+	}
+
+	initial := req.Task + Instructions(req.Schema)
+	prompt := initial
+	var last *ValidationError
+	for attempt := 1; attempt <= retries+1; attempt++ {
+		answer, err := ask(ctx, prompt)
+		if err != nil {
+			return nil, err
+		}
+
+		raw, obj, err := parse(answer, validator)
+		if err == nil {
+			return &Result{Object: obj, Raw: raw, Attempts: attempt}, nil
+		}
+
+		last = &ValidationError{Raw: answer, Err: err, Attempts: attempt}
+		prompt = correctionPrompt(answer, err)
+		if req.Stateless {
+			prompt = initial + "\n\n" + prompt
+		}
+	}
+	return nil, last
+}
+
+// Instructions returns the text appended to a task so the agent ends with
+// a JSON answer.
+func Instructions(schema map[string]any) string {
+	data, _ := json.MarshalIndent(schema, "", "  ")
+	return "\n\n## Output Format\n" +
+		"Use tools as needed. When you are done, reply with only a JSON value that matches this JSON schema, " +
+		"with no explanation and no code fences:\n" + string(data)
+}
+
+func correctionPrompt(previous string, err error) string {
+	return fmt.Sprintf("Your previous answer was not valid: %v\n\nPrevious answer:\n%s\n\n"+
+		"Reply again with only the corrected JSON value that matches the schema.",
+		err, utils.Truncate(previous, maxEchoedChars))
+}
+
+// Validate parses text as JSON (tolerating code fences and surrounding
+// prose) and checks it against schema. It returns the JSON text and the
+// parsed value.
+func Validate(text string, schema map[string]any) (string, any, error) {
+	validator, err := compile(schema)
+	if err != nil {
+		return "", nil, err
+	}
+	return parse(text, validator)
+}
+
+// CheckSchema reports whether schema is a usable JSON schema.
+func CheckSchema(schema map[string]any) error {
+	_, err := compile(schema)
+	return err
+}
+
+func compile(schema map[string]any) (*jsonschema.Schema, error) {
+	if len(schema) == 0 {
+		return nil, errors.New("schema is required")
+	}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	validator, err := jsonschema.NewCompiler().Compile(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return validator, nil
+}
+
+func parse(text string, validator *jsonschema.Schema) (string, any, error) {
+	raw := extractJSON(text)
+	if raw == "" {
+		return "", nil, errors.New("no JSON value found in the answer")
+	}
+	var obj any
+	if err := json.Unmarshal([]byte(raw), &obj); err != nil {
+		return "", nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	result := validator.Validate(obj)
+	if result.IsValid() {
+		return raw, obj, nil
+	}
+	details := result.DetailedErrors()
+	msgs := make([]string, 0, len(details))
+	for path, msg := range details {
+		if path == "" {
+			msgs = append(msgs, msg)
+		} else {
+			msgs = append(msgs, path+": "+msg)
+		}
+	}
+	sort.Strings(msgs)
+	return "", nil, fmt.Errorf("does not match the schema: %s", strings.Join(msgs, "; "))
+}
+
+// extractJSON returns the JSON value in text: the whole text, the body of
+// a fenced code block, or the span from the first '{' or '[' to the last
+// matching bracket.
+func extractJSON(text string) string {
+	text = strings.TrimSpace(text)
+	if json.Valid([]byte(text)) {
+		return text
+	}
+
+	if start := strings.Index(text, "```"); start >= 0 {
+		body := text[start+3:]
+		if nl := strings.IndexByte(body, '\n'); nl >= 0 {
+			body = body[nl+1:]
+		}
+		if end := strings.Index(body, "```"); end >= 0 {
+			if candidate := strings.TrimSpace(body[:end]); json.Valid([]byte(candidate)) {
+				return candidate
+			}
+		}
+	}
+
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return ""
+	}
+	closer := byte('}')
+	if text[start] == '[' {
+		closer = ']'
+	}
+	end := strings.LastIndexByte(text, closer)
+	if end <= start {
+		return ""
+	}
+	return text[start : end+1]
+}
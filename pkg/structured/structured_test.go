@@ -0,0 +1,106 @@
+package structured
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var countSchema = map[string]any{
+	"type":       "object",
+	"properties": map[string]any{"count": map[string]any{"type": "integer"}},
+	"required":   []string{"count"},
+}
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		name, text, want string
+	}{
+		{"plain", ` {"a": 1} `, `{"a": 1}`},
+		{"fenced", "Here you go:\n```json\n{\"a\": 1}\n```\nDone.", `{"a": 1}`},
+		{"prose", `The result is {"a": {"b": 2}} as requested.`, `{"a": {"b": 2}}`},
+		{"array", `Items: [1, 2, 3].`, `[1, 2, 3]`},
+		{"none", "no json here", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractJSON(tt.text); got != tt.want {
+				t.Errorf("extractJSON() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	raw, obj, err := Validate("```json\n{\"count\": 2}\n```", countSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw != `{"count": 2}` || obj.(map[string]any)["count"] != float64(2) {
+		t.Errorf("got %q, %#v", raw, obj)
+	}
+
+	if _, _, err := Validate(`{"count": "two"}`, countSchema); err == nil {
+		t.Error("a string count should not match the schema")
+	}
+	if _, _, err := Validate(`{"count": 2}`, nil); err == nil {
+		t.Error("a missing schema should be an error")
+	}
+}
+
+func TestRun_RetriesUntilValid(t *testing.T) {
+	replies := []string{`{"count": "two"}`, `{"count": 2}`}
+	var prompts []string
+	ask := func(ctx context.Context, prompt string) (string, error) {
+		prompts = append(prompts, prompt)
+		reply := replies[0]
+		replies = replies[1:]
+		return reply, nil
+	}
+
+	res, err := Run(context.Background(), Request{Task: "Count", Schema: countSchema}, ask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Attempts != 2 || res.Raw != `{"count": 2}` {
+		t.Errorf("got %+v", res)
+	}
+	if !strings.Contains(prompts[0], "## Output Format") {
+		t.Error("the first prompt should include the schema instructions")
+	}
+	if !strings.Contains(prompts[1], `{"count": "two"}`) || strings.Contains(prompts[1], "Count") {
+		t.Errorf("the correction should quote the answer without repeating the task: %q", prompts[1])
+	}
+}
+
+func TestRun_Stateless(t *testing.T) {
+	var prompts []string
+	ask := func(ctx context.Context, prompt string) (string, error) {
+		prompts = append(prompts, prompt)
+		return "not json", nil
+	}
+
+	_, err := Run(context.Background(), Request{Task: "Count", Schema: countSchema, MaxRetries: 1, Stateless: true}, ask)
+	var verr *ValidationError
+	if !errors.As(err, &verr) || verr.Attempts != 2 {
+		t.Fatalf("expected a ValidationError after 2 attempts, got %v", err)
+	}
+	if !strings.HasPrefix(prompts[1], "Count") {
+		t.Errorf("stateless corrections should repeat the task: %q", prompts[1])
+	}
+}
+
+func TestRun_NoRetries(t *testing.T) {
+	calls := 0
+	ask := func(ctx context.Context, prompt string) (string, error) {
+		calls++
+		return "{}", nil
+	}
+	if _, err := Run(context.Background(), Request{Task: "Count", Schema: countSchema, MaxRetries: -1}, ask); err == nil {
+		t.Error("expected a validation error")
+	}
+	if calls != 1 {
+		t.Errorf("ask called %d times, want 1", calls)
+	}
+}
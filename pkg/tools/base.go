@@ -17,6 +17,13 @@ type ContextualTool interface {
 	SetContext(channel, chatID string)
 }
 
+// SessionScopedTool is an optional interface that tools can implement to
+// receive the channel and session key of the conversation they are used in.
+type SessionScopedTool interface {
+	Tool
+	SetSession(channel, sessionKey string)
+}
+
 // AsyncCallback is a function type that async tools use to notify completion.
 // When an async tool finishes its work, it calls this callback with the result.
 //
@@ -9,6 +9,7 @@ import (
 	"github.com/Agentx-network/agentx/pkg/bus"
 	"github.com/Agentx-network/agentx/pkg/config"
 	"github.com/Agentx-network/agentx/pkg/cron"
+	"github.com/Agentx-network/agentx/pkg/structured"
 	"github.com/Agentx-network/agentx/pkg/utils"
 )
 
@@ -17,6 +18,16 @@ type JobExecutor interface {
 	ProcessDirectWithChannel(ctx context.Context, content, sessionKey, channel, chatID string) (string, error)
 }
 
+// StructuredExecutor is implemented by executors that can run jobs whose
+// payload has a JSON schema.
+type StructuredExecutor interface {
+	ProcessDirectStructured(
+		ctx context.Context,
+		content, senderID, sessionKey, channel, chatID string,
+		schema map[string]any,
+	) (*structured.Result, error)
+}
+
 // CronTool provides scheduling capabilities for the agent
 type CronTool struct {
 	cronService *cron.CronService
@@ -92,6 +103,10 @@ func (t *CronTool) Parameters() map[string]any {
 				"type":        "boolean",
 				"description": "If true, send message directly to channel. If false, let agent process message (for complex tasks). Default: true",
 			},
+			"schema": map[string]any{
+				"type":        "object",
+				"description": "Optional JSON schema for agent-processed jobs; the result is then JSON matching it. Forces 'deliver' to false.",
+			},
 		},
 		"required": []string{"action"},
 	}
@@ -187,6 +202,14 @@ func (t *CronTool) addJob(args map[string]any) *ToolResult {
 		deliver = false
 	}
 
+	schema, _ := args["schema"].(map[string]any)
+	if schema != nil {
+		if err := structured.CheckSchema(schema); err != nil {
+			return ErrorResult(err.Error())
+		}
+		deliver = false
+	}
+
 	// Truncate message for job name (max 30 chars)
 	messagePreview := utils.Truncate(message, 30)
 
@@ -202,8 +225,9 @@ func (t *CronTool) addJob(args map[string]any) *ToolResult {
 		return ErrorResult(fmt.Sprintf("Error adding job: %v", err))
 	}
 
-	if command != "" {
+	if command != "" || schema != nil {
 		job.Payload.Command = command
+		job.Payload.Schema = schema
 		// Need to save the updated payload
 		t.cronService.UpdateJob(job)
 	}
@@ -266,8 +290,9 @@ func (t *CronTool) enableJob(args map[string]any, enable bool) *ToolResult {
 	return SilentResult(fmt.Sprintf("Cron job '%s' %s", job.Name, status))
 }
 
-// ExecuteJob executes a cron job through the agent
-func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
+// ExecuteJob executes a cron job through the agent. It returns an error
+// when the agent run fails, so the job's last status records the failure.
+func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) (string, error) {
 	// Get channel/chatID from job payload
 	channel := job.Payload.Channel
 	chatID := job.Payload.To
@@ -299,7 +324,7 @@ func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
 			ChatID:  chatID,
 			Content: output,
 		})
-		return "ok"
+		return "ok", nil
 	}
 
 	// If deliver=true, send message directly without agent processing
@@ -309,12 +334,16 @@ func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
 			ChatID:  chatID,
 			Content: job.Payload.Message,
 		})
-		return "ok"
+		return "ok", nil
 	}
 
 	// For deliver=false, process through agent (for complex tasks)
 	sessionKey := fmt.Sprintf("cron-%s", job.ID)
 
+	if job.Payload.Schema != nil {
+		return t.executeStructuredJob(ctx, job, sessionKey, channel, chatID)
+	}
+
 	// Call agent with job's message
 	response, err := t.executor.ProcessDirectWithChannel(
 		ctx,
@@ -324,10 +353,39 @@ func (t *CronTool) ExecuteJob(ctx context.Context, job *cron.CronJob) string {
 		chatID,
 	)
 	if err != nil {
-		return fmt.Sprintf("Error: %v", err)
+		return "", err
 	}
 
 	// Response is automatically sent via MessageBus by AgentLoop
 	_ = response // Will be sent by AgentLoop
-	return "ok"
+	return "ok", nil
+}
+
+// executeStructuredJob runs an agent job whose payload has a schema, sends
+// the JSON result to the channel and returns it.
+func (t *CronTool) executeStructuredJob(ctx context.Context, job *cron.CronJob, sessionKey, channel, chatID string) (string, error) {
+	executor, ok := t.executor.(StructuredExecutor)
+	if !ok {
+		return "", fmt.Errorf("executor does not support structured output")
+	}
+
+	result, err := executor.ProcessDirectStructured(
+		ctx,
+		job.Payload.Message,
+		"cron",
+		sessionKey,
+		channel,
+		chatID,
+		job.Payload.Schema,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	t.msgBus.PublishOutbound(bus.OutboundMessage{
+		Channel: channel,
+		ChatID:  chatID,
+		Content: result.Raw,
+	})
+	return result.Raw, nil
 }
@@ -3,15 +3,26 @@ package tools
 import (
 	"context"
 	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
 )
 
 type SendCallback func(channel, chatID, content string) error
 
+// OutboundCallback delivers a full outbound message, including attachments,
+// reply target and buttons.
+type OutboundCallback func(msg bus.OutboundMessage) error
+
 type MessageTool struct {
-	sendCallback   SendCallback
-	defaultChannel string
-	defaultChatID  string
-	sentInRound    bool // Tracks whether a message was sent in the current processing round
+	sendCallback     SendCallback
+	outboundCallback OutboundCallback
+	workspace        string
+	restrict         bool
+	defaultChannel   string
+	defaultChatID    string
+	sentInRound      bool // Tracks whether a message was sent in the current processing round
 }
 
 func NewMessageTool() *MessageTool {
@@ -23,7 +34,7 @@ func (t *MessageTool) Name() string {
 }
 
 func (t *MessageTool) Description() string {
-	return "Send a message to user on a chat channel. Use this when you want to communicate something."
+	return "Send a message to user on a chat channel. Use this when you want to communicate something. Can attach files (local paths or URLs), reply to a specific message and offer quick-reply buttons."
 }
 
 func (t *MessageTool) Parameters() map[string]any {
@@ -42,6 +53,36 @@ func (t *MessageTool) Parameters() map[string]any {
 				"type":        "string",
 				"description": "Optional: target chat/user ID",
 			},
+			"attachments": map[string]any{
+				"type":        "array",
+				"description": "Optional: files to send with the message. Images, audio and video are shown natively where the channel supports it.",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"path":      map[string]any{"type": "string", "description": "Local file path"},
+						"url":       map[string]any{"type": "string", "description": "Remote file URL (instead of path)"},
+						"mime_type": map[string]any{"type": "string", "description": "Optional: MIME type, guessed from the name if omitted"},
+						"filename":  map[string]any{"type": "string", "description": "Optional: file name shown to the user"},
+					},
+				},
+			},
+			"reply_to": map[string]any{
+				"type":        "string",
+				"description": "Optional: ID of the message to reply to",
+			},
+			"buttons": map[string]any{
+				"type":        "array",
+				"description": "Optional: quick-reply buttons. Pressing one sends its value back as a user message; buttons with a url open the link.",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"text":  map[string]any{"type": "string", "description": "Button label"},
+						"value": map[string]any{"type": "string", "description": "Optional: text sent back when pressed (defaults to the label)"},
+						"url":   map[string]any{"type": "string", "description": "Optional: link to open instead"},
+					},
+					"required": []string{"text"},
+				},
+			},
 		},
 		"required": []string{"content"},
 	}
@@ -62,6 +103,20 @@ func (t *MessageTool) SetSendCallback(callback SendCallback) {
 	t.sendCallback = callback
 }
 
+// SetOutboundCallback sets the callback used to deliver messages. It takes
+// precedence over the send callback and is required for attachments,
+// replies and buttons.
+func (t *MessageTool) SetOutboundCallback(callback OutboundCallback) {
+	t.outboundCallback = callback
+}
+
+// SetWorkspace sets the workspace local attachment paths are resolved
+// against; with restrict, attachments outside it are rejected.
+func (t *MessageTool) SetWorkspace(workspace string, restrict bool) {
+	t.workspace = workspace
+	t.restrict = restrict
+}
+
 func (t *MessageTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
 	content, ok := args["content"].(string)
 	if !ok {
@@ -82,11 +137,31 @@ func (t *MessageTool) Execute(ctx context.Context, args map[string]any) *ToolRes
 		return &ToolResult{ForLLM: "No target channel/chat specified", IsError: true}
 	}
 
-	if t.sendCallback == nil {
-		return &ToolResult{ForLLM: "Message sending not configured", IsError: true}
+	msg := bus.OutboundMessage{Channel: channel, ChatID: chatID, Content: content}
+	msg.ReplyTo, _ = args["reply_to"].(string)
+
+	var err error
+	if msg.Attachments, err = t.parseAttachments(args["attachments"]); err != nil {
+		return ErrorResult(err.Error())
+	}
+	if msg.Buttons, err = parseButtons(args["buttons"]); err != nil {
+		return ErrorResult(err.Error())
+	}
+	if strings.TrimSpace(content) == "" && len(msg.Attachments) == 0 {
+		return ErrorResult("content is required when there are no attachments")
 	}
 
-	if err := t.sendCallback(channel, chatID, content); err != nil {
+	switch {
+	case t.outboundCallback != nil:
+		err = t.outboundCallback(msg)
+	case t.sendCallback == nil:
+		return &ToolResult{ForLLM: "Message sending not configured", IsError: true}
+	case len(msg.Attachments) > 0 || len(msg.Buttons) > 0 || msg.ReplyTo != "":
+		return ErrorResult("attachments, replies and buttons are not supported here")
+	default:
+		err = t.sendCallback(channel, chatID, content)
+	}
+	if err != nil {
 		return &ToolResult{
 			ForLLM:  fmt.Sprintf("sending message: %v", err),
 			IsError: true,
@@ -101,3 +176,88 @@ func (t *MessageTool) Execute(ctx context.Context, args map[string]any) *ToolRes
 		Silent: true,
 	}
 }
+
+// parseAttachments reads the attachments argument. Each item is an object
+// or a bare path/URL string. Local paths are resolved against the workspace.
+func (t *MessageTool) parseAttachments(raw any) ([]bus.Attachment, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	items, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("attachments must be an array")
+	}
+
+	attachments := make([]bus.Attachment, 0, len(items))
+	for i, item := range items {
+		var a bus.Attachment
+		switch v := item.(type) {
+		case string:
+			if strings.Contains(v, "://") {
+				a.URL = v
+			} else {
+				a.Path = v
+			}
+		case map[string]any:
+			a.Path, _ = v["path"].(string)
+			a.URL, _ = v["url"].(string)
+			a.MIMEType, _ = v["mime_type"].(string)
+			a.Filename, _ = v["filename"].(string)
+		default:
+			return nil, fmt.Errorf("attachment %d must be an object or a string", i+1)
+		}
+
+		switch {
+		case a.URL != "":
+			if !strings.HasPrefix(a.URL, "http://") && !strings.HasPrefix(a.URL, "https://") {
+				return nil, fmt.Errorf("attachment %d: only http(s) URLs are supported", i+1)
+			}
+			a.Path = ""
+		case a.Path != "":
+			resolved, err := validatePath(a.Path, t.workspace, t.restrict)
+			if err != nil {
+				return nil, fmt.Errorf("attachment %d: %w", i+1, err)
+			}
+			info, err := os.Stat(resolved)
+			if err != nil {
+				return nil, fmt.Errorf("attachment %d: %w", i+1, err)
+			}
+			if info.IsDir() {
+				return nil, fmt.Errorf("attachment %d: %s is a directory", i+1, a.Path)
+			}
+			a.Path = resolved
+		default:
+			return nil, fmt.Errorf("attachment %d needs a path or url", i+1)
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, nil
+}
+
+func parseButtons(raw any) ([]bus.Button, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	items, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("buttons must be an array")
+	}
+
+	buttons := make([]bus.Button, 0, len(items))
+	for i, item := range items {
+		var b bus.Button
+		switch v := item.(type) {
+		case string:
+			b.Text = v
+		case map[string]any:
+			b.Text, _ = v["text"].(string)
+			b.Value, _ = v["value"].(string)
+			b.URL, _ = v["url"].(string)
+		}
+		if strings.TrimSpace(b.Text) == "" {
+			return nil, fmt.Errorf("button %d needs a text label", i+1)
+		}
+		buttons = append(buttons, b)
+	}
+	return buttons, nil
+}
@@ -3,7 +3,12 @@ package tools
 import (
 	"context"
 	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/Agentx-network/agentx/pkg/bus"
 )
 
 func TestMessageTool_Execute_Success(t *testing.T) {
@@ -257,3 +262,87 @@ func TestMessageTool_Parameters(t *testing.T) {
 		t.Error("Expected chat_id type to be 'string'")
 	}
 }
+
+func TestMessageTool_Execute_RichMessage(t *testing.T) {
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "chart.png"), []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tool := NewMessageTool()
+	tool.SetContext("telegram", "42")
+	tool.SetWorkspace(workspace, true)
+
+	var sent bus.OutboundMessage
+	tool.SetOutboundCallback(func(msg bus.OutboundMessage) error {
+		sent = msg
+		return nil
+	})
+
+	result := tool.Execute(context.Background(), map[string]any{
+		"content": "Here is the chart",
+		"attachments": []any{
+			map[string]any{"path": "chart.png"},
+			"https://example.com/report.pdf",
+		},
+		"reply_to": "1001",
+		"buttons": []any{
+			map[string]any{"text": "Yes", "value": "approve"},
+			map[string]any{"text": "Docs", "url": "https://example.com/docs"},
+		},
+	})
+	if result.IsError {
+		t.Fatalf("unexpected error: %s", result.ForLLM)
+	}
+
+	if sent.Channel != "telegram" || sent.ChatID != "42" || sent.ReplyTo != "1001" {
+		t.Errorf("unexpected target: %+v", sent)
+	}
+	if len(sent.Attachments) != 2 {
+		t.Fatalf("expected 2 attachments, got %+v", sent.Attachments)
+	}
+	if sent.Attachments[0].Path != filepath.Join(workspace, "chart.png") || sent.Attachments[0].Kind() != bus.AttachmentImage {
+		t.Errorf("unexpected local attachment: %+v", sent.Attachments[0])
+	}
+	if sent.Attachments[1].URL != "https://example.com/report.pdf" || sent.Attachments[1].Name() != "report.pdf" {
+		t.Errorf("unexpected remote attachment: %+v", sent.Attachments[1])
+	}
+	if len(sent.Buttons) != 2 || sent.Buttons[0].Reply() != "approve" || sent.Buttons[1].URL == "" {
+		t.Errorf("unexpected buttons: %+v", sent.Buttons)
+	}
+}
+
+func TestMessageTool_Execute_AttachmentOutsideWorkspace(t *testing.T) {
+	tool := NewMessageTool()
+	tool.SetContext("telegram", "42")
+	tool.SetWorkspace(t.TempDir(), true)
+	tool.SetOutboundCallback(func(msg bus.OutboundMessage) error {
+		t.Error("message should not be sent")
+		return nil
+	})
+
+	result := tool.Execute(context.Background(), map[string]any{
+		"content":     "secrets",
+		"attachments": []any{map[string]any{"path": "/etc/passwd"}},
+	})
+	if !result.IsError || !strings.Contains(result.ForLLM, "outside the workspace") {
+		t.Errorf("expected workspace error, got %+v", result)
+	}
+}
+
+func TestMessageTool_Execute_RichMessageNeedsOutboundCallback(t *testing.T) {
+	tool := NewMessageTool()
+	tool.SetContext("telegram", "42")
+	tool.SetSendCallback(func(channel, chatID, content string) error {
+		t.Error("plain send callback should not receive a rich message")
+		return nil
+	})
+
+	result := tool.Execute(context.Background(), map[string]any{
+		"content": "Pick one",
+		"buttons": []any{"A", "B"},
+	})
+	if !result.IsError {
+		t.Error("expected an error without an outbound callback")
+	}
+}
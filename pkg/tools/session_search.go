@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Agentx-network/agentx/pkg/constants"
+	"github.com/Agentx-network/agentx/pkg/logger"
+	"github.com/Agentx-network/agentx/pkg/session"
+	"github.com/Agentx-network/agentx/pkg/skills"
+)
+
+// SessionSearcher runs full-text queries over stored conversations.
+// *session.SessionManager implements it.
+type SessionSearcher interface {
+	Search(query string, limit int, scope session.SearchScope) ([]session.SearchResult, error)
+}
+
+// SessionSearchTool lets the agent recall past conversations, including
+// messages summarized away or reset. Chat callers only see the sessions of
+// their own chat (see session.SearchScope); internal channels (cli, system)
+// can search everything.
+type SessionSearchTool struct {
+	searcher   SessionSearcher
+	rank       skills.RankFunc
+	channel    string
+	sessionKey string
+}
+
+// NewSessionSearchTool creates a SessionSearchTool backed by searcher.
+func NewSessionSearchTool(searcher SessionSearcher) *SessionSearchTool {
+	return &SessionSearchTool{searcher: searcher}
+}
+
+// SetRanker orders hits by rank's relevance scores instead of the store's
+// order. More hits are fetched than requested so ranking can pick the best.
+func (t *SessionSearchTool) SetRanker(rank skills.RankFunc) {
+	t.rank = rank
+}
+
+func (t *SessionSearchTool) Name() string {
+	return "session_search"
+}
+
+func (t *SessionSearchTool) Description() string {
+	return "Search past conversations and their summaries for a keyword or phrase. Returns matching snippets with the session key and timestamp. Use this to recall what was discussed earlier, e.g. 'what did we decide about the backup script?'."
+}
+
+func (t *SessionSearchTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"query": map[string]any{
+				"type":        "string",
+				"description": "Words to search for; every word must appear in a hit",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": "Maximum number of results to return (1-50, default 10)",
+				"minimum":     1.0,
+				"maximum":     50.0,
+			},
+		},
+		"required": []string{"query"},
+	}
+}
+
+func (t *SessionSearchTool) SetSession(channel, sessionKey string) {
+	t.channel = channel
+	t.sessionKey = sessionKey
+}
+
+// Scope returns the search scope for the current conversation.
+func (t *SessionSearchTool) Scope() session.SearchScope {
+	if constants.IsInternalChannel(t.channel) {
+		return session.SearchScope{All: true}
+	}
+	return session.SearchScope{SessionKey: t.sessionKey}
+}
+
+func (t *SessionSearchTool) Execute(ctx context.Context, args map[string]any) *ToolResult {
+	query, ok := args["query"].(string)
+	query = strings.TrimSpace(query)
+	if !ok || query == "" {
+		return ErrorResult("query is required and must be a non-empty string")
+	}
+
+	limit := 10
+	if l, ok := args["limit"].(float64); ok {
+		li := int(l)
+		if li >= 1 && li <= 50 {
+			limit = li
+		}
+	}
+
+	fetch := limit
+	if t.rank != nil {
+		fetch = min(limit*rankedSearchFactor, 50)
+	}
+	results, err := t.searcher.Search(query, fetch, t.Scope())
+	if err != nil {
+		return ErrorResult(fmt.Sprintf("session search failed: %v", err)).WithError(err)
+	}
+	if t.rank != nil && len(results) > 1 {
+		results = t.rankResults(ctx, query, results)
+	}
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return SilentResult(session.FormatSearchResults(query, results))
+}
+
+// rankedSearchFactor is how many more hits are fetched for ranking.
+const rankedSearchFactor = 3
+
+// rankResults sorts results by relevance, keeping the store's order if
+// ranking fails.
+func (t *SessionSearchTool) rankResults(ctx context.Context, query string, results []session.SearchResult) []session.SearchResult {
+	docs := make([]string, len(results))
+	for i, r := range results {
+		docs[i] = r.Snippet
+	}
+	scores, err := t.rank(ctx, query, docs)
+	if err != nil || len(scores) != len(results) {
+		logger.WarnCF("tool", "Session search ranking failed", map[string]any{"error": fmt.Sprint(err)})
+		return results
+	}
+	order := make([]int, len(results))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+	ranked := make([]session.SearchResult, len(results))
+	for i, idx := range order {
+		ranked[i] = results[idx]
+	}
+	return ranked
+}
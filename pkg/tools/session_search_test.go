@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/Agentx-network/agentx/pkg/session"
+)
+
+func newSearchTestSessions(t *testing.T) *session.SessionManager {
+	t.Helper()
+	sm := session.NewSessionManager(t.TempDir())
+	sm.AddMessage("agent:main:telegram:direct:42", "user", "remind me about the backup script")
+	sm.AddMessage("agent:main:discord:direct:42", "assistant", "The backup script runs at 02:00")
+	sm.AddMessage("agent:main:telegram:direct:7", "user", "my backup password is hunter2")
+	for _, key := range []string{
+		"agent:main:telegram:direct:42",
+		"agent:main:discord:direct:42",
+		"agent:main:telegram:direct:7",
+	} {
+		require.NoError(t, sm.Save(key))
+	}
+	return sm
+}
+
+func TestSessionSearchTool_MissingQuery(t *testing.T) {
+	tool := NewSessionSearchTool(session.NewSessionManager(""))
+	result := tool.Execute(context.Background(), map[string]any{"query": "  "})
+	assert.True(t, result.IsError)
+	assert.Contains(t, result.ForLLM, "query is required")
+}
+
+func TestSessionSearchTool_ScopedToSession(t *testing.T) {
+	tool := NewSessionSearchTool(newSearchTestSessions(t))
+	tool.SetSession("telegram", "agent:main:telegram:direct:42")
+
+	result := tool.Execute(context.Background(), map[string]any{"query": "backup"})
+	require.False(t, result.IsError, result.ForLLM)
+	assert.True(t, result.Silent)
+	assert.Contains(t, result.ForLLM, "agent:main:telegram:direct:42")
+	assert.NotContains(t, result.ForLLM, "agent:main:discord:direct:42")
+	assert.NotContains(t, result.ForLLM, "hunter2")
+}
+
+func TestSessionSearchTool_InternalChannelSeesAll(t *testing.T) {
+	tool := NewSessionSearchTool(newSearchTestSessions(t))
+	tool.SetSession("cli", "agent:main:main")
+
+	result := tool.Execute(context.Background(), map[string]any{"query": "backup"})
+	require.False(t, result.IsError, result.ForLLM)
+	assert.Contains(t, result.ForLLM, "hunter2")
+}
+
+func TestSessionSearchTool_Ranked(t *testing.T) {
+	tool := NewSessionSearchTool(newSearchTestSessions(t))
+	tool.SetSession("cli", "agent:main:main")
+	tool.SetRanker(func(ctx context.Context, query string, documents []string) ([]float64, error) {
+		scores := make([]float64, len(documents))
+		for i, doc := range documents {
+			if strings.Contains(doc, "02:00") {
+				scores[i] = 1
+			}
+		}
+		return scores, nil
+	})
+
+	result := tool.Execute(context.Background(), map[string]any{"query": "backup", "limit": 1.0})
+	require.False(t, result.IsError, result.ForLLM)
+	assert.Contains(t, result.ForLLM, "02:00")
+	assert.NotContains(t, result.ForLLM, "hunter2")
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/Agentx-network/agentx/pkg/bus"
 	"github.com/Agentx-network/agentx/pkg/logger"
 	"github.com/Agentx-network/agentx/pkg/providers"
+	"github.com/Agentx-network/agentx/pkg/structured"
 )
 
 type SubagentTask struct {
@@ -23,6 +24,9 @@ type SubagentTask struct {
 	Status        string
 	Result        string
 	Created       int64
+
+	Schema map[string]any // When set, Result must be JSON matching it
+	Object any            // The parsed Result of a structured task
 }
 
 type SubagentManager struct {
@@ -95,6 +99,18 @@ func (sm *SubagentManager) Spawn(
 	ctx context.Context,
 	task, label, agentID, originChannel, originChatID string,
 	callback AsyncCallback,
+) (string, error) {
+	return sm.SpawnWithSchema(ctx, task, label, agentID, originChannel, originChatID, nil, callback)
+}
+
+// SpawnWithSchema is like Spawn, but when schema is set the subagent must
+// finish with a JSON result matching it. Invalid results are retried, and
+// the parsed object is kept in SubagentTask.Object.
+func (sm *SubagentManager) SpawnWithSchema(
+	ctx context.Context,
+	task, label, agentID, originChannel, originChatID string,
+	schema map[string]any,
+	callback AsyncCallback,
 ) (string, error) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
@@ -111,6 +127,7 @@ func (sm *SubagentManager) Spawn(
 		OriginChatID:  originChatID,
 		Status:        "running",
 		Created:       time.Now().UnixMilli(),
+		Schema:        schema,
 	}
 	sm.tasks[taskID] = subagentTask
 
@@ -142,48 +159,19 @@ After completing the task, provide a clear summary of what was done.`
 	default:
 	}
 
-	sm.mu.RLock()
-	fModel := sm.fantasyModel
-	smTools := sm.tools
-	maxIter := sm.maxIterations
-	maxTokens := sm.maxTokens
-	temperature := sm.temperature
-	hasMaxTokens := sm.hasMaxTokens
-	hasTemperature := sm.hasTemperature
-	sm.mu.RUnlock()
-
 	var loopResult *ToolLoopResult
+	var object any
 	var err error
 
-	if fModel != nil {
-		// Use Fantasy agent for subagent execution
-		loopResult, err = runFantasyToolLoop(ctx, fModel, smTools, systemPrompt, task.Task,
-			maxIter, maxTokens, temperature, task.OriginChannel, task.OriginChatID)
-	} else {
-		// Fallback to legacy tool loop
-		messages := []providers.Message{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: task.Task},
-		}
-
-		var llmOptions map[string]any
-		if hasMaxTokens || hasTemperature {
-			llmOptions = map[string]any{}
-			if hasMaxTokens {
-				llmOptions["max_tokens"] = maxTokens
-			}
-			if hasTemperature {
-				llmOptions["temperature"] = temperature
-			}
+	if task.Schema != nil {
+		var res *structured.Result
+		res, err = sm.runStructured(ctx, systemPrompt, task.Task, task.Schema, task.OriginChannel, task.OriginChatID)
+		if err == nil {
+			loopResult = &ToolLoopResult{Content: res.Raw, Iterations: res.Attempts}
+			object = res.Object
 		}
-
-		loopResult, err = RunToolLoop(ctx, ToolLoopConfig{
-			Provider:      sm.provider,
-			Model:         sm.defaultModel,
-			Tools:         smTools,
-			MaxIterations: maxIter,
-			LLMOptions:    llmOptions,
-		}, messages, task.OriginChannel, task.OriginChatID)
+	} else {
+		loopResult, err = sm.runLoop(ctx, systemPrompt, task.Task, task.OriginChannel, task.OriginChatID)
 	}
 
 	sm.mu.Lock()
@@ -215,6 +203,7 @@ After completing the task, provide a clear summary of what was done.`
 	} else {
 		task.Status = "completed"
 		task.Result = loopResult.Content
+		task.Object = object
 		result = &ToolResult{
 			ForLLM: fmt.Sprintf(
 				"Subagent '%s' completed (iterations: %d): %s",
@@ -236,8 +225,9 @@ After completing the task, provide a clear summary of what was done.`
 			Channel:  "system",
 			SenderID: fmt.Sprintf("subagent:%s", task.ID),
 			// Format: "original_channel:original_chat_id" for routing back
-			ChatID:  fmt.Sprintf("%s:%s", task.OriginChannel, task.OriginChatID),
-			Content: announceContent,
+			ChatID:   fmt.Sprintf("%s:%s", task.OriginChannel, task.OriginChatID),
+			Content:  announceContent,
+			Metadata: map[string]string{"subagent_result": task.Result},
 		})
 	}
 }
@@ -297,6 +287,7 @@ func (t *SubagentTool) Parameters() map[string]any {
 				"type":        "string",
 				"description": "Optional short label for the task (for display)",
 			},
+			"schema": schemaParameter,
 		},
 		"required": []string{"task"},
 	}
@@ -319,49 +310,19 @@ func (t *SubagentTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 		return ErrorResult("Subagent manager not configured").WithError(fmt.Errorf("manager is nil"))
 	}
 
-	sm := t.manager
-	sm.mu.RLock()
-	fModel := sm.fantasyModel
-	smTools := sm.tools
-	maxIter := sm.maxIterations
-	maxTokens := sm.maxTokens
-	temperature := sm.temperature
-	hasMaxTokens := sm.hasMaxTokens
-	hasTemperature := sm.hasTemperature
-	sm.mu.RUnlock()
-
 	systemPrompt := "You are a subagent. Complete the given task independently and provide a clear, concise result."
 
 	var loopResult *ToolLoopResult
 	var err error
 
-	if fModel != nil {
-		loopResult, err = runFantasyToolLoop(ctx, fModel, smTools, systemPrompt, task,
-			maxIter, maxTokens, temperature, t.originChannel, t.originChatID)
-	} else {
-		messages := []providers.Message{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: task},
+	if schema, ok := args["schema"].(map[string]any); ok {
+		var res *structured.Result
+		res, err = t.manager.runStructured(ctx, systemPrompt, task, schema, t.originChannel, t.originChatID)
+		if err == nil {
+			loopResult = &ToolLoopResult{Content: res.Raw, Iterations: res.Attempts}
 		}
-
-		var llmOptions map[string]any
-		if hasMaxTokens || hasTemperature {
-			llmOptions = map[string]any{}
-			if hasMaxTokens {
-				llmOptions["max_tokens"] = maxTokens
-			}
-			if hasTemperature {
-				llmOptions["temperature"] = temperature
-			}
-		}
-
-		loopResult, err = RunToolLoop(ctx, ToolLoopConfig{
-			Provider:      sm.provider,
-			Model:         sm.defaultModel,
-			Tools:         smTools,
-			MaxIterations: maxIter,
-			LLMOptions:    llmOptions,
-		}, messages, t.originChannel, t.originChatID)
+	} else {
+		loopResult, err = t.manager.runLoop(ctx, systemPrompt, task, t.originChannel, t.originChatID)
 	}
 	if err != nil {
 		return ErrorResult(fmt.Sprintf("Subagent execution failed: %v", err)).WithError(err)
@@ -391,6 +352,77 @@ func (t *SubagentTool) Execute(ctx context.Context, args map[string]any) *ToolRe
 	}
 }
 
+// runLoop runs task with the Fantasy model, or with the legacy tool loop
+// when no Fantasy model is set.
+func (sm *SubagentManager) runLoop(
+	ctx context.Context,
+	systemPrompt, task, channel, chatID string,
+) (*ToolLoopResult, error) {
+	sm.mu.RLock()
+	fModel := sm.fantasyModel
+	smTools := sm.tools
+	maxIter := sm.maxIterations
+	maxTokens := sm.maxTokens
+	temperature := sm.temperature
+	hasMaxTokens := sm.hasMaxTokens
+	hasTemperature := sm.hasTemperature
+	sm.mu.RUnlock()
+
+	if fModel != nil {
+		return runFantasyToolLoop(ctx, fModel, smTools, systemPrompt, task,
+			maxIter, maxTokens, temperature, channel, chatID)
+	}
+
+	messages := []providers.Message{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: task},
+	}
+
+	var llmOptions map[string]any
+	if hasMaxTokens || hasTemperature {
+		llmOptions = map[string]any{}
+		if hasMaxTokens {
+			llmOptions["max_tokens"] = maxTokens
+		}
+		if hasTemperature {
+			llmOptions["temperature"] = temperature
+		}
+	}
+
+	return RunToolLoop(ctx, ToolLoopConfig{
+		Provider:      sm.provider,
+		Model:         sm.defaultModel,
+		Tools:         smTools,
+		MaxIterations: maxIter,
+		LLMOptions:    llmOptions,
+	}, messages, channel, chatID)
+}
+
+// runStructured runs task until it returns JSON matching schema. Each
+// attempt is a fresh loop, so corrections repeat the task.
+func (sm *SubagentManager) runStructured(
+	ctx context.Context,
+	systemPrompt, task string,
+	schema map[string]any,
+	channel, chatID string,
+) (*structured.Result, error) {
+	req := structured.Request{Task: task, Schema: schema, Stateless: true}
+	return structured.Run(ctx, req, func(ctx context.Context, prompt string) (string, error) {
+		loopResult, err := sm.runLoop(ctx, systemPrompt, prompt, channel, chatID)
+		if err != nil {
+			return "", err
+		}
+		return loopResult.Content, nil
+	})
+}
+
+// schemaParameter is the optional "schema" argument of the spawn and
+// subagent tools.
+var schemaParameter = map[string]any{
+	"type":        "object",
+	"description": "Optional JSON schema; the subagent must then finish with a JSON result matching it",
+}
+
 // runFantasyToolLoop runs a Fantasy agent loop for subagent execution.
 func runFantasyToolLoop(
 	ctx context.Context,
@@ -348,3 +348,59 @@ func TestSubagentTool_ForUserTruncation(t *testing.T) {
 		t.Error("ForLLM should contain reference to original task")
 	}
 }
+
+// scriptedProvider answers each call with the next reply in order.
+type scriptedProvider struct {
+	MockLLMProvider
+	replies []string
+	prompts []string
+}
+
+func (p *scriptedProvider) Chat(
+	ctx context.Context,
+	messages []providers.Message,
+	tools []providers.ToolDefinition,
+	model string,
+	options map[string]any,
+) (*providers.LLMResponse, error) {
+	p.prompts = append(p.prompts, messages[len(messages)-1].Content)
+	reply := p.replies[0]
+	p.replies = p.replies[1:]
+	return &providers.LLMResponse{Content: reply}, nil
+}
+
+func TestSubagentManager_SpawnWithSchema(t *testing.T) {
+	provider := &scriptedProvider{replies: []string{
+		"The count is three.",
+		"```json\n{\"count\": 3}\n```",
+	}}
+	msgBus := bus.NewMessageBus()
+	manager := NewSubagentManager(provider, "test-model", "/tmp/test", msgBus)
+	schema := map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"count": map[string]any{"type": "integer"}},
+		"required":   []string{"count"},
+	}
+
+	ctx := context.Background()
+	if _, err := manager.SpawnWithSchema(ctx, "Count the files", "count", "", "telegram", "42", schema, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, ok := msgBus.ConsumeInbound(ctx)
+	if !ok {
+		t.Fatal("expected an announce message")
+	}
+	if msg.Metadata["subagent_result"] != `{"count": 3}` {
+		t.Errorf("subagent_result = %q", msg.Metadata["subagent_result"])
+	}
+
+	task, _ := manager.GetTask("subagent-1")
+	obj, ok := task.Object.(map[string]any)
+	if !ok || obj["count"] != float64(3) {
+		t.Errorf("task object = %#v", task.Object)
+	}
+	if len(provider.prompts) != 2 || !strings.Contains(provider.prompts[1], "Count the files") {
+		t.Errorf("the retry should repeat the task, prompts: %q", provider.prompts)
+	}
+}
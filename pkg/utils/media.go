@@ -1,11 +1,15 @@
 package utils
 
 import (
+	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/netip"
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,7 +19,7 @@ import (
 
 // IsAudioFile checks if a file is an audio file based on its filename extension and content type.
 func IsAudioFile(filename, contentType string) bool {
-	audioExtensions := []string{".mp3", ".wav", ".ogg", ".m4a", ".flac", ".aac", ".wma"}
+	audioExtensions := []string{".mp3", ".wav", ".ogg", ".opus", ".m4a", ".flac", ".aac", ".wma", ".amr"}
 	audioTypes := []string{"audio/", "application/ogg", "application/x-ogg"}
 
 	for _, ext := range audioExtensions {
@@ -47,11 +51,124 @@ func SanitizeFilename(filename string) string {
 	return base
 }
 
+// MediaDir returns the temp directory that downloaded media is stored in.
+func MediaDir() string {
+	return filepath.Join(os.TempDir(), "agentx_media")
+}
+
+// RetainMedia gives the agent its own reference to each downloaded media
+// file, so channels can delete their copies as soon as a message has been
+// handed off. Files are hard-linked (or copied) inside MediaDir; paths that
+// are not local files, such as URLs, are returned unchanged. Release the
+// result with ReleaseMedia once the message has been processed.
+func RetainMedia(paths []string) []string {
+	if len(paths) == 0 {
+		return paths
+	}
+	mediaDir := MediaDir()
+	if err := os.MkdirAll(mediaDir, 0o700); err != nil {
+		return paths
+	}
+
+	retained := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+			retained = append(retained, path)
+			continue
+		}
+		dst := filepath.Join(mediaDir, uuid.New().String()[:8]+"_"+SanitizeFilename(path))
+		if err := os.Link(path, dst); err != nil {
+			if err := copyFile(path, dst); err != nil {
+				logger.WarnCF("media", "Failed to retain media file", map[string]any{
+					"path":  path,
+					"error": err.Error(),
+				})
+				retained = append(retained, path)
+				continue
+			}
+		}
+		retained = append(retained, dst)
+	}
+	return retained
+}
+
+// ReleaseMedia removes media files retained by RetainMedia. Paths outside
+// MediaDir are left alone.
+func ReleaseMedia(paths []string) {
+	mediaDir := MediaDir()
+	for _, path := range paths {
+		if filepath.Dir(path) != mediaDir {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			logger.DebugCF("media", "Failed to remove media file", map[string]any{
+				"path":  path,
+				"error": err.Error(),
+			})
+		}
+	}
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
+
 // DownloadOptions holds optional parameters for downloading files
 type DownloadOptions struct {
 	Timeout      time.Duration
 	ExtraHeaders map[string]string
 	LoggerPrefix string
+	// PublicOnly refuses to connect to loopback, private, link-local and
+	// other non-public addresses, for URLs taken from untrusted input.
+	PublicOnly bool
+}
+
+// publicOnlyTransport connects to public addresses only. The check runs on
+// the resolved address of every connection, redirects included, so a host
+// name cannot point it at an internal service or a cloud metadata endpoint.
+var publicOnlyTransport = func() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil // a proxy would make the connection on our behalf
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addr, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !isPublicAddr(addr.Addr()) {
+				return fmt.Errorf("refusing to connect to non-public address %s", addr.Addr())
+			}
+			return nil
+		},
+	}
+	t.DialContext = dialer.DialContext
+	return t
+}()
+
+// sharedAddressSpace is the carrier-grade NAT range, 100.64.0.0/10.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// isPublicAddr reports whether ip is a globally routable unicast address.
+func isPublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
 }
 
 // DownloadFile downloads a file from URL to a local temp directory.
@@ -65,7 +182,7 @@ func DownloadFile(url, filename string, opts DownloadOptions) string {
 		opts.LoggerPrefix = "utils"
 	}
 
-	mediaDir := filepath.Join(os.TempDir(), "agentx_media")
+	mediaDir := MediaDir()
 	if err := os.MkdirAll(mediaDir, 0o700); err != nil {
 		logger.ErrorCF(opts.LoggerPrefix, "Failed to create media directory", map[string]any{
 			"error": err.Error(),
@@ -92,6 +209,9 @@ func DownloadFile(url, filename string, opts DownloadOptions) string {
 	}
 
 	client := &http.Client{Timeout: opts.Timeout}
+	if opts.PublicOnly {
+		client.Transport = publicOnlyTransport
+	}
 	resp, err := client.Do(req)
 	if err != nil {
 		logger.ErrorCF(opts.LoggerPrefix, "Failed to download file", map[string]any{
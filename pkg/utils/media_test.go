@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRetainAndReleaseMedia(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "photo.jpg")
+	if err := os.WriteFile(src, []byte("jpeg"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	retained := RetainMedia([]string{src, "https://example.com/a.png"})
+	if len(retained) != 2 {
+		t.Fatalf("expected 2 paths, got %v", retained)
+	}
+	if filepath.Dir(retained[0]) != MediaDir() {
+		t.Errorf("expected retained copy in %s, got %s", MediaDir(), retained[0])
+	}
+	if retained[1] != "https://example.com/a.png" {
+		t.Errorf("URLs should pass through unchanged, got %s", retained[1])
+	}
+
+	// The channel deleting its own copy must not affect the retained one.
+	os.Remove(src)
+	if data, err := os.ReadFile(retained[0]); err != nil || string(data) != "jpeg" {
+		t.Fatalf("retained file unreadable after source removal: %v", err)
+	}
+
+	ReleaseMedia(retained)
+	if _, err := os.Stat(retained[0]); !os.IsNotExist(err) {
+		t.Errorf("expected retained file to be removed, stat err = %v", err)
+	}
+}
+
+func TestReleaseMedia_IgnoresFilesOutsideMediaDir(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keep.txt")
+	if err := os.WriteFile(path, []byte("x"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ReleaseMedia([]string{path})
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("file outside media dir was removed: %v", err)
+	}
+}
+
+func TestIsPublicAddr(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.216.34":      true,
+		"2606:2800:220:1::1": true,
+		"127.0.0.1":          false,
+		"::1":                false,
+		"10.1.2.3":           false,
+		"172.16.0.1":         false,
+		"192.168.1.1":        false,
+		"169.254.169.254":    false,
+		"100.64.0.1":         false,
+		"0.0.0.0":            false,
+		"fd00::1":            false,
+		"fe80::1":            false,
+		"::ffff:127.0.0.1":   false,
+		"224.0.0.1":          false,
+	} {
+		if got := isPublicAddr(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("isPublicAddr(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package voice
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Agentx-network/agentx/pkg/config"
+)
+
+// NewTranscriber returns the transcriber configured in voice.stt. It
+// returns nil when speech-to-text is not set up.
+func NewTranscriber(cfg *config.Config) (Transcriber, error) {
+	stt := cfg.Voice.STT
+	switch stt.Provider {
+	case "":
+		if key := providerAPIKey(cfg, "groq", cfg.Providers.Groq.APIKey); key != "" {
+			return NewGroqTranscriber(key), nil
+		}
+		return nil, nil
+	case "groq":
+		key := stt.APIKey
+		if key == "" {
+			key = providerAPIKey(cfg, "groq", cfg.Providers.Groq.APIKey)
+		}
+		return NewOpenAITranscriber(firstNonEmpty(stt.APIBase, groqAPIBase), key, firstNonEmpty(stt.Model, groqModel), stt.Language), nil
+	case "openai":
+		key := stt.APIKey
+		if key == "" && stt.APIBase == "" {
+			key = providerAPIKey(cfg, "openai", cfg.Providers.OpenAI.APIKey)
+		}
+		return NewOpenAITranscriber(stt.APIBase, key, stt.Model, stt.Language), nil
+	case "whisper_cpp":
+		if stt.ModelPath == "" {
+			return nil, fmt.Errorf("voice.stt.model_path is required for whisper_cpp")
+		}
+		return NewWhisperCppTranscriber(stt.Binary, stt.ModelPath, stt.Language), nil
+	default:
+		return nil, fmt.Errorf("unknown voice.stt.provider %q (want groq, openai or whisper_cpp)", stt.Provider)
+	}
+}
+
+// NewSynthesizer returns the synthesizer configured in voice.tts. It
+// returns nil when text-to-speech is not set up.
+func NewSynthesizer(cfg *config.Config) (Synthesizer, error) {
+	tts := cfg.Voice.TTS
+	switch tts.Provider {
+	case "":
+		if key := providerAPIKey(cfg, "openai", cfg.Providers.OpenAI.APIKey); key != "" {
+			return NewOpenAISynthesizer("", key, tts.Model, tts.Voice), nil
+		}
+		return nil, nil
+	case "openai":
+		key := tts.APIKey
+		if key == "" && tts.APIBase == "" {
+			key = providerAPIKey(cfg, "openai", cfg.Providers.OpenAI.APIKey)
+		}
+		return NewOpenAISynthesizer(tts.APIBase, key, tts.Model, tts.Voice), nil
+	case "piper":
+		if tts.ModelPath == "" {
+			return nil, fmt.Errorf("voice.tts.model_path is required for piper")
+		}
+		return NewPiperSynthesizer(tts.Binary, tts.ModelPath), nil
+	default:
+		return nil, fmt.Errorf("unknown voice.tts.provider %q (want openai or piper)", tts.Provider)
+	}
+}
+
+// providerAPIKey returns the key from the providers section, or from the
+// first model_list entry for that protocol.
+func providerAPIKey(cfg *config.Config, protocol, legacyKey string) string {
+	if legacyKey != "" {
+		return legacyKey
+	}
+	for _, mc := range cfg.ModelList {
+		if strings.HasPrefix(mc.Model, protocol+"/") && mc.APIKey != "" {
+			return mc.APIKey
+		}
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,189 @@
+package voice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Agentx-network/agentx/pkg/logger"
+)
+
+// Audio formats a Synthesizer can produce.
+const (
+	FormatOgg = "ogg" // Opus in Ogg, what Telegram and WhatsApp play as voice notes
+	FormatWAV = "wav"
+)
+
+// MaxSpeechChars is the longest reply spoken; longer replies stay text.
+const MaxSpeechChars = 4000
+
+// Synthesizer turns a reply into speech.
+type Synthesizer interface {
+	// Synthesize renders text as audio in format and returns the path of a
+	// temporary file, which the caller removes.
+	Synthesize(ctx context.Context, text, format string) (string, error)
+	IsAvailable() bool
+}
+
+const (
+	openAITTSModel = "tts-1"
+	openAITTSVoice = "alloy"
+)
+
+// OpenAISynthesizer calls an OpenAI-compatible /audio/speech endpoint:
+// OpenAI or a local server such as openedai-speech or Kokoro-FastAPI.
+type OpenAISynthesizer struct {
+	apiKey     string
+	apiBase    string
+	model      string
+	voice      string
+	httpClient *http.Client
+}
+
+// NewOpenAISynthesizer creates a synthesizer for the endpoint at apiBase.
+func NewOpenAISynthesizer(apiBase, apiKey, model, voice string) *OpenAISynthesizer {
+	return &OpenAISynthesizer{
+		apiKey:     apiKey,
+		apiBase:    strings.TrimRight(firstNonEmpty(apiBase, openAIAPIBase), "/"),
+		model:      firstNonEmpty(model, openAITTSModel),
+		voice:      firstNonEmpty(voice, openAITTSVoice),
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+func (s *OpenAISynthesizer) Synthesize(ctx context.Context, text, format string) (string, error) {
+	responseFormat := "opus"
+	if format == FormatWAV {
+		responseFormat = "wav"
+	}
+	body, err := json.Marshal(map[string]any{
+		"model":           s.model,
+		"voice":           s.voice,
+		"input":           text,
+		"response_format": responseFormat,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.apiBase+"/audio/speech", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	logger.DebugCF("voice", "Sending speech request", map[string]any{
+		"url":         req.URL.String(),
+		"text_length": len(text),
+		"format":      responseFormat,
+	})
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return "", fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(errBody))
+	}
+
+	out, err := os.CreateTemp("", "agentx-speech-*."+format)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		os.Remove(out.Name())
+		return "", fmt.Errorf("failed to read audio: %w", err)
+	}
+	return out.Name(), nil
+}
+
+// IsAvailable reports whether the endpoint can be called: OpenAI needs a
+// key, a local server does not.
+func (s *OpenAISynthesizer) IsAvailable() bool {
+	return s.apiKey != "" || s.apiBase != openAIAPIBase
+}
+
+// PiperSynthesizer runs the piper text-to-speech engine locally. Piper
+// writes WAV; Ogg output is converted with ffmpeg.
+type PiperSynthesizer struct {
+	binary    string
+	modelPath string
+}
+
+// NewPiperSynthesizer creates a synthesizer for the piper binary (piper
+// when empty) and the .onnx voice at modelPath.
+func NewPiperSynthesizer(binary, modelPath string) *PiperSynthesizer {
+	return &PiperSynthesizer{binary: firstNonEmpty(binary, "piper"), modelPath: modelPath}
+}
+
+func (s *PiperSynthesizer) Synthesize(ctx context.Context, text, format string) (string, error) {
+	out, err := os.CreateTemp("", "agentx-speech-*.wav")
+	if err != nil {
+		return "", err
+	}
+	out.Close()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.binary, "--model", s.modelPath, "--output_file", out.Name())
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		os.Remove(out.Name())
+		return "", fmt.Errorf("piper: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	if format == FormatWAV {
+		return out.Name(), nil
+	}
+
+	defer os.Remove(out.Name())
+	return convertAudio(ctx, out.Name(), ".ogg", "-c:a", "libopus", "-b:a", "32k")
+}
+
+// IsAvailable reports whether the binary is on PATH and the voice exists.
+func (s *PiperSynthesizer) IsAvailable() bool {
+	if _, err := exec.LookPath(s.binary); err != nil || s.modelPath == "" {
+		return false
+	}
+	_, err := os.Stat(s.modelPath)
+	return err == nil
+}
+
+var (
+	codeBlockRe = regexp.MustCompile("(?s)```.*?```")
+	linkRe      = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	markupRe    = regexp.MustCompile("[*_`#>~|]+")
+	spaceRe     = regexp.MustCompile(`[ \t]+`)
+	blankRe     = regexp.MustCompile(`\n\s*\n\s*`)
+)
+
+// SpeechText turns a Markdown reply into text worth reading aloud: code
+// blocks are dropped, links keep their label and formatting marks go.
+func SpeechText(markdown string) string {
+	text := codeBlockRe.ReplaceAllString(markdown, "")
+	text = linkRe.ReplaceAllString(text, "$1")
+	text = markupRe.ReplaceAllString(text, "")
+	text = spaceRe.ReplaceAllString(text, " ")
+	text = blankRe.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
+
+// SpeechOmits reports whether SpeechText leaves out part of a reply the
+// user may need in writing: a code block or the target of a link.
+func SpeechOmits(markdown string) bool {
+	return codeBlockRe.MatchString(markdown) || linkRe.MatchString(markdown)
+}
@@ -10,16 +10,17 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/Agentx-network/agentx/pkg/logger"
 	"github.com/Agentx-network/agentx/pkg/utils"
 )
 
-type GroqTranscriber struct {
-	apiKey     string
-	apiBase    string
-	httpClient *http.Client
+// Transcriber turns a voice message into text.
+type Transcriber interface {
+	Transcribe(ctx context.Context, audioFilePath string) (*TranscriptionResponse, error)
+	IsAvailable() bool
 }
 
 type TranscriptionResponse struct {
@@ -28,20 +29,55 @@ type TranscriptionResponse struct {
 	Duration float64 `json:"duration,omitempty"`
 }
 
-func NewGroqTranscriber(apiKey string) *GroqTranscriber {
-	logger.DebugCF("voice", "Creating Groq transcriber", map[string]any{"has_api_key": apiKey != ""})
+const (
+	groqAPIBase   = "https://api.groq.com/openai/v1"
+	groqModel     = "whisper-large-v3"
+	openAIAPIBase = "https://api.openai.com/v1"
+	openAIModel   = "whisper-1"
+)
 
-	apiBase := "https://api.groq.com/openai/v1"
-	return &GroqTranscriber{
-		apiKey:  apiKey,
-		apiBase: apiBase,
+// OpenAITranscriber calls an OpenAI-compatible /audio/transcriptions
+// endpoint: OpenAI, Groq, or a local server such as faster-whisper-server
+// or whisper.cpp's server.
+type OpenAITranscriber struct {
+	apiKey     string
+	apiBase    string
+	model      string
+	language   string
+	httpClient *http.Client
+}
+
+// NewOpenAITranscriber creates a transcriber for the endpoint at apiBase.
+// Local servers usually need no apiKey.
+func NewOpenAITranscriber(apiBase, apiKey, model, language string) *OpenAITranscriber {
+	if apiBase == "" {
+		apiBase = openAIAPIBase
+	}
+	if model == "" {
+		model = openAIModel
+	}
+	logger.DebugCF("voice", "Creating OpenAI-compatible transcriber", map[string]any{
+		"api_base":    apiBase,
+		"model":       model,
+		"has_api_key": apiKey != "",
+	})
+	return &OpenAITranscriber{
+		apiKey:   apiKey,
+		apiBase:  strings.TrimRight(apiBase, "/"),
+		model:    model,
+		language: language,
 		httpClient: &http.Client{
 			Timeout: 60 * time.Second,
 		},
 	}
 }
 
-func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string) (*TranscriptionResponse, error) {
+// NewGroqTranscriber creates a transcriber for Groq's hosted Whisper.
+func NewGroqTranscriber(apiKey string) *OpenAITranscriber {
+	return NewOpenAITranscriber(groqAPIBase, apiKey, groqModel, "")
+}
+
+func (t *OpenAITranscriber) Transcribe(ctx context.Context, audioFilePath string) (*TranscriptionResponse, error) {
 	logger.InfoCF("voice", "Starting transcription", map[string]any{"audio_file": audioFilePath})
 
 	audioFile, err := os.Open(audioFilePath)
@@ -79,14 +115,15 @@ func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string)
 
 	logger.DebugCF("voice", "File copied to request", map[string]any{"bytes_copied": copied})
 
-	if err = writer.WriteField("model", "whisper-large-v3"); err != nil {
-		logger.ErrorCF("voice", "Failed to write model field", map[string]any{"error": err})
-		return nil, fmt.Errorf("failed to write model field: %w", err)
+	fields := [][2]string{{"model", t.model}, {"response_format", "json"}}
+	if t.language != "" {
+		fields = append(fields, [2]string{"language", t.language})
 	}
-
-	if err = writer.WriteField("response_format", "json"); err != nil {
-		logger.ErrorCF("voice", "Failed to write response_format field", map[string]any{"error": err})
-		return nil, fmt.Errorf("failed to write response_format field: %w", err)
+	for _, f := range fields {
+		if err = writer.WriteField(f[0], f[1]); err != nil {
+			logger.ErrorCF("voice", "Failed to write form field", map[string]any{"field": f[0], "error": err})
+			return nil, fmt.Errorf("failed to write %s field: %w", f[0], err)
+		}
 	}
 
 	if err = writer.Close(); err != nil {
@@ -102,9 +139,11 @@ func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string)
 	}
 
 	req.Header.Set("Content-Type", writer.FormDataContentType())
-	req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	if t.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	}
 
-	logger.DebugCF("voice", "Sending transcription request to Groq API", map[string]any{
+	logger.DebugCF("voice", "Sending transcription request", map[string]any{
 		"url":                url,
 		"request_size_bytes": requestBody.Len(),
 		"file_size_bytes":    fileInfo.Size(),
@@ -131,7 +170,7 @@ func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string)
 		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
 	}
 
-	logger.DebugCF("voice", "Received response from Groq API", map[string]any{
+	logger.DebugCF("voice", "Received transcription response", map[string]any{
 		"status_code":         resp.StatusCode,
 		"response_size_bytes": len(body),
 	})
@@ -142,18 +181,23 @@ func (t *GroqTranscriber) Transcribe(ctx context.Context, audioFilePath string)
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
+	logTranscription(&result)
+	return &result, nil
+}
+
+// IsAvailable reports whether the endpoint can be called. Hosted APIs need
+// a key; a custom api_base (a local server) does not.
+func (t *OpenAITranscriber) IsAvailable() bool {
+	available := t.apiKey != "" || (t.apiBase != openAIAPIBase && t.apiBase != groqAPIBase)
+	logger.DebugCF("voice", "Checking transcriber availability", map[string]any{"available": available})
+	return available
+}
+
+func logTranscription(result *TranscriptionResponse) {
 	logger.InfoCF("voice", "Transcription completed successfully", map[string]any{
 		"text_length":           len(result.Text),
 		"language":              result.Language,
 		"duration_seconds":      result.Duration,
 		"transcription_preview": utils.Truncate(result.Text, 50),
 	})
-
-	return &result, nil
-}
-
-func (t *GroqTranscriber) IsAvailable() bool {
-	available := t.apiKey != ""
-	logger.DebugCF("voice", "Checking transcriber availability", map[string]any{"available": available})
-	return available
 }
@@ -0,0 +1,246 @@
+package voice
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/Agentx-network/agentx/pkg/config"
+)
+
+func writeAudio(t *testing.T, name string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte("fake audio"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOpenAITranscriber_Transcribe(t *testing.T) {
+	var form map[string]string
+	var auth, fileName string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" {
+			http.NotFound(w, r)
+			return
+		}
+		auth = r.Header.Get("Authorization")
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("parsing form: %v", err)
+		}
+		form = map[string]string{}
+		for k, v := range r.MultipartForm.Value {
+			form[k] = v[0]
+		}
+		f, header, _ := r.FormFile("file")
+		fileName = header.Filename
+		data, _ := io.ReadAll(f)
+		if string(data) != "fake audio" {
+			t.Errorf("file content = %q", data)
+		}
+		io.WriteString(w, `{"text":"turn on the lights","language":"en"}`)
+	}))
+	defer srv.Close()
+
+	tr := NewOpenAITranscriber(srv.URL+"/v1/", "", "large-v3", "en")
+	if !tr.IsAvailable() {
+		t.Error("a local server needs no key")
+	}
+	result, err := tr.Transcribe(context.Background(), writeAudio(t, "voice.ogg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Text != "turn on the lights" {
+		t.Errorf("text = %q", result.Text)
+	}
+	if form["model"] != "large-v3" || form["language"] != "en" || fileName != "voice.ogg" {
+		t.Errorf("form = %v, file = %q", form, fileName)
+	}
+	if auth != "" {
+		t.Errorf("no key should mean no Authorization header, got %q", auth)
+	}
+}
+
+func TestOpenAITranscriber_APIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"bad key"}`, http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	_, err := NewOpenAITranscriber(srv.URL, "k", "", "").Transcribe(context.Background(), writeAudio(t, "a.mp3"))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestHostedTranscriberNeedsKey(t *testing.T) {
+	if NewGroqTranscriber("").IsAvailable() {
+		t.Error("Groq without a key should not be available")
+	}
+	if !NewGroqTranscriber("gsk").IsAvailable() {
+		t.Error("Groq with a key should be available")
+	}
+}
+
+func TestNewTranscriber(t *testing.T) {
+	cfg := &config.Config{}
+	if tr, err := NewTranscriber(cfg); tr != nil || err != nil {
+		t.Errorf("nothing configured: %v, %v", tr, err)
+	}
+
+	cfg.ModelList = []config.ModelConfig{{ModelName: "llama", Model: "groq/llama-3.3-70b", APIKey: "gsk"}}
+	tr, err := NewTranscriber(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o, ok := tr.(*OpenAITranscriber); !ok || o.apiBase != groqAPIBase || o.apiKey != "gsk" {
+		t.Errorf("a Groq key should enable Groq, got %+v", tr)
+	}
+
+	cfg.Voice.STT = config.STTConfig{Provider: "openai", APIBase: "http://localhost:8000/v1"}
+	tr, _ = NewTranscriber(cfg)
+	if o := tr.(*OpenAITranscriber); o.apiBase != "http://localhost:8000/v1" || o.model != openAIModel {
+		t.Errorf("openai: %+v", o)
+	}
+
+	cfg.Voice.STT = config.STTConfig{Provider: "whisper_cpp"}
+	if _, err := NewTranscriber(cfg); err == nil {
+		t.Error("whisper_cpp without a model should fail")
+	}
+
+	cfg.Voice.STT = config.STTConfig{Provider: "vosk"}
+	if _, err := NewTranscriber(cfg); err == nil {
+		t.Error("unknown provider should fail")
+	}
+}
+
+func TestWhisperCppTranscriber(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the whisper binary")
+	}
+	dir := t.TempDir()
+	model := filepath.Join(dir, "ggml-base.bin")
+	os.WriteFile(model, []byte("model"), 0o644)
+	binary := filepath.Join(dir, "whisper-cli")
+	script := "#!/bin/sh\necho \"$@\" > " + filepath.Join(dir, "args") + "\necho ' Hello there.'\necho ' How are you?'\n"
+	if err := os.WriteFile(binary, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := NewWhisperCppTranscriber(binary, model, "")
+	if !tr.IsAvailable() {
+		t.Fatal("binary and model exist")
+	}
+	result, err := tr.Transcribe(context.Background(), writeAudio(t, "voice.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Text != "Hello there. How are you?" {
+		t.Errorf("text = %q", result.Text)
+	}
+	args, _ := os.ReadFile(filepath.Join(dir, "args"))
+	if want := "-m " + model; !strings.Contains(string(args), want) || !strings.Contains(string(args), "-l auto") {
+		t.Errorf("args = %q", args)
+	}
+
+	if NewWhisperCppTranscriber(binary, filepath.Join(dir, "missing.bin"), "").IsAvailable() {
+		t.Error("a missing model should not be available")
+	}
+}
+
+func TestOpenAISynthesizer(t *testing.T) {
+	var req map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/speech" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		io.WriteString(w, "OggS fake opus")
+	}))
+	defer srv.Close()
+
+	s := NewOpenAISynthesizer(srv.URL+"/v1", "", "", "nova")
+	if !s.IsAvailable() {
+		t.Error("a local server needs no key")
+	}
+	path, err := s.Synthesize(context.Background(), "Hello there", FormatOgg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(path)
+
+	if data, _ := os.ReadFile(path); string(data) != "OggS fake opus" || filepath.Ext(path) != ".ogg" {
+		t.Errorf("audio file %s = %q", path, data)
+	}
+	if req["input"] != "Hello there" || req["voice"] != "nova" || req["model"] != openAITTSModel || req["response_format"] != "opus" {
+		t.Errorf("request = %v", req)
+	}
+}
+
+func TestPiperSynthesizer(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the piper binary")
+	}
+	dir := t.TempDir()
+	model := filepath.Join(dir, "en_US-lessac-medium.onnx")
+	os.WriteFile(model, []byte("voice"), 0o644)
+	binary := filepath.Join(dir, "piper")
+	// Writes its stdin to the file after --output_file.
+	script := "#!/bin/sh\nwhile [ $# -gt 0 ]; do [ \"$1\" = --output_file ] && out=$2; shift; done\ncat > \"$out\"\n"
+	if err := os.WriteFile(binary, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewPiperSynthesizer(binary, model)
+	if !s.IsAvailable() {
+		t.Fatal("binary and voice exist")
+	}
+	path, err := s.Synthesize(context.Background(), "spoken text", FormatWAV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(path)
+	if data, _ := os.ReadFile(path); string(data) != "spoken text" {
+		t.Errorf("piper got %q", data)
+	}
+}
+
+func TestNewSynthesizer(t *testing.T) {
+	cfg := &config.Config{}
+	if s, err := NewSynthesizer(cfg); s != nil || err != nil {
+		t.Errorf("nothing configured: %v, %v", s, err)
+	}
+
+	cfg.Providers.OpenAI.APIKey = "sk"
+	if s, _ := NewSynthesizer(cfg); s == nil || !s.IsAvailable() {
+		t.Error("an OpenAI key should enable OpenAI speech")
+	}
+
+	cfg.Voice.TTS = config.TTSConfig{Provider: "piper"}
+	if _, err := NewSynthesizer(cfg); err == nil {
+		t.Error("piper without a voice should fail")
+	}
+}
+
+func TestSpeechText(t *testing.T) {
+	in := "## Result\n\nThe **answer** is in [the docs](https://example.com).\n\n```go\nfmt.Println(1)\n```\n\nUse `go test`."
+	want := "Result\n\nThe answer is in the docs.\n\nUse go test."
+	if got := SpeechText(in); got != want {
+		t.Errorf("SpeechText() = %q, want %q", got, want)
+	}
+	if !SpeechOmits(in) {
+		t.Error("SpeechOmits() = false for a reply with code and a link")
+	}
+	if SpeechOmits("The **answer** is `42`.") {
+		t.Error("SpeechOmits() = true for a reply that is spoken in full")
+	}
+}
@@ -0,0 +1,117 @@
+package voice
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/Agentx-network/agentx/pkg/logger"
+)
+
+const defaultWhisperBinary = "whisper-cli"
+
+// WhisperCppTranscriber runs a local whisper.cpp binary, so voice messages
+// never leave the machine. whisper.cpp reads 16 kHz mono WAV; other formats
+// are converted with ffmpeg first.
+type WhisperCppTranscriber struct {
+	binary    string
+	modelPath string
+	language  string
+}
+
+// NewWhisperCppTranscriber creates a transcriber for the whisper.cpp binary
+// (whisper-cli when empty) and the ggml model at modelPath.
+func NewWhisperCppTranscriber(binary, modelPath, language string) *WhisperCppTranscriber {
+	if binary == "" {
+		binary = defaultWhisperBinary
+	}
+	if language == "" {
+		language = "auto"
+	}
+	return &WhisperCppTranscriber{binary: binary, modelPath: modelPath, language: language}
+}
+
+func (t *WhisperCppTranscriber) Transcribe(ctx context.Context, audioFilePath string) (*TranscriptionResponse, error) {
+	logger.InfoCF("voice", "Starting whisper.cpp transcription", map[string]any{"audio_file": audioFilePath})
+
+	input := audioFilePath
+	if !strings.EqualFold(filepath.Ext(audioFilePath), ".wav") {
+		wav, err := convertToWAV(ctx, audioFilePath)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(wav)
+		input = wav
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, t.binary,
+		"-m", t.modelPath,
+		"-f", input,
+		"-l", t.language,
+		"--no-timestamps",
+		"--no-prints",
+	)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		logger.ErrorCF("voice", "whisper.cpp failed", map[string]any{
+			"error":  err.Error(),
+			"stderr": strings.TrimSpace(stderr.String()),
+		})
+		return nil, fmt.Errorf("whisper.cpp: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var lines []string
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	result := &TranscriptionResponse{Text: strings.Join(lines, " ")}
+	logTranscription(result)
+	return result, nil
+}
+
+// IsAvailable reports whether the binary is on PATH and the model exists.
+func (t *WhisperCppTranscriber) IsAvailable() bool {
+	_, err := exec.LookPath(t.binary)
+	available := err == nil && t.modelPath != ""
+	if available {
+		_, err = os.Stat(t.modelPath)
+		available = err == nil
+	}
+	logger.DebugCF("voice", "Checking whisper.cpp availability", map[string]any{"available": available})
+	return available
+}
+
+// convertToWAV converts audio to the 16 kHz mono WAV whisper.cpp expects and
+// returns the path of the temporary file.
+func convertToWAV(ctx context.Context, path string) (string, error) {
+	return convertAudio(ctx, path, ".wav", "-ar", "16000", "-ac", "1", "-c:a", "pcm_s16le")
+}
+
+// convertAudio converts audio with ffmpeg into a temporary file with the
+// given extension, passing args as the output options.
+func convertAudio(ctx context.Context, path, ext string, args ...string) (string, error) {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return "", fmt.Errorf("ffmpeg is not installed to convert %s to %s", filepath.Base(path), ext)
+	}
+	out, err := os.CreateTemp("", "agentx-voice-*"+ext)
+	if err != nil {
+		return "", err
+	}
+	out.Close()
+
+	cmdArgs := append([]string{"-y", "-loglevel", "error", "-i", path}, args...)
+	cmd := exec.CommandContext(ctx, "ffmpeg", append(cmdArgs, out.Name())...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(out.Name())
+		return "", fmt.Errorf("converting %s to %s: %w: %s", filepath.Base(path), ext, err, strings.TrimSpace(string(output)))
+	}
+	return out.Name(), nil
+}